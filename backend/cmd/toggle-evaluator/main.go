@@ -0,0 +1,71 @@
+// Command toggle-evaluator serves only the SDK evaluation routes, so the evaluation
+// tier can be scaled and deployed separately from the management API in cmd/toggle.
+// It shares the database and reads flags through the same snapshot cache.
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jalil32/toggle/config"
+	server "github.com/jalil32/toggle/internal/app"
+	"github.com/jalil32/toggle/internal/middleware"
+	"github.com/jalil32/toggle/internal/routes/sdk"
+	"github.com/lmittmann/tint"
+)
+
+// Exit codes match cmd/toggle
+const (
+	exitOK       = 0
+	exitConfig   = 2
+	exitDatabase = 3
+	exitServer   = 4
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run starts the evaluator and returns the process exit code
+func run() int {
+	logger := slog.New(tint.NewHandler(os.Stdout, nil))
+
+	// JWT settings are not needed: SDK routes authenticate with project API keys
+	cfg, err := config.LoadConfig()
+	if err == nil {
+		err = cfg.ValidateEvaluator()
+	}
+	if err != nil {
+		server.LogConfigError(logger, err)
+		return exitConfig
+	}
+
+	db, err := server.InitDb(cfg)
+	if err != nil {
+		logger.Error("Failed to connect to database",
+			"error", err,
+			"hint", fmt.Sprintf("Check that Postgres is reachable at %s:%s and the POSTGRES_* credentials are correct.", cfg.Database.Host, cfg.Database.Port),
+		)
+		return exitDatabase
+	}
+	logger.Info("Successfully connected to postgres database")
+
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			logger.Error("Failed to close database connection", "error", closeErr)
+		}
+	}()
+
+	reporter := middleware.LogReporter{Logger: logger}
+
+	if err := server.StartEvaluator(cfg, logger, db, reporter, sdk.EvaluatorRoutes); err != nil {
+		logger.Error("Evaluator stopped",
+			"error", err,
+			"hint", fmt.Sprintf("Check that port %s is free and the database schema is migrated (goose up).", cfg.Backend.Port),
+		)
+		return exitServer
+	}
+
+	return exitOK
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// forbiddenDeps are the management API packages the evaluator must not link: user JWTs,
+// users and tenants, change approval and the full route table
+var forbiddenDeps = []string{
+	"github.com/golang-jwt/jwt/v5",
+	"github.com/jalil32/toggle/internal/auth",
+	"github.com/jalil32/toggle/internal/users",
+	"github.com/jalil32/toggle/internal/tenants",
+	"github.com/jalil32/toggle/internal/quotas",
+	"github.com/jalil32/toggle/internal/changes",
+	"github.com/jalil32/toggle/internal/changesets",
+	"github.com/jalil32/toggle/internal/middleware/management",
+	"github.com/jalil32/toggle/internal/routes",
+}
+
+func TestEvaluatorDoesNotLinkManagementPackages(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	out, err := exec.Command(goTool, "list", "-deps", ".").Output()
+	if err != nil {
+		t.Fatalf("go list -deps: %v", err)
+	}
+
+	deps := map[string]bool{}
+	for _, dep := range strings.Fields(string(out)) {
+		deps[dep] = true
+	}
+	if !deps["github.com/jalil32/toggle/internal/routes/sdk"] {
+		t.Fatalf("go list -deps output does not include the SDK routes:\n%s", out)
+	}
+	for _, dep := range forbiddenDeps {
+		if deps[dep] {
+			t.Errorf("toggle-evaluator depends on %s", dep)
+		}
+	}
+}
@@ -1,14 +1,25 @@
 package main
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 
 	"github.com/jalil32/toggle/config"
 	server "github.com/jalil32/toggle/internal/app"
+	"github.com/jalil32/toggle/internal/middleware"
+	"github.com/jalil32/toggle/internal/routes"
 	"github.com/lmittmann/tint"
 )
 
+// Exit codes, so supervisors and scripts can tell startup failures apart
+const (
+	exitOK       = 0
+	exitConfig   = 2
+	exitDatabase = 3
+	exitServer   = 4
+)
+
 // @title Toggle API
 // @version 1.0
 // @description Feature flag management API
@@ -20,28 +31,35 @@ import (
 // @name Authorization
 // @description Enter your token as: Bearer <token>
 func main() {
+	os.Exit(run())
+}
+
+// run starts the API and returns the process exit code. Startup stops at the first
+// failing step so the server never runs with a broken configuration or database.
+func run() int {
 	// Initialise structures logger
 	logger := slog.New(tint.NewHandler(os.Stdout, nil))
 
-	// Load configuration
+	// Load and validate configuration
 	cfg, err := config.LoadConfig()
-
+	if err == nil {
+		err = cfg.Validate()
+	}
 	if err != nil {
-		logger.Error("Failed to load configuration", "error", err)
+		server.LogConfigError(logger, err)
+		return exitConfig
 	}
 
-	// Connect to the database
+	// Connect to the database (sqlx.Connect pings it)
 	db, err := server.InitDb(cfg)
 	if err != nil {
-		logger.Error("Failed to connect to database", "error", err)
-	}
-
-	// Test the database connection
-	if err := db.Ping(); err != nil {
-		logger.Error("Failed to connect to database", "error", err)
-	} else {
-		logger.Info(("Successfully connected to postgres database"))
+		logger.Error("Failed to connect to database",
+			"error", err,
+			"hint", fmt.Sprintf("Check that Postgres is reachable at %s:%s and the POSTGRES_* credentials are correct.", cfg.Database.Host, cfg.Database.Port),
+		)
+		return exitDatabase
 	}
+	logger.Info("Successfully connected to postgres database")
 
 	defer func() {
 		if closeErr := db.Close(); closeErr != nil {
@@ -50,8 +68,16 @@ func main() {
 	}()
 
 	// Start the server (blocks until error or termination)
-	if err := server.StartServer(cfg, logger, db); err != nil {
-		logger.Error(err.Error())
+	// Unexpected errors are logged; plug an error tracker (e.g. Sentry) in here
+	reporter := middleware.LogReporter{Logger: logger}
+
+	if err := server.StartServer(cfg, logger, db, reporter, routes.Routes); err != nil {
+		logger.Error("Server stopped",
+			"error", err,
+			"hint", fmt.Sprintf("Check that port %s is free and the database schema is migrated (goose up).", cfg.Backend.Port),
+		)
+		return exitServer
 	}
 
+	return exitOK
 }
@@ -1,24 +1,41 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	_ "github.com/joho/godotenv/autoload"
 )
 
 type Config struct {
-	Router   RouterConfig
-	Backend  BackendConfig
-	Database PostgresConfig
-	JWT      JWTConfig
+	Router      RouterConfig
+	Backend     BackendConfig
+	Database    PostgresConfig
+	JWT         JWTConfig
+	Migrations  MigrationsConfig
+	Quotas      QuotasConfig
+	Encryption  EncryptionConfig
+	Evaluation  EvaluationConfig
+	SmokeTest   SmokeTestConfig
+	Maintenance MaintenanceConfig
+	Benchmark   BenchmarkConfig
 }
 
 type RouterConfig struct {
 	GinMode string
+	// TrustedProxies are the IPs and CIDRs allowed to report the client's address in
+	// X-Forwarded-For or X-Real-IP; empty trusts none, so the connecting address is used
+	TrustedProxies []string
+	// ClientIPHeader is a header the hosting platform sets to the client's address, such as
+	// CF-Connecting-IP; it is trusted from any peer, so only set it behind that platform
+	ClientIPHeader string
 }
 
 type BackendConfig struct {
-	Port string
+	Port     string
+	GRPCPort string // port of the gRPC management API; empty doesn't serve it
 }
 
 type PostgresConfig struct {
@@ -37,13 +54,78 @@ type JWTConfig struct {
 	SkipAuth bool
 }
 
+// MigrationsConfig holds the rollout phase of each online column migration
+// (off, dual_write, dual_read or cutover; see internal/pkg/dualwrite)
+type MigrationsConfig struct {
+	FlagKeyPhase string
+}
+
+// QuotasConfig holds per-tenant management API quotas.
+// Limits are requests per hour by plan; zero keeps the built-in default.
+type QuotasConfig struct {
+	Store           string // postgres (shared by all instances) or memory (per instance)
+	FreeLimit       int
+	TeamLimit       int
+	EnterpriseLimit int
+}
+
+// EncryptionConfig holds the master key that wraps per-tenant data keys.
+// Without it tenant encryption keys can't be created or used.
+type EncryptionConfig struct {
+	MasterKey string // base64-encoded 32-byte key
+}
+
+// EvaluationConfig holds SDK evaluation settings
+type EvaluationConfig struct {
+	ArchiveGracePeriod string // Go duration archived flags are still served for; empty keeps the default
+	Budget             string // Go duration a bulk evaluation may take before partial results are returned; empty keeps the default
+	GeoIPDatabase      string // path to the IP range CSV geo targeting locates callers with; empty disables geo targeting
+}
+
+// SmokeTestConfig holds the token deployment pipelines use to run the smoke test.
+// Without it the smoke test endpoint isn't served.
+type SmokeTestConfig struct {
+	Token string
+}
+
+// MaintenanceConfig holds the token operators use to announce maintenance windows.
+// Without it the maintenance admin API isn't served.
+type MaintenanceConfig struct {
+	Token string
+}
+
+// BenchmarkConfig holds the token operators use to run the evaluation benchmark.
+// Without it the benchmark endpoint isn't served.
+type BenchmarkConfig struct {
+	Token string
+}
+
+// LoadConfig reads configuration from the environment (and .env, if present).
+// It only fails on values that can't be parsed; call Validate to check consistency.
 func LoadConfig() (*Config, error) {
+	skipAuth, err := parseBool(os.Getenv("SKIP_AUTH"))
+	if err != nil {
+		return nil, Problem{Setting: "SKIP_AUTH", Message: err.Error(), Hint: "Use true or false."}
+	}
+
+	quotaLimits := make(map[string]int)
+	for _, setting := range []string{"API_QUOTA_FREE", "API_QUOTA_TEAM", "API_QUOTA_ENTERPRISE"} {
+		limit, err := parseInt(os.Getenv(setting))
+		if err != nil {
+			return nil, Problem{Setting: setting, Message: err.Error(), Hint: "Use a whole number of requests per hour, or leave it empty for the default."}
+		}
+		quotaLimits[setting] = limit
+	}
+
 	cfg := &Config{
 		Router: RouterConfig{
-			GinMode: os.Getenv("GIN_MODE"),
+			GinMode:        os.Getenv("GIN_MODE"),
+			TrustedProxies: parseList(os.Getenv("TRUSTED_PROXIES")),
+			ClientIPHeader: os.Getenv("CLIENT_IP_HEADER"),
 		},
 		Backend: BackendConfig{
-			Port: os.Getenv("BACKEND_PORT"),
+			Port:     os.Getenv("BACKEND_PORT"),
+			GRPCPort: os.Getenv("GRPC_PORT"),
 		},
 		Database: PostgresConfig{
 			User:     os.Getenv("POSTGRES_USER"),
@@ -57,8 +139,69 @@ func LoadConfig() (*Config, error) {
 			JWKSURL:  os.Getenv("JWT_JWKS_URL"),
 			Issuer:   os.Getenv("JWT_ISSUER"),
 			Audience: os.Getenv("JWT_AUDIENCE"),
-			SkipAuth: os.Getenv("SKIP_AUTH") == "true",
+			SkipAuth: skipAuth,
+		},
+		Migrations: MigrationsConfig{
+			FlagKeyPhase: os.Getenv("FLAG_KEY_MIGRATION_PHASE"),
+		},
+		Quotas: QuotasConfig{
+			Store:           os.Getenv("API_QUOTA_STORE"),
+			FreeLimit:       quotaLimits["API_QUOTA_FREE"],
+			TeamLimit:       quotaLimits["API_QUOTA_TEAM"],
+			EnterpriseLimit: quotaLimits["API_QUOTA_ENTERPRISE"],
+		},
+		Encryption: EncryptionConfig{
+			MasterKey: os.Getenv("ENCRYPTION_MASTER_KEY"),
+		},
+		Evaluation: EvaluationConfig{
+			ArchiveGracePeriod: os.Getenv("ARCHIVED_FLAG_GRACE_PERIOD"),
+			Budget:             os.Getenv("EVALUATION_BUDGET"),
+			GeoIPDatabase:      os.Getenv("GEOIP_DATABASE"),
+		},
+		SmokeTest: SmokeTestConfig{
+			Token: os.Getenv("SMOKE_TEST_TOKEN"),
+		},
+		Maintenance: MaintenanceConfig{
+			Token: os.Getenv("MAINTENANCE_TOKEN"),
+		},
+		Benchmark: BenchmarkConfig{
+			Token: os.Getenv("BENCHMARK_TOKEN"),
 		},
 	}
 	return cfg, nil
 }
+
+// parseBool parses a boolean setting; empty means false
+func parseBool(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean %q", s)
+	}
+	return b, nil
+}
+
+// parseList splits a comma-separated setting, dropping blanks; empty means none
+func parseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseInt parses an integer setting; empty means zero
+func parseInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jalil32/toggle/internal/pkg/dualwrite"
+)
+
+// minSmokeTestTokenLength keeps the smoke test token, which can create tenants, from being guessable
+const minSmokeTestTokenLength = 32
+
+// minMaintenanceTokenLength keeps the maintenance token, which changes what every SDK is told, from being guessable
+const minMaintenanceTokenLength = 32
+
+// minBenchmarkTokenLength keeps the benchmark token, which can saturate the instance's CPUs, from being guessable
+const minBenchmarkTokenLength = 32
+
+// Problem is one invalid or inconsistent setting, with a hint on how to fix it
+type Problem struct {
+	Setting string
+	Message string
+	Hint    string
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Setting, p.Message)
+}
+
+// ValidationError lists every configuration problem found at startup
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid configuration: " + e.Problems[0].Error()
+	}
+	return fmt.Sprintf("invalid configuration: %d problems", len(e.Problems))
+}
+
+// Validate checks required settings and settings that depend on each other.
+// Returns a *ValidationError listing every problem, or nil if the config is usable.
+func (c *Config) Validate() error {
+	return c.validate(true)
+}
+
+// ValidateEvaluator is Validate for the standalone evaluator, which serves only
+// API key authenticated SDK routes and so needs no JWT settings
+func (c *Config) ValidateEvaluator() error {
+	return c.validate(false)
+}
+
+func (c *Config) validate(auth bool) error {
+	var problems []Problem
+	add := func(setting, message, hint string) {
+		problems = append(problems, Problem{Setting: setting, Message: message, Hint: hint})
+	}
+
+	switch c.Router.GinMode {
+	case "", "debug", "release", "test":
+	default:
+		add("GIN_MODE", fmt.Sprintf("unknown mode %q", c.Router.GinMode), "Use debug, release or test, or leave it empty for debug.")
+	}
+
+	for _, proxy := range c.Router.TrustedProxies {
+		if !isIPOrCIDR(proxy) {
+			add("TRUSTED_PROXIES", fmt.Sprintf("%q is not an IP address or CIDR", proxy),
+				"List the load balancers in front of the API, e.g. 10.0.0.0/8,192.168.1.2, or leave it empty to trust none.")
+		}
+	}
+
+	if !isPort(c.Backend.Port) {
+		add("BACKEND_PORT", "must be a port number", "Set BACKEND_PORT to the port the API should listen on, e.g. 8080.")
+	}
+	if c.Backend.GRPCPort != "" && (!isPort(c.Backend.GRPCPort) || c.Backend.GRPCPort == c.Backend.Port) {
+		add("GRPC_PORT", "must be a port number other than BACKEND_PORT",
+			"Set GRPC_PORT to the port the gRPC management API should listen on, e.g. 9090, or leave it empty to not serve it.")
+	}
+
+	required := []struct{ setting, value string }{
+		{"POSTGRES_USER", c.Database.User},
+		{"POSTGRES_NAME", c.Database.Name},
+		{"POSTGRES_HOST", c.Database.Host},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			add(r.setting, "is required", "Set the Postgres connection settings; see .env.template.")
+		}
+	}
+	if !isPort(c.Database.Port) {
+		add("POSTGRES_PORT", "must be a port number", "Set POSTGRES_PORT to the Postgres port, usually 5432.")
+	}
+
+	// The evaluator never reads the JWT settings
+	if auth {
+		if c.JWT.SkipAuth {
+			if c.Router.GinMode == "release" {
+				add("SKIP_AUTH", "auth cannot be skipped when GIN_MODE is release",
+					"Set SKIP_AUTH=false and configure the JWT_* settings, or run in debug mode for local development.")
+			}
+		} else {
+			jwt := []struct{ setting, value string }{
+				{"JWT_JWKS_URL", c.JWT.JWKSURL},
+				{"JWT_ISSUER", c.JWT.Issuer},
+				{"JWT_AUDIENCE", c.JWT.Audience},
+			}
+			for _, r := range jwt {
+				if r.value == "" {
+					add(r.setting, "is required when SKIP_AUTH is false",
+						"Set JWT_JWKS_URL, JWT_ISSUER and JWT_AUDIENCE from your identity provider, or SKIP_AUTH=true for local development.")
+				}
+			}
+			if c.JWT.JWKSURL != "" {
+				if u, err := url.Parse(c.JWT.JWKSURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+					add("JWT_JWKS_URL", "must be an absolute http(s) URL", "Use the JWKS endpoint of your identity provider, e.g. https://<tenant>/.well-known/jwks.json.")
+				}
+			}
+		}
+	}
+
+	if _, err := dualwrite.ParsePhase(c.Migrations.FlagKeyPhase); err != nil {
+		add("FLAG_KEY_MIGRATION_PHASE", err.Error(), "Use off, dual_write, dual_read or cutover.")
+	}
+
+	switch c.Quotas.Store {
+	case "", "postgres", "memory":
+	default:
+		add("API_QUOTA_STORE", fmt.Sprintf("unknown store %q", c.Quotas.Store), "Use postgres, or memory for a single instance.")
+	}
+	limits := []struct {
+		setting string
+		value   int
+	}{
+		{"API_QUOTA_FREE", c.Quotas.FreeLimit},
+		{"API_QUOTA_TEAM", c.Quotas.TeamLimit},
+		{"API_QUOTA_ENTERPRISE", c.Quotas.EnterpriseLimit},
+	}
+	for _, l := range limits {
+		if l.value < 0 {
+			add(l.setting, "must not be negative", "Use a whole number of requests per hour, or leave it empty for the default.")
+		}
+	}
+
+	if c.Evaluation.ArchiveGracePeriod != "" {
+		if grace, err := time.ParseDuration(c.Evaluation.ArchiveGracePeriod); err != nil || grace < 0 {
+			add("ARCHIVED_FLAG_GRACE_PERIOD", "must be a non-negative duration",
+				"Use a Go duration such as 168h, 0 to stop serving flags as soon as they are archived, or leave it empty for the default.")
+		}
+	}
+
+	if c.Evaluation.Budget != "" {
+		if budget, err := time.ParseDuration(c.Evaluation.Budget); err != nil || budget < 0 {
+			add("EVALUATION_BUDGET", "must be a non-negative duration",
+				"Use a Go duration such as 500ms, 0 to let bulk evaluations run to completion, or leave it empty for the default.")
+		}
+	}
+
+	if c.Encryption.MasterKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(c.Encryption.MasterKey); err != nil || len(key) != 32 {
+			add("ENCRYPTION_MASTER_KEY", "must be a base64-encoded 32-byte key",
+				"Generate one with: openssl rand -base64 32. Leave it empty to disable tenant encryption keys.")
+		}
+	}
+
+	if c.SmokeTest.Token != "" && len(c.SmokeTest.Token) < minSmokeTestTokenLength {
+		add("SMOKE_TEST_TOKEN", fmt.Sprintf("must be at least %d characters", minSmokeTestTokenLength),
+			"Generate one with: openssl rand -hex 32. Leave it empty to disable the smoke test endpoint.")
+	}
+
+	if c.Maintenance.Token != "" && len(c.Maintenance.Token) < minMaintenanceTokenLength {
+		add("MAINTENANCE_TOKEN", fmt.Sprintf("must be at least %d characters", minMaintenanceTokenLength),
+			"Generate one with: openssl rand -hex 32. Leave it empty to disable the maintenance admin API.")
+	}
+
+	if c.Benchmark.Token != "" && len(c.Benchmark.Token) < minBenchmarkTokenLength {
+		add("BENCHMARK_TOKEN", fmt.Sprintf("must be at least %d characters", minBenchmarkTokenLength),
+			"Generate one with: openssl rand -hex 32. Leave it empty to disable the evaluation benchmark endpoint.")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Problems returns the individual problems in a validation error, if err is one
+func Problems(err error) []Problem {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.Problems
+	}
+	return nil
+}
+
+func isPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && port > 0 && port <= 65535
+}
+
+func isIPOrCIDR(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
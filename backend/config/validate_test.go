@@ -0,0 +1,179 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Router:  RouterConfig{GinMode: "release"},
+		Backend: BackendConfig{Port: "8080"},
+		Database: PostgresConfig{
+			User: "admin",
+			Name: "toggle",
+			Host: "localhost",
+			Port: "5432",
+		},
+		JWT: JWTConfig{
+			JWKSURL:  "https://example.auth0.com/.well-known/jwks.json",
+			Issuer:   "https://example.auth0.com/",
+			Audience: "https://api.example.com",
+		},
+	}
+}
+
+func problemSettings(err error) []string {
+	var settings []string
+	for _, p := range Problems(err) {
+		settings = append(settings, p.Setting)
+	}
+	return settings
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "skip auth in debug mode", modify: func(c *Config) {
+			c.Router.GinMode = "debug"
+			c.JWT = JWTConfig{SkipAuth: true}
+		}},
+		{name: "skip auth in release mode", modify: func(c *Config) {
+			c.JWT.SkipAuth = true
+		}, want: []string{"SKIP_AUTH"}},
+		{name: "missing jwt settings", modify: func(c *Config) {
+			c.JWT = JWTConfig{}
+		}, want: []string{"JWT_JWKS_URL", "JWT_ISSUER", "JWT_AUDIENCE"}},
+		{name: "relative jwks url", modify: func(c *Config) {
+			c.JWT.JWKSURL = "/.well-known/jwks.json"
+		}, want: []string{"JWT_JWKS_URL"}},
+		{name: "missing database settings", modify: func(c *Config) {
+			c.Database = PostgresConfig{}
+		}, want: []string{"POSTGRES_USER", "POSTGRES_NAME", "POSTGRES_HOST", "POSTGRES_PORT"}},
+		{name: "bad ports and mode", modify: func(c *Config) {
+			c.Router.GinMode = "production"
+			c.Backend.Port = "http"
+		}, want: []string{"GIN_MODE", "BACKEND_PORT"}},
+		{name: "trusted proxies", modify: func(c *Config) {
+			c.Router.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.2", "::1"}
+		}},
+		{name: "bad trusted proxy", modify: func(c *Config) {
+			c.Router.TrustedProxies = []string{"10.0.0.0/8", "load-balancer"}
+		}, want: []string{"TRUSTED_PROXIES"}},
+		{name: "grpc port", modify: func(c *Config) {
+			c.Backend.GRPCPort = "9090"
+		}},
+		{name: "grpc port shared with backend", modify: func(c *Config) {
+			c.Backend.GRPCPort = "8080"
+		}, want: []string{"GRPC_PORT"}},
+		{name: "unknown migration phase", modify: func(c *Config) {
+			c.Migrations.FlagKeyPhase = "dual-write"
+		}, want: []string{"FLAG_KEY_MIGRATION_PHASE"}},
+		{name: "bad quota settings", modify: func(c *Config) {
+			c.Quotas = QuotasConfig{Store: "redis", TeamLimit: -1}
+		}, want: []string{"API_QUOTA_STORE", "API_QUOTA_TEAM"}},
+		{name: "archived flag grace period", modify: func(c *Config) {
+			c.Evaluation.ArchiveGracePeriod = "72h"
+		}},
+		{name: "bad archived flag grace period", modify: func(c *Config) {
+			c.Evaluation.ArchiveGracePeriod = "a week"
+		}, want: []string{"ARCHIVED_FLAG_GRACE_PERIOD"}},
+		{name: "negative archived flag grace period", modify: func(c *Config) {
+			c.Evaluation.ArchiveGracePeriod = "-1h"
+		}, want: []string{"ARCHIVED_FLAG_GRACE_PERIOD"}},
+		{name: "evaluation budget", modify: func(c *Config) {
+			c.Evaluation.Budget = "500ms"
+		}},
+		{name: "bad evaluation budget", modify: func(c *Config) {
+			c.Evaluation.Budget = "-1s"
+		}, want: []string{"EVALUATION_BUDGET"}},
+		{name: "valid encryption master key", modify: func(c *Config) {
+			c.Encryption.MasterKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+		}},
+		{name: "short encryption master key", modify: func(c *Config) {
+			c.Encryption.MasterKey = "c2hvcnQ="
+		}, want: []string{"ENCRYPTION_MASTER_KEY"}},
+		{name: "smoke test token", modify: func(c *Config) {
+			c.SmokeTest.Token = "9f2c4e6a8b0d1f3e5a7c9b2d4f6e8a0c"
+		}},
+		{name: "short smoke test token", modify: func(c *Config) {
+			c.SmokeTest.Token = "secret"
+		}, want: []string{"SMOKE_TEST_TOKEN"}},
+		{name: "short maintenance token", modify: func(c *Config) {
+			c.Maintenance.Token = "secret"
+		}, want: []string{"MAINTENANCE_TOKEN"}},
+		{name: "short benchmark token", modify: func(c *Config) {
+			c.Benchmark.Token = "secret"
+		}, want: []string{"BENCHMARK_TOKEN"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			if got := problemSettings(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected problems with %v, got %v", tt.want, got)
+			}
+			for _, p := range Problems(err) {
+				if p.Hint == "" {
+					t.Errorf("%s: expected a remediation hint", p.Setting)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateEvaluator_IgnoresJWTSettings(t *testing.T) {
+	cfg := validConfig()
+	cfg.JWT = JWTConfig{}
+
+	if err := cfg.ValidateEvaluator(); err != nil {
+		t.Errorf("expected no error without JWT settings, got %v", err)
+	}
+
+	cfg.Database = PostgresConfig{}
+	if got := problemSettings(cfg.ValidateEvaluator()); len(got) == 0 {
+		t.Error("expected database settings to still be required")
+	}
+}
+
+func TestLoadConfig_RejectsInvalidSkipAuth(t *testing.T) {
+	t.Setenv("SKIP_AUTH", "yes please")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Error("expected error for invalid SKIP_AUTH")
+	}
+}
+
+func TestLoadConfig_RejectsInvalidQuota(t *testing.T) {
+	t.Setenv("API_QUOTA_FREE", "lots")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Error("expected error for invalid API_QUOTA_FREE")
+	}
+}
+
+func TestLoadConfig_SplitsTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.0/8, ,192.168.1.2 ")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := []string{"10.0.0.0/8", "192.168.1.2"}; !reflect.DeepEqual(cfg.Router.TrustedProxies, want) {
+		t.Errorf("expected %v, got %v", want, cfg.Router.TrustedProxies)
+	}
+}
@@ -0,0 +1,54 @@
+package activity
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/tenant/activity", h.Recent)
+}
+
+// Recent returns the tenant's newest flag and project changes, up to ?limit= (default 50)
+func (h *handler) Recent(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := h.service.Recent(c.Request.Context(), tenantID, limit)
+	if err != nil {
+		if errors.Is(err, ErrInvalidLimit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list activity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,40 @@
+package activity
+
+import "time"
+
+// Entity types in the activity feed
+const (
+	EntityFlag    = "flag"
+	EntityProject = "project"
+)
+
+// Actions derived from row timestamps and change requests; flag history
+// entries keep their own action (e.g. expired, promoted)
+const (
+	ActionCreated         = "created"
+	ActionUpdated         = "updated"
+	ActionChangeRequested = "change_requested"
+	ActionChangeApproved  = "change_approved"
+	ActionChangeRejected  = "change_rejected"
+	ActionCommented       = "commented"
+	ActionAPIKeyRevealed  = "api_key_revealed"
+)
+
+// Feed sizes
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// Entry is one change to a flag or project. Actor fields are nil for system actions
+// and for changes whose author isn't recorded (plain creates and updates).
+type Entry struct {
+	EntityType string    `json:"entity_type" db:"entity_type"`
+	EntityID   string    `json:"entity_id" db:"entity_id"`
+	EntityName string    `json:"entity_name" db:"entity_name"`
+	ProjectID  *string   `json:"project_id" db:"project_id"`
+	Action     string    `json:"action" db:"action"`
+	ActorID    *string   `json:"actor_id" db:"actor_id"`
+	ActorName  *string   `json:"actor_name" db:"actor_name"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,91 @@
+package activity
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	// ListRecent returns the tenant's newest activity across flags and projects, newest first
+	ListRecent(ctx context.Context, tenantID string, limit int) ([]Entry, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// ListRecent merges flag history, change requests, comments, API key reveals and flag/project timestamps.
+// Each source is limited before the merge so the feed only reads the newest rows of each.
+func (r *postgresRepository) ListRecent(ctx context.Context, tenantID string, limit int) ([]Entry, error) {
+	query := `
+		SELECT a.entity_type, a.entity_id, a.entity_name, a.project_id, a.action,
+		       a.actor_id, u.name AS actor_name, a.created_at
+		FROM (
+			(SELECT 'flag' AS entity_type, f.id AS entity_id, f.name AS entity_name, f.project_id,
+			        h.action, h.actor_id, h.created_at
+			 FROM flag_history h
+			 INNER JOIN flags f ON f.id = h.flag_id
+			 WHERE h.tenant_id = $1
+			 ORDER BY h.created_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT 'flag', f.id, f.name, f.project_id, $3::text, cr.proposed_by, cr.created_at
+			 FROM flag_change_requests cr
+			 INNER JOIN flags f ON f.id = cr.flag_id
+			 WHERE cr.tenant_id = $1
+			 ORDER BY cr.created_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT 'flag', f.id, f.name, f.project_id,
+			        CASE cr.status WHEN 'approved' THEN $4::text ELSE $5::text END, cr.reviewed_by, cr.reviewed_at
+			 FROM flag_change_requests cr
+			 INNER JOIN flags f ON f.id = cr.flag_id
+			 WHERE cr.tenant_id = $1 AND cr.reviewed_at IS NOT NULL
+			 ORDER BY cr.reviewed_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT 'flag', f.id, f.name, f.project_id, $6::text, c.author_id, c.created_at
+			 FROM flag_comments c
+			 INNER JOIN flags f ON f.id = c.flag_id
+			 WHERE c.tenant_id = $1
+			 ORDER BY c.created_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT 'flag', id, name, project_id, $7::text, NULL::uuid, created_at
+			 FROM flags
+			 WHERE tenant_id = $1
+			 ORDER BY created_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT 'flag', id, name, project_id, $8::text, NULL::uuid, updated_at
+			 FROM flags
+			 WHERE tenant_id = $1 AND updated_at > created_at
+			 ORDER BY updated_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT 'project', id, name, id, $7::text, NULL::uuid, created_at
+			 FROM projects
+			 WHERE tenant_id = $1
+			 ORDER BY created_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT 'project', id, name, id, $8::text, NULL::uuid, updated_at
+			 FROM projects
+			 WHERE tenant_id = $1 AND updated_at > created_at
+			 ORDER BY updated_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT 'project', p.id, p.name, p.id, $9::text, kr.revealed_by, kr.revealed_at
+			 FROM api_key_reveals kr
+			 INNER JOIN projects p ON p.id = kr.project_id
+			 WHERE kr.tenant_id = $1
+			 ORDER BY kr.revealed_at DESC LIMIT $2)
+		) a
+		LEFT JOIN users u ON u.id = a.actor_id
+		ORDER BY a.created_at DESC, a.entity_id
+		LIMIT $2
+	`
+
+	entries := []Entry{}
+	err := r.db.SelectContext(ctx, &entries, query, tenantID, limit,
+		ActionChangeRequested, ActionChangeApproved, ActionChangeRejected, ActionCommented,
+		ActionCreated, ActionUpdated, ActionAPIKeyRevealed)
+	return entries, err
+}
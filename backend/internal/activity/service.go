@@ -0,0 +1,44 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var ErrInvalidLimit = errors.New("invalid limit")
+
+type Service interface {
+	// Recent returns up to limit of the tenant's newest changes; zero means DefaultLimit
+	Recent(ctx context.Context, tenantID string, limit int) ([]Entry, error)
+}
+
+type service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) Service {
+	return &service{repo: repo, logger: logger}
+}
+
+func (s *service) Recent(ctx context.Context, tenantID string, limit int) ([]Entry, error) {
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	if limit < 1 || limit > MaxLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLimit, MaxLimit)
+	}
+
+	entries, err := s.repo.ListRecent(ctx, tenantID, limit)
+	if err != nil {
+		s.logger.Error("failed to list activity",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+
+	return entries, nil
+}
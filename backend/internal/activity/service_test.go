@@ -0,0 +1,65 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type mockRepository struct {
+	entries   []Entry
+	err       error
+	lastLimit int
+}
+
+func (m *mockRepository) ListRecent(ctx context.Context, tenantID string, limit int) ([]Entry, error) {
+	m.lastLimit = limit
+	return m.entries, m.err
+}
+
+func newTestService(repo *mockRepository) Service {
+	return NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestServiceRecent(t *testing.T) {
+	repo := &mockRepository{entries: []Entry{
+		{EntityType: EntityFlag, EntityID: "flag-1", Action: "promoted", CreatedAt: time.Now()},
+	}}
+	svc := newTestService(repo)
+
+	entries, err := svc.Recent(context.Background(), "tenant-1", 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	if repo.lastLimit != DefaultLimit {
+		t.Errorf("expected default limit %d, got %d", DefaultLimit, repo.lastLimit)
+	}
+
+	if _, err := svc.Recent(context.Background(), "tenant-1", 10); err != nil || repo.lastLimit != 10 {
+		t.Errorf("expected limit 10 to be passed through, got %d (err %v)", repo.lastLimit, err)
+	}
+}
+
+func TestServiceRecent_RejectsInvalidLimit(t *testing.T) {
+	svc := newTestService(&mockRepository{})
+
+	for _, limit := range []int{-1, MaxLimit + 1} {
+		if _, err := svc.Recent(context.Background(), "tenant-1", limit); !errors.Is(err, ErrInvalidLimit) {
+			t.Errorf("limit %d: expected ErrInvalidLimit, got %v", limit, err)
+		}
+	}
+}
+
+func TestServiceRecent_WrapsRepositoryErrors(t *testing.T) {
+	svc := newTestService(&mockRepository{err: errors.New("database error")})
+
+	if _, err := svc.Recent(context.Background(), "tenant-1", 0); err == nil {
+		t.Error("expected an error")
+	}
+}
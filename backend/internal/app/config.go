@@ -0,0 +1,30 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/jalil32/toggle/config"
+)
+
+// LogConfigError logs each configuration problem with its remediation hint
+func LogConfigError(logger *slog.Logger, err error) {
+	var problem config.Problem
+	problems := config.Problems(err)
+	if problems == nil && errors.As(err, &problem) {
+		problems = []config.Problem{problem}
+	}
+
+	if problems == nil {
+		logger.Error("Failed to load configuration", "error", err)
+		return
+	}
+
+	for _, p := range problems {
+		logger.Error("Invalid configuration",
+			"setting", p.Setting,
+			"problem", p.Message,
+			"hint", p.Hint,
+		)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
 )
 
 // CustomLogger is a Gin middleware that uses slog for logging.
@@ -25,6 +27,7 @@ func CustomLogger(logger *slog.Logger) gin.HandlerFunc {
 			"path", c.Request.URL.Path,
 			"status", c.Writer.Status(),
 			"duration", duration.Seconds(),
+			"request_id", appContext.RequestID(c.Request.Context()),
 		)
 	}
 }
@@ -1,35 +1,115 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jalil32/toggle/config"
-	routes "github.com/jalil32/toggle/internal/routes"
+	"github.com/jalil32/toggle/internal/middleware"
+	"github.com/jalil32/toggle/internal/pkg/batch"
 	"github.com/jmoiron/sqlx"
 )
 
-func StartServer(cfg *config.Config, logger *slog.Logger, db *sqlx.DB) error {
+// ShutdownTimeout bounds how long in-flight requests get to finish once the process is
+// asked to stop
+const ShutdownTimeout = 15 * time.Second
+
+// RouteFunc registers a binary's routes: routes.Routes for the full API, or
+// sdk.EvaluatorRoutes for the evaluator. Taking it as an argument keeps this package from
+// linking the management API into the evaluator. Background work runs until ctx is
+// cancelled; trackers that buffer writes are started on flushers so shutdown can wait
+// for their final flush.
+type RouteFunc func(ctx context.Context, flushers *batch.Group, router *gin.Engine, logger *slog.Logger, cfg *config.Config, db *sqlx.DB) error
+
+// StartServer registers routes and serves the API. Panics in handlers are recovered and
+// sent to reporter; pass middleware.LogReporter when no error tracker is configured.
+func StartServer(cfg *config.Config, logger *slog.Logger, db *sqlx.DB, reporter middleware.ErrorReporter, routes RouteFunc) error {
+	return serve("Server", cfg, logger, db, reporter, routes)
+}
+
+// StartEvaluator serves only the health checks and SDK routes, for the standalone evaluation tier
+func StartEvaluator(cfg *config.Config, logger *slog.Logger, db *sqlx.DB, reporter middleware.ErrorReporter, routes RouteFunc) error {
+	return serve("Evaluator", cfg, logger, db, reporter, routes)
+}
+
+// serve runs the router until SIGINT or SIGTERM, then shuts down gracefully: in-flight
+// requests finish first, then background work stops and buffered writes are flushed
+// before it returns, while the database is still open.
+func serve(name string, cfg *config.Config, logger *slog.Logger, db *sqlx.DB, reporter middleware.ErrorReporter, routes RouteFunc) error {
+	router, err := newRouter(cfg, logger, reporter)
+	if err != nil {
+		return err
+	}
+
+	background, stopBackground := context.WithCancel(context.Background())
+	var flushers batch.Group
+	defer func() {
+		stopBackground()
+		flushers.Wait()
+		logger.Info("Background work flushed")
+	}()
+
+	// Register routes
+	if err := routes(background, &flushers, router, logger, cfg, db); err != nil {
+		logger.Error("Failed to register routes", "error", err)
+		return err
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Start the server
+	srv := &http.Server{Addr: "0.0.0.0:" + cfg.Backend.Port, Handler: router}
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.ListenAndServe()
+	}()
+	logger.Info("Starting "+name, "port", cfg.Backend.Port)
+
+	select {
+	case err := <-served:
+		return err
+	case <-signals.Done():
+	}
+
+	logger.Info("Shutting down " + name)
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// newRouter returns a gin router with the middleware every binary shares
+func newRouter(cfg *config.Config, logger *slog.Logger, reporter middleware.ErrorReporter) (*gin.Engine, error) {
 	// Set gin to release mode so we get clean logs
 	gin.SetMode(cfg.Router.GinMode)
 
 	// Initialise gin router
 	router := gin.New()
 
+	// Client IPs feed geo targeting and rate limits, so forwarding headers are only believed
+	// from the configured proxies; gin otherwise trusts them from anyone
+	if err := router.SetTrustedProxies(cfg.Router.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	router.TrustedPlatform = cfg.Router.ClientIPHeader
+
 	// router.Use(cors.New(corsConfig)) // pass cors config to gin router
 
+	// Request IDs come first so the logger and error reports can be correlated
+	router.Use(middleware.RequestID())
+
 	// This means all our logs will be same format instead of a mix between gins and slogs
 	router.Use(CustomLogger(logger))
 
-	// Register routes
-	if err := routes.Routes(router, logger, cfg, db); err != nil {
-		logger.Error("Failed to register routes", "error", err)
-		return err
-	}
-
-	// Start the server
-	logger.Info("Starting Server", "port", cfg.Backend.Port)
-	err := router.Run("0.0.0.0:" + cfg.Backend.Port)
+	// Panics become structured 500s instead of raw Gin/net/http output
+	router.Use(middleware.Recovery(reporter))
 
-	return err
+	return router, nil
 }
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/jalil32/toggle/config"
+	"github.com/jalil32/toggle/internal/middleware"
+)
+
+func TestNewRouter_ClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		router  config.RouterConfig
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "forwarding headers are ignored without trusted proxies",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7", "X-Real-IP": "203.0.113.7"},
+			want:    "192.0.2.1",
+		},
+		{
+			name:    "forwarding headers are believed from a trusted proxy",
+			router:  config.RouterConfig{TrustedProxies: []string{"192.0.2.0/24"}},
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "forwarding headers are ignored from other proxies",
+			router:  config.RouterConfig{TrustedProxies: []string{"10.0.0.0/8"}},
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			want:    "192.0.2.1",
+		},
+		{
+			name:    "the platform header is used when configured",
+			router:  config.RouterConfig{ClientIPHeader: "CF-Connecting-IP"},
+			headers: map[string]string{"CF-Connecting-IP": "203.0.113.7", "X-Forwarded-For": "198.51.100.9"},
+			want:    "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.router.GinMode = gin.TestMode
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			router, err := newRouter(&config.Config{Router: tt.router}, logger, middleware.LogReporter{Logger: logger})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = "192.0.2.1:4321"
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("expected client IP %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
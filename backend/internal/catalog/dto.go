@@ -0,0 +1,31 @@
+package catalog
+
+import "time"
+
+// PushTargetResponse is a push target as the API returns it. The signing secret is
+// write-only: responses only say whether one is set.
+type PushTargetResponse struct {
+	TenantID     string     `json:"tenant_id"`
+	URL          string     `json:"url"`
+	Signed       bool       `json:"signed"`
+	Template     string     `json:"template,omitempty"`
+	ContentType  string     `json:"content_type"`
+	LastPushedAt *time.Time `json:"last_pushed_at"`
+	LastError    string     `json:"last_error"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func newPushTargetResponse(t *PushTarget) PushTargetResponse {
+	return PushTargetResponse{
+		TenantID:     t.TenantID,
+		URL:          t.URL,
+		Signed:       t.Secret != "",
+		Template:     t.Template,
+		ContentType:  t.ContentType,
+		LastPushedAt: t.LastPushedAt,
+		LastError:    t.LastError,
+		CreatedAt:    t.CreatedAt,
+		UpdatedAt:    t.UpdatedAt,
+	}
+}
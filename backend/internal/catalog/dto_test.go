@@ -0,0 +1,22 @@
+package catalog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPushTargetResponse_OmitsSecret(t *testing.T) {
+	target := &PushTarget{TenantID: "tenant-1", URL: "https://cmdb.example.com/hooks/toggle", Secret: "whsec-9c1d7e"}
+
+	b, err := json.Marshal(newPushTargetResponse(target))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(string(b), "whsec-9c1d7e") || strings.Contains(string(b), `"secret"`) {
+		t.Errorf("expected the secret to be left out, got %s", b)
+	}
+	if !strings.Contains(string(b), `"signed":true`) {
+		t.Errorf("expected the target to be reported as signed, got %s", b)
+	}
+}
@@ -0,0 +1,164 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/payloadtmpl"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/catalog/flags.yaml", h.Feed)
+	r.GET("/catalog/push", h.GetTarget)
+	r.PUT("/catalog/push", h.SetTarget)
+	r.DELETE("/catalog/push", h.DeleteTarget)
+	r.POST("/catalog/push/run", h.Push)
+	r.POST("/catalog/push/preview", h.Preview)
+}
+
+// Feed returns the tenant's flags as Backstage catalog entities, for a Backstage URL location
+func (h *handler) Feed(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	feed, err := h.service.Feed(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render catalog"})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/yaml", feed)
+}
+
+func (h *handler) GetTarget(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	target, err := h.service.GetTarget(c.Request.Context(), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "push target not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get push target"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newPushTargetResponse(target))
+}
+
+// SetTarget configures the webhook the feed is pushed to
+func (h *handler) SetTarget(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	// Only owners/admins can configure integrations
+	if role != "owner" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req SetPushTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, err := h.service.SetTarget(c.Request.Context(), tenantID, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTarget) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrPreviewNotEnabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set push target"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newPushTargetResponse(target))
+}
+
+func (h *handler) DeleteTarget(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	// Only owners/admins can configure integrations
+	if role != "owner" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	if err := h.service.DeleteTarget(c.Request.Context(), tenantID); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "push target not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete push target"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Push sends the feed now instead of waiting for the next scheduled push.
+// Delivery failures are reported in the returned target's last_error.
+func (h *handler) Push(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	target, err := h.service.Push(c.Request.Context(), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "push target not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to push catalog"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newPushTargetResponse(target))
+}
+
+// Preview renders a payload template with the tenant's current flags without pushing it,
+// so a template can be checked before it is set on the push target
+func (h *handler) Preview(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var req PreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body, contentType, err := h.service.Preview(c.Request.Context(), tenantID, req)
+	if err != nil {
+		if payloadtmpl.IsTemplateError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrPreviewNotEnabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render template"})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
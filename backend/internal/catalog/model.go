@@ -0,0 +1,122 @@
+package catalog
+
+import "time"
+
+// Backstage entity fields written to the feed
+const (
+	entityAPIVersion = "backstage.io/v1alpha1"
+	entityKind       = "Resource"
+	entityType       = "feature-flag"
+	// unknownOwner is used when a flag has no owner custom field; Backstage requires an owner
+	unknownOwner = "unknown"
+)
+
+// Annotations identifying the flag behind each entity
+const (
+	AnnotationFlagID    = "toggle.io/flag-id"
+	AnnotationProjectID = "toggle.io/project-id"
+	AnnotationEnabled   = "toggle.io/enabled"
+)
+
+// OwnerField is the flag custom field naming the owning team, e.g. "group:payments"
+const OwnerField = "owner"
+
+// PushInterval is how often the feed is pushed to configured targets
+const PushInterval = 15 * time.Minute
+
+// FlagEntry is a flag with the metadata the catalog feed needs
+type FlagEntry struct {
+	ID          string    `db:"id"`
+	ProjectID   *string   `db:"project_id"`
+	ProjectName string    `db:"project_name"`
+	Key         string    `db:"key"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	Enabled     bool      `db:"enabled"`
+	Service     *string   `db:"service"` // the flag's service custom field
+	Owner       *string   `db:"owner"`   // the flag's owner custom field
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// Entity is a Backstage catalog entity
+type Entity struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   EntityMetadata `yaml:"metadata"`
+	Spec       EntitySpec     `yaml:"spec"`
+}
+
+type EntityMetadata struct {
+	Name        string            `yaml:"name"`
+	Title       string            `yaml:"title,omitempty"`
+	Description string            `yaml:"description,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+	Tags        []string          `yaml:"tags,omitempty"`
+}
+
+type EntitySpec struct {
+	Type  string `yaml:"type"`
+	Owner string `yaml:"owner"`
+	// DependencyOf lists the components the flag gates, from its service custom field
+	DependencyOf []string `yaml:"dependencyOf,omitempty"`
+}
+
+// PushTarget is a webhook that receives the tenant's catalog feed every PushInterval
+type PushTarget struct {
+	TenantID string `json:"tenant_id" db:"tenant_id"`
+	URL      string `json:"url" db:"url"`
+	Secret   string `json:"-" db:"secret"`
+	Signed   bool   `json:"signed" db:"-"`
+	// Template, when set, renders the payload instead of the Backstage feed (see pkg/payloadtmpl)
+	Template     string     `json:"template,omitempty" db:"template"`
+	ContentType  string     `json:"content_type" db:"content_type"`
+	LastPushedAt *time.Time `json:"last_pushed_at" db:"last_pushed_at"`
+	LastError    string     `json:"last_error" db:"last_error"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type SetPushTargetRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Secret, when set, signs each push with timestamped webhook signature headers (see pkg/webhook)
+	Secret string `json:"secret"`
+	// Template customizes the payload; empty pushes the Backstage feed
+	Template string `json:"template"`
+	// ContentType of templated payloads; defaults to DefaultTemplateContentType
+	ContentType string `json:"content_type"`
+}
+
+// PreviewRequest renders a payload template against the tenant's current flags
+type PreviewRequest struct {
+	Template    string `json:"template" binding:"required"`
+	ContentType string `json:"content_type"`
+}
+
+// Content types of pushed payloads
+const (
+	FeedContentType            = "application/yaml"
+	DefaultTemplateContentType = "application/json"
+)
+
+// TemplateData is what payload templates are rendered with
+type TemplateData struct {
+	TenantID    string
+	GeneratedAt time.Time
+	Flags       []TemplateFlag
+}
+
+// TemplateFlag is a flag as seen by payload templates; missing custom fields are empty
+type TemplateFlag struct {
+	ID          string
+	ProjectID   string
+	ProjectName string
+	Key         string
+	Name        string
+	Description string
+	Enabled     bool
+	Service     string
+	Owner       string
+	// EntityName is the flag's Backstage entity name in the default feed
+	EntityName string
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	flags "github.com/jalil32/toggle/internal/flags"
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	ListFlags(ctx context.Context, tenantID string) ([]FlagEntry, error)
+	GetTarget(ctx context.Context, tenantID string) (*PushTarget, error)
+	UpsertTarget(ctx context.Context, t *PushTarget) error
+	DeleteTarget(ctx context.Context, tenantID string) error
+	ListTargets(ctx context.Context) ([]PushTarget, error)
+	RecordPush(ctx context.Context, tenantID string, pushedAt *time.Time, lastError string) error
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+// ListFlags returns the tenant's flags with their service and owner custom fields
+func (r *postgresRepository) ListFlags(ctx context.Context, tenantID string) ([]FlagEntry, error) {
+	query := `
+		SELECT f.id, f.project_id, COALESCE(p.name, '') AS project_name, COALESCE(f.key, '') AS key,
+		       f.name, f.description, f.enabled, svc.value AS service, own.value AS owner, f.updated_at
+		FROM flags f
+		LEFT JOIN projects p ON p.id = f.project_id AND p.tenant_id = f.tenant_id
+		LEFT JOIN flag_custom_fields svc ON svc.flag_id = f.id AND svc.name = $2
+		LEFT JOIN flag_custom_fields own ON own.flag_id = f.id AND own.name = $3
+		WHERE f.tenant_id = $1
+		ORDER BY project_name ASC, f.name ASC, f.id ASC
+	`
+	entries := []FlagEntry{}
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &entries, query, tenantID, flags.CustomFieldService, OwnerField); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *postgresRepository) GetTarget(ctx context.Context, tenantID string) (*PushTarget, error) {
+	query := `
+		SELECT tenant_id, url, secret, template, content_type, last_pushed_at, last_error, created_at, updated_at
+		FROM catalog_push_targets
+		WHERE tenant_id = $1
+	`
+	var t PushTarget
+	if err := sqlx.GetContext(ctx, r.getDB(ctx), &t, query, tenantID); err != nil {
+		return nil, err
+	}
+	t.Signed = t.Secret != ""
+
+	return &t, nil
+}
+
+// UpsertTarget creates or replaces the tenant's push target, clearing its push status
+func (r *postgresRepository) UpsertTarget(ctx context.Context, t *PushTarget) error {
+	query := `
+		INSERT INTO catalog_push_targets (tenant_id, url, secret, template, content_type)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET url = EXCLUDED.url,
+		    secret = EXCLUDED.secret,
+		    template = EXCLUDED.template,
+		    content_type = EXCLUDED.content_type,
+		    last_pushed_at = NULL,
+		    last_error = '',
+		    updated_at = NOW()
+		RETURNING last_pushed_at, last_error, created_at, updated_at
+	`
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, t.TenantID, t.URL, t.Secret, t.Template, t.ContentType).
+		Scan(&t.LastPushedAt, &t.LastError, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	t.Signed = t.Secret != ""
+
+	return nil
+}
+
+// DeleteTarget removes the tenant's push target
+// Returns sql.ErrNoRows if it has none
+func (r *postgresRepository) DeleteTarget(ctx context.Context, tenantID string) error {
+	result, err := r.getDB(ctx).ExecContext(ctx, `DELETE FROM catalog_push_targets WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListTargets returns every tenant's push target
+func (r *postgresRepository) ListTargets(ctx context.Context) ([]PushTarget, error) {
+	query := `
+		SELECT tenant_id, url, secret, template, content_type, last_pushed_at, last_error, created_at, updated_at
+		FROM catalog_push_targets
+		ORDER BY tenant_id ASC
+	`
+	targets := []PushTarget{}
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &targets, query); err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+// RecordPush stores the outcome of a push; pushedAt is only updated on success
+func (r *postgresRepository) RecordPush(ctx context.Context, tenantID string, pushedAt *time.Time, lastError string) error {
+	query := `
+		UPDATE catalog_push_targets
+		SET last_pushed_at = COALESCE($2, last_pushed_at), last_error = $3
+		WHERE tenant_id = $1
+	`
+	_, err := r.getDB(ctx).ExecContext(ctx, query, tenantID, pushedAt, lastError)
+	return err
+}
@@ -0,0 +1,404 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	flags "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/featuregate"
+	"github.com/jalil32/toggle/internal/pkg/payloadtmpl"
+	"github.com/jalil32/toggle/internal/pkg/slugs"
+	"github.com/jalil32/toggle/internal/pkg/webhook"
+)
+
+var (
+	ErrInvalidTarget = errors.New("invalid push target")
+	// ErrPreviewNotEnabled indicates the tenant hasn't opted in to the payload templates preview
+	ErrPreviewNotEnabled = errors.New("payload templates preview is not enabled")
+)
+
+// maxEntityNameLength is Backstage's limit for metadata.name
+const maxEntityNameLength = 63
+
+// pushTimeout bounds one push so a slow receiver can't hold up the job
+const pushTimeout = 10 * time.Second
+
+type Service interface {
+	// Feed renders the tenant's flags as a multi-document Backstage catalog YAML
+	Feed(ctx context.Context, tenantID string) ([]byte, error)
+	GetTarget(ctx context.Context, tenantID string) (*PushTarget, error)
+	SetTarget(ctx context.Context, tenantID string, req SetPushTargetRequest) (*PushTarget, error)
+	DeleteTarget(ctx context.Context, tenantID string) error
+	// Push sends the feed to the tenant's target now and returns the updated status
+	Push(ctx context.Context, tenantID string) (*PushTarget, error)
+	// PushAll pushes every tenant's feed to its target; run by the scheduler
+	PushAll(ctx context.Context) error
+	// Preview renders a payload template with the tenant's current flags, returning the body and its content type
+	Preview(ctx context.Context, tenantID string, req PreviewRequest) ([]byte, string, error)
+	SetPreviews(previews featuregate.Checker)
+}
+
+type service struct {
+	repo     Repository
+	client   *http.Client
+	previews featuregate.Checker
+	logger   *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) Service {
+	return &service{
+		repo:   repo,
+		client: &http.Client{Timeout: pushTimeout},
+		logger: logger,
+	}
+}
+
+// SetPreviews restricts payload templates to tenants enrolled in the featuregate.PayloadTemplates
+// preview; without a checker every tenant may use them
+func (s *service) SetPreviews(previews featuregate.Checker) {
+	s.previews = previews
+}
+
+// templatesEnabled reports whether the tenant may use payload templates
+func (s *service) templatesEnabled(ctx context.Context, tenantID string) bool {
+	return s.previews == nil || s.previews.Enabled(ctx, tenantID, featuregate.PayloadTemplates)
+}
+
+func (s *service) Feed(ctx context.Context, tenantID string) ([]byte, error) {
+	entries, err := s.listFlags(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, e := range entries {
+		if err := enc.Encode(toEntity(e)); err != nil {
+			return nil, fmt.Errorf("failed to render catalog: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render catalog: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *service) listFlags(ctx context.Context, tenantID string) ([]FlagEntry, error) {
+	entries, err := s.repo.ListFlags(ctx, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list flags for catalog",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list flags for catalog: %w", err)
+	}
+	return entries, nil
+}
+
+// render renders a payload template with the tenant's current flags
+func (s *service) render(ctx context.Context, tenantID string, tmpl *payloadtmpl.Template) ([]byte, error) {
+	entries, err := s.listFlags(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := TemplateData{TenantID: tenantID, GeneratedAt: time.Now().UTC(), Flags: make([]TemplateFlag, len(entries))}
+	for i, e := range entries {
+		data.Flags[i] = toTemplateFlag(e)
+	}
+	return tmpl.Render(data)
+}
+
+func toTemplateFlag(e FlagEntry) TemplateFlag {
+	f := TemplateFlag{
+		ID:          e.ID,
+		ProjectName: e.ProjectName,
+		Key:         e.Key,
+		Name:        e.Name,
+		Description: e.Description,
+		Enabled:     e.Enabled,
+		EntityName:  toEntity(e).Metadata.Name,
+		UpdatedAt:   e.UpdatedAt,
+	}
+	if e.ProjectID != nil {
+		f.ProjectID = *e.ProjectID
+	}
+	if e.Service != nil {
+		f.Service = *e.Service
+	}
+	if e.Owner != nil {
+		f.Owner = *e.Owner
+	}
+	return f
+}
+
+// parseTemplate checks a payload template and its content type, defaulting the content type
+func parseTemplate(source string, contentType string) (*payloadtmpl.Template, string, error) {
+	tmpl, err := payloadtmpl.Parse(source)
+	if err != nil {
+		return nil, "", err
+	}
+	if contentType == "" {
+		contentType = DefaultTemplateContentType
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || !strings.Contains(mediaType, "/") {
+		return nil, "", fmt.Errorf("%w: content_type must be a media type such as application/json", payloadtmpl.ErrInvalidTemplate)
+	}
+	return tmpl, contentType, nil
+}
+
+func (s *service) Preview(ctx context.Context, tenantID string, req PreviewRequest) ([]byte, string, error) {
+	if !s.templatesEnabled(ctx, tenantID) {
+		return nil, "", ErrPreviewNotEnabled
+	}
+	tmpl, contentType, err := parseTemplate(req.Template, req.ContentType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := s.render(ctx, tenantID, tmpl)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, contentType, nil
+}
+
+// toEntity maps a flag to a Backstage Resource that depends on nothing and is a
+// dependency of the component named by its service custom field
+func toEntity(e FlagEntry) Entity {
+	key := e.Key
+	if key == "" {
+		key = flags.KeyFromName(e.Name)
+	}
+	if key == "" {
+		key = e.ID
+	}
+
+	annotations := map[string]string{
+		AnnotationFlagID:  e.ID,
+		AnnotationEnabled: strconv.FormatBool(e.Enabled),
+	}
+	if e.ProjectID != nil {
+		annotations[AnnotationProjectID] = *e.ProjectID
+	}
+
+	entity := Entity{
+		APIVersion: entityAPIVersion,
+		Kind:       entityKind,
+		Metadata: EntityMetadata{
+			Name:        entityName(e.ProjectName, key),
+			Title:       e.Name,
+			Description: e.Description,
+			Annotations: annotations,
+			Tags:        []string{entityType},
+		},
+		Spec: EntitySpec{
+			Type:  entityType,
+			Owner: unknownOwner,
+		},
+	}
+	if e.Owner != nil && *e.Owner != "" {
+		entity.Spec.Owner = *e.Owner
+	}
+	if e.Service != nil && *e.Service != "" {
+		entity.Spec.DependencyOf = []string{componentRef(*e.Service)}
+	}
+
+	return entity
+}
+
+// entityName prefixes the flag key with the project, since keys are only unique per project
+func entityName(projectName string, key string) string {
+	name := key
+	if project := slugs.Generate(projectName); project != "" {
+		name = project + "." + key
+	}
+	if len(name) > maxEntityNameLength {
+		name = strings.TrimRight(name[:maxEntityNameLength], "-_.")
+	}
+	return name
+}
+
+// componentRef turns a service name into a Backstage entity reference; full references
+// such as "component:default/checkout" are used as given
+func componentRef(service string) string {
+	if strings.Contains(service, ":") {
+		return service
+	}
+	return "component:" + service
+}
+
+func (s *service) GetTarget(ctx context.Context, tenantID string) (*PushTarget, error) {
+	target, err := s.repo.GetTarget(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get push target: %w", err)
+	}
+	return target, nil
+}
+
+func (s *service) SetTarget(ctx context.Context, tenantID string, req SetPushTargetRequest) (*PushTarget, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidTarget)
+	}
+
+	target := &PushTarget{TenantID: tenantID, URL: u.String(), Secret: req.Secret, ContentType: FeedContentType}
+	if req.Template != "" {
+		if !s.templatesEnabled(ctx, tenantID) {
+			return nil, ErrPreviewNotEnabled
+		}
+		// Rendering once with the current flags catches templates that only fail at runtime
+		tmpl, contentType, err := parseTemplate(req.Template, req.ContentType)
+		if err == nil {
+			_, err = s.render(ctx, tenantID, tmpl)
+		}
+		if err != nil {
+			if payloadtmpl.IsTemplateError(err) {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidTarget, err.Error())
+			}
+			return nil, err
+		}
+		target.Template = req.Template
+		target.ContentType = contentType
+	}
+	if err := s.repo.UpsertTarget(ctx, target); err != nil {
+		s.logger.Error("failed to set catalog push target",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to set push target: %w", err)
+	}
+
+	s.logger.Info("catalog push target set",
+		slog.String("tenant_id", tenantID),
+		slog.String("host", u.Host),
+	)
+
+	return target, nil
+}
+
+func (s *service) DeleteTarget(ctx context.Context, tenantID string) error {
+	if err := s.repo.DeleteTarget(ctx, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		return fmt.Errorf("failed to delete push target: %w", err)
+	}
+
+	s.logger.Info("catalog push target deleted", slog.String("tenant_id", tenantID))
+	return nil
+}
+
+func (s *service) Push(ctx context.Context, tenantID string) (*PushTarget, error) {
+	target, err := s.GetTarget(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.push(ctx, target); err != nil {
+		return nil, err
+	}
+
+	return s.GetTarget(ctx, tenantID)
+}
+
+func (s *service) PushAll(ctx context.Context) error {
+	targets, err := s.repo.ListTargets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list push targets: %w", err)
+	}
+
+	for i := range targets {
+		// A failed push is recorded on the target and retried next run
+		_ = s.push(ctx, &targets[i])
+	}
+
+	return nil
+}
+
+// push sends the feed to one target and records the outcome. Only failures to record
+// are returned; delivery failures are stored as the target's last error.
+func (s *service) push(ctx context.Context, target *PushTarget) error {
+	deliveryErr := s.deliver(ctx, target)
+
+	var pushedAt *time.Time
+	lastError := ""
+	if deliveryErr != nil {
+		lastError = deliveryErr.Error()
+		s.logger.Warn("catalog push failed",
+			slog.String("tenant_id", target.TenantID),
+			slog.String("error", lastError),
+		)
+	} else {
+		now := time.Now()
+		pushedAt = &now
+	}
+
+	if err := s.repo.RecordPush(ctx, target.TenantID, pushedAt, lastError); err != nil {
+		return fmt.Errorf("failed to record catalog push: %w", err)
+	}
+	return nil
+}
+
+// payload renders what is pushed to a target: its template if it has one, else the Backstage
+// feed. Tenants that opted out of the templates preview get the feed again.
+func (s *service) payload(ctx context.Context, target *PushTarget) ([]byte, string, error) {
+	if target.Template == "" || !s.templatesEnabled(ctx, target.TenantID) {
+		feed, err := s.Feed(ctx, target.TenantID)
+		return feed, FeedContentType, err
+	}
+
+	tmpl, contentType, err := parseTemplate(target.Template, target.ContentType)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := s.render(ctx, target.TenantID, tmpl)
+	return body, contentType, err
+}
+
+func (s *service) deliver(ctx context.Context, target *PushTarget) error {
+	body, contentType, err := s.payload(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if target.Secret != "" {
+		if err := webhook.SignRequest(req, target.Secret, body); err != nil {
+			return err
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("push rejected with status %d", resp.StatusCode)
+	}
+	return nil
+}
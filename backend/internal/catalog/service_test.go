@@ -0,0 +1,317 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/webhook"
+)
+
+type mockRepository struct {
+	entries []FlagEntry
+	targets map[string]*PushTarget
+}
+
+func (m *mockRepository) ListFlags(ctx context.Context, tenantID string) ([]FlagEntry, error) {
+	return m.entries, nil
+}
+
+func (m *mockRepository) GetTarget(ctx context.Context, tenantID string) (*PushTarget, error) {
+	t, ok := m.targets[tenantID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *t
+	return &copied, nil
+}
+
+func (m *mockRepository) UpsertTarget(ctx context.Context, t *PushTarget) error {
+	copied := *t
+	m.targets[t.TenantID] = &copied
+	return nil
+}
+
+func (m *mockRepository) DeleteTarget(ctx context.Context, tenantID string) error {
+	if _, ok := m.targets[tenantID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.targets, tenantID)
+	return nil
+}
+
+func (m *mockRepository) ListTargets(ctx context.Context) ([]PushTarget, error) {
+	var targets []PushTarget
+	for _, t := range m.targets {
+		targets = append(targets, *t)
+	}
+	return targets, nil
+}
+
+func (m *mockRepository) RecordPush(ctx context.Context, tenantID string, pushedAt *time.Time, lastError string) error {
+	t := m.targets[tenantID]
+	if pushedAt != nil {
+		t.LastPushedAt = pushedAt
+	}
+	t.LastError = lastError
+	return nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestServiceFeed(t *testing.T) {
+	repo := &mockRepository{entries: []FlagEntry{
+		{ID: "flag-1", ProjectID: strPtr("project-1"), ProjectName: "Web Store", Key: "new-checkout", Name: "New Checkout", Enabled: true, Service: strPtr("checkout-api"), Owner: strPtr("group:payments")},
+		{ID: "flag-2", Name: "Beta Banner", Service: strPtr("component:default/storefront")},
+		{ID: "flag-3", Name: "Dark Mode"},
+	}}
+	svc := NewService(repo, slog.Default())
+
+	feed, err := svc.Feed(context.Background(), "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var entities []Entity
+	dec := yaml.NewDecoder(strings.NewReader(string(feed)))
+	for {
+		var e Entity
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			t.Fatalf("failed to decode feed: %v", err)
+		}
+		entities = append(entities, e)
+	}
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 entities, got %d:\n%s", len(entities), feed)
+	}
+
+	checkout := entities[0]
+	if checkout.APIVersion != entityAPIVersion || checkout.Kind != entityKind || checkout.Spec.Type != entityType {
+		t.Errorf("unexpected entity header: %+v", checkout)
+	}
+	if checkout.Metadata.Name != "web-store.new-checkout" || checkout.Metadata.Title != "New Checkout" {
+		t.Errorf("expected project-prefixed name, got %+v", checkout.Metadata)
+	}
+	if checkout.Metadata.Annotations[AnnotationFlagID] != "flag-1" || checkout.Metadata.Annotations[AnnotationEnabled] != "true" {
+		t.Errorf("unexpected annotations: %v", checkout.Metadata.Annotations)
+	}
+	if checkout.Spec.Owner != "group:payments" || len(checkout.Spec.DependencyOf) != 1 || checkout.Spec.DependencyOf[0] != "component:checkout-api" {
+		t.Errorf("expected owner and service mapping, got %+v", checkout.Spec)
+	}
+
+	// Full references are kept; flags without a stored key fall back to their name
+	banner := entities[1]
+	if banner.Metadata.Name != "beta-banner" || banner.Spec.DependencyOf[0] != "component:default/storefront" {
+		t.Errorf("unexpected entity: %+v", banner)
+	}
+
+	// Unmapped flags still appear, with no dependency and the placeholder owner
+	darkMode := entities[2]
+	if darkMode.Spec.DependencyOf != nil || darkMode.Spec.Owner != unknownOwner {
+		t.Errorf("expected unmapped flag, got %+v", darkMode.Spec)
+	}
+}
+
+func TestEntityName_Truncates(t *testing.T) {
+	name := entityName(strings.Repeat("project ", 10), "flag")
+	if len(name) > maxEntityNameLength {
+		t.Errorf("expected at most %d characters, got %d", maxEntityNameLength, len(name))
+	}
+	if strings.HasSuffix(name, "-") {
+		t.Errorf("expected no trailing separator, got %q", name)
+	}
+}
+
+func TestServiceSetTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "https url", url: "https://backstage.example.com/api/toggle"},
+		{name: "relative url", url: "/api/toggle", wantErr: ErrInvalidTarget},
+		{name: "unsupported scheme", url: "ftp://backstage.example.com", wantErr: ErrInvalidTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(&mockRepository{targets: map[string]*PushTarget{}}, slog.Default())
+
+			target, err := svc.SetTarget(context.Background(), "tenant-1", SetPushTargetRequest{URL: tt.url, Secret: "s3cret"})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if target.URL != tt.url {
+				t.Errorf("expected url %s, got %s", tt.url, target.URL)
+			}
+		})
+	}
+}
+
+func TestServicePush(t *testing.T) {
+	var gotBody []byte
+	var gotHeader http.Header
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header.Clone()
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	repo := &mockRepository{
+		entries: []FlagEntry{{ID: "flag-1", Key: "new-checkout", Name: "New Checkout", Service: strPtr("checkout-api")}},
+		targets: map[string]*PushTarget{"tenant-1": {TenantID: "tenant-1", URL: server.URL, Secret: "s3cret"}},
+	}
+	svc := NewService(repo, slog.Default())
+	ctx := context.Background()
+
+	target, err := svc.Push(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if target.LastPushedAt == nil || target.LastError != "" {
+		t.Errorf("expected a successful push, got %+v", target)
+	}
+	if !strings.Contains(string(gotBody), "component:checkout-api") {
+		t.Errorf("expected the feed to be pushed, got %s", gotBody)
+	}
+	timestamp, _ := strconv.ParseInt(gotHeader.Get(webhook.TimestampHeader), 10, 64)
+	if want := webhook.Sign("s3cret", timestamp, gotHeader.Get(webhook.NonceHeader), gotBody); gotHeader.Get(webhook.SignatureHeader) != want {
+		t.Errorf("expected a signature of the body, got %s", gotHeader.Get(webhook.SignatureHeader))
+	}
+
+	// A rejected push is recorded without losing the last successful time
+	status = http.StatusInternalServerError
+	if err := svc.PushAll(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	target, _ = svc.GetTarget(ctx, "tenant-1")
+	if target.LastPushedAt == nil || !strings.Contains(target.LastError, "500") {
+		t.Errorf("expected the failure recorded, got %+v", target)
+	}
+
+	if _, err := svc.Push(ctx, "tenant-2"); !errors.Is(err, pkgErrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound without a target, got %v", err)
+	}
+}
+
+func TestServicePush_WithTemplate(t *testing.T) {
+	var gotBody []byte
+	var gotContentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotContentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	repo := &mockRepository{
+		entries: []FlagEntry{
+			{ID: "flag-1", ProjectName: "Web Store", Key: "new-checkout", Name: "New Checkout", Enabled: true, Owner: strPtr("group:payments")},
+			{ID: "flag-2", Name: "Dark Mode"},
+		},
+		targets: map[string]*PushTarget{},
+	}
+	svc := NewService(repo, slog.Default())
+	ctx := context.Background()
+	template := `[{{range $i, $f := .Flags}}{{if $i}},{{end}}{"text": {{json (printf "%s is %v (owner %s)" $f.Name $f.Enabled (default "nobody" $f.Owner))}}}{{end}}]`
+
+	target, err := svc.SetTarget(ctx, "tenant-1", SetPushTargetRequest{URL: server.URL, Template: template})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if target.ContentType != DefaultTemplateContentType {
+		t.Errorf("expected the default template content type, got %q", target.ContentType)
+	}
+
+	if _, err := svc.Push(ctx, "tenant-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `[{"text": "New Checkout is true (owner group:payments)"},{"text": "Dark Mode is false (owner nobody)"}]`
+	if string(gotBody) != want || gotContentType != DefaultTemplateContentType {
+		t.Errorf("expected %s as %s, got %s as %s", want, DefaultTemplateContentType, gotBody, gotContentType)
+	}
+
+	preview, contentType, err := svc.Preview(ctx, "tenant-1", PreviewRequest{Template: "{{range .Flags}}{{.EntityName}}\n{{end}}", ContentType: "text/plain"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(preview) != "web-store.new-checkout\ndark-mode\n" || contentType != "text/plain" {
+		t.Errorf("unexpected preview %q as %s", preview, contentType)
+	}
+}
+
+func TestServiceSetTarget_RejectsBadTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    string
+		contentType string
+	}{
+		{name: "syntax error", template: "{{.Flags"},
+		{name: "missing field", template: "{{.Missing}}"},
+		{name: "included template", template: `{{define "x"}}{{end}}{{template "x"}}`},
+		{name: "bad content type", template: "{{.TenantID}}", contentType: "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(&mockRepository{targets: map[string]*PushTarget{}}, slog.Default())
+
+			_, err := svc.SetTarget(context.Background(), "tenant-1", SetPushTargetRequest{
+				URL: "https://cmdb.example.com/hooks/toggle", Template: tt.template, ContentType: tt.contentType,
+			})
+			if !errors.Is(err, ErrInvalidTarget) {
+				t.Errorf("expected ErrInvalidTarget, got %v", err)
+			}
+		})
+	}
+}
+
+type previewSet map[string]bool
+
+func (p previewSet) Enabled(ctx context.Context, tenantID string, key string) bool {
+	return p[tenantID+"/"+key]
+}
+
+func TestServiceSetTarget_RequiresTemplatesPreview(t *testing.T) {
+	repo := &mockRepository{targets: map[string]*PushTarget{}}
+	svc := NewService(repo, slog.Default())
+	svc.SetPreviews(previewSet{"tenant-2/payload-templates": true})
+	req := SetPushTargetRequest{URL: "https://cmdb.example.com/hooks/toggle", Template: "{{.TenantID}}"}
+
+	if _, err := svc.SetTarget(context.Background(), "tenant-1", req); !errors.Is(err, ErrPreviewNotEnabled) {
+		t.Errorf("expected ErrPreviewNotEnabled, got %v", err)
+	}
+	if _, _, err := svc.Preview(context.Background(), "tenant-1", PreviewRequest{Template: req.Template}); !errors.Is(err, ErrPreviewNotEnabled) {
+		t.Errorf("expected ErrPreviewNotEnabled, got %v", err)
+	}
+	if _, err := svc.SetTarget(context.Background(), "tenant-1", SetPushTargetRequest{URL: req.URL}); err != nil {
+		t.Errorf("expected feed targets to need no preview, got %v", err)
+	}
+	if _, err := svc.SetTarget(context.Background(), "tenant-2", req); err != nil {
+		t.Errorf("expected enrolled tenants to set templates, got %v", err)
+	}
+}
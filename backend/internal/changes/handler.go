@@ -0,0 +1,169 @@
+package changes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/flags/:id/changes", h.Propose)
+	r.GET("/flags/:id/changes", h.List)
+	r.GET("/flags/:id/changes/:changeID", h.Get)
+	r.POST("/flags/:id/changes/:changeID/approve", h.Approve)
+	r.POST("/flags/:id/changes/:changeID/reject", h.Reject)
+
+	r.GET("/projects/:id/approval-policy", h.GetPolicy)
+	r.PUT("/projects/:id/approval-policy", h.UpdatePolicy)
+}
+
+// Propose records a pending change to a flag for an owner or admin to review
+func (h *handler) Propose(c *gin.Context) {
+	var req ProposeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+
+	cr := &ChangeRequest{
+		FlagID:      c.Param("id"),
+		ProposedBy:  &userID,
+		Environment: req.Environment,
+		Changes:     req.Changes,
+		Comment:     req.Comment,
+	}
+
+	if err := h.service.Propose(c.Request.Context(), cr, tenantID); err != nil {
+		h.writeError(c, err, "failed to propose change")
+		return
+	}
+
+	c.JSON(http.StatusCreated, cr)
+}
+
+// List returns a flag's change requests, optionally filtered by ?status=
+func (h *handler) List(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	requests, err := h.service.List(c.Request.Context(), c.Param("id"), tenantID, c.Query("status"))
+	if err != nil {
+		h.writeError(c, err, "failed to list changes")
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	cr, err := h.service.GetByID(c.Request.Context(), c.Param("changeID"), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get change")
+		return
+	}
+
+	c.JSON(http.StatusOK, cr)
+}
+
+// Approve records an approval and applies the change once the project's policy is satisfied
+func (h *handler) Approve(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+	userID := appContext.MustUserID(ctx)
+
+	result, err := h.service.Approve(ctx, c.Param("changeID"), c.Param("id"), tenantID, userID, appContext.UserRole(ctx))
+	if err != nil {
+		h.writeError(c, err, "failed to approve change")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *handler) Reject(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+	userID := appContext.MustUserID(ctx)
+
+	cr, err := h.service.Reject(ctx, c.Param("changeID"), c.Param("id"), tenantID, userID, appContext.UserRole(ctx))
+	if err != nil {
+		h.writeError(c, err, "failed to reject change")
+		return
+	}
+
+	c.JSON(http.StatusOK, cr)
+}
+
+// GetPolicy returns how change requests to the project's flags are approved
+func (h *handler) GetPolicy(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	policy, err := h.service.GetPolicy(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get approval policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *handler) UpdatePolicy(c *gin.Context) {
+	var req UpdateApprovalPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+
+	policy, err := h.service.UpdatePolicy(ctx, c.Param("id"), tenantID, appContext.UserRole(ctx), req)
+	if err != nil {
+		h.writeError(c, err, "failed to update approval policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *handler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidChangeData), errors.Is(err, flag.ErrInvalidFlagData):
+		c.JSON(http.StatusBadRequest, flag.InvalidDataResponse(err))
+	case errors.Is(err, ErrInvalidPolicy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInsufficientPermissions), errors.Is(err, ErrSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotPending), errors.Is(err, ErrAlreadyApproved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case pkgErrors.IsNotFoundError(err):
+		if errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "change request not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,104 @@
+package changes
+
+import (
+	"slices"
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// Change request statuses
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+// ChangeRequest is a proposed flag edit that is applied only once approved
+type ChangeRequest struct {
+	ID         string  `json:"id" db:"id"`
+	TenantID   string  `json:"tenant_id" db:"tenant_id"`
+	FlagID     string  `json:"flag_id" db:"flag_id"`
+	ProposedBy *string `json:"proposed_by" db:"proposed_by"`
+	// Environment is the key of the environment whose config the change edits, which only
+	// takes enabled, rules and rule_logic; nil edits the flag itself
+	Environment *string            `json:"environment" db:"environment"`
+	Changes     flag.UpdateRequest `json:"changes" db:"changes"`
+	Comment     string             `json:"comment" db:"comment"`
+	Status      string             `json:"status" db:"status"`
+	ReviewedBy  *string            `json:"reviewed_by" db:"reviewed_by"`
+	ReviewedAt  *time.Time         `json:"reviewed_at" db:"reviewed_at"`
+	CreatedAt   time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// ApprovalResult is an approval of a change. Once enough reviewers have approved, the change
+// is applied and Flag is the updated flag; until then the change stays pending and Flag is nil.
+type ApprovalResult struct {
+	Change            *ChangeRequest `json:"change"`
+	Flag              *flag.Flag     `json:"flag,omitempty"`
+	Approvals         int            `json:"approvals"`
+	RequiredApprovals int            `json:"required_approvals"`
+}
+
+// MaxRequiredApprovals bounds how many approvers a policy can require
+const MaxRequiredApprovals = 10
+
+// ApprovalPolicy configures the approval workflow for a project's flags. Edits it requires
+// approval for can only be made through change requests; the flags API refuses them.
+// Flags outside a project, and projects without a stored policy, use DefaultApprovalPolicy.
+type ApprovalPolicy struct {
+	ProjectID string `json:"project_id" db:"project_id"`
+	// RequireApproval covers the flags themselves. False allows direct edits, and applies
+	// proposed changes immediately, recorded as approved by their author.
+	RequireApproval bool `json:"require_approval" db:"require_approval"`
+	// Environments are the keys of the environments whose flag configs require approval,
+	// whether or not RequireApproval is set
+	Environments      []string `json:"environments" db:"environments"`
+	RequiredApprovals int      `json:"required_approvals" db:"required_approvals"`
+	AllowSelfApproval bool     `json:"allow_self_approval" db:"allow_self_approval"`
+	// ReviewerRoles are the tenant roles that may approve or reject changes
+	ReviewerRoles []string   `json:"reviewer_roles" db:"reviewer_roles"`
+	UpdatedAt     *time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultApprovalPolicy requires no approval; once enabled, changes need one approval from
+// an owner or admin other than the author
+func DefaultApprovalPolicy(projectID string) *ApprovalPolicy {
+	return &ApprovalPolicy{
+		ProjectID:         projectID,
+		RequireApproval:   false,
+		Environments:      []string{},
+		RequiredApprovals: 1,
+		ReviewerRoles:     []string{"owner", "admin"},
+	}
+}
+
+// RequiresApprovalIn reports whether changes to a flag's config in the environment with the
+// given key, or to the flag itself when environment is empty, require approval
+func (p *ApprovalPolicy) RequiresApprovalIn(environment string) bool {
+	if environment == "" {
+		return p.RequireApproval
+	}
+	return slices.Contains(p.Environments, environment)
+}
+
+// CanReview reports whether a tenant role may approve or reject changes under the policy
+func (p *ApprovalPolicy) CanReview(role string) bool {
+	return slices.Contains(p.ReviewerRoles, role)
+}
+
+// UpdateApprovalPolicyRequest changes some of a project's approval policy; omitted fields keep their value
+type UpdateApprovalPolicyRequest struct {
+	RequireApproval   *bool    `json:"require_approval"`
+	Environments      []string `json:"environments"`
+	RequiredApprovals *int     `json:"required_approvals"`
+	AllowSelfApproval *bool    `json:"allow_self_approval"`
+	ReviewerRoles     []string `json:"reviewer_roles"`
+}
+
+type ProposeRequest struct {
+	Environment *string            `json:"environment"`
+	Changes     flag.UpdateRequest `json:"changes" binding:"required"`
+	Comment     string             `json:"comment"`
+}
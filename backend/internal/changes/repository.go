@@ -0,0 +1,243 @@
+package changes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	Create(ctx context.Context, cr *ChangeRequest) error
+	GetByID(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error)
+	GetForUpdate(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error)
+	ListByFlag(ctx context.Context, flagID string, tenantID string, status string) ([]ChangeRequest, error)
+	SetStatus(ctx context.Context, cr *ChangeRequest) error
+
+	// GetPolicy returns a project's approval policy, or nil if it has none stored.
+	// sql.ErrNoRows means the project doesn't exist in the tenant.
+	GetPolicy(ctx context.Context, projectID string, tenantID string) (*ApprovalPolicy, error)
+	UpsertPolicy(ctx context.Context, p *ApprovalPolicy, tenantID string) error
+	// ListEnvironmentKeys returns the keys of a project's environments by environment ID
+	ListEnvironmentKeys(ctx context.Context, projectID string, tenantID string) (map[string]string, error)
+	// AddApproval records a reviewer's approval and reports whether it is new
+	AddApproval(ctx context.Context, changeID string, userID string, tenantID string) (bool, error)
+	CountApprovals(ctx context.Context, changeID string, tenantID string) (int, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+const selectColumns = `
+	SELECT id, tenant_id, flag_id, proposed_by, environment, changes, comment, status,
+	       reviewed_by, reviewed_at, created_at, updated_at
+	FROM flag_change_requests
+`
+
+func (r *postgresRepository) Create(ctx context.Context, cr *ChangeRequest) error {
+	changesJSON, err := json.Marshal(cr.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO flag_change_requests (tenant_id, flag_id, proposed_by, environment, changes, comment, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, cr.TenantID, cr.FlagID, cr.ProposedBy, cr.Environment, changesJSON, cr.Comment, cr.Status).
+		Scan(&cr.ID, &cr.CreatedAt, &cr.UpdatedAt)
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error) {
+	query := selectColumns + `WHERE id = $1 AND flag_id = $2 AND tenant_id = $3`
+	return scanChangeRequest(r.getDB(ctx).QueryRowxContext(ctx, query, id, flagID, tenantID))
+}
+
+// GetForUpdate loads a change request and locks it until the surrounding transaction ends,
+// so two reviewers can't act on the same request concurrently
+func (r *postgresRepository) GetForUpdate(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error) {
+	query := selectColumns + `WHERE id = $1 AND flag_id = $2 AND tenant_id = $3 FOR UPDATE`
+	return scanChangeRequest(r.getDB(ctx).QueryRowxContext(ctx, query, id, flagID, tenantID))
+}
+
+// ListByFlag returns a flag's change requests, newest first, optionally filtered by status
+func (r *postgresRepository) ListByFlag(ctx context.Context, flagID string, tenantID string, status string) ([]ChangeRequest, error) {
+	query := selectColumns + `
+		WHERE flag_id = $1 AND tenant_id = $2 AND ($3 = '' OR status = $3)
+		ORDER BY created_at DESC
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, flagID, tenantID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []ChangeRequest
+	for rows.Next() {
+		cr, err := scanChangeRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *cr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// SetStatus records the review outcome of a change request
+func (r *postgresRepository) SetStatus(ctx context.Context, cr *ChangeRequest) error {
+	query := `
+		UPDATE flag_change_requests
+		SET status = $3, reviewed_by = $4, reviewed_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING reviewed_at, updated_at
+	`
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, cr.ID, cr.TenantID, cr.Status, cr.ReviewedBy).
+		Scan(&cr.ReviewedAt, &cr.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) GetPolicy(ctx context.Context, projectID string, tenantID string) (*ApprovalPolicy, error) {
+	query := `
+		SELECT ap.require_approval, ap.environments, ap.required_approvals, ap.allow_self_approval, ap.reviewer_roles, ap.updated_at
+		FROM projects p
+		LEFT JOIN project_approval_policies ap ON ap.project_id = p.id
+		WHERE p.id = $1 AND p.tenant_id = $2
+	`
+	var (
+		requireApproval   sql.NullBool
+		environments      pq.StringArray
+		requiredApprovals sql.NullInt64
+		allowSelfApproval sql.NullBool
+		reviewerRoles     pq.StringArray
+		updatedAt         sql.NullTime
+	)
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, projectID, tenantID).
+		Scan(&requireApproval, &environments, &requiredApprovals, &allowSelfApproval, &reviewerRoles, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if !updatedAt.Valid {
+		return nil, nil
+	}
+
+	return &ApprovalPolicy{
+		ProjectID:         projectID,
+		RequireApproval:   requireApproval.Bool,
+		Environments:      environments,
+		RequiredApprovals: int(requiredApprovals.Int64),
+		AllowSelfApproval: allowSelfApproval.Bool,
+		ReviewerRoles:     reviewerRoles,
+		UpdatedAt:         &updatedAt.Time,
+	}, nil
+}
+
+func (r *postgresRepository) UpsertPolicy(ctx context.Context, p *ApprovalPolicy, tenantID string) error {
+	query := `
+		INSERT INTO project_approval_policies (project_id, tenant_id, require_approval, environments, required_approvals, allow_self_approval, reviewer_roles)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (project_id) DO UPDATE SET
+			require_approval = EXCLUDED.require_approval,
+			environments = EXCLUDED.environments,
+			required_approvals = EXCLUDED.required_approvals,
+			allow_self_approval = EXCLUDED.allow_self_approval,
+			reviewer_roles = EXCLUDED.reviewer_roles,
+			updated_at = NOW()
+		WHERE project_approval_policies.tenant_id = EXCLUDED.tenant_id
+		RETURNING updated_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, p.ProjectID, tenantID, p.RequireApproval, pq.Array(p.Environments),
+		p.RequiredApprovals, p.AllowSelfApproval, pq.Array(p.ReviewerRoles)).Scan(&p.UpdatedAt)
+}
+
+func (r *postgresRepository) ListEnvironmentKeys(ctx context.Context, projectID string, tenantID string) (map[string]string, error) {
+	query := `SELECT id, key FROM environments WHERE project_id = $1 AND tenant_id = $2`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]string)
+	for rows.Next() {
+		var id, key string
+		if err := rows.Scan(&id, &key); err != nil {
+			return nil, err
+		}
+		keys[id] = key
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *postgresRepository) AddApproval(ctx context.Context, changeID string, userID string, tenantID string) (bool, error) {
+	query := `
+		INSERT INTO flag_change_approvals (change_request_id, tenant_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (change_request_id, user_id) DO NOTHING
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, query, changeID, tenantID, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *postgresRepository) CountApprovals(ctx context.Context, changeID string, tenantID string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM flag_change_approvals WHERE change_request_id = $1 AND tenant_id = $2`
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, changeID, tenantID).Scan(&count)
+	return count, err
+}
+
+// rowScanner is implemented by both *sqlx.Row and *sqlx.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanChangeRequest(row rowScanner) (*ChangeRequest, error) {
+	var cr ChangeRequest
+	var changesJSON []byte
+
+	err := row.Scan(&cr.ID, &cr.TenantID, &cr.FlagID, &cr.ProposedBy, &cr.Environment, &changesJSON, &cr.Comment, &cr.Status,
+		&cr.ReviewedBy, &cr.ReviewedAt, &cr.CreatedAt, &cr.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(changesJSON, &cr.Changes); err != nil {
+		return nil, err
+	}
+
+	return &cr, nil
+}
@@ -0,0 +1,560 @@
+package changes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+var (
+	ErrInvalidChangeData       = errors.New("invalid change request")
+	ErrNotPending              = errors.New("change request has already been reviewed")
+	ErrInsufficientPermissions = errors.New("insufficient permissions")
+	ErrSelfApproval            = errors.New("change requests cannot be approved by their author")
+	ErrAlreadyApproved         = errors.New("change request already approved by this reviewer")
+	ErrInvalidPolicy           = errors.New("invalid approval policy")
+)
+
+type Service interface {
+	Propose(ctx context.Context, cr *ChangeRequest, tenantID string) error
+	GetByID(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error)
+	List(ctx context.Context, flagID string, tenantID string, status string) ([]ChangeRequest, error)
+	Approve(ctx context.Context, id string, flagID string, tenantID string, reviewerID string, role string) (*ApprovalResult, error)
+	Reject(ctx context.Context, id string, flagID string, tenantID string, reviewerID string, role string) (*ChangeRequest, error)
+
+	GetPolicy(ctx context.Context, projectID string, tenantID string) (*ApprovalPolicy, error)
+	UpdatePolicy(ctx context.Context, projectID string, tenantID string, role string, req UpdateApprovalPolicyRequest) (*ApprovalPolicy, error)
+	// RequiresApproval implements flag.ApprovalGate, so the flags API refuses edits the policy covers
+	RequiresApproval(ctx context.Context, projectID string, environmentID string, tenantID string) (bool, error)
+}
+
+type service struct {
+	repo   Repository
+	flags  flag.Service
+	uow    transaction.UnitOfWork
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, flags flag.Service, uow transaction.UnitOfWork, logger *slog.Logger) Service {
+	return &service{
+		repo:   repo,
+		flags:  flags,
+		uow:    uow,
+		logger: logger,
+	}
+}
+
+// Propose records a pending change to a flag, or to its config in one environment; the flag
+// itself is not modified. If the flag's project doesn't require approval for the change, it
+// is applied at once and recorded as approved by its author.
+func (s *service) Propose(ctx context.Context, cr *ChangeRequest, tenantID string) error {
+	if cr == nil || cr.Changes.IsEmpty() {
+		return fmt.Errorf("%w: changes must set at least one field", ErrInvalidChangeData)
+	}
+
+	comment, err := sanitize.Text(cr.Comment, sanitize.MaxCommentLength)
+	if err != nil {
+		return fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidChangeData, sanitize.MaxCommentLength)
+	}
+	cr.Comment = comment
+
+	if cr.Changes.Rules != nil {
+		if err := flag.ValidateRules(cr.Changes.Rules); err != nil {
+			return err
+		}
+	}
+
+	// The flag must exist in the tenant; GetByID maps missing/forbidden to ErrNotFound
+	f, err := s.flags.GetByID(ctx, cr.FlagID, tenantID)
+	if err != nil {
+		return err
+	}
+	if cr.Changes.Rules != nil {
+		// Rules proposed for the flag itself keep the IDs of the rules they replace
+		if cr.Environment == nil {
+			flag.KeepRuleIDs(cr.Changes.Rules, f.Rules)
+		}
+		flag.AssignRuleIDs(cr.Changes.Rules)
+	}
+
+	if cr.Environment != nil {
+		if err := s.validateEnvironmentChange(ctx, cr, f, tenantID); err != nil {
+			return err
+		}
+	}
+
+	policy, err := s.policyFor(ctx, f, tenantID)
+	if err != nil {
+		return err
+	}
+
+	cr.TenantID = tenantID
+	cr.Status = StatusPending
+
+	if !policy.RequiresApprovalIn(environmentOf(cr)) {
+		return s.applyUnreviewed(ctx, cr, f, tenantID)
+	}
+
+	if err := s.repo.Create(ctx, cr); err != nil {
+		s.logger.Error("failed to create change request",
+			slog.String("flag_id", cr.FlagID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to create change request: %w", err)
+	}
+
+	s.logger.Info("change request proposed",
+		slog.String("id", cr.ID),
+		slog.String("flag_id", cr.FlagID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// applyUnreviewed records a change and applies it in one transaction, for projects without approvals
+func (s *service) applyUnreviewed(ctx context.Context, cr *ChangeRequest, f *flag.Flag, tenantID string) error {
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.Create(txCtx, cr); err != nil {
+			return fmt.Errorf("failed to create change request: %w", err)
+		}
+
+		if err := s.apply(txCtx, cr, f, tenantID); err != nil {
+			return err
+		}
+
+		cr.Status = StatusApproved
+		cr.ReviewedBy = cr.ProposedBy
+		if err := s.repo.SetStatus(txCtx, cr); err != nil {
+			return fmt.Errorf("update change request status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logReviewError("apply", cr.ID, tenantID, err)
+		return err
+	}
+
+	s.logger.Info("change request applied without approval",
+		slog.String("id", cr.ID),
+		slog.String("flag_id", cr.FlagID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+func (s *service) GetByID(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error) {
+	cr, err := s.repo.GetByID(ctx, id, flagID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("change request not found or forbidden",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get change request",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to get change request: %w", err)
+	}
+
+	return cr, nil
+}
+
+// List returns a flag's change requests, optionally filtered by status
+func (s *service) List(ctx context.Context, flagID string, tenantID string, status string) ([]ChangeRequest, error) {
+	switch status {
+	case "", StatusPending, StatusApproved, StatusRejected:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidChangeData, status)
+	}
+
+	if _, err := s.flags.GetByID(ctx, flagID, tenantID); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.repo.ListByFlag(ctx, flagID, tenantID, status)
+	if err != nil {
+		s.logger.Error("failed to list change requests",
+			slog.String("flag_id", flagID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list change requests: %w", err)
+	}
+
+	if requests == nil {
+		return []ChangeRequest{}, nil
+	}
+
+	return requests, nil
+}
+
+// Approve records a reviewer's approval of a pending change. Once the project's policy has
+// enough approvals, the change is applied to its flag and marked approved, in one transaction.
+// Reviewers need a role the policy allows, and can't approve their own proposals unless it allows that.
+func (s *service) Approve(ctx context.Context, id string, flagID string, tenantID string, reviewerID string, role string) (*ApprovalResult, error) {
+	var result *ApprovalResult
+
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		cr, err := s.lockPending(txCtx, id, flagID, tenantID)
+		if err != nil {
+			return err
+		}
+
+		f, err := s.flags.GetByID(txCtx, flagID, tenantID)
+		if err != nil {
+			return err
+		}
+
+		policy, err := s.policyFor(txCtx, f, tenantID)
+		if err != nil {
+			return err
+		}
+		if !policy.CanReview(role) {
+			return ErrInsufficientPermissions
+		}
+		if !policy.AllowSelfApproval && cr.ProposedBy != nil && *cr.ProposedBy == reviewerID {
+			return ErrSelfApproval
+		}
+
+		added, err := s.repo.AddApproval(txCtx, cr.ID, reviewerID, tenantID)
+		if err != nil {
+			return fmt.Errorf("record approval: %w", err)
+		}
+		if !added {
+			return ErrAlreadyApproved
+		}
+
+		approvals, err := s.repo.CountApprovals(txCtx, cr.ID, tenantID)
+		if err != nil {
+			return fmt.Errorf("count approvals: %w", err)
+		}
+
+		result = &ApprovalResult{Change: cr, Approvals: approvals, RequiredApprovals: policy.RequiredApprovals}
+		if approvals < policy.RequiredApprovals {
+			return nil
+		}
+
+		if err := s.apply(txCtx, cr, f, tenantID); err != nil {
+			return err
+		}
+
+		cr.Status = StatusApproved
+		cr.ReviewedBy = &reviewerID
+		if err := s.repo.SetStatus(txCtx, cr); err != nil {
+			return fmt.Errorf("update change request status: %w", err)
+		}
+
+		result.Flag = f
+		return nil
+	})
+	if err != nil {
+		s.logReviewError("approve", id, tenantID, err)
+		return nil, err
+	}
+
+	if result.Flag == nil {
+		s.logger.Info("change request approval recorded",
+			slog.String("id", id),
+			slog.String("flag_id", flagID),
+			slog.String("reviewed_by", reviewerID),
+			slog.Int("approvals", result.Approvals),
+			slog.Int("required_approvals", result.RequiredApprovals),
+			slog.String("tenant_id", tenantID),
+		)
+		return result, nil
+	}
+
+	s.logger.Info("change request approved",
+		slog.String("id", id),
+		slog.String("flag_id", flagID),
+		slog.String("reviewed_by", reviewerID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return result, nil
+}
+
+// Reject closes a pending change without applying it; any reviewer the project's policy allows may reject
+func (s *service) Reject(ctx context.Context, id string, flagID string, tenantID string, reviewerID string, role string) (*ChangeRequest, error) {
+	var rejected *ChangeRequest
+
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		cr, err := s.lockPending(txCtx, id, flagID, tenantID)
+		if err != nil {
+			return err
+		}
+
+		f, err := s.flags.GetByID(txCtx, flagID, tenantID)
+		if err != nil {
+			return err
+		}
+		policy, err := s.policyFor(txCtx, f, tenantID)
+		if err != nil {
+			return err
+		}
+		if !policy.CanReview(role) {
+			return ErrInsufficientPermissions
+		}
+
+		cr.Status = StatusRejected
+		cr.ReviewedBy = &reviewerID
+		if err := s.repo.SetStatus(txCtx, cr); err != nil {
+			return fmt.Errorf("update change request status: %w", err)
+		}
+
+		rejected = cr
+		return nil
+	})
+	if err != nil {
+		s.logReviewError("reject", id, tenantID, err)
+		return nil, err
+	}
+
+	s.logger.Info("change request rejected",
+		slog.String("id", id),
+		slog.String("flag_id", flagID),
+		slog.String("reviewed_by", reviewerID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return rejected, nil
+}
+
+// validateEnvironmentChange checks that a change to a flag's config in an environment names
+// one of its project's environments and only sets what environment configs have
+func (s *service) validateEnvironmentChange(ctx context.Context, cr *ChangeRequest, f *flag.Flag, tenantID string) error {
+	changes := cr.Changes
+	if changes.ProjectID != nil || changes.OwnerUserID != nil || changes.Name != nil || changes.Description != nil ||
+		changes.Lifecycle != nil || changes.ExpiresAt != nil || changes.ClearExpiresAt {
+		return fmt.Errorf("%w: changes to an environment may only set enabled, rules and rule_logic", ErrInvalidChangeData)
+	}
+	if changes.RuleLogic != nil && !flag.ValidRuleLogic(*changes.RuleLogic) {
+		return fmt.Errorf("%w: rule_logic must be AND, OR or FIRST_MATCH", ErrInvalidChangeData)
+	}
+
+	if f.ProjectID == nil || *f.ProjectID == "" {
+		return fmt.Errorf("%w: flags outside a project have no environments", ErrInvalidChangeData)
+	}
+	keys, err := s.repo.ListEnvironmentKeys(ctx, *f.ProjectID, tenantID)
+	if err != nil {
+		return fmt.Errorf("list environments: %w", err)
+	}
+	for _, key := range keys {
+		if key == *cr.Environment {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: environment %q is not in the flag's project", ErrInvalidChangeData, *cr.Environment)
+}
+
+// apply makes an approved change to its flag, or to the flag's config in its environment
+func (s *service) apply(ctx context.Context, cr *ChangeRequest, f *flag.Flag, tenantID string) error {
+	if cr.Environment != nil {
+		_, err := s.flags.ApplyApprovedEnvironmentChange(ctx, f.ID, *cr.Environment, cr.Changes, tenantID)
+		return err
+	}
+	cr.Changes.Apply(f)
+	return s.flags.ApplyApprovedChange(ctx, f, tenantID)
+}
+
+// environmentOf returns the key of the environment a change edits, or "" for the flag itself
+func environmentOf(cr *ChangeRequest) string {
+	if cr.Environment == nil {
+		return ""
+	}
+	return *cr.Environment
+}
+
+// lockPending loads and locks a change request, failing unless it is still pending
+func (s *service) lockPending(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error) {
+	cr, err := s.repo.GetForUpdate(ctx, id, flagID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("get change request: %w", err)
+	}
+
+	if cr.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+
+	return cr, nil
+}
+
+// logReviewError logs unexpected review failures; expected outcomes are logged at debug level
+func (s *service) logReviewError(action string, id string, tenantID string, err error) {
+	if pkgErrors.IsNotFoundError(err) || errors.Is(err, ErrNotPending) || errors.Is(err, ErrSelfApproval) ||
+		errors.Is(err, ErrAlreadyApproved) || errors.Is(err, ErrInsufficientPermissions) || errors.Is(err, flag.ErrInvalidFlagData) {
+		s.logger.Debug("change request review refused",
+			slog.String("action", action),
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("reason", err.Error()),
+		)
+		return
+	}
+
+	s.logger.Error("failed to review change request",
+		slog.String("action", action),
+		slog.String("id", id),
+		slog.String("tenant_id", tenantID),
+		slog.String("error", err.Error()),
+	)
+}
+
+// reviewerRoles are the tenant roles an approval policy can name
+var reviewerRoles = []string{"owner", "admin", "member"}
+
+// policyFor returns the approval policy governing a flag's changes
+func (s *service) policyFor(ctx context.Context, f *flag.Flag, tenantID string) (*ApprovalPolicy, error) {
+	if f.ProjectID == nil || *f.ProjectID == "" {
+		return DefaultApprovalPolicy(""), nil
+	}
+
+	policy, err := s.repo.GetPolicy(ctx, *f.ProjectID, tenantID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get approval policy: %w", err)
+	}
+	if policy == nil {
+		return DefaultApprovalPolicy(*f.ProjectID), nil
+	}
+	return policy, nil
+}
+
+// GetPolicy returns a project's approval policy, or the default if it has none
+func (s *service) GetPolicy(ctx context.Context, projectID string, tenantID string) (*ApprovalPolicy, error) {
+	policy, err := s.repo.GetPolicy(ctx, projectID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrProjectNotInTenant
+		}
+		s.logger.Error("failed to get approval policy",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to get approval policy: %w", err)
+	}
+	if policy == nil {
+		return DefaultApprovalPolicy(projectID), nil
+	}
+	return policy, nil
+}
+
+// UpdatePolicy applies a partial update to a project's approval policy; only owners and admins may change it
+func (s *service) UpdatePolicy(ctx context.Context, projectID string, tenantID string, role string, req UpdateApprovalPolicyRequest) (*ApprovalPolicy, error) {
+	if role != "owner" && role != "admin" {
+		return nil, ErrInsufficientPermissions
+	}
+
+	policy, err := s.GetPolicy(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RequireApproval != nil {
+		policy.RequireApproval = *req.RequireApproval
+	}
+	if req.RequiredApprovals != nil {
+		if *req.RequiredApprovals < 1 || *req.RequiredApprovals > MaxRequiredApprovals {
+			return nil, fmt.Errorf("%w: required_approvals must be between 1 and %d", ErrInvalidPolicy, MaxRequiredApprovals)
+		}
+		policy.RequiredApprovals = *req.RequiredApprovals
+	}
+	if req.AllowSelfApproval != nil {
+		policy.AllowSelfApproval = *req.AllowSelfApproval
+	}
+	if req.Environments != nil {
+		keys, err := s.repo.ListEnvironmentKeys(ctx, projectID, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list environments: %w", err)
+		}
+		known := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			known[key] = true
+		}
+		environments := make([]string, 0, len(req.Environments))
+		for _, e := range req.Environments {
+			if !known[e] {
+				return nil, fmt.Errorf("%w: unknown environment %q", ErrInvalidPolicy, e)
+			}
+			if !slices.Contains(environments, e) {
+				environments = append(environments, e)
+			}
+		}
+		policy.Environments = environments
+	}
+	if req.ReviewerRoles != nil {
+		if len(req.ReviewerRoles) == 0 {
+			return nil, fmt.Errorf("%w: reviewer_roles must name at least one role", ErrInvalidPolicy)
+		}
+		roles := make([]string, 0, len(req.ReviewerRoles))
+		for _, r := range req.ReviewerRoles {
+			if !slices.Contains(reviewerRoles, r) {
+				return nil, fmt.Errorf("%w: unknown reviewer role %q", ErrInvalidPolicy, r)
+			}
+			if !slices.Contains(roles, r) {
+				roles = append(roles, r)
+			}
+		}
+		policy.ReviewerRoles = roles
+	}
+
+	if err := s.repo.UpsertPolicy(ctx, policy, tenantID); err != nil {
+		s.logger.Error("failed to update approval policy",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to update approval policy: %w", err)
+	}
+
+	s.logger.Info("approval policy updated",
+		slog.String("project_id", projectID),
+		slog.Bool("require_approval", policy.RequireApproval),
+		slog.Any("environments", policy.Environments),
+		slog.Int("required_approvals", policy.RequiredApprovals),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return policy, nil
+}
+
+// RequiresApproval reports whether the project's policy requires edits to a flag's config in
+// the environment, or to the flag itself when environmentID is empty, to go through a change
+// request. Environments outside the project require nothing; writing to them fails anyway.
+func (s *service) RequiresApproval(ctx context.Context, projectID string, environmentID string, tenantID string) (bool, error) {
+	policy, err := s.GetPolicy(ctx, projectID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	if environmentID == "" {
+		return policy.RequireApproval, nil
+	}
+	if len(policy.Environments) == 0 {
+		return false, nil
+	}
+
+	keys, err := s.repo.ListEnvironmentKeys(ctx, projectID, tenantID)
+	if err != nil {
+		return false, fmt.Errorf("failed to list environments: %w", err)
+	}
+	key, ok := keys[environmentID]
+	return ok && policy.RequiresApprovalIn(key), nil
+}
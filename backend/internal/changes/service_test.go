@@ -0,0 +1,469 @@
+package changes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type mockRepository struct {
+	requests     map[string]*ChangeRequest
+	statuses     []string
+	policies     map[string]*ApprovalPolicy
+	approvals    map[string][]string
+	environments map[string]string // environment ID -> key, in every project
+}
+
+func (m *mockRepository) Create(ctx context.Context, cr *ChangeRequest) error {
+	cr.ID = "test-generated-id"
+	return nil
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error) {
+	cr, ok := m.requests[id]
+	if !ok || cr.FlagID != flagID || cr.TenantID != tenantID {
+		return nil, sql.ErrNoRows
+	}
+	copied := *cr
+	return &copied, nil
+}
+
+func (m *mockRepository) GetForUpdate(ctx context.Context, id string, flagID string, tenantID string) (*ChangeRequest, error) {
+	return m.GetByID(ctx, id, flagID, tenantID)
+}
+
+func (m *mockRepository) ListByFlag(ctx context.Context, flagID string, tenantID string, status string) ([]ChangeRequest, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) SetStatus(ctx context.Context, cr *ChangeRequest) error {
+	m.statuses = append(m.statuses, cr.Status)
+	return nil
+}
+
+func (m *mockRepository) GetPolicy(ctx context.Context, projectID string, tenantID string) (*ApprovalPolicy, error) {
+	if projectID == "missing-project" {
+		return nil, sql.ErrNoRows
+	}
+	return m.policies[projectID], nil
+}
+
+func (m *mockRepository) UpsertPolicy(ctx context.Context, p *ApprovalPolicy, tenantID string) error {
+	if m.policies == nil {
+		m.policies = make(map[string]*ApprovalPolicy)
+	}
+	m.policies[p.ProjectID] = p
+	return nil
+}
+
+func (m *mockRepository) ListEnvironmentKeys(ctx context.Context, projectID string, tenantID string) (map[string]string, error) {
+	return m.environments, nil
+}
+
+func (m *mockRepository) AddApproval(ctx context.Context, changeID string, userID string, tenantID string) (bool, error) {
+	if m.approvals == nil {
+		m.approvals = make(map[string][]string)
+	}
+	if slices.Contains(m.approvals[changeID], userID) {
+		return false, nil
+	}
+	m.approvals[changeID] = append(m.approvals[changeID], userID)
+	return true, nil
+}
+
+func (m *mockRepository) CountApprovals(ctx context.Context, changeID string, tenantID string) (int, error) {
+	return len(m.approvals[changeID]), nil
+}
+
+// mockFlagService implements flag.Service; only GetByID and the approved change methods are used by change requests
+type mockFlagService struct {
+	flag.Service
+	flag       *flag.Flag
+	updateErr  error
+	updateCall *flag.Flag
+	// environmentCall is the environment key of the last approved environment change
+	environmentCall string
+}
+
+func (m *mockFlagService) GetByID(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+	if m.flag == nil || m.flag.ID != id || m.flag.TenantID != tenantID {
+		return nil, pkgErrors.ErrNotFound
+	}
+	copied := *m.flag
+	return &copied, nil
+}
+
+func (m *mockFlagService) ApplyApprovedChange(ctx context.Context, f *flag.Flag, tenantID string) error {
+	m.updateCall = f
+	return m.updateErr
+}
+
+func (m *mockFlagService) ApplyApprovedEnvironmentChange(ctx context.Context, id string, environment string, changes flag.UpdateRequest, tenantID string) (*flag.EnvironmentConfig, error) {
+	m.environmentCall = environment
+	return &flag.EnvironmentConfig{FlagID: id, Enabled: changes.Enabled != nil && *changes.Enabled}, m.updateErr
+}
+
+// mockUnitOfWork runs the function directly; rollback isn't observable in unit tests
+type mockUnitOfWork struct{}
+
+func (mockUnitOfWork) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func stringPtr(s string) *string { return &s }
+
+func newTestService(repo *mockRepository, flags *mockFlagService) Service {
+	return NewService(repo, flags, mockUnitOfWork{}, slog.Default())
+}
+
+func pendingRequest() *ChangeRequest {
+	return &ChangeRequest{
+		ID:         "change-1",
+		TenantID:   "tenant-1",
+		FlagID:     "flag-1",
+		ProposedBy: stringPtr("member-1"),
+		Changes:    flag.UpdateRequest{Enabled: boolPtr(true)},
+		Status:     StatusPending,
+	}
+}
+
+// requiringApproval stores a policy requiring one approval for project-1's flags
+func requiringApproval() *mockRepository {
+	return &mockRepository{
+		policies: map[string]*ApprovalPolicy{"project-1": {ProjectID: "project-1", RequireApproval: true, RequiredApprovals: 1, ReviewerRoles: []string{"owner", "admin"}}},
+	}
+}
+
+func TestServicePropose(t *testing.T) {
+	flags := &mockFlagService{flag: &flag.Flag{ID: "flag-1", TenantID: "tenant-1", ProjectID: stringPtr("project-1")}}
+
+	t.Run("records a pending change without touching the flag", func(t *testing.T) {
+		svc := newTestService(requiringApproval(), flags)
+
+		cr := &ChangeRequest{FlagID: "flag-1", Changes: flag.UpdateRequest{Enabled: boolPtr(true)}, Comment: "<b>ship it</b>"}
+		if err := svc.Propose(context.Background(), cr, "tenant-1"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if cr.Status != StatusPending {
+			t.Errorf("expected status pending, got %s", cr.Status)
+		}
+		if cr.Comment != "ship it" {
+			t.Errorf("expected sanitized comment, got %q", cr.Comment)
+		}
+		if flags.updateCall != nil {
+			t.Error("expected flag to be left unchanged")
+		}
+	})
+
+	t.Run("empty change is rejected", func(t *testing.T) {
+		svc := newTestService(&mockRepository{}, flags)
+
+		err := svc.Propose(context.Background(), &ChangeRequest{FlagID: "flag-1"}, "tenant-1")
+		if !errors.Is(err, ErrInvalidChangeData) {
+			t.Errorf("expected ErrInvalidChangeData, got %v", err)
+		}
+	})
+
+	t.Run("flag in another tenant is not found", func(t *testing.T) {
+		svc := newTestService(&mockRepository{}, flags)
+
+		cr := &ChangeRequest{FlagID: "flag-1", Changes: flag.UpdateRequest{Enabled: boolPtr(true)}}
+		if err := svc.Propose(context.Background(), cr, "tenant-2"); !pkgErrors.IsNotFoundError(err) {
+			t.Errorf("expected not found, got %v", err)
+		}
+	})
+}
+
+func TestServiceApprove(t *testing.T) {
+	tests := []struct {
+		name       string
+		reviewer   string
+		role       string
+		status     string
+		updateErr  error
+		wantErr    error
+		wantUpdate bool
+	}{
+		{name: "admin approves and change is applied", reviewer: "admin-1", role: "admin", status: StatusPending, wantUpdate: true},
+		{name: "owner approves", reviewer: "owner-1", role: "owner", status: StatusPending, wantUpdate: true},
+		{name: "member cannot approve", reviewer: "member-2", role: "member", status: StatusPending, wantErr: ErrInsufficientPermissions},
+		{name: "author cannot approve own change", reviewer: "member-1", role: "admin", status: StatusPending, wantErr: ErrSelfApproval},
+		{name: "already reviewed", reviewer: "admin-1", role: "admin", status: StatusRejected, wantErr: ErrNotPending},
+		{name: "change no longer valid", reviewer: "admin-1", role: "admin", status: StatusPending, updateErr: flag.ErrInvalidFlagData, wantErr: flag.ErrInvalidFlagData, wantUpdate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := pendingRequest()
+			cr.Status = tt.status
+			repo := &mockRepository{requests: map[string]*ChangeRequest{cr.ID: cr}}
+			flags := &mockFlagService{flag: &flag.Flag{ID: "flag-1", TenantID: "tenant-1"}, updateErr: tt.updateErr}
+			svc := newTestService(repo, flags)
+
+			result, err := svc.Approve(context.Background(), "change-1", "flag-1", "tenant-1", tt.reviewer, tt.role)
+
+			if (flags.updateCall != nil) != tt.wantUpdate {
+				t.Errorf("expected flag update=%v, got %v", tt.wantUpdate, flags.updateCall != nil)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				if len(repo.statuses) != 0 {
+					t.Errorf("expected status to be unchanged, got %v", repo.statuses)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !result.Flag.Enabled {
+				t.Error("expected change to enable the flag")
+			}
+			if result.Change.Status != StatusApproved || *result.Change.ReviewedBy != tt.reviewer {
+				t.Errorf("expected approved by %s, got %+v", tt.reviewer, result.Change)
+			}
+		})
+	}
+}
+
+func TestServiceReject(t *testing.T) {
+	cr := pendingRequest()
+	repo := &mockRepository{requests: map[string]*ChangeRequest{cr.ID: cr}}
+	flags := &mockFlagService{flag: &flag.Flag{ID: "flag-1", TenantID: "tenant-1"}}
+	svc := newTestService(repo, flags)
+
+	if _, err := svc.Reject(context.Background(), "change-1", "flag-1", "tenant-1", "member-2", "member"); !errors.Is(err, ErrInsufficientPermissions) {
+		t.Errorf("expected ErrInsufficientPermissions, got %v", err)
+	}
+
+	rejected, err := svc.Reject(context.Background(), "change-1", "flag-1", "tenant-1", "owner-1", "owner")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rejected.Status != StatusRejected {
+		t.Errorf("expected status rejected, got %s", rejected.Status)
+	}
+	if flags.updateCall != nil {
+		t.Error("expected rejected change not to be applied")
+	}
+
+	if _, err := svc.Reject(context.Background(), "change-1", "flag-2", "tenant-1", "owner-1", "owner"); !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected change on another flag to be not found, got %v", err)
+	}
+}
+
+func TestServiceApprove_ProjectPolicy(t *testing.T) {
+	projectFlag := func() *mockFlagService {
+		return &mockFlagService{flag: &flag.Flag{ID: "flag-1", TenantID: "tenant-1", ProjectID: stringPtr("project-1")}}
+	}
+
+	t.Run("waits for the required number of approvals", func(t *testing.T) {
+		cr := pendingRequest()
+		repo := &mockRepository{
+			requests: map[string]*ChangeRequest{cr.ID: cr},
+			policies: map[string]*ApprovalPolicy{"project-1": {ProjectID: "project-1", RequireApproval: true, RequiredApprovals: 2, ReviewerRoles: []string{"owner", "admin"}}},
+		}
+		flags := projectFlag()
+		svc := newTestService(repo, flags)
+
+		result, err := svc.Approve(context.Background(), "change-1", "flag-1", "tenant-1", "admin-1", "admin")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Flag != nil || result.Approvals != 1 || result.RequiredApprovals != 2 || flags.updateCall != nil {
+			t.Fatalf("expected the change to wait for a second approval, got %+v", result)
+		}
+
+		if _, err := svc.Approve(context.Background(), "change-1", "flag-1", "tenant-1", "admin-1", "admin"); !errors.Is(err, ErrAlreadyApproved) {
+			t.Errorf("expected ErrAlreadyApproved, got %v", err)
+		}
+
+		result, err = svc.Approve(context.Background(), "change-1", "flag-1", "tenant-1", "owner-1", "owner")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Flag == nil || result.Change.Status != StatusApproved || flags.updateCall == nil {
+			t.Errorf("expected the second approval to apply the change, got %+v", result)
+		}
+	})
+
+	t.Run("members as reviewers with self-approval", func(t *testing.T) {
+		cr := pendingRequest()
+		repo := &mockRepository{
+			requests: map[string]*ChangeRequest{cr.ID: cr},
+			policies: map[string]*ApprovalPolicy{"project-1": {ProjectID: "project-1", RequireApproval: true, RequiredApprovals: 1, AllowSelfApproval: true, ReviewerRoles: []string{"member"}}},
+		}
+		svc := newTestService(repo, projectFlag())
+
+		if _, err := svc.Approve(context.Background(), "change-1", "flag-1", "tenant-1", "admin-1", "admin"); !errors.Is(err, ErrInsufficientPermissions) {
+			t.Errorf("expected roles outside the policy to be refused, got %v", err)
+		}
+		if _, err := svc.Approve(context.Background(), "change-1", "flag-1", "tenant-1", "member-1", "member"); err != nil {
+			t.Errorf("expected the author to approve their own change, got %v", err)
+		}
+	})
+}
+
+func TestServicePropose_WithoutRequiredApproval(t *testing.T) {
+	repo := &mockRepository{
+		policies: map[string]*ApprovalPolicy{"project-1": {ProjectID: "project-1", RequireApproval: false, RequiredApprovals: 1, ReviewerRoles: []string{"owner"}}},
+	}
+	flags := &mockFlagService{flag: &flag.Flag{ID: "flag-1", TenantID: "tenant-1", ProjectID: stringPtr("project-1")}}
+	svc := newTestService(repo, flags)
+
+	cr := &ChangeRequest{FlagID: "flag-1", ProposedBy: stringPtr("member-1"), Changes: flag.UpdateRequest{Enabled: boolPtr(true)}}
+	if err := svc.Propose(context.Background(), cr, "tenant-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cr.Status != StatusApproved || cr.ReviewedBy == nil || *cr.ReviewedBy != "member-1" {
+		t.Errorf("expected the change to be recorded as approved by its author, got %+v", cr)
+	}
+	if flags.updateCall == nil || !flags.updateCall.Enabled {
+		t.Error("expected the change to be applied at once")
+	}
+}
+
+func TestServiceUpdatePolicy(t *testing.T) {
+	svc := newTestService(&mockRepository{}, &mockFlagService{})
+	ctx := context.Background()
+
+	if _, err := svc.UpdatePolicy(ctx, "project-1", "tenant-1", "member", UpdateApprovalPolicyRequest{}); !errors.Is(err, ErrInsufficientPermissions) {
+		t.Errorf("expected ErrInsufficientPermissions, got %v", err)
+	}
+	if _, err := svc.UpdatePolicy(ctx, "missing-project", "tenant-1", "owner", UpdateApprovalPolicyRequest{}); !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	invalid := []UpdateApprovalPolicyRequest{
+		{RequiredApprovals: intPtr(0)},
+		{RequiredApprovals: intPtr(MaxRequiredApprovals + 1)},
+		{ReviewerRoles: []string{}},
+		{ReviewerRoles: []string{"viewer"}},
+	}
+	for _, req := range invalid {
+		if _, err := svc.UpdatePolicy(ctx, "project-1", "tenant-1", "owner", req); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("expected ErrInvalidPolicy for %+v, got %v", req, err)
+		}
+	}
+
+	policy, err := svc.UpdatePolicy(ctx, "project-1", "tenant-1", "admin", UpdateApprovalPolicyRequest{
+		RequiredApprovals: intPtr(2),
+		ReviewerRoles:     []string{"admin", "member", "admin"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if policy.RequireApproval || policy.RequiredApprovals != 2 || !slices.Equal(policy.ReviewerRoles, []string{"admin", "member"}) {
+		t.Errorf("expected defaults updated with deduplicated roles, got %+v", policy)
+	}
+}
+
+func TestServiceRequiresApproval(t *testing.T) {
+	svc := newTestService(requiringApproval(), &mockFlagService{})
+	ctx := context.Background()
+
+	if required, err := svc.RequiresApproval(ctx, "project-1", "", "tenant-1"); err != nil || !required {
+		t.Errorf("expected the stored policy to require approval, got %v, %v", required, err)
+	}
+	if required, err := svc.RequiresApproval(ctx, "project-2", "", "tenant-1"); err != nil || required {
+		t.Errorf("expected projects without a policy to allow direct edits, got %v, %v", required, err)
+	}
+	if _, err := svc.RequiresApproval(ctx, "missing-project", "", "tenant-1"); !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestServiceUpdatePolicy_Environments(t *testing.T) {
+	repo := &mockRepository{environments: map[string]string{"env-1": "staging", "env-2": "production"}}
+	svc := newTestService(repo, &mockFlagService{})
+	ctx := context.Background()
+
+	if _, err := svc.UpdatePolicy(ctx, "project-1", "tenant-1", "owner", UpdateApprovalPolicyRequest{Environments: []string{"qa"}}); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("expected ErrInvalidPolicy for an unknown environment, got %v", err)
+	}
+
+	policy, err := svc.UpdatePolicy(ctx, "project-1", "tenant-1", "owner", UpdateApprovalPolicyRequest{Environments: []string{"production", "production"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.Equal(policy.Environments, []string{"production"}) {
+		t.Errorf("expected deduplicated environments, got %v", policy.Environments)
+	}
+
+	tests := []struct {
+		environmentID string
+		want          bool
+	}{
+		{environmentID: "", want: false},
+		{environmentID: "env-1", want: false},
+		{environmentID: "env-2", want: true},
+		{environmentID: "env-other-project", want: false},
+	}
+	for _, tt := range tests {
+		required, err := svc.RequiresApproval(ctx, "project-1", tt.environmentID, "tenant-1")
+		if err != nil || required != tt.want {
+			t.Errorf("RequiresApproval(%q) = %v, %v; want %v", tt.environmentID, required, err, tt.want)
+		}
+	}
+}
+
+func TestServicePropose_EnvironmentChange(t *testing.T) {
+	newService := func() (Service, *mockRepository, *mockFlagService) {
+		repo := &mockRepository{
+			policies:     map[string]*ApprovalPolicy{"project-1": {ProjectID: "project-1", Environments: []string{"production"}, RequiredApprovals: 1, ReviewerRoles: []string{"owner", "admin"}}},
+			environments: map[string]string{"env-1": "staging", "env-2": "production"},
+		}
+		flags := &mockFlagService{flag: &flag.Flag{ID: "flag-1", TenantID: "tenant-1", ProjectID: stringPtr("project-1")}}
+		return newTestService(repo, flags), repo, flags
+	}
+	ctx := context.Background()
+
+	svc, _, flags := newService()
+	cr := &ChangeRequest{FlagID: "flag-1", ProposedBy: stringPtr("member-1"), Environment: stringPtr("staging"), Changes: flag.UpdateRequest{Enabled: boolPtr(true)}}
+	if err := svc.Propose(ctx, cr, "tenant-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cr.Status != StatusApproved || flags.environmentCall != "staging" || flags.updateCall != nil {
+		t.Errorf("expected a change to an environment without approval to be applied there at once, got %+v", cr)
+	}
+
+	svc, repo, flags := newService()
+	cr = &ChangeRequest{FlagID: "flag-1", ProposedBy: stringPtr("member-1"), Environment: stringPtr("production"), Changes: flag.UpdateRequest{Enabled: boolPtr(true)}}
+	if err := svc.Propose(ctx, cr, "tenant-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cr.Status != StatusPending || flags.environmentCall != "" {
+		t.Fatalf("expected a production change to wait for approval, got %+v", cr)
+	}
+	cr.ID = "change-1"
+	repo.requests = map[string]*ChangeRequest{cr.ID: cr}
+	result, err := svc.Approve(ctx, "change-1", "flag-1", "tenant-1", "admin-1", "admin")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Change.Status != StatusApproved || flags.environmentCall != "production" || flags.updateCall != nil {
+		t.Errorf("expected the approval to apply the change to production only, got %+v", result)
+	}
+
+	invalid := []*ChangeRequest{
+		{FlagID: "flag-1", Environment: stringPtr("qa"), Changes: flag.UpdateRequest{Enabled: boolPtr(true)}},
+		{FlagID: "flag-1", Environment: stringPtr("production"), Changes: flag.UpdateRequest{Name: stringPtr("renamed")}},
+		{FlagID: "flag-1", Environment: stringPtr("production"), Changes: flag.UpdateRequest{RuleLogic: stringPtr("XOR")}},
+	}
+	for _, cr := range invalid {
+		if err := svc.Propose(ctx, cr, "tenant-1"); !errors.Is(err, ErrInvalidChangeData) {
+			t.Errorf("expected ErrInvalidChangeData for %+v, got %v", cr.Changes, err)
+		}
+	}
+}
+
+func intPtr(i int) *int { return &i }
@@ -0,0 +1,84 @@
+package changesets
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/change-sets", h.Apply)
+	r.GET("/change-sets/:id", h.Get)
+}
+
+// Apply modifies several flags of one project atomically
+func (h *handler) Apply(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+
+	cs := &ChangeSet{
+		ProjectID:   req.ProjectID,
+		Description: req.Description,
+		CreatedBy:   &userID,
+		Changes:     req.Changes,
+	}
+
+	result, err := h.service.Apply(c.Request.Context(), cs, tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to apply change set")
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	cs, err := h.service.GetByID(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get change set")
+		return
+	}
+
+	c.JSON(http.StatusOK, cs)
+}
+
+func (h *handler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidChangeSetData), errors.Is(err, flag.ErrInvalidFlagData),
+		errors.Is(err, pkgErrors.ErrUserNotInTenant):
+		c.JSON(http.StatusBadRequest, flag.InvalidDataResponse(err))
+	case errors.Is(err, flag.ErrDuplicateName):
+		c.JSON(http.StatusConflict, flag.ConflictResponse(err))
+	case errors.Is(err, flag.ErrApprovalRequired):
+		c.JSON(http.StatusConflict, flag.ApprovalRequiredResponse(err))
+	case pkgErrors.IsNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
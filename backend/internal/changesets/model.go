@@ -0,0 +1,41 @@
+package changesets
+
+import (
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// MaxFlagsPerChangeSet bounds how many flags one change set may modify
+const MaxFlagsPerChangeSet = 100
+
+// ChangeSet is a group of flag edits in one project applied atomically.
+// The stored change set is the audit entry for all of its edits.
+type ChangeSet struct {
+	ID          string       `json:"id" db:"id"`
+	TenantID    string       `json:"tenant_id" db:"tenant_id"`
+	ProjectID   string       `json:"project_id" db:"project_id"`
+	Description string       `json:"description" db:"description"`
+	CreatedBy   *string      `json:"created_by" db:"created_by"`
+	Changes     []FlagChange `json:"changes" db:"changes"`
+	Generation  int64        `json:"generation" db:"generation"` // project generation after the change set
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+}
+
+// FlagChange is the edit applied to one flag of a change set
+type FlagChange struct {
+	FlagID  string             `json:"flag_id" binding:"required"`
+	Changes flag.UpdateRequest `json:"changes"`
+}
+
+// ApplyResult is the recorded change set together with the updated flags, in request order
+type ApplyResult struct {
+	ChangeSet *ChangeSet  `json:"change_set"`
+	Flags     []flag.Flag `json:"flags"`
+}
+
+type CreateRequest struct {
+	ProjectID   string       `json:"project_id" binding:"required"`
+	Description string       `json:"description"`
+	Changes     []FlagChange `json:"changes" binding:"required,dive"`
+}
@@ -0,0 +1,88 @@
+package changesets
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	Create(ctx context.Context, cs *ChangeSet) error
+	GetByID(ctx context.Context, id string, tenantID string) (*ChangeSet, error)
+	DeferGenerationBump(ctx context.Context) error
+	BumpGeneration(ctx context.Context, projectID string, tenantID string) (int64, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *postgresRepository) Create(ctx context.Context, cs *ChangeSet) error {
+	changesJSON, err := json.Marshal(cs.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO change_sets (tenant_id, project_id, description, created_by, changes, generation)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, cs.TenantID, cs.ProjectID, cs.Description, cs.CreatedBy, changesJSON, cs.Generation).
+		Scan(&cs.ID, &cs.CreatedAt)
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id string, tenantID string) (*ChangeSet, error) {
+	query := `
+		SELECT id, tenant_id, project_id, description, created_by, changes, generation, created_at
+		FROM change_sets
+		WHERE id = $1 AND tenant_id = $2
+	`
+
+	var cs ChangeSet
+	var changesJSON []byte
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, id, tenantID).
+		Scan(&cs.ID, &cs.TenantID, &cs.ProjectID, &cs.Description, &cs.CreatedBy, &changesJSON, &cs.Generation, &cs.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(changesJSON, &cs.Changes); err != nil {
+		return nil, err
+	}
+
+	return &cs, nil
+}
+
+// DeferGenerationBump stops the flags trigger from bumping project generations for the rest
+// of the surrounding transaction; the caller bumps once with BumpGeneration instead
+func (r *postgresRepository) DeferGenerationBump(ctx context.Context) error {
+	_, err := r.getDB(ctx).ExecContext(ctx, `SELECT set_config('toggle.defer_generation_bump', 'on', true)`)
+	return err
+}
+
+// BumpGeneration increments a project's generation and returns the new value
+func (r *postgresRepository) BumpGeneration(ctx context.Context, projectID string, tenantID string) (int64, error) {
+	var generation int64
+	err := r.getDB(ctx).QueryRowxContext(ctx, `
+		UPDATE projects SET generation = generation + 1
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING generation
+	`, projectID, tenantID).Scan(&generation)
+	return generation, err
+}
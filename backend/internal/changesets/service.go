@@ -0,0 +1,171 @@
+package changesets
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+var ErrInvalidChangeSetData = errors.New("invalid change set")
+
+type Service interface {
+	Apply(ctx context.Context, cs *ChangeSet, tenantID string) (*ApplyResult, error)
+	GetByID(ctx context.Context, id string, tenantID string) (*ChangeSet, error)
+}
+
+type service struct {
+	repo   Repository
+	flags  flag.Service
+	uow    transaction.UnitOfWork
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, flags flag.Service, uow transaction.UnitOfWork, logger *slog.Logger) Service {
+	return &service{
+		repo:   repo,
+		flags:  flags,
+		uow:    uow,
+		logger: logger,
+	}
+}
+
+// Apply updates every flag of a change set in one transaction: either all edits are
+// applied or none are. The project generation is bumped once for the whole set, so
+// SDK relays never observe a partially applied change set. Like any direct edit, a change
+// set touching flags whose project requires approval is refused.
+func (s *service) Apply(ctx context.Context, cs *ChangeSet, tenantID string) (*ApplyResult, error) {
+	if err := validateChangeSet(cs); err != nil {
+		return nil, err
+	}
+	cs.TenantID = tenantID
+
+	var updated []flag.Flag
+
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.DeferGenerationBump(txCtx); err != nil {
+			return fmt.Errorf("defer generation bump: %w", err)
+		}
+
+		updated = make([]flag.Flag, 0, len(cs.Changes))
+		for i, change := range cs.Changes {
+			f, err := s.flags.GetByID(txCtx, change.FlagID, tenantID)
+			if err != nil {
+				return fmt.Errorf("changes[%d]: %w", i, err)
+			}
+			if f.ProjectID == nil || *f.ProjectID != cs.ProjectID {
+				return fmt.Errorf("%w: changes[%d]: flag %s is not in project %s", ErrInvalidChangeSetData, i, change.FlagID, cs.ProjectID)
+			}
+
+			change.Changes.Apply(f)
+			if err := s.flags.Update(txCtx, f, tenantID); err != nil {
+				return fmt.Errorf("changes[%d]: %w", i, err)
+			}
+			updated = append(updated, *f)
+		}
+
+		generation, err := s.repo.BumpGeneration(txCtx, cs.ProjectID, tenantID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return pkgErrors.ErrNotFound
+			}
+			return fmt.Errorf("bump project generation: %w", err)
+		}
+		cs.Generation = generation
+
+		if err := s.repo.Create(txCtx, cs); err != nil {
+			return fmt.Errorf("record change set: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) || errors.Is(err, ErrInvalidChangeSetData) || errors.Is(err, flag.ErrInvalidFlagData) ||
+			errors.Is(err, flag.ErrApprovalRequired) {
+			s.logger.Debug("change set refused",
+				slog.String("project_id", cs.ProjectID),
+				slog.String("tenant_id", tenantID),
+				slog.String("reason", err.Error()),
+			)
+		} else {
+			s.logger.Error("failed to apply change set",
+				slog.String("project_id", cs.ProjectID),
+				slog.String("tenant_id", tenantID),
+				slog.String("error", err.Error()),
+			)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("change set applied",
+		slog.String("id", cs.ID),
+		slog.String("project_id", cs.ProjectID),
+		slog.Int("flags", len(cs.Changes)),
+		slog.Int64("generation", cs.Generation),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return &ApplyResult{ChangeSet: cs, Flags: updated}, nil
+}
+
+func (s *service) GetByID(ctx context.Context, id string, tenantID string) (*ChangeSet, error) {
+	cs, err := s.repo.GetByID(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get change set",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to get change set: %w", err)
+	}
+
+	return cs, nil
+}
+
+// validateChangeSet checks the shape of a change set before any flag is loaded
+func validateChangeSet(cs *ChangeSet) error {
+	if cs == nil || cs.ProjectID == "" {
+		return fmt.Errorf("%w: project_id is required", ErrInvalidChangeSetData)
+	}
+	if len(cs.Changes) == 0 {
+		return fmt.Errorf("%w: at least one change is required", ErrInvalidChangeSetData)
+	}
+	if len(cs.Changes) > MaxFlagsPerChangeSet {
+		return fmt.Errorf("%w: at most %d flags may change at once", ErrInvalidChangeSetData, MaxFlagsPerChangeSet)
+	}
+
+	description, err := sanitize.Text(cs.Description, sanitize.MaxDescriptionLength)
+	if err != nil {
+		return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidChangeSetData, sanitize.MaxDescriptionLength)
+	}
+	cs.Description = description
+
+	seen := make(map[string]struct{}, len(cs.Changes))
+	for i, change := range cs.Changes {
+		if _, ok := seen[change.FlagID]; ok {
+			return fmt.Errorf("%w: changes[%d]: flag %s appears more than once", ErrInvalidChangeSetData, i, change.FlagID)
+		}
+		seen[change.FlagID] = struct{}{}
+
+		if change.Changes.IsEmpty() {
+			return fmt.Errorf("%w: changes[%d]: must set at least one field", ErrInvalidChangeSetData, i)
+		}
+		// Moving a flag out of the project would split the set across projects
+		if change.Changes.ProjectID != nil && *change.Changes.ProjectID != cs.ProjectID {
+			return fmt.Errorf("%w: changes[%d]: flags cannot move projects in a change set", ErrInvalidChangeSetData, i)
+		}
+		if err := change.Changes.Validate(); err != nil {
+			return fmt.Errorf("changes[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
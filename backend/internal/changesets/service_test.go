@@ -0,0 +1,197 @@
+package changesets
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type mockRepository struct {
+	created  *ChangeSet
+	deferred bool
+	bumps    int
+}
+
+func (m *mockRepository) Create(ctx context.Context, cs *ChangeSet) error {
+	cs.ID = "test-generated-id"
+	m.created = cs
+	return nil
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string, tenantID string) (*ChangeSet, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) DeferGenerationBump(ctx context.Context) error {
+	m.deferred = true
+	return nil
+}
+
+func (m *mockRepository) BumpGeneration(ctx context.Context, projectID string, tenantID string) (int64, error) {
+	m.bumps++
+	return int64(10 + m.bumps), nil
+}
+
+// mockFlagService implements flag.Service; only GetByID and Update are used by change sets
+type mockFlagService struct {
+	flag.Service
+	flags     map[string]*flag.Flag
+	updateErr map[string]error
+	updated   []string
+}
+
+func (m *mockFlagService) GetByID(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+	f, ok := m.flags[id]
+	if !ok || f.TenantID != tenantID {
+		return nil, pkgErrors.ErrNotFound
+	}
+	copied := *f
+	return &copied, nil
+}
+
+func (m *mockFlagService) Update(ctx context.Context, f *flag.Flag, tenantID string) error {
+	if err := m.updateErr[f.ID]; err != nil {
+		return err
+	}
+	m.updated = append(m.updated, f.ID)
+	return nil
+}
+
+// mockUnitOfWork runs the function directly; rollback isn't observable in unit tests
+type mockUnitOfWork struct{}
+
+func (mockUnitOfWork) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func stringPtr(s string) *string { return &s }
+
+func newFlags() *mockFlagService {
+	return &mockFlagService{flags: map[string]*flag.Flag{
+		"flag-1": {ID: "flag-1", TenantID: "tenant-1", ProjectID: stringPtr("project-1"), Name: "checkout"},
+		"flag-2": {ID: "flag-2", TenantID: "tenant-1", ProjectID: stringPtr("project-1"), Name: "payments"},
+		"flag-3": {ID: "flag-3", TenantID: "tenant-1", ProjectID: stringPtr("project-2"), Name: "search"},
+	}}
+}
+
+func enable(flagID string) FlagChange {
+	return FlagChange{FlagID: flagID, Changes: flag.UpdateRequest{Enabled: boolPtr(true)}}
+}
+
+func TestServiceApply(t *testing.T) {
+	t.Run("updates every flag and bumps the generation once", func(t *testing.T) {
+		repo := &mockRepository{}
+		flags := newFlags()
+		svc := NewService(repo, flags, mockUnitOfWork{}, slog.Default())
+
+		cs := &ChangeSet{ProjectID: "project-1", Changes: []FlagChange{enable("flag-1"), enable("flag-2")}}
+		result, err := svc.Apply(context.Background(), cs, "tenant-1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !repo.deferred {
+			t.Error("expected per-flag generation bumps to be deferred")
+		}
+		if repo.bumps != 1 {
+			t.Errorf("expected one generation bump, got %d", repo.bumps)
+		}
+		if repo.created == nil || repo.created.Generation != 11 || repo.created.TenantID != "tenant-1" {
+			t.Errorf("expected change set recorded at generation 11, got %+v", repo.created)
+		}
+		if len(result.Flags) != 2 || !result.Flags[0].Enabled || !result.Flags[1].Enabled {
+			t.Errorf("expected both flags enabled, got %+v", result.Flags)
+		}
+	})
+
+	t.Run("a failing flag aborts the whole set", func(t *testing.T) {
+		repo := &mockRepository{}
+		flags := newFlags()
+		flags.updateErr = map[string]error{"flag-2": flag.ErrInvalidFlagData}
+		svc := NewService(repo, flags, mockUnitOfWork{}, slog.Default())
+
+		cs := &ChangeSet{ProjectID: "project-1", Changes: []FlagChange{enable("flag-1"), enable("flag-2")}}
+		_, err := svc.Apply(context.Background(), cs, "tenant-1")
+		if !errors.Is(err, flag.ErrInvalidFlagData) {
+			t.Fatalf("expected ErrInvalidFlagData, got %v", err)
+		}
+		if repo.created != nil || repo.bumps != 0 {
+			t.Error("expected no change set or generation bump after a failure")
+		}
+	})
+
+	tests := []struct {
+		name    string
+		cs      *ChangeSet
+		tenant  string
+		wantErr error
+	}{
+		{
+			name:    "flag from another project",
+			cs:      &ChangeSet{ProjectID: "project-1", Changes: []FlagChange{enable("flag-1"), enable("flag-3")}},
+			tenant:  "tenant-1",
+			wantErr: ErrInvalidChangeSetData,
+		},
+		{
+			name:    "flag from another tenant",
+			cs:      &ChangeSet{ProjectID: "project-1", Changes: []FlagChange{enable("flag-1")}},
+			tenant:  "tenant-2",
+			wantErr: pkgErrors.ErrNotFound,
+		},
+		{
+			name:    "no changes",
+			cs:      &ChangeSet{ProjectID: "project-1"},
+			tenant:  "tenant-1",
+			wantErr: ErrInvalidChangeSetData,
+		},
+		{
+			name:    "duplicate flag",
+			cs:      &ChangeSet{ProjectID: "project-1", Changes: []FlagChange{enable("flag-1"), enable("flag-1")}},
+			tenant:  "tenant-1",
+			wantErr: ErrInvalidChangeSetData,
+		},
+		{
+			name:    "empty flag change",
+			cs:      &ChangeSet{ProjectID: "project-1", Changes: []FlagChange{{FlagID: "flag-1"}}},
+			tenant:  "tenant-1",
+			wantErr: ErrInvalidChangeSetData,
+		},
+		{
+			name: "moving a flag to another project",
+			cs: &ChangeSet{ProjectID: "project-1", Changes: []FlagChange{
+				{FlagID: "flag-1", Changes: flag.UpdateRequest{ProjectID: stringPtr("project-2")}},
+			}},
+			tenant:  "tenant-1",
+			wantErr: ErrInvalidChangeSetData,
+		},
+		{
+			name: "invalid rules",
+			cs: &ChangeSet{ProjectID: "project-1", Changes: []FlagChange{
+				{FlagID: "flag-1", Changes: flag.UpdateRequest{Rules: []flag.Rule{{Attribute: "country", Operator: "contains"}}}},
+			}},
+			tenant:  "tenant-1",
+			wantErr: flag.ErrInvalidFlagData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{}
+			svc := NewService(repo, newFlags(), mockUnitOfWork{}, slog.Default())
+
+			_, err := svc.Apply(context.Background(), tt.cs, tt.tenant)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if repo.created != nil {
+				t.Error("expected no change set to be recorded")
+			}
+		})
+	}
+}
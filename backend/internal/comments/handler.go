@@ -0,0 +1,76 @@
+package comments
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/flags/:id/comments", h.Create)
+	r.GET("/flags/:id/comments", h.List)
+}
+
+// Create adds a comment to the flag, authored by the caller
+func (h *handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+
+	comment := &Comment{
+		FlagID:   c.Param("id"),
+		AuthorID: &userID,
+		Body:     req.Body,
+	}
+
+	if err := h.service.Create(c.Request.Context(), comment, tenantID); err != nil {
+		h.writeError(c, err, "failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (h *handler) List(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	comments, err := h.service.List(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to list comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+func (h *handler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidCommentData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case pkgErrors.IsNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
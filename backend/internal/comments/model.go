@@ -0,0 +1,18 @@
+package comments
+
+import "time"
+
+// Comment is a note left on a flag by a tenant member
+type Comment struct {
+	ID         string    `json:"id" db:"id"`
+	TenantID   string    `json:"tenant_id" db:"tenant_id"`
+	FlagID     string    `json:"flag_id" db:"flag_id"`
+	AuthorID   *string   `json:"author_id" db:"author_id"`
+	AuthorName *string   `json:"author_name" db:"author_name"`
+	Body       string    `json:"body" db:"body"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateRequest struct {
+	Body string `json:"body" binding:"required"`
+}
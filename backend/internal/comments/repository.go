@@ -0,0 +1,63 @@
+package comments
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	Create(ctx context.Context, c *Comment) error
+	ListByFlag(ctx context.Context, flagID string, tenantID string) ([]Comment, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+// Create stores a comment and fills in its ID, timestamp and author name
+func (r *postgresRepository) Create(ctx context.Context, c *Comment) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO flag_comments (tenant_id, flag_id, author_id, body)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, author_id, created_at
+		)
+		SELECT i.id, i.created_at, u.name
+		FROM inserted i
+		LEFT JOIN users u ON u.id = i.author_id
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, c.TenantID, c.FlagID, c.AuthorID, c.Body).
+		Scan(&c.ID, &c.CreatedAt, &c.AuthorName)
+}
+
+// ListByFlag returns a flag's comments, oldest first
+func (r *postgresRepository) ListByFlag(ctx context.Context, flagID string, tenantID string) ([]Comment, error) {
+	query := `
+		SELECT c.id, c.tenant_id, c.flag_id, c.author_id, u.name AS author_name, c.body, c.created_at
+		FROM flag_comments c
+		LEFT JOIN users u ON u.id = c.author_id
+		WHERE c.flag_id = $1 AND c.tenant_id = $2
+		ORDER BY c.created_at ASC, c.id ASC
+	`
+	comments := []Comment{}
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &comments, query, flagID, tenantID); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
@@ -0,0 +1,91 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
+)
+
+var ErrInvalidCommentData = errors.New("invalid comment")
+
+type Service interface {
+	Create(ctx context.Context, c *Comment, tenantID string) error
+	List(ctx context.Context, flagID string, tenantID string) ([]Comment, error)
+}
+
+type service struct {
+	repo   Repository
+	flags  flag.Service
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, flags flag.Service, logger *slog.Logger) Service {
+	return &service{
+		repo:   repo,
+		flags:  flags,
+		logger: logger,
+	}
+}
+
+// Create adds a comment to a flag in the tenant
+func (s *service) Create(ctx context.Context, c *Comment, tenantID string) error {
+	if c == nil {
+		return ErrInvalidCommentData
+	}
+
+	body, err := sanitize.Text(c.Body, sanitize.MaxCommentLength)
+	if err != nil {
+		return fmt.Errorf("%w: body must be at most %d characters", ErrInvalidCommentData, sanitize.MaxCommentLength)
+	}
+	if body == "" {
+		return fmt.Errorf("%w: body is required", ErrInvalidCommentData)
+	}
+	c.Body = body
+
+	// The flag must exist in the tenant; GetByID maps missing/forbidden to ErrNotFound
+	if _, err := s.flags.GetByID(ctx, c.FlagID, tenantID); err != nil {
+		return err
+	}
+
+	c.TenantID = tenantID
+
+	if err := s.repo.Create(ctx, c); err != nil {
+		s.logger.Error("failed to create comment",
+			slog.String("flag_id", c.FlagID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	s.logger.Info("comment created",
+		slog.String("id", c.ID),
+		slog.String("flag_id", c.FlagID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// List returns a flag's comments, oldest first
+func (s *service) List(ctx context.Context, flagID string, tenantID string) ([]Comment, error) {
+	if _, err := s.flags.GetByID(ctx, flagID, tenantID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.repo.ListByFlag(ctx, flagID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list comments",
+			slog.String("flag_id", flagID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+
+	return comments, nil
+}
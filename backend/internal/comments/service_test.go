@@ -0,0 +1,119 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
+)
+
+type mockRepository struct {
+	created []*Comment
+}
+
+func (m *mockRepository) Create(ctx context.Context, c *Comment) error {
+	c.ID = "test-generated-id"
+	m.created = append(m.created, c)
+	return nil
+}
+
+func (m *mockRepository) ListByFlag(ctx context.Context, flagID string, tenantID string) ([]Comment, error) {
+	comments := []Comment{}
+	for _, c := range m.created {
+		if c.FlagID == flagID && c.TenantID == tenantID {
+			comments = append(comments, *c)
+		}
+	}
+	return comments, nil
+}
+
+// mockFlagService implements flag.Service; only GetByID is used by comments
+type mockFlagService struct {
+	flag.Service
+	flag *flag.Flag
+}
+
+func (m *mockFlagService) GetByID(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+	if m.flag == nil || m.flag.ID != id || m.flag.TenantID != tenantID {
+		return nil, pkgErrors.ErrNotFound
+	}
+	copied := *m.flag
+	return &copied, nil
+}
+
+func stringPtr(s string) *string { return &s }
+
+func newTestService(repo *mockRepository) Service {
+	flags := &mockFlagService{flag: &flag.Flag{ID: "flag-1", TenantID: "tenant-1"}}
+	return NewService(repo, flags, slog.Default())
+}
+
+func TestCreate_AttachesToFlagInTenant(t *testing.T) {
+	repo := &mockRepository{}
+	svc := newTestService(repo)
+
+	c := &Comment{FlagID: "flag-1", AuthorID: stringPtr("user-1"), Body: "  Rolled back after incident  "}
+	if err := svc.Create(context.Background(), c, "tenant-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.TenantID != "tenant-1" {
+		t.Errorf("expected tenant-1, got %s", c.TenantID)
+	}
+	if c.Body != "Rolled back after incident" {
+		t.Errorf("expected trimmed body, got %q", c.Body)
+	}
+	if len(repo.created) != 1 {
+		t.Errorf("expected comment to be stored")
+	}
+}
+
+func TestCreate_RejectsEmptyBody(t *testing.T) {
+	repo := &mockRepository{}
+	svc := newTestService(repo)
+
+	err := svc.Create(context.Background(), &Comment{FlagID: "flag-1", Body: "   "}, "tenant-1")
+	if !errors.Is(err, ErrInvalidCommentData) {
+		t.Errorf("expected ErrInvalidCommentData, got %v", err)
+	}
+	if len(repo.created) != 0 {
+		t.Errorf("expected nothing to be stored")
+	}
+}
+
+func TestCreate_RejectsOversizedBody(t *testing.T) {
+	svc := newTestService(&mockRepository{})
+
+	body := strings.Repeat("a", sanitize.MaxCommentLength+1)
+	err := svc.Create(context.Background(), &Comment{FlagID: "flag-1", Body: body}, "tenant-1")
+	if !errors.Is(err, ErrInvalidCommentData) {
+		t.Errorf("expected ErrInvalidCommentData, got %v", err)
+	}
+}
+
+func TestCreate_FlagInOtherTenant(t *testing.T) {
+	repo := &mockRepository{}
+	svc := newTestService(repo)
+
+	err := svc.Create(context.Background(), &Comment{FlagID: "flag-1", Body: "note"}, "tenant-2")
+	if !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if len(repo.created) != 0 {
+		t.Errorf("expected nothing to be stored")
+	}
+}
+
+func TestList_FlagInOtherTenant(t *testing.T) {
+	svc := newTestService(&mockRepository{})
+
+	_, err := svc.List(context.Background(), "flag-1", "tenant-2")
+	if !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package deprecations
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/deprecations", h.Report)
+}
+
+// Report lists deprecated endpoints and fields with the tenant's usage of each,
+// so integrations can be migrated before a sunset date
+func (h *handler) Report(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	report, err := h.service.Report(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get deprecation report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
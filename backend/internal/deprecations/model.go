@@ -0,0 +1,43 @@
+package deprecations
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Notice describes a deprecated endpoint or field
+type Notice struct {
+	Key         string     `json:"key"` // stable identifier, e.g. "route:GET /tenant"
+	Description string     `json:"description"`
+	Replacement string     `json:"replacement,omitempty"`
+	Link        string     `json:"link,omitempty"` // documentation for migrating off the deprecated behaviour
+	Since       time.Time  `json:"since"`
+	Sunset      *time.Time `json:"sunset,omitempty"` // planned removal; nil until scheduled
+}
+
+// setHeaders writes the Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers
+func (n Notice) setHeaders(h http.Header) {
+	h.Set("Deprecation", "@"+strconv.FormatInt(n.Since.Unix(), 10))
+	if n.Sunset != nil {
+		h.Set("Sunset", n.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if n.Link != "" {
+		h.Add("Link", "<"+n.Link+`>; rel="deprecation"`)
+	}
+}
+
+// Usage is how often one tenant used one deprecated endpoint or field
+type Usage struct {
+	Key         string    `json:"key" db:"key"`
+	TenantID    string    `json:"tenant_id" db:"tenant_id"`
+	Count       int64     `json:"count" db:"count"`
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// NoticeUsage is a notice together with the tenant's usage of it; Usage is nil if unused
+type NoticeUsage struct {
+	Notice
+	Usage *Usage `json:"usage"`
+}
@@ -0,0 +1,67 @@
+package deprecations
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	UsageStore
+	ListUsage(ctx context.Context, tenantID string) ([]Usage, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// RecordUsage adds usage counts; tenant-less usage is stored under a NULL tenant
+func (r *postgresRepository) RecordUsage(ctx context.Context, counts map[UsageKey]int64, at time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for key, count := range counts {
+		var tenantID *string
+		if key.TenantID != "" {
+			tenantID = &key.TenantID
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO deprecation_usage (key, tenant_id, count, first_seen_at, last_seen_at)
+			VALUES ($1, $2, $3, $4, $4)
+			ON CONFLICT (key, COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid)) DO UPDATE
+			SET count = deprecation_usage.count + EXCLUDED.count,
+			    last_seen_at = GREATEST(deprecation_usage.last_seen_at, EXCLUDED.last_seen_at)
+		`, key.Key, tenantID, count, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListUsage returns a tenant's usage of deprecated behaviour
+func (r *postgresRepository) ListUsage(ctx context.Context, tenantID string) ([]Usage, error) {
+	var usage []Usage
+	err := sqlx.SelectContext(ctx, r.db, &usage, `
+		SELECT key, tenant_id, count, first_seen_at, last_seen_at
+		FROM deprecation_usage
+		WHERE tenant_id = $1
+		ORDER BY key
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
@@ -0,0 +1,53 @@
+package deprecations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+type Service interface {
+	// Report lists every deprecation notice with the tenant's usage of it
+	Report(ctx context.Context, tenantID string) ([]NoticeUsage, error)
+}
+
+type service struct {
+	repo    Repository
+	tracker *Tracker
+	logger  *slog.Logger
+}
+
+func NewService(repo Repository, tracker *Tracker, logger *slog.Logger) Service {
+	return &service{
+		repo:    repo,
+		tracker: tracker,
+		logger:  logger,
+	}
+}
+
+func (s *service) Report(ctx context.Context, tenantID string) ([]NoticeUsage, error) {
+	usage, err := s.repo.ListUsage(ctx, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list deprecated usage",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list deprecated usage: %w", err)
+	}
+
+	byKey := make(map[string]Usage, len(usage))
+	for _, u := range usage {
+		byKey[u.Key] = u
+	}
+
+	notices := s.tracker.Notices()
+	report := make([]NoticeUsage, len(notices))
+	for i, n := range notices {
+		report[i] = NoticeUsage{Notice: n}
+		if u, ok := byKey[n.Key]; ok {
+			report[i].Usage = &u
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,128 @@
+package deprecations
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/jalil32/toggle/internal/pkg/batch"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+// UsageStore persists deprecated behaviour usage counts
+type UsageStore interface {
+	RecordUsage(ctx context.Context, counts map[UsageKey]int64, at time.Time) error
+}
+
+// UsageKey identifies one tenant's use of one notice
+type UsageKey struct {
+	Key      string
+	TenantID string
+}
+
+// Tracker emits deprecation headers and counts usage per tenant in memory, writing
+// counts to the store periodically so deprecated routes don't pay for a database write
+type Tracker struct {
+	store    UsageStore
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	notices map[string]Notice
+	pending map[UsageKey]int64
+}
+
+func NewTracker(store UsageStore, interval time.Duration, logger *slog.Logger) *Tracker {
+	return &Tracker{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		notices:  make(map[string]Notice),
+		pending:  make(map[UsageKey]int64),
+	}
+}
+
+// Register adds notices to the report without marking any route
+func (t *Tracker) Register(notices ...Notice) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, n := range notices {
+		t.notices[n.Key] = n
+	}
+}
+
+// Mark returns route middleware that flags every request to the route as deprecated
+func (t *Tracker) Mark(n Notice) gin.HandlerFunc {
+	t.Register(n)
+
+	return func(c *gin.Context) {
+		t.Use(c, n)
+		c.Next()
+	}
+}
+
+// Use records one use of deprecated behaviour and sets the response headers.
+// Handlers call it directly for deprecated request fields.
+func (t *Tracker) Use(c *gin.Context, n Notice) {
+	n.setHeaders(c.Writer.Header())
+
+	// Tenant-less requests (e.g. /me routes for new users) are counted without a tenant
+	tenantID, _ := appContext.TenantID(c.Request.Context())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[UsageKey{Key: n.Key, TenantID: tenantID}]++
+}
+
+// Notices returns every registered notice, ordered by key
+func (t *Tracker) Notices() []Notice {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	notices := make([]Notice, 0, len(t.notices))
+	for _, n := range t.notices {
+		notices = append(notices, n)
+	}
+	sort.Slice(notices, func(i, j int) bool { return notices[i].Key < notices[j].Key })
+	return notices
+}
+
+// Flush writes all pending usage counts to the store
+func (t *Tracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	counts := t.pending
+	t.pending = make(map[UsageKey]int64)
+	t.mu.Unlock()
+
+	if err := t.store.RecordUsage(ctx, counts, time.Now()); err != nil {
+		// Put the counts back so they are retried on the next flush
+		t.mu.Lock()
+		for key, n := range counts {
+			t.pending[key] += n
+		}
+		t.mu.Unlock()
+
+		t.logger.Warn("failed to record deprecated usage",
+			slog.Int("keys", len(counts)),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	t.logger.Debug("deprecated usage recorded", slog.Int("keys", len(counts)))
+	return nil
+}
+
+// Run flushes pending usage every interval until ctx is cancelled, then flushes once more
+func (t *Tracker) Run(ctx context.Context) {
+	batch.Run(ctx, t.interval, t.Flush)
+}
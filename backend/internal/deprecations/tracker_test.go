@@ -0,0 +1,97 @@
+package deprecations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+type mockStore struct {
+	recorded []map[UsageKey]int64
+	err      error
+}
+
+func (m *mockStore) RecordUsage(ctx context.Context, counts map[UsageKey]int64, at time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.recorded = append(m.recorded, counts)
+	return nil
+}
+
+func testNotice() Notice {
+	sunset := time.Date(2027, 4, 16, 0, 0, 0, 0, time.UTC)
+	return Notice{
+		Key:    "route:GET /old",
+		Since:  time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		Sunset: &sunset,
+		Link:   "https://docs.example.com/migrate",
+	}
+}
+
+func TestTracker_MarkSetsHeadersAndCountsPerTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &mockStore{}
+	tracker := NewTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if tenantID := c.GetHeader("X-Tenant-ID"); tenantID != "" {
+			c.Request = c.Request.WithContext(appContext.WithTenant(c.Request.Context(), tenantID, "member"))
+		}
+		c.Next()
+	})
+	router.GET("/old", tracker.Mark(testNotice()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, tenantID := range []string{"tenant-1", "tenant-1", "tenant-2", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/old", nil)
+		req.Header.Set("X-Tenant-ID", tenantID)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "@1792108800", w.Header().Get("Deprecation"))
+		assert.Equal(t, "Fri, 16 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+		assert.Equal(t, `<https://docs.example.com/migrate>; rel="deprecation"`, w.Header().Get("Link"))
+	}
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	require.Len(t, store.recorded, 1)
+	assert.Equal(t, map[UsageKey]int64{
+		{Key: "route:GET /old", TenantID: "tenant-1"}: 2,
+		{Key: "route:GET /old", TenantID: "tenant-2"}: 1,
+		{Key: "route:GET /old", TenantID: ""}:         1,
+	}, store.recorded[0])
+
+	assert.Equal(t, []Notice{testNotice()}, tracker.Notices())
+}
+
+func TestTracker_FlushKeepsCountsOnFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &mockStore{err: errors.New("db down")}
+	tracker := NewTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/old", nil)
+	tracker.Use(c, testNotice())
+
+	assert.Error(t, tracker.Flush(context.Background()))
+
+	store.err = nil
+	require.NoError(t, tracker.Flush(context.Background()))
+	require.Len(t, store.recorded, 1)
+	assert.Equal(t, int64(1), store.recorded[0][UsageKey{Key: "route:GET /old"}])
+}
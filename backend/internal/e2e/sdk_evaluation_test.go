@@ -41,7 +41,7 @@ func TestE2E_SDKEvaluationFlow(t *testing.T) {
 	projectRepo := projects.NewRepository(db)
 	flagRepo := flagspkg.NewRepository(db)
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	evalService := evaluation.NewService(flagRepo, logger)
+	evalService := evaluation.NewService(flagRepo, projectRepo, logger)
 	evalHandler := evaluation.NewHandler(evalService)
 
 	// Setup Gin router with SDK routes
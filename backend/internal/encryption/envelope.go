@@ -0,0 +1,149 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sealedPrefix marks values sealed with a tenant data key: tk1:<key version>:<base64 nonce+ciphertext>
+const sealedPrefix = "tk1:"
+
+// keySize is the length of master and data keys (AES-256)
+const keySize = 32
+
+var errMalformed = errors.New("malformed sealed value")
+
+// MasterKey wraps tenant data keys; it never encrypts tenant data directly
+type MasterKey struct {
+	aead cipher.AEAD
+}
+
+// ParseMasterKey decodes a base64-encoded 32-byte master key
+func ParseMasterKey(encoded string) (*MasterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("master key is not valid base64: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", keySize, len(key))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &MasterKey{aead: aead}, nil
+}
+
+// wrap encrypts a data key, bound to its tenant so it can't be swapped into another tenant
+func (m *MasterKey) wrap(dataKey []byte, tenantID string) ([]byte, error) {
+	return encrypt(m.aead, dataKey, []byte(tenantID))
+}
+
+func (m *MasterKey) unwrap(wrapped []byte, tenantID string) ([]byte, error) {
+	return decrypt(m.aead, wrapped, []byte(tenantID))
+}
+
+// newDataKey returns a random data key
+func newDataKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// IsSealed reports whether a stored value was sealed with a tenant data key
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// SealedVersion returns the key version a sealed value was sealed with
+func SealedVersion(value string) (int, bool) {
+	version, _, err := parseSealed(value)
+	return version, err == nil
+}
+
+// seal encrypts plaintext with a data key; the tenant ID is authenticated but not stored
+func seal(dataKey []byte, version int, plaintext []byte, tenantID string) (string, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := encrypt(aead, plaintext, []byte(tenantID))
+	if err != nil {
+		return "", err
+	}
+
+	return sealedPrefix + strconv.Itoa(version) + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// open decrypts a value sealed by seal with the same data key and tenant ID
+func open(dataKey []byte, value string, tenantID string) ([]byte, error) {
+	_, ciphertext, err := parseSealed(value)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return decrypt(aead, ciphertext, []byte(tenantID))
+}
+
+func parseSealed(value string) (int, []byte, error) {
+	if !IsSealed(value) {
+		return 0, nil, errMalformed
+	}
+
+	versionText, encoded, ok := strings.Cut(strings.TrimPrefix(value, sealedPrefix), ":")
+	if !ok {
+		return 0, nil, errMalformed
+	}
+
+	version, err := strconv.Atoi(versionText)
+	if err != nil || version < 1 {
+		return 0, nil, errMalformed
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, nil, errMalformed
+	}
+
+	return version, ciphertext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns nonce || ciphertext
+func encrypt(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func decrypt(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errMalformed
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,77 @@
+package encryption
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/tenant/encryption", h.GetStatus)
+	r.POST("/tenant/encryption/rotate", h.Rotate)
+}
+
+// GetStatus returns the tenant's active key version and re-encryption progress
+func (h *handler) GetStatus(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	status, err := h.service.Status(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get encryption status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Rotate creates a new tenant key version; existing data is re-encrypted in the background
+func (h *handler) Rotate(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	// Only owners/admins can rotate keys
+	if role != "owner" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	status, err := h.service.Rotate(c.Request.Context(), tenantID)
+	if err != nil {
+		if errors.Is(err, ErrNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrNotEnterprise) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrRotationInProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate key"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, status)
+}
@@ -0,0 +1,141 @@
+package encryption
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrNoKey means the tenant has no data key, so its data is stored in plain text
+	ErrNoKey = errors.New("tenant has no encryption key")
+	// ErrNotConfigured means the server has no master key
+	ErrNotConfigured = errors.New("encryption is not configured")
+)
+
+// Keyring seals and opens tenant data with the tenant's data keys.
+// Unwrapped keys are cached; key versions never change once created.
+type Keyring struct {
+	repo   Repository
+	master *MasterKey
+
+	mu   sync.RWMutex
+	keys map[keyID][]byte
+}
+
+type keyID struct {
+	tenantID string
+	version  int
+}
+
+// NewKeyring returns a keyring; with a nil master key it seals nothing and opens nothing
+func NewKeyring(repo Repository, master *MasterKey) *Keyring {
+	return &Keyring{
+		repo:   repo,
+		master: master,
+		keys:   make(map[keyID][]byte),
+	}
+}
+
+// Configured reports whether the keyring has a master key
+func (k *Keyring) Configured() bool {
+	return k.master != nil
+}
+
+// Seal encrypts plaintext with the tenant's active key.
+// Returns ErrNoKey if the tenant has none (or encryption is not configured).
+func (k *Keyring) Seal(ctx context.Context, tenantID string, plaintext []byte) (string, error) {
+	if k.master == nil {
+		return "", ErrNoKey
+	}
+
+	active, err := k.repo.ActiveKey(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNoKey
+		}
+		return "", fmt.Errorf("failed to get active key: %w", err)
+	}
+
+	dataKey, err := k.dataKey(ctx, tenantID, active)
+	if err != nil {
+		return "", err
+	}
+
+	return seal(dataKey, active.Version, plaintext, tenantID)
+}
+
+// Open decrypts a value sealed for the tenant with any of its key versions
+func (k *Keyring) Open(ctx context.Context, tenantID string, value string) ([]byte, error) {
+	if k.master == nil {
+		return nil, ErrNotConfigured
+	}
+
+	version, ok := SealedVersion(value)
+	if !ok {
+		return nil, errMalformed
+	}
+
+	stored, err := k.repo.GetKey(ctx, tenantID, version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoKey
+		}
+		return nil, fmt.Errorf("failed to get key version %d: %w", version, err)
+	}
+
+	dataKey, err := k.dataKey(ctx, tenantID, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(dataKey, value, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sealed value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// dataKey unwraps a stored key, caching the result
+func (k *Keyring) dataKey(ctx context.Context, tenantID string, stored *DataKey) ([]byte, error) {
+	id := keyID{tenantID: tenantID, version: stored.Version}
+
+	k.mu.RLock()
+	key, ok := k.keys[id]
+	k.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	key, err := k.master.unwrap(stored.WrappedKey, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key version %d: %w", stored.Version, err)
+	}
+
+	k.mu.Lock()
+	k.keys[id] = key
+	k.mu.Unlock()
+
+	return key, nil
+}
+
+// newKey creates and wraps a data key for the tenant
+func (k *Keyring) newKey(tenantID string, version int) (*DataKey, error) {
+	if k.master == nil {
+		return nil, ErrNotConfigured
+	}
+
+	dataKey, err := newDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := k.master.wrap(dataKey, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataKey{TenantID: tenantID, Version: version, WrappedKey: wrapped}, nil
+}
@@ -0,0 +1,43 @@
+package encryption
+
+import "time"
+
+// Rotation states
+const (
+	StateNone     = "none"     // the tenant has no data key; sensitive fields are stored in plain text
+	StateRotating = "rotating" // a new key is active and older data is being re-encrypted
+	StateComplete = "complete" // all sensitive data is sealed with the active key
+)
+
+// ReencryptBatchSize is how many records each re-encrypter handles per tenant per job run
+const ReencryptBatchSize = 100
+
+// DataKey is one version of a tenant's data encryption key, wrapped by the master key
+type DataKey struct {
+	TenantID   string    `db:"tenant_id"`
+	Version    int       `db:"version"`
+	WrappedKey []byte    `db:"wrapped_key"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// Rotation tracks re-encryption of a tenant's data to a key version
+type Rotation struct {
+	TenantID    string     `db:"tenant_id"`
+	KeyVersion  int        `db:"key_version"`
+	State       string     `db:"state"`
+	Reencrypted int        `db:"reencrypted"`
+	StartedAt   time.Time  `db:"started_at"`
+	CompletedAt *time.Time `db:"completed_at"`
+}
+
+// Status is a tenant's encryption key and rotation progress
+type Status struct {
+	TenantID    string     `json:"tenant_id"`
+	Enabled     bool       `json:"enabled"`
+	KeyVersion  int        `json:"key_version"`
+	State       string     `json:"state"`
+	Pending     int        `json:"pending"`     // records not yet sealed with the active key
+	Reencrypted int        `json:"reencrypted"` // records re-encrypted by the current rotation
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
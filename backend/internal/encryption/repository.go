@@ -0,0 +1,147 @@
+package encryption
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	GetPlan(ctx context.Context, tenantID string) (string, error)
+	// ActiveKey returns the tenant's highest key version, or sql.ErrNoRows if it has none
+	ActiveKey(ctx context.Context, tenantID string) (*DataKey, error)
+	GetKey(ctx context.Context, tenantID string, version int) (*DataKey, error)
+	CreateKey(ctx context.Context, k *DataKey) error
+	// GetRotation returns the tenant's latest rotation, or sql.ErrNoRows if it never rotated
+	GetRotation(ctx context.Context, tenantID string) (*Rotation, error)
+	StartRotation(ctx context.Context, tenantID string, version int) error
+	// ListRotating returns unfinished rotations across all tenants
+	ListRotating(ctx context.Context) ([]Rotation, error)
+	RecordProgress(ctx context.Context, tenantID string, version int, reencrypted int, complete bool) error
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *postgresRepository) GetPlan(ctx context.Context, tenantID string) (string, error) {
+	var plan string
+	err := r.getDB(ctx).QueryRowxContext(ctx, `SELECT plan FROM tenants WHERE id = $1`, tenantID).Scan(&plan)
+	return plan, err
+}
+
+func (r *postgresRepository) ActiveKey(ctx context.Context, tenantID string) (*DataKey, error) {
+	query := `
+		SELECT tenant_id, version, wrapped_key, created_at
+		FROM tenant_data_keys
+		WHERE tenant_id = $1
+		ORDER BY version DESC
+		LIMIT 1
+	`
+	var k DataKey
+	if err := sqlx.GetContext(ctx, r.getDB(ctx), &k, query, tenantID); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *postgresRepository) GetKey(ctx context.Context, tenantID string, version int) (*DataKey, error) {
+	query := `
+		SELECT tenant_id, version, wrapped_key, created_at
+		FROM tenant_data_keys
+		WHERE tenant_id = $1 AND version = $2
+	`
+	var k DataKey
+	if err := sqlx.GetContext(ctx, r.getDB(ctx), &k, query, tenantID, version); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// CreateKey stores a new key version; a concurrent rotation to the same version fails
+// with a unique violation
+func (r *postgresRepository) CreateKey(ctx context.Context, k *DataKey) error {
+	query := `
+		INSERT INTO tenant_data_keys (tenant_id, version, wrapped_key)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, k.TenantID, k.Version, k.WrappedKey).Scan(&k.CreatedAt)
+}
+
+func (r *postgresRepository) GetRotation(ctx context.Context, tenantID string) (*Rotation, error) {
+	query := `
+		SELECT tenant_id, key_version, state, reencrypted, started_at, completed_at
+		FROM tenant_key_rotations
+		WHERE tenant_id = $1
+	`
+	var rot Rotation
+	if err := sqlx.GetContext(ctx, r.getDB(ctx), &rot, query, tenantID); err != nil {
+		return nil, err
+	}
+	return &rot, nil
+}
+
+func (r *postgresRepository) StartRotation(ctx context.Context, tenantID string, version int) error {
+	query := `
+		INSERT INTO tenant_key_rotations (tenant_id, key_version, state, reencrypted, started_at, completed_at)
+		VALUES ($1, $2, $3, 0, NOW(), NULL)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET key_version = EXCLUDED.key_version,
+		    state = EXCLUDED.state,
+		    reencrypted = 0,
+		    started_at = EXCLUDED.started_at,
+		    completed_at = NULL
+	`
+	_, err := r.getDB(ctx).ExecContext(ctx, query, tenantID, version, StateRotating)
+	return err
+}
+
+func (r *postgresRepository) ListRotating(ctx context.Context) ([]Rotation, error) {
+	query := `
+		SELECT tenant_id, key_version, state, reencrypted, started_at, completed_at
+		FROM tenant_key_rotations
+		WHERE state = $1
+		ORDER BY started_at ASC
+	`
+	var rotations []Rotation
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &rotations, query, StateRotating); err != nil {
+		return nil, err
+	}
+	return rotations, nil
+}
+
+// RecordProgress adds to a rotation's re-encrypted count and optionally completes it.
+// Progress for a superseded key version is ignored.
+func (r *postgresRepository) RecordProgress(ctx context.Context, tenantID string, version int, reencrypted int, complete bool) error {
+	var completedAt *time.Time
+	state := StateRotating
+	if complete {
+		now := time.Now()
+		completedAt = &now
+		state = StateComplete
+	}
+
+	query := `
+		UPDATE tenant_key_rotations
+		SET reencrypted = reencrypted + $3, state = $4, completed_at = $5
+		WHERE tenant_id = $1 AND key_version = $2 AND state = 'rotating'
+	`
+	_, err := r.getDB(ctx).ExecContext(ctx, query, tenantID, version, reencrypted, state, completedAt)
+	return err
+}
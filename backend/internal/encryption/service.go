@@ -0,0 +1,223 @@
+package encryption
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+// enterprisePlan is the only plan that may hold tenant encryption keys
+const enterprisePlan = "enterprise"
+
+var (
+	ErrNotEnterprise      = errors.New("tenant encryption keys require the enterprise plan")
+	ErrRotationInProgress = errors.New("a key rotation is already in progress")
+)
+
+// Reencrypter re-seals one kind of stored sensitive data with a tenant's active key.
+// Domains holding sensitive fields implement it and register with Service.AddReencrypter.
+type Reencrypter interface {
+	// CountPending counts the tenant's records not sealed with the given key version
+	CountPending(ctx context.Context, tenantID string, version int) (int, error)
+	// Reencrypt re-seals up to limit pending records and returns how many it changed
+	Reencrypt(ctx context.Context, tenantID string, version int, limit int) (int, error)
+}
+
+type Service interface {
+	Status(ctx context.Context, tenantID string) (*Status, error)
+	// Rotate creates a new active key version and starts re-encrypting the tenant's data.
+	// The first rotation enables encryption for the tenant.
+	Rotate(ctx context.Context, tenantID string) (*Status, error)
+	// ReencryptPending advances every unfinished rotation by one batch; run by the scheduler
+	ReencryptPending(ctx context.Context) error
+	AddReencrypter(r Reencrypter)
+}
+
+type service struct {
+	repo         Repository
+	keyring      *Keyring
+	uow          transaction.UnitOfWork
+	reencrypters []Reencrypter
+	logger       *slog.Logger
+}
+
+func NewService(repo Repository, keyring *Keyring, uow transaction.UnitOfWork, logger *slog.Logger) Service {
+	return &service{
+		repo:    repo,
+		keyring: keyring,
+		uow:     uow,
+		logger:  logger,
+	}
+}
+
+// AddReencrypter registers a kind of sensitive data; call before the scheduler starts
+func (s *service) AddReencrypter(r Reencrypter) {
+	s.reencrypters = append(s.reencrypters, r)
+}
+
+func (s *service) Status(ctx context.Context, tenantID string) (*Status, error) {
+	status := &Status{TenantID: tenantID, State: StateNone}
+
+	active, err := s.repo.ActiveKey(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return status, nil
+		}
+		return nil, fmt.Errorf("failed to get active key: %w", err)
+	}
+	status.Enabled = true
+	status.KeyVersion = active.Version
+	status.State = StateComplete
+
+	rotation, err := s.repo.GetRotation(ctx, tenantID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get rotation: %w", err)
+	}
+	if rotation != nil && rotation.KeyVersion == active.Version {
+		status.State = rotation.State
+		status.Reencrypted = rotation.Reencrypted
+		status.StartedAt = &rotation.StartedAt
+		status.CompletedAt = rotation.CompletedAt
+	}
+
+	pending, err := s.countPending(ctx, tenantID, active.Version)
+	if err != nil {
+		return nil, err
+	}
+	status.Pending = pending
+
+	return status, nil
+}
+
+func (s *service) Rotate(ctx context.Context, tenantID string) (*Status, error) {
+	if !s.keyring.Configured() {
+		return nil, ErrNotConfigured
+	}
+
+	plan, err := s.repo.GetPlan(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get tenant plan: %w", err)
+	}
+	if plan != enterprisePlan {
+		return nil, ErrNotEnterprise
+	}
+
+	rotation, err := s.repo.GetRotation(ctx, tenantID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get rotation: %w", err)
+	}
+	if rotation != nil && rotation.State == StateRotating {
+		return nil, ErrRotationInProgress
+	}
+
+	version := 1
+	if active, err := s.repo.ActiveKey(ctx, tenantID); err == nil {
+		version = active.Version + 1
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get active key: %w", err)
+	}
+
+	key, err := s.keyring.newKey(tenantID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data key: %w", err)
+	}
+
+	err = s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.repo.CreateKey(txCtx, key); err != nil {
+			return err
+		}
+		return s.repo.StartRotation(txCtx, tenantID, version)
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrRotationInProgress
+		}
+		s.logger.Error("failed to rotate tenant key",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to rotate key: %w", err)
+	}
+
+	s.logger.Info("tenant key rotated",
+		slog.String("tenant_id", tenantID),
+		slog.Int("key_version", version),
+	)
+
+	return s.Status(ctx, tenantID)
+}
+
+func (s *service) ReencryptPending(ctx context.Context) error {
+	rotations, err := s.repo.ListRotating(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list key rotations: %w", err)
+	}
+
+	for _, rotation := range rotations {
+		if err := s.advance(ctx, rotation); err != nil {
+			// One tenant's failure shouldn't hold up the others; it is retried next run
+			s.logger.Error("failed to re-encrypt tenant data",
+				slog.String("tenant_id", rotation.TenantID),
+				slog.Int("key_version", rotation.KeyVersion),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	return nil
+}
+
+// advance re-encrypts one batch of each kind of data and completes the rotation once nothing is pending
+func (s *service) advance(ctx context.Context, rotation Rotation) error {
+	reencrypted := 0
+	for _, r := range s.reencrypters {
+		n, err := r.Reencrypt(ctx, rotation.TenantID, rotation.KeyVersion, ReencryptBatchSize)
+		reencrypted += n
+		if err != nil {
+			_ = s.repo.RecordProgress(ctx, rotation.TenantID, rotation.KeyVersion, reencrypted, false)
+			return err
+		}
+	}
+
+	pending, err := s.countPending(ctx, rotation.TenantID, rotation.KeyVersion)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RecordProgress(ctx, rotation.TenantID, rotation.KeyVersion, reencrypted, pending == 0); err != nil {
+		return fmt.Errorf("failed to record rotation progress: %w", err)
+	}
+
+	if pending == 0 {
+		s.logger.Info("tenant key rotation complete",
+			slog.String("tenant_id", rotation.TenantID),
+			slog.Int("key_version", rotation.KeyVersion),
+			slog.Int("reencrypted", rotation.Reencrypted+reencrypted),
+		)
+	}
+
+	return nil
+}
+
+func (s *service) countPending(ctx context.Context, tenantID string, version int) (int, error) {
+	total := 0
+	for _, r := range s.reencrypters {
+		n, err := r.CountPending(ctx, tenantID, version)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count pending records: %w", err)
+		}
+		total += n
+	}
+	return total, nil
+}
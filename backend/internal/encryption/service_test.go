@@ -0,0 +1,272 @@
+package encryption
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+const testMasterKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+// mockRepository keeps keys and rotations in memory
+type mockRepository struct {
+	plans     map[string]string
+	keys      map[string][]DataKey
+	rotations map[string]*Rotation
+}
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{
+		plans:     map[string]string{},
+		keys:      map[string][]DataKey{},
+		rotations: map[string]*Rotation{},
+	}
+}
+
+func (m *mockRepository) GetPlan(ctx context.Context, tenantID string) (string, error) {
+	plan, ok := m.plans[tenantID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return plan, nil
+}
+
+func (m *mockRepository) ActiveKey(ctx context.Context, tenantID string) (*DataKey, error) {
+	keys := m.keys[tenantID]
+	if len(keys) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	k := keys[len(keys)-1]
+	return &k, nil
+}
+
+func (m *mockRepository) GetKey(ctx context.Context, tenantID string, version int) (*DataKey, error) {
+	for _, k := range m.keys[tenantID] {
+		if k.Version == version {
+			return &k, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockRepository) CreateKey(ctx context.Context, k *DataKey) error {
+	m.keys[k.TenantID] = append(m.keys[k.TenantID], *k)
+	return nil
+}
+
+func (m *mockRepository) GetRotation(ctx context.Context, tenantID string) (*Rotation, error) {
+	rot, ok := m.rotations[tenantID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return rot, nil
+}
+
+func (m *mockRepository) StartRotation(ctx context.Context, tenantID string, version int) error {
+	m.rotations[tenantID] = &Rotation{TenantID: tenantID, KeyVersion: version, State: StateRotating, StartedAt: time.Now()}
+	return nil
+}
+
+func (m *mockRepository) ListRotating(ctx context.Context) ([]Rotation, error) {
+	var rotations []Rotation
+	for _, rot := range m.rotations {
+		if rot.State == StateRotating {
+			rotations = append(rotations, *rot)
+		}
+	}
+	return rotations, nil
+}
+
+func (m *mockRepository) RecordProgress(ctx context.Context, tenantID string, version int, reencrypted int, complete bool) error {
+	rot, ok := m.rotations[tenantID]
+	if !ok || rot.KeyVersion != version || rot.State != StateRotating {
+		return nil
+	}
+	rot.Reencrypted += reencrypted
+	if complete {
+		now := time.Now()
+		rot.State = StateComplete
+		rot.CompletedAt = &now
+	}
+	return nil
+}
+
+type mockUnitOfWork struct{}
+
+func (mockUnitOfWork) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// mockReencrypter holds the key version each record is sealed with
+type mockReencrypter struct {
+	versions []int
+}
+
+func (m *mockReencrypter) CountPending(ctx context.Context, tenantID string, version int) (int, error) {
+	n := 0
+	for _, v := range m.versions {
+		if v != version {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockReencrypter) Reencrypt(ctx context.Context, tenantID string, version int, limit int) (int, error) {
+	n := 0
+	for i, v := range m.versions {
+		if v != version && n < limit {
+			m.versions[i] = version
+			n++
+		}
+	}
+	return n, nil
+}
+
+func newTestService(t *testing.T, repo *mockRepository, configured bool) (*service, *Keyring) {
+	t.Helper()
+	var master *MasterKey
+	if configured {
+		var err error
+		master, err = ParseMasterKey(testMasterKey)
+		if err != nil {
+			t.Fatalf("failed to parse master key: %v", err)
+		}
+	}
+	keyring := NewKeyring(repo, master)
+	return NewService(repo, keyring, mockUnitOfWork{}, slog.Default()).(*service), keyring
+}
+
+func TestKeyringSealOpen(t *testing.T) {
+	repo := newMockRepository()
+	repo.plans["tenant-1"] = enterprisePlan
+	repo.plans["tenant-2"] = enterprisePlan
+	svc, keyring := newTestService(t, repo, true)
+	ctx := context.Background()
+
+	if _, err := keyring.Seal(ctx, "tenant-1", []byte("secret")); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("expected ErrNoKey before the first rotation, got %v", err)
+	}
+
+	for _, tenantID := range []string{"tenant-1", "tenant-2"} {
+		if _, err := svc.Rotate(ctx, tenantID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	sealed, err := keyring.Seal(ctx, "tenant-1", []byte("secret"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !IsSealed(sealed) {
+		t.Fatalf("expected a sealed value, got %q", sealed)
+	}
+	if version, _ := SealedVersion(sealed); version != 1 {
+		t.Errorf("expected key version 1, got %d", version)
+	}
+
+	plaintext, err := keyring.Open(ctx, "tenant-1", sealed)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(plaintext) != "secret" {
+		t.Errorf("expected secret, got %q", plaintext)
+	}
+
+	// Another tenant can't open it, even with a key of the same version
+	if _, err := keyring.Open(ctx, "tenant-2", sealed); err == nil {
+		t.Error("expected another tenant to fail opening the value")
+	}
+}
+
+func TestServiceRotate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not configured", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.plans["tenant-1"] = enterprisePlan
+		svc, _ := newTestService(t, repo, false)
+
+		if _, err := svc.Rotate(ctx, "tenant-1"); !errors.Is(err, ErrNotConfigured) {
+			t.Errorf("expected ErrNotConfigured, got %v", err)
+		}
+	})
+
+	t.Run("not enterprise", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.plans["tenant-1"] = "team"
+		svc, _ := newTestService(t, repo, true)
+
+		if _, err := svc.Rotate(ctx, "tenant-1"); !errors.Is(err, ErrNotEnterprise) {
+			t.Errorf("expected ErrNotEnterprise, got %v", err)
+		}
+	})
+
+	t.Run("rotation in progress", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.plans["tenant-1"] = enterprisePlan
+		svc, _ := newTestService(t, repo, true)
+		svc.AddReencrypter(&mockReencrypter{versions: []int{0}})
+
+		status, err := svc.Rotate(ctx, "tenant-1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if status.KeyVersion != 1 || status.State != StateRotating || status.Pending != 1 {
+			t.Errorf("expected version 1 rotating with 1 pending, got %+v", status)
+		}
+
+		if _, err := svc.Rotate(ctx, "tenant-1"); !errors.Is(err, ErrRotationInProgress) {
+			t.Errorf("expected ErrRotationInProgress, got %v", err)
+		}
+	})
+}
+
+func TestServiceReencryptPending(t *testing.T) {
+	repo := newMockRepository()
+	repo.plans["tenant-1"] = enterprisePlan
+	svc, _ := newTestService(t, repo, true)
+	ctx := context.Background()
+
+	records := &mockReencrypter{versions: make([]int, ReencryptBatchSize+1)}
+	svc.AddReencrypter(records)
+
+	if _, err := svc.Rotate(ctx, "tenant-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// The first run handles one batch and leaves the rest for the next
+	if err := svc.ReencryptPending(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	status, err := svc.Status(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.State != StateRotating || status.Pending != 1 || status.Reencrypted != ReencryptBatchSize {
+		t.Errorf("expected one record pending after the first batch, got %+v", status)
+	}
+
+	if err := svc.ReencryptPending(ctx); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	status, err = svc.Status(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.State != StateComplete || status.Pending != 0 || status.CompletedAt == nil {
+		t.Errorf("expected rotation complete, got %+v", status)
+	}
+
+	// A second rotation moves to the next version
+	status, err = svc.Rotate(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.KeyVersion != 2 || status.Pending != ReencryptBatchSize+1 {
+		t.Errorf("expected version 2 with every record pending, got %+v", status)
+	}
+}
@@ -0,0 +1,114 @@
+package evaluation
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jalil32/toggle/internal/pkg/batch"
+)
+
+// Bounds on tracked attribute names, so misbehaving clients can't grow memory or the table without limit
+const (
+	MaxTrackedAttributesPerProject = 500
+	MaxTrackedAttributeLength      = 255
+)
+
+// AttributeRecorder records which context attributes were sent to a project
+type AttributeRecorder interface {
+	Record(projectID string, attributes map[string]interface{})
+}
+
+// AttributeStore persists when context attributes were last seen
+type AttributeStore interface {
+	RecordAttributes(ctx context.Context, projectID string, attributes []string, at time.Time) error
+}
+
+// AttributeTracker collects attribute names per project in memory and writes them to the store
+// periodically, keeping database writes off the evaluation path
+type AttributeTracker struct {
+	store    AttributeStore
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]map[string]struct{} // project ID -> attribute names
+}
+
+func NewAttributeTracker(store AttributeStore, interval time.Duration, logger *slog.Logger) *AttributeTracker {
+	return &AttributeTracker{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		pending:  make(map[string]map[string]struct{}),
+	}
+}
+
+// Record marks the context's attribute names as seen; it never blocks on the database
+func (t *AttributeTracker) Record(projectID string, attributes map[string]interface{}) {
+	if projectID == "" || len(attributes) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names, ok := t.pending[projectID]
+	if !ok {
+		names = make(map[string]struct{})
+		t.pending[projectID] = names
+	}
+
+	for name := range attributes {
+		if name == "" || len(name) > MaxTrackedAttributeLength {
+			continue
+		}
+		if len(names) >= MaxTrackedAttributesPerProject {
+			break
+		}
+		names[name] = struct{}{}
+	}
+}
+
+// Flush writes all pending attributes to the store
+func (t *AttributeTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[string]map[string]struct{})
+	t.mu.Unlock()
+
+	now := time.Now()
+	var firstErr error
+	for projectID, names := range pending {
+		attributes := make([]string, 0, len(names))
+		for name := range names {
+			attributes = append(attributes, name)
+		}
+
+		if err := t.store.RecordAttributes(ctx, projectID, attributes, now); err != nil {
+			// Usage is best-effort: the next evaluation will record the attributes again
+			t.logger.Warn("failed to record attribute usage",
+				slog.String("project_id", projectID),
+				slog.Int("attributes", len(attributes)),
+				slog.String("error", err.Error()),
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		t.logger.Debug("attribute usage recorded",
+			slog.String("project_id", projectID),
+			slog.Int("attributes", len(attributes)),
+		)
+	}
+
+	return firstErr
+}
+
+// Run flushes pending attributes every interval until ctx is cancelled, then flushes once more
+func (t *AttributeTracker) Run(ctx context.Context) {
+	batch.Run(ctx, t.interval, t.Flush)
+}
@@ -0,0 +1,85 @@
+package evaluation
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+type mockAttributeStore struct {
+	mu    sync.Mutex
+	calls map[string][]string
+}
+
+func (m *mockAttributeStore) RecordAttributes(ctx context.Context, projectID string, attributes []string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sorted := append([]string(nil), attributes...)
+	sort.Strings(sorted)
+	if m.calls == nil {
+		m.calls = make(map[string][]string)
+	}
+	m.calls[projectID] = sorted
+	return nil
+}
+
+func TestAttributeTracker_FlushGroupsByProject(t *testing.T) {
+	store := &mockAttributeStore{}
+	tracker := NewAttributeTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tracker.Record("project-1", map[string]interface{}{"country": "AU", "plan": "pro"})
+	tracker.Record("project-1", map[string]interface{}{"country": "US"})
+	tracker.Record("project-2", map[string]interface{}{"email": "a@example.com"})
+	tracker.Record("project-3", nil)
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Equal(t, map[string][]string{
+		"project-1": {"country", "plan"},
+		"project-2": {"email"},
+	}, store.calls)
+}
+
+func TestAttributeTracker_CapsAttributesPerProject(t *testing.T) {
+	store := &mockAttributeStore{}
+	tracker := NewAttributeTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	attrs := make(map[string]interface{})
+	for i := 0; i < MaxTrackedAttributesPerProject+50; i++ {
+		attrs["attr-"+strconv.Itoa(i)] = i
+	}
+	tracker.Record("project-1", attrs)
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Len(t, store.calls["project-1"], MaxTrackedAttributesPerProject)
+}
+
+func TestService_EvaluateAll_RecordsAttributes(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{}, nil
+		},
+	}
+	store := &mockAttributeStore{}
+	tracker := NewAttributeTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.SetAttributeRecorder(tracker)
+
+	_, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{
+		UserID:     "user-1",
+		Attributes: map[string]interface{}{"country": "AU"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Equal(t, []string{"country"}, store.calls["project-1"])
+}
@@ -0,0 +1,268 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// Benchmark bounds, so a single request can't exhaust the instance it measures
+const (
+	DefaultBenchmarkFlags        = 100
+	MaxBenchmarkFlags            = 10000
+	DefaultBenchmarkRulesPerFlag = 3
+	MaxBenchmarkRulesPerFlag     = 20
+	MaxBenchmarkConcurrency      = 64
+	DefaultBenchmarkRequests     = 10000
+	MaxBenchmarkRequests         = 1000000
+	// benchmarkUsers is how many distinct synthetic users requests cycle through
+	benchmarkUsers = 1000
+	// MaxBenchmarkDuration stops a benchmark early, well within the management request timeout
+	MaxBenchmarkDuration = 20 * time.Second
+	// maxBenchmarkSamples bounds the latencies kept for the percentiles; larger runs keep
+	// every n-th request's
+	maxBenchmarkSamples = 10000
+)
+
+var (
+	ErrInvalidBenchmark = errors.New("invalid benchmark")
+	ErrBenchmarkRunning = errors.New("a benchmark is already running on this instance")
+)
+
+// BenchmarkRequest configures a synthetic evaluation benchmark; zero values take the defaults
+type BenchmarkRequest struct {
+	Flags        int    `json:"flags"`
+	RulesPerFlag int    `json:"rules_per_flag"`
+	RuleLogic    string `json:"rule_logic"`
+	Concurrency  int    `json:"concurrency"` // defaults to GOMAXPROCS
+	Requests     int    `json:"requests"`    // bulk evaluations to run, each evaluating every flag
+}
+
+// BenchmarkLatency summarises bulk evaluation latencies in microseconds. Percentiles of runs
+// over 10000 requests are taken from an evenly spaced sample of them; Max covers every request.
+type BenchmarkLatency struct {
+	P50 float64 `json:"p50_us"`
+	P90 float64 `json:"p90_us"`
+	P99 float64 `json:"p99_us"`
+	Max float64 `json:"max_us"`
+}
+
+// BenchmarkResult is the throughput and latency of the in-memory evaluation path
+type BenchmarkResult struct {
+	BenchmarkRequest
+	GoMaxProcs           int              `json:"gomaxprocs"`
+	CompletedRequests    int              `json:"completed_requests"`
+	Evaluations          int64            `json:"evaluations"`
+	DurationMS           float64          `json:"duration_ms"`
+	RequestsPerSecond    float64          `json:"requests_per_second"`
+	EvaluationsPerSecond float64          `json:"evaluations_per_second"`
+	Latency              BenchmarkLatency `json:"latency"`
+	// TimedOut is set when the benchmark stopped before completing every request
+	TimedOut bool `json:"timed_out"`
+}
+
+// Benchmarker runs evaluation benchmarks one at a time, since each one saturates the
+// CPUs it is given and concurrent runs would skew each other's numbers
+type Benchmarker struct {
+	evaluator *Evaluator
+	running   sync.Mutex
+}
+
+func NewBenchmarker() *Benchmarker {
+	return &Benchmarker{evaluator: NewEvaluator()}
+}
+
+// Run evaluates synthetic flags for synthetic users, as EvaluateAll does after loading a
+// project's flags. Database reads and recorders are left out: the result is the CPU cost
+// of evaluation on this instance.
+func (b *Benchmarker) Run(ctx context.Context, req BenchmarkRequest) (*BenchmarkResult, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+	if !b.running.TryLock() {
+		return nil, ErrBenchmarkRunning
+	}
+	defer b.running.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, MaxBenchmarkDuration)
+	defer cancel()
+
+	flags := benchmarkFlags(req)
+	users := make([]EvaluationContext, min(req.Requests, benchmarkUsers))
+	for i := range users {
+		users[i] = benchmarkContext(i)
+	}
+	// Percentiles come from an evenly spaced sample of requests; each sampled request has
+	// its own slot, so workers never write the same one
+	stride := (req.Requests + maxBenchmarkSamples - 1) / maxBenchmarkSamples
+	samples := make([]time.Duration, (req.Requests+stride-1)/stride)
+	slowest := make([]time.Duration, req.Concurrency) // per worker
+	var next, done atomic.Int64
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for w := 0; w < req.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			results := make(map[string]bool, len(flags))
+			for ctx.Err() == nil {
+				i := int(next.Add(1)) - 1
+				if i >= req.Requests {
+					return
+				}
+				evalCtx := users[i%len(users)]
+
+				began := time.Now()
+				for j := range flags {
+					results[flags[j].ID] = b.evaluator.Evaluate(&flags[j], evalCtx)
+				}
+				latency := max(time.Since(began), time.Nanosecond)
+				if i%stride == 0 {
+					samples[i/stride] = latency
+				}
+				slowest[w] = max(slowest[w], latency)
+				done.Add(1)
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sampled := make([]time.Duration, 0, len(samples))
+	for _, l := range samples {
+		if l > 0 {
+			sampled = append(sampled, l)
+		}
+	}
+	slices.Sort(sampled)
+	completed := int(done.Load())
+
+	result := &BenchmarkResult{
+		BenchmarkRequest:  req,
+		GoMaxProcs:        runtime.GOMAXPROCS(0),
+		CompletedRequests: completed,
+		Evaluations:       int64(completed) * int64(len(flags)),
+		DurationMS:        float64(elapsed.Microseconds()) / 1000,
+		Latency: BenchmarkLatency{
+			P50: percentileMicros(sampled, 50),
+			P90: percentileMicros(sampled, 90),
+			P99: percentileMicros(sampled, 99),
+			Max: float64(slices.Max(slowest).Nanoseconds()) / 1000,
+		},
+		TimedOut: completed < req.Requests,
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		result.RequestsPerSecond = float64(result.CompletedRequests) / seconds
+		result.EvaluationsPerSecond = float64(result.Evaluations) / seconds
+	}
+
+	return result, nil
+}
+
+// normalize applies defaults and checks bounds
+func (r *BenchmarkRequest) normalize() error {
+	if r.Flags == 0 {
+		r.Flags = DefaultBenchmarkFlags
+	}
+	if r.RulesPerFlag == 0 {
+		r.RulesPerFlag = DefaultBenchmarkRulesPerFlag
+	}
+	if r.RuleLogic == "" {
+		r.RuleLogic = flag.RuleLogicAnd
+	}
+	if r.Concurrency == 0 {
+		r.Concurrency = min(runtime.GOMAXPROCS(0), MaxBenchmarkConcurrency)
+	}
+	if r.Requests == 0 {
+		r.Requests = DefaultBenchmarkRequests
+	}
+
+	switch {
+	case r.Flags < 1 || r.Flags > MaxBenchmarkFlags:
+		return fmt.Errorf("%w: flags must be between 1 and %d", ErrInvalidBenchmark, MaxBenchmarkFlags)
+	case r.RulesPerFlag < 0 || r.RulesPerFlag > MaxBenchmarkRulesPerFlag:
+		return fmt.Errorf("%w: rules_per_flag must be between 0 and %d", ErrInvalidBenchmark, MaxBenchmarkRulesPerFlag)
+	case !flag.ValidRuleLogic(r.RuleLogic):
+		return fmt.Errorf("%w: rule_logic must be AND, OR or FIRST_MATCH", ErrInvalidBenchmark)
+	case r.Concurrency < 1 || r.Concurrency > MaxBenchmarkConcurrency:
+		return fmt.Errorf("%w: concurrency must be between 1 and %d", ErrInvalidBenchmark, MaxBenchmarkConcurrency)
+	case r.Requests < 1 || r.Requests > MaxBenchmarkRequests:
+		return fmt.Errorf("%w: requests must be between 1 and %d", ErrInvalidBenchmark, MaxBenchmarkRequests)
+	}
+	return nil
+}
+
+// benchmarkOperators cycles through the operators so rule cost reflects a mix of comparisons
+var benchmarkOperators = []string{
+	flag.OperatorEquals, flag.OperatorIn, flag.OperatorGreaterThan,
+	flag.OperatorNotEquals, flag.OperatorNotIn, flag.OperatorLessThan,
+}
+
+// benchmarkFlags builds enabled flags with the requested rules, each rule on its own attribute
+func benchmarkFlags(req BenchmarkRequest) []flag.Flag {
+	flags := make([]flag.Flag, req.Flags)
+	for i := range flags {
+		rules := make([]flag.Rule, req.RulesPerFlag)
+		for j := range rules {
+			op := benchmarkOperators[(i+j)%len(benchmarkOperators)]
+			rules[j] = flag.Rule{
+				ID:        fmt.Sprintf("rule-%d", j),
+				Attribute: fmt.Sprintf("attr_%d", j),
+				Operator:  op,
+				Value:     benchmarkRuleValue(op, j),
+				Rollout:   50 + (i+j)%51,
+				Order:     j,
+			}
+		}
+		flags[i] = flag.Flag{
+			ID:        fmt.Sprintf("benchmark-flag-%d", i),
+			Name:      fmt.Sprintf("benchmark-flag-%d", i),
+			Enabled:   true,
+			Rules:     rules,
+			RuleLogic: req.RuleLogic,
+			Lifecycle: flag.LifecycleActive,
+		}
+	}
+	return flags
+}
+
+func benchmarkRuleValue(operator string, j int) interface{} {
+	switch operator {
+	case flag.OperatorIn, flag.OperatorNotIn:
+		return []interface{}{fmt.Sprintf("value-%d", j), "value-x", "value-y"}
+	case flag.OperatorGreaterThan, flag.OperatorLessThan:
+		return float64(50)
+	}
+	return fmt.Sprintf("value-%d", j)
+}
+
+// benchmarkContext is a synthetic user whose attributes match some rules and miss others
+func benchmarkContext(i int) EvaluationContext {
+	attributes := make(map[string]interface{}, MaxBenchmarkRulesPerFlag)
+	for j := 0; j < MaxBenchmarkRulesPerFlag; j++ {
+		if (i+j)%2 == 0 {
+			attributes[fmt.Sprintf("attr_%d", j)] = fmt.Sprintf("value-%d", j)
+		} else {
+			attributes[fmt.Sprintf("attr_%d", j)] = float64(i % 100)
+		}
+	}
+	return EvaluationContext{UserID: fmt.Sprintf("benchmark-user-%d", i), Attributes: attributes}
+}
+
+// percentileMicros returns the p-th percentile of sorted latencies in microseconds
+func percentileMicros(sorted []time.Duration, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p + 99) / 100
+	i = max(min(i, len(sorted)), 1)
+	return float64(sorted[i-1].Nanoseconds()) / 1000
+}
@@ -0,0 +1,100 @@
+package evaluation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmarker_Run(t *testing.T) {
+	b := NewBenchmarker()
+
+	result, err := b.Run(context.Background(), BenchmarkRequest{Flags: 10, RulesPerFlag: 2, Concurrency: 2, Requests: 200})
+
+	require.NoError(t, err)
+	assert.Equal(t, "AND", result.RuleLogic, "rule logic defaults to AND")
+	assert.Equal(t, 200, result.CompletedRequests)
+	assert.Equal(t, int64(2000), result.Evaluations)
+	assert.False(t, result.TimedOut)
+	assert.Greater(t, result.RequestsPerSecond, 0.0)
+	assert.Greater(t, result.Latency.Max, 0.0)
+	assert.LessOrEqual(t, result.Latency.P50, result.Latency.P99)
+	assert.LessOrEqual(t, result.Latency.P99, result.Latency.Max)
+}
+
+func TestBenchmarker_RunSamplesLatenciesOfLargeRuns(t *testing.T) {
+	b := NewBenchmarker()
+
+	result, err := b.Run(context.Background(), BenchmarkRequest{Flags: 1, RulesPerFlag: 1, Concurrency: 4, Requests: 3*maxBenchmarkSamples + 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3*maxBenchmarkSamples+1, result.CompletedRequests)
+	assert.Greater(t, result.Latency.P50, 0.0)
+	assert.LessOrEqual(t, result.Latency.P99, result.Latency.Max)
+}
+
+func TestBenchmarker_RunRejectsOutOfBoundsRequests(t *testing.T) {
+	b := NewBenchmarker()
+
+	for _, req := range []BenchmarkRequest{
+		{Flags: MaxBenchmarkFlags + 1},
+		{RulesPerFlag: -1},
+		{RuleLogic: "XOR"},
+		{Concurrency: MaxBenchmarkConcurrency + 1},
+		{Requests: -5},
+	} {
+		_, err := b.Run(context.Background(), req)
+		assert.ErrorIs(t, err, ErrInvalidBenchmark, "%+v", req)
+	}
+}
+
+func TestBenchmarker_RunsOneAtATime(t *testing.T) {
+	b := NewBenchmarker()
+	b.running.Lock()
+	defer b.running.Unlock()
+
+	_, err := b.Run(context.Background(), BenchmarkRequest{})
+
+	assert.ErrorIs(t, err, ErrBenchmarkRunning)
+}
+
+func TestBenchmarker_StopsWhenContextEnds(t *testing.T) {
+	b := NewBenchmarker()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	result, err := b.Run(ctx, BenchmarkRequest{Flags: MaxBenchmarkFlags, RulesPerFlag: MaxBenchmarkRulesPerFlag, Requests: MaxBenchmarkRequests})
+
+	require.NoError(t, err)
+	assert.True(t, result.TimedOut)
+	assert.Less(t, result.CompletedRequests, MaxBenchmarkRequests)
+}
+
+func TestBenchmarkHandler_RequiresToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewBenchmarkHandler(NewBenchmarker(), "benchmark-token").RegisterRoutes(router.Group(""))
+
+	for _, tt := range []struct {
+		header string
+		want   int
+	}{
+		{header: "", want: http.StatusUnauthorized},
+		{header: "Bearer wrong-token", want: http.StatusUnauthorized},
+		{header: "Bearer benchmark-token", want: http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/evaluation-benchmark", strings.NewReader(`{"flags":1,"requests":10}`))
+		req.Header.Set("Authorization", tt.header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.want, w.Code, "Authorization %q", tt.header)
+	}
+}
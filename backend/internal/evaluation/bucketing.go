@@ -0,0 +1,153 @@
+package evaluation
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+	"slices"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// Bucketing algorithms a project can choose from
+const (
+	BucketingSHA256  = "sha256"  // SHA-256 of user, flag ID and salt, mod 101; the default
+	BucketingMurmur3 = "murmur3" // murmur3 of flag key and user, mod 100 plus 1, like Unleash SDKs
+	// BucketingSHA256Strict is sha256 with its bucket 0 spread over 1-100. Projects can't choose
+	// it: tenants that opt into strict rollouts get it in place of sha256.
+	BucketingSHA256Strict = "sha256_strict"
+)
+
+// BucketingAlgorithms lists the bucketing algorithms a project can choose
+var BucketingAlgorithms = []string{BucketingSHA256, BucketingMurmur3}
+
+// StrictBucketing returns the variant of a project's algorithm used when its tenant has
+// opted into strict rollouts. murmur3 already buckets strictly, so only sha256 changes.
+func StrictBucketing(algorithm string) string {
+	if algorithm == "" || algorithm == BucketingSHA256 {
+		return BucketingSHA256Strict
+	}
+	return algorithm
+}
+
+// Bucketer assigns a user a deterministic rollout bucket for a flag. A user is in a
+// rule's rollout when their bucket is at most the rule's rollout percentage, so bucketers
+// that start at bucket 1 admit no one to a 0% rollout. Changing
+// the flag's salt reassigns every user's bucket.
+type Bucketer interface {
+	Bucket(f *flag.Flag, userID string) int
+}
+
+// BucketerFor returns the bucketer for an algorithm; unknown or empty names get the default
+func BucketerFor(algorithm string) Bucketer {
+	switch algorithm {
+	case BucketingMurmur3:
+		return murmur3Bucketer{}
+	case BucketingSHA256Strict:
+		return sha256StrictBucketer{}
+	}
+	return sha256Bucketer{}
+}
+
+// IsBucketingAlgorithm reports whether algorithm is one of BucketingAlgorithms
+func IsBucketingAlgorithm(algorithm string) bool {
+	return slices.Contains(BucketingAlgorithms, algorithm)
+}
+
+// sha256Bucketer maps users to buckets 0-100. Rollout 0 still admits bucket 0.
+type sha256Bucketer struct{}
+
+func (sha256Bucketer) Bucket(f *flag.Flag, userID string) int {
+	return sha256Bucket(userID, f.ID, f.Salt)
+}
+
+// sha256Bucket hashes "<user>:<flag ID>", suffixed with ":<salt>" once the flag has a salt
+// so flags that were never reshuffled keep their buckets
+func sha256Bucket(userID, flagID, salt string) int {
+	hash := sha256Hash(userID, flagID, salt)
+	return int(binary.BigEndian.Uint64(hash[:8]) % 101)
+}
+
+func sha256Hash(userID, flagID, salt string) [sha256.Size]byte {
+	input := userID + ":" + flagID
+	if salt != "" {
+		input += ":" + salt
+	}
+	return sha256.Sum256([]byte(input))
+}
+
+// sha256StrictBucketer maps users to buckets 1-100, so rollout N% admits exactly the users
+// whose 0-99 position is strictly less than N: 0% admits nobody and each bucket holds 1%
+// of users. Users keep their sha256 bucket, except those in bucket 0, whom sha256 admits
+// to every rollout; they are spread over 1-100 by the next 8 bytes of the same hash.
+// Switching a tenant to strict rollouts therefore only moves about 1% of users.
+type sha256StrictBucketer struct{}
+
+func (sha256StrictBucketer) Bucket(f *flag.Flag, userID string) int {
+	hash := sha256Hash(userID, f.ID, f.Salt)
+	if bucket := int(binary.BigEndian.Uint64(hash[:8]) % 101); bucket != 0 {
+		return bucket
+	}
+	return int(binary.BigEndian.Uint64(hash[8:16])%100) + 1
+}
+
+// murmur3Bucketer buckets users the way Unleash SDKs do for a rollout whose groupId is the
+// flag key (Unleash's default): 32-bit murmur3 (seed 0) of "<group>:<user>", mod 100, plus 1.
+// A rollout of N% therefore admits the same users in Toggle and Unleash. Flags without a key
+// are grouped by ID; salted flags use "<flag key>.<salt>" as the group, so they no longer
+// match Unleash unless its strategy's groupId is changed to the same value.
+type murmur3Bucketer struct{}
+
+func (murmur3Bucketer) Bucket(f *flag.Flag, userID string) int {
+	group := f.Key
+	if group == "" {
+		group = f.ID
+	}
+	if f.Salt != "" {
+		group += "." + f.Salt
+	}
+	return int(murmur3([]byte(group+":"+userID), 0)%100) + 1
+}
+
+// murmur3 is the x86 32-bit variant of MurmurHash3
+func murmur3(data []byte, seed uint32) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+
+	h := seed
+	blocks := len(data) / 4
+	for i := 0; i < blocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[blocks*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
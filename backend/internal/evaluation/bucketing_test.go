@@ -0,0 +1,198 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMurmur3_MatchesReferenceVectors(t *testing.T) {
+	assert.Equal(t, uint32(0), murmur3([]byte(""), 0))
+	assert.Equal(t, uint32(0x248bfa47), murmur3([]byte("hello"), 0))
+	assert.Equal(t, uint32(0x2e4ff723), murmur3([]byte("The quick brown fox jumps over the lazy dog"), 0))
+}
+
+func TestMurmur3Bucketer_MatchesUnleash(t *testing.T) {
+	b := BucketerFor(BucketingMurmur3)
+
+	// The normalized values Unleash's Node, Java and Go clients are tested against
+	assert.Equal(t, 73, b.Bucket(&flag.Flag{ID: "flag-1", Key: "gr1"}, "123"))
+	assert.Equal(t, 25, b.Bucket(&flag.Flag{ID: "flag-1", Key: "groupX"}, "999"))
+}
+
+func TestBucketerFor_DefaultsToSHA256(t *testing.T) {
+	f := &flag.Flag{ID: "flag-1", Key: "new-checkout"}
+
+	assert.Equal(t, sha256Bucket("user-1", "flag-1", ""), BucketerFor("").Bucket(f, "user-1"))
+	assert.Equal(t, sha256Bucket("user-1", "flag-1", ""), BucketerFor("unknown").Bucket(f, "user-1"))
+}
+
+func TestMurmur3Bucketer_RolloutAdmitsItsPercentage(t *testing.T) {
+	b := BucketerFor(BucketingMurmur3)
+	f := &flag.Flag{ID: "flag-1", Key: "new-checkout"}
+
+	admitted := map[int]int{0: 0, 25: 0, 100: 0}
+	const users = 10000
+	for i := 0; i < users; i++ {
+		bucket := b.Bucket(f, fmt.Sprintf("user-%d", i))
+		require.GreaterOrEqual(t, bucket, 1)
+		require.LessOrEqual(t, bucket, 100)
+		for rollout := range admitted {
+			if bucket <= rollout {
+				admitted[rollout]++
+			}
+		}
+	}
+
+	assert.Zero(t, admitted[0], "a 0% rollout must admit nobody")
+	assert.InDelta(t, users/4, admitted[25], users*0.02)
+	assert.Equal(t, users, admitted[100])
+}
+
+func TestSHA256StrictBucketer_OnlyMovesBucketZero(t *testing.T) {
+	strict := BucketerFor(StrictBucketing(BucketingSHA256))
+	f := &flag.Flag{ID: "flag-1", Salt: "5f0c"}
+
+	admitted := map[int]int{0: 0, 25: 0, 100: 0}
+	moved := 0
+	const users = 10000
+	for i := 0; i < users; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		bucket := strict.Bucket(f, userID)
+		require.GreaterOrEqual(t, bucket, 1)
+		require.LessOrEqual(t, bucket, 100)
+		for rollout := range admitted {
+			if bucket <= rollout {
+				admitted[rollout]++
+			}
+		}
+
+		if legacy := sha256Bucket(userID, f.ID, f.Salt); legacy != bucket {
+			require.Zero(t, legacy, "only users in sha256's bucket 0 may move")
+			moved++
+		}
+	}
+
+	assert.Zero(t, admitted[0], "a 0% rollout must admit nobody")
+	assert.InDelta(t, users/4, admitted[25], users*0.02)
+	assert.Equal(t, users, admitted[100])
+	assert.InDelta(t, users/101, moved, users*0.005)
+
+	assert.Equal(t, BucketingMurmur3, StrictBucketing(BucketingMurmur3), "murmur3 is already strict")
+	assert.Equal(t, BucketingSHA256Strict, StrictBucketing(""))
+}
+
+func TestMurmur3Bucketer_HashesFlagKey(t *testing.T) {
+	b := BucketerFor(BucketingMurmur3)
+
+	// Flags keep their buckets when imported under a new ID with the same key
+	assert.Equal(t,
+		b.Bucket(&flag.Flag{ID: "flag-1", Key: "new-checkout"}, "user-1"),
+		b.Bucket(&flag.Flag{ID: "flag-2", Key: "new-checkout"}, "user-1"),
+	)
+	assert.Equal(t,
+		b.Bucket(&flag.Flag{ID: "flag-1"}, "user-1"),
+		b.Bucket(&flag.Flag{ID: "flag-1", Key: "flag-1"}, "user-1"),
+		"flags without a key are hashed by ID",
+	)
+}
+
+func TestBucketers_SaltReshufflesUsers(t *testing.T) {
+	for _, algorithm := range BucketingAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			b := BucketerFor(algorithm)
+			unsalted := &flag.Flag{ID: "flag-1", Key: "new-checkout"}
+			salted := &flag.Flag{ID: "flag-1", Key: "new-checkout", Salt: "5f0c"}
+
+			moved := 0
+			const users = 1000
+			for i := 0; i < users; i++ {
+				userID := fmt.Sprintf("user-%d", i)
+				require.Equal(t, b.Bucket(salted, userID), b.Bucket(salted, userID), "a salted bucket is still stable")
+				if b.Bucket(unsalted, userID) != b.Bucket(salted, userID) {
+					moved++
+				}
+			}
+			assert.Greater(t, moved, users*9/10, "a new salt should move almost every user")
+		})
+	}
+
+	// Flags that were never reshuffled keep their buckets
+	assert.Equal(t, sha256Bucket("user-1", "flag-1", ""), BucketerFor(BucketingSHA256).Bucket(&flag.Flag{ID: "flag-1"}, "user-1"))
+
+	// Flags evaluated from a cached snapshot keep their salt
+	flags := flagsFromSnapshot(&Snapshot{Flags: []SnapshotFlag{{ID: "flag-1", Salt: "5f0c"}}}, time.Now())
+	assert.Equal(t, "5f0c", flags[0].Salt)
+}
+
+func TestEvaluator_AnonymousContextsBucketOnKey(t *testing.T) {
+	e := NewEvaluator()
+	f := &flag.Flag{ID: "flag-1", Key: "new-checkout"}
+
+	device := EvaluationContext{Anonymous: true, Key: "device-42"}
+	require.NoError(t, device.Validate())
+	assert.Equal(t, "device-42", device.BucketingKey())
+	assert.Equal(t, e.bucket(f, device), e.bucket(f, device), "an anonymous device keeps its bucket")
+	assert.Equal(t, sha256Bucket("device-42", "flag-1", ""), e.bucket(f, device))
+
+	user := EvaluationContext{UserID: "user-1", Key: "device-42"}
+	assert.Equal(t, "user-1", user.BucketingKey(), "known users bucket on their user ID")
+
+	for _, ctx := range []EvaluationContext{
+		{},
+		{Attributes: map[string]interface{}{"plan": "pro"}},
+		{Anonymous: true},
+		{Anonymous: true, Key: "device-42", UserID: "user-1"},
+	} {
+		assert.ErrorIs(t, ctx.Validate(), ErrInvalidContext)
+	}
+}
+
+func TestService_EvaluateAll_UsesProjectBucketing(t *testing.T) {
+	// A 30% rollout: find a user the two algorithms disagree on
+	f := flag.Flag{ID: "flag-1", Key: "new-checkout", Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{
+		{ID: "r1", Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 30},
+	}}
+	userID := ""
+	for i := 0; userID == ""; i++ {
+		candidate := fmt.Sprintf("user-%d", i)
+		if (BucketerFor(BucketingSHA256).Bucket(&f, candidate) <= 30) != (BucketerFor(BucketingMurmur3).Bucket(&f, candidate) <= 30) {
+			userID = candidate
+		}
+	}
+	want := BucketerFor(BucketingMurmur3).Bucket(&f, userID) <= 30
+
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{f}, nil
+		},
+	}
+	evalCtx := EvaluationContext{UserID: userID, Attributes: map[string]interface{}{"plan": "pro"}}
+
+	t.Run("uncached", func(t *testing.T) {
+		svc := NewService(flags, &mockProjectReader{bucketing: BucketingMurmur3}, discardLogger())
+
+		resp, err := svc.EvaluateAll(sdkContext(), "project-1", evalCtx)
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.Flags["flag-1"])
+	})
+
+	t.Run("from snapshot", func(t *testing.T) {
+		svc := NewService(flags, &mockProjectReader{generations: []int64{1}, bucketing: BucketingMurmur3}, discardLogger())
+		svc.SetSnapshotCache(NewSnapshotCache(discardLogger()), nil)
+
+		resp, err := svc.EvaluateAll(sdkContext(), "project-1", evalCtx)
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.Flags["flag-1"])
+
+		snapshot, err := svc.Snapshot(sdkContext(), "project-1")
+		require.NoError(t, err)
+		assert.Equal(t, BucketingMurmur3, snapshot.Bucketing)
+		assert.Equal(t, "new-checkout", snapshot.Flags[0].Key)
+	})
+}
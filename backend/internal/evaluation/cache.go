@@ -0,0 +1,214 @@
+package evaluation
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// PrimeTimeout bounds how long a new instance waits on persisted snapshots before serving SDK traffic
+const PrimeTimeout = 30 * time.Second
+
+// PrimeMaxAge skips persisted snapshots of projects nobody has evaluated recently
+const PrimeMaxAge = 7 * 24 * time.Hour
+
+// StoredSnapshot is a persisted project snapshot and the tenant it belongs to
+type StoredSnapshot struct {
+	TenantID string
+	Snapshot *Snapshot
+}
+
+// SnapshotStore persists the latest snapshot of each project so new instances start warm
+type SnapshotStore interface {
+	SaveSnapshot(ctx context.Context, tenantID string, snapshot *Snapshot) error
+	ListSnapshots(ctx context.Context, since time.Time) ([]StoredSnapshot, error)
+}
+
+// SnapshotCache holds the latest snapshot of each project in memory. An entry is served
+// for the generation it was built at, or, when its project tolerates staleness, for a
+// while after its generation was last confirmed current.
+type SnapshotCache struct {
+	logger *slog.Logger
+	ready  atomic.Bool
+
+	mu      sync.RWMutex
+	entries map[string]cacheEntry // by project ID
+}
+
+// cacheEntry is a cached snapshot and when its generation was last confirmed current
+type cacheEntry struct {
+	StoredSnapshot
+	validatedAt time.Time
+	// maxStaleness is how long the project tolerates serving the entry without revalidating
+	maxStaleness time.Duration
+}
+
+func NewSnapshotCache(logger *slog.Logger) *SnapshotCache {
+	return &SnapshotCache{
+		logger:  logger,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the project's cached snapshot if it belongs to the tenant and is at generation
+func (c *SnapshotCache) Get(projectID string, tenantID string, generation int64) (*Snapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[projectID]
+	if !ok || entry.TenantID != tenantID || entry.Snapshot.Generation != generation {
+		return nil, false
+	}
+	return entry.Snapshot, true
+}
+
+// Fresh returns the project's cached snapshot without revalidation, with how long ago it
+// was last confirmed current, if that is within the project's staleness tolerance
+func (c *SnapshotCache) Fresh(projectID string, tenantID string, now time.Time) (*Snapshot, time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[projectID]
+	if !ok || entry.TenantID != tenantID || entry.maxStaleness <= 0 {
+		return nil, 0, false
+	}
+	staleness := now.Sub(entry.validatedAt)
+	if staleness > entry.maxStaleness {
+		return nil, 0, false
+	}
+	return entry.Snapshot, staleness, true
+}
+
+// Validate records that the cached snapshot of a project is current at generation as of now,
+// and how long the project tolerates serving it without revalidating
+func (c *SnapshotCache) Validate(projectID string, generation int64, maxStaleness time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[projectID]
+	if !ok || entry.Snapshot.Generation != generation {
+		return
+	}
+	entry.validatedAt = now
+	entry.maxStaleness = maxStaleness
+	c.entries[projectID] = entry
+}
+
+// InvalidateTenant makes the tenant's cached snapshots revalidate their generation before
+// they are served again, so projects that tolerate staleness see flag changes made through
+// this instance immediately. Snapshots that are still current stay cached.
+func (c *SnapshotCache) InvalidateTenant(tenantID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for projectID, entry := range c.entries {
+		if entry.TenantID == tenantID {
+			entry.validatedAt = time.Time{}
+			c.entries[projectID] = entry
+		}
+	}
+}
+
+// Put caches a snapshot unless a newer generation of the project is already cached.
+// The snapshot counts as validated when it was generated, keeping the project's tolerance.
+func (c *SnapshotCache) Put(tenantID string, snapshot *Snapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, ok := c.entries[snapshot.ProjectID]
+	if ok && previous.Snapshot.Generation > snapshot.Generation {
+		return
+	}
+	c.entries[snapshot.ProjectID] = cacheEntry{
+		StoredSnapshot: StoredSnapshot{TenantID: tenantID, Snapshot: snapshot},
+		validatedAt:    snapshot.GeneratedAt,
+		maxStaleness:   previous.maxStaleness,
+	}
+}
+
+// Len returns the number of cached projects
+func (c *SnapshotCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Ready reports whether priming has finished, successfully or not
+func (c *SnapshotCache) Ready() bool {
+	return c.ready.Load()
+}
+
+// Prime loads recently persisted snapshots into the cache, then marks it ready.
+// A failed prime still marks the cache ready: evaluation falls back to Postgres.
+func (c *SnapshotCache) Prime(ctx context.Context, store SnapshotStore) {
+	defer c.ready.Store(true)
+
+	start := time.Now()
+	snapshots, err := store.ListSnapshots(ctx, start.Add(-PrimeMaxAge))
+	if err != nil {
+		c.logger.Error("failed to prime evaluation cache, serving from postgres",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, stored := range snapshots {
+		c.Put(stored.TenantID, stored.Snapshot)
+	}
+
+	c.logger.Info("evaluation cache primed",
+		slog.Int("projects", len(snapshots)),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// flagsFromSnapshot returns the evaluable flags of a snapshot at now. Archived flags
+// whose grace period has ended are left out, even if the snapshot is still current.
+func flagsFromSnapshot(snapshot *Snapshot, now time.Time) []flag.Flag {
+	flags := make([]flag.Flag, 0, len(snapshot.Flags))
+	for _, sf := range snapshot.Flags {
+		if sf.ArchivedUntil != nil && !now.Before(*sf.ArchivedUntil) {
+			continue
+		}
+		projectID := snapshot.ProjectID
+		f := flag.Flag{
+			ID:        sf.ID,
+			Key:       sf.Key,
+			Name:      sf.Name,
+			ProjectID: &projectID,
+			Enabled:   sf.Enabled,
+			Rules:     sf.Rules,
+			RuleLogic: sf.RuleLogic,
+			UpdatedAt: sf.UpdatedAt,
+			Salt:      sf.Salt,
+		}
+		if sf.ArchivedUntil != nil {
+			f.Lifecycle = flag.LifecycleArchived
+		}
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// snapshotTargeting returns the flags of a snapshot the user is excluded from and the
+// user's overrides that are still active at now, keyed by flag ID
+func snapshotTargeting(snapshot *Snapshot, userKey string, now time.Time) ([]string, map[string]bool) {
+	var excluded []string
+	overrides := make(map[string]bool)
+	for _, sf := range snapshot.Flags {
+		if slices.Contains(sf.ExcludedUserKeys, userKey) {
+			excluded = append(excluded, sf.ID)
+		}
+		for _, o := range sf.Overrides {
+			if o.UserKey == userKey && now.Before(o.ExpiresAt) {
+				overrides[sf.ID] = o.Enabled
+			}
+		}
+	}
+	return excluded, overrides
+}
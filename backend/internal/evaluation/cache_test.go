@@ -0,0 +1,302 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+type memorySnapshotStore struct {
+	saved   []StoredSnapshot
+	listErr error
+}
+
+func (m *memorySnapshotStore) SaveSnapshot(ctx context.Context, tenantID string, snapshot *Snapshot) error {
+	m.saved = append(m.saved, StoredSnapshot{TenantID: tenantID, Snapshot: snapshot})
+	return nil
+}
+
+func (m *memorySnapshotStore) ListSnapshots(ctx context.Context, since time.Time) ([]StoredSnapshot, error) {
+	return m.saved, m.listErr
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestSnapshotCache_ServesOnlyMatchingGenerationAndTenant(t *testing.T) {
+	cache := NewSnapshotCache(discardLogger())
+	cache.Put("tenant-1", &Snapshot{ProjectID: "project-1", Generation: 5})
+
+	_, ok := cache.Get("project-1", "tenant-1", 5)
+	assert.True(t, ok)
+
+	_, ok = cache.Get("project-1", "tenant-1", 6)
+	assert.False(t, ok, "a newer generation must miss")
+
+	_, ok = cache.Get("project-1", "tenant-2", 5)
+	assert.False(t, ok, "another tenant must miss")
+}
+
+func TestSnapshotCache_KeepsNewestGeneration(t *testing.T) {
+	cache := NewSnapshotCache(discardLogger())
+	cache.Put("tenant-1", &Snapshot{ProjectID: "project-1", Generation: 5})
+	cache.Put("tenant-1", &Snapshot{ProjectID: "project-1", Generation: 4})
+
+	_, ok := cache.Get("project-1", "tenant-1", 5)
+	assert.True(t, ok)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestSnapshotCache_Prime(t *testing.T) {
+	store := &memorySnapshotStore{saved: []StoredSnapshot{
+		{TenantID: "tenant-1", Snapshot: &Snapshot{ProjectID: "project-1", Generation: 3}},
+		{TenantID: "tenant-2", Snapshot: &Snapshot{ProjectID: "project-2", Generation: 9}},
+	}}
+	cache := NewSnapshotCache(discardLogger())
+	require.False(t, cache.Ready())
+
+	cache.Prime(context.Background(), store)
+
+	assert.True(t, cache.Ready())
+	assert.Equal(t, 2, cache.Len())
+	_, ok := cache.Get("project-2", "tenant-2", 9)
+	assert.True(t, ok)
+}
+
+func TestSnapshotCache_PrimeFailureStillBecomesReady(t *testing.T) {
+	cache := NewSnapshotCache(discardLogger())
+
+	cache.Prime(context.Background(), &memorySnapshotStore{listErr: errors.New("connection refused")})
+
+	assert.True(t, cache.Ready())
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestService_EvaluateAll_ServesFromPrimedCache(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			t.Error("expected flags to come from the cache")
+			return nil, nil
+		},
+	}
+	store := &memorySnapshotStore{saved: []StoredSnapshot{{TenantID: "tenant-1", Snapshot: &Snapshot{
+		ProjectID:  "project-1",
+		Generation: 7,
+		Flags:      []SnapshotFlag{{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}},
+	}}}}
+	cache := NewSnapshotCache(discardLogger())
+	cache.Prime(context.Background(), store)
+
+	svc := NewService(flags, &mockProjectReader{generations: []int64{7}}, discardLogger())
+	svc.SetSnapshotCache(cache, store)
+
+	resp, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"flag-1": true}, resp.Flags)
+}
+
+func TestService_EvaluateAll_RebuildsAndPersistsStaleSnapshot(t *testing.T) {
+	reads := 0
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			reads++
+			return []flag.Flag{{ID: "flag-1", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+	}
+	store := &memorySnapshotStore{}
+	cache := NewSnapshotCache(discardLogger())
+	cache.Put("tenant-1", &Snapshot{ProjectID: "project-1", Generation: 7,
+		Flags: []SnapshotFlag{{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}}})
+
+	svc := NewService(flags, &mockProjectReader{generations: []int64{8}}, discardLogger())
+	svc.SetSnapshotCache(cache, store)
+
+	resp, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"flag-1": false}, resp.Flags)
+	assert.Equal(t, 1, reads)
+	require.Len(t, store.saved, 1)
+	assert.Equal(t, int64(8), store.saved[0].Snapshot.Generation)
+
+	_, ok := cache.Get("project-1", "tenant-1", 8)
+	assert.True(t, ok, "the rebuilt snapshot must be cached")
+}
+
+func TestSnapshotCache_Fresh_HonoursStalenessTolerance(t *testing.T) {
+	cache := NewSnapshotCache(discardLogger())
+	validated := time.Now()
+	cache.Put("tenant-1", &Snapshot{ProjectID: "project-1", Generation: 3, GeneratedAt: validated})
+
+	_, _, ok := cache.Fresh("project-1", "tenant-1", validated)
+	assert.False(t, ok, "projects without a tolerance always revalidate")
+
+	cache.Validate("project-1", 3, 5*time.Second, validated)
+
+	_, staleness, ok := cache.Fresh("project-1", "tenant-1", validated.Add(4*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 4*time.Second, staleness)
+
+	_, _, ok = cache.Fresh("project-1", "tenant-1", validated.Add(6*time.Second))
+	assert.False(t, ok, "entries past the tolerance must be revalidated")
+
+	_, _, ok = cache.Fresh("project-1", "tenant-2", validated)
+	assert.False(t, ok, "entries are never served to another tenant")
+
+	cache.Validate("project-1", 2, time.Minute, validated.Add(10*time.Second))
+	_, _, ok = cache.Fresh("project-1", "tenant-1", validated.Add(10*time.Second))
+	assert.False(t, ok, "validating another generation must not refresh the entry")
+}
+
+func TestSnapshotCache_InvalidateTenant_ForcesRevalidation(t *testing.T) {
+	cache := NewSnapshotCache(discardLogger())
+	validated := time.Now()
+	cache.Put("tenant-1", &Snapshot{ProjectID: "project-1", Generation: 3, GeneratedAt: validated})
+	cache.Put("tenant-2", &Snapshot{ProjectID: "project-2", Generation: 1, GeneratedAt: validated})
+	cache.Validate("project-1", 3, time.Minute, validated)
+	cache.Validate("project-2", 1, time.Minute, validated)
+
+	cache.InvalidateTenant("tenant-1")
+
+	_, _, ok := cache.Fresh("project-1", "tenant-1", validated)
+	assert.False(t, ok, "invalidated entries must revalidate before they are served")
+	_, ok = cache.Get("project-1", "tenant-1", 3)
+	assert.True(t, ok, "an invalidated entry is still served once its generation is confirmed")
+	_, _, ok = cache.Fresh("project-2", "tenant-2", validated)
+	assert.True(t, ok, "other tenants' entries are untouched")
+}
+
+func TestService_EvaluateAll_SkipsGenerationReadWithinTolerance(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+	}
+	projects := &mockProjectReader{generations: []int64{4, 5}, maxStaleness: time.Minute}
+	svc := NewService(flags, projects, discardLogger())
+	svc.SetSnapshotCache(NewSnapshotCache(discardLogger()), nil)
+
+	first, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.Zero(t, first.Staleness)
+	calls := projects.calls
+
+	second, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.Equal(t, calls, projects.calls, "the generation must not be read again within the tolerance")
+	assert.Equal(t, first.Flags, second.Flags)
+	assert.Positive(t, second.Staleness)
+}
+
+func TestService_EvaluateAll_ReturnsPartialPastBudgetAndWarmsCache(t *testing.T) {
+	release := make(chan struct{})
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			<-release
+			return []flag.Flag{{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+	}
+	cache := NewSnapshotCache(discardLogger())
+	svc := NewService(flags, &mockProjectReader{generations: []int64{3}}, discardLogger())
+	svc.SetSnapshotCache(cache, nil)
+	svc.SetEvaluationBudget(10 * time.Millisecond)
+
+	first, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.True(t, first.Partial)
+	assert.Empty(t, first.Flags)
+
+	close(release)
+	require.Eventually(t, func() bool { return cache.Len() == 1 }, time.Second, time.Millisecond,
+		"the load must keep running after the budget to warm the cache")
+
+	second, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.False(t, second.Partial)
+	assert.Equal(t, map[string]bool{"flag-1": true}, second.Flags)
+}
+
+func TestService_CacheHitTargetsUsersFromSnapshot(t *testing.T) {
+	now := time.Now()
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			t.Error("a cache hit must not read flags")
+			return nil, nil
+		},
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			projectID := "project-1"
+			return &flag.Flag{ID: id, ProjectID: &projectID, Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"}, nil
+		},
+	}
+	cache := NewSnapshotCache(discardLogger())
+	cache.Put("tenant-1", &Snapshot{
+		ProjectID:   "project-1",
+		Generation:  2,
+		GeneratedAt: now,
+		Flags: []SnapshotFlag{
+			{ID: "excluded", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND", ExcludedUserKeys: []string{"user-1"}},
+			{ID: "overridden", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND",
+				Overrides: []SnapshotOverride{{UserKey: "user-1", Enabled: true, ExpiresAt: now.Add(time.Hour)}}},
+			{ID: "expired", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND",
+				Overrides: []SnapshotOverride{{UserKey: "user-1", Enabled: true, ExpiresAt: now.Add(-time.Minute)}}},
+		},
+	})
+	cache.Validate("project-1", 2, time.Minute, now)
+	// No generations: reading one would panic
+	svc := NewService(flags, &mockProjectReader{}, discardLogger())
+	svc.SetSnapshotCache(cache, nil)
+
+	resp, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"excluded": false, "overridden": true, "expired": false}, resp.Flags)
+
+	single, err := svc.EvaluateSingle(sdkContext(), "overridden", "tenant-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.True(t, single.Enabled)
+
+	assert.Zero(t, flags.targetingReads, "exclusions and overrides must come from the snapshot")
+}
+
+func TestService_EvaluateAll_OverBudgetRequestsShareOneLoad(t *testing.T) {
+	release := make(chan struct{})
+	var loads atomic.Int32
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			loads.Add(1)
+			<-release
+			return []flag.Flag{{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+	}
+	cache := NewSnapshotCache(discardLogger())
+	svc := NewService(flags, &mockProjectReader{generations: []int64{3}}, discardLogger())
+	svc.SetSnapshotCache(cache, nil)
+	svc.SetEvaluationBudget(10 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+			assert.NoError(t, err)
+			assert.True(t, resp.Partial)
+		}()
+	}
+	wg.Wait()
+
+	close(release)
+	require.Eventually(t, func() bool { return cache.Len() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), loads.Load(), "requests past the budget must share the pending load")
+}
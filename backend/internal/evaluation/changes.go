@@ -0,0 +1,176 @@
+package evaluation
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Change event types sent to streaming SDKs
+const (
+	EventReady        = "ready"         // sent once on connect, with the current version
+	EventFlagsChanged = "flags_changed" // a flag in the project changed; reload the ruleset or re-evaluate
+	EventHeartbeat    = "heartbeat"     // keeps idle connections open through proxies
+	EventMaintenance  = "maintenance"   // maintenance starts soon or is under way; extend local cache TTLs past its end
+)
+
+// ChangePollInterval is how often watched projects are checked for flag changes
+const ChangePollInterval = time.Second
+
+// changePollTimeout bounds one poll, so a slow database delays events rather than piling up polls
+const changePollTimeout = 5 * time.Second
+
+// ChangeEvent tells a streaming SDK about its project's flags. Events carry no flag data:
+// SDKs fetch the ruleset or evaluate again, so every transport delivers the same thing.
+type ChangeEvent struct {
+	Type      string `json:"type"`
+	ProjectID string `json:"project_id,omitempty"`
+	// Version is the project's flag version, as in Ruleset.Version
+	Version int64 `json:"version,omitempty"`
+	// Maintenance is set on maintenance events
+	Maintenance *MaintenanceNotice `json:"maintenance,omitempty"`
+}
+
+// MaintenanceNotice is planned maintenance as announced to streaming SDKs
+type MaintenanceNotice struct {
+	ID       string    `json:"id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	// Degradation is the expected impact on SDKs, such as delayed_updates
+	Degradation string `json:"degradation"`
+	Message     string `json:"message,omitempty"`
+}
+
+// MaintenanceSource tells streams about planned maintenance
+type MaintenanceSource interface {
+	// Notice returns the maintenance under way or starting soon at now, or nil
+	Notice(now time.Time) *MaintenanceNotice
+}
+
+// ChangeWatcher detects flag changes in projects with connected streaming SDKs. Each
+// watched project's generation is read once per interval however many SDKs subscribe.
+type ChangeWatcher struct {
+	projects ProjectReader
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	watched map[string]*watchedProject
+}
+
+type watchedProject struct {
+	tenantID    string
+	generation  int64
+	subscribers map[chan ChangeEvent]struct{}
+}
+
+func NewChangeWatcher(projects ProjectReader, logger *slog.Logger) *ChangeWatcher {
+	return &ChangeWatcher{
+		projects: projects,
+		logger:   logger,
+		watched:  make(map[string]*watchedProject),
+	}
+}
+
+// Subscribe returns a channel of the project's flag changes and its current version.
+// Events are coalesced: a subscriber that hasn't read the last event misses the next,
+// which only ever says to reload. Call unsubscribe when done.
+func (w *ChangeWatcher) Subscribe(ctx context.Context, projectID, tenantID string) (events <-chan ChangeEvent, version int64, unsubscribe func(), err error) {
+	generation, err := w.projects.GetGeneration(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	ch := make(chan ChangeEvent, 1)
+	w.mu.Lock()
+	p, ok := w.watched[projectID]
+	if !ok {
+		p = &watchedProject{tenantID: tenantID, generation: generation, subscribers: make(map[chan ChangeEvent]struct{})}
+		w.watched[projectID] = p
+	}
+	p.subscribers[ch] = struct{}{}
+	w.mu.Unlock()
+
+	unsubscribe = func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(p.subscribers, ch)
+		if len(p.subscribers) == 0 && w.watched[projectID] == p {
+			delete(w.watched, projectID)
+		}
+	}
+	return ch, generation, unsubscribe, nil
+}
+
+// Subscribers returns how many streams are open across all projects
+func (w *ChangeWatcher) Subscribers() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := 0
+	for _, p := range w.watched {
+		n += len(p.subscribers)
+	}
+	return n
+}
+
+// Poll reads the generation of every watched project and notifies subscribers of those
+// that changed
+func (w *ChangeWatcher) Poll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, changePollTimeout)
+	defer cancel()
+
+	w.mu.Lock()
+	projects := make(map[string]string, len(w.watched))
+	for id, p := range w.watched {
+		projects[id] = p.tenantID
+	}
+	w.mu.Unlock()
+
+	for projectID, tenantID := range projects {
+		generation, err := w.projects.GetGeneration(ctx, projectID, tenantID)
+		if err != nil {
+			// Subscribers keep their streams; the next poll tries again
+			w.logger.Warn("failed to check project for flag changes",
+				slog.String("project_id", projectID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		w.notify(projectID, generation)
+	}
+}
+
+func (w *ChangeWatcher) notify(projectID string, generation int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p, ok := w.watched[projectID]
+	if !ok || generation <= p.generation {
+		return
+	}
+	p.generation = generation
+
+	event := ChangeEvent{Type: EventFlagsChanged, ProjectID: projectID, Version: generation}
+	for ch := range p.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Run polls watched projects every interval until ctx is cancelled
+func (w *ChangeWatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Poll(ctx)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package evaluation
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+// generationReader is a ProjectReader whose generations tests bump concurrently with reads
+type generationReader struct {
+	mockProjectReader
+	mu         sync.Mutex
+	generation map[string]int64
+	reads      int
+}
+
+func (g *generationReader) GetGeneration(ctx context.Context, id string, tenantID string) (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reads++
+	return g.generation[id], nil
+}
+
+func (g *generationReader) bump(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.generation[id]++
+}
+
+func TestChangeWatcher_NotifiesSubscribersOfChangedProjects(t *testing.T) {
+	projects := &generationReader{generation: map[string]int64{"project-1": 4, "project-2": 9}}
+	watcher := NewChangeWatcher(projects, discardLogger())
+
+	first, version, unsubscribeFirst, err := watcher.Subscribe(context.Background(), "project-1", "tenant-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), version)
+	second, _, unsubscribeSecond, err := watcher.Subscribe(context.Background(), "project-1", "tenant-1")
+	require.NoError(t, err)
+	other, _, unsubscribeOther, err := watcher.Subscribe(context.Background(), "project-2", "tenant-1")
+	require.NoError(t, err)
+	defer unsubscribeOther()
+	assert.Equal(t, 3, watcher.Subscribers())
+
+	watcher.Poll(context.Background())
+	assert.Empty(t, first, "unchanged projects must not notify")
+
+	projects.bump("project-1")
+	watcher.Poll(context.Background())
+
+	want := ChangeEvent{Type: EventFlagsChanged, ProjectID: "project-1", Version: 5}
+	assert.Equal(t, want, <-first)
+	assert.Equal(t, want, <-second)
+	assert.Empty(t, other)
+
+	unsubscribeFirst()
+	unsubscribeSecond()
+	assert.Equal(t, 1, watcher.Subscribers())
+
+	reads := projects.reads
+	watcher.Poll(context.Background())
+	assert.Equal(t, reads+1, projects.reads, "projects without subscribers must not be polled")
+}
+
+func TestChangeWatcher_CoalescesUnreadEvents(t *testing.T) {
+	projects := &generationReader{generation: map[string]int64{"project-1": 1}}
+	watcher := NewChangeWatcher(projects, discardLogger())
+
+	events, _, unsubscribe, err := watcher.Subscribe(context.Background(), "project-1", "tenant-1")
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	for range 3 {
+		projects.bump("project-1")
+		watcher.Poll(context.Background())
+	}
+
+	assert.Equal(t, int64(2), (<-events).Version)
+	assert.Empty(t, events)
+}
+
+func TestStreamHandler_WebSocketDeliversChangeEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	projects := &generationReader{generation: map[string]int64{"project-1": 3}}
+	watcher := NewChangeWatcher(projects, discardLogger())
+
+	router := gin.New()
+	sdk := router.Group("/sdk")
+	sdk.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(appContext.WithSDKAuth(c.Request.Context(), "project-1", "tenant-1"))
+	})
+	NewStreamHandler(watcher).RegisterRoutes(sdk)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/sdk/ws", "", "http://sdk.example.com")
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var event ChangeEvent
+	require.NoError(t, websocket.JSON.Receive(conn, &event))
+	assert.Equal(t, ChangeEvent{Type: EventReady, ProjectID: "project-1", Version: 3}, event)
+
+	projects.bump("project-1")
+	watcher.Poll(context.Background())
+
+	require.NoError(t, websocket.JSON.Receive(conn, &event))
+	assert.Equal(t, ChangeEvent{Type: EventFlagsChanged, ProjectID: "project-1", Version: 4}, event)
+
+	conn.Close()
+	require.Eventually(t, func() bool { return watcher.Subscribers() == 0 }, time.Second, time.Millisecond,
+		"closing the connection must unsubscribe")
+}
+
+type fixedMaintenance struct{ notice *MaintenanceNotice }
+
+func (m fixedMaintenance) Notice(now time.Time) *MaintenanceNotice { return m.notice }
+
+func TestStreamHandler_WebSocketAnnouncesMaintenance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	watcher := NewChangeWatcher(&generationReader{generation: map[string]int64{"project-1": 3}}, discardLogger())
+	notice := &MaintenanceNotice{
+		ID:          "window-1",
+		StartsAt:    time.Now().Add(30 * time.Minute).UTC().Truncate(time.Second),
+		EndsAt:      time.Now().Add(90 * time.Minute).UTC().Truncate(time.Second),
+		Degradation: "delayed_updates",
+	}
+
+	router := gin.New()
+	sdk := router.Group("/sdk")
+	sdk.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(appContext.WithSDKAuth(c.Request.Context(), "project-1", "tenant-1"))
+	})
+	handler := NewStreamHandler(watcher)
+	handler.SetMaintenance(fixedMaintenance{notice: notice})
+	handler.RegisterRoutes(sdk)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/sdk/ws", "", "http://sdk.example.com")
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var event ChangeEvent
+	require.NoError(t, websocket.JSON.Receive(conn, &event))
+	assert.Equal(t, EventReady, event.Type)
+
+	var maintenance ChangeEvent
+	require.NoError(t, websocket.JSON.Receive(conn, &maintenance))
+	assert.Equal(t, ChangeEvent{Type: EventMaintenance, Maintenance: notice}, maintenance)
+}
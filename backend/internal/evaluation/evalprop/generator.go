@@ -0,0 +1,166 @@
+// Package evalprop generates random flags and evaluation contexts and checks
+// evaluator invariants against them. It is shared by the evaluator's property
+// and fuzz tests so that new operators are held to the same guarantees.
+package evalprop
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// attribute describes one context attribute the generator draws from
+type attribute struct {
+	name   string
+	values []interface{}
+}
+
+// Numbers are float64, matching what JSON decoding produces for SDK contexts
+var attributes = []attribute{
+	{name: "country", values: []interface{}{"AU", "US", "NZ", "GB", "au"}},
+	{name: "plan", values: []interface{}{"free", "pro", "enterprise"}},
+	{name: "age", values: []interface{}{float64(17), float64(18), float64(30), float64(65)}},
+	{name: "beta", values: []interface{}{true, false}},
+}
+
+// patterns are matches rule values, some matching the string attributes and some not
+var patterns = []interface{}{"^(AU|NZ)$", "^pr", "e", "^[A-Z]{2}$", "xyz"}
+
+// evaluatedAt is when generated contexts are evaluated, so time window rules give the same
+// result on every run
+var evaluatedAt = time.Date(2026, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+// timezones are time window rule timezones
+var timezones = []string{"UTC", "Australia/Sydney", "America/New_York"}
+
+// Generator produces random but reproducible flags and contexts from a seed
+type Generator struct {
+	rng *rand.Rand
+}
+
+func NewGenerator(seed uint64) *Generator {
+	return &Generator{rng: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+var ruleLogics = []string{flag.RuleLogicAnd, flag.RuleLogicOr, flag.RuleLogicFirstMatch}
+
+// Flag returns an enabled or disabled flag with up to four rules under any rule logic
+func (g *Generator) Flag() *flag.Flag {
+	f := &flag.Flag{
+		ID:        fmt.Sprintf("flag-%d", g.rng.IntN(1000)),
+		Enabled:   g.rng.IntN(5) != 0,
+		RuleLogic: ruleLogics[g.rng.IntN(len(ruleLogics))],
+	}
+
+	f.Rules = make([]flag.Rule, g.rng.IntN(5))
+	for i := range f.Rules {
+		// Rule IDs are UUIDs; a fixed prefix keeps them readable in failure output
+		f.Rules[i] = g.Rule(fmt.Sprintf("00000000-0000-4000-8000-%012d", i))
+	}
+	return f
+}
+
+// Rule returns a valid rule using any operator from flag.Operators
+func (g *Generator) Rule(id string) flag.Rule {
+	op := flag.Operators[g.rng.IntN(len(flag.Operators))]
+
+	// Comparisons only make sense against numeric attributes
+	attr := attributes[g.rng.IntN(len(attributes))]
+	if op == flag.OperatorGreaterThan || op == flag.OperatorLessThan {
+		attr = attributes[2]
+	}
+	// Patterns only make sense against string attributes
+	if op == flag.OperatorMatches {
+		attr = attributes[g.rng.IntN(2)]
+	}
+
+	value, err := g.value(op, attr)
+	if err != nil {
+		panic(err)
+	}
+
+	// Time windows match on the evaluation time and have no attribute
+	name := attr.name
+	if op == flag.OperatorTimeWindow {
+		name = ""
+	}
+
+	rule := flag.Rule{
+		ID:        id,
+		Attribute: name,
+		Operator:  op,
+		Value:     value,
+		Rollout:   g.rollout(),
+		Order:     g.rng.IntN(4), // small range so ties are common
+	}
+
+	// Numeric and time comparisons reject case_sensitive, so only string operators may ignore case
+	if op != flag.OperatorGreaterThan && op != flag.OperatorLessThan && op != flag.OperatorTimeWindow && g.rng.IntN(4) == 0 {
+		caseSensitive := false
+		rule.CaseSensitive = &caseSensitive
+	}
+	return rule
+}
+
+// value returns a rule value suitable for an operator.
+// New operators must be added here before they can be generated.
+func (g *Generator) value(op string, attr attribute) (interface{}, error) {
+	switch op {
+	case flag.OperatorEquals, flag.OperatorNotEquals, flag.OperatorGreaterThan, flag.OperatorLessThan:
+		return attr.values[g.rng.IntN(len(attr.values))], nil
+	case flag.OperatorIn, flag.OperatorNotIn:
+		n := 1 + g.rng.IntN(len(attr.values))
+		values := make([]interface{}, n)
+		for i := range values {
+			values[i] = attr.values[g.rng.IntN(len(attr.values))]
+		}
+		return values, nil
+	case flag.OperatorMatches:
+		return patterns[g.rng.IntN(len(patterns))], nil
+	case flag.OperatorTimeWindow:
+		// Windows up to two days either side of evaluatedAt, so some are open and some aren't
+		loc, err := time.LoadLocation(timezones[g.rng.IntN(len(timezones))])
+		if err != nil {
+			return nil, err
+		}
+		start := evaluatedAt.Add(time.Duration(g.rng.IntN(96)-48) * time.Hour).In(loc)
+		end := start.Add(time.Duration(1+g.rng.IntN(48)) * time.Hour)
+		return map[string]interface{}{
+			"start_at": start.Format(flag.TimeWindowLayout),
+			"end_at":   end.Format(flag.TimeWindowLayout),
+			"timezone": loc.String(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("evalprop: no value generator for operator %q", op)
+	}
+}
+
+// rollout favours the boundaries, where off-by-one mistakes hide
+func (g *Generator) rollout() int {
+	switch g.rng.IntN(4) {
+	case 0:
+		return 0
+	case 1:
+		return 100
+	default:
+		return g.rng.IntN(101)
+	}
+}
+
+// Context returns a context for a random user, evaluated at evaluatedAt; each attribute is
+// present three times in four
+func (g *Generator) Context() evaluation.EvaluationContext {
+	ctx := evaluation.EvaluationContext{
+		UserID:     fmt.Sprintf("user-%d", g.rng.IntN(100000)),
+		Attributes: make(map[string]interface{}),
+	}.WithTime(evaluatedAt)
+	for _, attr := range attributes {
+		if g.rng.IntN(4) != 0 {
+			ctx.Attributes[attr.name] = attr.values[g.rng.IntN(len(attr.values))]
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,37 @@
+package evalprop
+
+import (
+	"testing"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+func TestGenerator_CoversEveryOperator(t *testing.T) {
+	g := NewGenerator(1)
+	for _, op := range flag.Operators {
+		for _, attr := range attributes {
+			if _, err := g.value(op, attr); err != nil {
+				t.Fatalf("operator %q is not generated; add it to Generator.value: %v", op, err)
+			}
+		}
+	}
+}
+
+func TestGenerator_ProducesValidRules(t *testing.T) {
+	g := NewGenerator(2)
+	for i := 0; i < 500; i++ {
+		f := g.Flag()
+		if err := flag.ValidateRules(f.Rules); err != nil {
+			t.Fatalf("generated invalid rules %+v: %v", f.Rules, err)
+		}
+	}
+}
+
+func TestGenerator_IsReproducible(t *testing.T) {
+	a, b := NewGenerator(3), NewGenerator(3)
+	for i := 0; i < 50; i++ {
+		if a.Context().UserID != b.Context().UserID {
+			t.Fatal("generators with the same seed diverged")
+		}
+	}
+}
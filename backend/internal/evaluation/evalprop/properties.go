@@ -0,0 +1,210 @@
+package evalprop
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// Evaluator is the behaviour under test; *evaluation.Evaluator satisfies it
+type Evaluator interface {
+	Evaluate(f *flag.Flag, ctx evaluation.EvaluationContext) bool
+}
+
+// Property is an invariant that must hold for every flag and context
+type Property struct {
+	Name  string
+	Check func(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error
+}
+
+// complements pairs operators whose results are opposite whenever the attribute is present
+var complements = map[string]string{
+	flag.OperatorEquals:    flag.OperatorNotEquals,
+	flag.OperatorNotEquals: flag.OperatorEquals,
+	flag.OperatorIn:        flag.OperatorNotIn,
+	flag.OperatorNotIn:     flag.OperatorIn,
+}
+
+// Properties are the evaluator guarantees checked by Check
+var Properties = []Property{
+	{Name: "deterministic", Check: checkDeterministic},
+	{Name: "disabled flags are off", Check: checkDisabled},
+	{Name: "rollout monotonicity", Check: checkRolloutMonotonic},
+	{Name: "rule order independence", Check: checkOrderIndependent},
+	{Name: "AND/OR algebra", Check: checkRuleAlgebra},
+	{Name: "operator complements", Check: checkComplements},
+}
+
+// Check runs every property and returns all violations joined, or nil
+func Check(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error {
+	var errs []error
+	for _, p := range Properties {
+		if err := p.Check(e, f, ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// clone copies a flag so properties can change rules without affecting the caller
+func clone(f *flag.Flag) *flag.Flag {
+	c := *f
+	c.Rules = slices.Clone(f.Rules)
+	return &c
+}
+
+// checkDeterministic: the same flag and context always evaluate the same way
+func checkDeterministic(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error {
+	first := e.Evaluate(clone(f), ctx)
+	for i := 0; i < 3; i++ {
+		if got := e.Evaluate(clone(f), ctx); got != first {
+			return fmt.Errorf("evaluation %d returned %t, first returned %t", i+2, got, first)
+		}
+	}
+	return nil
+}
+
+// checkDisabled: a disabled flag is off regardless of rules
+func checkDisabled(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error {
+	disabled := clone(f)
+	disabled.Enabled = false
+	if e.Evaluate(disabled, ctx) {
+		return errors.New("disabled flag evaluated to true")
+	}
+	return nil
+}
+
+// checkRolloutMonotonic: raising any rule's rollout never turns the flag off for a user
+func checkRolloutMonotonic(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error {
+	if !e.Evaluate(clone(f), ctx) {
+		return nil
+	}
+	for i := range f.Rules {
+		for _, rollout := range []int{f.Rules[i].Rollout + 1, 100} {
+			if rollout > 100 {
+				continue
+			}
+			raised := clone(f)
+			raised.Rules[i].Rollout = rollout
+			if !e.Evaluate(raised, ctx) {
+				return fmt.Errorf("raising rule %d rollout from %d to %d turned the flag off",
+					i, f.Rules[i].Rollout, rollout)
+			}
+		}
+	}
+	return nil
+}
+
+// checkOrderIndependent: reordering rules does not change the result. Under FIRST_MATCH
+// only the list position of rules with equal Order may matter, so ties are skipped.
+func checkOrderIndependent(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error {
+	if f.RuleLogic == flag.RuleLogicFirstMatch && hasOrderTies(f.Rules) {
+		return nil
+	}
+
+	reversed := clone(f)
+	slices.Reverse(reversed.Rules)
+	if got, want := e.Evaluate(reversed, ctx), e.Evaluate(clone(f), ctx); got != want {
+		return fmt.Errorf("reversed rules returned %t, original order returned %t", got, want)
+	}
+	return nil
+}
+
+// hasOrderTies reports whether two rules share an Order
+func hasOrderTies(rules []flag.Rule) bool {
+	seen := make(map[int]struct{}, len(rules))
+	for _, rule := range rules {
+		if _, ok := seen[rule.Order]; ok {
+			return true
+		}
+		seen[rule.Order] = struct{}{}
+	}
+	return false
+}
+
+// checkRuleAlgebra: an AND flag is on when every single-rule flag is on, an OR flag when any is,
+// and a FIRST_MATCH flag when the first rule matching at full rollout is on by itself
+func checkRuleAlgebra(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error {
+	if !f.Enabled || len(f.Rules) == 0 {
+		return nil
+	}
+
+	if f.RuleLogic == flag.RuleLogicFirstMatch {
+		return checkFirstMatch(e, f, ctx)
+	}
+
+	isAnd := f.RuleLogic == "AND"
+	want := isAnd
+	for _, rule := range f.Rules {
+		single := clone(f)
+		single.Rules = []flag.Rule{rule}
+		on := e.Evaluate(single, ctx)
+		if isAnd {
+			want = want && on
+		} else {
+			want = want || on
+		}
+	}
+
+	if got := e.Evaluate(clone(f), ctx); got != want {
+		return fmt.Errorf("%s of %d rules returned %t, combining single rules gives %t",
+			f.RuleLogic, len(f.Rules), got, want)
+	}
+	return nil
+}
+
+// checkFirstMatch: the first rule in Order whose condition matches decides the result alone
+func checkFirstMatch(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error {
+	want := false
+	for _, rule := range flag.OrderedRules(f.Rules) {
+		probe := clone(f)
+		probe.Rules = []flag.Rule{rule}
+		probe.Rules[0].Rollout = 100
+		if !e.Evaluate(probe, ctx) {
+			continue
+		}
+
+		probe.Rules[0].Rollout = rule.Rollout
+		want = e.Evaluate(probe, ctx)
+		break
+	}
+
+	if got := e.Evaluate(clone(f), ctx); got != want {
+		return fmt.Errorf("FIRST_MATCH of %d rules returned %t, first matching rule gives %t", len(f.Rules), got, want)
+	}
+	return nil
+}
+
+// checkComplements: with full rollout, a rule and its complement disagree when the
+// attribute is present and both fail when it is missing
+func checkComplements(e Evaluator, f *flag.Flag, ctx evaluation.EvaluationContext) error {
+	if !f.Enabled {
+		return nil
+	}
+
+	for i, rule := range f.Rules {
+		complement, ok := complements[rule.Operator]
+		if !ok {
+			continue
+		}
+
+		single := clone(f)
+		rule.Rollout = 100
+		single.Rules = []flag.Rule{rule}
+		negated := clone(single)
+		negated.Rules[0].Operator = complement
+
+		got, gotNegated := e.Evaluate(single, ctx), e.Evaluate(negated, ctx)
+		if _, present := ctx.Attributes[rule.Attribute]; present {
+			if got == gotNegated {
+				return fmt.Errorf("rule %d: %s and %s both returned %t", i, rule.Operator, complement, got)
+			}
+		} else if got || gotNegated {
+			return fmt.Errorf("rule %d: missing attribute %q matched", i, rule.Attribute)
+		}
+	}
+	return nil
+}
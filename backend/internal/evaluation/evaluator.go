@@ -1,23 +1,64 @@
 package evaluation
 
 import (
-	"crypto/sha256"
-	"encoding/binary"
+	"cmp"
 	"fmt"
+	"strings"
+	"time"
 
 	flag "github.com/jalil32/toggle/internal/flags"
 )
 
 // Evaluator handles feature flag evaluation logic
-type Evaluator struct{}
+type Evaluator struct {
+	patterns *patternCache
+}
 
 func NewEvaluator() *Evaluator {
-	return &Evaluator{}
+	return &Evaluator{patterns: newPatternCache()}
 }
 
 // Evaluate determines if a flag is enabled for the given context
 // Returns false on any error (fail-safe behavior)
 func (e *Evaluator) Evaluate(f *flag.Flag, ctx EvaluationContext) bool {
+	// Step 0: A temporary per-user override wins over everything else
+	if enabled, ok := ctx.override(f.ID); ok {
+		return enabled
+	}
+
+	if !e.evaluateRollout(f, ctx) {
+		return false
+	}
+
+	// Step 6: Excluded users never receive the flag, even when bucketed in
+	return !ctx.isExcluded(f.ID)
+}
+
+// EvaluateWithReason is Evaluate that also reports the reason for the result, as
+// Explain would, without listing how each rule fared
+func (e *Evaluator) EvaluateWithReason(f *flag.Flag, ctx EvaluationContext) (bool, string) {
+	if enabled, ok := ctx.override(f.ID); ok {
+		return enabled, ReasonOverride
+	}
+	if !f.Enabled {
+		return false, ReasonDisabled
+	}
+	if len(f.Rules) > 0 {
+		if reason := e.rulesReason(f, ctx, e.bucket(f, ctx)); reason != ReasonRulesPassed {
+			return false, reason
+		}
+	}
+	if ctx.isExcluded(f.ID) {
+		return false, ReasonExcluded
+	}
+	if len(f.Rules) == 0 {
+		return true, ReasonNoRules
+	}
+	return true, ReasonRulesPassed
+}
+
+// evaluateRollout applies the enabled state, rules and rollout bucketing
+func (e *Evaluator) evaluateRollout(f *flag.Flag, ctx EvaluationContext) bool {
 	// Step 1: If flag is globally disabled, return false immediately
 	if !f.Enabled {
 		return false
@@ -28,32 +69,42 @@ func (e *Evaluator) Evaluate(f *flag.Flag, ctx EvaluationContext) bool {
 		return f.Enabled
 	}
 
-	// Step 3: Evaluate all rules based on rule_logic (AND/OR)
-	rulesPassed := e.evaluateRules(f, ctx)
-
-	// Step 4: If rules failed, return false
-	if !rulesPassed {
-		return false
-	}
-
-	// Step 5: Apply rollout percentage using consistent hashing
-	rolloutPercentage := e.getMaxRollout(f.Rules)
-	userRolloutBucket := e.consistentHash(ctx.UserID, f.ID)
+	// Step 3: Evaluate all rules, each with its own rollout, based on rule_logic (AND/OR/FIRST_MATCH)
+	userRolloutBucket := e.bucket(f, ctx)
 
-	return userRolloutBucket <= rolloutPercentage
+	return e.evaluateRules(f, ctx, userRolloutBucket)
 }
 
-// evaluateRules checks if rules pass based on AND/OR logic
-func (e *Evaluator) evaluateRules(f *flag.Flag, ctx EvaluationContext) bool {
+// evaluateRules checks if rules pass based on rule logic, applying each rule's rollout.
+//
+// A rule passes when its condition matches and the user's bucket is within its rollout.
+// The bucket is the same for every rule of a flag, so:
+//   - AND: every rule must pass, making the effective rollout the smallest of the rules
+//   - OR: any passing rule is enough; a user outside one rule's rollout can still
+//     receive the flag through another matching rule with a larger rollout
+//   - FIRST_MATCH: rules run in Order; the first whose condition matches decides
+//     through its own rollout, and no match means the flag is off
+//
+// Sharing the bucket keeps assignments sticky: raising a rollout only ever adds users.
+func (e *Evaluator) evaluateRules(f *flag.Flag, ctx EvaluationContext, bucket int) bool {
 	if len(f.Rules) == 0 {
 		return true
 	}
 
+	if f.RuleLogic == flag.RuleLogicFirstMatch {
+		for _, rule := range flag.OrderedRules(f.Rules) {
+			if e.evaluateRule(rule, ctx) {
+				return bucket <= rule.Rollout
+			}
+		}
+		return false
+	}
+
 	// Determine if AND or OR logic
-	isAndLogic := f.RuleLogic == "AND"
+	isAndLogic := f.RuleLogic == flag.RuleLogicAnd
 
 	for _, rule := range f.Rules {
-		matched := e.evaluateRule(rule, ctx)
+		matched := e.evaluateRule(rule, ctx) && bucket <= rule.Rollout
 
 		if isAndLogic && !matched {
 			// AND: all must pass, early exit on first failure
@@ -70,40 +121,171 @@ func (e *Evaluator) evaluateRules(f *flag.Flag, ctx EvaluationContext) bool {
 	return isAndLogic
 }
 
+// rulesReason evaluates the rules like evaluateRules, returning ReasonRulesPassed when
+// they admit the user. A user left out tells ReasonRolloutExcluded, when the conditions
+// that decide the result matched but the bucket is outside their rollout, from
+// ReasonRulesFailed, when those conditions didn't match:
+//   - AND: every condition matched
+//   - OR: at least one condition matched
+//   - FIRST_MATCH: a condition matched, so its rule decided
+func (e *Evaluator) rulesReason(f *flag.Flag, ctx EvaluationContext, bucket int) string {
+	if len(f.Rules) == 0 {
+		return ReasonRulesPassed
+	}
+
+	if f.RuleLogic == flag.RuleLogicFirstMatch {
+		for _, rule := range flag.OrderedRules(f.Rules) {
+			if e.evaluateRule(rule, ctx) {
+				if bucket <= rule.Rollout {
+					return ReasonRulesPassed
+				}
+				return ReasonRolloutExcluded
+			}
+		}
+		return ReasonRulesFailed
+	}
+
+	isAndLogic := f.RuleLogic == flag.RuleLogicAnd
+	outsideRollout := false
+	for _, rule := range f.Rules {
+		matched := e.evaluateRule(rule, ctx)
+		if isAndLogic && !matched {
+			return ReasonRulesFailed
+		}
+		if !matched {
+			continue
+		}
+		if bucket > rule.Rollout {
+			outsideRollout = true
+		} else if !isAndLogic {
+			return ReasonRulesPassed
+		}
+	}
+
+	if outsideRollout {
+		return ReasonRolloutExcluded
+	}
+	if isAndLogic {
+		return ReasonRulesPassed
+	}
+	return ReasonRulesFailed
+}
+
+// Explain evaluates a flag like Evaluate and reports how each rule fared.
+// Rules are listed in the order they run; under FIRST_MATCH only the rules up to
+// and including the first match are listed, since later ones never run.
+func (e *Evaluator) Explain(f *flag.Flag, ctx EvaluationContext) *Explanation {
+	bucket := e.bucket(f, ctx)
+	ex := &Explanation{
+		FlagID:    f.ID,
+		Bucket:    bucket,
+		Bucketing: ctx.bucketing,
+		Targeted:  e.evaluateRules(f, ctx, bucket),
+		Rules:     e.ruleResults(f, ctx, bucket),
+	}
+
+	switch enabled, ok := ctx.override(f.ID); {
+	case ok:
+		ex.Enabled, ex.Reason = enabled, ReasonOverride
+	case !f.Enabled:
+		ex.Reason = ReasonDisabled
+	case !ex.Targeted:
+		ex.Reason = e.rulesReason(f, ctx, bucket)
+	case ctx.isExcluded(f.ID):
+		ex.Reason = ReasonExcluded
+	case len(f.Rules) == 0:
+		ex.Enabled, ex.Reason = true, ReasonNoRules
+	default:
+		ex.Enabled, ex.Reason = true, ReasonRulesPassed
+	}
+
+	return ex
+}
+
+// ruleResults evaluates each rule that runs under the flag's rule logic
+func (e *Evaluator) ruleResults(f *flag.Flag, ctx EvaluationContext, bucket int) []RuleResult {
+	rules := f.Rules
+	if f.RuleLogic == flag.RuleLogicFirstMatch {
+		rules = flag.OrderedRules(f.Rules)
+	}
+
+	results := make([]RuleResult, 0, len(rules))
+	for _, rule := range rules {
+		result := RuleResult{
+			RuleID:      rule.ID,
+			Attribute:   rule.Attribute,
+			ContextKind: rule.ContextKind,
+			Operator:    rule.Operator,
+			Value:       rule.Value,
+			Matched:     e.evaluateRule(rule, ctx),
+			Rollout:     rule.Rollout,
+			InRollout:   bucket <= rule.Rollout,
+		}
+		if rule.Operator == flag.OperatorTimeWindow {
+			result.ContextValue = ctx.now().UTC().Format(time.RFC3339)
+		} else if value, ok := ctx.attribute(rule.ContextKind, rule.Attribute); ok {
+			result.ContextValue = value
+		}
+		results = append(results, result)
+		if f.RuleLogic == flag.RuleLogicFirstMatch && result.Matched {
+			break
+		}
+	}
+	return results
+}
+
 // evaluateRule checks if a single rule matches the context
 func (e *Evaluator) evaluateRule(rule flag.Rule, ctx EvaluationContext) bool {
-	// Get attribute value from context
-	attrValue, exists := ctx.Attributes[rule.Attribute]
+	// Time window rules match on the time of the evaluation, not an attribute
+	if rule.Operator == flag.OperatorTimeWindow {
+		return e.inTimeWindow(rule.Value, ctx.now())
+	}
+
+	// Get attribute value from the context kind the rule targets
+	attrValue, exists := ctx.attribute(rule.ContextKind, rule.Attribute)
 	if !exists {
-		return false // Missing attribute = no match
+		return false // Missing attribute or kind = no match
 	}
 
+	caseSensitive := rule.IsCaseSensitive()
+
 	switch rule.Operator {
 	case "equals":
-		return e.compareEquals(attrValue, rule.Value)
+		return e.compareEquals(attrValue, rule.Value, caseSensitive)
 	case "not_equals":
-		return !e.compareEquals(attrValue, rule.Value)
+		return !e.compareEquals(attrValue, rule.Value, caseSensitive)
 	case "in":
-		return e.compareIn(attrValue, rule.Value)
+		return e.compareIn(attrValue, rule.Value, caseSensitive)
 	case "not_in":
-		return !e.compareIn(attrValue, rule.Value)
+		return !e.compareIn(attrValue, rule.Value, caseSensitive)
 	case "greater_than":
 		return e.compareGreaterThan(attrValue, rule.Value)
 	case "less_than":
 		return e.compareLessThan(attrValue, rule.Value)
+	case "matches":
+		return e.compareMatches(attrValue, rule.Value, caseSensitive)
 	default:
 		// Unknown operator = fail-safe to false
 		return false
 	}
 }
 
+// inTimeWindow checks now is within a time_window rule's window. Invalid windows never match.
+func (e *Evaluator) inTimeWindow(ruleValue interface{}, now time.Time) bool {
+	start, end, err := flag.ParseTimeWindow(ruleValue)
+	if err != nil {
+		return false
+	}
+	return !now.Before(start) && now.Before(end)
+}
+
 // compareEquals checks equality
-func (e *Evaluator) compareEquals(attrValue, ruleValue interface{}) bool {
-	return fmt.Sprintf("%v", attrValue) == fmt.Sprintf("%v", ruleValue)
+func (e *Evaluator) compareEquals(attrValue, ruleValue interface{}, caseSensitive bool) bool {
+	return sameString(fmt.Sprintf("%v", attrValue), fmt.Sprintf("%v", ruleValue), caseSensitive)
 }
 
 // compareIn checks if attribute is in array
-func (e *Evaluator) compareIn(attrValue, ruleValue interface{}) bool {
+func (e *Evaluator) compareIn(attrValue, ruleValue interface{}, caseSensitive bool) bool {
 	// ruleValue should be an array
 	arr, ok := ruleValue.([]interface{})
 	if !ok {
@@ -112,31 +294,62 @@ func (e *Evaluator) compareIn(attrValue, ruleValue interface{}) bool {
 
 	attrStr := fmt.Sprintf("%v", attrValue)
 	for _, v := range arr {
-		if fmt.Sprintf("%v", v) == attrStr {
+		if sameString(fmt.Sprintf("%v", v), attrStr, caseSensitive) {
 			return true
 		}
 	}
 	return false
 }
 
-// compareGreaterThan for numeric comparisons
-func (e *Evaluator) compareGreaterThan(attrValue, ruleValue interface{}) bool {
-	attrNum, ok1 := e.toFloat64(attrValue)
-	ruleNum, ok2 := e.toFloat64(ruleValue)
-	if !ok1 || !ok2 {
-		return false
+// sameString compares two strings, ignoring case unless caseSensitive
+func sameString(a, b string, caseSensitive bool) bool {
+	if caseSensitive {
+		return a == b
 	}
-	return attrNum > ruleNum
+	return strings.EqualFold(a, b)
 }
 
-// compareLessThan for numeric comparisons
+// compareGreaterThan for numeric and date comparisons
+func (e *Evaluator) compareGreaterThan(attrValue, ruleValue interface{}) bool {
+	c, ok := e.compareOrdered(attrValue, ruleValue)
+	return ok && c > 0
+}
+
+// compareLessThan for numeric and date comparisons
 func (e *Evaluator) compareLessThan(attrValue, ruleValue interface{}) bool {
+	c, ok := e.compareOrdered(attrValue, ruleValue)
+	return ok && c < 0
+}
+
+// compareOrdered compares two numbers, or two dates (see flag.ParseDate). Anything else,
+// including a number against a date, can't be compared.
+func (e *Evaluator) compareOrdered(attrValue, ruleValue interface{}) (int, bool) {
 	attrNum, ok1 := e.toFloat64(attrValue)
 	ruleNum, ok2 := e.toFloat64(ruleValue)
+	if ok1 && ok2 {
+		return cmp.Compare(attrNum, ruleNum), true
+	}
+	attrDate, ok1 := flag.ParseDate(attrValue)
+	ruleDate, ok2 := flag.ParseDate(ruleValue)
+	if ok1 && ok2 {
+		return attrDate.Compare(ruleDate), true
+	}
+	return 0, false
+}
+
+// compareMatches checks a string attribute against a regex pattern.
+// Non-string attributes and invalid patterns never match.
+func (e *Evaluator) compareMatches(attrValue, ruleValue interface{}, caseSensitive bool) bool {
+	attrStr, ok1 := attrValue.(string)
+	pattern, ok2 := ruleValue.(string)
 	if !ok1 || !ok2 {
 		return false
 	}
-	return attrNum < ruleNum
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re := e.patterns.get(pattern)
+	return re != nil && re.MatchString(attrStr)
 }
 
 // toFloat64 converts interface{} to float64
@@ -153,27 +366,13 @@ func (e *Evaluator) toFloat64(val interface{}) (float64, bool) {
 	}
 }
 
-// getMaxRollout finds the maximum rollout percentage from all rules
-// (assumes all rules have the same rollout, uses first rule's value)
-func (e *Evaluator) getMaxRollout(rules []flag.Rule) int {
-	if len(rules) == 0 {
-		return 100 // No rules = 100% rollout
-	}
-	return rules[0].Rollout
+// consistentHash generates a deterministic 0-100 value from userID + flagID + salt
+// Same user + flag + salt always returns same value
+func (e *Evaluator) consistentHash(userID, flagID, salt string) int {
+	return sha256Bucket(userID, flagID, salt)
 }
 
-// consistentHash generates a deterministic 0-100 value from userID + flagID
-// Same user + flag always returns same value
-func (e *Evaluator) consistentHash(userID, flagID string) int {
-	// Create deterministic hash input
-	input := userID + ":" + flagID
-
-	// SHA256 hash
-	hash := sha256.Sum256([]byte(input))
-
-	// Convert first 8 bytes to uint64
-	hashInt := binary.BigEndian.Uint64(hash[:8])
-
-	// Map to 0-100 range
-	return int(hashInt % 101)
+// bucket returns the context's rollout bucket for a flag with the context's bucketing algorithm
+func (e *Evaluator) bucket(f *flag.Flag, ctx EvaluationContext) int {
+	return BucketerFor(ctx.bucketing).Bucket(f, ctx.BucketingKey())
 }
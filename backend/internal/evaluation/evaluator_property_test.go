@@ -0,0 +1,36 @@
+package evaluation_test
+
+import (
+	"testing"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+	"github.com/jalil32/toggle/internal/evaluation/evalprop"
+)
+
+func TestEvaluator_Properties(t *testing.T) {
+	e := evaluation.NewEvaluator()
+	g := evalprop.NewGenerator(42)
+
+	for i := 0; i < 2000; i++ {
+		f, ctx := g.Flag(), g.Context()
+		if err := evalprop.Check(e, f, ctx); err != nil {
+			t.Fatalf("case %d violated evaluator properties\nflag: %+v\ncontext: %+v\n%v", i, *f, ctx, err)
+		}
+	}
+}
+
+// FuzzEvaluator explores generator seeds: go test -fuzz=FuzzEvaluator ./internal/evaluation
+func FuzzEvaluator(f *testing.F) {
+	for _, seed := range []uint64{0, 1, 42, 1 << 32} {
+		f.Add(seed)
+	}
+
+	e := evaluation.NewEvaluator()
+	f.Fuzz(func(t *testing.T, seed uint64) {
+		g := evalprop.NewGenerator(seed)
+		fl, ctx := g.Flag(), g.Context()
+		if err := evalprop.Check(e, fl, ctx); err != nil {
+			t.Fatalf("seed %d violated evaluator properties\nflag: %+v\ncontext: %+v\n%v", seed, *fl, ctx, err)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package evaluation
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	flag "github.com/jalil32/toggle/internal/flags"
 	"github.com/stretchr/testify/assert"
@@ -15,9 +16,9 @@ func TestEvaluator_ConsistentHash_IsDeterministic(t *testing.T) {
 	flagID := "flag456"
 
 	// Call multiple times, should always return same value
-	hash1 := e.consistentHash(userID, flagID)
-	hash2 := e.consistentHash(userID, flagID)
-	hash3 := e.consistentHash(userID, flagID)
+	hash1 := e.consistentHash(userID, flagID, "")
+	hash2 := e.consistentHash(userID, flagID, "")
+	hash3 := e.consistentHash(userID, flagID, "")
 
 	assert.Equal(t, hash1, hash2)
 	assert.Equal(t, hash2, hash3)
@@ -33,7 +34,7 @@ func TestEvaluator_ConsistentHash_DifferentUsers(t *testing.T) {
 	// Test with multiple users to ensure hashing distributes values
 	hashes := make(map[int]bool)
 	for i := 0; i < 10; i++ {
-		hash := e.consistentHash(fmt.Sprintf("user%d", i), flagID)
+		hash := e.consistentHash(fmt.Sprintf("user%d", i), flagID, "")
 		hashes[hash] = true
 	}
 
@@ -78,6 +79,52 @@ func TestEvaluator_NoRules_ReturnsEnabled(t *testing.T) {
 	assert.True(t, result, "Enabled flag with no rules should return true")
 }
 
+func TestEvaluator_Exclusion_OverridesRollout(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "flag1",
+		Enabled:   true,
+		RuleLogic: "AND",
+		Rules: []flag.Rule{
+			{Attribute: "country", Operator: "equals", Value: "AU", Rollout: 100},
+		},
+	}
+
+	ctx := EvaluationContext{
+		UserID:     "user1",
+		Attributes: map[string]interface{}{"country": "AU"},
+	}
+
+	assert.True(t, e.Evaluate(f, ctx), "Fully rolled out user should be bucketed in")
+	assert.False(t, e.Evaluate(f, ctx.WithExclusions([]string{"flag1"})), "Excluded user should never receive the flag")
+	assert.True(t, e.Evaluate(f, ctx.WithExclusions([]string{"other-flag"})), "Exclusions on other flags should not apply")
+}
+
+func TestEvaluator_Override_ConsultedBeforeRules(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "flag1",
+		Enabled:   true,
+		RuleLogic: "AND",
+		Rules: []flag.Rule{
+			{Attribute: "country", Operator: "equals", Value: "AU", Rollout: 100},
+		},
+	}
+
+	ctx := EvaluationContext{
+		UserID:     "user1",
+		Attributes: map[string]interface{}{"country": "US"},
+	}
+
+	assert.False(t, e.Evaluate(f, ctx), "Non-matching user should not receive the flag")
+	assert.True(t, e.Evaluate(f, ctx.WithOverrides(map[string]bool{"flag1": true})), "Override should force the flag on")
+
+	f.Enabled = false
+	assert.True(t, e.Evaluate(f, ctx.WithOverrides(map[string]bool{"flag1": true})), "Override should apply to disabled flags")
+}
+
 func TestEvaluator_Operator_Equals(t *testing.T) {
 	e := NewEvaluator()
 
@@ -569,6 +616,39 @@ func TestEvaluator_NumericComparison_WithFloat64(t *testing.T) {
 	assert.False(t, e.Evaluate(f, ctx))
 }
 
+func TestEvaluator_DateComparison(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "flag1",
+		Enabled:   true,
+		RuleLogic: "AND",
+		Rules: []flag.Rule{
+			{
+				Attribute: "signed_up",
+				Operator:  "greater_than",
+				Value:     "2026-03-01",
+				Rollout:   100,
+			},
+		},
+	}
+
+	ctx := EvaluationContext{
+		UserID: "user1",
+		Attributes: map[string]interface{}{
+			"signed_up": "2026-03-01T09:30:00Z",
+		},
+	}
+
+	assert.True(t, e.Evaluate(f, ctx))
+
+	ctx.Attributes["signed_up"] = "2026-02-28"
+	assert.False(t, e.Evaluate(f, ctx))
+
+	ctx.Attributes["signed_up"] = float64(20260401)
+	assert.False(t, e.Evaluate(f, ctx), "a number can't be compared with a date")
+}
+
 func TestEvaluator_NumericComparison_InvalidType(t *testing.T) {
 	e := NewEvaluator()
 
@@ -597,3 +677,323 @@ func TestEvaluator_NumericComparison_InvalidType(t *testing.T) {
 	result := e.Evaluate(f, ctx)
 	assert.False(t, result, "Invalid numeric type should fail comparison")
 }
+
+func TestEvaluator_Matches(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "flag1",
+		Enabled:   true,
+		RuleLogic: "AND",
+		Rules: []flag.Rule{
+			{Attribute: "email", Operator: "matches", Value: `@example\.com$`, Rollout: 100},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		email interface{}
+		want  bool
+	}{
+		{name: "matching string", email: "ana@example.com", want: true},
+		{name: "non-matching string", email: "ana@example.org", want: false},
+		{name: "non-string attribute", email: float64(1), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := EvaluationContext{UserID: "user1", Attributes: map[string]interface{}{"email": tt.email}}
+			assert.Equal(t, tt.want, e.Evaluate(f, ctx))
+		})
+	}
+}
+
+func TestEvaluator_CaseInsensitiveComparison(t *testing.T) {
+	e := NewEvaluator()
+	insensitive := false
+
+	tests := []struct {
+		name  string
+		rule  flag.Rule
+		value string
+	}{
+		{name: "equals", rule: flag.Rule{Attribute: "email", Operator: "equals", Value: "Ana@Example.com"}, value: "ana@example.COM"},
+		{name: "in", rule: flag.Rule{Attribute: "country", Operator: "in", Value: []interface{}{"AU", "NZ"}}, value: "au"},
+		{name: "matches", rule: flag.Rule{Attribute: "email", Operator: "matches", Value: "@example\\.com$"}, value: "ana@EXAMPLE.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Rollout = 100
+			f := &flag.Flag{ID: "flag1", Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{tt.rule}}
+			ctx := EvaluationContext{UserID: "user1", Attributes: map[string]interface{}{tt.rule.Attribute: tt.value}}
+
+			assert.False(t, e.Evaluate(f, ctx), "comparisons are case-sensitive by default")
+
+			f.Rules[0].CaseSensitive = &insensitive
+			assert.True(t, e.Evaluate(f, ctx))
+		})
+	}
+}
+
+func TestEvaluator_Matches_InvalidPatternFailsSafe(t *testing.T) {
+	e := NewEvaluator()
+
+	for _, pattern := range []string{"(unclosed", "^(a+)+$"} {
+		f := &flag.Flag{
+			ID:        "flag1",
+			Enabled:   true,
+			RuleLogic: "OR",
+			Rules:     []flag.Rule{{Attribute: "name", Operator: "matches", Value: pattern, Rollout: 100}},
+		}
+		ctx := EvaluationContext{UserID: "user1", Attributes: map[string]interface{}{"name": "aaaa"}}
+
+		// Evaluated twice so the cached result is used the second time
+		assert.False(t, e.Evaluate(f, ctx), pattern)
+		assert.False(t, e.Evaluate(f, ctx), pattern)
+	}
+}
+
+// userInBucketRange finds a user ID whose bucket for flagID falls within [low, high]
+func userInBucketRange(t *testing.T, e *Evaluator, flagID string, low, high int) string {
+	t.Helper()
+	for i := 0; i < 10000; i++ {
+		userID := fmt.Sprintf("user%d", i)
+		if bucket := e.consistentHash(userID, flagID, ""); bucket >= low && bucket <= high {
+			return userID
+		}
+	}
+	t.Fatalf("no user found with bucket in [%d, %d]", low, high)
+	return ""
+}
+
+func TestEvaluator_PerRuleRollout_AND_UsesEveryRulesRollout(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "flag1",
+		Enabled:   true,
+		RuleLogic: "AND",
+		Rules: []flag.Rule{
+			{Attribute: "country", Operator: "equals", Value: "US", Rollout: 100},
+			{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 20},
+		},
+	}
+	attrs := map[string]interface{}{"country": "US", "plan": "pro"}
+
+	inside := userInBucketRange(t, e, f.ID, 1, 20)
+	outside := userInBucketRange(t, e, f.ID, 21, 100)
+
+	assert.True(t, e.Evaluate(f, EvaluationContext{UserID: inside, Attributes: attrs}))
+	assert.False(t, e.Evaluate(f, EvaluationContext{UserID: outside, Attributes: attrs}),
+		"the second rule's rollout must apply, not only the first rule's")
+}
+
+func TestEvaluator_TimeWindow_MatchesInItsTimezone(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "holiday-banner",
+		Enabled:   true,
+		RuleLogic: "AND",
+		Rules: []flag.Rule{{
+			Operator: flag.OperatorTimeWindow,
+			Value: map[string]interface{}{
+				"start_at": "2026-12-20T00:00:00",
+				"end_at":   "2026-12-27T00:00:00",
+				"timezone": "Australia/Sydney", // UTC+11 in December
+			},
+			Rollout: 100,
+		}},
+	}
+	at := func(utc string) EvaluationContext {
+		now, err := time.Parse(time.RFC3339, utc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return EvaluationContext{UserID: "user-1"}.WithTime(now)
+	}
+
+	assert.False(t, e.Evaluate(f, at("2026-12-19T12:59:59Z")), "before midnight Dec 20 in Sydney")
+	assert.True(t, e.Evaluate(f, at("2026-12-19T13:00:00Z")), "midnight Dec 20 in Sydney")
+	assert.True(t, e.Evaluate(f, at("2026-12-26T12:59:59Z")))
+	assert.False(t, e.Evaluate(f, at("2026-12-26T13:00:00Z")), "the window ends at end_at")
+
+	f.Rules[0].Value = map[string]interface{}{"start_at": "2026-12-20", "end_at": "2026-12-27"}
+	assert.False(t, e.Evaluate(f, at("2026-12-22T00:00:00Z")), "invalid windows never match")
+}
+
+func TestEvaluator_ContextKinds(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "enterprise-beta",
+		Enabled:   true,
+		RuleLogic: "AND",
+		Rules: []flag.Rule{
+			{ContextKind: flag.ContextKindOrganization, Attribute: "plan", Operator: "equals", Value: "enterprise", Rollout: 100},
+			{Attribute: "plan", Operator: "equals", Value: "free", Rollout: 100},
+		},
+	}
+	ctx := EvaluationContext{
+		UserID:     "user-1",
+		Attributes: map[string]interface{}{"plan": "free"},
+		Kinds: map[string]KindContext{
+			flag.ContextKindOrganization: {Key: "org-1", Attributes: map[string]interface{}{"plan": "enterprise"}},
+		},
+	}
+	assert.NoError(t, ctx.Validate())
+	assert.True(t, e.Evaluate(f, ctx), "each rule matches the attributes of the kind it targets")
+
+	withoutOrg := ctx
+	withoutOrg.Kinds = nil
+	assert.False(t, e.Evaluate(f, withoutOrg), "a rule on a kind the context doesn't have never matches")
+
+	f.Rules = []flag.Rule{{ContextKind: flag.ContextKindOrganization, Attribute: "key", Operator: "in", Value: []interface{}{"org-1"}, Rollout: 100}}
+	assert.True(t, e.Evaluate(f, ctx), "a kind's key is its key attribute")
+
+	ctx.Kinds = map[string]KindContext{"team": {Key: "team-1"}}
+	assert.ErrorIs(t, ctx.Validate(), ErrInvalidContext)
+	ctx.Kinds = map[string]KindContext{flag.ContextKindDevice: {}}
+	assert.ErrorIs(t, ctx.Validate(), ErrInvalidContext, "kinds need a key")
+}
+
+func TestEvaluator_PerRuleRollout_OR_AnyPassingRule(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "flag1",
+		Enabled:   true,
+		RuleLogic: "OR",
+		Rules: []flag.Rule{
+			{Attribute: "country", Operator: "equals", Value: "US", Rollout: 10},
+			{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100},
+		},
+	}
+
+	userID := userInBucketRange(t, e, f.ID, 11, 100)
+
+	// Outside the first rule's rollout, but the second rule matches at 100%
+	assert.True(t, e.Evaluate(f, EvaluationContext{
+		UserID:     userID,
+		Attributes: map[string]interface{}{"country": "US", "plan": "pro"},
+	}))
+
+	// Only the first rule matches, and the user is outside its rollout
+	assert.False(t, e.Evaluate(f, EvaluationContext{
+		UserID:     userID,
+		Attributes: map[string]interface{}{"country": "US", "plan": "free"},
+	}))
+}
+
+func TestEvaluator_FirstMatch_UsesFirstMatchingRuleInOrder(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "flag1",
+		Enabled:   true,
+		RuleLogic: flag.RuleLogicFirstMatch,
+		Rules: []flag.Rule{
+			// Listed first but ordered last
+			{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100, Order: 2},
+			{Attribute: "country", Operator: "equals", Value: "US", Rollout: 0, Order: 1},
+		},
+	}
+	userID := userInBucketRange(t, e, f.ID, 1, 100)
+
+	// The country rule runs first and serves its 0% rollout, so the plan rule is never reached
+	assert.False(t, e.Evaluate(f, EvaluationContext{
+		UserID:     userID,
+		Attributes: map[string]interface{}{"country": "US", "plan": "pro"},
+	}))
+
+	// Without a country match, evaluation falls through to the plan rule
+	assert.True(t, e.Evaluate(f, EvaluationContext{
+		UserID:     userID,
+		Attributes: map[string]interface{}{"country": "AU", "plan": "pro"},
+	}))
+
+	// No rule matches
+	assert.False(t, e.Evaluate(f, EvaluationContext{
+		UserID:     userID,
+		Attributes: map[string]interface{}{"country": "AU", "plan": "free"},
+	}))
+}
+
+func TestEvaluator_Explain_AgreesWithEvaluate(t *testing.T) {
+	e := NewEvaluator()
+
+	f := &flag.Flag{
+		ID:        "flag1",
+		Enabled:   true,
+		RuleLogic: flag.RuleLogicFirstMatch,
+		Rules: []flag.Rule{
+			{ID: "plan", Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100, Order: 2},
+			{ID: "country", Attribute: "country", Operator: "equals", Value: "US", Rollout: 0, Order: 1},
+		},
+	}
+	userID := userInBucketRange(t, e, f.ID, 1, 100)
+	ctx := EvaluationContext{UserID: userID, Attributes: map[string]interface{}{"country": "AU", "plan": "pro"}}
+
+	ex := e.Explain(f, ctx)
+	assert.Equal(t, e.Evaluate(f, ctx), ex.Enabled)
+	assert.Equal(t, ReasonRulesPassed, ex.Reason)
+	// Rules are listed in run order, stopping at the first match
+	assert.Equal(t, []RuleResult{
+		{RuleID: "country", Attribute: "country", Operator: "equals", Value: "US", ContextValue: "AU", Matched: false, Rollout: 0, InRollout: false},
+		{RuleID: "plan", Attribute: "plan", Operator: "equals", Value: "pro", ContextValue: "pro", Matched: true, Rollout: 100, InRollout: true},
+	}, ex.Rules)
+
+	excluded := e.Explain(f, ctx.WithExclusions([]string{f.ID}))
+	assert.False(t, excluded.Enabled)
+	assert.Equal(t, ReasonExcluded, excluded.Reason)
+	assert.True(t, excluded.Targeted)
+
+	f.Enabled = false
+	disabled := e.Explain(f, ctx)
+	assert.False(t, disabled.Enabled)
+	assert.Equal(t, ReasonDisabled, disabled.Reason)
+	assert.True(t, disabled.Targeted)
+}
+
+func TestEvaluator_EvaluateWithReason(t *testing.T) {
+	e := NewEvaluator()
+
+	rules := []flag.Rule{
+		{Attribute: "country", Operator: "equals", Value: "US", Rollout: 20},
+		{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100},
+	}
+	inside := userInBucketRange(t, e, "flag1", 1, 20)
+	outside := userInBucketRange(t, e, "flag1", 21, 100)
+	usPro := map[string]interface{}{"country": "US", "plan": "pro"}
+	usFree := map[string]interface{}{"country": "US", "plan": "free"}
+	auFree := map[string]interface{}{"country": "AU", "plan": "free"}
+
+	tests := []struct {
+		name    string
+		flag    flag.Flag
+		ctx     EvaluationContext
+		enabled bool
+		reason  string
+	}{
+		{"disabled", flag.Flag{ID: "flag1"}, EvaluationContext{UserID: inside}, false, ReasonDisabled},
+		{"no rules", flag.Flag{ID: "flag1", Enabled: true}, EvaluationContext{UserID: inside}, true, ReasonNoRules},
+		{"override", flag.Flag{ID: "flag1"}, EvaluationContext{UserID: inside}.WithOverrides(map[string]bool{"flag1": true}), true, ReasonOverride},
+		{"AND passed", flag.Flag{ID: "flag1", Enabled: true, RuleLogic: "AND", Rules: rules}, EvaluationContext{UserID: inside, Attributes: usPro}, true, ReasonRulesPassed},
+		{"AND outside rollout", flag.Flag{ID: "flag1", Enabled: true, RuleLogic: "AND", Rules: rules}, EvaluationContext{UserID: outside, Attributes: usPro}, false, ReasonRolloutExcluded},
+		{"AND condition failed", flag.Flag{ID: "flag1", Enabled: true, RuleLogic: "AND", Rules: rules}, EvaluationContext{UserID: outside, Attributes: usFree}, false, ReasonRulesFailed},
+		{"OR outside rollout", flag.Flag{ID: "flag1", Enabled: true, RuleLogic: "OR", Rules: rules}, EvaluationContext{UserID: outside, Attributes: usFree}, false, ReasonRolloutExcluded},
+		{"OR condition failed", flag.Flag{ID: "flag1", Enabled: true, RuleLogic: "OR", Rules: rules}, EvaluationContext{UserID: inside, Attributes: auFree}, false, ReasonRulesFailed},
+		{"excluded", flag.Flag{ID: "flag1", Enabled: true}, EvaluationContext{UserID: inside}.WithExclusions([]string{"flag1"}), false, ReasonExcluded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enabled, reason := e.EvaluateWithReason(&tt.flag, tt.ctx)
+			assert.Equal(t, tt.enabled, enabled)
+			assert.Equal(t, tt.reason, reason)
+			assert.Equal(t, e.Evaluate(&tt.flag, tt.ctx), enabled, "must agree with Evaluate")
+			assert.Equal(t, tt.reason, e.Explain(&tt.flag, tt.ctx).Reason, "must agree with Explain")
+		})
+	}
+}
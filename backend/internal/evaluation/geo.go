@@ -0,0 +1,165 @@
+package evaluation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Attributes geo targeting fills in from the caller's IP
+const (
+	GeoCountryAttribute = "country" // ISO 3166-1 alpha-2 country code, such as "DE"
+	GeoRegionAttribute  = "region"  // ISO 3166-2 subdivision code within the country, such as "BY"
+)
+
+// Location is where an IP address is; Region is empty when the database doesn't know it
+type Location struct {
+	Country string
+	Region  string
+}
+
+// GeoLocator finds where IP addresses are
+type GeoLocator interface {
+	Locate(ip netip.Addr) (Location, bool)
+}
+
+// geoRange is a block of addresses at one location
+type geoRange struct {
+	first, last netip.Addr
+	location    Location
+}
+
+// GeoIPDatabase is a GeoLocator backed by an in-memory table of IP ranges
+type GeoIPDatabase struct {
+	ranges []geoRange // sorted by first address, non-overlapping
+}
+
+// LoadGeoIPDatabase reads a GeoIP database from a CSV file of IP ranges, one per line:
+// first_ip,last_ip,country[,region]. IPv4 and IPv6 ranges can be mixed; lines starting
+// with # are ignored. This is the layout of the free DB-IP and IP2Location LITE country
+// and region databases.
+func LoadGeoIPDatabase(path string) (*GeoIPDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseGeoIPDatabase(f)
+}
+
+// ParseGeoIPDatabase reads a GeoIP database in LoadGeoIPDatabase's CSV layout
+func ParseGeoIPDatabase(r io.Reader) (*GeoIPDatabase, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var ranges []geoRange
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: want first_ip,last_ip,country[,region]", line)
+		}
+
+		first, err := netip.ParseAddr(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		last, err := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		first, last = first.Unmap(), last.Unmap()
+		if first.Is4() != last.Is4() || last.Less(first) {
+			return nil, fmt.Errorf("line %d: %s-%s is not a range", line, first, last)
+		}
+
+		location := Location{Country: strings.ToUpper(strings.TrimSpace(record[2]))}
+		if len(record) > 3 {
+			location.Region = strings.ToUpper(strings.TrimSpace(record[3]))
+		}
+		// "-" and "ZZ" mark unassigned or reserved ranges
+		if location.Country == "" || location.Country == "-" || location.Country == "ZZ" {
+			continue
+		}
+		ranges = append(ranges, geoRange{first: first, last: last, location: location})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].first.Less(ranges[j].first) })
+	for i := 1; i < len(ranges); i++ {
+		if !ranges[i-1].last.Less(ranges[i].first) {
+			return nil, fmt.Errorf("ranges starting at %s and %s overlap", ranges[i-1].first, ranges[i].first)
+		}
+	}
+	return &GeoIPDatabase{ranges: ranges}, nil
+}
+
+// Len returns the number of ranges in the database
+func (d *GeoIPDatabase) Len() int {
+	return len(d.ranges)
+}
+
+// Locate returns the location of the range holding ip
+func (d *GeoIPDatabase) Locate(ip netip.Addr) (Location, bool) {
+	ip = ip.Unmap()
+	// The first range starting after ip; the one before it is the only one that can hold ip
+	i := sort.Search(len(d.ranges), func(i int) bool { return ip.Less(d.ranges[i].first) })
+	if i == 0 {
+		return Location{}, false
+	}
+	r := d.ranges[i-1]
+	if r.last.Less(ip) || r.first.Is4() != ip.Is4() {
+		return Location{}, false
+	}
+	return r.location, true
+}
+
+// enrichGeo returns the context with country and region attributes for its client IP added,
+// unless the context already supplies them. Contexts without a client IP, and IPs the
+// locator can't place, are returned unchanged.
+func enrichGeo(evalCtx EvaluationContext, locator GeoLocator) EvaluationContext {
+	if locator == nil || evalCtx.clientIP == "" {
+		return evalCtx
+	}
+	_, hasCountry := evalCtx.Attributes[GeoCountryAttribute]
+	_, hasRegion := evalCtx.Attributes[GeoRegionAttribute]
+	if hasCountry && hasRegion {
+		return evalCtx
+	}
+
+	ip, err := netip.ParseAddr(evalCtx.clientIP)
+	if err != nil {
+		return evalCtx
+	}
+	location, ok := locator.Locate(ip)
+	if !ok {
+		return evalCtx
+	}
+
+	// Copy the attributes rather than write to the caller's map
+	attributes := make(map[string]interface{}, len(evalCtx.Attributes)+2)
+	for k, v := range evalCtx.Attributes {
+		attributes[k] = v
+	}
+	if !hasCountry {
+		attributes[GeoCountryAttribute] = location.Country
+	}
+	// A region only means something in the country it was located in
+	if !hasRegion && location.Region != "" && (!hasCountry || attributes[GeoCountryAttribute] == location.Country) {
+		attributes[GeoRegionAttribute] = location.Region
+	}
+	evalCtx.Attributes = attributes
+	return evalCtx
+}
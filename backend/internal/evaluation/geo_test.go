@@ -0,0 +1,93 @@
+package evaluation
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+const testGeoIPDatabase = `# first_ip,last_ip,country,region
+203.0.113.0,203.0.113.255,DE,BY
+198.51.100.0,198.51.100.127,au,NSW
+198.51.100.128,198.51.100.255,ZZ,
+2001:db8::,2001:db8::ffff,NZ
+`
+
+func TestGeoIPDatabase_Locate(t *testing.T) {
+	db, err := ParseGeoIPDatabase(strings.NewReader(testGeoIPDatabase))
+	require.NoError(t, err)
+	assert.Equal(t, 3, db.Len(), "reserved ranges are dropped")
+
+	tests := []struct {
+		ip   string
+		want Location
+		ok   bool
+	}{
+		{"203.0.113.7", Location{Country: "DE", Region: "BY"}, true},
+		{"::ffff:203.0.113.255", Location{Country: "DE", Region: "BY"}, true},
+		{"198.51.100.0", Location{Country: "AU", Region: "NSW"}, true},
+		{"198.51.100.200", Location{}, false},
+		{"2001:db8::1", Location{Country: "NZ"}, true},
+		{"192.0.2.1", Location{}, false},
+		{"2001:db9::1", Location{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := db.Locate(netip.MustParseAddr(tt.ip))
+		assert.Equal(t, tt.ok, ok, tt.ip)
+		assert.Equal(t, tt.want, got, tt.ip)
+	}
+}
+
+func TestParseGeoIPDatabase_RejectsOverlappingRanges(t *testing.T) {
+	_, err := ParseGeoIPDatabase(strings.NewReader("10.0.0.0,10.0.0.255,US\n10.0.0.128,10.0.1.0,CA\n"))
+	assert.Error(t, err)
+}
+
+func TestService_EvaluateAll_GeoTargeting(t *testing.T) {
+	db, err := ParseGeoIPDatabase(strings.NewReader(testGeoIPDatabase))
+	require.NoError(t, err)
+
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "germany", Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{
+					{Attribute: "country", Operator: "equals", Value: "DE", Rollout: 100},
+				}},
+				{ID: "bavaria", Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{
+					{Attribute: "region", Operator: "equals", Value: "BY", Rollout: 100},
+				}},
+			}, nil
+		},
+	}
+	newService := func(geoTargeting bool) Service {
+		svc := NewService(flags, &mockProjectReader{geoTargeting: geoTargeting}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		svc.SetGeoLocator(db)
+		return svc
+	}
+	bavarian := EvaluationContext{UserID: "user-1"}.WithClientIP("203.0.113.7")
+
+	resp, err := newService(true).EvaluateAll(sdkContext(), "project-1", bavarian)
+	require.NoError(t, err)
+	assert.True(t, resp.Flags["germany"])
+	assert.True(t, resp.Flags["bavaria"])
+
+	resp, err = newService(false).EvaluateAll(sdkContext(), "project-1", bavarian)
+	require.NoError(t, err)
+	assert.False(t, resp.Flags["germany"], "projects without geo targeting evaluate the context as sent")
+
+	travelling := bavarian
+	travelling.Attributes = map[string]interface{}{"country": "FR"}
+	resp, err = newService(true).EvaluateAll(sdkContext(), "project-1", travelling)
+	require.NoError(t, err)
+	assert.False(t, resp.Flags["germany"], "a supplied country wins over the located one")
+	assert.False(t, resp.Flags["bavaria"], "a located region isn't added to a different supplied country")
+	assert.Equal(t, map[string]interface{}{"country": "FR"}, travelling.Attributes, "the caller's attributes aren't modified")
+}
@@ -1,11 +1,25 @@
 package evaluation
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
 
 	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
 )
 
 type Handler interface {
@@ -23,8 +37,27 @@ func NewHandler(service Service) Handler {
 func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/evaluate", h.EvaluateAll)
 	r.POST("/flags/:id/evaluate", h.EvaluateSingle)
+	r.POST("/flags/key/:key/evaluate", h.EvaluateByKey)
+	r.GET("/snapshot", serverSideKey, h.GetSnapshot)
+	r.GET("/ruleset", serverSideKey, h.GetRuleset)
+	r.GET("/bootstrap", h.Bootstrap)
+	r.POST("/bootstrap", h.Bootstrap)
 }
 
+// serverSideKey refuses requests made with a client-side key: snapshots and rulesets carry
+// every rule and targeted user key, which must not reach browsers and apps
+func serverSideKey(c *gin.Context) {
+	if appContext.IsClientSideKey(c.Request.Context()) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "client-side keys can only evaluate flags; use a server-side key"})
+		return
+	}
+	c.Next()
+}
+
+// StalenessHeader reports, in seconds, how long ago bulk evaluation's flags were confirmed
+// current; above zero only for projects that tolerate cache staleness
+const StalenessHeader = "X-Toggle-Cache-Staleness"
+
 // EvaluateAll handles bulk evaluation for all flags in a project
 func (h *handler) EvaluateAll(c *gin.Context) {
 	var req EvaluationRequest
@@ -32,17 +65,106 @@ func (h *handler) EvaluateAll(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := req.Context.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.KeyBy != "" && req.KeyBy != KeyByID && req.KeyBy != KeyByKey {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key_by must be id or key"})
+		return
+	}
 
 	// Extract project_id from context (set by API key middleware)
 	projectID := appContext.MustProjectID(c.Request.Context())
 
-	result, err := h.service.EvaluateAll(c.Request.Context(), projectID, req.Context)
+	result, err := h.service.EvaluateAll(c.Request.Context(), projectID, req.Context.WithClientIP(c.ClientIP()))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "evaluation failed"})
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	c.Header(StalenessHeader, strconv.FormatFloat(result.Staleness.Seconds(), 'f', 3, 64))
+	if req.KeyBy == KeyByKey {
+		result = result.KeyedByFlagKey()
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "evaluation failed"})
+		return
+	}
+
+	// Polling SDKs resend the ETag of their last result and skip the download when their
+	// flags haven't changed. Partial results are incomplete, so they are never matched.
+	if !result.Partial {
+		etag := contentETag(body)
+		c.Header("ETag", etag)
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// Bootstrap returns the flag values browser SDKs start with, by flag key, for the context in
+// a POST body or in ?context= (base64url-encoded JSON) on GET. Only values are returned, so
+// it can be called with a project's client-side key. Prefer POST: query strings end up in
+// access logs. Complete results carry an ETag for If-None-Match revalidation.
+func (h *handler) Bootstrap(c *gin.Context) {
+	var evalCtx EvaluationContext
+	if c.Request.Method == http.MethodPost {
+		var req BootstrapRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		evalCtx = req.Context
+	} else {
+		raw := c.Query("context")
+		if raw == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "context is required"})
+			return
+		}
+		decoded, err := DecodeContextParam(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		evalCtx = decoded
+	}
+	if err := evalCtx.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projectID := appContext.MustProjectID(c.Request.Context())
+
+	result, err := h.service.EvaluateAll(c.Request.Context(), projectID, evalCtx.WithClientIP(c.ClientIP()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "evaluation failed"})
+		return
+	}
+
+	body, err := json.Marshal(result.Bootstrap())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "evaluation failed"})
+		return
+	}
+
+	// The values are specific to the context, so shared caches must not keep them
+	c.Header("Cache-Control", "private, no-cache")
+	if !result.Partial {
+		etag := contentETag(body)
+		c.Header("ETag", etag)
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
 }
 
 // EvaluateSingle handles evaluation for a single flag
@@ -54,11 +176,15 @@ func (h *handler) EvaluateSingle(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := req.Context.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Extract tenant_id from context (set by API key middleware)
 	tenantID := appContext.MustTenantID(c.Request.Context())
 
-	result, err := h.service.EvaluateSingle(c.Request.Context(), flagID, tenantID, req.Context)
+	result, err := h.service.EvaluateSingle(c.Request.Context(), flagID, tenantID, req.Context.WithClientIP(c.ClientIP()))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
 		return
@@ -66,3 +192,511 @@ func (h *handler) EvaluateSingle(c *gin.Context) {
 
 	c.JSON(http.StatusOK, result)
 }
+
+// EvaluateByKey handles evaluation for a single flag of the key's project, found by flag key
+func (h *handler) EvaluateByKey(c *gin.Context) {
+	var req SingleEvaluationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Context.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projectID := appContext.MustProjectID(c.Request.Context())
+
+	result, err := h.service.EvaluateByKey(c.Request.Context(), projectID, c.Param("key"), req.Context.WithClientIP(c.ClientIP()))
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) || errors.Is(err, ErrFlagNotActive) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "evaluation failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetSnapshot returns every flag in the project for relay warm-up, as configured in the
+// key's environment when an environment key is used.
+// Supports If-None-Match revalidation and gzip-compresses the payload when accepted.
+func (h *handler) GetSnapshot(c *gin.Context) {
+	projectID := appContext.MustProjectID(c.Request.Context())
+	environmentID := appContext.EnvironmentID(c.Request.Context())
+
+	maxStaleness := int(SnapshotMaxStaleness.Seconds())
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d, must-revalidate", maxStaleness))
+	c.Header("X-Max-Staleness", strconv.Itoa(maxStaleness))
+
+	// Cheap revalidation: only the generation counter is read
+	generation, err := h.service.Generation(c.Request.Context(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load snapshot"})
+		return
+	}
+
+	etag := snapshotETag(projectID, environmentID, generation)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Header("ETag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	snapshot, err := h.service.Snapshot(c.Request.Context(), projectID)
+	if err != nil {
+		if errors.Is(err, ErrSnapshotUnstable) {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "project is changing, retry shortly"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load snapshot"})
+		return
+	}
+
+	body, err := json.Marshal(snapshot.ForEnvironment(environmentID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode snapshot"})
+		return
+	}
+
+	// The snapshot may be newer than the generation checked above
+	c.Header("ETag", snapshotETag(projectID, environmentID, snapshot.Generation))
+	writeCompressible(c, body)
+}
+
+// GetRuleset returns the project's flag definitions for server-side SDKs that evaluate
+// locally, as configured in the key's environment when an environment key is used. The
+// ETag hashes the definitions, so SDKs revalidating with If-None-Match only download the
+// ruleset again when a definition they use changed, not on every version bump.
+func (h *handler) GetRuleset(c *gin.Context) {
+	c.Header("Cache-Control", "private, no-cache")
+
+	ruleset, err := h.service.Ruleset(c.Request.Context(), appContext.MustProjectID(c.Request.Context()))
+	if err != nil {
+		if errors.Is(err, ErrSnapshotUnstable) {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "project is changing, retry shortly"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load ruleset"})
+		return
+	}
+
+	definitions, err := json.Marshal(struct {
+		Bucketing         string                 `json:"bucketing"`
+		Flags             []SnapshotFlag         `json:"flags"`
+		DefaultAttributes map[string]interface{} `json:"default_attributes,omitempty"`
+	}{ruleset.Bucketing, ruleset.Flags, ruleset.DefaultAttributes})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode ruleset"})
+		return
+	}
+
+	etag := contentETag(definitions)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	body, err := json.Marshal(ruleset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode ruleset"})
+		return
+	}
+	writeCompressible(c, body)
+}
+
+// writeCompressible writes a JSON body, gzip-compressed when the client accepts it
+func writeCompressible(c *gin.Context, body []byte) {
+	c.Header("Vary", "Accept-Encoding")
+
+	if strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err == nil && gz.Close() == nil {
+			c.Header("Content-Encoding", "gzip")
+			c.Data(http.StatusOK, "application/json; charset=utf-8", buf.Bytes())
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// snapshotETag is weak because generated_at differs between identical snapshots.
+// Environment keys see different content at the same generation, so they get their own tags.
+func snapshotETag(projectID string, environmentID string, generation int64) string {
+	if environmentID != "" {
+		return fmt.Sprintf(`W/"%s-%s-%d"`, projectID, environmentID, generation)
+	}
+	return fmt.Sprintf(`W/"%s-%d"`, projectID, generation)
+}
+
+// contentETag is a strong ETag over a payload's content
+func contentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag (weak comparison)
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// BenchmarkHandler serves the evaluation benchmark to instance operators
+type BenchmarkHandler struct {
+	benchmarker *Benchmarker
+	token       string
+}
+
+// NewBenchmarkHandler serves the benchmark to callers presenting token as a bearer token.
+// A run saturates the instance's CPUs for every tenant on it, so no tenant role is enough.
+func NewBenchmarkHandler(benchmarker *Benchmarker, token string) *BenchmarkHandler {
+	return &BenchmarkHandler{benchmarker: benchmarker, token: token}
+}
+
+// RegisterRoutes registers the benchmark route; it authenticates its own callers and must
+// not be behind Auth
+func (h *BenchmarkHandler) RegisterRoutes(r *gin.RouterGroup, middleware ...gin.HandlerFunc) {
+	r.POST("/admin/evaluation-benchmark", append(middleware, h.authorize, h.Run)...)
+}
+
+func (h *BenchmarkHandler) authorize(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid benchmark token"})
+		return
+	}
+	c.Next()
+}
+
+// Run benchmarks the evaluation path inside this instance, for capacity planning
+func (h *BenchmarkHandler) Run(c *gin.Context) {
+	var req BenchmarkRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.benchmarker.Run(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidBenchmark) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrBenchmarkRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "benchmark failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExplainHandler serves rule-match traces on the management API
+type ExplainHandler struct {
+	service Service
+}
+
+func NewExplainHandler(service Service) *ExplainHandler {
+	return &ExplainHandler{service: service}
+}
+
+func (h *ExplainHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/flags/:id/explain", h.Explain)
+}
+
+// Explain traces how a flag evaluates for a context, rule by rule, as SDKs would see it.
+// It reads the user's overrides and exclusions, so only owners and admins may run it.
+func (h *ExplainHandler) Explain(c *gin.Context) {
+	role := appContext.UserRole(c.Request.Context())
+	if role != "owner" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	var req ExplainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Context.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	explanation, err := h.service.Explain(c.Request.Context(), c.Param("id"), tenantID, req.EnvironmentID, req.Context)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "explain failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, explanation)
+}
+
+// OFREPHandler serves the OpenFeature Remote Evaluation Protocol on the SDK API, so
+// OpenFeature SDKs can evaluate flags with their stock OFREP provider
+type OFREPHandler struct {
+	service Service
+}
+
+func NewOFREPHandler(service Service) *OFREPHandler {
+	return &OFREPHandler{service: service}
+}
+
+func (h *OFREPHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/ofrep/v1/evaluate/flags", h.EvaluateAll)
+	r.POST("/ofrep/v1/evaluate/flags/:key", h.Evaluate)
+}
+
+// bindContext reads an OFREP request's evaluation context, answering 400 when it can't be used
+func (h *OFREPHandler) bindContext(c *gin.Context, key string) (EvaluationContext, bool) {
+	var req OFREPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, OFREPError{Key: key, ErrorCode: OFREPErrorParse, ErrorDetails: err.Error()})
+		return EvaluationContext{}, false
+	}
+	evalCtx, code, err := ofrepContext(req.Context)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, OFREPError{Key: key, ErrorCode: code, ErrorDetails: err.Error()})
+		return EvaluationContext{}, false
+	}
+	return evalCtx.WithClientIP(c.ClientIP()), true
+}
+
+// EvaluateAll evaluates every flag in the key's project. Like bulk evaluation, it sets an
+// ETag on complete results and answers 304 when the provider's cached results are current.
+func (h *OFREPHandler) EvaluateAll(c *gin.Context) {
+	evalCtx, ok := h.bindContext(c, "")
+	if !ok {
+		return
+	}
+
+	projectID := appContext.MustProjectID(c.Request.Context())
+
+	result, err := h.service.EvaluateAll(c.Request.Context(), projectID, evalCtx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, OFREPError{ErrorCode: OFREPErrorGeneral, ErrorDetails: "evaluation failed"})
+		return
+	}
+
+	body, err := json.Marshal(result.OFREP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, OFREPError{ErrorCode: OFREPErrorGeneral, ErrorDetails: "evaluation failed"})
+		return
+	}
+
+	if !result.Partial {
+		etag := contentETag(body)
+		c.Header("ETag", etag)
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// Evaluate evaluates one flag of the key's project, found by flag key
+func (h *OFREPHandler) Evaluate(c *gin.Context) {
+	key := c.Param("key")
+	evalCtx, ok := h.bindContext(c, key)
+	if !ok {
+		return
+	}
+
+	projectID := appContext.MustProjectID(c.Request.Context())
+
+	result, err := h.service.EvaluateByKey(c.Request.Context(), projectID, key, evalCtx)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) || errors.Is(err, ErrFlagNotActive) {
+			c.JSON(http.StatusNotFound, OFREPError{Key: key, ErrorCode: OFREPErrorFlagNotFound, ErrorDetails: "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, OFREPError{Key: key, ErrorCode: OFREPErrorGeneral, ErrorDetails: "evaluation failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result.OFREP())
+}
+
+// SDKConfigReader reads the SDK config of a tenant
+type SDKConfigReader interface {
+	GetSDKConfig(ctx context.Context, tenantID string) (*SDKConfig, error)
+}
+
+// SDKConfigHandler tells SDKs how often to poll and whether to stream, so they can tune
+// themselves without a release
+type SDKConfigHandler struct {
+	reader SDKConfigReader
+}
+
+func NewSDKConfigHandler(reader SDKConfigReader) *SDKConfigHandler {
+	return &SDKConfigHandler{reader: reader}
+}
+
+func (h *SDKConfigHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/config", h.GetConfig)
+}
+
+// GetConfig returns the SDK config of the API key's tenant
+func (h *SDKConfigHandler) GetConfig(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	cfg, err := h.reader.GetSDKConfig(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sdk config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// StreamHeartbeatInterval is how often idle SDK streams receive a heartbeat event
+const StreamHeartbeatInterval = 30 * time.Second
+
+// maxStreamMessageBytes caps messages read from SDK streams, which are only ever discarded
+const maxStreamMessageBytes = 4 << 10
+
+// streamWriteTimeout bounds sending one event, so a stalled client can't hold its stream open
+const streamWriteTimeout = 10 * time.Second
+
+// MaintenanceCheckInterval is how often open SDK streams check for announced maintenance
+const MaintenanceCheckInterval = 30 * time.Second
+
+// StreamHandler pushes flag change events to SDKs over long-lived connections. Its routes
+// must not be behind a request timeout.
+type StreamHandler struct {
+	watcher     *ChangeWatcher
+	maintenance MaintenanceSource
+}
+
+func NewStreamHandler(watcher *ChangeWatcher) *StreamHandler {
+	return &StreamHandler{watcher: watcher}
+}
+
+// SetMaintenance makes streams send a maintenance event when maintenance is near or under way
+func (h *StreamHandler) SetMaintenance(source MaintenanceSource) {
+	h.maintenance = source
+}
+
+// notice returns the maintenance to tell streams about at now, if any
+func (h *StreamHandler) notice(now time.Time) *MaintenanceNotice {
+	if h.maintenance == nil {
+		return nil
+	}
+	return h.maintenance.Notice(now)
+}
+
+func (h *StreamHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/ws", h.WebSocket)
+}
+
+// WebSocket streams the project's ChangeEvents as JSON text messages, for networks that
+// block server-sent events. The API key middleware has already authorized the project;
+// messages from the client are read and discarded.
+func (h *StreamHandler) WebSocket(c *gin.Context) {
+	ctx := c.Request.Context()
+	projectID := appContext.MustProjectID(ctx)
+	tenantID := appContext.MustTenantID(ctx)
+
+	events, version, unsubscribe, err := h.watcher.Subscribe(ctx, projectID, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to subscribe to flag changes"})
+		return
+	}
+	defer unsubscribe()
+
+	server := websocket.Server{
+		// SDKs authenticate with their API key rather than cookies, so any origin may connect
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			streamEvents(conn, ChangeEvent{Type: EventReady, ProjectID: projectID, Version: version}, events, h.notice)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// streamEvents sends ready, then every change event and a heartbeat when idle, until the
+// client disconnects or a send fails. Each maintenance notice is sent once, on connect or
+// at the first check after it's announced.
+func streamEvents(conn *websocket.Conn, ready ChangeEvent, events <-chan ChangeEvent, notice func(time.Time) *MaintenanceNotice) {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.MaxPayloadBytes = maxStreamMessageBytes
+		var discard string
+		for {
+			if err := websocket.Message.Receive(conn, &discard); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(event ChangeEvent) bool {
+		_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return websocket.JSON.Send(conn, event) == nil
+	}
+
+	var announced string
+	sendMaintenance := func() bool {
+		n := notice(time.Now())
+		if n == nil || n.ID == announced {
+			return true
+		}
+		announced = n.ID
+		return send(ChangeEvent{Type: EventMaintenance, Maintenance: n})
+	}
+
+	heartbeat := time.NewTicker(StreamHeartbeatInterval)
+	defer heartbeat.Stop()
+	maintenance := time.NewTicker(MaintenanceCheckInterval)
+	defer maintenance.Stop()
+
+	if !send(ready) || !sendMaintenance() {
+		return
+	}
+	for {
+		select {
+		case <-closed:
+			return
+		case <-maintenance.C:
+			if !sendMaintenance() {
+				return
+			}
+		case event := <-events:
+			if !send(event) {
+				return
+			}
+			heartbeat.Reset(StreamHeartbeatInterval)
+		case <-heartbeat.C:
+			if !send(ChangeEvent{Type: EventHeartbeat}) {
+				return
+			}
+		}
+	}
+}
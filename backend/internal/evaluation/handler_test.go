@@ -0,0 +1,408 @@
+package evaluation
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+func setupSnapshotRouter(generation int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{{ID: "flag-1", Name: "checkout", Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+	}
+	projects := &mockProjectReader{generations: []int64{generation}}
+	h := NewHandler(NewService(flags, projects, slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(sdkContext())
+		c.Next()
+	})
+	router.GET("/snapshot", h.(*handler).GetSnapshot)
+	router.GET("/ruleset", h.(*handler).GetRuleset)
+	return router
+}
+
+func TestHandler_GetSnapshot_CompressesAndSetsETag(t *testing.T) {
+	router := setupSnapshotRouter(42)
+
+	req := httptest.NewRequest(http.MethodGet, "/snapshot", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `W/"project-1-42"`, w.Header().Get("ETag"))
+	assert.Equal(t, "30", w.Header().Get("X-Max-Staleness"))
+
+	gz, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	var snapshot Snapshot
+	require.NoError(t, json.NewDecoder(gz).Decode(&snapshot))
+	assert.Equal(t, int64(42), snapshot.Generation)
+	assert.Len(t, snapshot.Flags, 1)
+}
+
+func TestHandler_GetSnapshot_NotModified(t *testing.T) {
+	router := setupSnapshotRouter(42)
+
+	req := httptest.NewRequest(http.MethodGet, "/snapshot", nil)
+	req.Header.Set("If-None-Match", `W/"project-1-42"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	// A stale ETag gets the full (uncompressed) snapshot
+	req = httptest.NewRequest(http.MethodGet, "/snapshot", nil)
+	req.Header.Set("If-None-Match", `W/"project-1-41"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+func TestHandler_GetRuleset_VersionsDefinitions(t *testing.T) {
+	router := setupSnapshotRouter(42)
+
+	req := httptest.NewRequest(http.MethodGet, "/ruleset", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+	var ruleset Ruleset
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ruleset))
+	assert.Equal(t, int64(42), ruleset.Version)
+	assert.Equal(t, BucketingSHA256, ruleset.Bucketing)
+	require.Len(t, ruleset.Flags, 1)
+	assert.Equal(t, "flag-1", ruleset.Flags[0].ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/ruleset", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestHandler_GetRuleset_ETagIgnoresVersionBumps(t *testing.T) {
+	first := httptest.NewRecorder()
+	setupSnapshotRouter(42).ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/ruleset", nil))
+	require.Equal(t, http.StatusOK, first.Code)
+
+	// Same definitions at a later version: nothing for the SDK to download
+	req := httptest.NewRequest(http.MethodGet, "/ruleset", nil)
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	w := httptest.NewRecorder()
+	setupSnapshotRouter(43).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestHandler_EvaluateAll_NotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enabled := true
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{{ID: "flag-1", Enabled: enabled, Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+	}
+	h := NewHandler(NewService(flags, &mockProjectReader{generations: []int64{1}}, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(sdkContext())
+		c.Next()
+	})
+	router.POST("/evaluate", h.(*handler).EvaluateAll)
+
+	evaluate := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(`{"context":{"user_id":"user-1"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := evaluate("")
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	second := evaluate(etag)
+	assert.Equal(t, http.StatusNotModified, second.Code)
+	assert.Empty(t, second.Body.Bytes())
+
+	enabled = false
+	third := evaluate(etag)
+	assert.Equal(t, http.StatusOK, third.Code)
+	assert.NotEqual(t, etag, third.Header().Get("ETag"))
+}
+
+func TestHandler_EvaluateByKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stored := flag.Flag{ID: "flag-1", Key: "new-checkout", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{stored}, nil
+		},
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			return &stored, nil
+		},
+	}
+	h := NewHandler(NewService(flags, &mockProjectReader{generations: []int64{1}}, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(sdkContext())
+		c.Next()
+	})
+	h.RegisterRoutes(router.Group(""))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/flags/key/new-checkout/evaluate", `{"context":{"user_id":"user-1"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"enabled":true,"flag_id":"flag-1","flag_key":"new-checkout"}`, w.Body.String())
+
+	w = post("/flags/key/unknown/evaluate", `{"context":{"user_id":"user-1"}}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = post("/evaluate", `{"context":{"user_id":"user-1"},"key_by":"key"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"flags":{"new-checkout":true}}`, w.Body.String())
+
+	w = post("/evaluate", `{"context":{"user_id":"user-1"},"key_by":"name"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandler_Evaluate_RequiresBucketingKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+	}
+	h := NewHandler(NewService(flags, &mockProjectReader{generations: []int64{1}}, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(sdkContext())
+		c.Next()
+	})
+	h.RegisterRoutes(router.Group(""))
+
+	tests := []struct {
+		context string
+		want    int
+	}{
+		{context: `{"user_id":"user-1"}`, want: http.StatusOK},
+		{context: `{"anonymous":true,"key":"device-1"}`, want: http.StatusOK},
+		{context: `{}`, want: http.StatusBadRequest},
+		{context: `{"attributes":{"plan":"pro"}}`, want: http.StatusBadRequest},
+		{context: `{"anonymous":true}`, want: http.StatusBadRequest},
+		{context: `{"anonymous":true,"key":"device-1","user_id":"user-1"}`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/evaluate", strings.NewReader(`{"context":`+tt.context+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.want, w.Code, tt.context)
+	}
+}
+
+func TestOFREPHandler_EvaluatesByKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stored := []flag.Flag{
+		{ID: "flag-1", Key: "new-checkout", Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{
+			{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100},
+		}},
+		{ID: "flag-2", Key: "dark-mode", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"},
+		{ID: "flag-3", Key: "big-teams", Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{
+			{Attribute: "seats", ContextKind: flag.ContextKindOrganization, Operator: "greater_than", Value: float64(50), Rollout: 100},
+		}},
+	}
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return stored, nil
+		},
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			for i := range stored {
+				if stored[i].ID == id {
+					return &stored[i], nil
+				}
+			}
+			return nil, sql.ErrNoRows
+		},
+	}
+	h := NewOFREPHandler(NewService(flags, &mockProjectReader{generations: []int64{1}}, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(sdkContext())
+		c.Next()
+	})
+	h.RegisterRoutes(router.Group(""))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	const context = `{"context":{"targetingKey":"user-1","plan":"pro","organization":{"key":"acme","seats":10}}}`
+
+	w := post("/ofrep/v1/evaluate/flags/new-checkout", context)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"key":"new-checkout","value":true,"reason":"TARGETING_MATCH","variant":"on","metadata":{"flagId":"flag-1"}}`, w.Body.String())
+
+	w = post("/ofrep/v1/evaluate/flags/unknown", context)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"key":"unknown","errorCode":"FLAG_NOT_FOUND","errorDetails":"flag not found"}`, w.Body.String())
+
+	w = post("/ofrep/v1/evaluate/flags/new-checkout", `{"context":{"plan":"pro"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), OFREPErrorTargetingKeyMissing)
+
+	w = post("/ofrep/v1/evaluate/flags", context)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"flags":[
+		{"key":"big-teams","value":false,"reason":"DEFAULT","variant":"off","metadata":{"flagId":"flag-3"}},
+		{"key":"dark-mode","value":false,"reason":"DISABLED","variant":"off","metadata":{"flagId":"flag-2"}},
+		{"key":"new-checkout","value":true,"reason":"TARGETING_MATCH","variant":"on","metadata":{"flagId":"flag-1"}}
+	]}`, w.Body.String())
+}
+
+func TestHandler_Bootstrap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "flag-1", Key: "new-checkout", Description: "internal notes", Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{
+					{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100},
+				}},
+				{ID: "flag-2", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+	}
+	h := NewHandler(NewService(flags, &mockProjectReader{generations: []int64{1}}, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(sdkContext())
+		c.Next()
+	})
+	h.RegisterRoutes(router.Group(""))
+
+	bootstrap := func(context string, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/bootstrap?context="+context, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	encoded := base64.URLEncoding.EncodeToString([]byte(`{"user_id":"user-12","attributes":{"plan":"pro"}}`))
+
+	w := bootstrap(encoded, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"flags":{"new-checkout":true,"flag-2":true}}`, w.Body.String(), "values only, keyed by flag key")
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+
+	w = bootstrap(strings.TrimRight(encoded, "="), w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, w.Code, "unpadded contexts decode the same")
+
+	assert.Equal(t, http.StatusBadRequest, bootstrap("", "").Code)
+	assert.Equal(t, http.StatusBadRequest, bootstrap("not-json", "").Code)
+	assert.Equal(t, http.StatusBadRequest, bootstrap(base64.RawURLEncoding.EncodeToString([]byte(`{"attributes":{}}`)), "").Code)
+}
+
+func TestHandler_ClientSideKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{{ID: "flag-1", Key: "new-checkout", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+	}
+	h := NewHandler(NewService(flags, &mockProjectReader{generations: []int64{1}}, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(appContext.WithClientSideKey(sdkContext()))
+		c.Next()
+	})
+	h.RegisterRoutes(router.Group(""))
+
+	for _, path := range []string{"/ruleset", "/snapshot"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bootstrap", strings.NewReader(`{"context":{"user_id":"user-12"}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"flags":{"new-checkout":true}}`, w.Body.String())
+}
+
+type sdkConfigFunc func(ctx context.Context, tenantID string) (*SDKConfig, error)
+
+func (f sdkConfigFunc) GetSDKConfig(ctx context.Context, tenantID string) (*SDKConfig, error) {
+	return f(ctx, tenantID)
+}
+
+func TestSDKConfigHandler_ReturnsTenantConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewSDKConfigHandler(sdkConfigFunc(func(ctx context.Context, tenantID string) (*SDKConfig, error) {
+		if tenantID != "tenant-1" {
+			return nil, errors.New("wrong tenant")
+		}
+		return &SDKConfig{PollingIntervalSeconds: 120, StreamingAvailable: false, PayloadVersion: PayloadVersion}, nil
+	}))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(sdkContext())
+		c.Next()
+	})
+	h.RegisterRoutes(router.Group(""))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/config", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"polling_interval_seconds":120,"streaming_available":false,"payload_version":1}`, w.Body.String())
+}
@@ -0,0 +1,174 @@
+package evaluation
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// OpenFeature Remote Evaluation Protocol (OFREP) lets any OpenFeature SDK's OFREP provider
+// evaluate Toggle flags. Flags are addressed by key and resolve to boolean values; the
+// provider's base URL is the SDK API root, /api/v1/sdk.
+// See https://github.com/open-feature/protocol
+
+// OFREP reasons, from the OpenFeature specification
+const (
+	OFREPReasonStatic         = "STATIC"
+	OFREPReasonDefault        = "DEFAULT"
+	OFREPReasonTargetingMatch = "TARGETING_MATCH"
+	OFREPReasonSplit          = "SPLIT"
+	OFREPReasonDisabled       = "DISABLED"
+	OFREPReasonUnknown        = "UNKNOWN"
+)
+
+// OFREP error codes
+const (
+	OFREPErrorParse               = "PARSE_ERROR"
+	OFREPErrorTargetingKeyMissing = "TARGETING_KEY_MISSING"
+	OFREPErrorInvalidContext      = "INVALID_CONTEXT"
+	OFREPErrorFlagNotFound        = "FLAG_NOT_FOUND"
+	OFREPErrorGeneral             = "GENERAL"
+)
+
+// Variants boolean flags resolve to
+const (
+	OFREPVariantOn  = "on"
+	OFREPVariantOff = "off"
+)
+
+// ofrepTargetingKey is the OpenFeature context field identifying the user
+const ofrepTargetingKey = "targetingKey"
+
+// OFREPRequest is the body of both OFREP evaluation endpoints
+type OFREPRequest struct {
+	Context map[string]interface{} `json:"context"`
+}
+
+// OFREPEvaluation is one flag's OFREP result: a value with its reason and variant, or,
+// in bulk responses, an error code for a flag that couldn't be evaluated
+type OFREPEvaluation struct {
+	Key          string                 `json:"key"`
+	Value        *bool                  `json:"value,omitempty"`
+	Reason       string                 `json:"reason,omitempty"`
+	Variant      string                 `json:"variant,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	ErrorCode    string                 `json:"errorCode,omitempty"`
+	ErrorDetails string                 `json:"errorDetails,omitempty"`
+}
+
+// OFREPBulkResponse is the body of a bulk OFREP evaluation
+type OFREPBulkResponse struct {
+	Flags []OFREPEvaluation `json:"flags"`
+}
+
+// OFREPError is the body of a failed OFREP request
+type OFREPError struct {
+	Key          string `json:"key,omitempty"`
+	ErrorCode    string `json:"errorCode,omitempty"`
+	ErrorDetails string `json:"errorDetails"`
+}
+
+// ofrepContext converts an OpenFeature evaluation context into an EvaluationContext. The
+// targeting key is the user ID; an object under a context kind's name with a string "key"
+// becomes that kind; every other field is a user attribute. Returns the OFREP error code
+// of a context that can't be evaluated.
+func ofrepContext(fields map[string]interface{}) (EvaluationContext, string, error) {
+	userID, _ := fields[ofrepTargetingKey].(string)
+	if userID == "" {
+		return EvaluationContext{}, OFREPErrorTargetingKeyMissing, fmt.Errorf("%w: %s is required", ErrInvalidContext, ofrepTargetingKey)
+	}
+
+	evalCtx := EvaluationContext{UserID: userID, Attributes: make(map[string]interface{}, len(fields))}
+	for name, value := range fields {
+		if name == ofrepTargetingKey {
+			continue
+		}
+		if object, ok := value.(map[string]interface{}); ok && name != flag.ContextKindUser && slices.Contains(flag.ContextKinds, name) {
+			key, _ := object["key"].(string)
+			attributes := make(map[string]interface{}, len(object))
+			for k, v := range object {
+				if k != "key" {
+					attributes[k] = v
+				}
+			}
+			if evalCtx.Kinds == nil {
+				evalCtx.Kinds = make(map[string]KindContext)
+			}
+			evalCtx.Kinds[name] = KindContext{Key: key, Attributes: attributes}
+			continue
+		}
+		evalCtx.Attributes[name] = value
+	}
+
+	if err := evalCtx.Validate(); err != nil {
+		return EvaluationContext{}, OFREPErrorInvalidContext, err
+	}
+	return evalCtx, "", nil
+}
+
+// ofrepReason maps an evaluation reason onto the OpenFeature reason vocabulary
+func ofrepReason(reason string) string {
+	switch reason {
+	case ReasonDisabled:
+		return OFREPReasonDisabled
+	case ReasonNoRules:
+		return OFREPReasonStatic
+	case ReasonRulesPassed, ReasonOverride, ReasonExcluded:
+		return OFREPReasonTargetingMatch
+	case ReasonRolloutExcluded:
+		return OFREPReasonSplit
+	case ReasonRulesFailed:
+		return OFREPReasonDefault
+	}
+	return OFREPReasonUnknown
+}
+
+// ofrepEvaluation builds one flag's OFREP result
+func ofrepEvaluation(key, flagID string, enabled bool, reason string, archived bool) OFREPEvaluation {
+	variant := OFREPVariantOff
+	if enabled {
+		variant = OFREPVariantOn
+	}
+	metadata := map[string]interface{}{"flagId": flagID}
+	if archived {
+		metadata["archived"] = true
+	}
+	return OFREPEvaluation{
+		Key:      key,
+		Value:    &enabled,
+		Reason:   ofrepReason(reason),
+		Variant:  variant,
+		Metadata: metadata,
+	}
+}
+
+// OFREP returns the single flag's OFREP result
+func (r *SingleEvaluationResponse) OFREP() OFREPEvaluation {
+	key := r.FlagKey
+	if key == "" {
+		key = r.FlagID
+	}
+	return ofrepEvaluation(key, r.FlagID, r.Enabled, r.evaluationReason, r.Reason == ReasonArchived)
+}
+
+// OFREP returns the response as an OFREP bulk result, with flags keyed by flag key and
+// sorted by key so equal results encode identically
+func (r *EvaluationResponse) OFREP() *OFREPBulkResponse {
+	archived := make(map[string]bool, len(r.Archived))
+	for _, id := range r.Archived {
+		archived[id] = true
+	}
+
+	resp := &OFREPBulkResponse{Flags: make([]OFREPEvaluation, 0, len(r.Flags))}
+	for id, enabled := range r.Flags {
+		key := r.keys[id]
+		if key == "" {
+			key = id
+		}
+		resp.Flags = append(resp.Flags, ofrepEvaluation(key, id, enabled, r.reasons[id], archived[id]))
+	}
+	slices.SortFunc(resp.Flags, func(a, b OFREPEvaluation) int { return cmp.Compare(a.Key, b.Key) })
+	return resp
+}
@@ -0,0 +1,34 @@
+package evaluation
+
+import (
+	"net/http"
+
+	"github.com/jalil32/toggle/internal/pkg/openapi"
+)
+
+// SDKOperations documents the SDK routes: those of Handler, OFREPHandler, SDKConfigHandler
+// and StreamHandler, for the OpenAPI document
+func SDKOperations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: http.MethodPost, Path: "/evaluate", Summary: "Evaluate every flag of the key's project", Request: EvaluationRequest{}, Response: EvaluationResponse{}},
+		{Method: http.MethodPost, Path: "/flags/:id/evaluate", Summary: "Evaluate one flag by ID", Request: SingleEvaluationRequest{}, Response: SingleEvaluationResponse{}},
+		{Method: http.MethodPost, Path: "/flags/key/:key/evaluate", Summary: "Evaluate one flag by key", Request: SingleEvaluationRequest{}, Response: SingleEvaluationResponse{}},
+		{Method: http.MethodGet, Path: "/snapshot", Summary: "Get every flag of the key's project, for relays; server-side keys only", Response: Snapshot{}},
+		{Method: http.MethodGet, Path: "/ruleset", Summary: "Get the key's flag definitions, for SDKs evaluating locally; server-side keys only", Response: Ruleset{}},
+		{Method: http.MethodPost, Path: "/bootstrap", Summary: "Get flag values by key for one context, for browser SDKs", Request: BootstrapRequest{}, Response: BootstrapResponse{}},
+		{Method: http.MethodGet, Path: "/bootstrap", Summary: "Get flag values by key for a context passed in the query string", Response: BootstrapResponse{},
+			Query: []openapi.Param{{Name: "context", Description: "The evaluation context as base64url-encoded JSON", Required: true}}},
+		{Method: http.MethodPost, Path: "/ofrep/v1/evaluate/flags", Summary: "Evaluate every flag with the OpenFeature Remote Evaluation Protocol", Request: OFREPRequest{}, Response: OFREPBulkResponse{}},
+		{Method: http.MethodPost, Path: "/ofrep/v1/evaluate/flags/:key", Summary: "Evaluate one flag with the OpenFeature Remote Evaluation Protocol", Request: OFREPRequest{}, Response: OFREPEvaluation{}},
+		{Method: http.MethodGet, Path: "/config", Summary: "Get how SDKs should poll and stream", Response: SDKConfig{}},
+		{Method: http.MethodGet, Path: "/ws", Summary: "Stream the project's change events as WebSocket text messages", Status: http.StatusSwitchingProtocols},
+	}
+}
+
+// Operations documents the management routes of ExplainHandler, for the OpenAPI document.
+// The benchmark is an operator route outside the tenant API and isn't documented.
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: http.MethodPost, Path: "/flags/:id/explain", Summary: "Trace how a flag evaluates for a context", Request: ExplainRequest{}, Response: Explanation{}},
+	}
+}
@@ -0,0 +1,46 @@
+package evaluation
+
+import (
+	"regexp"
+	"sync"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// maxCachedPatterns bounds the pattern cache; it is cleared when full, since the
+// patterns in use at any time are the few that live flags reference
+const maxCachedPatterns = 1024
+
+// patternCache holds compiled matches patterns so each is compiled once, not per evaluation
+type patternCache struct {
+	mu       sync.RWMutex
+	patterns map[string]*regexp.Regexp
+}
+
+func newPatternCache() *patternCache {
+	return &patternCache{patterns: make(map[string]*regexp.Regexp)}
+}
+
+// get returns the compiled pattern, or nil if it is invalid or over the pattern limits.
+// Invalid patterns are cached too, so a bad rule is not recompiled on every evaluation.
+func (c *patternCache) get(pattern string) *regexp.Regexp {
+	c.mu.RLock()
+	re, ok := c.patterns[pattern]
+	c.mu.RUnlock()
+	if ok {
+		return re
+	}
+
+	re, err := flag.CompilePattern(pattern)
+	if err != nil {
+		re = nil
+	}
+
+	c.mu.Lock()
+	if len(c.patterns) >= maxCachedPatterns {
+		clear(c.patterns)
+	}
+	c.patterns[pattern] = re
+	c.mu.Unlock()
+	return re
+}
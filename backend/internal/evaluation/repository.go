@@ -0,0 +1,69 @@
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type snapshotRepository struct {
+	db *sqlx.DB
+}
+
+// NewSnapshotRepository returns a SnapshotStore backed by the project_snapshots table
+func NewSnapshotRepository(db *sqlx.DB) SnapshotStore {
+	return &snapshotRepository{db: db}
+}
+
+// SaveSnapshot stores a project's snapshot unless a newer generation is already stored
+func (r *snapshotRepository) SaveSnapshot(ctx context.Context, tenantID string, snapshot *Snapshot) error {
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO project_snapshots (project_id, tenant_id, generation, snapshot, generated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id) DO UPDATE
+		SET generation = EXCLUDED.generation,
+		    snapshot = EXCLUDED.snapshot,
+		    generated_at = EXCLUDED.generated_at
+		WHERE project_snapshots.generation < EXCLUDED.generation
+	`
+	_, err = r.db.ExecContext(ctx, query, snapshot.ProjectID, tenantID, snapshot.Generation, snapshotJSON, snapshot.GeneratedAt)
+	return err
+}
+
+// ListSnapshots returns every stored snapshot generated since the given time
+func (r *snapshotRepository) ListSnapshots(ctx context.Context, since time.Time) ([]StoredSnapshot, error) {
+	query := `
+		SELECT tenant_id, snapshot
+		FROM project_snapshots
+		WHERE generated_at >= $1
+	`
+	rows, err := r.db.QueryxContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []StoredSnapshot{}
+	for rows.Next() {
+		var tenantID string
+		var snapshotJSON []byte
+		if err := rows.Scan(&tenantID, &snapshotJSON); err != nil {
+			return nil, err
+		}
+
+		var snapshot Snapshot
+		if err := json.Unmarshal(snapshotJSON, &snapshot); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, StoredSnapshot{TenantID: tenantID, Snapshot: &snapshot})
+	}
+
+	return snapshots, rows.Err()
+}
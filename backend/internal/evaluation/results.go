@@ -0,0 +1,118 @@
+package evaluation
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	"github.com/jalil32/toggle/internal/pkg/batch"
+)
+
+// ResultRecorder records evaluation results, and the reason for each, for the flag list stats
+type ResultRecorder interface {
+	Record(results map[string]bool, reasons map[string]string)
+}
+
+// ResultStore persists hourly evaluation counts per flag
+type ResultStore interface {
+	RecordResults(ctx context.Context, counts map[string]flag.ResultCount, hour time.Time) error
+}
+
+// ResultTracker counts evaluations, true results and reasons per flag in memory and writes them
+// to the store periodically, keeping database writes off the evaluation path
+type ResultTracker struct {
+	store    ResultStore
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending map[time.Time]map[string]flag.ResultCount // hour -> flag ID -> counts
+}
+
+func NewResultTracker(store ResultStore, interval time.Duration, logger *slog.Logger) *ResultTracker {
+	return &ResultTracker{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		pending:  make(map[time.Time]map[string]flag.ResultCount),
+	}
+}
+
+// Record counts one evaluation per flag, under its reason in reasons if it has one;
+// it never blocks on the database
+func (t *ResultTracker) Record(results map[string]bool, reasons map[string]string) {
+	t.RecordAt(results, reasons, time.Now())
+}
+
+// RecordAt is Record at a given time, counted in that time's hourly bucket
+func (t *ResultTracker) RecordAt(results map[string]bool, reasons map[string]string, at time.Time) {
+	if len(results) == 0 {
+		return
+	}
+	hour := at.UTC().Truncate(time.Hour)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counts, ok := t.pending[hour]
+	if !ok {
+		counts = make(map[string]flag.ResultCount)
+		t.pending[hour] = counts
+	}
+	for id, enabled := range results {
+		c := counts[id]
+		c.Evaluations++
+		if enabled {
+			c.True++
+		}
+		if reason, ok := reasons[id]; ok {
+			if c.Reasons == nil {
+				c.Reasons = make(map[string]int64)
+			}
+			c.Reasons[reason]++
+		}
+		counts[id] = c
+	}
+}
+
+// Flush writes all pending counts to the store
+func (t *ResultTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	pending := t.pending
+	t.pending = make(map[time.Time]map[string]flag.ResultCount)
+	t.mu.Unlock()
+
+	var firstErr error
+	for hour, counts := range pending {
+		if err := t.store.RecordResults(ctx, counts, hour); err != nil {
+			// Stats are best-effort: a failed flush drops its counts
+			t.logger.Warn("failed to record evaluation results",
+				slog.Time("hour", hour),
+				slog.Int("flags", len(counts)),
+				slog.String("error", err.Error()),
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		t.logger.Debug("evaluation results recorded",
+			slog.Time("hour", hour),
+			slog.Int("flags", len(counts)),
+		)
+	}
+
+	return firstErr
+}
+
+// Run flushes pending counts every interval until ctx is cancelled, then flushes once more
+func (t *ResultTracker) Run(ctx context.Context) {
+	batch.Run(ctx, t.interval, t.Flush)
+}
@@ -0,0 +1,85 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+type mockResultStore struct {
+	recorded map[time.Time]map[string]flag.ResultCount
+	err      error
+}
+
+func (m *mockResultStore) RecordResults(ctx context.Context, counts map[string]flag.ResultCount, hour time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.recorded == nil {
+		m.recorded = make(map[time.Time]map[string]flag.ResultCount)
+	}
+	m.recorded[hour] = counts
+	return nil
+}
+
+func TestResultTracker_CountsPerFlagAndHour(t *testing.T) {
+	store := &mockResultStore{}
+	tracker := NewResultTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	hour := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tracker.RecordAt(map[string]bool{"flag-1": true, "flag-2": false}, nil, hour.Add(10*time.Minute))
+	tracker.RecordAt(map[string]bool{"flag-1": false}, nil, hour.Add(50*time.Minute))
+	tracker.RecordAt(map[string]bool{"flag-1": true}, nil, hour.Add(70*time.Minute))
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Equal(t, map[time.Time]map[string]flag.ResultCount{
+		hour: {
+			"flag-1": {Evaluations: 2, True: 1},
+			"flag-2": {Evaluations: 1, True: 0},
+		},
+		hour.Add(time.Hour): {
+			"flag-1": {Evaluations: 1, True: 1},
+		},
+	}, store.recorded)
+
+	// Nothing pending: no write
+	store.recorded = nil
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Nil(t, store.recorded)
+}
+
+func TestResultTracker_FlushReportsStoreErrors(t *testing.T) {
+	store := &mockResultStore{err: errors.New("database error")}
+	tracker := NewResultTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tracker.Record(map[string]bool{"flag-1": true}, nil)
+
+	assert.Error(t, tracker.Flush(context.Background()))
+}
+
+func TestResultTracker_CountsReasons(t *testing.T) {
+	store := &mockResultStore{}
+	tracker := NewResultTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	hour := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tracker.RecordAt(map[string]bool{"flag-1": true, "flag-2": false},
+		map[string]string{"flag-1": ReasonRulesPassed, "flag-2": ReasonDisabled}, hour)
+	tracker.RecordAt(map[string]bool{"flag-1": false},
+		map[string]string{"flag-1": ReasonRolloutExcluded}, hour)
+	tracker.RecordAt(map[string]bool{"flag-1": true},
+		map[string]string{"flag-1": ReasonRulesPassed}, hour)
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Equal(t, map[string]flag.ResultCount{
+		"flag-1": {Evaluations: 3, True: 2, Reasons: map[string]int64{ReasonRulesPassed: 2, ReasonRolloutExcluded: 1}},
+		"flag-2": {Evaluations: 1, Reasons: map[string]int64{ReasonDisabled: 1}},
+	}, store.recorded[hour])
+}
@@ -2,28 +2,418 @@ package evaluation
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log/slog"
+	"time"
 
+	"golang.org/x/sync/singleflight"
+
+	"github.com/jalil32/toggle/internal/events"
 	flag "github.com/jalil32/toggle/internal/flags"
 	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	"github.com/jalil32/toggle/internal/pkg/degrade"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
 )
 
+// SnapshotMaxStaleness is the longest a relay may serve a snapshot without revalidating it.
+// Any flag change is visible to relays within this window.
+const SnapshotMaxStaleness = 30 * time.Second
+
+// DefaultArchiveGracePeriod is how long archived flags keep being served when none is configured
+const DefaultArchiveGracePeriod = 7 * 24 * time.Hour
+
+// DefaultEvaluationBudget is how long a bulk evaluation may take when no budget is configured
+const DefaultEvaluationBudget = 2 * time.Second
+
+// budgetCheckInterval is how many flags are evaluated between checks of the budget
+const budgetCheckInterval = 64
+
+// snapshotAttempts bounds retries when flags change while a snapshot is being read
+const snapshotAttempts = 3
+
+// ErrSnapshotUnstable indicates flags kept changing while a snapshot was being read
+var ErrSnapshotUnstable = errors.New("project changed while building snapshot")
+
+// ErrFlagNotActive indicates a draft or deprecated flag, or an archived flag past its grace period,
+// which SDKs can't evaluate
+var ErrFlagNotActive = errors.New("flag is not active")
+
+// ProjectReader is the subset of the projects repository needed for snapshots
+type ProjectReader interface {
+	GetGeneration(ctx context.Context, id string, tenantID string) (int64, error)
+	// GetCacheState returns the generation with how long cached flags may be served without reading it again
+	GetCacheState(ctx context.Context, id string, tenantID string) (int64, time.Duration, error)
+	GetEvaluationSettings(ctx context.Context, id string, tenantID string) (*ProjectSettings, error)
+}
+
+// ProjectSettings are the project settings that change how its flags evaluate
+type ProjectSettings struct {
+	// Bucketing is the project's bucketing algorithm, in its strict variant when the tenant
+	// has opted into strict rollouts
+	Bucketing string
+	// GeoTargeting fills in missing country and region attributes from the caller's IP
+	GeoTargeting bool
+	// DefaultAttributes are merged into every context's user attributes; the context's own
+	// values, and geo targeting's, take precedence
+	DefaultAttributes map[string]interface{}
+}
+
 type Service interface {
 	EvaluateAll(ctx context.Context, projectID string, evalCtx EvaluationContext) (*EvaluationResponse, error)
 	EvaluateSingle(ctx context.Context, flagID string, tenantID string, evalCtx EvaluationContext) (*SingleEvaluationResponse, error)
+	EvaluateByKey(ctx context.Context, projectID string, key string, evalCtx EvaluationContext) (*SingleEvaluationResponse, error)
+	Explain(ctx context.Context, flagID string, tenantID string, environmentID string, evalCtx EvaluationContext) (*Explanation, error)
+	Generation(ctx context.Context, projectID string) (int64, error)
+	Snapshot(ctx context.Context, projectID string) (*Snapshot, error)
+	Ruleset(ctx context.Context, projectID string) (*Ruleset, error)
+	SetUsageRecorder(usage UsageRecorder)
+	SetResultRecorder(results ResultRecorder)
+	SetDegradation(gate degrade.Gate)
+	SetAttributeRecorder(attributes AttributeRecorder)
+	SetEventRecorder(recorder events.Recorder)
+	SetSnapshotCache(cache *SnapshotCache, store SnapshotStore)
+	SetArchiveGracePeriod(grace time.Duration)
+	SetEvaluationBudget(budget time.Duration)
+	SetGeoLocator(geo GeoLocator)
 }
 
 type service struct {
-	flagRepo  flag.Repository
-	evaluator *Evaluator
-	logger    *slog.Logger
+	flagRepo    flag.Repository
+	projectRepo ProjectReader
+	usage       UsageRecorder
+	results     ResultRecorder
+	degradation degrade.Gate
+	attributes  AttributeRecorder
+	events      events.Recorder
+	cache       *SnapshotCache
+	snapshots   SnapshotStore
+	evaluator   *Evaluator
+	// archiveGrace is how long archived flags are still served; zero drops them on archival
+	archiveGrace time.Duration
+	// budget bounds how long a bulk evaluation takes before partial results are returned; zero is unbounded
+	budget time.Duration
+	// loads collapses concurrent budgeted loads of the same project's flags into one
+	loads singleflight.Group
+	// geo locates callers for projects with geo targeting; nil leaves their contexts as sent
+	geo    GeoLocator
+	logger *slog.Logger
 }
 
-func NewService(flagRepo flag.Repository, logger *slog.Logger) Service {
+func NewService(flagRepo flag.Repository, projectRepo ProjectReader, logger *slog.Logger) Service {
 	return &service{
-		flagRepo:  flagRepo,
-		evaluator: NewEvaluator(),
-		logger:    logger,
+		flagRepo:    flagRepo,
+		projectRepo: projectRepo,
+		evaluator:   NewEvaluator(),
+		logger:      logger,
+	}
+}
+
+// SetUsageRecorder sets where evaluated flags are reported for stale flag detection
+func (s *service) SetUsageRecorder(usage UsageRecorder) {
+	s.usage = usage
+}
+
+// SetDegradation lets an overloaded instance shed debug logging and analytics writes
+func (s *service) SetDegradation(gate degrade.Gate) {
+	s.degradation = gate
+}
+
+// allow reports whether non-critical work may run; without a gate it always may
+func (s *service) allow(f degrade.Feature) bool {
+	return s.degradation == nil || s.degradation.Allow(f)
+}
+
+// recordUsage reports evaluated flags, if a recorder is configured
+func (s *service) recordUsage(flagIDs ...string) {
+	if s.usage != nil && len(flagIDs) > 0 && s.allow(degrade.FeatureAnalytics) {
+		s.usage.Record(flagIDs...)
+	}
+}
+
+// SetResultRecorder sets where evaluation results are reported for the flag list stats
+func (s *service) SetResultRecorder(results ResultRecorder) {
+	s.results = results
+}
+
+// recordResults reports evaluation results and their reasons, if a recorder is configured
+func (s *service) recordResults(results map[string]bool, reasons map[string]string) {
+	if s.results != nil && len(results) > 0 && s.allow(degrade.FeatureAnalytics) {
+		s.results.Record(results, reasons)
+	}
+}
+
+// SetEventRecorder sets where each flag evaluation is reported as an event for analytics
+func (s *service) SetEventRecorder(recorder events.Recorder) {
+	s.events = recorder
+}
+
+// recordEvents reports one event per evaluated flag, if a recorder is configured
+func (s *service) recordEvents(tenantID string, projectID *string, userID string, results map[string]bool, reasons map[string]string) {
+	if s.events == nil || len(results) == 0 || !s.allow(degrade.FeatureAnalytics) {
+		return
+	}
+	now := time.Now()
+	batch := make([]events.Event, 0, len(results))
+	for id, enabled := range results {
+		batch = append(batch, events.Event{
+			FlagID:      id,
+			TenantID:    tenantID,
+			ProjectID:   projectID,
+			UserID:      userID,
+			Enabled:     enabled,
+			Reason:      reasons[id],
+			EvaluatedAt: now,
+		})
+	}
+	s.events.Record(batch...)
+}
+
+// SetAttributeRecorder sets where context attribute names are reported for the attribute mismatch report
+func (s *service) SetAttributeRecorder(attributes AttributeRecorder) {
+	s.attributes = attributes
+}
+
+// recordAttributes reports the context's attribute names, if a recorder is configured
+func (s *service) recordAttributes(projectID *string, evalCtx EvaluationContext) {
+	if s.attributes != nil && projectID != nil && s.allow(degrade.FeatureAnalytics) {
+		s.attributes.Record(*projectID, evalCtx.qualifiedAttributes())
+	}
+}
+
+// SetSnapshotCache serves bulk evaluations from cached project snapshots and persists
+// every snapshot built to store, so new instances can prime their cache from it
+func (s *service) SetSnapshotCache(cache *SnapshotCache, store SnapshotStore) {
+	s.cache = cache
+	s.snapshots = store
+}
+
+// SetArchiveGracePeriod keeps archived flags served, with an archived reason, for grace after archival
+func (s *service) SetArchiveGracePeriod(grace time.Duration) {
+	s.archiveGrace = grace
+}
+
+// SetEvaluationBudget bounds how long a bulk evaluation may take. Past the budget, the flags
+// evaluated so far are returned marked partial; zero disables the budget.
+func (s *service) SetEvaluationBudget(budget time.Duration) {
+	s.budget = budget
+}
+
+// SetGeoLocator sets the GeoIP database that fills in country and region attributes for
+// projects with geo targeting
+func (s *service) SetGeoLocator(geo GeoLocator) {
+	s.geo = geo
+}
+
+// projectFlagSet is a project's flags as served to a bulk evaluation
+type projectFlagSet struct {
+	flags    []flag.Flag
+	settings ProjectSettings
+	// staleness is how long ago the flags were confirmed current
+	staleness time.Duration
+	// snapshot is set when the flags came from a snapshot; users are then targeted from its
+	// exclusions and overrides rather than read from the repository
+	snapshot *Snapshot
+}
+
+// projectFlags returns a project's flags from the snapshot cache when it holds the
+// current generation, and otherwise builds (and caches) a fresh snapshot. Projects that
+// tolerate staleness are served from the cache without checking the generation until
+// their tolerance runs out.
+// Requests made with an environment key get the flags as configured in that environment.
+func (s *service) projectFlags(ctx context.Context, projectID string, tenantID string) (*projectFlagSet, error) {
+	environmentID := appContext.EnvironmentID(ctx)
+	if s.cache == nil {
+		return s.uncachedProjectFlags(ctx, projectID, tenantID, environmentID)
+	}
+
+	now := time.Now()
+	if snapshot, staleness, ok := s.cache.Fresh(projectID, tenantID, now); ok {
+		return snapshotFlagSet(snapshot.ForEnvironment(environmentID), now, staleness), nil
+	}
+
+	generation, maxStaleness, err := s.projectRepo.GetCacheState(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to fetch project generation",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	if snapshot, ok := s.cache.Get(projectID, tenantID, generation); ok {
+		s.cache.Validate(projectID, generation, maxStaleness, now)
+		return snapshotFlagSet(snapshot.ForEnvironment(environmentID), now, 0), nil
+	}
+
+	snapshot, err := s.Snapshot(ctx, projectID)
+	if errors.Is(err, ErrSnapshotUnstable) {
+		// Flags are changing right now; read them directly rather than failing the evaluation
+		return s.uncachedProjectFlags(ctx, projectID, tenantID, environmentID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Validate(projectID, snapshot.Generation, maxStaleness, snapshot.GeneratedAt)
+	return snapshotFlagSet(snapshot.ForEnvironment(environmentID), time.Now(), 0), nil
+}
+
+func snapshotFlagSet(snapshot *Snapshot, now time.Time, staleness time.Duration) *projectFlagSet {
+	return &projectFlagSet{
+		flags: flagsFromSnapshot(snapshot, now),
+		settings: ProjectSettings{
+			Bucketing:         snapshot.Bucketing,
+			GeoTargeting:      snapshot.GeoTargeting,
+			DefaultAttributes: snapshot.DefaultAttributes,
+		},
+		staleness: staleness,
+		snapshot:  snapshot,
+	}
+}
+
+// uncachedProjectFlags reads a project's flags and evaluation settings from the repositories
+func (s *service) uncachedProjectFlags(ctx context.Context, projectID string, tenantID string, environmentID string) (*projectFlagSet, error) {
+	settings, err := s.settings(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := s.environmentFlags(ctx, projectID, tenantID, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	return &projectFlagSet{flags: flags, settings: *settings}, nil
+}
+
+// settings returns the project settings its flags are evaluated with
+func (s *service) settings(ctx context.Context, projectID string, tenantID string) (*ProjectSettings, error) {
+	settings, err := s.projectRepo.GetEvaluationSettings(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to fetch project evaluation settings",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return settings, nil
+}
+
+// applySettings returns the context as the project evaluates it: bucketed with the project's
+// algorithm, with country and region filled in from its client IP under geo targeting, and
+// then with the project's default attributes filling in whatever is still missing
+func (s *service) applySettings(evalCtx EvaluationContext, settings ProjectSettings) EvaluationContext {
+	evalCtx = evalCtx.WithBucketing(settings.Bucketing)
+	if settings.GeoTargeting {
+		evalCtx = enrichGeo(evalCtx, s.geo)
+	}
+	return evalCtx.withDefaults(settings.DefaultAttributes)
+}
+
+// budgetedProjectFlags is projectFlags bounded by deadline. When the deadline passes first it
+// returns nil flags, leaving the load running detached from the request so it still warms
+// the snapshot cache for the next evaluation. Requests for the same project and environment
+// share one load, so a slow load isn't repeated by every request that gives up on it.
+// A zero deadline waits for the load.
+func (s *service) budgetedProjectFlags(ctx context.Context, projectID string, tenantID string, deadline time.Time) (*projectFlagSet, error) {
+	if deadline.IsZero() {
+		return s.projectFlags(ctx, projectID, tenantID)
+	}
+
+	key := tenantID + "/" + projectID + "/" + appContext.EnvironmentID(ctx)
+	done := s.loads.DoChan(key, func() (interface{}, error) {
+		return s.projectFlags(context.WithoutCancel(ctx), projectID, tenantID)
+	})
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case l := <-done:
+		if l.Err != nil {
+			return nil, l.Err
+		}
+		return l.Val.(*projectFlagSet), nil
+	case <-timer.C:
+		s.logger.Warn("evaluation budget exceeded loading flags, warming cache in background",
+			slog.String("project_id", projectID),
+			slog.Duration("budget", s.budget),
+		)
+		return nil, nil
+	}
+}
+
+// environmentFlags reads a project's active flags from the repository with an
+// environment's configs applied; an empty environment ID returns them unchanged
+func (s *service) environmentFlags(ctx context.Context, projectID string, tenantID string, environmentID string) ([]flag.Flag, error) {
+	flags, err := s.activeProjectFlags(ctx, projectID, tenantID)
+	if err != nil || environmentID == "" {
+		return flags, err
+	}
+
+	configs, err := s.flagRepo.ListEnvironmentConfigsByProject(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range flags {
+		flags[i] = f.InEnvironment(findEnvironmentConfig(configs[f.ID], environmentID))
+	}
+	return flags, nil
+}
+
+// findEnvironmentConfig returns the config for an environment, or nil if the flag has none there
+func findEnvironmentConfig(configs []flag.EnvironmentConfig, environmentID string) *flag.EnvironmentConfig {
+	for i := range configs {
+		if configs[i].EnvironmentID == environmentID {
+			return &configs[i]
+		}
+	}
+	return nil
+}
+
+// activeProjectFlags reads a project's flags from the repository, keeping only those
+// served to SDKs (draft, deprecated and archived flags past their grace period are left out)
+func (s *service) activeProjectFlags(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+	flags, err := s.flagRepo.ListByProject(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return servedFlags(flags, s.archiveGrace, time.Now()), nil
+}
+
+func servedFlags(flags []flag.Flag, archiveGrace time.Duration, now time.Time) []flag.Flag {
+	served := make([]flag.Flag, 0, len(flags))
+	for _, f := range flags {
+		if f.IsActive() || f.InArchiveGrace(archiveGrace, now) {
+			served = append(served, f)
+		}
+	}
+	return served
+}
+
+// logArchivedRequests warns about consumers still evaluating archived flags, so they can be
+// chased before the grace period ends and the flags disappear
+func (s *service) logArchivedRequests(ctx context.Context, projectID string, flagIDs []string) {
+	if len(flagIDs) == 0 {
+		return
+	}
+	s.logger.Warn("archived flags still requested",
+		slog.String("project_id", projectID),
+		slog.String("environment_id", appContext.EnvironmentID(ctx)),
+		slog.Any("flag_ids", flagIDs),
+	)
+}
+
+// storeSnapshot caches and persists a freshly built snapshot; persistence failures are only logged
+func (s *service) storeSnapshot(ctx context.Context, tenantID string, snapshot *Snapshot) {
+	if s.cache != nil {
+		s.cache.Put(tenantID, snapshot)
+	}
+	if s.snapshots == nil {
+		return
+	}
+	if err := s.snapshots.SaveSnapshot(ctx, tenantID, snapshot); err != nil {
+		s.logger.Warn("failed to persist snapshot",
+			slog.String("project_id", snapshot.ProjectID),
+			slog.Int64("generation", snapshot.Generation),
+			slog.String("error", err.Error()),
+		)
 	}
 }
 
@@ -32,8 +422,13 @@ func (s *service) EvaluateAll(ctx context.Context, projectID string, evalCtx Eva
 	// Extract tenant ID from context (injected by API key middleware)
 	tenantID := appContext.MustTenantID(ctx)
 
+	var deadline time.Time
+	if s.budget > 0 {
+		deadline = time.Now().Add(s.budget)
+	}
+
 	// Fetch all flags for this project
-	flags, err := s.flagRepo.ListByProject(ctx, projectID, tenantID)
+	set, err := s.budgetedProjectFlags(ctx, projectID, tenantID, deadline)
 	if err != nil {
 		s.logger.Error("failed to fetch flags for evaluation",
 			slog.String("project_id", projectID),
@@ -41,28 +436,83 @@ func (s *service) EvaluateAll(ctx context.Context, projectID string, evalCtx Eva
 		)
 		return nil, err
 	}
+	if set == nil {
+		return &EvaluationResponse{Flags: map[string]bool{}, Partial: true}, nil
+	}
+
+	evalCtx, err = s.userTargeting(ctx, tenantID, set.snapshot, evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	evalCtx = s.applySettings(evalCtx, set.settings)
 
 	// Evaluate each flag
 	results := make(map[string]bool)
-	for _, f := range flags {
-		enabled := s.evaluator.Evaluate(&f, evalCtx)
+	reasons := make(map[string]string, len(set.flags))
+	keys := make(map[string]string, len(set.flags))
+	flagIDs := make([]string, 0, len(set.flags))
+	var archived []string
+	partial := false
+	for i, f := range set.flags {
+		if !deadline.IsZero() && i%budgetCheckInterval == 0 && time.Now().After(deadline) {
+			partial = true
+			break
+		}
+		enabled, reason := s.evaluator.EvaluateWithReason(&f, evalCtx)
 		results[f.ID] = enabled
+		reasons[f.ID] = reason
+		keys[f.ID] = f.Key
+		flagIDs = append(flagIDs, f.ID)
+		if f.Lifecycle == flag.LifecycleArchived {
+			archived = append(archived, f.ID)
+		}
 
-		s.logger.Debug("flag evaluated",
-			slog.String("flag_id", f.ID),
-			slog.String("flag_name", f.Name),
-			slog.Bool("enabled", enabled),
-			slog.String("user_id", evalCtx.UserID),
-		)
+		if s.allow(degrade.FeatureDebugLogging) {
+			s.logger.Debug("flag evaluated",
+				slog.String("flag_id", f.ID),
+				slog.String("flag_name", f.Name),
+				slog.Bool("enabled", enabled),
+				slog.String("user_id", evalCtx.UserID),
+			)
+		}
 	}
 
+	s.recordUsage(flagIDs...)
+	s.recordResults(results, reasons)
+	s.recordEvents(tenantID, &projectID, evalCtx.BucketingKey(), results, reasons)
+	s.recordAttributes(&projectID, evalCtx)
+	s.logArchivedRequests(ctx, projectID, archived)
+
 	s.logger.Info("bulk evaluation completed",
 		slog.String("project_id", projectID),
 		slog.String("user_id", evalCtx.UserID),
 		slog.Int("flags_evaluated", len(results)),
+		slog.Bool("partial", partial),
 	)
 
-	return &EvaluationResponse{Flags: results}, nil
+	return &EvaluationResponse{Flags: results, Archived: archived, Partial: partial, Staleness: set.staleness, keys: keys, reasons: reasons}, nil
+}
+
+// EvaluateByKey evaluates the project's flag with the given key. The key is looked up among
+// the project's evaluable flags, so draft and deprecated flags are not found.
+func (s *service) EvaluateByKey(ctx context.Context, projectID string, key string, evalCtx EvaluationContext) (*SingleEvaluationResponse, error) {
+	tenantID := appContext.MustTenantID(ctx)
+
+	set, err := s.projectFlags(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to fetch flags for evaluation by key",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	for _, f := range set.flags {
+		if f.Key == key {
+			return s.EvaluateSingle(ctx, f.ID, tenantID, evalCtx)
+		}
+	}
+	return nil, pkgErrors.ErrNotFound
 }
 
 // EvaluateSingle evaluates a single flag
@@ -76,9 +526,35 @@ func (s *service) EvaluateSingle(ctx context.Context, flagID string, tenantID st
 		)
 		return nil, err
 	}
+	archived := f.InArchiveGrace(s.archiveGrace, time.Now())
+	if !f.IsActive() && !archived {
+		return nil, ErrFlagNotActive
+	}
+	if environmentID := appContext.EnvironmentID(ctx); environmentID != "" {
+		config, err := s.flagRepo.GetEnvironmentConfig(ctx, flagID, environmentID, tenantID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Error("failed to fetch flag environment config for evaluation",
+				slog.String("flag_id", flagID),
+				slog.String("environment_id", environmentID),
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+		resolved := f.InEnvironment(config)
+		f = &resolved
+	}
+
+	evalCtx, err = s.flagTargeting(ctx, f, tenantID, evalCtx)
+	if err != nil {
+		return nil, err
+	}
 
 	// Evaluate
-	enabled := s.evaluator.Evaluate(f, evalCtx)
+	enabled, reason := s.evaluator.EvaluateWithReason(f, evalCtx)
+	s.recordUsage(f.ID)
+	s.recordResults(map[string]bool{f.ID: enabled}, map[string]string{f.ID: reason})
+	s.recordEvents(tenantID, f.ProjectID, evalCtx.BucketingKey(), map[string]bool{f.ID: enabled}, map[string]string{f.ID: reason})
+	s.recordAttributes(f.ProjectID, evalCtx)
 
 	s.logger.Info("flag evaluated",
 		slog.String("flag_id", flagID),
@@ -87,8 +563,351 @@ func (s *service) EvaluateSingle(ctx context.Context, flagID string, tenantID st
 		slog.String("user_id", evalCtx.UserID),
 	)
 
-	return &SingleEvaluationResponse{
-		Enabled: enabled,
-		FlagID:  flagID,
-	}, nil
+	resp := &SingleEvaluationResponse{
+		Enabled:          enabled,
+		FlagID:           flagID,
+		FlagKey:          f.Key,
+		evaluationReason: reason,
+	}
+	if archived {
+		resp.Reason = ReasonArchived
+		if f.ProjectID != nil {
+			s.logArchivedRequests(ctx, *f.ProjectID, []string{f.ID})
+		}
+	}
+	return resp, nil
+}
+
+// Explain evaluates a flag like EvaluateSingle, with the user's overrides and exclusions and
+// the project's bucketing, and reports how each rule fared. An environment ID evaluates the
+// environment's config of the flag. Nothing is recorded, so explaining doesn't count as use.
+func (s *service) Explain(ctx context.Context, flagID string, tenantID string, environmentID string, evalCtx EvaluationContext) (*Explanation, error) {
+	f, err := s.flagRepo.GetByID(ctx, flagID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to fetch flag for explanation",
+			slog.String("flag_id", flagID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	if environmentID != "" {
+		config, err := s.flagRepo.GetEnvironmentConfig(ctx, flagID, environmentID, tenantID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Error("failed to fetch flag environment config for explanation",
+				slog.String("flag_id", flagID),
+				slog.String("environment_id", environmentID),
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+		resolved := f.InEnvironment(config)
+		f = &resolved
+	}
+
+	evalCtx, err = s.flagTargeting(ctx, f, tenantID, evalCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := s.evaluator.Explain(f, evalCtx)
+	if !f.IsActive() && !f.InArchiveGrace(s.archiveGrace, time.Now()) {
+		ex.Enabled, ex.Reason = false, ReasonNotServed
+	}
+	return ex, nil
+}
+
+// flagTargeting attaches the user's exclusions and overrides to the evaluation context and
+// applies the settings of the flag's project, both taken from the project's cached snapshot
+// when it is current
+func (s *service) flagTargeting(ctx context.Context, f *flag.Flag, tenantID string, evalCtx EvaluationContext) (EvaluationContext, error) {
+	if f.ProjectID == nil {
+		return s.loadUserTargeting(ctx, tenantID, evalCtx)
+	}
+
+	snapshot, err := s.cachedSnapshot(ctx, *f.ProjectID, tenantID)
+	if err != nil {
+		return evalCtx, err
+	}
+	evalCtx, err = s.userTargeting(ctx, tenantID, snapshot, evalCtx)
+	if err != nil {
+		return evalCtx, err
+	}
+	if snapshot != nil {
+		return s.applySettings(evalCtx, ProjectSettings{
+			Bucketing:         snapshot.Bucketing,
+			GeoTargeting:      snapshot.GeoTargeting,
+			DefaultAttributes: snapshot.DefaultAttributes,
+		}), nil
+	}
+
+	settings, err := s.settings(ctx, *f.ProjectID, tenantID)
+	if err != nil {
+		return evalCtx, err
+	}
+	return s.applySettings(evalCtx, *settings), nil
+}
+
+// cachedSnapshot returns the project's cached snapshot if it is current, or nil. Unlike
+// projectFlags it never builds one, so a miss costs at most the generation check.
+func (s *service) cachedSnapshot(ctx context.Context, projectID string, tenantID string) (*Snapshot, error) {
+	if s.cache == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if snapshot, _, ok := s.cache.Fresh(projectID, tenantID, now); ok {
+		return snapshot, nil
+	}
+
+	generation, maxStaleness, err := s.projectRepo.GetCacheState(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to fetch project generation",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	snapshot, ok := s.cache.Get(projectID, tenantID, generation)
+	if !ok {
+		return nil, nil
+	}
+	s.cache.Validate(projectID, generation, maxStaleness, now)
+	return snapshot, nil
+}
+
+// userTargeting attaches the user's exclusions and active overrides to the evaluation context,
+// from the snapshot when there is one and from the repository otherwise
+func (s *service) userTargeting(ctx context.Context, tenantID string, snapshot *Snapshot, evalCtx EvaluationContext) (EvaluationContext, error) {
+	if snapshot == nil {
+		return s.loadUserTargeting(ctx, tenantID, evalCtx)
+	}
+	if evalCtx.Anonymous {
+		return evalCtx, nil
+	}
+	excluded, overrides := snapshotTargeting(snapshot, evalCtx.UserID, time.Now())
+	return evalCtx.WithExclusions(excluded).WithOverrides(overrides), nil
+}
+
+// loadUserTargeting attaches the user's exclusions and active overrides to the evaluation context.
+// Anonymous contexts have no user to target.
+func (s *service) loadUserTargeting(ctx context.Context, tenantID string, evalCtx EvaluationContext) (EvaluationContext, error) {
+	if evalCtx.Anonymous {
+		return evalCtx, nil
+	}
+
+	excluded, err := s.flagRepo.ListExcludedFlagIDs(ctx, tenantID, evalCtx.UserID)
+	if err != nil {
+		s.logger.Error("failed to fetch flag exclusions for evaluation",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return evalCtx, err
+	}
+
+	overrides, err := s.flagRepo.ListActiveOverrides(ctx, tenantID, evalCtx.UserID, time.Now())
+	if err != nil {
+		s.logger.Error("failed to fetch flag overrides for evaluation",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return evalCtx, err
+	}
+
+	return evalCtx.WithExclusions(excluded).WithOverrides(overrides), nil
+}
+
+// Generation returns the project's current flag generation
+func (s *service) Generation(ctx context.Context, projectID string) (int64, error) {
+	tenantID := appContext.MustTenantID(ctx)
+
+	generation, err := s.projectRepo.GetGeneration(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to fetch project generation",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return 0, err
+	}
+
+	return generation, nil
+}
+
+// Snapshot returns every flag in the project together with the generation they belong to.
+// The generation is read before and after the flags; if it moved, the read is retried
+// so the flags are guaranteed to match the returned generation.
+func (s *service) Snapshot(ctx context.Context, projectID string) (*Snapshot, error) {
+	tenantID := appContext.MustTenantID(ctx)
+
+	for attempt := 1; attempt <= snapshotAttempts; attempt++ {
+		before, err := s.Generation(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+
+		settings, err := s.settings(ctx, projectID, tenantID)
+		if err != nil {
+			return nil, err
+		}
+
+		flags, err := s.activeProjectFlags(ctx, projectID, tenantID)
+		if err != nil {
+			s.logger.Error("failed to fetch flags for snapshot",
+				slog.String("project_id", projectID),
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+
+		exclusions, err := s.flagRepo.ListExclusionsByProject(ctx, projectID, tenantID)
+		if err != nil {
+			s.logger.Error("failed to fetch flag exclusions for snapshot",
+				slog.String("project_id", projectID),
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+
+		overrides, err := s.flagRepo.ListOverridesByProject(ctx, projectID, tenantID, time.Now())
+		if err != nil {
+			s.logger.Error("failed to fetch flag overrides for snapshot",
+				slog.String("project_id", projectID),
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+
+		environments, err := s.flagRepo.ListEnvironmentConfigsByProject(ctx, projectID, tenantID)
+		if err != nil {
+			s.logger.Error("failed to fetch flag environment configs for snapshot",
+				slog.String("project_id", projectID),
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+
+		after, err := s.Generation(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+
+		if before != after {
+			s.logger.Debug("project changed during snapshot, retrying",
+				slog.String("project_id", projectID),
+				slog.Int("attempt", attempt),
+			)
+			continue
+		}
+
+		snapshotFlags := make([]SnapshotFlag, len(flags))
+		for i, f := range flags {
+			snapshotFlags[i] = SnapshotFlag{
+				ID:        f.ID,
+				Key:       f.Key,
+				Name:      f.Name,
+				Enabled:   f.Enabled,
+				Rules:     f.Rules,
+				RuleLogic: f.RuleLogic,
+				UpdatedAt: f.UpdatedAt,
+				Salt:      f.Salt,
+
+				ExcludedUserKeys: exclusions[f.ID],
+				Overrides:        snapshotOverrides(overrides[f.ID]),
+				Environments:     snapshotEnvironments(environments[f.ID]),
+			}
+			if f.Lifecycle == flag.LifecycleArchived && f.ArchivedAt != nil {
+				until := f.ArchivedAt.Add(s.archiveGrace)
+				snapshotFlags[i].ArchivedUntil = &until
+			}
+		}
+
+		s.logger.Info("snapshot built",
+			slog.String("project_id", projectID),
+			slog.Int64("generation", after),
+			slog.Int("flags", len(snapshotFlags)),
+		)
+
+		snapshot := &Snapshot{
+			ProjectID:           projectID,
+			Generation:          after,
+			Bucketing:           settings.Bucketing,
+			GeoTargeting:        settings.GeoTargeting,
+			DefaultAttributes:   settings.DefaultAttributes,
+			MaxStalenessSeconds: int(SnapshotMaxStaleness.Seconds()),
+			GeneratedAt:         time.Now().UTC(),
+			Flags:               snapshotFlags,
+		}
+		s.storeSnapshot(ctx, tenantID, snapshot)
+
+		return snapshot, nil
+	}
+
+	s.logger.Warn("snapshot unstable after retries",
+		slog.String("project_id", projectID),
+	)
+	return nil, ErrSnapshotUnstable
+}
+
+// Ruleset returns the project's flag definitions as seen through the request's key, served
+// from the snapshot cache when it holds the current generation
+func (s *service) Ruleset(ctx context.Context, projectID string) (*Ruleset, error) {
+	tenantID := appContext.MustTenantID(ctx)
+
+	var snapshot *Snapshot
+	if s.cache != nil {
+		generation, err := s.Generation(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		snapshot, _ = s.cache.Get(projectID, tenantID, generation)
+	}
+	if snapshot == nil {
+		var err error
+		if snapshot, err = s.Snapshot(ctx, projectID); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot = snapshot.ForEnvironment(appContext.EnvironmentID(ctx))
+	ruleset := &Ruleset{
+		ProjectID: snapshot.ProjectID,
+		Version:   snapshot.Generation,
+		Bucketing: snapshot.Bucketing,
+		Flags:     snapshot.Flags,
+
+		DefaultAttributes: snapshot.DefaultAttributes,
+	}
+	if ruleset.Bucketing == "" {
+		// Snapshots persisted before projects chose an algorithm
+		ruleset.Bucketing = BucketingSHA256
+	}
+	return ruleset, nil
+}
+
+// snapshotEnvironments keys a flag's environment configs by environment ID
+func snapshotEnvironments(configs []flag.EnvironmentConfig) map[string]SnapshotEnvironment {
+	if len(configs) == 0 {
+		return nil
+	}
+
+	out := make(map[string]SnapshotEnvironment, len(configs))
+	for _, c := range configs {
+		out[c.EnvironmentID] = SnapshotEnvironment{Enabled: c.Enabled, Rules: c.Rules, RuleLogic: c.RuleLogic}
+	}
+	return out
+}
+
+// snapshotOverrides strips audit fields from overrides before they are served to relays
+func snapshotOverrides(overrides []flag.Override) []SnapshotOverride {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	out := make([]SnapshotOverride, len(overrides))
+	for i, o := range overrides {
+		out[i] = SnapshotOverride{UserKey: o.UserKey, Enabled: o.Enabled, ExpiresAt: o.ExpiresAt}
+	}
+	return out
 }
@@ -0,0 +1,583 @@
+package evaluation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jalil32/toggle/internal/events"
+	flag "github.com/jalil32/toggle/internal/flags"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+// mockFlagRepository implements flag.Repository; only the read paths used by evaluation are stubbed
+type mockFlagRepository struct {
+	flag.Repository
+	listByProjectFn func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error)
+	getByIDFn       func(ctx context.Context, id string, tenantID string) (*flag.Flag, error)
+	excluded        map[string][]string                 // user key -> excluded flag IDs
+	overrides       map[string]map[string]bool          // user key -> flag ID -> forced value
+	environments    map[string][]flag.EnvironmentConfig // flag ID -> environment configs
+	targetingReads  int                                 // per-user exclusion and override reads
+}
+
+func (m *mockFlagRepository) ListByProject(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+	return m.listByProjectFn(ctx, projectID, tenantID)
+}
+
+func (m *mockFlagRepository) GetByID(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+	return m.getByIDFn(ctx, id, tenantID)
+}
+
+func (m *mockFlagRepository) ListExcludedFlagIDs(ctx context.Context, tenantID string, userKey string) ([]string, error) {
+	m.targetingReads++
+	return m.excluded[userKey], nil
+}
+
+func (m *mockFlagRepository) ListActiveOverrides(ctx context.Context, tenantID string, userKey string, now time.Time) (map[string]bool, error) {
+	m.targetingReads++
+	return m.overrides[userKey], nil
+}
+
+func (m *mockFlagRepository) ListOverridesByProject(ctx context.Context, projectID string, tenantID string, now time.Time) (map[string][]flag.Override, error) {
+	byFlag := make(map[string][]flag.Override)
+	for userKey, values := range m.overrides {
+		for id, enabled := range values {
+			byFlag[id] = append(byFlag[id], flag.Override{FlagID: id, UserKey: userKey, Enabled: enabled, ExpiresAt: now.Add(time.Hour)})
+		}
+	}
+	return byFlag, nil
+}
+
+func (m *mockFlagRepository) ListExclusionsByProject(ctx context.Context, projectID string, tenantID string) (map[string][]string, error) {
+	byFlag := make(map[string][]string)
+	for userKey, flagIDs := range m.excluded {
+		for _, id := range flagIDs {
+			byFlag[id] = append(byFlag[id], userKey)
+		}
+	}
+	return byFlag, nil
+}
+
+func (m *mockFlagRepository) ListEnvironmentConfigsByProject(ctx context.Context, projectID string, tenantID string) (map[string][]flag.EnvironmentConfig, error) {
+	return m.environments, nil
+}
+
+func (m *mockFlagRepository) GetEnvironmentConfig(ctx context.Context, flagID string, environmentID string, tenantID string) (*flag.EnvironmentConfig, error) {
+	for _, c := range m.environments[flagID] {
+		if c.EnvironmentID == environmentID {
+			return &c, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+type mockProjectReader struct {
+	generations  []int64
+	calls        int
+	maxStaleness time.Duration
+	bucketing    string
+	geoTargeting bool
+	defaults     map[string]interface{}
+}
+
+func (m *mockProjectReader) GetGeneration(ctx context.Context, id string, tenantID string) (int64, error) {
+	g := m.generations[m.calls]
+	if m.calls < len(m.generations)-1 {
+		m.calls++
+	}
+	return g, nil
+}
+
+func (m *mockProjectReader) GetCacheState(ctx context.Context, id string, tenantID string) (int64, time.Duration, error) {
+	g, err := m.GetGeneration(ctx, id, tenantID)
+	return g, m.maxStaleness, err
+}
+
+func (m *mockProjectReader) GetEvaluationSettings(ctx context.Context, id string, tenantID string) (*ProjectSettings, error) {
+	return &ProjectSettings{Bucketing: m.bucketing, GeoTargeting: m.geoTargeting, DefaultAttributes: m.defaults}, nil
+}
+
+func sdkContext() context.Context {
+	return appContext.WithSDKAuth(context.Background(), "project-1", "tenant-1")
+}
+
+func TestService_Snapshot_ReturnsFlagsAtGeneration(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			assert.Equal(t, "tenant-1", tenantID)
+			return []flag.Flag{
+				{ID: "flag-1", Name: "checkout", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+	}
+	projects := &mockProjectReader{generations: []int64{7, 7}}
+	svc := NewService(flags, projects, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	snapshot, err := svc.Snapshot(sdkContext(), "project-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), snapshot.Generation)
+	assert.Equal(t, "project-1", snapshot.ProjectID)
+	assert.Equal(t, int(SnapshotMaxStaleness.Seconds()), snapshot.MaxStalenessSeconds)
+	require.Len(t, snapshot.Flags, 1)
+	assert.Equal(t, "checkout", snapshot.Flags[0].Name)
+}
+
+func TestService_Snapshot_RetriesWhenGenerationMoves(t *testing.T) {
+	reads := 0
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			reads++
+			return []flag.Flag{}, nil
+		},
+	}
+	// First attempt sees 1 -> 2, second attempt is stable at 2
+	projects := &mockProjectReader{generations: []int64{1, 2, 2, 2}}
+	svc := NewService(flags, projects, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	snapshot, err := svc.Snapshot(sdkContext(), "project-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), snapshot.Generation)
+	assert.Equal(t, 2, reads)
+}
+
+func TestService_Snapshot_GivesUpWhenAlwaysChanging(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{}, nil
+		},
+	}
+	projects := &mockProjectReader{generations: []int64{1, 2, 3, 4, 5, 6, 7}}
+	svc := NewService(flags, projects, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err := svc.Snapshot(sdkContext(), "project-1")
+
+	assert.True(t, errors.Is(err, ErrSnapshotUnstable))
+}
+
+func TestService_EvaluateAll_AppliesExclusions(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-2", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+		excluded: map[string][]string{"escalated-user": {"flag-1"}},
+	}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resp, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "escalated-user"})
+	require.NoError(t, err)
+	assert.False(t, resp.Flags["flag-1"])
+	assert.True(t, resp.Flags["flag-2"])
+
+	resp, err = svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "other-user"})
+	require.NoError(t, err)
+	assert.True(t, resp.Flags["flag-1"])
+}
+
+func TestService_EvaluateSingle_AppliesExclusions(t *testing.T) {
+	flags := &mockFlagRepository{
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			return &flag.Flag{ID: id, Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}, nil
+		},
+		excluded: map[string][]string{"escalated-user": {"flag-1"}},
+	}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resp, err := svc.EvaluateSingle(sdkContext(), "flag-1", "tenant-1", EvaluationContext{UserID: "escalated-user"})
+
+	require.NoError(t, err)
+	assert.False(t, resp.Enabled)
+}
+
+func TestService_EvaluateByKey(t *testing.T) {
+	stored := []flag.Flag{
+		{ID: "flag-1", Key: "new-checkout", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+		{ID: "flag-2", Key: "dark-mode", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"},
+	}
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return stored, nil
+		},
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			for _, f := range stored {
+				if f.ID == id {
+					return &f, nil
+				}
+			}
+			return nil, sql.ErrNoRows
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{generations: []int64{1}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resp, err := svc.EvaluateByKey(sdkContext(), "project-1", "new-checkout", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.Equal(t, &SingleEvaluationResponse{Enabled: true, FlagID: "flag-1", FlagKey: "new-checkout", evaluationReason: ReasonNoRules}, resp)
+
+	_, err = svc.EvaluateByKey(sdkContext(), "project-1", "unknown", EvaluationContext{UserID: "user-1"})
+	assert.ErrorIs(t, err, pkgErrors.ErrNotFound)
+
+	bulk, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"new-checkout": true, "dark-mode": false}, bulk.KeyedByFlagKey().Flags)
+	assert.Equal(t, map[string]bool{"flag-1": true, "flag-2": false}, bulk.Flags, "the ID-keyed response is left as it was")
+}
+
+func TestService_Snapshot_IncludesExclusions(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}}, nil
+		},
+		excluded: map[string][]string{"escalated-user": {"flag-1"}},
+	}
+	projects := &mockProjectReader{generations: []int64{3, 3}}
+	svc := NewService(flags, projects, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	snapshot, err := svc.Snapshot(sdkContext(), "project-1")
+
+	require.NoError(t, err)
+	require.Len(t, snapshot.Flags, 1)
+	assert.Equal(t, []string{"escalated-user"}, snapshot.Flags[0].ExcludedUserKeys)
+}
+
+func TestService_EvaluateAll_OverridesWinOverRulesAndExclusions(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "disabled-flag", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "excluded-flag", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "enabled-flag", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+		excluded: map[string][]string{"qa-user": {"excluded-flag"}},
+		overrides: map[string]map[string]bool{
+			"qa-user": {"disabled-flag": true, "excluded-flag": true, "enabled-flag": false},
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resp, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "qa-user"})
+
+	require.NoError(t, err)
+	assert.True(t, resp.Flags["disabled-flag"], "override forces a disabled flag on")
+	assert.True(t, resp.Flags["excluded-flag"], "override wins over exclusion")
+	assert.False(t, resp.Flags["enabled-flag"], "override forces an enabled flag off")
+}
+
+func TestService_Snapshot_SkipsInactiveFlags(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "flag-1", Name: "checkout", Enabled: true, Lifecycle: flag.LifecycleActive, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-2", Name: "legacy", Enabled: true, Lifecycle: flag.LifecycleDeprecated, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-3", Name: "wip", Enabled: true, Lifecycle: flag.LifecycleDraft, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{generations: []int64{1, 1}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	snapshot, err := svc.Snapshot(sdkContext(), "project-1")
+
+	require.NoError(t, err)
+	require.Len(t, snapshot.Flags, 1)
+	assert.Equal(t, "checkout", snapshot.Flags[0].Name)
+}
+
+func TestService_EvaluateSingle_RejectsInactiveFlag(t *testing.T) {
+	flags := &mockFlagRepository{
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			return &flag.Flag{ID: id, Enabled: true, Lifecycle: flag.LifecycleArchived, Rules: []flag.Rule{}, RuleLogic: "AND"}, nil
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	_, err := svc.EvaluateSingle(sdkContext(), "flag-1", "tenant-1", EvaluationContext{UserID: "user-1"})
+
+	assert.ErrorIs(t, err, ErrFlagNotActive)
+}
+
+func TestService_Explain_TracesRulesWithUserTargeting(t *testing.T) {
+	projectID := "project-1"
+	stored := &flag.Flag{ID: "flag-1", ProjectID: &projectID, Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{
+		{ID: "rule-1", Attribute: "country", Operator: "equals", Value: "AU", Rollout: 100},
+		{ID: "rule-2", Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 0},
+	}}
+	flags := &mockFlagRepository{
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			if id != stored.ID {
+				return nil, sql.ErrNoRows
+			}
+			f := *stored
+			return &f, nil
+		},
+		overrides: map[string]map[string]bool{"qa-user": {"flag-1": true}},
+	}
+	svc := NewService(flags, &mockProjectReader{bucketing: BucketingMurmur3}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	evalCtx := EvaluationContext{UserID: "user-1", Attributes: map[string]interface{}{"country": "AU"}}
+
+	ex, err := svc.Explain(context.Background(), "flag-1", "tenant-1", "", evalCtx)
+	require.NoError(t, err)
+	assert.False(t, ex.Enabled)
+	assert.Equal(t, ReasonRulesFailed, ex.Reason)
+	assert.Equal(t, BucketingMurmur3, ex.Bucketing)
+	assert.Equal(t, BucketerFor(BucketingMurmur3).Bucket(stored, "user-1"), ex.Bucket)
+	require.Len(t, ex.Rules, 2)
+	assert.True(t, ex.Rules[0].Matched)
+	assert.Equal(t, "AU", ex.Rules[0].ContextValue)
+	assert.Equal(t, 100, ex.Rules[0].Rollout)
+	assert.False(t, ex.Rules[1].Matched)
+	assert.Nil(t, ex.Rules[1].ContextValue, "a missing attribute has no context value")
+
+	evalCtx.UserID = "qa-user"
+	ex, err = svc.Explain(context.Background(), "flag-1", "tenant-1", "", evalCtx)
+	require.NoError(t, err)
+	assert.True(t, ex.Enabled)
+	assert.Equal(t, ReasonOverride, ex.Reason)
+
+	stored.Lifecycle = flag.LifecycleDraft
+	ex, err = svc.Explain(context.Background(), "flag-1", "tenant-1", "", evalCtx)
+	require.NoError(t, err)
+	assert.False(t, ex.Enabled)
+	assert.Equal(t, ReasonNotServed, ex.Reason)
+
+	_, err = svc.Explain(context.Background(), "missing", "tenant-1", "", evalCtx)
+	assert.ErrorIs(t, err, pkgErrors.ErrNotFound)
+}
+
+func TestService_EvaluateAll_MergesProjectDefaultAttributes(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "production-only", Enabled: true, RuleLogic: "AND", Rules: []flag.Rule{
+					{Attribute: "environment", Operator: "equals", Value: "production", Rollout: 100},
+				}},
+			}, nil
+		},
+	}
+	projects := &mockProjectReader{generations: []int64{1}, defaults: map[string]interface{}{"environment": "production"}}
+	svc := NewService(flags, projects, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.SetSnapshotCache(NewSnapshotCache(slog.New(slog.NewTextHandler(io.Discard, nil))), nil)
+
+	resp, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.True(t, resp.Flags["production-only"], "defaults fill in attributes the context doesn't send")
+
+	staging := EvaluationContext{UserID: "user-1", Attributes: map[string]interface{}{"environment": "staging"}}
+	resp, err = svc.EvaluateAll(sdkContext(), "project-1", staging)
+	require.NoError(t, err)
+	assert.False(t, resp.Flags["production-only"], "the context's own attributes take precedence")
+	assert.Equal(t, map[string]interface{}{"environment": "staging"}, staging.Attributes)
+}
+
+func TestService_EvaluateAll_ServesArchivedFlagsDuringGracePeriod(t *testing.T) {
+	recent := time.Now().Add(-time.Hour)
+	old := time.Now().Add(-48 * time.Hour)
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-2", Enabled: true, Lifecycle: flag.LifecycleArchived, ArchivedAt: &recent, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-3", Enabled: true, Lifecycle: flag.LifecycleArchived, ArchivedAt: &old, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.SetArchiveGracePeriod(24 * time.Hour)
+
+	resp, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"flag-1": true, "flag-2": true}, resp.Flags)
+	assert.Equal(t, []string{"flag-2"}, resp.Archived)
+}
+
+func TestService_EvaluateSingle_ServesArchivedFlagDuringGracePeriod(t *testing.T) {
+	archivedAt := time.Now().Add(-time.Hour)
+	flags := &mockFlagRepository{
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			projectID := "project-1"
+			return &flag.Flag{ID: id, ProjectID: &projectID, Enabled: true, Lifecycle: flag.LifecycleArchived, ArchivedAt: &archivedAt, Rules: []flag.Rule{}, RuleLogic: "AND"}, nil
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.SetArchiveGracePeriod(24 * time.Hour)
+
+	resp, err := svc.EvaluateSingle(sdkContext(), "flag-1", "tenant-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.True(t, resp.Enabled)
+	assert.Equal(t, ReasonArchived, resp.Reason)
+
+	svc.SetArchiveGracePeriod(time.Minute)
+	_, err = svc.EvaluateSingle(sdkContext(), "flag-1", "tenant-1", EvaluationContext{UserID: "user-1"})
+	assert.ErrorIs(t, err, ErrFlagNotActive)
+}
+
+func TestService_Snapshot_MarksArchivedFlagsWithGraceDeadline(t *testing.T) {
+	archivedAt := time.Now().Add(-time.Hour)
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "flag-1", Name: "checkout", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-2", Name: "legacy", Enabled: true, Lifecycle: flag.LifecycleArchived, ArchivedAt: &archivedAt, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{generations: []int64{1, 1}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.SetArchiveGracePeriod(24 * time.Hour)
+
+	snapshot, err := svc.Snapshot(sdkContext(), "project-1")
+
+	require.NoError(t, err)
+	require.Len(t, snapshot.Flags, 2)
+	assert.Nil(t, snapshot.Flags[0].ArchivedUntil)
+	require.NotNil(t, snapshot.Flags[1].ArchivedUntil)
+	assert.True(t, snapshot.Flags[1].ArchivedUntil.Equal(archivedAt.Add(24*time.Hour)))
+
+	// Once the deadline passes, the cached snapshot no longer serves the flag
+	served := flagsFromSnapshot(snapshot, archivedAt.Add(25*time.Hour))
+	require.Len(t, served, 1)
+	assert.Equal(t, "flag-1", served[0].ID)
+}
+
+func TestService_EvaluateAll_UsesEnvironmentConfig(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-2", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-3", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+		environments: map[string][]flag.EnvironmentConfig{
+			"flag-1": {
+				{FlagID: "flag-1", EnvironmentID: "staging", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{FlagID: "flag-1", EnvironmentID: "production", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			},
+			"flag-3": {
+				{FlagID: "flag-3", EnvironmentID: "staging", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			},
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resp, err := svc.EvaluateAll(appContext.WithEnvironment(sdkContext(), "staging"), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.True(t, resp.Flags["flag-1"], "staging enables the flag")
+	assert.False(t, resp.Flags["flag-2"], "flags never configured in an environment are disabled there")
+	assert.False(t, resp.Flags["flag-3"], "a flag toggled off or expired is disabled in every environment")
+
+	resp, err = svc.EvaluateAll(appContext.WithEnvironment(sdkContext(), "production"), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.False(t, resp.Flags["flag-1"])
+
+	resp, err = svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+	assert.True(t, resp.Flags["flag-1"], "the project key keeps the flag's own config")
+	assert.True(t, resp.Flags["flag-2"])
+	assert.False(t, resp.Flags["flag-3"])
+}
+
+func TestService_EvaluateSingle_UsesEnvironmentConfig(t *testing.T) {
+	flags := &mockFlagRepository{
+		getByIDFn: func(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+			return &flag.Flag{ID: id, Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"}, nil
+		},
+		environments: map[string][]flag.EnvironmentConfig{
+			"flag-1": {{FlagID: "flag-1", EnvironmentID: "production", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"}},
+		},
+	}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resp, err := svc.EvaluateSingle(appContext.WithEnvironment(sdkContext(), "production"), "flag-1", "tenant-1", EvaluationContext{UserID: "user-1"})
+
+	require.NoError(t, err)
+	assert.False(t, resp.Enabled)
+}
+
+func TestSnapshot_ForEnvironment(t *testing.T) {
+	rules := []flag.Rule{{ID: "r1", Attribute: "country", Operator: "equals", Value: "AU", Rollout: 100}}
+	snapshot := &Snapshot{
+		ProjectID:  "project-1",
+		Generation: 4,
+		Flags: []SnapshotFlag{{
+			ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND",
+			Environments: map[string]SnapshotEnvironment{
+				"staging": {Enabled: true, Rules: rules, RuleLogic: "OR"},
+			},
+		}, {
+			ID: "flag-2", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND",
+			Environments: map[string]SnapshotEnvironment{
+				"staging": {Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			},
+		}},
+	}
+
+	staging := snapshot.ForEnvironment("staging")
+	require.Len(t, staging.Flags, 2)
+	assert.False(t, staging.Flags[1].Enabled, "a disabled flag stays disabled in every environment")
+	assert.True(t, staging.Flags[0].Enabled)
+	assert.Equal(t, rules, staging.Flags[0].Rules)
+	assert.Equal(t, "OR", staging.Flags[0].RuleLogic)
+	assert.Nil(t, staging.Flags[0].Environments, "other environments' configs are not served")
+
+	production := snapshot.ForEnvironment("production")
+	assert.False(t, production.Flags[0].Enabled)
+	assert.Empty(t, production.Flags[0].Rules)
+
+	assert.Same(t, snapshot, snapshot.ForEnvironment(""))
+	assert.NotNil(t, snapshot.Flags[0].Environments, "resolving doesn't modify the cached snapshot")
+}
+
+// recordingEvents collects recorded evaluation events
+type recordingEvents struct {
+	events []events.Event
+}
+
+func (r *recordingEvents) Record(evs ...events.Event) {
+	r.events = append(r.events, evs...)
+}
+
+func TestService_EvaluateAll_RecordsEvents(t *testing.T) {
+	flags := &mockFlagRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]flag.Flag, error) {
+			return []flag.Flag{
+				{ID: "flag-1", Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND"},
+				{ID: "flag-2", Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND"},
+			}, nil
+		},
+	}
+	recorder := &recordingEvents{}
+	svc := NewService(flags, &mockProjectReader{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	svc.SetEventRecorder(recorder)
+
+	_, err := svc.EvaluateAll(sdkContext(), "project-1", EvaluationContext{UserID: "user-1"})
+	require.NoError(t, err)
+
+	require.Len(t, recorder.events, 2)
+	byFlag := map[string]events.Event{}
+	for _, e := range recorder.events {
+		byFlag[e.FlagID] = e
+	}
+	assert.True(t, byFlag["flag-1"].Enabled)
+	assert.Equal(t, ReasonNoRules, byFlag["flag-1"].Reason)
+	assert.False(t, byFlag["flag-2"].Enabled)
+	assert.Equal(t, ReasonDisabled, byFlag["flag-2"].Reason)
+	for _, e := range recorder.events {
+		assert.Equal(t, "tenant-1", e.TenantID)
+		assert.Equal(t, "project-1", *e.ProjectID)
+		assert.Equal(t, "user-1", e.UserID)
+		assert.False(t, e.EvaluatedAt.IsZero())
+	}
+}
@@ -1,19 +1,287 @@
 package evaluation
 
-// EvaluationContext contains user attributes and context for evaluation
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// ErrInvalidContext indicates an evaluation context with nothing to bucket on
+var ErrInvalidContext = errors.New("invalid evaluation context")
+
+// EvaluationContext contains user attributes and context for evaluation.
+//
+// Rollouts bucket on the context's bucketing key: UserID for known users, or Key for
+// anonymous ones. Key should be a stable device or session ID; bucketing is a hash of it,
+// so the same key always lands in the same bucket, and a visitor who signs in moves to the
+// bucket of their user ID.
+//
+// UserID, Key and Attributes describe the user. Kinds optionally describes other entities
+// the user is acting through, keyed by context kind (organization or device); rules with a
+// context_kind match against that entity's attributes instead of the user's.
 type EvaluationContext struct {
-	UserID     string                 `json:"user_id" binding:"required"`
+	UserID string `json:"user_id"`
+	// Anonymous marks a visitor without a user ID, bucketed on Key
+	Anonymous  bool                   `json:"anonymous,omitempty"`
+	Key        string                 `json:"key,omitempty"`
 	Attributes map[string]interface{} `json:"attributes"`
+	Kinds      map[string]KindContext `json:"kinds,omitempty"`
+
+	// excludedFlags, overrides, bucketing, at and clientIP are set server-side and never bound from requests
+	excludedFlags map[string]struct{}
+	overrides     map[string]bool
+	bucketing     string
+	at            time.Time
+	clientIP      string
+}
+
+// KindContext is one non-user entity of an evaluation context
+type KindContext struct {
+	Key        string                 `json:"key"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// BucketingKey returns the value rollouts bucket the context on
+func (c EvaluationContext) BucketingKey() string {
+	if c.Anonymous {
+		return c.Key
+	}
+	return c.UserID
+}
+
+// Validate checks that the context has a bucketing key, so it is never silently bucketed
+// as an empty string
+func (c EvaluationContext) Validate() error {
+	switch {
+	case c.Anonymous && c.Key == "":
+		return fmt.Errorf("%w: anonymous contexts need a key", ErrInvalidContext)
+	case c.Anonymous && c.UserID != "":
+		return fmt.Errorf("%w: anonymous contexts can't have a user_id", ErrInvalidContext)
+	case !c.Anonymous && c.UserID == "":
+		return fmt.Errorf("%w: user_id is required, or anonymous with a key", ErrInvalidContext)
+	}
+	for kind, kc := range c.Kinds {
+		switch {
+		case kind == flag.ContextKindUser:
+			return fmt.Errorf("%w: the user is described by user_id and attributes, not kinds", ErrInvalidContext)
+		case !slices.Contains(flag.ContextKinds, kind):
+			return fmt.Errorf("%w: unknown context kind %q", ErrInvalidContext, kind)
+		case kc.Key == "":
+			return fmt.Errorf("%w: %s context needs a key", ErrInvalidContext, kind)
+		}
+	}
+	return nil
+}
+
+// attribute returns the value of an attribute of one of the context's kinds. A kind's
+// "key" attribute is its key unless the kind's attributes say otherwise.
+func (c EvaluationContext) attribute(kind, name string) (interface{}, bool) {
+	if kind == "" || kind == flag.ContextKindUser {
+		value, ok := c.Attributes[name]
+		return value, ok
+	}
+	kc, ok := c.Kinds[kind]
+	if !ok {
+		return nil, false
+	}
+	if value, ok := kc.Attributes[name]; ok {
+		return value, true
+	}
+	if name == "key" {
+		return kc.Key, true
+	}
+	return nil, false
+}
+
+// qualifiedAttributes returns the names of every attribute the context sends, as rules'
+// QualifiedAttribute names them
+func (c EvaluationContext) qualifiedAttributes() map[string]interface{} {
+	if len(c.Kinds) == 0 {
+		return c.Attributes
+	}
+	names := make(map[string]interface{}, len(c.Attributes))
+	for name, value := range c.Attributes {
+		names[name] = value
+	}
+	for kind, kc := range c.Kinds {
+		for name, value := range kc.Attributes {
+			names[flag.QualifiedAttribute(kind, name)] = value
+		}
+	}
+	return names
+}
+
+// WithBucketing returns a copy of the context that buckets users with the project's algorithm
+func (c EvaluationContext) WithBucketing(algorithm string) EvaluationContext {
+	c.bucketing = algorithm
+	return c
+}
+
+// WithTime returns a copy of the context that evaluates time window rules as of at rather than now
+func (c EvaluationContext) WithTime(at time.Time) EvaluationContext {
+	c.at = at
+	return c
 }
 
+// WithClientIP returns a copy of the context that knows the IP address the SDK request came
+// from, for projects with geo targeting
+func (c EvaluationContext) WithClientIP(ip string) EvaluationContext {
+	c.clientIP = ip
+	return c
+}
+
+// withDefaults returns a copy of the context with defaults added to the attributes it doesn't have
+func (c EvaluationContext) withDefaults(defaults map[string]interface{}) EvaluationContext {
+	if len(defaults) == 0 {
+		return c
+	}
+	// Copy the attributes rather than write to the caller's map
+	attributes := make(map[string]interface{}, len(c.Attributes)+len(defaults))
+	for name, value := range defaults {
+		attributes[name] = value
+	}
+	for name, value := range c.Attributes {
+		attributes[name] = value
+	}
+	c.Attributes = attributes
+	return c
+}
+
+// now is the time time window rules are evaluated at
+func (c EvaluationContext) now() time.Time {
+	if c.at.IsZero() {
+		return time.Now()
+	}
+	return c.at
+}
+
+// WithExclusions returns a copy of the context that excludes the user from the given flags
+func (c EvaluationContext) WithExclusions(flagIDs []string) EvaluationContext {
+	c.excludedFlags = make(map[string]struct{}, len(flagIDs))
+	for _, id := range flagIDs {
+		c.excludedFlags[id] = struct{}{}
+	}
+	return c
+}
+
+// WithOverrides returns a copy of the context with the user's override values, keyed by flag ID
+func (c EvaluationContext) WithOverrides(overrides map[string]bool) EvaluationContext {
+	c.overrides = overrides
+	return c
+}
+
+// override returns the user's forced value for a flag, if any
+func (c EvaluationContext) override(flagID string) (bool, bool) {
+	enabled, ok := c.overrides[flagID]
+	return enabled, ok
+}
+
+// isExcluded reports whether the user is excluded from a flag
+func (c EvaluationContext) isExcluded(flagID string) bool {
+	_, ok := c.excludedFlags[flagID]
+	return ok
+}
+
+// Ways a bulk evaluation response can key its flags
+const (
+	KeyByID  = "id"  // flag IDs; the default
+	KeyByKey = "key" // flag keys, so application code needn't hardcode UUIDs
+)
+
 // EvaluationRequest is the bulk evaluation request from SDK
+// BootstrapRequest is the body of POST /sdk/bootstrap
+type BootstrapRequest struct {
+	Context EvaluationContext `json:"context" binding:"required"`
+}
+
 type EvaluationRequest struct {
 	Context EvaluationContext `json:"context" binding:"required"`
+	// KeyBy is KeyByID (the default) or KeyByKey
+	KeyBy string `json:"key_by"`
 }
 
 // EvaluationResponse returns all flag states for the user
 type EvaluationResponse struct {
 	Flags map[string]bool `json:"flags"` // map[flag_id]enabled
+	// Archived lists flags that are archived and only served until their grace period ends
+	Archived []string `json:"archived,omitempty"`
+	// Partial is set when the evaluation budget ran out; flags missing from Flags weren't
+	// evaluated and should keep their previous or default values
+	Partial bool `json:"partial,omitempty"`
+	// Staleness is how long ago the evaluated flags were confirmed current; sent as a header
+	Staleness time.Duration `json:"-"`
+
+	// keys maps the evaluated flags' IDs to their keys, for KeyedByFlagKey
+	keys map[string]string
+	// reasons maps the evaluated flags' IDs to the reasons for their results, for OFREP
+	reasons map[string]string
+}
+
+// KeyedByFlagKey returns the response with flags keyed by flag key instead of ID.
+// Flags without a key keep their ID.
+func (r *EvaluationResponse) KeyedByFlagKey() *EvaluationResponse {
+	keyOf := func(id string) string {
+		if key := r.keys[id]; key != "" {
+			return key
+		}
+		return id
+	}
+
+	keyed := *r
+	keyed.Flags = make(map[string]bool, len(r.Flags))
+	for id, enabled := range r.Flags {
+		keyed.Flags[keyOf(id)] = enabled
+	}
+	keyed.Archived = nil
+	for _, id := range r.Archived {
+		keyed.Archived = append(keyed.Archived, keyOf(id))
+	}
+	return &keyed
+}
+
+// BootstrapResponse is what a browser SDK needs to start: the value of each flag, by flag
+// key, for one context. It carries no rules, names or descriptions.
+type BootstrapResponse struct {
+	Flags map[string]bool `json:"flags"`
+}
+
+// Bootstrap returns the response reduced to a BootstrapResponse
+func (r *EvaluationResponse) Bootstrap() *BootstrapResponse {
+	return &BootstrapResponse{Flags: r.KeyedByFlagKey().Flags}
+}
+
+// DecodeContextParam decodes an evaluation context sent in a URL as base64url-encoded JSON,
+// padded or not
+func DecodeContextParam(raw string) (EvaluationContext, error) {
+	var evalCtx EvaluationContext
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(raw, "="))
+	if err != nil {
+		return evalCtx, fmt.Errorf("%w: context must be base64url-encoded JSON", ErrInvalidContext)
+	}
+	if err := json.Unmarshal(data, &evalCtx); err != nil {
+		return evalCtx, fmt.Errorf("%w: context must be base64url-encoded JSON", ErrInvalidContext)
+	}
+	return evalCtx, nil
+}
+
+// PayloadVersion is the version of the SDK API's response formats. It changes only when a
+// response changes incompatibly, so SDKs can tell a server they don't understand.
+const PayloadVersion = 1
+
+// SDKConfig is what SDKs read from GET /sdk/config to tune themselves. The polling interval
+// and streaming are set per tenant.
+type SDKConfig struct {
+	// PollingIntervalSeconds is how often SDKs that aren't streaming should re-evaluate
+	PollingIntervalSeconds int `json:"polling_interval_seconds"`
+	// StreamingAvailable tells SDKs to follow flag changes over /sdk/ws rather than poll
+	StreamingAvailable bool `json:"streaming_available"`
+	PayloadVersion     int  `json:"payload_version"`
 }
 
 // SingleEvaluationRequest is for evaluating a single flag
@@ -25,4 +293,157 @@ type SingleEvaluationRequest struct {
 type SingleEvaluationResponse struct {
 	Enabled bool   `json:"enabled"`
 	FlagID  string `json:"flag_id"`
+	FlagKey string `json:"flag_key,omitempty"`
+	// Reason is ReasonArchived for an archived flag served during its grace period
+	Reason string `json:"reason,omitempty"`
+
+	// evaluationReason is the reason for the result, as Explain would give it, for OFREP
+	evaluationReason string
+}
+
+// Reasons an Explanation gives for its result
+const (
+	ReasonOverride        = "override"         // a per-user override decided the result
+	ReasonDisabled        = "disabled"         // the flag is switched off
+	ReasonNoRules         = "no_rules"         // the flag is on and has no rules
+	ReasonRulesPassed     = "rules_passed"     // the rules and their rollouts admitted the user
+	ReasonRulesFailed     = "rules_failed"     // the rules' conditions left the user out
+	ReasonRolloutExcluded = "rollout_excluded" // the conditions matched but the user's bucket is outside the rollout
+	ReasonExcluded        = "excluded"         // the user is excluded from the flag
+	ReasonArchived        = "archived"         // the flag is archived and served only during its grace period
+	ReasonNotServed       = "not_served"       // the flag's lifecycle state keeps it from SDKs, which get a not found
+)
+
+// RuleResult is how one rule fared against an evaluation context
+type RuleResult struct {
+	RuleID      string `json:"rule_id"`
+	Attribute   string `json:"attribute"`
+	ContextKind string `json:"context_kind,omitempty"`
+	Operator    string `json:"operator"`
+	// Value is the value the rule compares against
+	Value interface{} `json:"value"`
+	// ContextValue is the context's value of the rule's attribute (the evaluation time for
+	// time_window rules); omitted when the context doesn't have the attribute
+	ContextValue interface{} `json:"context_value,omitempty"`
+	// Matched reports whether the rule's condition matched the context
+	Matched bool `json:"matched"`
+	// Rollout is the rule's rollout percentage, the highest bucket it admits
+	Rollout int `json:"rollout"`
+	// InRollout reports whether the user's bucket is within the rule's rollout
+	InRollout bool `json:"in_rollout"`
+}
+
+// Explanation is an evaluation result together with the steps that produced it
+type Explanation struct {
+	FlagID  string `json:"flag_id"`
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+	// Targeted reports whether the rules admit the user, ignoring whether the flag is on
+	Targeted bool `json:"targeted"`
+	// Bucket is the user's rollout bucket for the flag, 0-100 (1-100 under murmur3 or strict bucketing)
+	Bucket int `json:"bucket"`
+	// Bucketing is the algorithm Bucket was computed with, when the project's is known
+	Bucketing string       `json:"bucketing,omitempty"`
+	Rules     []RuleResult `json:"rules"`
+}
+
+// ExplainRequest asks how a flag evaluates for a context, as an SDK with a project key or,
+// given EnvironmentID, with that environment's key would see it
+type ExplainRequest struct {
+	Context       EvaluationContext `json:"context" binding:"required"`
+	EnvironmentID string            `json:"environment_id"`
+}
+
+// SnapshotFlag is the evaluable definition of a flag served to relays
+type SnapshotFlag struct {
+	ID        string      `json:"id"`
+	Key       string      `json:"key,omitempty"`
+	Name      string      `json:"name"`
+	Enabled   bool        `json:"enabled"`
+	Rules     []flag.Rule `json:"rules"`
+	RuleLogic string      `json:"rule_logic"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// Salt is mixed into rollout bucketing when set; see Bucketer
+	Salt string `json:"salt,omitempty"`
+	// ExcludedUserKeys must be checked after rollout bucketing
+	ExcludedUserKeys []string `json:"excluded_user_keys,omitempty"`
+	// Overrides must be checked before anything else, ignoring expired entries
+	Overrides []SnapshotOverride `json:"overrides,omitempty"`
+	// ArchivedUntil is set on archived flags; relays must stop serving the flag after it
+	ArchivedUntil *time.Time `json:"archived_until,omitempty"`
+	// Environments holds the flag's config per environment ID; an environment
+	// missing here has never configured the flag, so it is disabled there
+	Environments map[string]SnapshotEnvironment `json:"environments,omitempty"`
+}
+
+// SnapshotEnvironment is a flag's enabled state and rules in one environment
+type SnapshotEnvironment struct {
+	Enabled   bool        `json:"enabled"`
+	Rules     []flag.Rule `json:"rules"`
+	RuleLogic string      `json:"rule_logic"`
+}
+
+// SnapshotOverride is a temporary per-user override served to relays
+type SnapshotOverride struct {
+	UserKey   string    `json:"user_key"`
+	Enabled   bool      `json:"enabled"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Snapshot is every evaluable flag in a project at a single generation
+type Snapshot struct {
+	ProjectID  string `json:"project_id"`
+	Generation int64  `json:"generation"`
+	// Bucketing is the project's bucketing algorithm; relays must bucket users with it
+	Bucketing string `json:"bucketing"`
+	// GeoTargeting is set when the project fills in missing country and region attributes
+	// from the caller's IP; relays without a GeoIP database leave them missing
+	GeoTargeting bool `json:"geo_targeting,omitempty"`
+	// DefaultAttributes must be merged into every context's attributes that don't already have them
+	DefaultAttributes map[string]interface{} `json:"default_attributes,omitempty"`
+	// MaxStalenessSeconds is how long a relay may serve this snapshot before it must revalidate
+	MaxStalenessSeconds int            `json:"max_staleness_seconds"`
+	GeneratedAt         time.Time      `json:"generated_at"`
+	Flags               []SnapshotFlag `json:"flags"`
+}
+
+// ForEnvironment returns a copy of the snapshot as seen through an environment key: each
+// flag carries the environment's config and no other environment's, and stays disabled if
+// the flag itself is (see flag.Flag.InEnvironment). An empty environment ID is the project
+// key, which is served the snapshot as is.
+func (s *Snapshot) ForEnvironment(environmentID string) *Snapshot {
+	if environmentID == "" {
+		return s
+	}
+
+	resolved := *s
+	resolved.Flags = make([]SnapshotFlag, len(s.Flags))
+	for i, sf := range s.Flags {
+		env := sf.Environments[environmentID]
+		sf.Enabled = sf.Enabled && env.Enabled
+		sf.Rules = env.Rules
+		sf.RuleLogic = env.RuleLogic
+		if sf.Rules == nil {
+			sf.Rules = []flag.Rule{}
+		}
+		if sf.RuleLogic == "" {
+			sf.RuleLogic = flag.RuleLogicAnd
+		}
+		sf.Environments = nil
+		resolved.Flags[i] = sf
+	}
+	return &resolved
+}
+
+// Ruleset is a project's flag definitions for server-side SDKs evaluating locally.
+// SDKs must evaluate them exactly like the server: overrides first, then the enabled
+// state, rules and rollout with the project's bucketing algorithm, then exclusions.
+type Ruleset struct {
+	ProjectID string `json:"project_id"`
+	// Version changes whenever a flag definition does; SDKs compare it to decide whether to reload
+	Version   int64          `json:"version"`
+	Bucketing string         `json:"bucketing"`
+	Flags     []SnapshotFlag `json:"flags"`
+	// DefaultAttributes must be merged into every context's attributes that don't already have them
+	DefaultAttributes map[string]interface{} `json:"default_attributes,omitempty"`
 }
@@ -0,0 +1,82 @@
+package evaluation
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jalil32/toggle/internal/pkg/batch"
+)
+
+// UsageRecorder records that flags were evaluated
+type UsageRecorder interface {
+	Record(flagIDs ...string)
+}
+
+// UsageStore persists when flags were last evaluated
+type UsageStore interface {
+	RecordEvaluations(ctx context.Context, flagIDs []string, at time.Time) error
+}
+
+// UsageTracker collects evaluated flag IDs in memory and writes them to the store
+// periodically, keeping database writes off the evaluation path
+type UsageTracker struct {
+	store    UsageStore
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+func NewUsageTracker(store UsageStore, interval time.Duration, logger *slog.Logger) *UsageTracker {
+	return &UsageTracker{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		pending:  make(map[string]struct{}),
+	}
+}
+
+// Record marks flags as evaluated; it never blocks on the database
+func (t *UsageTracker) Record(flagIDs ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, id := range flagIDs {
+		t.pending[id] = struct{}{}
+	}
+}
+
+// Flush writes all pending evaluations to the store
+func (t *UsageTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	flagIDs := make([]string, 0, len(t.pending))
+	for id := range t.pending {
+		flagIDs = append(flagIDs, id)
+	}
+	t.pending = make(map[string]struct{})
+	t.mu.Unlock()
+
+	if err := t.store.RecordEvaluations(ctx, flagIDs, time.Now()); err != nil {
+		// Usage is best-effort: the next evaluation will record the flags again
+		t.logger.Warn("failed to record flag usage",
+			slog.Int("flags", len(flagIDs)),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	t.logger.Debug("flag usage recorded", slog.Int("flags", len(flagIDs)))
+	return nil
+}
+
+// Run flushes pending evaluations every interval until ctx is cancelled, then flushes once more
+func (t *UsageTracker) Run(ctx context.Context) {
+	batch.Run(ctx, t.interval, t.Flush)
+}
@@ -0,0 +1,74 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockUsageStore struct {
+	mu    sync.Mutex
+	calls [][]string
+	err   error
+}
+
+func (m *mockUsageStore) RecordEvaluations(ctx context.Context, flagIDs []string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sorted := append([]string(nil), flagIDs...)
+	sort.Strings(sorted)
+	m.calls = append(m.calls, sorted)
+	return m.err
+}
+
+func TestUsageTracker_FlushDeduplicates(t *testing.T) {
+	store := &mockUsageStore{}
+	tracker := NewUsageTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tracker.Record("flag-1", "flag-2")
+	tracker.Record("flag-1")
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	require.Len(t, store.calls, 1)
+	assert.Equal(t, []string{"flag-1", "flag-2"}, store.calls[0])
+
+	// Nothing pending means no write
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Len(t, store.calls, 1)
+}
+
+func TestUsageTracker_RunFlushesOnShutdown(t *testing.T) {
+	store := &mockUsageStore{}
+	tracker := NewUsageTracker(store, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tracker.Record("flag-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tracker.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	require.Len(t, store.calls, 1)
+	assert.Equal(t, []string{"flag-1"}, store.calls[0])
+}
+
+func TestUsageTracker_StoreErrorIsReturned(t *testing.T) {
+	store := &mockUsageStore{err: errors.New("database down")}
+	tracker := NewUsageTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tracker.Record("flag-1")
+
+	assert.Error(t, tracker.Flush(context.Background()))
+}
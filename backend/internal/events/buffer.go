@@ -0,0 +1,106 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jalil32/toggle/internal/pkg/batch"
+)
+
+// Recorder records evaluation events
+type Recorder interface {
+	Record(events ...Event)
+}
+
+// Store persists batches of evaluation events
+type Store interface {
+	InsertBatch(ctx context.Context, events []Event) error
+}
+
+// Buffer holds evaluation events in memory and writes them to the store in batches
+// periodically, keeping database writes off the evaluation path
+type Buffer struct {
+	store    Store
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending []Event
+	dropped int64
+}
+
+func NewBuffer(store Store, interval time.Duration, logger *slog.Logger) *Buffer {
+	return &Buffer{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Record buffers events; it never blocks on the database. Events past MaxBufferedEvents
+// are dropped and counted.
+func (b *Buffer) Record(events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range events {
+		if len(b.pending) >= MaxBufferedEvents {
+			b.dropped++
+			continue
+		}
+		if len(e.UserID) > MaxUserIDLength {
+			e.UserID = e.UserID[:MaxUserIDLength]
+		}
+		b.pending = append(b.pending, e)
+	}
+}
+
+// Len returns the number of buffered events
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// Flush writes all buffered events to the store in batches of BatchSize
+func (b *Buffer) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	pending, dropped := b.pending, b.dropped
+	b.pending, b.dropped = nil, 0
+	b.mu.Unlock()
+
+	if dropped > 0 {
+		b.logger.Warn("evaluation event buffer full, events dropped", slog.Int64("dropped", dropped))
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var firstErr error
+	written := 0
+	for start := 0; start < len(pending); start += BatchSize {
+		batch := pending[start:min(start+BatchSize, len(pending))]
+		if err := b.store.InsertBatch(ctx, batch); err != nil {
+			// Events are best-effort: a failed batch is dropped
+			b.logger.Warn("failed to write evaluation events",
+				slog.Int("events", len(batch)),
+				slog.String("error", err.Error()),
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		written += len(batch)
+	}
+
+	b.logger.Debug("evaluation events written", slog.Int("events", written))
+	return firstErr
+}
+
+// Run flushes buffered events every interval until ctx is cancelled, then flushes once more
+func (b *Buffer) Run(ctx context.Context) {
+	batch.Run(ctx, b.interval, b.Flush)
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStore struct {
+	batches [][]Event
+	err     error
+}
+
+func (m *mockStore) InsertBatch(ctx context.Context, events []Event) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.batches = append(m.batches, events)
+	return nil
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestBuffer_FlushWritesInBatches(t *testing.T) {
+	store := &mockStore{}
+	buffer := NewBuffer(store, time.Minute, discardLogger())
+
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < BatchSize+1; i++ {
+		buffer.Record(Event{FlagID: "flag-1", TenantID: "tenant-1", UserID: "user-1", Enabled: true, Reason: "no_rules", EvaluatedAt: at})
+	}
+	require.Equal(t, BatchSize+1, buffer.Len())
+
+	require.NoError(t, buffer.Flush(context.Background()))
+	require.Len(t, store.batches, 2)
+	assert.Len(t, store.batches[0], BatchSize)
+	assert.Len(t, store.batches[1], 1)
+	assert.Zero(t, buffer.Len())
+
+	// Nothing buffered: no write
+	require.NoError(t, buffer.Flush(context.Background()))
+	assert.Len(t, store.batches, 2)
+}
+
+func TestBuffer_DropsEventsWhenFull(t *testing.T) {
+	buffer := NewBuffer(&mockStore{}, time.Minute, discardLogger())
+
+	events := make([]Event, MaxBufferedEvents+10)
+	buffer.Record(events...)
+
+	assert.Equal(t, MaxBufferedEvents, buffer.Len())
+}
+
+func TestBuffer_TruncatesLongUserIDs(t *testing.T) {
+	store := &mockStore{}
+	buffer := NewBuffer(store, time.Minute, discardLogger())
+
+	buffer.Record(Event{FlagID: "flag-1", UserID: strings.Repeat("u", MaxUserIDLength+1)})
+	require.NoError(t, buffer.Flush(context.Background()))
+
+	assert.Len(t, store.batches[0][0].UserID, MaxUserIDLength)
+}
+
+func TestBuffer_FlushReportsStoreErrors(t *testing.T) {
+	buffer := NewBuffer(&mockStore{err: errors.New("database error")}, time.Minute, discardLogger())
+
+	buffer.Record(Event{FlagID: "flag-1"})
+
+	assert.Error(t, buffer.Flush(context.Background()))
+	assert.Zero(t, buffer.Len(), "a failed batch is dropped rather than retried")
+}
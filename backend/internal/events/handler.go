@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+// tailKeepAliveInterval is how often an idle live tail receives an SSE comment, keeping
+// it open through proxies
+const tailKeepAliveInterval = 15 * time.Second
+
+// FlagReader finds a tenant's flag (implemented by flags.Service)
+type FlagReader interface {
+	GetByID(ctx context.Context, id string, tenantID string) (*flag.Flag, error)
+}
+
+type Handler struct {
+	tail   *Tail
+	flags  FlagReader
+	logger *slog.Logger
+	// maxDuration is how long a tail streams; MaxTailDuration outside tests
+	maxDuration time.Duration
+}
+
+func NewHandler(tail *Tail, flags FlagReader, logger *slog.Logger) *Handler {
+	return &Handler{tail: tail, flags: flags, logger: logger, maxDuration: MaxTailDuration}
+}
+
+// RegisterRoutes registers the live tail. Its group must skip the request timeout, since a
+// tail streams for up to MaxTailDuration; limiter should allow only a few tails a minute.
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup, limiter gin.HandlerFunc) {
+	r.GET("/flags/:id/tail", limiter, h.Tail)
+}
+
+// Tail streams a sampled, redacted feed of a flag's live evaluations as server-sent
+// events for up to MaxTailDuration; owners and admins only. Events are "evaluation",
+// then "end" once the time is up.
+func (h *Handler) Tail(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+	role := appContext.UserRole(ctx)
+	if role != "owner" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+
+	flagID := c.Param("id")
+	if _, err := h.flags.GetByID(ctx, flagID, tenantID); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	events, stop, err := h.tail.Subscribe(flagID, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrTooManyTails) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	defer stop()
+
+	userID, _ := appContext.UserID(ctx)
+	h.logger.Info("live tail started",
+		slog.String("flag_id", flagID),
+		slog.String("user_id", userID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	deadline := time.NewTimer(h.maxDuration)
+	defer deadline.Stop()
+	keepAlive := time.NewTicker(tailKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			c.SSEvent("end", gin.H{"reason": "max_duration"})
+			c.Writer.Flush()
+			return
+		case event := <-events:
+			c.SSEvent("evaluation", event)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package events
+
+import "time"
+
+// Buffer limits
+const (
+	// MaxBufferedEvents bounds events held in memory between flushes; past it new events
+	// are dropped, so a slow database costs analytics rather than memory
+	MaxBufferedEvents = 50000
+	// BatchSize is the most events written in one insert
+	BatchSize = 1000
+	// FlushInterval is how often buffered events are written
+	FlushInterval = 10 * time.Second
+)
+
+// Retention is how long evaluation events are kept
+const Retention = 7 * 24 * time.Hour
+
+// MaxUserIDLength bounds user IDs taken from evaluation contexts
+const MaxUserIDLength = 255
+
+// Event is one evaluation of a flag for a user
+type Event struct {
+	FlagID    string  `json:"flag_id" db:"flag_id"`
+	TenantID  string  `json:"-" db:"tenant_id"`
+	ProjectID *string `json:"project_id" db:"project_id"`
+	UserID    string  `json:"user_id" db:"user_id"`
+	Enabled   bool    `json:"enabled" db:"enabled"`
+	// Reason is the evaluation reason, such as rules_passed or disabled
+	Reason      string    `json:"reason" db:"reason"`
+	EvaluatedAt time.Time `json:"evaluated_at" db:"evaluated_at"`
+}
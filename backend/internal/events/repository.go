@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository interface {
+	Store
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// InsertBatch writes events in one statement. Events for flags that no longer exist, or
+// that don't belong to the event's tenant, are ignored; the project is taken from the flag.
+func (r *postgresRepository) InsertBatch(ctx context.Context, events []Event) error {
+	flagIDs := make([]string, len(events))
+	tenantIDs := make([]string, len(events))
+	userIDs := make([]string, len(events))
+	enabled := make([]bool, len(events))
+	reasons := make([]string, len(events))
+	evaluatedAt := make([]string, len(events)) // pq.Array can't encode times
+	for i, e := range events {
+		flagIDs[i] = e.FlagID
+		tenantIDs[i] = e.TenantID
+		userIDs[i] = e.UserID
+		enabled[i] = e.Enabled
+		reasons[i] = e.Reason
+		evaluatedAt[i] = e.EvaluatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO evaluation_events (tenant_id, project_id, flag_id, user_id, enabled, reason, evaluated_at)
+		SELECT f.tenant_id, f.project_id, f.id, e.user_id, e.enabled, e.reason, e.evaluated_at
+		FROM unnest($1::uuid[], $2::uuid[], $3::text[], $4::boolean[], $5::text[], $6::timestamptz[])
+		     AS e(flag_id, tenant_id, user_id, enabled, reason, evaluated_at)
+		INNER JOIN flags f ON f.id = e.flag_id AND f.tenant_id = e.tenant_id
+	`, pq.Array(flagIDs), pq.Array(tenantIDs), pq.Array(userIDs), pq.Array(enabled), pq.Array(reasons), pq.Array(evaluatedAt))
+	return err
+}
+
+// DeleteBefore removes events evaluated before before, across all tenants
+func (r *postgresRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM evaluation_events WHERE evaluated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,38 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type Service interface {
+	// Prune deletes events older than Retention (run by the jobs scheduler)
+	Prune(ctx context.Context) error
+}
+
+type service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *service) Prune(ctx context.Context) error {
+	deleted, err := s.repo.DeleteBefore(ctx, time.Now().Add(-Retention))
+	if err != nil {
+		return fmt.Errorf("failed to prune evaluation events: %w", err)
+	}
+
+	if deleted > 0 {
+		s.logger.Debug("evaluation events pruned", slog.Int64("count", deleted))
+	}
+
+	return nil
+}
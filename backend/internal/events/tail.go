@@ -0,0 +1,152 @@
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Live tail limits
+const (
+	// MaxTailDuration is how long a live tail streams before it is closed
+	MaxTailDuration = 5 * time.Minute
+	// MaxTailEventsPerSecond samples busy flags: evaluations past it in a second aren't shown
+	MaxTailEventsPerSecond = 10
+	// MaxTailsPerTenant bounds concurrent live tails per tenant
+	MaxTailsPerTenant = 2
+	// tailBufferSize is how many events a slow tail may fall behind before events are dropped
+	tailBufferSize = 64
+)
+
+var ErrTooManyTails = errors.New("too many live tails for this tenant")
+
+// TailEvent is an evaluation as shown in a live tail. The user is redacted to a hash that
+// is stable within one tail, so repeat evaluations for a user can be followed, but can't
+// be reversed or matched across tails.
+type TailEvent struct {
+	User        string    `json:"user"`
+	Enabled     bool      `json:"enabled"`
+	Reason      string    `json:"reason"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+// Tail fans evaluation events out to live tails of their flag. Only evaluations served by
+// this instance are seen. With no tails open, recording costs one atomic load.
+type Tail struct {
+	open atomic.Int64
+
+	mu        sync.Mutex
+	byFlag    map[string]map[*tailSubscriber]struct{}
+	perTenant map[string]int
+}
+
+type tailSubscriber struct {
+	tenantID string
+	salt     []byte
+	events   chan TailEvent
+
+	// Sampling window: events sent in the second starting at windowStart
+	windowStart time.Time
+	sent        int
+}
+
+func NewTail() *Tail {
+	return &Tail{
+		byFlag:    make(map[string]map[*tailSubscriber]struct{}),
+		perTenant: make(map[string]int),
+	}
+}
+
+// Subscribe opens a live tail of a flag's evaluations. Call stop when done.
+func (t *Tail) Subscribe(flagID string, tenantID string) (events <-chan TailEvent, stop func(), err error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+	sub := &tailSubscriber{tenantID: tenantID, salt: salt, events: make(chan TailEvent, tailBufferSize)}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.perTenant[tenantID] >= MaxTailsPerTenant {
+		return nil, nil, ErrTooManyTails
+	}
+	t.perTenant[tenantID]++
+	subs, ok := t.byFlag[flagID]
+	if !ok {
+		subs = make(map[*tailSubscriber]struct{})
+		t.byFlag[flagID] = subs
+	}
+	subs[sub] = struct{}{}
+	t.open.Add(1)
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			delete(subs, sub)
+			if len(subs) == 0 {
+				delete(t.byFlag, flagID)
+			}
+			if t.perTenant[tenantID]--; t.perTenant[tenantID] <= 0 {
+				delete(t.perTenant, tenantID)
+			}
+			t.open.Add(-1)
+		})
+	}
+	return sub.events, stop, nil
+}
+
+// Record passes events to the tails of their flags; it never blocks
+func (t *Tail) Record(events ...Event) {
+	if t.open.Load() == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, e := range events {
+		for sub := range t.byFlag[e.FlagID] {
+			if sub.tenantID != e.TenantID {
+				continue
+			}
+			if e.EvaluatedAt.Sub(sub.windowStart) >= time.Second {
+				sub.windowStart, sub.sent = e.EvaluatedAt, 0
+			}
+			if sub.sent >= MaxTailEventsPerSecond {
+				continue
+			}
+			select {
+			case sub.events <- TailEvent{User: redactUser(sub.salt, e.UserID), Enabled: e.Enabled, Reason: e.Reason, EvaluatedAt: e.EvaluatedAt}:
+				sub.sent++
+			default:
+			}
+		}
+	}
+}
+
+// redactUser replaces a user ID with a short keyed hash
+func redactUser(salt []byte, userID string) string {
+	if userID == "" {
+		return "anonymous"
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(userID))
+	return "user_" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// Tee records events with every recorder in turn
+type Tee []Recorder
+
+func (t Tee) Record(events ...Event) {
+	for _, r := range t {
+		r.Record(events...)
+	}
+}
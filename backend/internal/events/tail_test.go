@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+func drain(events <-chan TailEvent) []TailEvent {
+	var out []TailEvent
+	for {
+		select {
+		case e := <-events:
+			out = append(out, e)
+		default:
+			return out
+		}
+	}
+}
+
+func TestTail_RedactsUsersAndSamples(t *testing.T) {
+	tail := NewTail()
+	events, stop, err := tail.Subscribe("flag-1", "tenant-1")
+	require.NoError(t, err)
+	defer stop()
+
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < MaxTailEventsPerSecond+5; i++ {
+		tail.Record(Event{FlagID: "flag-1", TenantID: "tenant-1", UserID: "alice@example.com", Enabled: true, Reason: "no_rules", EvaluatedAt: at})
+	}
+	tail.Record(
+		Event{FlagID: "flag-2", TenantID: "tenant-1", UserID: "bob", EvaluatedAt: at},
+		Event{FlagID: "flag-1", TenantID: "tenant-2", UserID: "bob", EvaluatedAt: at},
+	)
+
+	got := drain(events)
+	require.Len(t, got, MaxTailEventsPerSecond, "events past the per-second limit, other flags and other tenants aren't shown")
+	assert.NotContains(t, got[0].User, "alice")
+	assert.True(t, strings.HasPrefix(got[0].User, "user_"))
+	assert.Equal(t, got[0].User, got[1].User, "a user's hash is stable within a tail")
+
+	tail.Record(Event{FlagID: "flag-1", TenantID: "tenant-1", UserID: "alice@example.com", EvaluatedAt: at.Add(time.Second)})
+	assert.Len(t, drain(events), 1, "the next second is sampled afresh")
+}
+
+func TestTail_LimitsTailsPerTenant(t *testing.T) {
+	tail := NewTail()
+	var stops []func()
+	for i := 0; i < MaxTailsPerTenant; i++ {
+		_, stop, err := tail.Subscribe("flag-1", "tenant-1")
+		require.NoError(t, err)
+		stops = append(stops, stop)
+	}
+
+	_, _, err := tail.Subscribe("flag-2", "tenant-1")
+	assert.ErrorIs(t, err, ErrTooManyTails)
+	_, stop, err := tail.Subscribe("flag-1", "tenant-2")
+	require.NoError(t, err, "other tenants have their own limit")
+	stop()
+
+	stops[0]()
+	stops[0]()
+	_, _, err = tail.Subscribe("flag-2", "tenant-1")
+	assert.NoError(t, err, "stopping a tail frees its slot once")
+}
+
+type fakeFlags struct{}
+
+func (fakeFlags) GetByID(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+	if id != "flag-1" {
+		return nil, pkgErrors.ErrNotFound
+	}
+	return &flag.Flag{ID: id, TenantID: tenantID}, nil
+}
+
+func tailRouter(h *Handler, role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(appContext.WithTenant(c.Request.Context(), "tenant-1", role))
+		c.Next()
+	})
+	h.RegisterRoutes(router.Group(""), func(c *gin.Context) { c.Next() })
+	return router
+}
+
+func TestHandler_Tail(t *testing.T) {
+	tail := NewTail()
+	h := NewHandler(tail, fakeFlags{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	h.maxDuration = 200 * time.Millisecond
+
+	rec := httptest.NewRecorder()
+	tailRouter(h, "member").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flags/flag-1/tail", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	tailRouter(h, "admin").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flags/flag-2/tail", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	done := make(chan struct{})
+	rec = httptest.NewRecorder()
+	go func() {
+		defer close(done)
+		tailRouter(h, "owner").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/flags/flag-1/tail", nil))
+	}()
+	require.Eventually(t, func() bool { return tail.open.Load() == 1 }, time.Second, 5*time.Millisecond)
+	tail.Record(Event{FlagID: "flag-1", TenantID: "tenant-1", UserID: "alice", Enabled: true, Reason: "rules_passed", EvaluatedAt: time.Now()})
+	<-done
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/event-stream")
+	body := rec.Body.String()
+	assert.Contains(t, body, "event:evaluation")
+	assert.Contains(t, body, `"reason":"rules_passed"`)
+	assert.NotContains(t, body, "alice")
+	assert.Contains(t, body, "event:end")
+	assert.Zero(t, tail.open.Load(), "the tail is closed when the stream ends")
+}
@@ -0,0 +1,205 @@
+package flag
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/grpcjson"
+)
+
+// GRPCServiceName is the flag service's name on the gRPC management API
+const GRPCServiceName = "toggle.management.v1.FlagService"
+
+// ListFlagsRequest lists a project's flags, or the tenant's when ProjectID is empty
+type ListFlagsRequest struct {
+	ProjectID string `json:"project_id"`
+}
+
+type ListFlagsResponse struct {
+	Flags []Flag `json:"flags"`
+}
+
+type GetFlagRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// CreateFlagRequest is the body of POST /flags; TemplateID is its ?template_id=
+type CreateFlagRequest struct {
+	CreateRequest
+	TemplateID string `json:"template_id,omitempty"`
+}
+
+// UpdateFlagRequest sets the fields named in its update_mask, like PATCH /flags/:id
+type UpdateFlagRequest struct {
+	ID string `json:"id" binding:"required"`
+	PatchRequest
+}
+
+type ToggleFlagRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+type DeleteFlagRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+type DeleteFlagResponse struct{}
+
+// GRPCServer serves flag CRUD on the gRPC management API, for automation that prefers
+// typed clients over REST. Calls must have passed management.GRPCAuth.
+type GRPCServer struct {
+	service Service
+}
+
+func NewGRPCServer(service Service) *GRPCServer {
+	return &GRPCServer{service: service}
+}
+
+var flagServiceDesc = grpc.ServiceDesc{
+	ServiceName: GRPCServiceName,
+	HandlerType: (*any)(nil), // grpcjson.Method checks the server's type
+	Methods: []grpc.MethodDesc{
+		grpcjson.Method(GRPCServiceName, "ListFlags", (*GRPCServer).ListFlags),
+		grpcjson.Method(GRPCServiceName, "GetFlag", (*GRPCServer).GetFlag),
+		grpcjson.Method(GRPCServiceName, "CreateFlag", (*GRPCServer).CreateFlag),
+		grpcjson.Method(GRPCServiceName, "UpdateFlag", (*GRPCServer).UpdateFlag),
+		grpcjson.Method(GRPCServiceName, "ToggleFlag", (*GRPCServer).ToggleFlag),
+		grpcjson.Method(GRPCServiceName, "DeleteFlag", (*GRPCServer).DeleteFlag),
+	},
+}
+
+func (s *GRPCServer) Register(r grpc.ServiceRegistrar) {
+	r.RegisterService(&flagServiceDesc, s)
+}
+
+func (s *GRPCServer) ListFlags(ctx context.Context, req *ListFlagsRequest) (*ListFlagsResponse, error) {
+	tenantID := appContext.MustTenantID(ctx)
+
+	var flags []Flag
+	var err error
+	if req.ProjectID != "" {
+		flags, err = s.service.ListByProject(ctx, req.ProjectID, tenantID)
+	} else {
+		flags, err = s.service.List(ctx, tenantID)
+	}
+	if err != nil {
+		return nil, grpcError(err, "failed to list flags")
+	}
+
+	return &ListFlagsResponse{Flags: flags}, nil
+}
+
+func (s *GRPCServer) GetFlag(ctx context.Context, req *GetFlagRequest) (*Flag, error) {
+	flag, err := s.service.GetByID(ctx, req.ID, appContext.MustTenantID(ctx))
+	if err != nil {
+		return nil, grpcError(err, "failed to get flag")
+	}
+	return flag, nil
+}
+
+func (s *GRPCServer) CreateFlag(ctx context.Context, req *CreateFlagRequest) (*Flag, error) {
+	tenantID := appContext.MustTenantID(ctx)
+
+	flag := &Flag{
+		ProjectID:   req.ProjectID,
+		OwnerUserID: req.OwnerUserID,
+		Name:        req.Name,
+		Description: req.Description,
+		Rules:       req.Rules,
+		RuleLogic:   req.RuleLogic,
+		Lifecycle:   req.Lifecycle,
+		ExpiresAt:   req.ExpiresAt,
+	}
+
+	var err error
+	if req.TemplateID != "" {
+		err = s.service.CreateFromTemplate(ctx, flag, req.TemplateID, tenantID)
+	} else {
+		err = s.service.Create(ctx, flag, tenantID)
+	}
+	if err != nil {
+		return nil, grpcError(err, "failed to create flag")
+	}
+
+	return flag, nil
+}
+
+func (s *GRPCServer) UpdateFlag(ctx context.Context, req *UpdateFlagRequest) (*Flag, error) {
+	flag, err := s.service.Patch(ctx, req.ID, req.PatchRequest, appContext.MustTenantID(ctx))
+	if err != nil {
+		return nil, grpcError(err, "failed to update flag")
+	}
+	return flag, nil
+}
+
+func (s *GRPCServer) ToggleFlag(ctx context.Context, req *ToggleFlagRequest) (*Flag, error) {
+	flag, err := s.service.Toggle(ctx, req.ID, appContext.MustUserID(ctx), appContext.MustTenantID(ctx))
+	if err != nil {
+		return nil, grpcError(err, "failed to toggle flag")
+	}
+	return flag, nil
+}
+
+func (s *GRPCServer) DeleteFlag(ctx context.Context, req *DeleteFlagRequest) (*DeleteFlagResponse, error) {
+	if err := s.service.Delete(ctx, req.ID, appContext.MustTenantID(ctx)); err != nil {
+		return nil, grpcError(err, "failed to delete flag")
+	}
+	return &DeleteFlagResponse{}, nil
+}
+
+// grpcError maps service errors to gRPC statuses as the handlers map them to HTTP statuses
+func grpcError(err error, fallback string) error {
+	switch {
+	case errors.Is(err, ErrInvalidFlagData):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrDuplicateName):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrApprovalRequired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, pkgErrors.ErrProjectNotInTenant):
+		return status.Error(codes.NotFound, "project not found")
+	case pkgErrors.IsNotFoundError(err):
+		return status.Error(codes.NotFound, "flag not found")
+	default:
+		return status.Error(codes.Internal, fallback)
+	}
+}
+
+// GRPCClient is a typed client of the flag service
+type GRPCClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGRPCClient(cc grpc.ClientConnInterface) *GRPCClient {
+	return &GRPCClient{cc: cc}
+}
+
+func (c *GRPCClient) ListFlags(ctx context.Context, req *ListFlagsRequest, opts ...grpc.CallOption) (*ListFlagsResponse, error) {
+	return grpcjson.Invoke[ListFlagsRequest, ListFlagsResponse](ctx, c.cc, GRPCServiceName, "ListFlags", req, opts...)
+}
+
+func (c *GRPCClient) GetFlag(ctx context.Context, req *GetFlagRequest, opts ...grpc.CallOption) (*Flag, error) {
+	return grpcjson.Invoke[GetFlagRequest, Flag](ctx, c.cc, GRPCServiceName, "GetFlag", req, opts...)
+}
+
+func (c *GRPCClient) CreateFlag(ctx context.Context, req *CreateFlagRequest, opts ...grpc.CallOption) (*Flag, error) {
+	return grpcjson.Invoke[CreateFlagRequest, Flag](ctx, c.cc, GRPCServiceName, "CreateFlag", req, opts...)
+}
+
+func (c *GRPCClient) UpdateFlag(ctx context.Context, req *UpdateFlagRequest, opts ...grpc.CallOption) (*Flag, error) {
+	return grpcjson.Invoke[UpdateFlagRequest, Flag](ctx, c.cc, GRPCServiceName, "UpdateFlag", req, opts...)
+}
+
+func (c *GRPCClient) ToggleFlag(ctx context.Context, req *ToggleFlagRequest, opts ...grpc.CallOption) (*Flag, error) {
+	return grpcjson.Invoke[ToggleFlagRequest, Flag](ctx, c.cc, GRPCServiceName, "ToggleFlag", req, opts...)
+}
+
+func (c *GRPCClient) DeleteFlag(ctx context.Context, req *DeleteFlagRequest, opts ...grpc.CallOption) (*DeleteFlagResponse, error) {
+	return grpcjson.Invoke[DeleteFlagRequest, DeleteFlagResponse](ctx, c.cc, GRPCServiceName, "DeleteFlag", req, opts...)
+}
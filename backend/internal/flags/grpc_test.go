@@ -0,0 +1,127 @@
+package flag
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+// newGRPCTestClient serves service over an in-memory connection, with every call
+// authenticated as a member of tenant-1 in place of management.GRPCAuth
+func newGRPCTestClient(t *testing.T, service Service) *GRPCClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(appContext.WithAuth(ctx, "user-1", "tenant-1", "member"), req)
+	}))
+	NewGRPCServer(service).Register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return NewGRPCClient(conn)
+}
+
+func TestGRPCCreateFlag(t *testing.T) {
+	var gotTenant string
+	client := newGRPCTestClient(t, &mockService{
+		createFunc: func(ctx context.Context, f *Flag, tenantID string) error {
+			gotTenant = tenantID
+			f.ID = "flag-1"
+			return nil
+		},
+	})
+
+	projectID := "project-1"
+	flag, err := client.CreateFlag(context.Background(), &CreateFlagRequest{CreateRequest: CreateRequest{Name: "dark-mode", ProjectID: &projectID}})
+	if err != nil {
+		t.Fatalf("CreateFlag() error = %v", err)
+	}
+	if flag.ID != "flag-1" || flag.Name != "dark-mode" || flag.ProjectID == nil || *flag.ProjectID != projectID {
+		t.Errorf("CreateFlag() = %+v", flag)
+	}
+	if gotTenant != "tenant-1" {
+		t.Errorf("tenantID = %q, want tenant-1", gotTenant)
+	}
+}
+
+func TestGRPCCreateFlag_MissingName(t *testing.T) {
+	client := newGRPCTestClient(t, &mockService{
+		createFunc: func(ctx context.Context, f *Flag, tenantID string) error {
+			t.Error("Create called with an invalid request")
+			return nil
+		},
+	})
+
+	_, err := client.CreateFlag(context.Background(), &CreateFlagRequest{CreateRequest: CreateRequest{Description: "no name"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateFlag() code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestGRPCUpdateFlag(t *testing.T) {
+	client := newGRPCTestClient(t, &mockService{
+		patchFunc: func(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error) {
+			if id != "flag-1" || len(req.UpdateMask) != 1 || req.UpdateMask[0] != "enabled" {
+				t.Errorf("Patch(%q, %+v)", id, req)
+			}
+			return &Flag{ID: id, Enabled: req.Enabled}, nil
+		},
+	})
+
+	flag, err := client.UpdateFlag(context.Background(), &UpdateFlagRequest{ID: "flag-1", PatchRequest: PatchRequest{UpdateMask: []string{"enabled"}, Enabled: true}})
+	if err != nil {
+		t.Fatalf("UpdateFlag() error = %v", err)
+	}
+	if !flag.Enabled {
+		t.Errorf("UpdateFlag() = %+v, want enabled", flag)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "not found", err: pkgErrors.ErrNotFound, want: codes.NotFound},
+		{name: "project in another tenant", err: pkgErrors.ErrProjectNotInTenant, want: codes.NotFound},
+		{name: "invalid data", err: ErrInvalidFlagData, want: codes.InvalidArgument},
+		{name: "duplicate name", err: ErrDuplicateName, want: codes.AlreadyExists},
+		{name: "approval required", err: ErrApprovalRequired, want: codes.FailedPrecondition},
+		{name: "database error", err: context.DeadlineExceeded, want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newGRPCTestClient(t, &mockService{
+				deleteFunc: func(ctx context.Context, id string, tenantID string) error {
+					return tt.err
+				},
+			})
+
+			_, err := client.DeleteFlag(context.Background(), &DeleteFlagRequest{ID: "flag-1"})
+			if status.Code(err) != tt.want {
+				t.Errorf("DeleteFlag() code = %v, want %v", status.Code(err), tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package flag
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -25,10 +27,66 @@ func NewHandler(service Service) Handler {
 func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/flags", h.Create)
 	r.GET("/flags", h.List)
+	r.GET("/flags/stale", h.ListStale)
+	r.GET("/projects/:id/flags", h.ListByProject)
+	r.GET("/projects/:id/attributes", h.ListAttributes)
+	r.POST("/projects/:id/attributes", h.RegisterAttribute)
+	r.DELETE("/projects/:id/attributes/:attributeID", h.DeleteAttribute)
+	r.GET("/projects/:id/attributes/report", h.AttributeReport)
+	r.POST("/projects/:id/attributes/rename", h.RenameAttribute)
+	r.GET("/projects/:id/flags/export", h.Export)
+	r.POST("/projects/:id/flags/import", h.Import)
+	r.PUT("/projects/:id/flags/key/:key", h.Apply)
+	r.GET("/projects/:id/flags/export/unleash", h.ExportUnleash)
+	r.POST("/projects/:id/flags/import/unleash", h.ImportUnleash)
 	r.GET("/flags/:id", h.Get)
 	r.PUT("/flags/:id", h.Update)
+	r.PATCH("/flags/:id", h.Patch)
 	r.PATCH("/flags/:id/toggle", h.Toggle)
+	r.POST("/flags/:id/reshuffle", h.Reshuffle)
 	r.DELETE("/flags/:id", h.Delete)
+	r.POST("/flags/:id/clone", h.Clone)
+	r.GET("/flags/:id/exclusions", h.ListExclusions)
+	r.POST("/flags/:id/exclusions", h.AddExclusions)
+	r.DELETE("/flags/:id/exclusions/:user_key", h.RemoveExclusion)
+	r.GET("/flags/:id/fields", h.ListCustomFields)
+	r.PUT("/flags/:id/fields/:name", h.SetCustomField)
+	r.DELETE("/flags/:id/fields/:name", h.DeleteCustomField)
+	r.GET("/flags/:id/overrides", h.ListOverrides)
+	r.POST("/flags/:id/overrides", h.CreateOverride)
+	r.DELETE("/flags/:id/overrides/:user_key", h.DeleteOverride)
+	r.GET("/flags/:id/history", h.ListHistory)
+	r.GET("/flags/:id/metrics", h.Metrics)
+	r.GET("/flags/:id/environments", h.ListEnvironmentConfigs)
+	r.PUT("/flags/:id/environments/:envID", h.SetEnvironmentConfig)
+	r.GET("/flags/:id/diff", h.DiffEnvironments)
+	r.POST("/flags/:id/promote", h.Promote)
+}
+
+// InvalidDataResponse builds the 400 body for invalid flag data, listing each invalid rule when known
+func InvalidDataResponse(err error) gin.H {
+	var rulesErr *RuleValidationError
+	if errors.As(err, &rulesErr) {
+		return gin.H{"error": err.Error(), "rule_errors": rulesErr.Errors}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+// ConflictResponse builds the 409 body for a duplicate flag name, naming the conflicting field and value
+func ConflictResponse(err error) gin.H {
+	body := gin.H{"error": err.Error(), "code": "duplicate_name", "field": "name"}
+	var dupErr *DuplicateNameError
+	if errors.As(err, &dupErr) {
+		body["name"] = dupErr.Name
+		body["project_id"] = dupErr.ProjectID
+	}
+	return body
+}
+
+// ApprovalRequiredResponse builds the 409 body for an edit the project's approval policy
+// only allows through a change request
+func ApprovalRequiredResponse(err error) gin.H {
+	return gin.H{"error": err.Error(), "code": "approval_required"}
 }
 
 func (h *handler) Create(c *gin.Context) {
@@ -42,25 +100,35 @@ func (h *handler) Create(c *gin.Context) {
 
 	flag := &Flag{
 		ProjectID:   req.ProjectID,
+		OwnerUserID: req.OwnerUserID,
 		Name:        req.Name,
 		Description: req.Description,
 		Enabled:     false,
 		Rules:       req.Rules,
 		RuleLogic:   req.RuleLogic,
+		Lifecycle:   req.Lifecycle,
+		ExpiresAt:   req.ExpiresAt,
 	}
 
-	if flag.Rules == nil {
-		flag.Rules = []Rule{}
+	// Unset fields are filled from the template, when one is given
+	var err error
+	if templateID := c.Query("template_id"); templateID != "" {
+		err = h.service.CreateFromTemplate(c.Request.Context(), flag, templateID, tenantID)
+	} else {
+		err = h.service.Create(c.Request.Context(), flag, tenantID)
 	}
 
-	// Default to AND if not provided
-	if flag.RuleLogic == "" {
-		flag.RuleLogic = "AND"
-	}
-
-	if err := h.service.Create(c.Request.Context(), flag, tenantID); err != nil {
+	if err != nil {
 		if errors.Is(err, ErrInvalidFlagData) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if errors.Is(err, ErrDuplicateName) {
+			c.JSON(http.StatusConflict, ConflictResponse(err))
+			return
+		}
+		if errors.Is(err, ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
 			return
 		}
 		if pkgErrors.IsNotFoundError(err) {
@@ -74,18 +142,238 @@ func (h *handler) Create(c *gin.Context) {
 	c.JSON(http.StatusCreated, flag)
 }
 
+// List returns the tenant's flags, optionally filtered by ?owner= (a user ID, or "me") and ?lifecycle=.
+// ?include_stats=true adds each flag's evaluation stats.
 func (h *handler) List(c *gin.Context) {
 	tenantID := appContext.MustTenantID(c.Request.Context())
 
-	flags, err := h.service.List(c.Request.Context(), tenantID)
+	lifecycles, err := ParseLifecycleFilter(c.Query("lifecycle"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+		return
+	}
+	includeStats, err := strconv.ParseBool(c.DefaultQuery("include_stats", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "include_stats must be true or false"})
+		return
+	}
+
+	var flags []Flag
+	if owner := c.Query("owner"); owner != "" {
+		if owner == "me" {
+			owner = appContext.MustUserID(c.Request.Context())
+		}
+		flags, err = h.service.ListByOwner(c.Request.Context(), tenantID, owner)
+	} else {
+		flags, err = h.service.List(c.Request.Context(), tenantID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list flags"})
+		return
+	}
+
+	h.respondWithFlags(c, FilterByLifecycle(flags, lifecycles), includeStats)
+}
+
+// ListByProject returns the flags in one project, optionally filtered by ?lifecycle=.
+// ?include_stats=true adds each flag's evaluation stats.
+func (h *handler) ListByProject(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	lifecycles, err := ParseLifecycleFilter(c.Query("lifecycle"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+		return
+	}
+	includeStats, err := strconv.ParseBool(c.DefaultQuery("include_stats", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "include_stats must be true or false"})
+		return
+	}
+
+	flags, err := h.service.ListByProject(c.Request.Context(), c.Param("id"), tenantID)
 	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list flags"})
 		return
 	}
 
+	h.respondWithFlags(c, FilterByLifecycle(flags, lifecycles), includeStats)
+}
+
+// respondWithFlags writes a flag list, loading evaluation stats only when asked for
+// so the default list doesn't pay for the aggregation
+func (h *handler) respondWithFlags(c *gin.Context, flags []Flag, includeStats bool) {
+	if includeStats {
+		tenantID := appContext.MustTenantID(c.Request.Context())
+		if err := h.service.AttachStats(c.Request.Context(), flags, tenantID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load flag stats"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, flags)
+}
+
+// ListStale returns flags not evaluated or modified within ?days= (default 30)
+func (h *handler) ListStale(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	days := DefaultStaleDays
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a number"})
+			return
+		}
+		days = parsed
+	}
+
+	flags, err := h.service.ListStale(c.Request.Context(), tenantID, days)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list stale flags"})
+		return
+	}
+
 	c.JSON(http.StatusOK, flags)
 }
 
+// ListAttributes returns the project's attribute registry, used to validate rules and to
+// autocomplete attribute names in rule editors
+func (h *handler) ListAttributes(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	attributes, err := h.service.ListAttributes(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list attributes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attributes)
+}
+
+// RegisterAttribute adds an attribute to the project's registry, or retypes a registered one
+func (h *handler) RegisterAttribute(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var req RegisterAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attribute, err := h.service.RegisterAttribute(c.Request.Context(), c.Param("id"), req, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register attribute"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attribute)
+}
+
+// DeleteAttribute removes an attribute from the project's registry
+func (h *handler) DeleteAttribute(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	if err := h.service.DeleteAttribute(c.Request.Context(), c.Param("id"), c.Param("attributeID"), tenantID); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "attribute not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete attribute"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// AttributeReport lists rule attributes SDKs haven't sent within ?days= (default 7), and vice versa
+func (h *handler) AttributeReport(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	days := DefaultAttributeWindowDays
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a number"})
+			return
+		}
+		days = parsed
+	}
+
+	report, err := h.service.AttributeReport(c.Request.Context(), c.Param("id"), tenantID, days)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build attribute report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RenameAttribute moves the rules of every flag in the project from one attribute to another.
+// With dry_run it returns the rules that would change and changes nothing.
+func (h *handler) RenameAttribute(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+
+	var req RenameAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.RenameAttribute(c.Request.Context(), c.Param("id"), req, userID, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rename attribute"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *handler) Get(c *gin.Context) {
 	id := c.Param("id")
 	tenantID := appContext.MustTenantID(c.Request.Context())
@@ -112,6 +400,10 @@ func (h *handler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+		return
+	}
 
 	// Fetch existing flag first to ensure it exists and belongs to tenant
 	flag, err := h.service.GetByID(c.Request.Context(), id, tenantID)
@@ -125,34 +417,29 @@ func (h *handler) Update(c *gin.Context) {
 	}
 
 	// Apply updates
-	if req.ProjectID != nil {
-		flag.ProjectID = req.ProjectID
-	}
-	if req.Name != nil {
-		flag.Name = *req.Name
-	}
-	if req.Description != nil {
-		flag.Description = *req.Description
-	}
-	if req.Enabled != nil {
-		flag.Enabled = *req.Enabled
-	}
-	if req.Rules != nil {
-		flag.Rules = req.Rules
-	}
-	if req.RuleLogic != nil {
-		flag.RuleLogic = *req.RuleLogic
-	}
+	req.Apply(flag)
 
 	if err := h.service.Update(c.Request.Context(), flag, tenantID); err != nil {
 		if errors.Is(err, ErrInvalidFlagData) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if errors.Is(err, ErrDuplicateName) {
+			c.JSON(http.StatusConflict, ConflictResponse(err))
+			return
+		}
+		if errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
 			return
 		}
 		if pkgErrors.IsNotFoundError(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
 			return
 		}
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update flag"})
 		return
 	}
@@ -160,23 +447,62 @@ func (h *handler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, flag)
 }
 
-func (h *handler) Toggle(c *gin.Context) {
+// Patch updates the fields named in the request's update_mask, leaving the rest unchanged
+func (h *handler) Patch(c *gin.Context) {
 	id := c.Param("id")
 	tenantID := appContext.MustTenantID(c.Request.Context())
 
-	flag, err := h.service.GetByID(c.Request.Context(), id, tenantID)
+	var req PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flag, err := h.service.Patch(c.Request.Context(), id, req, tenantID)
 	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if errors.Is(err, ErrDuplicateName) {
+			c.JSON(http.StatusConflict, ConflictResponse(err))
+			return
+		}
+		if errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
 		if pkgErrors.IsNotFoundError(err) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get flag"})
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to patch flag"})
 		return
 	}
 
-	flag.Enabled = !flag.Enabled
+	c.JSON(http.StatusOK, flag)
+}
+
+// Toggle flips the flag's enabled state in one atomic update
+func (h *handler) Toggle(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
 
-	if err := h.service.Update(c.Request.Context(), flag, tenantID); err != nil {
+	flag, err := h.service.Toggle(c.Request.Context(), id, userID, tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to toggle flag"})
 		return
 	}
@@ -184,6 +510,29 @@ func (h *handler) Toggle(c *gin.Context) {
 	c.JSON(http.StatusOK, flag)
 }
 
+// Reshuffle re-randomizes which users fall inside the flag's rollouts
+func (h *handler) Reshuffle(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+
+	flag, err := h.service.Reshuffle(c.Request.Context(), id, userID, tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reshuffle flag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, flag)
+}
+
 func (h *handler) Delete(c *gin.Context) {
 	id := c.Param("id")
 	tenantID := appContext.MustTenantID(c.Request.Context())
@@ -199,3 +548,560 @@ func (h *handler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+func (h *handler) Clone(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var req CloneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flag, err := h.service.Clone(c.Request.Context(), id, req.ProjectID, req.Name, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if errors.Is(err, ErrDuplicateName) {
+			c.JSON(http.StatusConflict, ConflictResponse(err))
+			return
+		}
+		if errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clone flag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, flag)
+}
+
+// Export returns a project's flags as a JSON document that Import accepts.
+// ?encrypt=true seals the document with the tenant encryption key.
+func (h *handler) Export(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	encrypt, err := strconv.ParseBool(c.DefaultQuery("encrypt", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "encrypt must be true or false"})
+		return
+	}
+
+	var doc *ExportDocument
+	if encrypt {
+		doc, err = h.service.ExportSealed(c.Request.Context(), c.Param("id"), tenantID)
+	} else {
+		doc, err = h.service.Export(c.Request.Context(), c.Param("id"), tenantID)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+// Apply makes the project's flag with the URL's key match the body, creating it if needed,
+// so infrastructure-as-code tools can manage flags declaratively. The body is the flag's
+// whole definition, as in exports: omitted fields take their defaults. Answers 201 when the
+// flag was created; reapplying the same definition answers "unchanged" and writes nothing.
+func (h *handler) Apply(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var def ExportedFlag
+	if err := c.ShouldBindJSON(&def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.Apply(c.Request.Context(), c.Param("id"), c.Param("key"), def, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if errors.Is(err, ErrDuplicateName) {
+			c.JSON(http.StatusConflict, ConflictResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply flag"})
+		return
+	}
+
+	status := http.StatusOK
+	if result.Result == ApplyCreated {
+		status = http.StatusCreated
+	}
+	c.JSON(status, result)
+}
+
+// Import creates or updates a project's flags from an export document.
+// ?on_conflict=skip|overwrite chooses what happens to existing keys; ?dry_run=true only validates.
+func (h *handler) Import(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var doc ExportDocument
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts, ok := importOptions(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Import(c.Request.Context(), c.Param("id"), tenantID, &doc, opts)
+	respondImport(c, result, err)
+}
+
+// UnleashSkippedHeader lists, comma-separated, the keys of flags an Unleash export left out
+const UnleashSkippedHeader = "X-Toggle-Skipped-Flags"
+
+// ExportUnleash returns a project's flags as an Unleash state export. Flags Unleash can't
+// express are left out and named in the X-Toggle-Skipped-Flags header.
+func (h *handler) ExportUnleash(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	doc, skipped, err := h.service.ExportUnleash(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export flags"})
+		return
+	}
+
+	if len(skipped) > 0 {
+		c.Header(UnleashSkippedHeader, strings.Join(skipped, ","))
+	}
+	c.JSON(http.StatusOK, doc)
+}
+
+// ImportUnleash creates or updates a project's flags from an Unleash state export. It takes
+// Import's query parameters, and ?environment= picks the environment of an Unleash 4 export.
+func (h *handler) ImportUnleash(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var doc UnleashExport
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts, ok := importOptions(c)
+	if !ok {
+		return
+	}
+	opts.Environment = c.Query("environment")
+
+	result, err := h.service.ImportUnleash(c.Request.Context(), c.Param("id"), tenantID, &doc, opts)
+	respondImport(c, result, err)
+}
+
+// importOptions reads an import's query parameters, answering 400 when they're invalid
+func importOptions(c *gin.Context) (ImportOptions, bool) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return ImportOptions{}, false
+	}
+	return ImportOptions{OnConflict: c.Query("on_conflict"), DryRun: dryRun}, true
+}
+
+// respondImport answers an import with its result, or the status for its error
+func respondImport(c *gin.Context, result *ImportResult, err error) {
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if errors.Is(err, ErrDuplicateName) {
+			c.JSON(http.StatusConflict, ConflictResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import flags"})
+		return
+	}
+
+	// A real import with invalid flags wrote nothing; a dry run always reports
+	if len(result.Errors) > 0 && !result.DryRun {
+		c.JSON(http.StatusBadRequest, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *handler) ListExclusions(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	exclusions, err := h.service.ListExclusions(c.Request.Context(), id, tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list exclusions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exclusions)
+}
+
+// AddExclusions adds user keys to a flag's exclusion list without replacing existing entries
+func (h *handler) AddExclusions(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var req AddExclusionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exclusions, err := h.service.AddExclusions(c.Request.Context(), id, req.UserKeys, req.Reason, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add exclusions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exclusions)
+}
+
+func (h *handler) RemoveExclusion(c *gin.Context) {
+	id := c.Param("id")
+	userKey := c.Param("user_key")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	if err := h.service.RemoveExclusion(c.Request.Context(), id, userKey, tenantID); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "exclusion not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove exclusion"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *handler) ListCustomFields(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	fields, err := h.service.ListCustomFields(c.Request.Context(), id, tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list custom fields"})
+		return
+	}
+
+	c.JSON(http.StatusOK, fields)
+}
+
+// SetCustomField creates or replaces one custom field, e.g. PUT /flags/:id/fields/service
+func (h *handler) SetCustomField(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var req SetCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	field, err := h.service.SetCustomField(c.Request.Context(), id, c.Param("name"), req.Value, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set custom field"})
+		return
+	}
+
+	c.JSON(http.StatusOK, field)
+}
+
+func (h *handler) DeleteCustomField(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	if err := h.service.DeleteCustomField(c.Request.Context(), id, c.Param("name"), tenantID); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "custom field not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete custom field"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *handler) ListEnvironmentConfigs(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	configs, err := h.service.ListEnvironmentConfigs(c.Request.Context(), id, tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list environment configs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, configs)
+}
+
+// SetEnvironmentConfig replaces a flag's config in one environment, e.g. PUT /flags/:id/environments/:envID
+func (h *handler) SetEnvironmentConfig(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var req SetEnvironmentConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config, err := h.service.SetEnvironmentConfig(c.Request.Context(), id, c.Param("envID"), req, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag or environment not found"})
+			return
+		}
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set environment config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// DiffEnvironments compares a flag across environments, e.g. GET /flags/:id/diff?from=staging&to=production
+func (h *handler) DiffEnvironments(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to environment keys are required"})
+		return
+	}
+
+	diff, err := h.service.DiffEnvironments(c.Request.Context(), id, from, to, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag or environment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to diff environments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// Promote copies a flag's config from one environment to another
+func (h *handler) Promote(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+
+	var req PromoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diff, err := h.service.Promote(c.Request.Context(), id, req, userID, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag or environment not found"})
+			return
+		}
+		if errors.Is(err, ErrApprovalRequired) {
+			c.JSON(http.StatusConflict, ApprovalRequiredResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to promote flag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+func (h *handler) ListOverrides(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	overrides, err := h.service.ListOverrides(c.Request.Context(), id, tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list overrides"})
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+// CreateOverride forces the flag on or off for one user key until expires_at
+func (h *handler) CreateOverride(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+
+	var req CreateOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	override := &Override{
+		FlagID:    id,
+		UserKey:   req.UserKey,
+		Enabled:   *req.Enabled,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: &userID,
+	}
+
+	if err := h.service.CreateOverride(c.Request.Context(), override, tenantID); err != nil {
+		if errors.Is(err, ErrInvalidFlagData) {
+			c.JSON(http.StatusBadRequest, InvalidDataResponse(err))
+			return
+		}
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create override"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, override)
+}
+
+func (h *handler) DeleteOverride(c *gin.Context) {
+	id := c.Param("id")
+	userKey := c.Param("user_key")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	if err := h.service.DeleteOverride(c.Request.Context(), id, userKey, tenantID); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "override not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete override"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Metrics returns the flag's rolling evaluation counters
+func (h *handler) Metrics(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	metrics, err := h.service.Metrics(c.Request.Context(), id, tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get flag metrics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// ListHistory returns the flag's history, newest first
+func (h *handler) ListHistory(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	entries, err := h.service.ListHistory(c.Request.Context(), id, tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "flag not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list flag history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -7,20 +7,35 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	appContext "github.com/jalil32/toggle/internal/pkg/context"
 	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/transaction"
 )
 
 type mockService struct {
-	createFunc  func(ctx context.Context, f *Flag, tenantID string) error
-	getByIDFunc func(ctx context.Context, id string, tenantID string) (*Flag, error)
-	listFunc    func(ctx context.Context, tenantID string) ([]Flag, error)
-	updateFunc  func(ctx context.Context, f *Flag, tenantID string) error
-	deleteFunc  func(ctx context.Context, id string, tenantID string) error
+	createFunc   func(ctx context.Context, f *Flag, tenantID string) error
+	getByIDFunc  func(ctx context.Context, id string, tenantID string) (*Flag, error)
+	listFunc     func(ctx context.Context, tenantID string) ([]Flag, error)
+	ownerFunc    func(ctx context.Context, tenantID string, ownerUserID string) ([]Flag, error)
+	projectFunc  func(ctx context.Context, projectID string, tenantID string) ([]Flag, error)
+	updateFunc   func(ctx context.Context, f *Flag, tenantID string) error
+	toggleFunc   func(ctx context.Context, id string, tenantID string) (*Flag, error)
+	patchFunc    func(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error)
+	deleteFunc   func(ctx context.Context, id string, tenantID string) error
+	cloneFunc    func(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error)
+	fromTplFunc  func(ctx context.Context, f *Flag, templateID string, tenantID string) error
+	staleFunc    func(ctx context.Context, tenantID string, days int) ([]StaleFlag, error)
+	addExclFunc  func(ctx context.Context, id string, userKeys []string, reason string, tenantID string) ([]Exclusion, error)
+	rmExclFunc   func(ctx context.Context, id string, userKey string, tenantID string) error
+	setFieldFunc func(ctx context.Context, id string, name string, value string, tenantID string) (*CustomField, error)
+	overrideFn   func(ctx context.Context, o *Override, tenantID string) error
+	statsFunc    func(ctx context.Context, flags []Flag, tenantID string) error
 }
 
 func (m *mockService) Create(ctx context.Context, f *Flag, tenantID string) error {
@@ -44,6 +59,20 @@ func (m *mockService) List(ctx context.Context, tenantID string) ([]Flag, error)
 	return nil, nil
 }
 
+func (m *mockService) ListByOwner(ctx context.Context, tenantID string, ownerUserID string) ([]Flag, error) {
+	if m.ownerFunc != nil {
+		return m.ownerFunc(ctx, tenantID, ownerUserID)
+	}
+	return nil, nil
+}
+
+func (m *mockService) ListByProject(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+	if m.projectFunc != nil {
+		return m.projectFunc(ctx, projectID, tenantID)
+	}
+	return nil, nil
+}
+
 func (m *mockService) Update(ctx context.Context, f *Flag, tenantID string) error {
 	if m.updateFunc != nil {
 		return m.updateFunc(ctx, f, tenantID)
@@ -51,6 +80,24 @@ func (m *mockService) Update(ctx context.Context, f *Flag, tenantID string) erro
 	return nil
 }
 
+func (m *mockService) Toggle(ctx context.Context, id string, actorID string, tenantID string) (*Flag, error) {
+	if m.toggleFunc != nil {
+		return m.toggleFunc(ctx, id, tenantID)
+	}
+	return nil, nil
+}
+
+func (m *mockService) Patch(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error) {
+	if m.patchFunc != nil {
+		return m.patchFunc(ctx, id, req, tenantID)
+	}
+	return nil, nil
+}
+
+func (m *mockService) Reshuffle(ctx context.Context, id string, actorID string, tenantID string) (*Flag, error) {
+	return nil, nil
+}
+
 func (m *mockService) Delete(ctx context.Context, id string, tenantID string) error {
 	if m.deleteFunc != nil {
 		return m.deleteFunc(ctx, id, tenantID)
@@ -58,6 +105,180 @@ func (m *mockService) Delete(ctx context.Context, id string, tenantID string) er
 	return nil
 }
 
+func (m *mockService) Clone(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error) {
+	if m.cloneFunc != nil {
+		return m.cloneFunc(ctx, id, targetProjectID, name, tenantID)
+	}
+	return nil, nil
+}
+
+func (m *mockService) CreateFromTemplate(ctx context.Context, f *Flag, templateID string, tenantID string) error {
+	if m.fromTplFunc != nil {
+		return m.fromTplFunc(ctx, f, templateID, tenantID)
+	}
+	return nil
+}
+
+func (m *mockService) SetTemplateSource(templates TemplateSource) {}
+
+func (m *mockService) AttributeReport(ctx context.Context, projectID string, tenantID string, days int) (*AttributeReport, error) {
+	return nil, nil
+}
+
+func (m *mockService) RenameAttribute(ctx context.Context, projectID string, req RenameAttributeRequest, actorID string, tenantID string) (*AttributeRename, error) {
+	return nil, nil
+}
+
+func (m *mockService) ListAttributes(ctx context.Context, projectID string, tenantID string) ([]RegisteredAttribute, error) {
+	return nil, nil
+}
+
+func (m *mockService) RegisterAttribute(ctx context.Context, projectID string, req RegisterAttributeRequest, tenantID string) (*RegisteredAttribute, error) {
+	return nil, nil
+}
+
+func (m *mockService) DeleteAttribute(ctx context.Context, projectID string, attributeID string, tenantID string) error {
+	return nil
+}
+
+func (m *mockService) DisableExpired(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockService) AttachStats(ctx context.Context, flags []Flag, tenantID string) error {
+	if m.statsFunc != nil {
+		return m.statsFunc(ctx, flags, tenantID)
+	}
+	return nil
+}
+
+func (m *mockService) Metrics(ctx context.Context, id string, tenantID string) (*FlagMetrics, error) {
+	return nil, nil
+}
+
+func (m *mockService) PruneResultCounts(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockService) ListHistory(ctx context.Context, id string, tenantID string) ([]HistoryEntry, error) {
+	return nil, nil
+}
+
+func (m *mockService) ListStale(ctx context.Context, tenantID string, days int) ([]StaleFlag, error) {
+	if m.staleFunc != nil {
+		return m.staleFunc(ctx, tenantID, days)
+	}
+	return nil, nil
+}
+
+func (m *mockService) ListExclusions(ctx context.Context, id string, tenantID string) ([]Exclusion, error) {
+	return []Exclusion{}, nil
+}
+
+func (m *mockService) AddExclusions(ctx context.Context, id string, userKeys []string, reason string, tenantID string) ([]Exclusion, error) {
+	if m.addExclFunc != nil {
+		return m.addExclFunc(ctx, id, userKeys, reason, tenantID)
+	}
+	return nil, nil
+}
+
+func (m *mockService) ListCustomFields(ctx context.Context, id string, tenantID string) ([]CustomField, error) {
+	return []CustomField{}, nil
+}
+
+func (m *mockService) SetCustomField(ctx context.Context, id string, name string, value string, tenantID string) (*CustomField, error) {
+	if m.setFieldFunc != nil {
+		return m.setFieldFunc(ctx, id, name, value, tenantID)
+	}
+	return &CustomField{FlagID: id, Name: name, Value: value}, nil
+}
+
+func (m *mockService) DeleteCustomField(ctx context.Context, id string, name string, tenantID string) error {
+	return nil
+}
+
+func (m *mockService) ListEnvironmentConfigs(ctx context.Context, id string, tenantID string) ([]EnvironmentConfig, error) {
+	return nil, nil
+}
+
+func (m *mockService) SetEnvironmentConfig(ctx context.Context, id string, environmentID string, req SetEnvironmentConfigRequest, tenantID string) (*EnvironmentConfig, error) {
+	return nil, nil
+}
+
+func (m *mockService) DiffEnvironments(ctx context.Context, id string, from string, to string, tenantID string) (*EnvironmentDiff, error) {
+	return nil, nil
+}
+
+func (m *mockService) Promote(ctx context.Context, id string, req PromoteRequest, actorID string, tenantID string) (*EnvironmentDiff, error) {
+	return nil, nil
+}
+
+func (m *mockService) RemoveExclusion(ctx context.Context, id string, userKey string, tenantID string) error {
+	if m.rmExclFunc != nil {
+		return m.rmExclFunc(ctx, id, userKey, tenantID)
+	}
+	return nil
+}
+
+func (m *mockService) CreateOverride(ctx context.Context, o *Override, tenantID string) error {
+	if m.overrideFn != nil {
+		return m.overrideFn(ctx, o, tenantID)
+	}
+	return nil
+}
+
+func (m *mockService) ListOverrides(ctx context.Context, id string, tenantID string) ([]Override, error) {
+	return []Override{}, nil
+}
+
+func (m *mockService) DeleteOverride(ctx context.Context, id string, userKey string, tenantID string) error {
+	return nil
+}
+
+func (m *mockService) ExpireOverrides(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockService) Export(ctx context.Context, projectID string, tenantID string) (*ExportDocument, error) {
+	return &ExportDocument{Version: ExportVersion, ProjectID: projectID, Flags: []ExportedFlag{}}, nil
+}
+
+func (m *mockService) Import(ctx context.Context, projectID string, tenantID string, doc *ExportDocument, opts ImportOptions) (*ImportResult, error) {
+	return &ImportResult{DryRun: opts.DryRun}, nil
+}
+
+func (m *mockService) Apply(ctx context.Context, projectID string, key string, def ExportedFlag, tenantID string) (*ApplyResult, error) {
+	return &ApplyResult{Result: ApplyCreated, Flag: &Flag{Key: key, Name: def.Name}}, nil
+}
+
+func (m *mockService) ExportUnleash(ctx context.Context, projectID string, tenantID string) (*UnleashExport, []string, error) {
+	return &UnleashExport{Version: UnleashExportVersion, Features: []UnleashFeature{}}, nil, nil
+}
+
+func (m *mockService) ImportUnleash(ctx context.Context, projectID string, tenantID string, doc *UnleashExport, opts ImportOptions) (*ImportResult, error) {
+	return &ImportResult{DryRun: opts.DryRun}, nil
+}
+
+func (m *mockService) ExportSealed(ctx context.Context, projectID string, tenantID string) (*ExportDocument, error) {
+	return &ExportDocument{Version: ExportVersion, ProjectID: projectID, Sealed: "tk1:1:c2VhbGVk"}, nil
+}
+
+func (m *mockService) SetUnitOfWork(uow transaction.UnitOfWork) {}
+
+func (m *mockService) SetSealer(sealer Sealer) {}
+
+func (m *mockService) SetFlagCache(cache FlagCache) {}
+
+func (m *mockService) SetApprovalGate(gate ApprovalGate) {}
+
+func (m *mockService) ApplyApprovedChange(ctx context.Context, f *Flag, tenantID string) error {
+	return nil
+}
+
+func (m *mockService) ApplyApprovedEnvironmentChange(ctx context.Context, id string, environment string, changes UpdateRequest, tenantID string) (*EnvironmentConfig, error) {
+	return nil, nil
+}
+
 func setupTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	return gin.New()
@@ -121,6 +342,26 @@ func TestHandlerCreate(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			checkResponse:  nil,
 		},
+		{
+			name: "duplicate name in project",
+			body: CreateRequest{
+				ProjectID: stringPtr("test-project-id"),
+				Name:      "test-flag",
+			},
+			mockFn: func(ctx context.Context, f *Flag, tenantID string) error {
+				return &DuplicateNameError{ProjectID: "test-project-id", Name: "test-flag"}
+			},
+			expectedStatus: http.StatusConflict,
+			checkResponse: func(t *testing.T, body []byte) {
+				var resp map[string]string
+				if err := json.Unmarshal(body, &resp); err != nil {
+					t.Fatalf("failed to unmarshal response: %v", err)
+				}
+				if resp["code"] != "duplicate_name" || resp["field"] != "name" || resp["name"] != "test-flag" {
+					t.Errorf("expected structured duplicate_name error, got %v", resp)
+				}
+			},
+		},
 		{
 			name: "service validation error",
 			body: CreateRequest{
@@ -134,6 +375,34 @@ func TestHandlerCreate(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			checkResponse:  nil,
 		},
+		{
+			name: "invalid rules are listed",
+			body: CreateRequest{
+				ProjectID: stringPtr("test-project-id"),
+				Name:      "test-flag",
+			},
+			mockFn: func(ctx context.Context, f *Flag, tenantID string) error {
+				return &RuleValidationError{Errors: []RuleError{
+					{Index: 0, Field: "operator", Message: `unknown operator "contains"`},
+					{Index: 1, Field: "rollout", Message: "rollout must be between 0 and 100"},
+				}}
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, body []byte) {
+				var resp struct {
+					RuleErrors []RuleError `json:"rule_errors"`
+				}
+				if err := json.Unmarshal(body, &resp); err != nil {
+					t.Fatalf("failed to unmarshal response: %v", err)
+				}
+				if len(resp.RuleErrors) != 2 {
+					t.Fatalf("expected 2 rule errors, got %d", len(resp.RuleErrors))
+				}
+				if resp.RuleErrors[1].Index != 1 || resp.RuleErrors[1].Field != "rollout" {
+					t.Errorf("unexpected rule error: %+v", resp.RuleErrors[1])
+				}
+			},
+		},
 		{
 			name: "service error",
 			body: CreateRequest{
@@ -179,6 +448,66 @@ func TestHandlerCreate(t *testing.T) {
 	}
 }
 
+func TestHandlerCreate_WithTemplate(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockFn         func(ctx context.Context, f *Flag, templateID string, tenantID string) error
+		expectedStatus int
+	}{
+		{
+			name: "creates from template",
+			mockFn: func(ctx context.Context, f *Flag, templateID string, tenantID string) error {
+				if templateID != "tpl-1" {
+					t.Errorf("expected template 'tpl-1', got '%s'", templateID)
+				}
+				// Defaults must be left to the template
+				if f.Rules != nil || f.RuleLogic != "" {
+					t.Errorf("expected unset rules and rule logic, got %v / %q", f.Rules, f.RuleLogic)
+				}
+				f.ID = "generated-id"
+				return nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "template not found",
+			mockFn: func(ctx context.Context, f *Flag, templateID string, tenantID string) error {
+				return ErrTemplateNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockService{
+				createFunc: func(ctx context.Context, f *Flag, tenantID string) error {
+					t.Error("expected CreateFromTemplate to be used")
+					return nil
+				},
+				fromTplFunc: tt.mockFn,
+			}
+			h := NewHandler(mockSvc)
+
+			router := setupTestRouter()
+			router.POST("/flags", h.(*handler).Create)
+
+			bodyBytes, _ := json.Marshal(map[string]string{"name": "from-template"})
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "admin")
+			req := httptest.NewRequest(http.MethodPost, "/flags?template_id=tpl-1", bytes.NewReader(bodyBytes))
+			req = req.WithContext(ctx)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
 func TestHandlerList(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -413,6 +742,51 @@ func TestHandlerUpdate(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			checkResponse:  nil,
 		},
+		{
+			name: "move to another project and change rule logic",
+			id:   "test-id",
+			body: UpdateRequest{
+				ProjectID: stringPtr("project-2"),
+				RuleLogic: stringPtr(RuleLogicOr),
+			},
+			mockGetFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return &Flag{ID: id, Name: "old-name", ProjectID: stringPtr("project-1"), RuleLogic: RuleLogicAnd}, nil
+			},
+			mockUpdateFn: func(ctx context.Context, f *Flag, tenantID string) error {
+				if f.ProjectID == nil || *f.ProjectID != "project-2" || f.RuleLogic != RuleLogicOr {
+					t.Errorf("expected project-2 with OR logic, got %v %s", f.ProjectID, f.RuleLogic)
+				}
+				return nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "move to project in another tenant",
+			id:   "test-id",
+			body: UpdateRequest{ProjectID: stringPtr("foreign-project")},
+			mockGetFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return &Flag{ID: id, Name: "old-name"}, nil
+			},
+			mockUpdateFn: func(ctx context.Context, f *Flag, tenantID string) error {
+				return pkgErrors.ErrProjectNotInTenant
+			},
+			expectedStatus: http.StatusNotFound,
+			checkResponse: func(t *testing.T, body []byte) {
+				if !bytes.Contains(body, []byte("project not found")) {
+					t.Errorf("expected project not found, got %s", body)
+				}
+			},
+		},
+		{
+			name: "empty rule logic",
+			id:   "test-id",
+			body: UpdateRequest{RuleLogic: stringPtr("")},
+			mockGetFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				t.Error("expected request to be rejected before loading the flag")
+				return nil, nil
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -446,81 +820,140 @@ func TestHandlerUpdate(t *testing.T) {
 	}
 }
 
-func TestHandlerToggle(t *testing.T) {
+func TestHandlerPatch(t *testing.T) {
 	tests := []struct {
 		name           string
-		id             string
-		mockGetFn      func(ctx context.Context, id string, tenantID string) (*Flag, error)
-		mockUpdateFn   func(ctx context.Context, f *Flag, tenantID string) error
+		body           string
+		mockPatchFn    func(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error)
 		expectedStatus int
-		checkResponse  func(t *testing.T, body []byte)
 	}{
 		{
-			name: "successful toggle from false to true",
-			id:   "test-id",
-			mockGetFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
-				return &Flag{
-					ID:      id,
-					Name:    "test-flag",
-					Enabled: false,
-				}, nil
+			name: "patches the masked fields",
+			body: `{"update_mask": ["enabled"], "enabled": false}`,
+			mockPatchFn: func(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error) {
+				if !req.Has(PatchFieldEnabled) || req.Enabled {
+					t.Errorf("expected the mask and value to reach the service, got %+v", req)
+				}
+				return &Flag{ID: id, Name: "kept-name"}, nil
 			},
-			mockUpdateFn:   nil,
 			expectedStatus: http.StatusOK,
-			checkResponse: func(t *testing.T, body []byte) {
-				var flag Flag
-				if err := json.Unmarshal(body, &flag); err != nil {
-					t.Errorf("failed to unmarshal response: %v", err)
-				}
-				if !flag.Enabled {
-					t.Error("expected flag to be enabled")
-				}
+		},
+		{
+			name:           "missing update mask",
+			body:           `{"enabled": true}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid masked field",
+			body: `{"update_mask": ["salt"]}`,
+			mockPatchFn: func(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error) {
+				return nil, req.Validate()
 			},
+			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name: "successful toggle from true to false",
-			id:   "test-id",
-			mockGetFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
-				return &Flag{
-					ID:      id,
-					Name:    "test-flag",
-					Enabled: true,
-				}, nil
+			name: "duplicate name",
+			body: `{"update_mask": ["name"], "name": "taken"}`,
+			mockPatchFn: func(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error) {
+				return nil, &DuplicateNameError{Name: req.Name}
 			},
-			mockUpdateFn:   nil,
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "flag not found",
+			body: `{"update_mask": ["enabled"]}`,
+			mockPatchFn: func(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error) {
+				return nil, pkgErrors.ErrNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockService{
+				getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+					t.Error("patch must not read the flag before updating it")
+					return nil, nil
+				},
+				patchFunc: tt.mockPatchFn,
+			}
+			h := NewHandler(mockSvc)
+
+			router := setupTestRouter()
+			router.PATCH("/flags/:id", h.(*handler).Patch)
+
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "admin")
+			req := httptest.NewRequest(http.MethodPatch, "/flags/test-id", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerToggle(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		mockToggleFn   func(ctx context.Context, id string, tenantID string) (*Flag, error)
+		expectedStatus int
+		checkResponse  func(t *testing.T, body []byte)
+	}{
+		{
+			name: "successful toggle returns the updated flag",
+			id:   "test-id",
+			mockToggleFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return &Flag{
+					ID:      id,
+					Name:    "test-flag",
+					Enabled: true,
+				}, nil
+			},
+			expectedStatus: http.StatusOK,
 			checkResponse: func(t *testing.T, body []byte) {
 				var flag Flag
 				if err := json.Unmarshal(body, &flag); err != nil {
 					t.Errorf("failed to unmarshal response: %v", err)
 				}
-				if flag.Enabled {
-					t.Error("expected flag to be disabled")
+				if !flag.Enabled {
+					t.Error("expected flag to be enabled")
 				}
 			},
 		},
 		{
 			name: "flag not found",
 			id:   "non-existent",
-			mockGetFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			mockToggleFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
 				return nil, pkgErrors.ErrNotFound
 			},
-			mockUpdateFn:   nil,
 			expectedStatus: http.StatusNotFound,
 			checkResponse:  nil,
 		},
 		{
-			name: "update error",
+			name: "project requires approval",
 			id:   "test-id",
-			mockGetFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
-				return &Flag{
-					ID:      id,
-					Name:    "test-flag",
-					Enabled: false,
-				}, nil
+			mockToggleFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return nil, ErrApprovalRequired
 			},
-			mockUpdateFn: func(ctx context.Context, f *Flag, tenantID string) error {
-				return errors.New("database error")
+			expectedStatus: http.StatusConflict,
+			checkResponse: func(t *testing.T, body []byte) {
+				if !strings.Contains(string(body), `"code":"approval_required"`) {
+					t.Errorf("expected an approval_required code, got %s", body)
+				}
+			},
+		},
+		{
+			name: "toggle error",
+			id:   "test-id",
+			mockToggleFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return nil, errors.New("database error")
 			},
 			expectedStatus: http.StatusInternalServerError,
 			checkResponse:  nil,
@@ -530,8 +963,11 @@ func TestHandlerToggle(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockSvc := &mockService{
-				getByIDFunc: tt.mockGetFn,
-				updateFunc:  tt.mockUpdateFn,
+				getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+					t.Error("toggle must not read the flag before updating it")
+					return nil, nil
+				},
+				toggleFunc: tt.mockToggleFn,
 			}
 			h := NewHandler(mockSvc)
 
@@ -611,6 +1047,477 @@ func TestHandlerDelete(t *testing.T) {
 	}
 }
 
+func TestHandlerClone(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		body           interface{}
+		mockFn         func(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error)
+		expectedStatus int
+		checkResponse  func(t *testing.T, body []byte)
+	}{
+		{
+			name: "successful clone",
+			id:   "source-id",
+			body: CloneRequest{ProjectID: "target-project-id", Name: "cloned-flag"},
+			mockFn: func(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error) {
+				if tenantID != "test-tenant-id" {
+					t.Errorf("expected tenant 'test-tenant-id', got '%s'", tenantID)
+				}
+				return &Flag{
+					ID:        "clone-id",
+					TenantID:  tenantID,
+					ProjectID: &targetProjectID,
+					Name:      name,
+				}, nil
+			},
+			expectedStatus: http.StatusCreated,
+			checkResponse: func(t *testing.T, body []byte) {
+				var flag Flag
+				if err := json.Unmarshal(body, &flag); err != nil {
+					t.Errorf("failed to unmarshal response: %v", err)
+				}
+				if flag.ProjectID == nil || *flag.ProjectID != "target-project-id" {
+					t.Errorf("expected project 'target-project-id', got %v", flag.ProjectID)
+				}
+				if flag.Name != "cloned-flag" {
+					t.Errorf("expected name 'cloned-flag', got '%s'", flag.Name)
+				}
+			},
+		},
+		{
+			name:           "missing project_id",
+			id:             "source-id",
+			body:           map[string]string{"name": "cloned-flag"},
+			mockFn:         nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "source flag not found",
+			id:   "non-existent",
+			body: CloneRequest{ProjectID: "target-project-id"},
+			mockFn: func(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error) {
+				return nil, pkgErrors.ErrNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+			checkResponse: func(t *testing.T, body []byte) {
+				if !bytes.Contains(body, []byte("flag not found")) {
+					t.Errorf("expected flag not found error, got %s", body)
+				}
+			},
+		},
+		{
+			name: "target project in another tenant",
+			id:   "source-id",
+			body: CloneRequest{ProjectID: "foreign-project-id"},
+			mockFn: func(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error) {
+				return nil, pkgErrors.ErrProjectNotInTenant
+			},
+			expectedStatus: http.StatusNotFound,
+			checkResponse: func(t *testing.T, body []byte) {
+				if !bytes.Contains(body, []byte("project not found")) {
+					t.Errorf("expected project not found error, got %s", body)
+				}
+			},
+		},
+		{
+			name: "service error",
+			id:   "source-id",
+			body: CloneRequest{ProjectID: "target-project-id"},
+			mockFn: func(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error) {
+				return nil, errors.New("database error")
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockService{
+				cloneFunc: tt.mockFn,
+			}
+			h := NewHandler(mockSvc)
+
+			router := setupTestRouter()
+			router.POST("/flags/:id/clone", h.(*handler).Clone)
+
+			bodyBytes, _ := json.Marshal(tt.body)
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "admin")
+			req := httptest.NewRequest(http.MethodPost, "/flags/"+tt.id+"/clone", bytes.NewReader(bodyBytes))
+			req = req.WithContext(ctx)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestHandlerListByOwner(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		expectedOwner string
+	}{
+		{name: "me resolves to the caller", query: "?owner=me", expectedOwner: "test-user-id"},
+		{name: "explicit user ID", query: "?owner=other-user-id", expectedOwner: "other-user-id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockService{
+				listFunc: func(ctx context.Context, tenantID string) ([]Flag, error) {
+					t.Error("expected owner filter to be used")
+					return nil, nil
+				},
+				ownerFunc: func(ctx context.Context, tenantID string, ownerUserID string) ([]Flag, error) {
+					if ownerUserID != tt.expectedOwner {
+						t.Errorf("expected owner %s, got %s", tt.expectedOwner, ownerUserID)
+					}
+					return []Flag{{ID: "1", Name: "owned-flag", OwnerUserID: &ownerUserID}}, nil
+				},
+			}
+			h := NewHandler(mockSvc)
+
+			router := setupTestRouter()
+			router.GET("/flags", h.(*handler).List)
+
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "member")
+			req := httptest.NewRequest(http.MethodGet, "/flags"+tt.query, nil)
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandlerListByProject(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockFn         func(ctx context.Context, projectID string, tenantID string) ([]Flag, error)
+		expectedStatus int
+	}{
+		{
+			name: "project in tenant",
+			mockFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+				if projectID != "project-1" {
+					t.Errorf("expected project-1, got %s", projectID)
+				}
+				return []Flag{{ID: "1", Name: "project-flag", ProjectID: &projectID}}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "project in another tenant",
+			mockFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+				return nil, pkgErrors.ErrProjectNotInTenant
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(&mockService{projectFunc: tt.mockFn})
+
+			router := setupTestRouter()
+			router.GET("/projects/:id/flags", h.(*handler).ListByProject)
+
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "member")
+			req := httptest.NewRequest(http.MethodGet, "/projects/project-1/flags", nil)
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandlerListByProject_IncludeStats(t *testing.T) {
+	listed := func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+		return []Flag{{ID: "1", Name: "project-flag", ProjectID: &projectID}}, nil
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectStats    bool
+	}{
+		{name: "stats omitted by default", query: "", expectedStatus: http.StatusOK},
+		{name: "stats included on request", query: "?include_stats=true", expectedStatus: http.StatusOK, expectStats: true},
+		{name: "invalid include_stats", query: "?include_stats=maybe", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statsLoaded := false
+			h := NewHandler(&mockService{
+				projectFunc: listed,
+				statsFunc: func(ctx context.Context, flags []Flag, tenantID string) error {
+					statsLoaded = true
+					for i := range flags {
+						flags[i].Stats = &EvaluationStats{Evaluations: 10, TruePercent: 40}
+					}
+					return nil
+				},
+			})
+
+			router := setupTestRouter()
+			router.GET("/projects/:id/flags", h.(*handler).ListByProject)
+
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "member")
+			req := httptest.NewRequest(http.MethodGet, "/projects/project-1/flags"+tt.query, nil)
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if statsLoaded != tt.expectStats {
+				t.Errorf("expected stats loaded=%t, got %t", tt.expectStats, statsLoaded)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var flags []Flag
+			if err := json.Unmarshal(w.Body.Bytes(), &flags); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got := flags[0].Stats != nil; got != tt.expectStats {
+				t.Errorf("expected stats in response=%t, got %t", tt.expectStats, got)
+			}
+		})
+	}
+}
+
+func TestHandlerListStale(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		mockFn         func(ctx context.Context, tenantID string, days int) ([]StaleFlag, error)
+		expectedStatus int
+	}{
+		{
+			name:  "defaults to 30 days",
+			query: "",
+			mockFn: func(ctx context.Context, tenantID string, days int) ([]StaleFlag, error) {
+				if days != DefaultStaleDays {
+					t.Errorf("expected %d days, got %d", DefaultStaleDays, days)
+				}
+				return []StaleFlag{{Flag: Flag{ID: "1", Name: "old-flag"}}}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "custom window",
+			query: "?days=90",
+			mockFn: func(ctx context.Context, tenantID string, days int) ([]StaleFlag, error) {
+				if days != 90 {
+					t.Errorf("expected 90 days, got %d", days)
+				}
+				return []StaleFlag{}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "non-numeric days",
+			query:          "?days=month",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "out of range days",
+			query: "?days=0",
+			mockFn: func(ctx context.Context, tenantID string, days int) ([]StaleFlag, error) {
+				return nil, ErrInvalidFlagData
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockService{
+				staleFunc: tt.mockFn,
+			}
+			h := NewHandler(mockSvc)
+
+			router := setupTestRouter()
+			h.RegisterRoutes(router.Group(""))
+
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "admin")
+			req := httptest.NewRequest(http.MethodGet, "/flags/stale"+tt.query, nil)
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestHandlerCreateOverride(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name           string
+		body           interface{}
+		mockFn         func(ctx context.Context, o *Override, tenantID string) error
+		expectedStatus int
+	}{
+		{
+			name: "creates override as the caller",
+			body: map[string]interface{}{"user_key": "user-42", "enabled": false, "expires_at": expiresAt},
+			mockFn: func(ctx context.Context, o *Override, tenantID string) error {
+				if o.FlagID != "flag-1" || o.UserKey != "user-42" || o.Enabled {
+					t.Errorf("unexpected override %+v", o)
+				}
+				if o.CreatedBy == nil || *o.CreatedBy != "test-user-id" {
+					t.Errorf("expected created_by to be the caller, got %v", o.CreatedBy)
+				}
+				return nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "enabled is required",
+			body:           map[string]interface{}{"user_key": "user-42", "expires_at": expiresAt},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "expiry rejected by service",
+			body: map[string]interface{}{"user_key": "user-42", "enabled": true, "expires_at": expiresAt},
+			mockFn: func(ctx context.Context, o *Override, tenantID string) error {
+				return ErrInvalidFlagData
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "flag not found",
+			body: map[string]interface{}{"user_key": "user-42", "enabled": true, "expires_at": expiresAt},
+			mockFn: func(ctx context.Context, o *Override, tenantID string) error {
+				return pkgErrors.ErrNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockService{overrideFn: tt.mockFn}
+			h := NewHandler(mockSvc)
+
+			router := setupTestRouter()
+			router.POST("/flags/:id/overrides", h.(*handler).CreateOverride)
+
+			body, _ := json.Marshal(tt.body)
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "admin")
+			req := httptest.NewRequest(http.MethodPost, "/flags/flag-1/overrides", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerSetCustomField(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		mockFn         func(ctx context.Context, id string, name string, value string, tenantID string) (*CustomField, error)
+		expectedStatus int
+	}{
+		{
+			name: "sets field from the path name",
+			body: map[string]interface{}{"value": "checkout-api"},
+			mockFn: func(ctx context.Context, id string, name string, value string, tenantID string) (*CustomField, error) {
+				if id != "flag-1" || name != CustomFieldService || value != "checkout-api" || tenantID != "test-tenant-id" {
+					t.Errorf("unexpected call: %s %s %s %s", id, name, value, tenantID)
+				}
+				return &CustomField{FlagID: id, Name: name, Value: value}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "value is required",
+			body:           map[string]interface{}{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid name",
+			body: map[string]interface{}{"value": "checkout-api"},
+			mockFn: func(ctx context.Context, id string, name string, value string, tenantID string) (*CustomField, error) {
+				return nil, ErrInvalidFlagData
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "flag not found",
+			body: map[string]interface{}{"value": "checkout-api"},
+			mockFn: func(ctx context.Context, id string, name string, value string, tenantID string) (*CustomField, error) {
+				return nil, pkgErrors.ErrNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockService{setFieldFunc: tt.mockFn}
+			h := NewHandler(mockSvc)
+
+			router := setupTestRouter()
+			router.PUT("/flags/:id/fields/:name", h.(*handler).SetCustomField)
+
+			body, _ := json.Marshal(tt.body)
+			ctx := setupTestContext("test-user-id", "test-tenant-id", "admin")
+			req := httptest.NewRequest(http.MethodPut, "/flags/flag-1/fields/service", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -1,24 +1,935 @@
 package flag
 
-import "time"
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jalil32/toggle/internal/pkg/slugs"
+)
 
 type Flag struct {
+	ID          string     `json:"id" db:"id"`
+	TenantID    string     `json:"tenant_id" db:"tenant_id"`
+	ProjectID   *string    `json:"project_id,omitempty" db:"project_id"`
+	OwnerUserID *string    `json:"owner_user_id" db:"owner_user_id"`
+	Key         string     `json:"key" db:"key"` // stable, URL-safe identifier derived from the name at creation
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	Enabled     bool       `json:"enabled" db:"enabled"`
+	Rules       []Rule     `json:"rules" db:"rules"`
+	RuleLogic   string     `json:"rule_logic" db:"rule_logic"`
+	Lifecycle   string     `json:"lifecycle" db:"lifecycle"`   // draft, active, deprecated or archived
+	ExpiresAt   *time.Time `json:"expires_at" db:"expires_at"` // automatically disabled at this time; nil means never
+	ArchivedAt  *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	Salt        string     `json:"salt" db:"salt"` // mixed into rollout bucketing; changed by a reshuffle
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	// Stats is only loaded for flag lists requested with ?include_stats=true
+	Stats *EvaluationStats `json:"stats,omitempty" db:"-"`
+}
+
+// StatsWindow is the period covered by EvaluationStats
+const StatsWindow = 24 * time.Hour
+
+// ResultCountRetention is how long hourly evaluation counts are kept
+const ResultCountRetention = 7 * 24 * time.Hour
+
+// ResultCount is how many times a flag was evaluated, and how many of those were true
+type ResultCount struct {
+	Evaluations int64 `json:"evaluations" db:"evaluations"`
+	True        int64 `json:"true_count" db:"true_count"`
+	// Reasons counts the evaluations by the reason for their result (disabled, rules_passed, ...)
+	Reasons map[string]int64 `json:"reasons,omitempty" db:"-"`
+}
+
+// EvaluationStats summarises a flag's SDK evaluations over the last StatsWindow
+type EvaluationStats struct {
+	Evaluations int64   `json:"evaluations_24h"`
+	TruePercent float64 `json:"true_percent"` // 0 when there were no evaluations
+	// Reasons counts the evaluations by reason; few rules_passed next to many rules_failed
+	// means the targeting rules rarely match
+	Reasons map[string]int64 `json:"reasons_24h"`
+}
+
+// NewEvaluationStats computes stats from a window's result counts
+func NewEvaluationStats(c ResultCount) EvaluationStats {
+	stats := EvaluationStats{Evaluations: c.Evaluations, Reasons: c.Reasons}
+	if stats.Reasons == nil {
+		stats.Reasons = map[string]int64{}
+	}
+	if c.Evaluations > 0 {
+		stats.TruePercent = math.Round(float64(c.True)/float64(c.Evaluations)*10000) / 100
+	}
+	return stats
+}
+
+// MetricsWindows are the rolling periods a flag's evaluation metrics are totalled over.
+// Counts are kept by the hour for ResultCountRetention, so the longest is that long.
+var MetricsWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", StatsWindow},
+	{"7d", ResultCountRetention},
+}
+
+// HourlyResultCount is a flag's evaluation counts for the hour starting at Hour
+type HourlyResultCount struct {
+	Hour time.Time `json:"hour" db:"hour"`
+	ResultCount
+}
+
+// WindowMetrics totals a flag's evaluations over one rolling window
+type WindowMetrics struct {
+	Evaluations int64   `json:"evaluations"`
+	True        int64   `json:"true"`
+	False       int64   `json:"false"`
+	TruePercent float64 `json:"true_percent"` // 0 when there were no evaluations
+}
+
+// FlagMetrics are a flag's SDK evaluation counters. Evaluations are counted in memory and
+// written periodically, so the latest few seconds may be missing.
+type FlagMetrics struct {
+	FlagID string `json:"flag_id"`
+	// Windows totals the evaluations over each of MetricsWindows, by name
+	Windows map[string]WindowMetrics `json:"windows"`
+	// Hourly lists the hours with evaluations, oldest first
+	Hourly []HourlyResultCount `json:"hourly"`
+}
+
+// NewFlagMetrics totals hourly counts into each of MetricsWindows, ending at now's hour
+func NewFlagMetrics(flagID string, hourly []HourlyResultCount, now time.Time) *FlagMetrics {
+	m := &FlagMetrics{FlagID: flagID, Windows: make(map[string]WindowMetrics, len(MetricsWindows)), Hourly: hourly}
+	if m.Hourly == nil {
+		m.Hourly = []HourlyResultCount{}
+	}
+
+	for _, w := range MetricsWindows {
+		since := now.Add(-w.Duration).Truncate(time.Hour).Add(time.Hour)
+		var total WindowMetrics
+		for _, h := range hourly {
+			if h.Hour.Before(since) {
+				continue
+			}
+			total.Evaluations += h.Evaluations
+			total.True += h.True
+		}
+		total.False = total.Evaluations - total.True
+		if total.Evaluations > 0 {
+			total.TruePercent = math.Round(float64(total.True)/float64(total.Evaluations)*10000) / 100
+		}
+		m.Windows[w.Name] = total
+	}
+	return m
+}
+
+// Lifecycle states, in their usual order
+const (
+	LifecycleDraft      = "draft"      // being set up; not served to SDKs yet
+	LifecycleActive     = "active"     // served to SDKs
+	LifecycleDeprecated = "deprecated" // on its way out; no longer served to SDKs
+	LifecycleArchived   = "archived"   // kept for history only
+)
+
+// lifecycleTransitions lists the states each state may move to.
+// Deprecation can be undone; archiving can't.
+var lifecycleTransitions = map[string][]string{
+	LifecycleDraft:      {LifecycleActive, LifecycleArchived},
+	LifecycleActive:     {LifecycleDeprecated},
+	LifecycleDeprecated: {LifecycleActive, LifecycleArchived},
+	LifecycleArchived:   {},
+}
+
+// ValidLifecycle reports whether state is a known lifecycle state
+func ValidLifecycle(state string) bool {
+	_, ok := lifecycleTransitions[state]
+	return ok
+}
+
+// CanTransition reports whether a flag may move from one lifecycle state to another.
+// Staying in the same state is always allowed.
+func CanTransition(from string, to string) bool {
+	if from == to {
+		return true
+	}
+	return slices.Contains(lifecycleTransitions[from], to)
+}
+
+// IsActive reports whether the flag is served to SDKs. Flags built without a lifecycle,
+// such as those read back from snapshots, only ever hold active flags.
+func (f *Flag) IsActive() bool {
+	return f.Lifecycle == LifecycleActive || f.Lifecycle == ""
+}
+
+// InArchiveGrace reports whether an archived flag is still served to SDKs at now,
+// which it is until grace has passed since it was archived
+func (f *Flag) InArchiveGrace(grace time.Duration, now time.Time) bool {
+	return f.Lifecycle == LifecycleArchived && f.ArchivedAt != nil && now.Before(f.ArchivedAt.Add(grace))
+}
+
+// ParseLifecycleFilter parses a comma-separated ?lifecycle= filter; empty means no filter
+func ParseLifecycleFilter(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var states []string
+	for _, state := range strings.Split(raw, ",") {
+		state = strings.TrimSpace(state)
+		if !ValidLifecycle(state) {
+			return nil, fmt.Errorf("%w: lifecycle must be draft, active, deprecated or archived", ErrInvalidFlagData)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// FilterByLifecycle keeps the flags in any of the given states; no states keeps every flag
+func FilterByLifecycle(flags []Flag, states []string) []Flag {
+	if len(states) == 0 {
+		return flags
+	}
+
+	filtered := make([]Flag, 0, len(flags))
+	for _, f := range flags {
+		if slices.Contains(states, f.Lifecycle) {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
+
+type Rule struct {
+	ID        string      `json:"id"`
+	Attribute string      `json:"attribute"` // e.g., "country", "email"
+	Operator  string      `json:"operator"`  // e.g., "equals", "matches", "in"
+	Value     interface{} `json:"value"`     // e.g., "AU" or ["AU", "US"]
+	Rollout   int         `json:"rollout"`   // 0-100 percentage of matching users this rule applies to
+	Order     int         `json:"order"`     // priority under FIRST_MATCH; lower runs first, ties keep list order
+	// CaseSensitive set to false makes equals, in and matches (and their negations) ignore
+	// case; nil means true
+	CaseSensitive *bool `json:"case_sensitive,omitempty"`
+	// ContextKind is the kind of context whose attributes the rule matches; empty targets the user
+	ContextKind string `json:"context_kind,omitempty"`
+}
+
+// Context kinds a rule can target. An evaluation context is always about a user, and may
+// also describe the organization and device the user is acting through, each with its own
+// key and attributes.
+const (
+	ContextKindUser         = "user"
+	ContextKindOrganization = "organization"
+	ContextKindDevice       = "device"
+)
+
+// ContextKinds lists every context kind rules can target
+var ContextKinds = []string{ContextKindUser, ContextKindOrganization, ContextKindDevice}
+
+// Kind returns the context kind the rule targets, ContextKindUser when unset
+func (r Rule) Kind() string {
+	if r.ContextKind == "" {
+		return ContextKindUser
+	}
+	return r.ContextKind
+}
+
+// QualifiedAttribute names the rule's attribute together with its context kind, as
+// "organization.plan"; user attributes keep their plain name
+func (r Rule) QualifiedAttribute() string {
+	return QualifiedAttribute(r.Kind(), r.Attribute)
+}
+
+// QualifiedAttribute names an attribute of a context kind; see Rule.QualifiedAttribute
+func QualifiedAttribute(kind, attribute string) string {
+	if kind == ContextKindUser {
+		return attribute
+	}
+	return kind + "." + attribute
+}
+
+// AssignRuleIDs gives every rule without an ID a new UUID. Rules that have one keep it,
+// so clients that send rules back with their IDs keep them stable across updates.
+func AssignRuleIDs(rules []Rule) {
+	for i := range rules {
+		if rules[i].ID == "" {
+			rules[i].ID = uuid.NewString()
+		}
+	}
+}
+
+// KeepRuleIDs gives rules sent without an ID the ID of the current rule they replace, so
+// clients that send rules back without their IDs still keep them stable across updates.
+// A rule takes the ID of an identical current rule first, then of the current rule at its
+// position; IDs the rules already carry are never given to another rule. Rules left without
+// an ID get a new one from AssignRuleIDs.
+func KeepRuleIDs(rules, current []Rule) {
+	taken := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule.ID != "" {
+			taken[rule.ID] = true
+		}
+	}
+	keep := func(i int, c Rule) {
+		rules[i].ID = c.ID
+		taken[c.ID] = true
+	}
+
+	for i := range rules {
+		if rules[i].ID != "" {
+			continue
+		}
+		for _, c := range current {
+			if c.ID != "" && !taken[c.ID] && sameRule(rules[i], c) {
+				keep(i, c)
+				break
+			}
+		}
+	}
+	for i := range rules {
+		if rules[i].ID == "" && i < len(current) && current[i].ID != "" && !taken[current[i].ID] {
+			keep(i, current[i])
+		}
+	}
+}
+
+// sameRule reports whether two rules match the same way, whatever their IDs
+func sameRule(a, b Rule) bool {
+	a.ID, b.ID = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// validRuleID reports whether id is a UUID in its canonical lowercase form
+func validRuleID(id string) bool {
+	parsed, err := uuid.Parse(id)
+	return err == nil && parsed.String() == id
+}
+
+// IsCaseSensitive reports whether the rule compares strings case-sensitively (the default)
+func (r Rule) IsCaseSensitive() bool {
+	return r.CaseSensitive == nil || *r.CaseSensitive
+}
+
+// Rule logic modes
+const (
+	RuleLogicAnd = "AND" // every rule must pass
+	RuleLogicOr  = "OR"  // any rule may pass
+	// RuleLogicFirstMatch evaluates rules by Order; the first rule whose condition
+	// matches decides the result through its own rollout, and later rules are ignored
+	RuleLogicFirstMatch = "FIRST_MATCH"
+)
+
+// ValidRuleLogic reports whether logic is a known rule logic mode
+func ValidRuleLogic(logic string) bool {
+	return logic == RuleLogicAnd || logic == RuleLogicOr || logic == RuleLogicFirstMatch
+}
+
+// OrderedRules returns the rules sorted by Order, keeping list order for ties
+func OrderedRules(rules []Rule) []Rule {
+	ordered := slices.Clone(rules)
+	slices.SortStableFunc(ordered, func(a, b Rule) int {
+		return cmp.Compare(a.Order, b.Order)
+	})
+	return ordered
+}
+
+// KeyFromName derives a flag key from its name; it may be empty for names without letters or digits
+func KeyFromName(name string) string {
+	return slugs.Generate(name)
+}
+
+// legacyKey is the key a flag had before keys were stored: derived from the name, or the ID
+func legacyKey(f *Flag) string {
+	if key := KeyFromName(f.Name); key != "" {
+		return key
+	}
+	return f.ID
+}
+
+// AttributeUsage describes one context attribute in a project's attribute report
+type AttributeUsage struct {
+	Attribute  string     `json:"attribute"`
+	FlagIDs    []string   `json:"flag_ids"`     // flags with rules on the attribute
+	LastSeenAt *time.Time `json:"last_seen_at"` // last time an SDK sent the attribute
+}
+
+// AttributeReport compares attributes referenced by rules with attributes SDKs actually send
+type AttributeReport struct {
+	ProjectID  string `json:"project_id"`
+	WindowDays int    `json:"window_days"`
+	// Unsent attributes are referenced by rules but weren't sent within the window,
+	// so those rules can never match (often a renamed client attribute)
+	Unsent []AttributeUsage `json:"unsent"`
+	// Unreferenced attributes are sent but no rule uses them
+	Unreferenced []AttributeUsage `json:"unreferenced"`
+	// InUse attributes are both referenced and sent
+	InUse []AttributeUsage `json:"in_use"`
+}
+
+// RenameAttributeRequest renames a context attribute in the rules of every flag in a project
+type RenameAttributeRequest struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+	// DryRun reports the rules that would change without changing them
+	DryRun bool `json:"dry_run"`
+}
+
+// AttributeRename is what an attribute rename changed, or would change on a dry run
+type AttributeRename struct {
+	ProjectID string `json:"project_id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	DryRun    bool   `json:"dry_run"`
+	// Changes lists each rule set with rules on the attribute
+	Changes      []AttributeRenameChange `json:"changes"`
+	RulesChanged int                     `json:"rules_changed"`
+}
+
+// AttributeRenameChange is one flag's rules, or its rules in one environment, that reference
+// the renamed attribute
+type AttributeRenameChange struct {
+	FlagID   string `json:"flag_id"`
+	FlagName string `json:"flag_name"`
+	// EnvironmentID is set for the flag's rules in that environment
+	EnvironmentID string   `json:"environment_id,omitempty"`
+	RuleIDs       []string `json:"rule_ids"`
+}
+
+// Types an attribute can be registered with
+const (
+	AttributeTypeString = "string"
+	AttributeTypeNumber = "number"
+	AttributeTypeBool   = "bool"
+	AttributeTypeDate   = "date" // a string in one of DateLayouts
+)
+
+// AttributeTypes lists every type an attribute can be registered with
+var AttributeTypes = []string{AttributeTypeString, AttributeTypeNumber, AttributeTypeBool, AttributeTypeDate}
+
+// RegisteredAttribute is a context attribute declared in a project's attribute registry.
+// Rules on a registered attribute must compare it with values of its type; rules on
+// attributes missing from the registry aren't checked.
+type RegisteredAttribute struct {
 	ID          string    `json:"id" db:"id"`
-	TenantID    string    `json:"tenant_id" db:"tenant_id"`
-	ProjectID   *string   `json:"project_id,omitempty" db:"project_id"`
+	ProjectID   string    `json:"project_id" db:"project_id"`
 	Name        string    `json:"name" db:"name"`
+	ContextKind string    `json:"context_kind" db:"context_kind"` // one of ContextKinds
+	Type        string    `json:"type" db:"type"`                 // one of AttributeTypes
 	Description string    `json:"description" db:"description"`
-	Enabled     bool      `json:"enabled" db:"enabled"`
-	Rules       []Rule    `json:"rules" db:"rules"`
-	RuleLogic   string    `json:"rule_logic" db:"rule_logic"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
-type Rule struct {
-	ID        string      `json:"id"`
-	Attribute string      `json:"attribute"` // e.g., "country", "email"
-	Operator  string      `json:"operator"`  // e.g., "equals", "contains", "in"
-	Value     interface{} `json:"value"`     // e.g., "AU" or ["AU", "US"]
-	Rollout   int         `json:"rollout"`   // 0-100 percentage
+// RegisterAttributeRequest adds an attribute to a project's registry, or changes the type
+// and description of one already registered under the same name and context kind
+type RegisterAttributeRequest struct {
+	Name string `json:"name" binding:"required"`
+	// ContextKind defaults to user
+	ContextKind string `json:"context_kind"`
+	Type        string `json:"type" binding:"required"`
+	Description string `json:"description"`
+}
+
+// MaxAttributeNameLength bounds registered attribute names
+const MaxAttributeNameLength = 128
+
+// CheckRegisteredTypes checks the rules on registered attributes compare them with values of
+// the attribute's type, using operators that apply to it. Returns a *RuleValidationError
+// listing all problems, or nil.
+func CheckRegisteredTypes(rules []Rule, registry []RegisteredAttribute) error {
+	if len(registry) == 0 {
+		return nil
+	}
+	types := make(map[string]string, len(registry))
+	for _, a := range registry {
+		types[QualifiedAttribute(a.ContextKind, a.Name)] = a.Type
+	}
+
+	var errs []RuleError
+	for i, rule := range rules {
+		if rule.Operator == OperatorTimeWindow {
+			continue
+		}
+		attrType, ok := types[rule.QualifiedAttribute()]
+		if !ok {
+			continue
+		}
+		if message := checkAttributeType(rule, attrType); message != "" {
+			errs = append(errs, RuleError{Index: i, RuleID: rule.ID, Field: "value", Message: message})
+		}
+	}
+	if len(errs) > 0 {
+		return &RuleValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// checkAttributeType returns why a rule can't apply to an attribute of attrType, or ""
+func checkAttributeType(rule Rule, attrType string) string {
+	var ofType func(v interface{}) bool
+	switch attrType {
+	case AttributeTypeString:
+		if rule.Operator == OperatorGreaterThan || rule.Operator == OperatorLessThan {
+			return fmt.Sprintf("%s is a string attribute, so it can't be compared with %s", rule.Attribute, rule.Operator)
+		}
+		ofType = func(v interface{}) bool { _, ok := v.(string); return ok }
+	case AttributeTypeNumber:
+		ofType = isNumber
+	case AttributeTypeBool:
+		if rule.Operator == OperatorGreaterThan || rule.Operator == OperatorLessThan {
+			return fmt.Sprintf("%s is a bool attribute, so it can't be compared with %s", rule.Attribute, rule.Operator)
+		}
+		ofType = func(v interface{}) bool { _, ok := v.(bool); return ok }
+	case AttributeTypeDate:
+		ofType = func(v interface{}) bool { _, ok := ParseDate(v); return ok }
+	default:
+		return ""
+	}
+
+	switch rule.Operator {
+	case OperatorMatches:
+		if attrType != AttributeTypeString {
+			return fmt.Sprintf("%s is a %s attribute, so it can't be matched against a pattern", rule.Attribute, attrType)
+		}
+		return ""
+	case OperatorIn, OperatorNotIn:
+		values, _ := rule.Value.([]interface{})
+		for _, v := range values {
+			if !ofType(v) {
+				return fmt.Sprintf("%s is a %s attribute, so %s values must be %ss", rule.Attribute, attrType, rule.Operator, attrType)
+			}
+		}
+		return ""
+	}
+	if !ofType(rule.Value) {
+		return fmt.Sprintf("%s is a %s attribute, so the value must be a %s", rule.Attribute, attrType, attrType)
+	}
+	return ""
+}
+
+// renameAttribute returns a copy of rules with every rule on from moved to to, and the IDs
+// of the rules it changed
+func renameAttribute(rules []Rule, from, to string) ([]Rule, []string) {
+	var renamed []Rule
+	var ids []string
+	for i, rule := range rules {
+		if rule.Attribute != from {
+			continue
+		}
+		if renamed == nil {
+			renamed = slices.Clone(rules)
+		}
+		renamed[i].Attribute = to
+		ids = append(ids, rule.ID)
+	}
+	if renamed == nil {
+		return rules, nil
+	}
+	return renamed, ids
+}
+
+// DateLayouts are the formats date attributes and rule values can take: an RFC 3339
+// timestamp, or a calendar date (midnight UTC)
+var DateLayouts = []string{time.RFC3339, time.DateOnly}
+
+// ParseDate parses a date attribute or rule value in one of DateLayouts
+func ParseDate(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range DateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Rule operators understood by the evaluator
+const (
+	OperatorEquals      = "equals"
+	OperatorNotEquals   = "not_equals"
+	OperatorIn          = "in"
+	OperatorNotIn       = "not_in"
+	OperatorGreaterThan = "greater_than"
+	OperatorLessThan    = "less_than"
+	OperatorMatches     = "matches"
+	OperatorTimeWindow  = "time_window"
+)
+
+// Operators lists every rule operator the evaluator understands
+var Operators = []string{
+	OperatorEquals,
+	OperatorNotEquals,
+	OperatorIn,
+	OperatorNotIn,
+	OperatorGreaterThan,
+	OperatorLessThan,
+	OperatorMatches,
+	OperatorTimeWindow,
+}
+
+// TimeWindowLayout is the format of a time_window rule's start_at and end_at: a wall clock
+// time, read in the window's timezone
+const TimeWindowLayout = "2006-01-02T15:04:05"
+
+// TimeWindow is a time_window rule's value. The rule matches from StartAt until EndAt, read
+// in Timezone (an IANA name such as "Australia/Sydney"; UTC when empty), so a campaign runs
+// on the audience's calendar. Time window rules have no attribute: they match on the time
+// of the evaluation.
+type TimeWindow struct {
+	StartAt  string `json:"start_at"`
+	EndAt    string `json:"end_at"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// locations caches time zones by name, since time.LoadLocation reads the zone database on each call
+var locations sync.Map
+
+// ParseTimeWindow reads a time_window rule value and returns when the window starts and ends
+func ParseTimeWindow(value interface{}) (start, end time.Time, err error) {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return start, end, errors.New("time_window requires an object with start_at, end_at and timezone")
+	}
+	text := func(name string) string {
+		s, _ := fields[name].(string)
+		return s
+	}
+
+	name := text("timezone")
+	if name == "" {
+		name = "UTC"
+	}
+	loc, err := loadLocation(name)
+	if err != nil {
+		return start, end, fmt.Errorf("unknown timezone %q", name)
+	}
+
+	if start, err = time.ParseInLocation(TimeWindowLayout, text("start_at"), loc); err != nil {
+		return start, end, fmt.Errorf("start_at must be a time like %s", TimeWindowLayout)
+	}
+	if end, err = time.ParseInLocation(TimeWindowLayout, text("end_at"), loc); err != nil {
+		return start, end, fmt.Errorf("end_at must be a time like %s", TimeWindowLayout)
+	}
+	if !end.After(start) {
+		return start, end, errors.New("end_at must be after start_at")
+	}
+	return start, end, nil
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// Limits on a matches pattern. Go's regexp runs in linear time, but relays and SDKs
+// may evaluate snapshots with backtracking engines, so patterns are kept small and
+// nested repetition, the shape behind catastrophic backtracking, is rejected.
+const (
+	MaxPatternLength = 256
+	maxPatternNodes  = 100
+)
+
+// CompilePattern compiles a matches rule value, enforcing the pattern limits
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > MaxPatternLength {
+		return nil, fmt.Errorf("pattern must be at most %d characters", MaxPatternLength)
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if _, nested := patternComplexity(re, false); nested {
+		return nil, errors.New("pattern must not nest repetitions")
+	}
+	// Counted after simplifying, which expands counted repetitions like x{50}
+	if nodes, _ := patternComplexity(re.Simplify(), false); nodes > maxPatternNodes {
+		return nil, fmt.Errorf("pattern is too complex (%d nodes, max %d)", nodes, maxPatternNodes)
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// patternComplexity counts a parsed pattern's nodes and reports whether a repetition
+// appears inside another
+func patternComplexity(re *syntax.Regexp, inRepeat bool) (int, bool) {
+	repeat := re.Op == syntax.OpStar || re.Op == syntax.OpPlus || re.Op == syntax.OpRepeat
+	if repeat && inRepeat {
+		return 0, true
+	}
+
+	nodes := 1
+	for _, sub := range re.Sub {
+		n, nested := patternComplexity(sub, inRepeat || repeat)
+		if nested {
+			return 0, true
+		}
+		nodes += n
+	}
+	return nodes, false
+}
+
+// RuleError describes one problem with one rule of a flag
+type RuleError struct {
+	Index   int    `json:"index"`
+	RuleID  string `json:"rule_id,omitempty"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// RuleValidationError lists every problem found in a flag's rules.
+// It wraps ErrInvalidFlagData so callers that only check for invalid data still match.
+type RuleValidationError struct {
+	Errors []RuleError `json:"rule_errors"`
+}
+
+func (e *RuleValidationError) Error() string {
+	return fmt.Sprintf("%s: %d invalid rule field(s)", ErrInvalidFlagData, len(e.Errors))
+}
+
+func (e *RuleValidationError) Unwrap() error {
+	return ErrInvalidFlagData
+}
+
+// DuplicateNameError reports a flag name already used in the project.
+// It wraps ErrDuplicateName so callers can match it with errors.Is.
+type DuplicateNameError struct {
+	ProjectID string `json:"project_id"`
+	Name      string `json:"name"`
+}
+
+func (e *DuplicateNameError) Error() string {
+	return fmt.Sprintf("%s: %q", ErrDuplicateName, e.Name)
+}
+
+func (e *DuplicateNameError) Unwrap() error {
+	return ErrDuplicateName
+}
+
+// ExportVersion is the format version written to flag exports
+const ExportVersion = 1
+
+// ExportDocument is a project's flags in a portable form; it can be posted back to import.
+// An encrypted export carries the document in Sealed instead of Flags.
+type ExportDocument struct {
+	Version    int            `json:"version"`
+	ProjectID  string         `json:"project_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Flags      []ExportedFlag `json:"flags,omitempty"`
+	Sealed     string         `json:"sealed,omitempty"` // the whole document sealed with the tenant data key
+}
+
+// ExportedFlag is a flag without tenant-specific fields (IDs, owner, timestamps).
+// Flags are matched by key on import.
+type ExportedFlag struct {
+	Key         string     `json:"key"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Rules       []Rule     `json:"rules"`
+	RuleLogic   string     `json:"rule_logic"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// Import conflict strategies, used when an imported key already exists in the project
+const (
+	ImportConflictSkip      = "skip"
+	ImportConflictOverwrite = "overwrite"
+)
+
+// ImportOptions control how an export document is imported
+type ImportOptions struct {
+	OnConflict string // skip (default) or overwrite
+	DryRun     bool   // validate and report without writing
+	// Environment is the environment of an Unleash 4 export whose strategies are imported;
+	// it may be empty when the export has only one
+	Environment string
+}
+
+// ImportError is a problem with one flag of an import; nothing is written while any exist
+type ImportError struct {
+	Index      int         `json:"index"`
+	Key        string      `json:"key"`
+	Error      string      `json:"error"`
+	RuleErrors []RuleError `json:"rule_errors,omitempty"`
+}
+
+// ImportResult lists the keys an import created, updated and skipped
+type ImportResult struct {
+	DryRun  bool          `json:"dry_run"`
+	Created []string      `json:"created"`
+	Updated []string      `json:"updated"`
+	Skipped []string      `json:"skipped"`
+	Errors  []ImportError `json:"errors"`
+}
+
+// Apply outcomes: what PUT /projects/:id/flags/key/:key had to do to bring a flag to its definition
+const (
+	ApplyCreated   = "created"
+	ApplyUpdated   = "updated"
+	ApplyUnchanged = "unchanged"
+)
+
+// ApplyResult is a flag after Apply, with what Apply did to it
+type ApplyResult struct {
+	Result string `json:"result"`
+	Flag   *Flag  `json:"flag"`
+}
+
+// StaleFlag is a flag that hasn't been evaluated or modified recently
+type StaleFlag struct {
+	Flag
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at" db:"last_evaluated_at"`
+}
+
+// Exclusion is a user key that never receives a flag, even when bucketed in by rollout
+type Exclusion struct {
+	FlagID    string    `json:"flag_id" db:"flag_id"`
+	UserKey   string    `json:"user_key" db:"user_key"`
+	Reason    string    `json:"reason" db:"reason"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CustomFieldService names the service a flag gates; service catalogs use it to place the flag
+const CustomFieldService = "service"
+
+// CustomField is free-form metadata on a flag; custom fields don't affect evaluation
+type CustomField struct {
+	FlagID    string    `json:"flag_id" db:"flag_id"`
+	Name      string    `json:"name" db:"name"`
+	Value     string    `json:"value" db:"value"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// EnvironmentConfig is a flag's enabled state and rules in one project environment
+type EnvironmentConfig struct {
+	FlagID        string    `json:"flag_id"`
+	EnvironmentID string    `json:"environment_id"`
+	Enabled       bool      `json:"enabled"`
+	Rules         []Rule    `json:"rules"`
+	RuleLogic     string    `json:"rule_logic"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// orDisabled returns the config, or for a flag never configured in the environment
+// (nil) a disabled config with no rules
+func (c *EnvironmentConfig) orDisabled() EnvironmentConfig {
+	if c == nil {
+		return EnvironmentConfig{Enabled: false, Rules: []Rule{}, RuleLogic: RuleLogicAnd}
+	}
+	return *c
+}
+
+// InEnvironment returns a copy of the flag with an environment's config applied.
+// A nil config means the flag was never configured there, so it is disabled. The flag's
+// own enabled state is a kill switch: a flag toggled off or expired is disabled in every
+// environment, whatever its environment configs say.
+func (f Flag) InEnvironment(config *EnvironmentConfig) Flag {
+	c := config.orDisabled()
+	f.Enabled = f.Enabled && c.Enabled
+	f.Rules = c.Rules
+	f.RuleLogic = c.RuleLogic
+	return f
+}
+
+// Fields compared by an environment diff
+const (
+	DiffFieldEnabled   = "enabled"
+	DiffFieldRules     = "rules"
+	DiffFieldRuleLogic = "rule_logic"
+)
+
+// EnvironmentDiff compares a flag's config in two environments of its project.
+// A nil config means the flag was never configured in that environment.
+type EnvironmentDiff struct {
+	FlagID string             `json:"flag_id"`
+	From   string             `json:"from"`
+	To     string             `json:"to"`
+	Source *EnvironmentConfig `json:"source"`
+	Target *EnvironmentConfig `json:"target"`
+	// Differences lists the fields that differ; empty means promoting would change nothing
+	Differences []string `json:"differences"`
+}
+
+// DiffEnvironmentConfigs lists the fields that differ between two environment configs,
+// treating a missing config as disabled with no rules
+func DiffEnvironmentConfigs(from *EnvironmentConfig, to *EnvironmentConfig) []string {
+	a := from.orDisabled()
+	b := to.orDisabled()
+
+	differences := []string{}
+	if a.Enabled != b.Enabled {
+		differences = append(differences, DiffFieldEnabled)
+	}
+	if !rulesEqual(a.Rules, b.Rules) {
+		differences = append(differences, DiffFieldRules)
+	}
+	if a.RuleLogic != b.RuleLogic {
+		differences = append(differences, DiffFieldRuleLogic)
+	}
+	return differences
+}
+
+// rulesEqual compares rules by their JSON form, since rule values are decoded JSON
+func rulesEqual(a []Rule, b []Rule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// Override temporarily forces a flag on or off for one user key, ahead of rules
+type Override struct {
+	FlagID    string    `json:"flag_id" db:"flag_id"`
+	UserKey   string    `json:"user_key" db:"user_key"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	Reason    string    `json:"reason" db:"reason"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedBy *string   `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// History actions
+const (
+	HistoryActionExpired    = "expired"
+	HistoryActionPromoted   = "promoted"
+	HistoryActionToggled    = "toggled"
+	HistoryActionReshuffled = "reshuffled"
+	// HistoryActionAttributeRenamed records a project-wide rename of an attribute the flag's rules use
+	HistoryActionAttributeRenamed = "attribute_renamed"
+)
+
+// HistoryEntry records an action taken on a flag; ActorID is nil for system actions
+type HistoryEntry struct {
+	ID        string                 `json:"id" db:"id"`
+	FlagID    string                 `json:"flag_id" db:"flag_id"`
+	Action    string                 `json:"action" db:"action"`
+	ActorID   *string                `json:"actor_id" db:"actor_id"`
+	Details   map[string]interface{} `json:"details" db:"details"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,66 @@
+package flag
+
+import (
+	"net/http"
+
+	"github.com/jalil32/toggle/internal/pkg/openapi"
+)
+
+var (
+	lifecycleParam    = openapi.Param{Name: "lifecycle", Description: "Comma-separated lifecycles to include; all when omitted"}
+	includeStatsParam = openapi.Param{Name: "include_stats", Description: "true to include each flag's evaluation stats"}
+	importParams      = []openapi.Param{
+		{Name: "on_conflict", Description: "skip or overwrite flags whose key already exists"},
+		{Name: "dry_run", Description: "true to only validate the document"},
+	}
+)
+
+// Operations documents the routes RegisterRoutes serves, for the OpenAPI document
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: http.MethodPost, Path: "/flags", Summary: "Create a flag", Request: CreateRequest{}, Response: Flag{}, Status: http.StatusCreated,
+			Query: []openapi.Param{{Name: "template_id", Description: "Template to fill in the flag's unset fields from"}}},
+		{Method: http.MethodGet, Path: "/flags", Summary: "List the tenant's flags", Response: []Flag{},
+			Query: []openapi.Param{lifecycleParam, includeStatsParam, {Name: "owner", Description: "Owner user ID, or me"}}},
+		{Method: http.MethodGet, Path: "/flags/stale", Summary: "List flags not evaluated or modified recently", Response: []StaleFlag{},
+			Query: []openapi.Param{{Name: "days", Description: "Days without activity; defaults to 30"}}},
+		{Method: http.MethodGet, Path: "/projects/:id/flags", Summary: "List a project's flags", Response: []Flag{},
+			Query: []openapi.Param{lifecycleParam, includeStatsParam}},
+		{Method: http.MethodGet, Path: "/projects/:id/attributes", Summary: "List a project's registered attributes", Response: []RegisteredAttribute{}},
+		{Method: http.MethodPost, Path: "/projects/:id/attributes", Summary: "Register an attribute", Request: RegisterAttributeRequest{}, Response: RegisteredAttribute{}},
+		{Method: http.MethodDelete, Path: "/projects/:id/attributes/:attributeID", Summary: "Remove a registered attribute", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/projects/:id/attributes/report", Summary: "Compare the attributes rules use with those SDKs send", Response: AttributeReport{},
+			Query: []openapi.Param{{Name: "days", Description: "Days of SDK traffic to compare; defaults to 7"}}},
+		{Method: http.MethodPost, Path: "/projects/:id/attributes/rename", Summary: "Rename an attribute in every rule of a project", Request: RenameAttributeRequest{}, Response: AttributeRename{}},
+		{Method: http.MethodGet, Path: "/projects/:id/flags/export", Summary: "Export a project's flags", Response: ExportDocument{},
+			Query: []openapi.Param{{Name: "encrypt", Description: "true to seal the document with the tenant's key"}}},
+		{Method: http.MethodPost, Path: "/projects/:id/flags/import", Summary: "Import flags from an export", Request: ExportDocument{}, Response: ImportResult{}, Query: importParams},
+		{Method: http.MethodPut, Path: "/projects/:id/flags/key/:key", Summary: "Create or update a flag by key from its definition", Request: ExportedFlag{}, Response: ApplyResult{}},
+		{Method: http.MethodGet, Path: "/projects/:id/flags/export/unleash", Summary: "Export a project's flags as an Unleash state export", Response: UnleashExport{}},
+		{Method: http.MethodPost, Path: "/projects/:id/flags/import/unleash", Summary: "Import flags from an Unleash state export", Request: UnleashExport{}, Response: ImportResult{},
+			Query: append([]openapi.Param{{Name: "environment", Description: "Environment to import from an Unleash 4 export"}}, importParams...)},
+		{Method: http.MethodGet, Path: "/flags/:id", Summary: "Get a flag", Response: Flag{}},
+		{Method: http.MethodPut, Path: "/flags/:id", Summary: "Update a flag", Request: UpdateRequest{}, Response: Flag{}},
+		{Method: http.MethodPatch, Path: "/flags/:id", Summary: "Update the fields named in update_mask", Request: PatchRequest{}, Response: Flag{}},
+		{Method: http.MethodPatch, Path: "/flags/:id/toggle", Summary: "Flip a flag's enabled state", Response: Flag{}},
+		{Method: http.MethodPost, Path: "/flags/:id/reshuffle", Summary: "Re-randomize which users fall inside a flag's rollouts", Response: Flag{}},
+		{Method: http.MethodDelete, Path: "/flags/:id", Summary: "Delete a flag", Status: http.StatusNoContent},
+		{Method: http.MethodPost, Path: "/flags/:id/clone", Summary: "Clone a flag", Request: CloneRequest{}, Response: Flag{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/flags/:id/exclusions", Summary: "List a flag's excluded users", Response: []Exclusion{}},
+		{Method: http.MethodPost, Path: "/flags/:id/exclusions", Summary: "Exclude users from a flag", Request: AddExclusionsRequest{}, Response: []Exclusion{}},
+		{Method: http.MethodDelete, Path: "/flags/:id/exclusions/:user_key", Summary: "Remove a user's exclusion", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/flags/:id/fields", Summary: "List a flag's custom fields", Response: []CustomField{}},
+		{Method: http.MethodPut, Path: "/flags/:id/fields/:name", Summary: "Set a custom field", Request: SetCustomFieldRequest{}, Response: CustomField{}},
+		{Method: http.MethodDelete, Path: "/flags/:id/fields/:name", Summary: "Delete a custom field", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/flags/:id/overrides", Summary: "List a flag's user overrides", Response: []Override{}},
+		{Method: http.MethodPost, Path: "/flags/:id/overrides", Summary: "Force a flag on or off for one user", Request: CreateOverrideRequest{}, Response: Override{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/flags/:id/overrides/:user_key", Summary: "Delete a user override", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/flags/:id/history", Summary: "List a flag's history, newest first", Response: []HistoryEntry{}},
+		{Method: http.MethodGet, Path: "/flags/:id/metrics", Summary: "Get a flag's evaluation counters", Response: FlagMetrics{}},
+		{Method: http.MethodGet, Path: "/flags/:id/environments", Summary: "List a flag's per-environment configs", Response: []EnvironmentConfig{}},
+		{Method: http.MethodPut, Path: "/flags/:id/environments/:envID", Summary: "Set a flag's config in one environment", Request: SetEnvironmentConfigRequest{}, Response: EnvironmentConfig{}},
+		{Method: http.MethodGet, Path: "/flags/:id/diff", Summary: "Compare a flag across two environments", Response: EnvironmentDiff{},
+			Query: []openapi.Param{{Name: "from", Required: true}, {Name: "to", Required: true}}},
+		{Method: http.MethodPost, Path: "/flags/:id/promote", Summary: "Copy a flag's config from one environment to another", Request: PromoteRequest{}, Response: EnvironmentDiff{}},
+	}
+}
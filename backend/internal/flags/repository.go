@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"slices"
 	"time"
 
+	"github.com/jalil32/toggle/internal/pkg/dualwrite"
 	"github.com/jalil32/toggle/internal/pkg/transaction"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type Repository interface {
@@ -15,16 +18,87 @@ type Repository interface {
 	GetByID(ctx context.Context, id string, tenantID string) (*Flag, error)
 	List(ctx context.Context, tenantID string) ([]Flag, error)
 	ListByProject(ctx context.Context, projectID string, tenantID string) ([]Flag, error)
+	ListByOwner(ctx context.Context, tenantID string, ownerUserID string) ([]Flag, error)
 	Update(ctx context.Context, f *Flag, tenantID string) error
+	Patch(ctx context.Context, id string, tenantID string, f *Flag, mask []string) (*Flag, error)
+	Toggle(ctx context.Context, id string, tenantID string) (*Flag, error)
+	SetSalt(ctx context.Context, id string, tenantID string, salt string) error
 	Delete(ctx context.Context, id string, tenantID string) error
+	RecordEvaluations(ctx context.Context, flagIDs []string, at time.Time) error
+	RecordResults(ctx context.Context, counts map[string]ResultCount, hour time.Time) error
+	SumResults(ctx context.Context, tenantID string, since time.Time) (map[string]ResultCount, error)
+	ListResultCounts(ctx context.Context, flagID string, tenantID string, since time.Time) ([]HourlyResultCount, error)
+	DeleteResultCounts(ctx context.Context, before time.Time) (int64, error)
+	ListStale(ctx context.Context, tenantID string, cutoff time.Time) ([]StaleFlag, error)
+	RecordAttributes(ctx context.Context, projectID string, attributes []string, at time.Time) error
+	ListSeenAttributes(ctx context.Context, projectID string, tenantID string) (map[string]time.Time, error)
+	ListRegisteredAttributes(ctx context.Context, projectID string, tenantID string) ([]RegisteredAttribute, error)
+	UpsertRegisteredAttribute(ctx context.Context, a *RegisteredAttribute, tenantID string) error
+	DeleteRegisteredAttribute(ctx context.Context, id string, projectID string, tenantID string) error
+	ListExclusions(ctx context.Context, flagID string, tenantID string) ([]Exclusion, error)
+	AddExclusions(ctx context.Context, flagID string, tenantID string, userKeys []string, reason string) error
+	RemoveExclusion(ctx context.Context, flagID string, tenantID string, userKey string) error
+	ListExcludedFlagIDs(ctx context.Context, tenantID string, userKey string) ([]string, error)
+	ListExclusionsByProject(ctx context.Context, projectID string, tenantID string) (map[string][]string, error)
+	UpsertOverride(ctx context.Context, o *Override, tenantID string) error
+	ListOverrides(ctx context.Context, flagID string, tenantID string, now time.Time) ([]Override, error)
+	DeleteOverride(ctx context.Context, flagID string, tenantID string, userKey string) error
+	ListActiveOverrides(ctx context.Context, tenantID string, userKey string, now time.Time) (map[string]bool, error)
+	ListOverridesByProject(ctx context.Context, projectID string, tenantID string, now time.Time) (map[string][]Override, error)
+	DeleteExpiredOverrides(ctx context.Context, now time.Time) (int64, error)
+	DisableExpired(ctx context.Context, now time.Time) (int64, error)
+	BackfillKeys(ctx context.Context, limit int) (int64, error)
+	CountMissingKeys(ctx context.Context) (int64, error)
+	ListHistory(ctx context.Context, flagID string, tenantID string) ([]HistoryEntry, error)
+	ListCustomFields(ctx context.Context, flagID string, tenantID string) ([]CustomField, error)
+	SetCustomField(ctx context.Context, flagID string, tenantID string, name string, value string) (*CustomField, error)
+	DeleteCustomField(ctx context.Context, flagID string, tenantID string, name string) error
+	ListEnvironmentConfigs(ctx context.Context, flagID string, tenantID string) ([]EnvironmentConfig, error)
+	GetEnvironmentConfig(ctx context.Context, flagID string, environmentID string, tenantID string) (*EnvironmentConfig, error)
+	SetEnvironmentConfig(ctx context.Context, c *EnvironmentConfig, tenantID string) error
+	ListEnvironmentConfigsByProject(ctx context.Context, projectID string, tenantID string) (map[string][]EnvironmentConfig, error)
+	GetEnvironmentConfigByKey(ctx context.Context, flagID string, environmentKey string, tenantID string) (string, *EnvironmentConfig, error)
+	ResetEnvironmentConfigs(ctx context.Context, flagID string, tenantID string) error
+	RecordHistory(ctx context.Context, e *HistoryEntry, tenantID string) error
 }
 
 type postgresRepository struct {
-	db *sqlx.DB
+	db           *sqlx.DB
+	keyMigration *dualwrite.Migration
 }
 
-func NewRepository(db *sqlx.DB) Repository {
-	return &postgresRepository{db: db}
+// RepositoryOption configures optional repository behaviour
+type RepositoryOption func(*postgresRepository)
+
+// WithKeyMigration makes reads and writes of flag keys follow the migration's phase.
+// Without it the repository stays in dualwrite.PhaseOff and derives keys from names.
+func WithKeyMigration(m *dualwrite.Migration) RepositoryOption {
+	return func(r *postgresRepository) {
+		r.keyMigration = m
+	}
+}
+
+func NewRepository(db *sqlx.DB, opts ...RepositoryOption) Repository {
+	r := &postgresRepository{db: db}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// resolveKey sets a scanned flag's key from the stored column or the legacy name-derived
+// value, depending on the key migration phase
+func (r *postgresRepository) resolveKey(f *Flag, storedKey *string) {
+	phase := r.keyMigration.Phase()
+
+	if phase.ReadsNew() && storedKey != nil {
+		f.Key = *storedKey
+		return
+	}
+
+	if phase.FallsBack() {
+		f.Key = legacyKey(f)
+	}
 }
 
 // getDB returns the transaction from context if present, otherwise returns the DB
@@ -41,33 +115,49 @@ func (r *postgresRepository) Create(ctx context.Context, f *Flag) error {
 		return err
 	}
 
+	// Keys are written once at creation, from the name unless one is given (e.g. by an import);
+	// flags created without one are filled in by BackfillKeys
+	var key *string
+	if r.keyMigration.Phase().WritesNew() {
+		k := f.Key
+		if k == "" {
+			k = KeyFromName(f.Name)
+		}
+		if k != "" {
+			key = &k
+		}
+	}
+
 	query := `
-		INSERT INTO flags (tenant_id, project_id, name, description, enabled, rules, rule_logic)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO flags (tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic, expires_at, key, lifecycle)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at, updated_at
 	`
-	err = r.getDB(ctx).QueryRowxContext(ctx, query, f.TenantID, f.ProjectID, f.Name, f.Description, f.Enabled, rulesJSON, f.RuleLogic).
+	err = r.getDB(ctx).QueryRowxContext(ctx, query, f.TenantID, f.ProjectID, f.OwnerUserID, f.Name, f.Description, f.Enabled, rulesJSON, f.RuleLogic, f.ExpiresAt, key, f.Lifecycle).
 		Scan(&f.ID, &f.CreatedAt, &f.UpdatedAt)
 	if err != nil {
 		return err
 	}
 
+	r.resolveKey(f, key)
+
 	return nil
 }
 
 func (r *postgresRepository) GetByID(ctx context.Context, id string, tenantID string) (*Flag, error) {
 	var f Flag
 	var rulesJSON []byte
+	var storedKey *string
 
 	query := `
-		SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic,
+		SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic, expires_at, key, lifecycle, archived_at, salt,
 		       created_at, updated_at
 		FROM flags
 		WHERE id = $1 AND tenant_id = $2
 	`
 
 	err := r.getDB(ctx).QueryRowxContext(ctx, query, id, tenantID).Scan(
-		&f.ID, &f.TenantID, &f.ProjectID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic,
+		&f.ID, &f.TenantID, &f.ProjectID, &f.OwnerUserID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic, &f.ExpiresAt, &storedKey, &f.Lifecycle, &f.ArchivedAt, &f.Salt,
 		&f.CreatedAt, &f.UpdatedAt,
 	)
 
@@ -79,12 +169,14 @@ func (r *postgresRepository) GetByID(ctx context.Context, id string, tenantID st
 		return nil, err
 	}
 
+	r.resolveKey(&f, storedKey)
+
 	return &f, nil
 }
 
 func (r *postgresRepository) List(ctx context.Context, tenantID string) ([]Flag, error) {
 	query := `
-		SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic,
+		SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic, expires_at, key, lifecycle, archived_at, salt,
 		       created_at, updated_at
 		FROM flags
 		WHERE tenant_id = $1
@@ -103,8 +195,9 @@ func (r *postgresRepository) List(ctx context.Context, tenantID string) ([]Flag,
 	for rows.Next() {
 		var f Flag
 		var rulesJSON []byte
+		var storedKey *string
 
-		err := rows.Scan(&f.ID, &f.TenantID, &f.ProjectID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic,
+		err := rows.Scan(&f.ID, &f.TenantID, &f.ProjectID, &f.OwnerUserID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic, &f.ExpiresAt, &storedKey, &f.Lifecycle, &f.ArchivedAt, &f.Salt,
 			&f.CreatedAt, &f.UpdatedAt)
 		if err != nil {
 			return nil, err
@@ -114,6 +207,8 @@ func (r *postgresRepository) List(ctx context.Context, tenantID string) ([]Flag,
 			return nil, err
 		}
 
+		r.resolveKey(&f, storedKey)
+
 		flags = append(flags, f)
 	}
 
@@ -127,7 +222,7 @@ func (r *postgresRepository) List(ctx context.Context, tenantID string) ([]Flag,
 // ListByProject returns all flags for a specific project within a tenant
 func (r *postgresRepository) ListByProject(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
 	query := `
-		SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic,
+		SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic, expires_at, key, lifecycle, archived_at, salt,
 		       created_at, updated_at
 		FROM flags
 		WHERE project_id = $1 AND tenant_id = $2
@@ -146,8 +241,55 @@ func (r *postgresRepository) ListByProject(ctx context.Context, projectID string
 	for rows.Next() {
 		var f Flag
 		var rulesJSON []byte
+		var storedKey *string
+
+		err := rows.Scan(&f.ID, &f.TenantID, &f.ProjectID, &f.OwnerUserID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic, &f.ExpiresAt, &storedKey, &f.Lifecycle, &f.ArchivedAt, &f.Salt,
+			&f.CreatedAt, &f.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(rulesJSON, &f.Rules); err != nil {
+			return nil, err
+		}
+
+		r.resolveKey(&f, storedKey)
+
+		flags = append(flags, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return flags, nil
+}
+
+// ListByOwner returns all flags owned by a user within a tenant
+func (r *postgresRepository) ListByOwner(ctx context.Context, tenantID string, ownerUserID string) ([]Flag, error) {
+	query := `
+		SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic, expires_at, key, lifecycle, archived_at, salt,
+		       created_at, updated_at
+		FROM flags
+		WHERE tenant_id = $1 AND owner_user_id = $2
+		ORDER BY created_at DESC
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, tenantID, ownerUserID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var flags []Flag
+
+	for rows.Next() {
+		var f Flag
+		var rulesJSON []byte
+		var storedKey *string
 
-		err := rows.Scan(&f.ID, &f.TenantID, &f.ProjectID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic,
+		err := rows.Scan(&f.ID, &f.TenantID, &f.ProjectID, &f.OwnerUserID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic, &f.ExpiresAt, &storedKey, &f.Lifecycle, &f.ArchivedAt, &f.Salt,
 			&f.CreatedAt, &f.UpdatedAt)
 		if err != nil {
 			return nil, err
@@ -157,6 +299,8 @@ func (r *postgresRepository) ListByProject(ctx context.Context, projectID string
 			return nil, err
 		}
 
+		r.resolveKey(&f, storedKey)
+
 		flags = append(flags, f)
 	}
 
@@ -177,11 +321,12 @@ func (r *postgresRepository) Update(ctx context.Context, f *Flag, tenantID strin
 
 	query := `
 		UPDATE flags
-		SET name = $2, description = $3, enabled = $4, rules = $5, rule_logic = $6, project_id = $7, updated_at = $8
+		SET name = $2, description = $3, enabled = $4, rules = $5, rule_logic = $6, project_id = $7, updated_at = $8,
+		    owner_user_id = $10, expires_at = $11, lifecycle = $12
 		WHERE id = $1 AND tenant_id = $9
 	`
 	result, err := r.getDB(ctx).ExecContext(ctx, query,
-		f.ID, f.Name, f.Description, f.Enabled, rulesJSON, f.RuleLogic, f.ProjectID, now, tenantID)
+		f.ID, f.Name, f.Description, f.Enabled, rulesJSON, f.RuleLogic, f.ProjectID, now, tenantID, f.OwnerUserID, f.ExpiresAt, f.Lifecycle)
 	if err != nil {
 		return err
 	}
@@ -199,6 +344,101 @@ func (r *postgresRepository) Update(ctx context.Context, f *Flag, tenantID strin
 	return nil
 }
 
+// Patch copies the masked fields of f (PatchFields names) onto the stored flag in a single
+// statement, leaving the others as they are, and returns the updated flag.
+// Returns sql.ErrNoRows if the flag does not exist in the tenant.
+func (r *postgresRepository) Patch(ctx context.Context, id string, tenantID string, f *Flag, mask []string) (*Flag, error) {
+	var rulesJSON []byte
+	if slices.Contains(mask, PatchFieldRules) {
+		var err error
+		if rulesJSON, err = json.Marshal(f.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		UPDATE flags
+		SET name = CASE WHEN $3 THEN $4 ELSE name END,
+		    description = CASE WHEN $5 THEN $6 ELSE description END,
+		    enabled = CASE WHEN $7 THEN $8 ELSE enabled END,
+		    rules = CASE WHEN $9 THEN $10::jsonb ELSE rules END,
+		    rule_logic = CASE WHEN $11 THEN $12 ELSE rule_logic END,
+		    project_id = CASE WHEN $13 THEN $14::uuid ELSE project_id END,
+		    updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic, expires_at, key, lifecycle, archived_at, salt,
+		          created_at, updated_at
+	`
+	return r.updateReturning(ctx, query, id, tenantID,
+		slices.Contains(mask, PatchFieldName), f.Name,
+		slices.Contains(mask, PatchFieldDescription), f.Description,
+		slices.Contains(mask, PatchFieldEnabled), f.Enabled,
+		slices.Contains(mask, PatchFieldRules), rulesJSON,
+		slices.Contains(mask, PatchFieldRuleLogic), f.RuleLogic,
+		slices.Contains(mask, PatchFieldProjectID), f.ProjectID,
+	)
+}
+
+// Toggle flips a flag's enabled state in a single statement, so concurrent toggles never
+// overwrite each other, and returns the updated flag.
+// Returns sql.ErrNoRows if the flag does not exist in the tenant.
+func (r *postgresRepository) Toggle(ctx context.Context, id string, tenantID string) (*Flag, error) {
+	query := `
+		UPDATE flags
+		SET enabled = NOT enabled, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic, expires_at, key, lifecycle, archived_at, salt,
+		          created_at, updated_at
+	`
+	return r.updateReturning(ctx, query, id, tenantID)
+}
+
+// updateReturning runs an UPDATE that returns a single flag row
+func (r *postgresRepository) updateReturning(ctx context.Context, query string, args ...interface{}) (*Flag, error) {
+	var f Flag
+	var rulesJSON []byte
+	var storedKey *string
+
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, args...).Scan(
+		&f.ID, &f.TenantID, &f.ProjectID, &f.OwnerUserID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic, &f.ExpiresAt, &storedKey, &f.Lifecycle, &f.ArchivedAt, &f.Salt,
+		&f.CreatedAt, &f.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(rulesJSON, &f.Rules); err != nil {
+		return nil, err
+	}
+
+	r.resolveKey(&f, storedKey)
+
+	return &f, nil
+}
+
+// SetSalt replaces a flag's bucketing salt.
+// Returns sql.ErrNoRows if the flag does not exist in the tenant.
+func (r *postgresRepository) SetSalt(ctx context.Context, id string, tenantID string, salt string) error {
+	query := `
+		UPDATE flags
+		SET salt = $3, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, query, id, tenantID, salt)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (r *postgresRepository) Delete(ctx context.Context, id string, tenantID string) error {
 	query := `
 		DELETE FROM flags
@@ -220,3 +460,925 @@ func (r *postgresRepository) Delete(ctx context.Context, id string, tenantID str
 
 	return nil
 }
+
+// RecordEvaluations marks flags as evaluated at the given time
+// IDs of flags that no longer exist are ignored
+func (r *postgresRepository) RecordEvaluations(ctx context.Context, flagIDs []string, at time.Time) error {
+	query := `
+		INSERT INTO flag_usage (flag_id, last_evaluated_at)
+		SELECT id, $2 FROM flags WHERE id = ANY($1::uuid[])
+		ON CONFLICT (flag_id) DO UPDATE
+		SET last_evaluated_at = GREATEST(flag_usage.last_evaluated_at, EXCLUDED.last_evaluated_at)
+	`
+	_, err := r.getDB(ctx).ExecContext(ctx, query, pq.Array(flagIDs), at)
+	return err
+}
+
+// RecordResults adds evaluation counts, and their reasons, to each flag's bucket for the given hour
+// IDs of flags that no longer exist are ignored
+func (r *postgresRepository) RecordResults(ctx context.Context, counts map[string]ResultCount, hour time.Time) error {
+	flagIDs := make([]string, 0, len(counts))
+	evaluations := make([]int64, 0, len(counts))
+	trues := make([]int64, 0, len(counts))
+	var reasonFlagIDs, reasons []string
+	var reasonCounts []int64
+	for id, c := range counts {
+		flagIDs = append(flagIDs, id)
+		evaluations = append(evaluations, c.Evaluations)
+		trues = append(trues, c.True)
+		for reason, n := range c.Reasons {
+			reasonFlagIDs = append(reasonFlagIDs, id)
+			reasons = append(reasons, reason)
+			reasonCounts = append(reasonCounts, n)
+		}
+	}
+
+	query := `
+		INSERT INTO flag_evaluation_counts (flag_id, tenant_id, hour, evaluations, true_count)
+		SELECT f.id, f.tenant_id, $4, c.evaluations, c.true_count
+		FROM unnest($1::uuid[], $2::bigint[], $3::bigint[]) AS c(flag_id, evaluations, true_count)
+		INNER JOIN flags f ON f.id = c.flag_id
+		ON CONFLICT (flag_id, hour) DO UPDATE
+		SET evaluations = flag_evaluation_counts.evaluations + EXCLUDED.evaluations,
+		    true_count = flag_evaluation_counts.true_count + EXCLUDED.true_count
+	`
+	if _, err := r.getDB(ctx).ExecContext(ctx, query, pq.Array(flagIDs), pq.Array(evaluations), pq.Array(trues), hour); err != nil {
+		return err
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	query = `
+		INSERT INTO flag_evaluation_reasons (flag_id, tenant_id, hour, reason, evaluations)
+		SELECT f.id, f.tenant_id, $4, c.reason, c.evaluations
+		FROM unnest($1::uuid[], $2::text[], $3::bigint[]) AS c(flag_id, reason, evaluations)
+		INNER JOIN flags f ON f.id = c.flag_id
+		ON CONFLICT (flag_id, hour, reason) DO UPDATE
+		SET evaluations = flag_evaluation_reasons.evaluations + EXCLUDED.evaluations
+	`
+	_, err := r.getDB(ctx).ExecContext(ctx, query, pq.Array(reasonFlagIDs), pq.Array(reasons), pq.Array(reasonCounts), hour)
+	return err
+}
+
+// SumResults totals the tenant's evaluation counts per flag over the hours starting at or after since
+func (r *postgresRepository) SumResults(ctx context.Context, tenantID string, since time.Time) (map[string]ResultCount, error) {
+	query := `
+		SELECT flag_id, SUM(evaluations), SUM(true_count)
+		FROM flag_evaluation_counts
+		WHERE tenant_id = $1 AND hour >= $2
+		GROUP BY flag_id
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, tenantID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]ResultCount)
+	for rows.Next() {
+		var flagID string
+		var c ResultCount
+		if err := rows.Scan(&flagID, &c.Evaluations, &c.True); err != nil {
+			return nil, err
+		}
+		counts[flagID] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reasonRows, err := r.getDB(ctx).QueryxContext(ctx, `
+		SELECT flag_id, reason, SUM(evaluations)
+		FROM flag_evaluation_reasons
+		WHERE tenant_id = $1 AND hour >= $2
+		GROUP BY flag_id, reason
+	`, tenantID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer reasonRows.Close()
+
+	for reasonRows.Next() {
+		var flagID, reason string
+		var n int64
+		if err := reasonRows.Scan(&flagID, &reason, &n); err != nil {
+			return nil, err
+		}
+		c := counts[flagID]
+		if c.Reasons == nil {
+			c.Reasons = make(map[string]int64)
+		}
+		c.Reasons[reason] = n
+		counts[flagID] = c
+	}
+
+	return counts, reasonRows.Err()
+}
+
+// ListResultCounts returns a flag's hourly evaluation counts for the hours starting at or
+// after since, oldest first
+func (r *postgresRepository) ListResultCounts(ctx context.Context, flagID string, tenantID string, since time.Time) ([]HourlyResultCount, error) {
+	query := `
+		SELECT hour, evaluations, true_count
+		FROM flag_evaluation_counts
+		WHERE flag_id = $1 AND tenant_id = $2 AND hour >= $3
+		ORDER BY hour
+	`
+	var counts []HourlyResultCount
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &counts, query, flagID, tenantID, since); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// DeleteResultCounts removes hourly evaluation counts and reasons older than before, across
+// all tenants. The count returned is of hourly evaluation counts.
+func (r *postgresRepository) DeleteResultCounts(ctx context.Context, before time.Time) (int64, error) {
+	if _, err := r.getDB(ctx).ExecContext(ctx, `DELETE FROM flag_evaluation_reasons WHERE hour < $1`, before); err != nil {
+		return 0, err
+	}
+	result, err := r.getDB(ctx).ExecContext(ctx, `DELETE FROM flag_evaluation_counts WHERE hour < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListStale returns flags that were neither modified nor evaluated since cutoff, oldest activity first
+func (r *postgresRepository) ListStale(ctx context.Context, tenantID string, cutoff time.Time) ([]StaleFlag, error) {
+	query := `
+		SELECT f.id, f.tenant_id, f.project_id, f.owner_user_id, f.name, f.description, f.enabled, f.rules, f.rule_logic, f.expires_at, f.key, f.lifecycle, f.archived_at, f.salt,
+		       f.created_at, f.updated_at, u.last_evaluated_at
+		FROM flags f
+		LEFT JOIN flag_usage u ON u.flag_id = f.id
+		WHERE f.tenant_id = $1
+		  AND f.updated_at < $2
+		  AND (u.last_evaluated_at IS NULL OR u.last_evaluated_at < $2)
+		ORDER BY COALESCE(u.last_evaluated_at, f.updated_at) ASC
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, tenantID, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []StaleFlag
+
+	for rows.Next() {
+		var f StaleFlag
+		var rulesJSON []byte
+		var storedKey *string
+
+		err := rows.Scan(&f.ID, &f.TenantID, &f.ProjectID, &f.OwnerUserID, &f.Name, &f.Description, &f.Enabled, &rulesJSON, &f.RuleLogic, &f.ExpiresAt, &storedKey, &f.Lifecycle, &f.ArchivedAt, &f.Salt,
+			&f.CreatedAt, &f.UpdatedAt, &f.LastEvaluatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(rulesJSON, &f.Rules); err != nil {
+			return nil, err
+		}
+
+		r.resolveKey(&f.Flag, storedKey)
+
+		flags = append(flags, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return flags, nil
+}
+
+// RecordAttributes marks context attributes as sent to a project at the given time
+// Attributes for projects that no longer exist are ignored
+func (r *postgresRepository) RecordAttributes(ctx context.Context, projectID string, attributes []string, at time.Time) error {
+	query := `
+		INSERT INTO attribute_usage (project_id, attribute, last_seen_at)
+		SELECT p.id, a.attribute, $3
+		FROM projects p, unnest($2::text[]) AS a(attribute)
+		WHERE p.id = $1
+		ON CONFLICT (project_id, attribute) DO UPDATE
+		SET last_seen_at = GREATEST(attribute_usage.last_seen_at, EXCLUDED.last_seen_at)
+	`
+	_, err := r.getDB(ctx).ExecContext(ctx, query, projectID, pq.Array(attributes), at)
+	return err
+}
+
+// ListSeenAttributes returns when each attribute was last sent to the project
+func (r *postgresRepository) ListSeenAttributes(ctx context.Context, projectID string, tenantID string) (map[string]time.Time, error) {
+	query := `
+		SELECT a.attribute, a.last_seen_at
+		FROM attribute_usage a
+		JOIN projects p ON p.id = a.project_id
+		WHERE a.project_id = $1 AND p.tenant_id = $2
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]time.Time)
+	for rows.Next() {
+		var attribute string
+		var lastSeenAt time.Time
+		if err := rows.Scan(&attribute, &lastSeenAt); err != nil {
+			return nil, err
+		}
+		seen[attribute] = lastSeenAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return seen, nil
+}
+
+// ListRegisteredAttributes returns the project's attribute registry ordered by context kind and name
+func (r *postgresRepository) ListRegisteredAttributes(ctx context.Context, projectID string, tenantID string) ([]RegisteredAttribute, error) {
+	query := `
+		SELECT id, project_id, name, context_kind, type, description, created_at, updated_at
+		FROM project_attributes
+		WHERE project_id = $1 AND tenant_id = $2
+		ORDER BY context_kind ASC, name ASC
+	`
+	attributes := []RegisteredAttribute{}
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &attributes, query, projectID, tenantID); err != nil {
+		return nil, err
+	}
+
+	return attributes, nil
+}
+
+// UpsertRegisteredAttribute registers an attribute, replacing the type and description of one
+// already registered under the same name and context kind. Populates the ID and timestamps.
+// Returns sql.ErrNoRows if the project does not exist in the tenant.
+func (r *postgresRepository) UpsertRegisteredAttribute(ctx context.Context, a *RegisteredAttribute, tenantID string) error {
+	query := `
+		INSERT INTO project_attributes (tenant_id, project_id, name, context_kind, type, description)
+		SELECT p.tenant_id, p.id, $3, $4, $5, $6
+		FROM projects p
+		WHERE p.id = $1 AND p.tenant_id = $2
+		ON CONFLICT (project_id, context_kind, name) DO UPDATE
+		SET type = EXCLUDED.type, description = EXCLUDED.description, updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, a.ProjectID, tenantID, a.Name, a.ContextKind, a.Type, a.Description).
+		Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// DeleteRegisteredAttribute removes an attribute from the project's registry
+// Returns sql.ErrNoRows if the project has no such attribute
+func (r *postgresRepository) DeleteRegisteredAttribute(ctx context.Context, id string, projectID string, tenantID string) error {
+	query := `
+		DELETE FROM project_attributes
+		WHERE id = $1 AND project_id = $2 AND tenant_id = $3
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, query, id, projectID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListExclusions returns the excluded user keys for a flag, oldest first
+func (r *postgresRepository) ListExclusions(ctx context.Context, flagID string, tenantID string) ([]Exclusion, error) {
+	query := `
+		SELECT flag_id, user_key, reason, created_at
+		FROM flag_exclusions
+		WHERE flag_id = $1 AND tenant_id = $2
+		ORDER BY created_at ASC, user_key ASC
+	`
+	exclusions := []Exclusion{}
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &exclusions, query, flagID, tenantID); err != nil {
+		return nil, err
+	}
+
+	return exclusions, nil
+}
+
+// AddExclusions excludes user keys from a flag. Keys that are already excluded keep
+// their original timestamp and take the new reason.
+// Returns sql.ErrNoRows if the flag does not exist in the tenant.
+func (r *postgresRepository) AddExclusions(ctx context.Context, flagID string, tenantID string, userKeys []string, reason string) error {
+	query := `
+		INSERT INTO flag_exclusions (flag_id, tenant_id, user_key, reason)
+		SELECT f.id, f.tenant_id, k.user_key, $4
+		FROM flags f, unnest($3::text[]) AS k(user_key)
+		WHERE f.id = $1 AND f.tenant_id = $2
+		ON CONFLICT (flag_id, user_key) DO UPDATE
+		SET reason = EXCLUDED.reason
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, query, flagID, tenantID, pq.Array(userKeys), reason)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RemoveExclusion lets a user key receive the flag again
+// Returns sql.ErrNoRows if the key was not excluded
+func (r *postgresRepository) RemoveExclusion(ctx context.Context, flagID string, tenantID string, userKey string) error {
+	query := `
+		DELETE FROM flag_exclusions
+		WHERE flag_id = $1 AND tenant_id = $2 AND user_key = $3
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, query, flagID, tenantID, userKey)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListCustomFields returns a flag's custom fields ordered by name
+func (r *postgresRepository) ListCustomFields(ctx context.Context, flagID string, tenantID string) ([]CustomField, error) {
+	query := `
+		SELECT flag_id, name, value, updated_at
+		FROM flag_custom_fields
+		WHERE flag_id = $1 AND tenant_id = $2
+		ORDER BY name ASC
+	`
+	fields := []CustomField{}
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &fields, query, flagID, tenantID); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// SetCustomField creates or replaces one custom field.
+// Returns sql.ErrNoRows if the flag does not exist in the tenant.
+func (r *postgresRepository) SetCustomField(ctx context.Context, flagID string, tenantID string, name string, value string) (*CustomField, error) {
+	query := `
+		INSERT INTO flag_custom_fields (flag_id, tenant_id, name, value)
+		SELECT f.id, f.tenant_id, $3, $4
+		FROM flags f
+		WHERE f.id = $1 AND f.tenant_id = $2
+		ON CONFLICT (flag_id, name) DO UPDATE
+		SET value = EXCLUDED.value, updated_at = NOW()
+		RETURNING flag_id, name, value, updated_at
+	`
+	var field CustomField
+	if err := sqlx.GetContext(ctx, r.getDB(ctx), &field, query, flagID, tenantID, name, value); err != nil {
+		return nil, err
+	}
+
+	return &field, nil
+}
+
+// DeleteCustomField removes one custom field
+// Returns sql.ErrNoRows if the flag has no such field
+func (r *postgresRepository) DeleteCustomField(ctx context.Context, flagID string, tenantID string, name string) error {
+	query := `
+		DELETE FROM flag_custom_fields
+		WHERE flag_id = $1 AND tenant_id = $2 AND name = $3
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, query, flagID, tenantID, name)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListExcludedFlagIDs returns the IDs of flags the user key is excluded from
+func (r *postgresRepository) ListExcludedFlagIDs(ctx context.Context, tenantID string, userKey string) ([]string, error) {
+	query := `
+		SELECT flag_id
+		FROM flag_exclusions
+		WHERE tenant_id = $1 AND user_key = $2
+	`
+	var ids []string
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &ids, query, tenantID, userKey); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// ListExclusionsByProject returns excluded user keys for every flag in a project, keyed by flag ID
+func (r *postgresRepository) ListExclusionsByProject(ctx context.Context, projectID string, tenantID string) (map[string][]string, error) {
+	query := `
+		SELECT e.flag_id, e.user_key
+		FROM flag_exclusions e
+		INNER JOIN flags f ON f.id = e.flag_id
+		WHERE f.project_id = $1 AND e.tenant_id = $2
+		ORDER BY e.flag_id, e.user_key
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exclusions := make(map[string][]string)
+
+	for rows.Next() {
+		var flagID, userKey string
+		if err := rows.Scan(&flagID, &userKey); err != nil {
+			return nil, err
+		}
+		exclusions[flagID] = append(exclusions[flagID], userKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exclusions, nil
+}
+
+// UpsertOverride creates or replaces the override for a flag and user key
+// Returns sql.ErrNoRows if the flag does not exist in the tenant.
+func (r *postgresRepository) UpsertOverride(ctx context.Context, o *Override, tenantID string) error {
+	query := `
+		INSERT INTO flag_overrides (flag_id, tenant_id, user_key, enabled, reason, expires_at, created_by)
+		SELECT f.id, f.tenant_id, $3, $4, $5, $6, $7
+		FROM flags f
+		WHERE f.id = $1 AND f.tenant_id = $2
+		ON CONFLICT (flag_id, user_key) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+		    reason = EXCLUDED.reason,
+		    expires_at = EXCLUDED.expires_at,
+		    created_by = EXCLUDED.created_by,
+		    created_at = NOW()
+		RETURNING created_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query,
+		o.FlagID, tenantID, o.UserKey, o.Enabled, o.Reason, o.ExpiresAt, o.CreatedBy).
+		Scan(&o.CreatedAt)
+}
+
+// ListOverrides returns a flag's unexpired overrides, soonest to expire first
+func (r *postgresRepository) ListOverrides(ctx context.Context, flagID string, tenantID string, now time.Time) ([]Override, error) {
+	query := `
+		SELECT flag_id, user_key, enabled, reason, expires_at, created_by, created_at
+		FROM flag_overrides
+		WHERE flag_id = $1 AND tenant_id = $2 AND expires_at > $3
+		ORDER BY expires_at ASC, user_key ASC
+	`
+	overrides := []Override{}
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &overrides, query, flagID, tenantID, now); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+// DeleteOverride removes the override for a flag and user key
+// Returns sql.ErrNoRows if there was none
+func (r *postgresRepository) DeleteOverride(ctx context.Context, flagID string, tenantID string, userKey string) error {
+	query := `
+		DELETE FROM flag_overrides
+		WHERE flag_id = $1 AND tenant_id = $2 AND user_key = $3
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, query, flagID, tenantID, userKey)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ListActiveOverrides returns the user key's unexpired override values, keyed by flag ID
+func (r *postgresRepository) ListActiveOverrides(ctx context.Context, tenantID string, userKey string, now time.Time) (map[string]bool, error) {
+	query := `
+		SELECT flag_id, enabled
+		FROM flag_overrides
+		WHERE tenant_id = $1 AND user_key = $2 AND expires_at > $3
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, tenantID, userKey, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]bool)
+
+	for rows.Next() {
+		var flagID string
+		var enabled bool
+		if err := rows.Scan(&flagID, &enabled); err != nil {
+			return nil, err
+		}
+		overrides[flagID] = enabled
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+// ListOverridesByProject returns unexpired overrides for every flag in a project, keyed by flag ID
+func (r *postgresRepository) ListOverridesByProject(ctx context.Context, projectID string, tenantID string, now time.Time) (map[string][]Override, error) {
+	query := `
+		SELECT o.flag_id, o.user_key, o.enabled, o.reason, o.expires_at, o.created_by, o.created_at
+		FROM flag_overrides o
+		INNER JOIN flags f ON f.id = o.flag_id
+		WHERE f.project_id = $1 AND o.tenant_id = $2 AND o.expires_at > $3
+		ORDER BY o.flag_id, o.user_key
+	`
+	var rows []Override
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &rows, query, projectID, tenantID, now); err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string][]Override)
+	for _, o := range rows {
+		overrides[o.FlagID] = append(overrides[o.FlagID], o)
+	}
+
+	return overrides, nil
+}
+
+// DeleteExpiredOverrides removes overrides that expired at or before now, across all tenants
+func (r *postgresRepository) DeleteExpiredOverrides(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		DELETE FROM flag_overrides
+		WHERE expires_at <= $1
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DisableExpired disables flags whose expiry passed at or before now, across all tenants,
+// and records an "expired" history entry for each. The expiry is cleared so a flag that is
+// re-enabled afterwards stays on.
+func (r *postgresRepository) DisableExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+		WITH expired AS (
+			UPDATE flags f
+			SET enabled = false, expires_at = NULL, updated_at = $1
+			FROM (
+				SELECT id, enabled, expires_at
+				FROM flags
+				WHERE expires_at <= $1
+				FOR UPDATE
+			) old
+			WHERE f.id = old.id
+			RETURNING f.id, f.tenant_id, old.enabled AS was_enabled, old.expires_at
+		), recorded AS (
+			INSERT INTO flag_history (tenant_id, flag_id, action, details)
+			SELECT tenant_id, id, $2, jsonb_build_object('expires_at', expires_at, 'was_enabled', was_enabled)
+			FROM expired
+		)
+		SELECT COUNT(*) FROM expired
+	`
+	var count int64
+	if err := r.getDB(ctx).QueryRowxContext(ctx, query, now, HistoryActionExpired).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ListHistory returns a flag's history, newest first
+func (r *postgresRepository) ListHistory(ctx context.Context, flagID string, tenantID string) ([]HistoryEntry, error) {
+	query := `
+		SELECT id, flag_id, action, actor_id, details, created_at
+		FROM flag_history
+		WHERE flag_id = $1 AND tenant_id = $2
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, flagID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		var e HistoryEntry
+		var detailsJSON []byte
+
+		if err := rows.Scan(&e.ID, &e.FlagID, &e.Action, &e.ActorID, &detailsJSON, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(detailsJSON, &e.Details); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// BackfillKeys stores the name-derived key for up to limit flags that don't have one yet,
+// across all tenants. Rows keyed concurrently by another replica are left untouched.
+func (r *postgresRepository) BackfillKeys(ctx context.Context, limit int) (int64, error) {
+	query := `
+		SELECT id, name
+		FROM flags
+		WHERE key IS NULL
+		ORDER BY created_at ASC
+		LIMIT $1
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids, keys []string
+	for rows.Next() {
+		var f Flag
+		if err := rows.Scan(&f.ID, &f.Name); err != nil {
+			return 0, err
+		}
+		ids = append(ids, f.ID)
+		keys = append(keys, legacyKey(&f))
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	update := `
+		UPDATE flags f
+		SET key = k.key
+		FROM unnest($1::uuid[], $2::text[]) AS k(id, key)
+		WHERE f.id = k.id AND f.key IS NULL
+	`
+	result, err := r.getDB(ctx).ExecContext(ctx, update, pq.Array(ids), pq.Array(keys))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// CountMissingKeys counts flags, across all tenants, that don't have a stored key yet
+func (r *postgresRepository) CountMissingKeys(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.getDB(ctx).QueryRowxContext(ctx, `SELECT COUNT(*) FROM flags WHERE key IS NULL`).Scan(&count)
+	return count, err
+}
+
+// KeyStore adapts the repository's key backfill to the dualwrite verifier
+type KeyStore struct {
+	Repo Repository
+}
+
+func (s KeyStore) Backfill(ctx context.Context, limit int) (int64, error) {
+	return s.Repo.BackfillKeys(ctx, limit)
+}
+
+func (s KeyStore) Pending(ctx context.Context) (int64, error) {
+	return s.Repo.CountMissingKeys(ctx)
+}
+
+// scanEnvironmentConfigs reads flag_id, environment_id, enabled, rules, rule_logic, updated_at rows
+func scanEnvironmentConfigs(rows *sqlx.Rows) ([]EnvironmentConfig, error) {
+	defer rows.Close()
+
+	configs := []EnvironmentConfig{}
+	for rows.Next() {
+		var c EnvironmentConfig
+		var rulesJSON []byte
+		if err := rows.Scan(&c.FlagID, &c.EnvironmentID, &c.Enabled, &rulesJSON, &c.RuleLogic, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(rulesJSON, &c.Rules); err != nil {
+			return nil, err
+		}
+		configs = append(configs, c)
+	}
+
+	return configs, rows.Err()
+}
+
+// ListEnvironmentConfigs returns a flag's config in every environment it has been configured in
+func (r *postgresRepository) ListEnvironmentConfigs(ctx context.Context, flagID string, tenantID string) ([]EnvironmentConfig, error) {
+	query := `
+		SELECT fe.flag_id, fe.environment_id, fe.enabled, fe.rules, fe.rule_logic, fe.updated_at
+		FROM flag_environments fe
+		INNER JOIN environments e ON e.id = fe.environment_id
+		WHERE fe.flag_id = $1 AND fe.tenant_id = $2
+		ORDER BY e.created_at ASC, e.key ASC
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, flagID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanEnvironmentConfigs(rows)
+}
+
+// GetEnvironmentConfig returns a flag's config in one environment
+// Returns sql.ErrNoRows if the flag was never configured there
+func (r *postgresRepository) GetEnvironmentConfig(ctx context.Context, flagID string, environmentID string, tenantID string) (*EnvironmentConfig, error) {
+	query := `
+		SELECT flag_id, environment_id, enabled, rules, rule_logic, updated_at
+		FROM flag_environments
+		WHERE flag_id = $1 AND environment_id = $2 AND tenant_id = $3
+	`
+	var c EnvironmentConfig
+	var rulesJSON []byte
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, flagID, environmentID, tenantID).
+		Scan(&c.FlagID, &c.EnvironmentID, &c.Enabled, &rulesJSON, &c.RuleLogic, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(rulesJSON, &c.Rules); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// SetEnvironmentConfig creates or replaces a flag's config in one environment.
+// Returns sql.ErrNoRows unless the flag and environment exist in the tenant and the
+// environment belongs to the flag's project.
+func (r *postgresRepository) SetEnvironmentConfig(ctx context.Context, c *EnvironmentConfig, tenantID string) error {
+	rulesJSON, err := json.Marshal(c.Rules)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO flag_environments (flag_id, environment_id, tenant_id, enabled, rules, rule_logic)
+		SELECT f.id, e.id, f.tenant_id, $4, $5, $6
+		FROM flags f
+		INNER JOIN environments e ON e.project_id = f.project_id AND e.tenant_id = f.tenant_id
+		WHERE f.id = $1 AND e.id = $2 AND f.tenant_id = $3
+		ON CONFLICT (flag_id, environment_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, rules = EXCLUDED.rules, rule_logic = EXCLUDED.rule_logic
+		RETURNING updated_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, c.FlagID, c.EnvironmentID, tenantID, c.Enabled, rulesJSON, c.RuleLogic).
+		Scan(&c.UpdatedAt)
+}
+
+// ResetEnvironmentConfigs re-creates the environment configs of a flag moved to another
+// project: configs in environments outside its project are deleted, and it is configured in
+// each of its project's environments with its own enabled state and rules, as when
+// environments were introduced
+func (r *postgresRepository) ResetEnvironmentConfigs(ctx context.Context, flagID string, tenantID string) error {
+	query := `
+		WITH flag AS (
+			SELECT id, tenant_id, project_id, enabled, rules, rule_logic
+			FROM flags
+			WHERE id = $1 AND tenant_id = $2
+		), removed AS (
+			DELETE FROM flag_environments fe
+			USING flag f, environments e
+			WHERE fe.flag_id = f.id AND e.id = fe.environment_id AND e.project_id IS DISTINCT FROM f.project_id
+		)
+		INSERT INTO flag_environments (flag_id, environment_id, tenant_id, enabled, rules, rule_logic)
+		SELECT f.id, e.id, f.tenant_id, f.enabled, f.rules, f.rule_logic
+		FROM flag f
+		INNER JOIN environments e ON e.project_id = f.project_id AND e.tenant_id = f.tenant_id
+		ON CONFLICT (flag_id, environment_id) DO NOTHING
+	`
+	_, err := r.getDB(ctx).ExecContext(ctx, query, flagID, tenantID)
+	return err
+}
+
+// ListEnvironmentConfigsByProject returns the environment configs of every flag in a project, keyed by flag ID
+func (r *postgresRepository) ListEnvironmentConfigsByProject(ctx context.Context, projectID string, tenantID string) (map[string][]EnvironmentConfig, error) {
+	query := `
+		SELECT fe.flag_id, fe.environment_id, fe.enabled, fe.rules, fe.rule_logic, fe.updated_at
+		FROM flag_environments fe
+		INNER JOIN flags f ON f.id = fe.flag_id
+		WHERE f.project_id = $1 AND fe.tenant_id = $2
+		ORDER BY fe.flag_id, fe.environment_id
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := scanEnvironmentConfigs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byFlag := make(map[string][]EnvironmentConfig)
+	for _, c := range configs {
+		byFlag[c.FlagID] = append(byFlag[c.FlagID], c)
+	}
+	return byFlag, nil
+}
+
+// GetEnvironmentConfigByKey resolves an environment of the flag's project by key and returns its
+// ID and the flag's config there (nil if the flag was never configured in it).
+// Returns sql.ErrNoRows if the flag's project has no such environment.
+func (r *postgresRepository) GetEnvironmentConfigByKey(ctx context.Context, flagID string, environmentKey string, tenantID string) (string, *EnvironmentConfig, error) {
+	query := `
+		SELECT e.id, fe.flag_id, fe.enabled, fe.rules, fe.rule_logic, fe.updated_at
+		FROM flags f
+		INNER JOIN environments e ON e.project_id = f.project_id AND e.tenant_id = f.tenant_id
+		LEFT JOIN flag_environments fe ON fe.flag_id = f.id AND fe.environment_id = e.id
+		WHERE f.id = $1 AND e.key = $2 AND f.tenant_id = $3
+	`
+	var environmentID string
+	var configFlagID, ruleLogic sql.NullString
+	var enabled sql.NullBool
+	var updatedAt sql.NullTime
+	var rulesJSON []byte
+
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, flagID, environmentKey, tenantID).
+		Scan(&environmentID, &configFlagID, &enabled, &rulesJSON, &ruleLogic, &updatedAt)
+	if err != nil {
+		return "", nil, err
+	}
+	if !configFlagID.Valid {
+		return environmentID, nil, nil
+	}
+
+	c := &EnvironmentConfig{
+		FlagID:        configFlagID.String,
+		EnvironmentID: environmentID,
+		Enabled:       enabled.Bool,
+		RuleLogic:     ruleLogic.String,
+		UpdatedAt:     updatedAt.Time,
+	}
+	if err := json.Unmarshal(rulesJSON, &c.Rules); err != nil {
+		return "", nil, err
+	}
+	return environmentID, c, nil
+}
+
+// RecordHistory appends an entry to a flag's history
+func (r *postgresRepository) RecordHistory(ctx context.Context, e *HistoryEntry, tenantID string) error {
+	detailsJSON, err := json.Marshal(e.Details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO flag_history (tenant_id, flag_id, action, actor_id, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, tenantID, e.FlagID, e.Action, e.ActorID, detailsJSON).
+		Scan(&e.ID, &e.CreatedAt)
+}
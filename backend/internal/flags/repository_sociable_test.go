@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	flag "github.com/jalil32/toggle/internal/flags"
 	"github.com/jalil32/toggle/internal/testutil"
@@ -65,6 +66,40 @@ func TestRepository_Create_Sociable(t *testing.T) {
 	})
 }
 
+func TestRepository_Create_RoundTripsAllFields(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant := testutil.CreateTenant(t, tx, "Test Tenant", "test-tenant")
+		project := testutil.CreateProject(t, tx, tenant.ID, "Test Project", "test-api-key-123")
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Microsecond)
+		newFlag := &flag.Flag{
+			TenantID:    tenant.ID,
+			ProjectID:   &project.ID,
+			Name:        "round-trip",
+			Description: "every field survives",
+			Enabled:     true,
+			Rules:       []flag.Rule{{ID: "r1", Attribute: "country", Operator: "equals", Value: "AU", Rollout: 50}},
+			RuleLogic:   flag.RuleLogicFirstMatch,
+			ExpiresAt:   &expiresAt,
+		}
+		require.NoError(t, repo.Create(ctx, newFlag))
+
+		stored, err := repo.GetByID(ctx, newFlag.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tenant.ID, stored.TenantID)
+		assert.Equal(t, project.ID, *stored.ProjectID)
+		assert.Equal(t, "every field survives", stored.Description)
+		assert.True(t, stored.Enabled)
+		assert.Equal(t, flag.RuleLogicFirstMatch, stored.RuleLogic)
+		require.Len(t, stored.Rules, 1)
+		assert.Equal(t, 50, stored.Rules[0].Rollout)
+		require.NotNil(t, stored.ExpiresAt)
+		assert.True(t, expiresAt.Equal(*stored.ExpiresAt))
+	})
+}
+
 func TestRepository_GetByID_TenantIsolation(t *testing.T) {
 	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
 		// Setup: Create two tenants with their own projects and flags
@@ -220,6 +255,84 @@ func TestRepository_Update_EnforcesTenantBoundary(t *testing.T) {
 	})
 }
 
+func TestRepository_Toggle_FlipsAtomicallyWithinTenant(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		project1 := testutil.CreateProject(t, tx, tenant1.ID, "Project 1", "api-key-1")
+
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		f := &flag.Flag{
+			TenantID:  tenant1.ID,
+			Name:      "toggled-flag",
+			Enabled:   false,
+			Rules:     []flag.Rule{{ID: "r1", Attribute: "country", Operator: "equals", Value: "AU", Rollout: 100}},
+			RuleLogic: "AND",
+			ProjectID: &project1.ID,
+		}
+		require.NoError(t, repo.Create(ctx, f))
+
+		toggled, err := repo.Toggle(ctx, f.ID, tenant1.ID)
+		require.NoError(t, err)
+		assert.True(t, toggled.Enabled)
+		assert.Equal(t, "toggled-flag", toggled.Name)
+		assert.Len(t, toggled.Rules, 1, "Rules should be returned with the updated flag")
+
+		toggled, err = repo.Toggle(ctx, f.ID, tenant1.ID)
+		require.NoError(t, err)
+		assert.False(t, toggled.Enabled)
+
+		// Test: Tenant 2 CANNOT toggle Tenant 1's flag
+		_, err = repo.Toggle(ctx, f.ID, tenant2.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		retrieved, err := repo.GetByID(ctx, f.ID, tenant1.ID)
+		require.NoError(t, err)
+		assert.False(t, retrieved.Enabled, "Tenant 2's toggle should not have changed the flag")
+	})
+}
+
+func TestRepository_Patch_UpdatesOnlyMaskedFieldsWithinTenant(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		project1 := testutil.CreateProject(t, tx, tenant1.ID, "Project 1", "api-key-1")
+
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		f := &flag.Flag{
+			TenantID:    tenant1.ID,
+			Name:        "patched-flag",
+			Description: "kept",
+			Enabled:     true,
+			Rules:       []flag.Rule{{ID: "r1", Attribute: "country", Operator: "equals", Value: "AU", Rollout: 100}},
+			RuleLogic:   "AND",
+			ProjectID:   &project1.ID,
+		}
+		require.NoError(t, repo.Create(ctx, f))
+
+		patched, err := repo.Patch(ctx, f.ID, tenant1.ID, &flag.Flag{Enabled: false, Name: "ignored"}, []string{flag.PatchFieldEnabled})
+		require.NoError(t, err)
+		assert.False(t, patched.Enabled)
+		assert.Equal(t, "patched-flag", patched.Name, "unmasked fields must be kept")
+		assert.Equal(t, "kept", patched.Description)
+		assert.Len(t, patched.Rules, 1)
+
+		patched, err = repo.Patch(ctx, f.ID, tenant1.ID, &flag.Flag{Rules: []flag.Rule{}, RuleLogic: "OR"}, []string{flag.PatchFieldRules, flag.PatchFieldRuleLogic})
+		require.NoError(t, err)
+		assert.Empty(t, patched.Rules)
+		assert.Equal(t, "OR", patched.RuleLogic)
+		assert.Equal(t, &project1.ID, patched.ProjectID)
+
+		// Test: Tenant 2 CANNOT patch Tenant 1's flag
+		_, err = repo.Patch(ctx, f.ID, tenant2.ID, &flag.Flag{Enabled: true}, []string{flag.PatchFieldEnabled})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
 func TestRepository_Delete_EnforcesTenantBoundary(t *testing.T) {
 	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
 		// Setup
@@ -266,3 +379,372 @@ func TestRepository_Delete_EnforcesTenantBoundary(t *testing.T) {
 // In a real UoW pattern, repositories should always receive a context with either
 // a transaction (from UoW) or use the DB directly. Testing "zero context" isn't
 // meaningful in our transactional test setup since test data lives in a transaction.
+
+// TestRepository_ListStale_ExcludesRecentlyEvaluated tests that flags with
+// recent evaluations are not reported stale and that results are tenant-scoped
+func TestRepository_ListStale_ExcludesRecentlyEvaluated(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+
+		unused := testutil.CreateFlag(t, tx, tenant1.ID, nil, "unused-flag", "", true)
+		used := testutil.CreateFlag(t, tx, tenant1.ID, nil, "used-flag", "", true)
+		evaluatedLongAgo := testutil.CreateFlag(t, tx, tenant1.ID, nil, "old-usage-flag", "", true)
+		testutil.CreateFlag(t, tx, tenant2.ID, nil, "other-tenant-flag", "", true)
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		// updated_at is fixed to the transaction start, so use a cutoff after it
+		cutoff := time.Now().Add(time.Hour)
+
+		require.NoError(t, repo.RecordEvaluations(ctx, []string{used.ID}, cutoff.Add(time.Hour)))
+		require.NoError(t, repo.RecordEvaluations(ctx, []string{evaluatedLongAgo.ID}, cutoff.Add(-2*time.Hour)))
+		// Recording an older time never moves last_evaluated_at backwards
+		require.NoError(t, repo.RecordEvaluations(ctx, []string{used.ID}, cutoff.Add(-48*time.Hour)))
+
+		stale, err := repo.ListStale(ctx, tenant1.ID, cutoff)
+		require.NoError(t, err)
+
+		ids := map[string]*time.Time{}
+		for _, f := range stale {
+			ids[f.ID] = f.LastEvaluatedAt
+		}
+		assert.Len(t, ids, 2)
+		assert.Contains(t, ids, unused.ID)
+		assert.Nil(t, ids[unused.ID], "never-evaluated flag has no last_evaluated_at")
+		assert.Contains(t, ids, evaluatedLongAgo.ID)
+		assert.NotContains(t, ids, used.ID)
+	})
+}
+
+// TestRepository_Exclusions_AreIncrementalAndTenantScoped tests that exclusions can be
+// added and removed one key at a time and never cross tenant boundaries
+func TestRepository_Exclusions_AreIncrementalAndTenantScoped(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+		project1 := testutil.CreateProject(t, tx, tenant1.ID, "Project 1", "api-key-1")
+
+		f := testutil.CreateFlag(t, tx, tenant1.ID, &project1.ID, "checkout", "", true)
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		require.NoError(t, repo.AddExclusions(ctx, f.ID, tenant1.ID, []string{"user-1"}, "ticket 1"))
+		require.NoError(t, repo.AddExclusions(ctx, f.ID, tenant1.ID, []string{"user-1", "user-2"}, "ticket 2"))
+
+		exclusions, err := repo.ListExclusions(ctx, f.ID, tenant1.ID)
+		require.NoError(t, err)
+		require.Len(t, exclusions, 2)
+		assert.Equal(t, "ticket 2", exclusions[0].Reason, "re-adding a key updates its reason")
+
+		excluded, err := repo.ListExcludedFlagIDs(ctx, tenant1.ID, "user-2")
+		require.NoError(t, err)
+		assert.Equal(t, []string{f.ID}, excluded)
+
+		byFlag, err := repo.ListExclusionsByProject(ctx, project1.ID, tenant1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user-1", "user-2"}, byFlag[f.ID])
+
+		// Another tenant can neither add to nor remove from the flag's exclusions
+		assert.ErrorIs(t, repo.AddExclusions(ctx, f.ID, tenant2.ID, []string{"user-3"}, ""), sql.ErrNoRows)
+		assert.ErrorIs(t, repo.RemoveExclusion(ctx, f.ID, tenant2.ID, "user-1"), sql.ErrNoRows)
+
+		require.NoError(t, repo.RemoveExclusion(ctx, f.ID, tenant1.ID, "user-1"))
+		assert.ErrorIs(t, repo.RemoveExclusion(ctx, f.ID, tenant1.ID, "user-1"), sql.ErrNoRows)
+
+		exclusions, err = repo.ListExclusions(ctx, f.ID, tenant1.ID)
+		require.NoError(t, err)
+		require.Len(t, exclusions, 1)
+		assert.Equal(t, "user-2", exclusions[0].UserKey)
+	})
+}
+
+// TestRepository_ListByOwner_IsTenantScoped tests that the owner filter only returns
+// the owner's flags in the requested tenant
+func TestRepository_ListByOwner_IsTenantScoped(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+		owner := testutil.CreateUser(t, tx, "Owner", "owner@example.com")
+		testutil.CreateTenantMember(t, tx, owner.ID, tenant1.ID, "member")
+		testutil.CreateTenantMember(t, tx, owner.ID, tenant2.ID, "member")
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		owned := &flag.Flag{TenantID: tenant1.ID, OwnerUserID: &owner.ID, Name: "owned", Rules: []flag.Rule{}, RuleLogic: "AND"}
+		require.NoError(t, repo.Create(ctx, owned))
+		otherTenant := &flag.Flag{TenantID: tenant2.ID, OwnerUserID: &owner.ID, Name: "owned-elsewhere", Rules: []flag.Rule{}, RuleLogic: "AND"}
+		require.NoError(t, repo.Create(ctx, otherTenant))
+		testutil.CreateFlag(t, tx, tenant1.ID, nil, "unowned", "", true)
+
+		flags, err := repo.ListByOwner(ctx, tenant1.ID, owner.ID)
+		require.NoError(t, err)
+		require.Len(t, flags, 1)
+		assert.Equal(t, owned.ID, flags[0].ID)
+		require.NotNil(t, flags[0].OwnerUserID)
+		assert.Equal(t, owner.ID, *flags[0].OwnerUserID)
+	})
+}
+
+// TestRepository_Overrides_IgnoreAndDeleteExpired tests that expired overrides are
+// never returned and are removed by DeleteExpiredOverrides
+func TestRepository_Overrides_IgnoreAndDeleteExpired(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+		f := testutil.CreateFlag(t, tx, tenant1.ID, nil, "checkout", "", false)
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+		now := time.Now()
+
+		active := &flag.Override{FlagID: f.ID, UserKey: "qa-user", Enabled: true, ExpiresAt: now.Add(time.Hour)}
+		require.NoError(t, repo.UpsertOverride(ctx, active, tenant1.ID))
+		expired := &flag.Override{FlagID: f.ID, UserKey: "old-user", Enabled: true, ExpiresAt: now.Add(-time.Hour)}
+		require.NoError(t, repo.UpsertOverride(ctx, expired, tenant1.ID))
+
+		// Another tenant cannot override the flag
+		assert.ErrorIs(t, repo.UpsertOverride(ctx, &flag.Override{FlagID: f.ID, UserKey: "x", ExpiresAt: now.Add(time.Hour)}, tenant2.ID), sql.ErrNoRows)
+
+		values, err := repo.ListActiveOverrides(ctx, tenant1.ID, "qa-user", now)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{f.ID: true}, values)
+
+		values, err = repo.ListActiveOverrides(ctx, tenant1.ID, "old-user", now)
+		require.NoError(t, err)
+		assert.Empty(t, values)
+
+		deleted, err := repo.DeleteExpiredOverrides(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		overrides, err := repo.ListOverrides(ctx, f.ID, tenant1.ID, now)
+		require.NoError(t, err)
+		require.Len(t, overrides, 1)
+		assert.Equal(t, "qa-user", overrides[0].UserKey)
+	})
+}
+
+// TestRepository_DisableExpired_RecordsHistory verifies expired flags are disabled, their
+// expiry is cleared and an "expired" history entry is written
+func TestRepository_DisableExpired_RecordsHistory(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		expiringFixture := testutil.CreateFlag(t, tx, tenant.ID, nil, "holiday-banner", "", true)
+		futureFixture := testutil.CreateFlag(t, tx, tenant.ID, nil, "checkout", "", true)
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+		now := time.Now()
+
+		expiring, err := repo.GetByID(ctx, expiringFixture.ID, tenant.ID)
+		require.NoError(t, err)
+		future, err := repo.GetByID(ctx, futureFixture.ID, tenant.ID)
+		require.NoError(t, err)
+
+		past := now.Add(-time.Minute)
+		expiring.ExpiresAt = &past
+		require.NoError(t, repo.Update(ctx, expiring, tenant.ID))
+		later := now.Add(time.Hour)
+		future.ExpiresAt = &later
+		require.NoError(t, repo.Update(ctx, future, tenant.ID))
+
+		disabled, err := repo.DisableExpired(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), disabled)
+
+		got, err := repo.GetByID(ctx, expiring.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.False(t, got.Enabled)
+		assert.Nil(t, got.ExpiresAt)
+
+		got, err = repo.GetByID(ctx, future.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.True(t, got.Enabled)
+
+		history, err := repo.ListHistory(ctx, expiring.ID, tenant.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, flag.HistoryActionExpired, history[0].Action)
+		assert.Nil(t, history[0].ActorID)
+	})
+}
+
+// TestRepository_EnvironmentConfigs_StayWithinFlagProject tests that a flag can only be
+// configured in environments of its own project, and that each environment keeps its own config
+func TestRepository_EnvironmentConfigs_StayWithinFlagProject(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+		project1 := testutil.CreateProject(t, tx, tenant1.ID, "Project 1", "api-key-1")
+		project2 := testutil.CreateProject(t, tx, tenant1.ID, "Project 2", "api-key-2")
+
+		createEnvironment := func(projectID, key, apiKey string) string {
+			var id string
+			require.NoError(t, tx.QueryRowx(`
+				INSERT INTO environments (tenant_id, project_id, key, name, client_api_key)
+				VALUES ($1, $2, $3, $3, $4) RETURNING id
+			`, tenant1.ID, projectID, key, apiKey).Scan(&id))
+			return id
+		}
+		staging := createEnvironment(project1.ID, "staging", "env-key-staging")
+		production := createEnvironment(project1.ID, "production", "env-key-production")
+		otherProject := createEnvironment(project2.ID, "staging", "env-key-other")
+
+		f := testutil.CreateFlag(t, tx, tenant1.ID, &project1.ID, "checkout", "", false)
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		rules := []flag.Rule{{ID: "r1", Attribute: "country", Operator: "equals", Value: "AU", Rollout: 100}}
+		require.NoError(t, repo.SetEnvironmentConfig(ctx, &flag.EnvironmentConfig{
+			FlagID: f.ID, EnvironmentID: staging, Enabled: true, Rules: rules, RuleLogic: "AND",
+		}, tenant1.ID))
+
+		config, err := repo.GetEnvironmentConfig(ctx, f.ID, staging, tenant1.ID)
+		require.NoError(t, err)
+		assert.True(t, config.Enabled)
+		assert.Equal(t, "country", config.Rules[0].Attribute)
+
+		_, err = repo.GetEnvironmentConfig(ctx, f.ID, production, tenant1.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows, "production was never configured")
+
+		// Environments of other projects and other tenants can't be targeted
+		assert.ErrorIs(t, repo.SetEnvironmentConfig(ctx, &flag.EnvironmentConfig{
+			FlagID: f.ID, EnvironmentID: otherProject, Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND",
+		}, tenant1.ID), sql.ErrNoRows)
+		assert.ErrorIs(t, repo.SetEnvironmentConfig(ctx, &flag.EnvironmentConfig{
+			FlagID: f.ID, EnvironmentID: staging, Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND",
+		}, tenant2.ID), sql.ErrNoRows)
+
+		byFlag, err := repo.ListEnvironmentConfigsByProject(ctx, project1.ID, tenant1.ID)
+		require.NoError(t, err)
+		require.Len(t, byFlag[f.ID], 1)
+		assert.Equal(t, staging, byFlag[f.ID][0].EnvironmentID)
+
+		// The flag's own config is untouched
+		stored, err := repo.GetByID(ctx, f.ID, tenant1.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.Enabled)
+	})
+}
+
+// TestRepository_ResetEnvironmentConfigs_FollowsProjectMove tests that a flag moved to another
+// project loses its old environments' configs and is configured in the new project's environments
+func TestRepository_ResetEnvironmentConfigs_FollowsProjectMove(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		project1 := testutil.CreateProject(t, tx, tenant.ID, "Project 1", "api-key-1")
+		project2 := testutil.CreateProject(t, tx, tenant.ID, "Project 2", "api-key-2")
+
+		createEnvironment := func(projectID, key, apiKey string) string {
+			var id string
+			require.NoError(t, tx.QueryRowx(`
+				INSERT INTO environments (tenant_id, project_id, key, name, client_api_key)
+				VALUES ($1, $2, $3, $3, $4) RETURNING id
+			`, tenant.ID, projectID, key, apiKey).Scan(&id))
+			return id
+		}
+		oldStaging := createEnvironment(project1.ID, "staging", "env-key-old-staging")
+		newStaging := createEnvironment(project2.ID, "staging", "env-key-new-staging")
+		newProduction := createEnvironment(project2.ID, "production", "env-key-new-production")
+
+		f := testutil.CreateFlag(t, tx, tenant.ID, &project1.ID, "checkout", "", true)
+		repo := flag.NewRepository(testutil.GetTestDB())
+		require.NoError(t, repo.SetEnvironmentConfig(ctx, &flag.EnvironmentConfig{
+			FlagID: f.ID, EnvironmentID: oldStaging, Enabled: false, Rules: []flag.Rule{}, RuleLogic: "AND",
+		}, tenant.ID))
+
+		_, err := repo.Patch(ctx, f.ID, tenant.ID, &flag.Flag{ProjectID: &project2.ID}, []string{flag.PatchFieldProjectID})
+		require.NoError(t, err)
+		require.NoError(t, repo.ResetEnvironmentConfigs(ctx, f.ID, tenant.ID))
+
+		configs, err := repo.ListEnvironmentConfigs(ctx, f.ID, tenant.ID)
+		require.NoError(t, err)
+		require.Len(t, configs, 2, "only the new project's environments are configured")
+		for _, c := range configs {
+			assert.Contains(t, []string{newStaging, newProduction}, c.EnvironmentID)
+			assert.True(t, c.Enabled, "seeded from the flag's own config")
+		}
+	})
+}
+
+// TestRepository_GetEnvironmentConfigByKey_AndRecordHistory tests resolving environments by key,
+// including ones the flag was never configured in, and appending history entries
+func TestRepository_GetEnvironmentConfigByKey_AndRecordHistory(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		user := testutil.CreateUser(t, tx, "Promoter", "promoter@example.com")
+		project := testutil.CreateProject(t, tx, tenant.ID, "Project 1", "api-key-1")
+
+		var staging string
+		require.NoError(t, tx.QueryRowx(`
+			INSERT INTO environments (tenant_id, project_id, key, name, client_api_key)
+			VALUES ($1, $2, 'staging', 'Staging', 'env-key-staging') RETURNING id
+		`, tenant.ID, project.ID).Scan(&staging))
+
+		f := testutil.CreateFlag(t, tx, tenant.ID, &project.ID, "checkout", "", false)
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		id, config, err := repo.GetEnvironmentConfigByKey(ctx, f.ID, "staging", tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, staging, id)
+		assert.Nil(t, config, "never configured")
+
+		require.NoError(t, repo.SetEnvironmentConfig(ctx, &flag.EnvironmentConfig{
+			FlagID: f.ID, EnvironmentID: staging, Enabled: true, Rules: []flag.Rule{}, RuleLogic: "AND",
+		}, tenant.ID))
+
+		_, config, err = repo.GetEnvironmentConfigByKey(ctx, f.ID, "staging", tenant.ID)
+		require.NoError(t, err)
+		require.NotNil(t, config)
+		assert.True(t, config.Enabled)
+
+		_, _, err = repo.GetEnvironmentConfigByKey(ctx, f.ID, "production", tenant.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		require.NoError(t, repo.RecordHistory(ctx, &flag.HistoryEntry{
+			FlagID:  f.ID,
+			Action:  flag.HistoryActionPromoted,
+			ActorID: &user.ID,
+			Details: map[string]interface{}{"from": "staging", "to": "production"},
+		}, tenant.ID))
+
+		history, err := repo.ListHistory(ctx, f.ID, tenant.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, flag.HistoryActionPromoted, history[0].Action)
+		assert.Equal(t, "staging", history[0].Details["from"])
+	})
+}
+
+func TestRepository_RegisteredAttributes_UpsertAndTenantScope(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+		project1 := testutil.CreateProject(t, tx, tenant1.ID, "Project 1", "api-key-1")
+
+		repo := flag.NewRepository(testutil.GetTestDB())
+
+		seats := &flag.RegisteredAttribute{ProjectID: project1.ID, Name: "seats", ContextKind: flag.ContextKindOrganization, Type: flag.AttributeTypeString}
+		require.NoError(t, repo.UpsertRegisteredAttribute(ctx, seats, tenant1.ID))
+		retyped := &flag.RegisteredAttribute{ProjectID: project1.ID, Name: "seats", ContextKind: flag.ContextKindOrganization, Type: flag.AttributeTypeNumber, Description: "Paid seats"}
+		require.NoError(t, repo.UpsertRegisteredAttribute(ctx, retyped, tenant1.ID))
+		assert.Equal(t, seats.ID, retyped.ID, "registering the same name and kind replaces the type")
+
+		// Another tenant can't register attributes in the project
+		assert.ErrorIs(t, repo.UpsertRegisteredAttribute(ctx, &flag.RegisteredAttribute{
+			ProjectID: project1.ID, Name: "plan", ContextKind: flag.ContextKindUser, Type: flag.AttributeTypeString,
+		}, tenant2.ID), sql.ErrNoRows)
+
+		attributes, err := repo.ListRegisteredAttributes(ctx, project1.ID, tenant1.ID)
+		require.NoError(t, err)
+		require.Len(t, attributes, 1)
+		assert.Equal(t, flag.AttributeTypeNumber, attributes[0].Type)
+		assert.Equal(t, "Paid seats", attributes[0].Description)
+
+		assert.ErrorIs(t, repo.DeleteRegisteredAttribute(ctx, seats.ID, project1.ID, tenant2.ID), sql.ErrNoRows)
+		require.NoError(t, repo.DeleteRegisteredAttribute(ctx, seats.ID, project1.ID, tenant1.ID))
+
+		attributes, err = repo.ListRegisteredAttributes(ctx, project1.ID, tenant1.ID)
+		require.NoError(t, err)
+		assert.Empty(t, attributes)
+	})
+}
@@ -35,6 +35,7 @@ func TestRepositoryCreate(t *testing.T) {
 				Enabled:     false,
 				Rules:       []Rule{},
 				RuleLogic:   "AND",
+				Lifecycle:   "active",
 				ProjectID:   stringPtr("test-project-id"),
 			},
 			mockFn: func() {
@@ -44,11 +45,15 @@ func TestRepositoryCreate(t *testing.T) {
 					WithArgs(
 						"test-tenant-id",
 						stringPtr("test-project-id"),
+						nil,
 						"test-flag",
 						"test description",
 						false,
 						sqlmock.AnyArg(),
 						"AND",
+						nil,
+						nil,
+						"active",
 					).
 					WillReturnRows(rows)
 			},
@@ -63,6 +68,7 @@ func TestRepositoryCreate(t *testing.T) {
 				Enabled:     false,
 				Rules:       []Rule{},
 				RuleLogic:   "AND",
+				Lifecycle:   "active",
 				ProjectID:   stringPtr("test-project-id"),
 			},
 			mockFn: func() {
@@ -128,9 +134,9 @@ func TestRepositoryGetByID(t *testing.T) {
 			name: "successful get",
 			id:   "test-id",
 			mockFn: func() {
-				rows := sqlmock.NewRows([]string{"id", "tenant_id", "project_id", "name", "description", "enabled", "rules", "rule_logic", "created_at", "updated_at"}).
-					AddRow("test-id", "test-tenant-id", "test-project-id", "test-flag", "test description", false, rulesJSON, "AND", now, now)
-				mock.ExpectQuery("SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic").
+				rows := sqlmock.NewRows([]string{"id", "tenant_id", "project_id", "owner_user_id", "name", "description", "enabled", "rules", "rule_logic", "expires_at", "key", "lifecycle", "archived_at", "salt", "created_at", "updated_at"}).
+					AddRow("test-id", "test-tenant-id", "test-project-id", nil, "test-flag", "test description", false, rulesJSON, "AND", nil, nil, "active", nil, "", now, now)
+				mock.ExpectQuery("SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic").
 					WithArgs("test-id", "test-tenant-id").
 					WillReturnRows(rows)
 			},
@@ -150,7 +156,7 @@ func TestRepositoryGetByID(t *testing.T) {
 			name: "not found",
 			id:   "non-existent",
 			mockFn: func() {
-				mock.ExpectQuery("SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic").
+				mock.ExpectQuery("SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic").
 					WithArgs("non-existent", "test-tenant-id").
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -161,7 +167,7 @@ func TestRepositoryGetByID(t *testing.T) {
 			name: "database error",
 			id:   "test-id",
 			mockFn: func() {
-				mock.ExpectQuery("SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic").
+				mock.ExpectQuery("SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic").
 					WithArgs("test-id", "test-tenant-id").
 					WillReturnError(sql.ErrConnDone)
 			},
@@ -217,10 +223,10 @@ func TestRepositoryList(t *testing.T) {
 		{
 			name: "successful list",
 			mockFn: func() {
-				rows := sqlmock.NewRows([]string{"id", "tenant_id", "project_id", "name", "description", "enabled", "rules", "rule_logic", "created_at", "updated_at"}).
-					AddRow("id1", "test-tenant-id", "test-project-id", "flag1", "desc1", true, rulesJSON, "AND", now, now).
-					AddRow("id2", "test-tenant-id", "test-project-id", "flag2", "desc2", false, rulesJSON, "AND", now, now)
-				mock.ExpectQuery("SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic").
+				rows := sqlmock.NewRows([]string{"id", "tenant_id", "project_id", "owner_user_id", "name", "description", "enabled", "rules", "rule_logic", "expires_at", "key", "lifecycle", "archived_at", "salt", "created_at", "updated_at"}).
+					AddRow("id1", "test-tenant-id", "test-project-id", nil, "flag1", "desc1", true, rulesJSON, "AND", nil, nil, "active", nil, "", now, now).
+					AddRow("id2", "test-tenant-id", "test-project-id", nil, "flag2", "desc2", false, rulesJSON, "AND", nil, nil, "active", nil, "", now, now)
+				mock.ExpectQuery("SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic").
 					WithArgs("test-tenant-id").
 					WillReturnRows(rows)
 			},
@@ -230,8 +236,8 @@ func TestRepositoryList(t *testing.T) {
 		{
 			name: "empty list",
 			mockFn: func() {
-				rows := sqlmock.NewRows([]string{"id", "tenant_id", "project_id", "name", "description", "enabled", "rules", "rule_logic", "created_at", "updated_at"})
-				mock.ExpectQuery("SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic").
+				rows := sqlmock.NewRows([]string{"id", "tenant_id", "project_id", "owner_user_id", "name", "description", "enabled", "rules", "rule_logic", "expires_at", "key", "lifecycle", "archived_at", "salt", "created_at", "updated_at"})
+				mock.ExpectQuery("SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic").
 					WithArgs("test-tenant-id").
 					WillReturnRows(rows)
 			},
@@ -241,7 +247,7 @@ func TestRepositoryList(t *testing.T) {
 		{
 			name: "database error",
 			mockFn: func() {
-				mock.ExpectQuery("SELECT id, tenant_id, project_id, name, description, enabled, rules, rule_logic").
+				mock.ExpectQuery("SELECT id, tenant_id, project_id, owner_user_id, name, description, enabled, rules, rule_logic").
 					WithArgs("test-tenant-id").
 					WillReturnError(sql.ErrConnDone)
 			},
@@ -298,6 +304,7 @@ func TestRepositoryUpdate(t *testing.T) {
 				Enabled:     true,
 				Rules:       []Rule{},
 				RuleLogic:   "AND",
+				Lifecycle:   "active",
 				ProjectID:   stringPtr("test-project-id"),
 			},
 			mockFn: func() {
@@ -312,6 +319,9 @@ func TestRepositoryUpdate(t *testing.T) {
 						stringPtr("test-project-id"),
 						sqlmock.AnyArg(),
 						"test-tenant-id",
+						nil,
+						nil,
+						"active",
 					).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 			},
@@ -326,6 +336,7 @@ func TestRepositoryUpdate(t *testing.T) {
 				Enabled:     false,
 				Rules:       []Rule{},
 				RuleLogic:   "AND",
+				Lifecycle:   "active",
 				ProjectID:   stringPtr("test-project-id"),
 			},
 			mockFn: func() {
@@ -340,6 +351,9 @@ func TestRepositoryUpdate(t *testing.T) {
 						stringPtr("test-project-id"),
 						sqlmock.AnyArg(),
 						"test-tenant-id",
+						nil,
+						nil,
+						"active",
 					).
 					WillReturnResult(sqlmock.NewResult(0, 0))
 			},
@@ -354,6 +368,7 @@ func TestRepositoryUpdate(t *testing.T) {
 				Enabled:     false,
 				Rules:       []Rule{},
 				RuleLogic:   "AND",
+				Lifecycle:   "active",
 				ProjectID:   stringPtr("test-project-id"),
 			},
 			mockFn: func() {
@@ -368,6 +383,9 @@ func TestRepositoryUpdate(t *testing.T) {
 						stringPtr("test-project-id"),
 						sqlmock.AnyArg(),
 						"test-tenant-id",
+						nil,
+						nil,
+						"active",
 					).
 					WillReturnError(sql.ErrConnDone)
 			},
@@ -400,6 +418,52 @@ func TestRepositoryUpdate(t *testing.T) {
 	}
 }
 
+func TestRepositoryToggle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	repo := NewRepository(sqlxDB)
+
+	columns := []string{"id", "tenant_id", "project_id", "owner_user_id", "name", "description", "enabled", "rules", "rule_logic", "expires_at", "key", "lifecycle", "archived_at", "salt", "created_at", "updated_at"}
+
+	t.Run("flips enabled in a single update", func(t *testing.T) {
+		rows := sqlmock.NewRows(columns).
+			AddRow("test-id", "test-tenant-id", nil, nil, "test-flag", "", true, []byte("[]"), "AND", nil, nil, "active", nil, "", time.Now(), time.Now())
+		mock.ExpectQuery(`UPDATE flags\s+SET enabled = NOT enabled`).
+			WithArgs("test-id", "test-tenant-id").
+			WillReturnRows(rows)
+
+		f, err := repo.Toggle(context.Background(), "test-id", "test-tenant-id")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !f.Enabled {
+			t.Error("expected the updated flag to be enabled")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE flags\s+SET enabled = NOT enabled`).
+			WithArgs("non-existent", "test-tenant-id").
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.Toggle(context.Background(), "non-existent", "test-tenant-id")
+		if err != sql.ErrNoRows {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+	})
+}
+
 func TestRepositoryDelete(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
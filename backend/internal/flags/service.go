@@ -2,31 +2,158 @@ package flag
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/jalil32/toggle/internal/encryption"
 	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
+	"github.com/jalil32/toggle/internal/pkg/transaction"
 	"github.com/jalil32/toggle/internal/pkg/validator"
 )
 
 var (
-	ErrFlagNotFound    = errors.New("flag not found")
-	ErrInvalidFlagData = errors.New("invalid flag data")
+	ErrFlagNotFound     = errors.New("flag not found")
+	ErrInvalidFlagData  = errors.New("invalid flag data")
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrDuplicateName    = errors.New("a flag with this name already exists in the project")
+	ErrApprovalRequired = errors.New("the project requires changes to this flag to go through an approved change request")
+)
+
+// projectNameConstraint is the unique index on (project_id, name)
+const projectNameConstraint = "idx_flags_project_name"
+
+// Bounds for the stale flag window, in days
+const (
+	DefaultStaleDays = 30
+	MaxStaleDays     = 3650
+)
+
+// DefaultAttributeWindowDays is how far back attribute usage counts as "sent" in attribute reports
+const DefaultAttributeWindowDays = 7
+
+// Limits for exclusion edits
+const (
+	MaxExclusionsPerRequest = 1000
+	MaxUserKeyLength        = 255
+)
+
+// Limits for custom fields
+const (
+	MaxCustomFieldValueLength = 255
+	MaxCustomFieldsPerFlag    = 50
 )
 
+// customFieldNamePattern allows short lowercase names such as "service" or "jira_ticket"
+var customFieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+
+// MaxOverrideDuration is the longest a temporary override may stay in effect
+const MaxOverrideDuration = 30 * 24 * time.Hour
+
+// TemplateSource fills a new flag's unset fields from a stored template
+// Implemented by the templates package (which imports this one)
+type TemplateSource interface {
+	ApplyTemplate(ctx context.Context, templateID string, tenantID string, f *Flag) error
+}
+
 type Service interface {
 	Create(ctx context.Context, f *Flag, tenantID string) error
 	GetByID(ctx context.Context, id string, tenantID string) (*Flag, error)
 	List(ctx context.Context, tenantID string) ([]Flag, error)
+	ListByOwner(ctx context.Context, tenantID string, ownerUserID string) ([]Flag, error)
+	ListByProject(ctx context.Context, projectID string, tenantID string) ([]Flag, error)
+	AttachStats(ctx context.Context, flags []Flag, tenantID string) error
+	Metrics(ctx context.Context, id string, tenantID string) (*FlagMetrics, error)
 	Update(ctx context.Context, f *Flag, tenantID string) error
+	Patch(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error)
+	Toggle(ctx context.Context, id string, actorID string, tenantID string) (*Flag, error)
+	Reshuffle(ctx context.Context, id string, actorID string, tenantID string) (*Flag, error)
 	Delete(ctx context.Context, id string, tenantID string) error
+	Clone(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error)
+	CreateFromTemplate(ctx context.Context, f *Flag, templateID string, tenantID string) error
+	ListStale(ctx context.Context, tenantID string, days int) ([]StaleFlag, error)
+	AttributeReport(ctx context.Context, projectID string, tenantID string, days int) (*AttributeReport, error)
+	RenameAttribute(ctx context.Context, projectID string, req RenameAttributeRequest, actorID string, tenantID string) (*AttributeRename, error)
+	ListAttributes(ctx context.Context, projectID string, tenantID string) ([]RegisteredAttribute, error)
+	RegisterAttribute(ctx context.Context, projectID string, req RegisterAttributeRequest, tenantID string) (*RegisteredAttribute, error)
+	DeleteAttribute(ctx context.Context, projectID string, attributeID string, tenantID string) error
+	ListExclusions(ctx context.Context, id string, tenantID string) ([]Exclusion, error)
+	AddExclusions(ctx context.Context, id string, userKeys []string, reason string, tenantID string) ([]Exclusion, error)
+	RemoveExclusion(ctx context.Context, id string, userKey string, tenantID string) error
+	ListCustomFields(ctx context.Context, id string, tenantID string) ([]CustomField, error)
+	SetCustomField(ctx context.Context, id string, name string, value string, tenantID string) (*CustomField, error)
+	DeleteCustomField(ctx context.Context, id string, name string, tenantID string) error
+	ListEnvironmentConfigs(ctx context.Context, id string, tenantID string) ([]EnvironmentConfig, error)
+	SetEnvironmentConfig(ctx context.Context, id string, environmentID string, req SetEnvironmentConfigRequest, tenantID string) (*EnvironmentConfig, error)
+	DiffEnvironments(ctx context.Context, id string, from string, to string, tenantID string) (*EnvironmentDiff, error)
+	Promote(ctx context.Context, id string, req PromoteRequest, actorID string, tenantID string) (*EnvironmentDiff, error)
+	CreateOverride(ctx context.Context, o *Override, tenantID string) error
+	ListOverrides(ctx context.Context, id string, tenantID string) ([]Override, error)
+	DeleteOverride(ctx context.Context, id string, userKey string, tenantID string) error
+	ExpireOverrides(ctx context.Context) error
+	DisableExpired(ctx context.Context) error
+	PruneResultCounts(ctx context.Context) error
+	ListHistory(ctx context.Context, id string, tenantID string) ([]HistoryEntry, error)
+	Export(ctx context.Context, projectID string, tenantID string) (*ExportDocument, error)
+	ExportSealed(ctx context.Context, projectID string, tenantID string) (*ExportDocument, error)
+	Import(ctx context.Context, projectID string, tenantID string, doc *ExportDocument, opts ImportOptions) (*ImportResult, error)
+	ExportUnleash(ctx context.Context, projectID string, tenantID string) (*UnleashExport, []string, error)
+	ImportUnleash(ctx context.Context, projectID string, tenantID string, doc *UnleashExport, opts ImportOptions) (*ImportResult, error)
+	Apply(ctx context.Context, projectID string, key string, def ExportedFlag, tenantID string) (*ApplyResult, error)
+	SetTemplateSource(templates TemplateSource)
+	SetUnitOfWork(uow transaction.UnitOfWork)
+	SetSealer(sealer Sealer)
+	SetFlagCache(cache FlagCache)
+	SetApprovalGate(gate ApprovalGate)
+
+	// ApplyApprovedChange and ApplyApprovedEnvironmentChange skip the approval policy, and are
+	// only called by the changes package for changes that have the approvals it requires
+	ApplyApprovedChange(ctx context.Context, f *Flag, tenantID string) error
+	ApplyApprovedEnvironmentChange(ctx context.Context, id string, environment string, changes UpdateRequest, tenantID string) (*EnvironmentConfig, error)
+}
+
+// FlagCache caches flags for evaluation and is told when a tenant's flags change
+// Implemented by evaluation.SnapshotCache (which imports this package)
+type FlagCache interface {
+	InvalidateTenant(tenantID string)
+}
+
+// ApprovalGate tells whether a project's approval policy requires edits to a flag's config in
+// an environment, or to the flag's own config when environmentID is empty, to go through an
+// approved change request
+// Implemented by changes.Service (which imports this package)
+type ApprovalGate interface {
+	RequiresApproval(ctx context.Context, projectID string, environmentID string, tenantID string) (bool, error)
+}
+
+// Sealer encrypts values with a tenant's data key (implemented by encryption.Keyring)
+type Sealer interface {
+	Seal(ctx context.Context, tenantID string, plaintext []byte) (string, error)
+	Open(ctx context.Context, tenantID string, value string) ([]byte, error)
 }
 
 type service struct {
 	repo      Repository
 	validator validator.Validator
+	templates TemplateSource
+	uow       transaction.UnitOfWork
+	sealer    Sealer
+	cache     FlagCache
+	approvals ApprovalGate
 	logger    *slog.Logger
 }
 
@@ -53,9 +180,31 @@ func (s *service) Create(ctx context.Context, f *Flag, tenantID string) error {
 		return err
 	}
 
+	if err := s.sanitizeFlag(f); err != nil {
+		s.logger.Warn("flag sanitization failed",
+			slog.String("name", f.Name),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
 	// Set tenant ID
 	f.TenantID = tenantID
 
+	// Every stored flag has rules and a rule logic, whichever path created it
+	if f.Rules == nil {
+		f.Rules = []Rule{}
+	}
+	if f.RuleLogic == "" {
+		f.RuleLogic = RuleLogicAnd
+	}
+	if f.Lifecycle == "" {
+		f.Lifecycle = LifecycleActive
+	}
+	if f.Lifecycle != LifecycleDraft && f.Lifecycle != LifecycleActive {
+		return fmt.Errorf("%w: new flags must be draft or active", ErrInvalidFlagData)
+	}
+
 	// Validate project ownership ONLY if project_id is provided
 	if f.ProjectID != nil && *f.ProjectID != "" {
 		if err := s.validator.ValidateProjectOwnership(ctx, *f.ProjectID, tenantID); err != nil {
@@ -68,7 +217,18 @@ func (s *service) Create(ctx context.Context, f *Flag, tenantID string) error {
 		}
 	}
 
+	if err := s.checkRegisteredTypes(ctx, f.ProjectID, f.Rules, tenantID); err != nil {
+		return err
+	}
+
+	if err := s.validateOwner(ctx, f, tenantID); err != nil {
+		return err
+	}
+
 	if err := s.repo.Create(ctx, f); err != nil {
+		if dupErr := duplicateName(err, f); dupErr != nil {
+			return dupErr
+		}
 		projectID := "none"
 		if f.ProjectID != nil {
 			projectID = *f.ProjectID
@@ -85,6 +245,7 @@ func (s *service) Create(ctx context.Context, f *Flag, tenantID string) error {
 	if f.ProjectID != nil {
 		projectID = *f.ProjectID
 	}
+	s.invalidateCache(tenantID)
 	s.logger.Info("flag created",
 		slog.String("id", f.ID),
 		slog.String("name", f.Name),
@@ -95,6 +256,100 @@ func (s *service) Create(ctx context.Context, f *Flag, tenantID string) error {
 	return nil
 }
 
+// SetTemplateSource sets the template source (called after initialization to avoid circular dependency)
+func (s *service) SetTemplateSource(templates TemplateSource) {
+	s.templates = templates
+}
+
+// SetUnitOfWork sets the unit of work used by multi-flag operations such as Import
+func (s *service) SetUnitOfWork(uow transaction.UnitOfWork) {
+	s.uow = uow
+}
+
+// SetSealer sets the sealer used for encrypted exports
+func (s *service) SetSealer(sealer Sealer) {
+	s.sealer = sealer
+}
+
+// SetFlagCache sets the evaluation cache told about flag changes. Changes made through
+// other instances or packages are picked up when the cache next revalidates.
+func (s *service) SetFlagCache(cache FlagCache) {
+	s.cache = cache
+}
+
+// SetApprovalGate sets the approval policy direct edits are checked against. Without one,
+// every edit is allowed.
+func (s *service) SetApprovalGate(gate ApprovalGate) {
+	s.approvals = gate
+}
+
+// checkApproval returns ErrApprovalRequired when the project's approval policy requires edits
+// to a flag's config in the environment, or to its own config when environmentID is empty, to
+// go through a change request. Flags outside a project have no policy.
+func (s *service) checkApproval(ctx context.Context, projectID *string, environmentID string, tenantID string) error {
+	if s.approvals == nil || projectID == nil || *projectID == "" {
+		return nil
+	}
+	required, err := s.approvals.RequiresApproval(ctx, *projectID, environmentID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to check approval policy: %w", err)
+	}
+	if required {
+		return ErrApprovalRequired
+	}
+	return nil
+}
+
+// checkFlagApproval is checkApproval for the project of the flag with the given ID
+func (s *service) checkFlagApproval(ctx context.Context, id string, environmentID string, tenantID string) error {
+	if s.approvals == nil {
+		return nil
+	}
+	f, err := s.repo.GetByID(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		return fmt.Errorf("failed to get flag: %w", err)
+	}
+	return s.checkApproval(ctx, f.ProjectID, environmentID, tenantID)
+}
+
+// invalidateCache makes cached evaluations of the tenant's flags revalidate before they are served again
+func (s *service) invalidateCache(tenantID string) {
+	if s.cache != nil {
+		s.cache.InvalidateTenant(tenantID)
+	}
+}
+
+// CreateFromTemplate creates a flag whose unset description, rules and rule logic come from a template
+func (s *service) CreateFromTemplate(ctx context.Context, f *Flag, templateID string, tenantID string) error {
+	if f == nil {
+		return ErrInvalidFlagData
+	}
+	if s.templates == nil {
+		return ErrTemplateNotFound
+	}
+
+	if err := s.templates.ApplyTemplate(ctx, templateID, tenantID, f); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			s.logger.Debug("template not found or forbidden",
+				slog.String("template_id", templateID),
+				slog.String("tenant_id", tenantID),
+			)
+			return ErrTemplateNotFound
+		}
+		s.logger.Error("failed to apply template",
+			slog.String("template_id", templateID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to apply template: %w", err)
+	}
+
+	return s.Create(ctx, f, tenantID)
+}
+
 func (s *service) GetByID(ctx context.Context, id string, tenantID string) (*Flag, error) {
 	if id == "" {
 		return nil, ErrInvalidFlagData
@@ -137,116 +392,2380 @@ func (s *service) List(ctx context.Context, tenantID string) ([]Flag, error) {
 	return flags, nil
 }
 
-func (s *service) Update(ctx context.Context, f *Flag, tenantID string) error {
-	if err := s.validateFlag(f); err != nil {
-		if f != nil {
-			s.logger.Warn("flag validation failed on update",
-				slog.String("id", f.ID),
-				slog.String("error", err.Error()),
-			)
-		} else {
-			s.logger.Warn("flag validation failed on update: nil flag",
-				slog.String("error", err.Error()),
-			)
-		}
-		return err
+// ListByOwner returns the tenant's flags owned by a user
+func (s *service) ListByOwner(ctx context.Context, tenantID string, ownerUserID string) ([]Flag, error) {
+	if _, err := uuid.Parse(ownerUserID); err != nil {
+		return nil, fmt.Errorf("%w: owner must be a user ID or \"me\"", ErrInvalidFlagData)
 	}
 
-	if f.ID == "" {
-		return ErrInvalidFlagData
+	flags, err := s.repo.ListByOwner(ctx, tenantID, ownerUserID)
+	if err != nil {
+		s.logger.Error("failed to list flags by owner",
+			slog.String("tenant_id", tenantID),
+			slog.String("owner_user_id", ownerUserID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list flags: %w", err)
 	}
 
-	// Validate project ownership if project_id is being set/changed
-	if f.ProjectID != nil && *f.ProjectID != "" {
-		if err := s.validator.ValidateProjectOwnership(ctx, *f.ProjectID, tenantID); err != nil {
-			s.logger.Warn("project ownership validation failed on update",
-				slog.String("flag_id", f.ID),
-				slog.String("project_id", *f.ProjectID),
-				slog.String("tenant_id", tenantID),
-			)
-			return pkgErrors.ErrProjectNotInTenant
-		}
+	if flags == nil {
+		return []Flag{}, nil
 	}
 
-	if err := s.repo.Update(ctx, f, tenantID); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			s.logger.Debug("flag not found or forbidden on update",
-				slog.String("id", f.ID),
-				slog.String("tenant_id", tenantID),
-			)
-			return pkgErrors.ErrNotFound
-		}
-		s.logger.Error("failed to update flag",
-			slog.String("id", f.ID),
+	return flags, nil
+}
+
+// ListByProject returns the flags in one of the tenant's projects
+func (s *service) ListByProject(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed",
+			slog.String("project_id", projectID),
 			slog.String("tenant_id", tenantID),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("failed to update flag: %w", err)
+		return nil, pkgErrors.ErrProjectNotInTenant
 	}
 
-	s.logger.Info("flag updated",
-		slog.String("id", f.ID),
-		slog.String("name", f.Name),
-		slog.String("tenant_id", tenantID),
-	)
+	flags, err := s.repo.ListByProject(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list flags by project",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list flags: %w", err)
+	}
 
-	return nil
+	if flags == nil {
+		return []Flag{}, nil
+	}
+
+	return flags, nil
 }
 
-func (s *service) Delete(ctx context.Context, id string, tenantID string) error {
-	if id == "" {
-		return ErrInvalidFlagData
+// ListStale returns flags that haven't been evaluated or modified in the last `days` days
+func (s *service) ListStale(ctx context.Context, tenantID string, days int) ([]StaleFlag, error) {
+	if days < 1 || days > MaxStaleDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidFlagData, MaxStaleDays)
 	}
 
-	if err := s.repo.Delete(ctx, id, tenantID); err != nil {
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	flags, err := s.repo.ListStale(ctx, tenantID, cutoff)
+	if err != nil {
+		s.logger.Error("failed to list stale flags",
+			slog.String("tenant_id", tenantID),
+			slog.Int("days", days),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list stale flags: %w", err)
+	}
+
+	if flags == nil {
+		return []StaleFlag{}, nil
+	}
+
+	return flags, nil
+}
+
+// ListAttributes returns the project's attribute registry
+func (s *service) ListAttributes(ctx context.Context, projectID string, tenantID string) ([]RegisteredAttribute, error) {
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	attributes, err := s.repo.ListRegisteredAttributes(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list registered attributes",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list attributes: %w", err)
+	}
+
+	return attributes, nil
+}
+
+// RegisterAttribute adds an attribute to the project's registry, or changes the type and
+// description of one already registered. Rules saved before a type change aren't revalidated.
+func (s *service) RegisterAttribute(ctx context.Context, projectID string, req RegisterAttributeRequest, tenantID string) (*RegisteredAttribute, error) {
+	attribute := &RegisteredAttribute{
+		ProjectID:   projectID,
+		Name:        strings.TrimSpace(req.Name),
+		ContextKind: req.ContextKind,
+		Type:        req.Type,
+		Description: strings.TrimSpace(req.Description),
+	}
+	if attribute.ContextKind == "" {
+		attribute.ContextKind = ContextKindUser
+	}
+	if attribute.Name == "" || len(attribute.Name) > MaxAttributeNameLength {
+		return nil, fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidFlagData, MaxAttributeNameLength)
+	}
+	if !slices.Contains(ContextKinds, attribute.ContextKind) {
+		return nil, fmt.Errorf("%w: context_kind must be one of %s", ErrInvalidFlagData, strings.Join(ContextKinds, ", "))
+	}
+	if !slices.Contains(AttributeTypes, attribute.Type) {
+		return nil, fmt.Errorf("%w: type must be one of %s", ErrInvalidFlagData, strings.Join(AttributeTypes, ", "))
+	}
+
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	if err := s.repo.UpsertRegisteredAttribute(ctx, attribute, tenantID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			s.logger.Debug("flag not found or forbidden on delete",
-				slog.String("id", id),
-				slog.String("tenant_id", tenantID),
-			)
-			return pkgErrors.ErrNotFound
+			return nil, pkgErrors.ErrProjectNotInTenant
 		}
-		s.logger.Error("failed to delete flag",
-			slog.String("id", id),
-			slog.String("tenant_id", tenantID),
+		s.logger.Error("failed to register attribute",
+			slog.String("project_id", projectID),
+			slog.String("name", attribute.Name),
 			slog.String("error", err.Error()),
 		)
-		return fmt.Errorf("failed to delete flag: %w", err)
+		return nil, fmt.Errorf("failed to register attribute: %w", err)
 	}
 
-	s.logger.Info("flag deleted",
-		slog.String("id", id),
+	s.logger.Info("attribute registered",
+		slog.String("project_id", projectID),
+		slog.String("name", attribute.Name),
+		slog.String("type", attribute.Type),
 		slog.String("tenant_id", tenantID),
 	)
 
+	return attribute, nil
+}
+
+// DeleteAttribute removes an attribute from the project's registry; rules on it are no longer type checked
+func (s *service) DeleteAttribute(ctx context.Context, projectID string, attributeID string, tenantID string) error {
+	if err := s.repo.DeleteRegisteredAttribute(ctx, attributeID, projectID, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to delete registered attribute",
+			slog.String("project_id", projectID),
+			slog.String("attribute_id", attributeID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to delete attribute: %w", err)
+	}
+
 	return nil
 }
 
-func (s *service) validateFlag(f *Flag) error {
-	if f == nil {
-		return ErrInvalidFlagData
+// checkRegisteredTypes checks rules against the attribute registry of the project, if any
+func (s *service) checkRegisteredTypes(ctx context.Context, projectID *string, rules []Rule, tenantID string) error {
+	if projectID == nil || *projectID == "" || len(rules) == 0 {
+		return nil
 	}
 
-	if f.Name == "" {
-		return fmt.Errorf("%w: name is required", ErrInvalidFlagData)
+	registry, err := s.repo.ListRegisteredAttributes(ctx, *projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to load attribute registry",
+			slog.String("project_id", *projectID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to load attribute registry: %w", err)
 	}
 
-	return nil
+	return CheckRegisteredTypes(rules, registry)
 }
 
-type CreateRequest struct {
-	ProjectID   *string `json:"project_id,omitempty"`
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Rules       []Rule  `json:"rules"`
-	RuleLogic   string  `json:"rule_logic"`
+// AttributeReport compares the attributes the project's rules reference with those SDKs sent in the last `days` days
+func (s *service) AttributeReport(ctx context.Context, projectID string, tenantID string, days int) (*AttributeReport, error) {
+	if days < 1 || days > MaxStaleDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidFlagData, MaxStaleDays)
+	}
+
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	flags, err := s.repo.ListByProject(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list flags for attribute report",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to build attribute report: %w", err)
+	}
+
+	seen, err := s.repo.ListSeenAttributes(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list seen attributes",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to build attribute report: %w", err)
+	}
+
+	return buildAttributeReport(projectID, days, flags, seen, time.Now().AddDate(0, 0, -days)), nil
 }
 
-type UpdateRequest struct {
-	ProjectID   *string `json:"project_id,omitempty"`
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
-	Enabled     *bool   `json:"enabled"`
-	Rules       []Rule  `json:"rules"`
-	RuleLogic   *string `json:"rule_logic"`
+// buildAttributeReport groups attributes by whether rules reference them and whether they were seen since cutoff
+func buildAttributeReport(projectID string, days int, flags []Flag, seen map[string]time.Time, cutoff time.Time) *AttributeReport {
+	referenced := make(map[string][]string)
+	for _, f := range flags {
+		for _, rule := range f.Rules {
+			// Time window rules match on the evaluation time, not an attribute
+			if rule.Operator == OperatorTimeWindow {
+				continue
+			}
+			attribute := rule.QualifiedAttribute()
+			ids := referenced[attribute]
+			// A flag with several rules on one attribute is listed once
+			if len(ids) == 0 || ids[len(ids)-1] != f.ID {
+				referenced[attribute] = append(ids, f.ID)
+			}
+		}
+	}
+
+	report := &AttributeReport{
+		ProjectID:    projectID,
+		WindowDays:   days,
+		Unsent:       []AttributeUsage{},
+		Unreferenced: []AttributeUsage{},
+		InUse:        []AttributeUsage{},
+	}
+
+	for attribute, flagIDs := range referenced {
+		usage := AttributeUsage{Attribute: attribute, FlagIDs: flagIDs}
+		if lastSeen, ok := seen[attribute]; ok {
+			usage.LastSeenAt = &lastSeen
+			if !lastSeen.Before(cutoff) {
+				report.InUse = append(report.InUse, usage)
+				continue
+			}
+		}
+		report.Unsent = append(report.Unsent, usage)
+	}
+
+	for attribute, lastSeen := range seen {
+		if _, ok := referenced[attribute]; ok || lastSeen.Before(cutoff) {
+			continue
+		}
+		lastSeen := lastSeen
+		report.Unreferenced = append(report.Unreferenced, AttributeUsage{
+			Attribute:  attribute,
+			FlagIDs:    []string{},
+			LastSeenAt: &lastSeen,
+		})
+	}
+
+	for _, list := range [][]AttributeUsage{report.Unsent, report.Unreferenced, report.InUse} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Attribute < list[j].Attribute })
+	}
+
+	return report
+}
+
+// Update replaces a flag's fields. Edits to a flag whose project requires approval, or
+// moving a flag into such a project, are refused with ErrApprovalRequired.
+func (s *service) Update(ctx context.Context, f *Flag, tenantID string) error {
+	return s.update(ctx, f, tenantID, true)
+}
+
+// ApplyApprovedChange updates a flag like Update, without checking its project's approval policy
+func (s *service) ApplyApprovedChange(ctx context.Context, f *Flag, tenantID string) error {
+	return s.update(ctx, f, tenantID, false)
+}
+
+func (s *service) update(ctx context.Context, f *Flag, tenantID string, gated bool) error {
+	if err := s.validateFlag(f); err != nil {
+		if f != nil {
+			s.logger.Warn("flag validation failed on update",
+				slog.String("id", f.ID),
+				slog.String("error", err.Error()),
+			)
+		} else {
+			s.logger.Warn("flag validation failed on update: nil flag",
+				slog.String("error", err.Error()),
+			)
+		}
+		return err
+	}
+
+	if f.ID == "" {
+		return ErrInvalidFlagData
+	}
+
+	if err := s.sanitizeFlag(f); err != nil {
+		s.logger.Warn("flag sanitization failed on update",
+			slog.String("id", f.ID),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	// Validate project ownership if project_id is being set/changed
+	if f.ProjectID != nil && *f.ProjectID != "" {
+		if err := s.validator.ValidateProjectOwnership(ctx, *f.ProjectID, tenantID); err != nil {
+			s.logger.Warn("project ownership validation failed on update",
+				slog.String("flag_id", f.ID),
+				slog.String("project_id", *f.ProjectID),
+				slog.String("tenant_id", tenantID),
+			)
+			return pkgErrors.ErrProjectNotInTenant
+		}
+	}
+
+	if err := s.checkRegisteredTypes(ctx, f.ProjectID, f.Rules, tenantID); err != nil {
+		return err
+	}
+
+	if err := s.validateOwner(ctx, f, tenantID); err != nil {
+		return err
+	}
+
+	current, err := s.repo.GetByID(ctx, f.ID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		return fmt.Errorf("failed to get flag: %w", err)
+	}
+
+	if err := validateLifecycleChange(f, current); err != nil {
+		return err
+	}
+
+	if gated {
+		if err := s.checkApproval(ctx, current.ProjectID, "", tenantID); err != nil {
+			return err
+		}
+		if movedProject(current.ProjectID, f.ProjectID) {
+			if err := s.checkApproval(ctx, f.ProjectID, "", tenantID); err != nil {
+				return err
+			}
+		}
+	}
+
+	write := func(ctx context.Context) error {
+		if err := s.repo.Update(ctx, f, tenantID); err != nil {
+			return err
+		}
+		if movedProject(current.ProjectID, f.ProjectID) {
+			return s.repo.ResetEnvironmentConfigs(ctx, f.ID, tenantID)
+		}
+		return nil
+	}
+	if s.uow != nil {
+		err = s.uow.RunInTransaction(ctx, write)
+	} else {
+		err = write(ctx)
+	}
+	if err != nil {
+		if dupErr := duplicateName(err, f); dupErr != nil {
+			return dupErr
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag not found or forbidden on update",
+				slog.String("id", f.ID),
+				slog.String("tenant_id", tenantID),
+			)
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to update flag",
+			slog.String("id", f.ID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to update flag: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag updated",
+		slog.String("id", f.ID),
+		slog.String("name", f.Name),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// Patch updates only the fields named in the request's mask, writing nothing else, so
+// concurrent patches to different fields don't overwrite each other
+func (s *service) Patch(ctx context.Context, id string, req PatchRequest, tenantID string) (*Flag, error) {
+	if id == "" {
+		return nil, ErrInvalidFlagData
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	f := req.Flag()
+	if req.Has(PatchFieldDescription) {
+		if err := s.sanitizeFlag(f); err != nil {
+			return nil, err
+		}
+	}
+	if req.Has(PatchFieldProjectID) {
+		if err := s.validator.ValidateProjectOwnership(ctx, *f.ProjectID, tenantID); err != nil {
+			s.logger.Warn("project ownership validation failed on patch",
+				slog.String("flag_id", id),
+				slog.String("project_id", *f.ProjectID),
+				slog.String("tenant_id", tenantID),
+			)
+			return nil, pkgErrors.ErrProjectNotInTenant
+		}
+	}
+	var current *Flag
+	if req.Has(PatchFieldRules) || req.Has(PatchFieldProjectID) || s.approvals != nil {
+		var err error
+		current, err = s.repo.GetByID(ctx, id, tenantID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, pkgErrors.ErrNotFound
+			}
+			return nil, fmt.Errorf("failed to patch flag: %w", err)
+		}
+	}
+	if req.Has(PatchFieldRules) {
+		KeepRuleIDs(f.Rules, current.Rules)
+		AssignRuleIDs(f.Rules)
+	}
+	if current != nil {
+		if err := s.checkApproval(ctx, current.ProjectID, "", tenantID); err != nil {
+			return nil, err
+		}
+	}
+	if req.Has(PatchFieldProjectID) {
+		if err := s.checkApproval(ctx, f.ProjectID, "", tenantID); err != nil {
+			return nil, err
+		}
+	}
+	// Rules must fit the registry of the project they end up in, so a move checks the
+	// flag's current rules against the destination project
+	if req.Has(PatchFieldRules) || req.Has(PatchFieldProjectID) {
+		projectID, rules := current.ProjectID, current.Rules
+		if req.Has(PatchFieldProjectID) {
+			projectID = f.ProjectID
+		}
+		if req.Has(PatchFieldRules) {
+			rules = f.Rules
+		}
+		if err := s.checkRegisteredTypes(ctx, projectID, rules, tenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	var patched *Flag
+	write := func(ctx context.Context) error {
+		var err error
+		if patched, err = s.repo.Patch(ctx, id, tenantID, f, req.UpdateMask); err != nil {
+			return err
+		}
+		if req.Has(PatchFieldProjectID) && movedProject(current.ProjectID, patched.ProjectID) {
+			return s.repo.ResetEnvironmentConfigs(ctx, id, tenantID)
+		}
+		return nil
+	}
+	var err error
+	if s.uow != nil {
+		err = s.uow.RunInTransaction(ctx, write)
+	} else {
+		err = write(ctx)
+	}
+	if err != nil {
+		if dupErr := duplicateName(err, f); dupErr != nil {
+			return nil, dupErr
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag not found or forbidden on patch",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to patch flag",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to patch flag: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag patched",
+		slog.String("id", id),
+		slog.Any("fields", req.UpdateMask),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return patched, nil
+}
+
+// Toggle flips a flag's enabled state atomically, records who toggled it in the flag's
+// history and returns the updated flag. An empty actorID records a system action.
+func (s *service) Toggle(ctx context.Context, id string, actorID string, tenantID string) (*Flag, error) {
+	if id == "" {
+		return nil, ErrInvalidFlagData
+	}
+	if err := s.checkFlagApproval(ctx, id, "", tenantID); err != nil {
+		return nil, err
+	}
+
+	var flag *Flag
+	write := func(ctx context.Context) error {
+		var err error
+		flag, err = s.repo.Toggle(ctx, id, tenantID)
+		if err != nil {
+			return err
+		}
+
+		entry := &HistoryEntry{
+			FlagID:  id,
+			Action:  HistoryActionToggled,
+			Details: map[string]interface{}{"enabled": flag.Enabled},
+		}
+		if actorID != "" {
+			entry.ActorID = &actorID
+		}
+		return s.repo.RecordHistory(ctx, entry, tenantID)
+	}
+
+	var err error
+	if s.uow != nil {
+		err = s.uow.RunInTransaction(ctx, write)
+	} else {
+		err = write(ctx)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag not found or forbidden on toggle",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to toggle flag",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to toggle flag: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag toggled",
+		slog.String("id", id),
+		slog.Bool("enabled", flag.Enabled),
+		slog.String("actor_id", actorID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return flag, nil
+}
+
+// Reshuffle gives a flag a new random bucketing salt, re-randomizing which users fall inside
+// its rollouts without changing the percentages, and records it in the flag's history
+func (s *service) Reshuffle(ctx context.Context, id string, actorID string, tenantID string) (*Flag, error) {
+	if id == "" {
+		return nil, ErrInvalidFlagData
+	}
+	if err := s.checkFlagApproval(ctx, id, "", tenantID); err != nil {
+		return nil, err
+	}
+
+	salt, err := newSalt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	write := func(ctx context.Context) error {
+		if err := s.repo.SetSalt(ctx, id, tenantID, salt); err != nil {
+			return err
+		}
+		entry := &HistoryEntry{FlagID: id, Action: HistoryActionReshuffled, Details: map[string]interface{}{}}
+		if actorID != "" {
+			entry.ActorID = &actorID
+		}
+		return s.repo.RecordHistory(ctx, entry, tenantID)
+	}
+
+	if s.uow != nil {
+		err = s.uow.RunInTransaction(ctx, write)
+	} else {
+		err = write(ctx)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to reshuffle flag",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to reshuffle flag: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag reshuffled",
+		slog.String("id", id),
+		slog.String("actor_id", actorID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return s.GetByID(ctx, id, tenantID)
+}
+
+// RenameAttribute moves every rule in the project's flags, and in their environment configs,
+// from one context attribute to another in a single transaction, recording the rename in each
+// changed flag's history. A dry run reports the rules that would change and changes nothing.
+func (s *service) RenameAttribute(ctx context.Context, projectID string, req RenameAttributeRequest, actorID string, tenantID string) (*AttributeRename, error) {
+	req.From = strings.TrimSpace(req.From)
+	req.To = strings.TrimSpace(req.To)
+	if req.From == "" || req.To == "" {
+		return nil, fmt.Errorf("%w: from and to are required", ErrInvalidFlagData)
+	}
+	if req.From == req.To {
+		return nil, fmt.Errorf("%w: to must differ from from", ErrInvalidFlagData)
+	}
+
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed on attribute rename",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+		)
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	result := &AttributeRename{ProjectID: projectID, From: req.From, To: req.To, DryRun: req.DryRun, Changes: []AttributeRenameChange{}}
+
+	// approved remembers the approval check of each environment ("" for the flags themselves)
+	approved := map[string]error{}
+	checkApproval := func(ctx context.Context, environmentID string) error {
+		if err, ok := approved[environmentID]; ok {
+			return err
+		}
+		err := s.checkApproval(ctx, &projectID, environmentID, tenantID)
+		approved[environmentID] = err
+		return err
+	}
+
+	write := func(ctx context.Context) error {
+		flags, err := s.repo.ListByProject(ctx, projectID, tenantID)
+		if err != nil {
+			return fmt.Errorf("list flags: %w", err)
+		}
+		configs, err := s.repo.ListEnvironmentConfigsByProject(ctx, projectID, tenantID)
+		if err != nil {
+			return fmt.Errorf("list environment configs: %w", err)
+		}
+
+		for _, f := range flags {
+			var changed []AttributeRenameChange
+
+			rules, ids := renameAttribute(f.Rules, req.From, req.To)
+			if len(ids) > 0 {
+				changed = append(changed, AttributeRenameChange{FlagID: f.ID, FlagName: f.Name, RuleIDs: ids})
+				if !req.DryRun {
+					if err := checkApproval(ctx, ""); err != nil {
+						return err
+					}
+					if _, err := s.repo.Patch(ctx, f.ID, tenantID, &Flag{Rules: rules}, []string{PatchFieldRules}); err != nil {
+						return fmt.Errorf("update flag %s: %w", f.ID, err)
+					}
+				}
+			}
+
+			for _, config := range configs[f.ID] {
+				rules, ids := renameAttribute(config.Rules, req.From, req.To)
+				if len(ids) == 0 {
+					continue
+				}
+				changed = append(changed, AttributeRenameChange{FlagID: f.ID, FlagName: f.Name, EnvironmentID: config.EnvironmentID, RuleIDs: ids})
+				if !req.DryRun {
+					if err := checkApproval(ctx, config.EnvironmentID); err != nil {
+						return err
+					}
+					config.Rules = rules
+					if err := s.repo.SetEnvironmentConfig(ctx, &config, tenantID); err != nil {
+						return fmt.Errorf("update flag %s in environment %s: %w", f.ID, config.EnvironmentID, err)
+					}
+				}
+			}
+
+			if len(changed) == 0 {
+				continue
+			}
+			result.Changes = append(result.Changes, changed...)
+			for _, c := range changed {
+				result.RulesChanged += len(c.RuleIDs)
+			}
+
+			if !req.DryRun {
+				entry := &HistoryEntry{FlagID: f.ID, Action: HistoryActionAttributeRenamed, Details: map[string]interface{}{"from": req.From, "to": req.To}}
+				if actorID != "" {
+					entry.ActorID = &actorID
+				}
+				if err := s.repo.RecordHistory(ctx, entry, tenantID); err != nil {
+					return fmt.Errorf("record history for flag %s: %w", f.ID, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if s.uow != nil && !req.DryRun {
+		err = s.uow.RunInTransaction(ctx, write)
+	} else {
+		err = write(ctx)
+	}
+	if err != nil {
+		if errors.Is(err, ErrApprovalRequired) {
+			return nil, err
+		}
+		s.logger.Error("failed to rename attribute",
+			slog.String("project_id", projectID),
+			slog.String("from", req.From),
+			slog.String("to", req.To),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to rename attribute: %w", err)
+	}
+
+	if !req.DryRun && result.RulesChanged > 0 {
+		s.invalidateCache(tenantID)
+		s.logger.Info("attribute renamed",
+			slog.String("project_id", projectID),
+			slog.String("from", req.From),
+			slog.String("to", req.To),
+			slog.Int("rules_changed", result.RulesChanged),
+			slog.String("actor_id", actorID),
+			slog.String("tenant_id", tenantID),
+		)
+	}
+
+	return result, nil
+}
+
+// newSalt returns a random bucketing salt
+func newSalt() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *service) Delete(ctx context.Context, id string, tenantID string) error {
+	if id == "" {
+		return ErrInvalidFlagData
+	}
+
+	if err := s.repo.Delete(ctx, id, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag not found or forbidden on delete",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to delete flag",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to delete flag: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag deleted",
+		slog.String("id", id),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// Clone copies a flag's description, rules and rule logic into a target project in the same tenant.
+// The clone keeps the source name unless a new one is given, and always starts disabled.
+func (s *service) Clone(ctx context.Context, id string, targetProjectID string, name string, tenantID string) (*Flag, error) {
+	if targetProjectID == "" {
+		return nil, fmt.Errorf("%w: project_id is required", ErrInvalidFlagData)
+	}
+
+	source, err := s.GetByID(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validator.ValidateProjectOwnership(ctx, targetProjectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed on clone",
+			slog.String("flag_id", id),
+			slog.String("project_id", targetProjectID),
+			slog.String("tenant_id", tenantID),
+		)
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	if name == "" {
+		name = source.Name
+	}
+
+	// Copy the rules so the clone does not share the source's backing array
+	rules := make([]Rule, len(source.Rules))
+	copy(rules, source.Rules)
+
+	clone := &Flag{
+		TenantID:    tenantID,
+		ProjectID:   &targetProjectID,
+		Name:        name,
+		Description: source.Description,
+		Enabled:     false,
+		Rules:       rules,
+		RuleLogic:   source.RuleLogic,
+		Lifecycle:   LifecycleActive,
+	}
+
+	if err := s.repo.Create(ctx, clone); err != nil {
+		if dupErr := duplicateName(err, clone); dupErr != nil {
+			return nil, dupErr
+		}
+		s.logger.Error("failed to clone flag",
+			slog.String("id", id),
+			slog.String("project_id", targetProjectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to clone flag: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag cloned",
+		slog.String("source_id", id),
+		slog.String("id", clone.ID),
+		slog.String("project_id", targetProjectID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return clone, nil
+}
+
+// Export returns every flag in a project in a portable form, ordered by key
+func (s *service) Export(ctx context.Context, projectID string, tenantID string) (*ExportDocument, error) {
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed on export",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+		)
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	flags, err := s.repo.ListByProject(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list flags for export",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to export flags: %w", err)
+	}
+
+	doc := &ExportDocument{
+		Version:    ExportVersion,
+		ProjectID:  projectID,
+		ExportedAt: time.Now().UTC(),
+		Flags:      make([]ExportedFlag, 0, len(flags)),
+	}
+	for i := range flags {
+		doc.Flags = append(doc.Flags, exportFlag(&flags[i]))
+	}
+	sort.Slice(doc.Flags, func(i, j int) bool { return doc.Flags[i].Key < doc.Flags[j].Key })
+
+	return doc, nil
+}
+
+// ExportSealed is Export with the document sealed by the tenant data key, so only the same
+// tenant can read it back through Import
+func (s *service) ExportSealed(ctx context.Context, projectID string, tenantID string) (*ExportDocument, error) {
+	doc, err := s.Export(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if s.sealer == nil {
+		return nil, fmt.Errorf("%w: encrypted exports require a tenant encryption key", ErrInvalidFlagData)
+	}
+
+	plaintext, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export flags: %w", err)
+	}
+
+	sealed, err := s.sealer.Seal(ctx, tenantID, plaintext)
+	if err != nil {
+		if errors.Is(err, encryption.ErrNoKey) {
+			return nil, fmt.Errorf("%w: encrypted exports require a tenant encryption key", ErrInvalidFlagData)
+		}
+		s.logger.Error("failed to seal flag export",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to export flags: %w", err)
+	}
+
+	return &ExportDocument{
+		Version:    doc.Version,
+		ProjectID:  doc.ProjectID,
+		ExportedAt: doc.ExportedAt,
+		Sealed:     sealed,
+	}, nil
+}
+
+// openExport replaces a sealed export document with its decrypted contents
+func (s *service) openExport(ctx context.Context, tenantID string, doc *ExportDocument) (*ExportDocument, error) {
+	if doc.Sealed == "" {
+		return doc, nil
+	}
+	if s.sealer == nil {
+		return nil, fmt.Errorf("%w: encrypted exports require a tenant encryption key", ErrInvalidFlagData)
+	}
+
+	plaintext, err := s.sealer.Open(ctx, tenantID, doc.Sealed)
+	if err != nil {
+		// Also covers exports sealed by another tenant, which can't be opened here
+		return nil, fmt.Errorf("%w: encrypted export can't be opened by this tenant", ErrInvalidFlagData)
+	}
+
+	var opened ExportDocument
+	if err := json.Unmarshal(plaintext, &opened); err != nil {
+		return nil, fmt.Errorf("%w: encrypted export is not a valid export document", ErrInvalidFlagData)
+	}
+	return &opened, nil
+}
+
+func exportFlag(f *Flag) ExportedFlag {
+	key := f.Key
+	if key == "" {
+		key = legacyKey(f)
+	}
+	rules := f.Rules
+	if rules == nil {
+		rules = []Rule{}
+	}
+
+	return ExportedFlag{
+		Key:         key,
+		Name:        f.Name,
+		Description: f.Description,
+		Enabled:     f.Enabled,
+		Rules:       rules,
+		RuleLogic:   f.RuleLogic,
+		ExpiresAt:   f.ExpiresAt,
+	}
+}
+
+// Import creates or updates a project's flags from an export document, matching flags by key.
+// Every flag is validated first; if any is invalid nothing is written and the result lists
+// the problems. Writes happen in one transaction when a unit of work is set.
+func (s *service) Import(ctx context.Context, projectID string, tenantID string, doc *ExportDocument, opts ImportOptions) (*ImportResult, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("%w: export document is required", ErrInvalidFlagData)
+	}
+	doc, err := s.openExport(ctx, tenantID, doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Flags == nil {
+		return nil, fmt.Errorf("%w: flags is required", ErrInvalidFlagData)
+	}
+	if doc.Version > ExportVersion {
+		return nil, fmt.Errorf("%w: unsupported export version %d", ErrInvalidFlagData, doc.Version)
+	}
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = ImportConflictSkip
+	case ImportConflictSkip, ImportConflictOverwrite:
+	default:
+		return nil, fmt.Errorf("%w: on_conflict must be skip or overwrite", ErrInvalidFlagData)
+	}
+
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed on import",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+		)
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	existing, err := s.repo.ListByProject(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flags for import: %w", err)
+	}
+	byKey := make(map[string]*Flag, len(existing))
+	for i := range existing {
+		byKey[exportFlag(&existing[i]).Key] = &existing[i]
+	}
+	registry, err := s.repo.ListRegisteredAttributes(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attribute registry for import: %w", err)
+	}
+
+	result := &ImportResult{
+		DryRun:  opts.DryRun,
+		Created: []string{},
+		Updated: []string{},
+		Skipped: []string{},
+		Errors:  []ImportError{},
+	}
+	var creates, updates []*Flag
+	seen := make(map[string]int, len(doc.Flags))
+
+	for i, imported := range doc.Flags {
+		key := imported.Key
+		if key == "" {
+			key = KeyFromName(imported.Name)
+		}
+		addError := func(err error) {
+			importErr := ImportError{Index: i, Key: key, Error: err.Error()}
+			var rulesErr *RuleValidationError
+			if errors.As(err, &rulesErr) {
+				importErr.RuleErrors = rulesErr.Errors
+			}
+			result.Errors = append(result.Errors, importErr)
+		}
+
+		if key == "" {
+			addError(fmt.Errorf("%w: key or name is required", ErrInvalidFlagData))
+			continue
+		}
+		if first, ok := seen[key]; ok {
+			addError(fmt.Errorf("%w: key %q is also used by flags[%d]", ErrInvalidFlagData, key, first))
+			continue
+		}
+		seen[key] = i
+
+		f := &Flag{
+			TenantID:    tenantID,
+			ProjectID:   &projectID,
+			Key:         key,
+			Name:        imported.Name,
+			Description: imported.Description,
+			Enabled:     imported.Enabled,
+			Rules:       imported.Rules,
+			RuleLogic:   imported.RuleLogic,
+			ExpiresAt:   imported.ExpiresAt,
+		}
+		if f.Rules == nil {
+			f.Rules = []Rule{}
+		}
+		if f.RuleLogic == "" {
+			f.RuleLogic = RuleLogicAnd
+		}
+
+		current, exists := byKey[key]
+		if exists && opts.OnConflict == ImportConflictSkip {
+			result.Skipped = append(result.Skipped, key)
+			continue
+		}
+		f.Lifecycle = LifecycleActive
+		if exists {
+			// Overwriting keeps the existing flag's identity, owner and lifecycle
+			f.ID = current.ID
+			f.OwnerUserID = current.OwnerUserID
+			f.Lifecycle = current.Lifecycle
+		}
+
+		if err := s.validateFlag(f); err != nil {
+			addError(err)
+			continue
+		}
+		if err := CheckRegisteredTypes(f.Rules, registry); err != nil {
+			addError(err)
+			continue
+		}
+		if err := s.sanitizeFlag(f); err != nil {
+			addError(err)
+			continue
+		}
+
+		if exists {
+			updates = append(updates, f)
+			result.Updated = append(result.Updated, key)
+		} else {
+			creates = append(creates, f)
+			result.Created = append(result.Created, key)
+		}
+	}
+
+	if len(result.Errors) > 0 || opts.DryRun {
+		return result, nil
+	}
+
+	// Overwriting a flag is an edit like any other; new flags need no approval
+	if len(updates) > 0 {
+		if err := s.checkApproval(ctx, &projectID, "", tenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	write := func(ctx context.Context) error {
+		for _, f := range creates {
+			if err := s.repo.Create(ctx, f); err != nil {
+				if dupErr := duplicateName(err, f); dupErr != nil {
+					return dupErr
+				}
+				return fmt.Errorf("create flag %q: %w", f.Key, err)
+			}
+		}
+		for _, f := range updates {
+			if err := s.repo.Update(ctx, f, tenantID); err != nil {
+				if dupErr := duplicateName(err, f); dupErr != nil {
+					return dupErr
+				}
+				return fmt.Errorf("update flag %q: %w", f.Key, err)
+			}
+		}
+		return nil
+	}
+
+	if s.uow != nil {
+		err = s.uow.RunInTransaction(ctx, write)
+	} else {
+		err = write(ctx)
+	}
+	if err != nil {
+		s.logger.Error("failed to import flags",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to import flags: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flags imported",
+		slog.String("project_id", projectID),
+		slog.Int("created", len(result.Created)),
+		slog.Int("updated", len(result.Updated)),
+		slog.Int("skipped", len(result.Skipped)),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return result, nil
+}
+
+// Apply makes the project's flag with the given key match def exactly, creating it when the
+// key is new. Fields def omits take their defaults; the owner and lifecycle of an existing
+// flag are kept. A flag that already matches isn't written, so applying the same definition
+// again changes nothing.
+func (s *service) Apply(ctx context.Context, projectID string, key string, def ExportedFlag, tenantID string) (*ApplyResult, error) {
+	if key == "" || KeyFromName(key) != key {
+		return nil, fmt.Errorf("%w: key must be a lowercase slug such as new-checkout", ErrInvalidFlagData)
+	}
+	if def.Key != "" && def.Key != key {
+		return nil, fmt.Errorf("%w: key %q in the body doesn't match %q", ErrInvalidFlagData, def.Key, key)
+	}
+
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed on apply",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+		)
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	flags, err := s.repo.ListByProject(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flags for apply: %w", err)
+	}
+	var current *Flag
+	for i := range flags {
+		if exportFlag(&flags[i]).Key == key {
+			current = &flags[i]
+			break
+		}
+	}
+
+	if def.Rules == nil {
+		def.Rules = []Rule{}
+	}
+	if def.RuleLogic == "" {
+		def.RuleLogic = RuleLogicAnd
+	}
+
+	if current == nil {
+		f := &Flag{
+			ProjectID:   &projectID,
+			Key:         key,
+			Name:        def.Name,
+			Description: def.Description,
+			Enabled:     def.Enabled,
+			Rules:       def.Rules,
+			RuleLogic:   def.RuleLogic,
+			Lifecycle:   LifecycleActive,
+			ExpiresAt:   def.ExpiresAt,
+		}
+		if err := s.Create(ctx, f, tenantID); err != nil {
+			return nil, err
+		}
+		return &ApplyResult{Result: ApplyCreated, Flag: f}, nil
+	}
+
+	// Rules sent without IDs keep the IDs of the rules they replace, so a definition that
+	// never mentions rule IDs still matches the flag it was applied to
+	KeepRuleIDs(def.Rules, current.Rules)
+
+	f := *current
+	f.Name = def.Name
+	f.Description = def.Description
+	f.Enabled = def.Enabled
+	f.Rules = def.Rules
+	f.RuleLogic = def.RuleLogic
+	f.ExpiresAt = def.ExpiresAt
+	if err := s.sanitizeFlag(&f); err != nil {
+		return nil, err
+	}
+	if sameDefinition(exportFlag(&f), exportFlag(current)) {
+		return &ApplyResult{Result: ApplyUnchanged, Flag: current}, nil
+	}
+
+	if err := s.Update(ctx, &f, tenantID); err != nil {
+		return nil, err
+	}
+	return &ApplyResult{Result: ApplyUpdated, Flag: &f}, nil
+}
+
+// sameDefinition reports whether two flags have the same portable definition
+func sameDefinition(a, b ExportedFlag) bool {
+	sameExpiry := (a.ExpiresAt == nil) == (b.ExpiresAt == nil) && (a.ExpiresAt == nil || a.ExpiresAt.Equal(*b.ExpiresAt))
+	a.ExpiresAt, b.ExpiresAt = nil, nil
+	return sameExpiry && reflect.DeepEqual(a, b)
+}
+
+// ExportUnleash returns a project's flags as an Unleash state export, with the keys of the
+// flags Unleash can't express, which are left out
+func (s *service) ExportUnleash(ctx context.Context, projectID string, tenantID string) (*UnleashExport, []string, error) {
+	doc, err := s.Export(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, skipped := UnleashFromExport(doc)
+	return u, skipped, nil
+}
+
+// ImportUnleash creates or updates a project's flags from an Unleash state export, matching
+// features to flags by key like Import. Features whose strategies can't be expressed as
+// Toggle rules are reported as errors, and like any other error they stop the import.
+func (s *service) ImportUnleash(ctx context.Context, projectID string, tenantID string, doc *UnleashExport, opts ImportOptions) (*ImportResult, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("%w: export document is required", ErrInvalidFlagData)
+	}
+	conv, err := convertUnleash(doc, opts.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unconvertible features must stop the import, so only validate until they're reported
+	dryRun := opts.DryRun
+	if len(conv.Errors) > 0 {
+		opts.DryRun = true
+	}
+	result, err := s.Import(ctx, projectID, tenantID, conv.Document, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.DryRun = dryRun
+
+	for i := range result.Errors {
+		result.Errors[i].Index = conv.Indexes[result.Errors[i].Index]
+	}
+	result.Errors = append(result.Errors, conv.Errors...)
+	slices.SortStableFunc(result.Errors, func(a, b ImportError) int { return a.Index - b.Index })
+	return result, nil
+}
+
+// ListExclusions returns the user keys excluded from a flag
+func (s *service) ListExclusions(ctx context.Context, id string, tenantID string) ([]Exclusion, error) {
+	if _, err := s.GetByID(ctx, id, tenantID); err != nil {
+		return nil, err
+	}
+
+	exclusions, err := s.repo.ListExclusions(ctx, id, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list flag exclusions",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list flag exclusions: %w", err)
+	}
+
+	return exclusions, nil
+}
+
+// AddExclusions excludes user keys from a flag, keeping any existing exclusions.
+// Returns the flag's full exclusion list after the change.
+func (s *service) AddExclusions(ctx context.Context, id string, userKeys []string, reason string, tenantID string) ([]Exclusion, error) {
+	keys, err := normalizeUserKeys(userKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	reason, err = sanitize.Text(reason, sanitize.MaxDescriptionLength)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidFlagData, sanitize.MaxDescriptionLength)
+	}
+
+	if err := s.repo.AddExclusions(ctx, id, tenantID, keys, reason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag not found or forbidden on add exclusions",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to add flag exclusions",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to add flag exclusions: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag exclusions added",
+		slog.String("id", id),
+		slog.Int("count", len(keys)),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return s.ListExclusions(ctx, id, tenantID)
+}
+
+// RemoveExclusion lets a user key receive a flag again
+func (s *service) RemoveExclusion(ctx context.Context, id string, userKey string, tenantID string) error {
+	if id == "" || userKey == "" {
+		return ErrInvalidFlagData
+	}
+
+	if err := s.repo.RemoveExclusion(ctx, id, tenantID, userKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag exclusion not found or forbidden",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to remove flag exclusion",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to remove flag exclusion: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag exclusion removed",
+		slog.String("id", id),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// ListCustomFields returns a flag's custom fields
+func (s *service) ListCustomFields(ctx context.Context, id string, tenantID string) ([]CustomField, error) {
+	if _, err := s.GetByID(ctx, id, tenantID); err != nil {
+		return nil, err
+	}
+
+	fields, err := s.repo.ListCustomFields(ctx, id, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list flag custom fields",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list flag custom fields: %w", err)
+	}
+
+	return fields, nil
+}
+
+// SetCustomField creates or replaces one custom field on a flag
+func (s *service) SetCustomField(ctx context.Context, id string, name string, value string, tenantID string) (*CustomField, error) {
+	if !customFieldNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: custom field names must be lowercase letters, digits, _ or - (at most 64)", ErrInvalidFlagData)
+	}
+
+	value, err := sanitize.Text(value, MaxCustomFieldValueLength)
+	if err != nil {
+		return nil, fmt.Errorf("%w: value must be at most %d characters", ErrInvalidFlagData, MaxCustomFieldValueLength)
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("%w: value is required", ErrInvalidFlagData)
+	}
+
+	existing, err := s.ListCustomFields(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MaxCustomFieldsPerFlag && !hasCustomField(existing, name) {
+		return nil, fmt.Errorf("%w: a flag can have at most %d custom fields", ErrInvalidFlagData, MaxCustomFieldsPerFlag)
+	}
+
+	field, err := s.repo.SetCustomField(ctx, id, tenantID, name, value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to set flag custom field",
+			slog.String("id", id),
+			slog.String("name", name),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to set flag custom field: %w", err)
+	}
+
+	s.logger.Info("flag custom field set",
+		slog.String("id", id),
+		slog.String("name", name),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return field, nil
+}
+
+// DeleteCustomField removes one custom field from a flag
+func (s *service) DeleteCustomField(ctx context.Context, id string, name string, tenantID string) error {
+	if id == "" || name == "" {
+		return ErrInvalidFlagData
+	}
+
+	if err := s.repo.DeleteCustomField(ctx, id, tenantID, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag custom field not found or forbidden",
+				slog.String("id", id),
+				slog.String("name", name),
+				slog.String("tenant_id", tenantID),
+			)
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to delete flag custom field",
+			slog.String("id", id),
+			slog.String("name", name),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to delete flag custom field: %w", err)
+	}
+
+	s.logger.Info("flag custom field deleted",
+		slog.String("id", id),
+		slog.String("name", name),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// ListEnvironmentConfigs returns a flag's config in each environment it has been configured in
+func (s *service) ListEnvironmentConfigs(ctx context.Context, id string, tenantID string) ([]EnvironmentConfig, error) {
+	if _, err := s.GetByID(ctx, id, tenantID); err != nil {
+		return nil, err
+	}
+
+	configs, err := s.repo.ListEnvironmentConfigs(ctx, id, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list flag environment configs",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list flag environment configs: %w", err)
+	}
+
+	return configs, nil
+}
+
+// SetEnvironmentConfig sets a flag's enabled state and rules in one environment of its project.
+// Other environments, and the flag's own config served to the project key, are unaffected.
+// SetEnvironmentConfig replaces a flag's config in one environment, unless the project's
+// approval policy covers that environment
+func (s *service) SetEnvironmentConfig(ctx context.Context, id string, environmentID string, req SetEnvironmentConfigRequest, tenantID string) (*EnvironmentConfig, error) {
+	return s.setEnvironmentConfig(ctx, id, environmentID, req, tenantID, true)
+}
+
+// ApplyApprovedEnvironmentChange applies a change's enabled state, rules and rule logic to a
+// flag's config in the environment with the given key, keeping the config's other values, without
+// checking the project's approval policy
+func (s *service) ApplyApprovedEnvironmentChange(ctx context.Context, id string, environment string, changes UpdateRequest, tenantID string) (*EnvironmentConfig, error) {
+	environmentID, current, err := s.environmentConfigByKey(ctx, id, environment, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	config := current.orDisabled()
+	req := SetEnvironmentConfigRequest{Enabled: &config.Enabled, Rules: config.Rules, RuleLogic: config.RuleLogic}
+	if changes.Enabled != nil {
+		req.Enabled = changes.Enabled
+	}
+	if changes.Rules != nil {
+		req.Rules = changes.Rules
+	}
+	if changes.RuleLogic != nil {
+		req.RuleLogic = *changes.RuleLogic
+	}
+
+	return s.setEnvironmentConfig(ctx, id, environmentID, req, tenantID, false)
+}
+
+func (s *service) setEnvironmentConfig(ctx context.Context, id string, environmentID string, req SetEnvironmentConfigRequest, tenantID string, gated bool) (*EnvironmentConfig, error) {
+	config := &EnvironmentConfig{
+		FlagID:        id,
+		EnvironmentID: environmentID,
+		Enabled:       *req.Enabled,
+		Rules:         req.Rules,
+		RuleLogic:     req.RuleLogic,
+	}
+	if config.Rules == nil {
+		config.Rules = []Rule{}
+	}
+	if config.RuleLogic == "" {
+		config.RuleLogic = RuleLogicAnd
+	}
+	if !ValidRuleLogic(config.RuleLogic) {
+		return nil, fmt.Errorf("%w: rule_logic must be AND, OR or FIRST_MATCH", ErrInvalidFlagData)
+	}
+	if err := ValidateRules(config.Rules); err != nil {
+		return nil, err
+	}
+	if len(config.Rules) > 0 || (gated && s.approvals != nil) {
+		f, err := s.repo.GetByID(ctx, id, tenantID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, pkgErrors.ErrNotFound
+			}
+			return nil, fmt.Errorf("failed to set flag environment config: %w", err)
+		}
+		if gated {
+			if err := s.checkApproval(ctx, f.ProjectID, environmentID, tenantID); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.checkRegisteredTypes(ctx, f.ProjectID, config.Rules, tenantID); err != nil {
+			return nil, err
+		}
+	}
+	AssignRuleIDs(config.Rules)
+
+	if err := s.repo.SetEnvironmentConfig(ctx, config, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to set flag environment config",
+			slog.String("id", id),
+			slog.String("environment_id", environmentID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to set flag environment config: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag environment config set",
+		slog.String("id", id),
+		slog.String("environment_id", environmentID),
+		slog.Bool("enabled", config.Enabled),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return config, nil
+}
+
+// DiffEnvironments compares a flag's config in two environments of its project, by environment key
+func (s *service) DiffEnvironments(ctx context.Context, id string, from string, to string, tenantID string) (*EnvironmentDiff, error) {
+	if from == to {
+		return nil, fmt.Errorf("%w: from and to must be different environments", ErrInvalidFlagData)
+	}
+
+	diff := &EnvironmentDiff{FlagID: id, From: from, To: to}
+	var err error
+	if _, diff.Source, err = s.environmentConfigByKey(ctx, id, from, tenantID); err != nil {
+		return nil, err
+	}
+	if _, diff.Target, err = s.environmentConfigByKey(ctx, id, to, tenantID); err != nil {
+		return nil, err
+	}
+	diff.Differences = DiffEnvironmentConfigs(diff.Source, diff.Target)
+
+	return diff, nil
+}
+
+// Promote copies a flag's config from one environment to another and records the promotion
+// in the flag's history, atomically. The returned diff is what the promotion changed.
+// Promoting into an environment the project's approval policy covers is refused.
+func (s *service) Promote(ctx context.Context, id string, req PromoteRequest, actorID string, tenantID string) (*EnvironmentDiff, error) {
+	if req.From == req.To {
+		return nil, fmt.Errorf("%w: from and to must be different environments", ErrInvalidFlagData)
+	}
+
+	var diff *EnvironmentDiff
+	write := func(ctx context.Context) error {
+		_, source, err := s.environmentConfigByKey(ctx, id, req.From, tenantID)
+		if err != nil {
+			return err
+		}
+		targetID, target, err := s.environmentConfigByKey(ctx, id, req.To, tenantID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkFlagApproval(ctx, id, targetID, tenantID); err != nil {
+			return err
+		}
+		diff = &EnvironmentDiff{FlagID: id, From: req.From, To: req.To, Source: source, Target: target}
+		diff.Differences = DiffEnvironmentConfigs(source, target)
+
+		promoted := source.orDisabled()
+		config := &EnvironmentConfig{
+			FlagID:        id,
+			EnvironmentID: targetID,
+			Enabled:       promoted.Enabled,
+			Rules:         promoted.Rules,
+			RuleLogic:     promoted.RuleLogic,
+		}
+		if err := s.repo.SetEnvironmentConfig(ctx, config, tenantID); err != nil {
+			return err
+		}
+
+		return s.repo.RecordHistory(ctx, &HistoryEntry{
+			FlagID:  id,
+			Action:  HistoryActionPromoted,
+			ActorID: &actorID,
+			Details: map[string]interface{}{
+				"from":        req.From,
+				"to":          req.To,
+				"enabled":     config.Enabled,
+				"differences": diff.Differences,
+			},
+		}, tenantID)
+	}
+
+	var err error
+	if s.uow != nil {
+		err = s.uow.RunInTransaction(ctx, write)
+	} else {
+		err = write(ctx)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidFlagData) || errors.Is(err, ErrApprovalRequired) || pkgErrors.IsNotFoundError(err) {
+			return nil, err
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to promote flag",
+			slog.String("id", id),
+			slog.String("from", req.From),
+			slog.String("to", req.To),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to promote flag: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag promoted",
+		slog.String("id", id),
+		slog.String("from", req.From),
+		slog.String("to", req.To),
+		slog.Any("differences", diff.Differences),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return diff, nil
+}
+
+// environmentConfigByKey resolves an environment key of the flag's project to its ID and the flag's config there
+func (s *service) environmentConfigByKey(ctx context.Context, id string, key string, tenantID string) (string, *EnvironmentConfig, error) {
+	environmentID, config, err := s.repo.GetEnvironmentConfigByKey(ctx, id, key, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, fmt.Errorf("%w: environment %q", pkgErrors.ErrNotFound, key)
+		}
+		s.logger.Error("failed to get flag environment config",
+			slog.String("id", id),
+			slog.String("environment", key),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return "", nil, fmt.Errorf("failed to get flag environment config: %w", err)
+	}
+	return environmentID, config, nil
+}
+
+func hasCustomField(fields []CustomField, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateOverride forces a flag on or off for one user key until the override expires.
+// An existing override for the same user key is replaced.
+func (s *service) CreateOverride(ctx context.Context, o *Override, tenantID string) error {
+	if o == nil || o.FlagID == "" {
+		return ErrInvalidFlagData
+	}
+
+	keys, err := normalizeUserKeys([]string{o.UserKey})
+	if err != nil {
+		return err
+	}
+	o.UserKey = keys[0]
+
+	now := time.Now()
+	if !o.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidFlagData)
+	}
+	if o.ExpiresAt.Sub(now) > MaxOverrideDuration {
+		return fmt.Errorf("%w: expires_at must be within %d days", ErrInvalidFlagData, int(MaxOverrideDuration.Hours()/24))
+	}
+
+	o.Reason, err = sanitize.Text(o.Reason, sanitize.MaxDescriptionLength)
+	if err != nil {
+		return fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidFlagData, sanitize.MaxDescriptionLength)
+	}
+
+	if err := s.repo.UpsertOverride(ctx, o, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag not found or forbidden on create override",
+				slog.String("id", o.FlagID),
+				slog.String("tenant_id", tenantID),
+			)
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to create flag override",
+			slog.String("id", o.FlagID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to create flag override: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag override created",
+		slog.String("id", o.FlagID),
+		slog.Bool("enabled", o.Enabled),
+		slog.Time("expires_at", o.ExpiresAt),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// ListOverrides returns a flag's unexpired overrides
+func (s *service) ListOverrides(ctx context.Context, id string, tenantID string) ([]Override, error) {
+	if _, err := s.GetByID(ctx, id, tenantID); err != nil {
+		return nil, err
+	}
+
+	overrides, err := s.repo.ListOverrides(ctx, id, tenantID, time.Now())
+	if err != nil {
+		s.logger.Error("failed to list flag overrides",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list flag overrides: %w", err)
+	}
+
+	return overrides, nil
+}
+
+// DeleteOverride ends a user's override before it expires
+func (s *service) DeleteOverride(ctx context.Context, id string, userKey string, tenantID string) error {
+	if id == "" || userKey == "" {
+		return ErrInvalidFlagData
+	}
+
+	if err := s.repo.DeleteOverride(ctx, id, tenantID, userKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("flag override not found or forbidden",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to delete flag override",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to delete flag override: %w", err)
+	}
+
+	s.invalidateCache(tenantID)
+	s.logger.Info("flag override deleted",
+		slog.String("id", id),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// ExpireOverrides deletes expired overrides across all tenants (run by the jobs scheduler).
+// Evaluation already ignores expired overrides; this keeps the table small.
+func (s *service) ExpireOverrides(ctx context.Context) error {
+	deleted, err := s.repo.DeleteExpiredOverrides(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to expire flag overrides: %w", err)
+	}
+
+	if deleted > 0 {
+		s.logger.Info("expired flag overrides removed", slog.Int64("count", deleted))
+	}
+
+	return nil
+}
+
+// DisableExpired disables every flag whose expiry has passed; run periodically by the scheduler
+func (s *service) DisableExpired(ctx context.Context) error {
+	disabled, err := s.repo.DisableExpired(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to disable expired flags: %w", err)
+	}
+
+	if disabled > 0 {
+		s.logger.Info("expired flags disabled", slog.Int64("count", disabled))
+	}
+
+	return nil
+}
+
+// AttachStats sets each flag's evaluation stats over the last StatsWindow.
+// Flags without evaluations get zero stats rather than none.
+func (s *service) AttachStats(ctx context.Context, flags []Flag, tenantID string) error {
+	since := time.Now().Add(-StatsWindow).Truncate(time.Hour).Add(time.Hour)
+	counts, err := s.repo.SumResults(ctx, tenantID, since)
+	if err != nil {
+		s.logger.Error("failed to load flag evaluation stats",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to load flag evaluation stats: %w", err)
+	}
+
+	for i := range flags {
+		stats := NewEvaluationStats(counts[flags[i].ID])
+		flags[i].Stats = &stats
+	}
+
+	return nil
+}
+
+// Metrics returns a flag's evaluation counters over each of MetricsWindows
+func (s *service) Metrics(ctx context.Context, id string, tenantID string) (*FlagMetrics, error) {
+	if _, err := s.GetByID(ctx, id, tenantID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	since := now.Add(-ResultCountRetention).Truncate(time.Hour).Add(time.Hour)
+	hourly, err := s.repo.ListResultCounts(ctx, id, tenantID, since)
+	if err != nil {
+		s.logger.Error("failed to load flag metrics",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to load flag metrics: %w", err)
+	}
+
+	return NewFlagMetrics(id, hourly, now), nil
+}
+
+// PruneResultCounts deletes evaluation counts older than ResultCountRetention (run by the jobs scheduler)
+func (s *service) PruneResultCounts(ctx context.Context) error {
+	deleted, err := s.repo.DeleteResultCounts(ctx, time.Now().Add(-ResultCountRetention))
+	if err != nil {
+		return fmt.Errorf("failed to prune flag evaluation counts: %w", err)
+	}
+
+	if deleted > 0 {
+		s.logger.Debug("flag evaluation counts pruned", slog.Int64("count", deleted))
+	}
+
+	return nil
+}
+
+// ListHistory returns a flag's history, newest first
+func (s *service) ListHistory(ctx context.Context, id string, tenantID string) ([]HistoryEntry, error) {
+	if _, err := s.GetByID(ctx, id, tenantID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.ListHistory(ctx, id, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list flag history",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list flag history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// normalizeUserKeys trims and de-duplicates user keys, rejecting empty or oversized input
+func normalizeUserKeys(userKeys []string) ([]string, error) {
+	if len(userKeys) == 0 {
+		return nil, fmt.Errorf("%w: user_keys is required", ErrInvalidFlagData)
+	}
+	if len(userKeys) > MaxExclusionsPerRequest {
+		return nil, fmt.Errorf("%w: at most %d user_keys per request", ErrInvalidFlagData, MaxExclusionsPerRequest)
+	}
+
+	seen := make(map[string]struct{}, len(userKeys))
+	keys := make([]string, 0, len(userKeys))
+	for _, key := range userKeys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("%w: user_keys must not be empty", ErrInvalidFlagData)
+		}
+		if len(key) > MaxUserKeyLength {
+			return nil, fmt.Errorf("%w: user_keys must be at most %d characters", ErrInvalidFlagData, MaxUserKeyLength)
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// validateLifecycleChange checks the flag's new lifecycle state against the stored one
+func validateLifecycleChange(f *Flag, current *Flag) error {
+	if f.Lifecycle == "" {
+		f.Lifecycle = current.Lifecycle
+	}
+	if !CanTransition(current.Lifecycle, f.Lifecycle) {
+		return fmt.Errorf("%w: lifecycle can't change from %s to %s", ErrInvalidFlagData, current.Lifecycle, f.Lifecycle)
+	}
+	return nil
+}
+
+// movedProject reports whether an update moves a flag to another project, whose
+// environments its environment configs then have to be reset to
+func movedProject(from *string, to *string) bool {
+	if from == nil || to == nil {
+		return (from == nil) != (to == nil)
+	}
+	return *from != *to
+}
+
+func (s *service) validateFlag(f *Flag) error {
+	if f == nil {
+		return ErrInvalidFlagData
+	}
+
+	if f.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidFlagData)
+	}
+
+	if f.ExpiresAt != nil && !f.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidFlagData)
+	}
+
+	if f.RuleLogic != "" && !ValidRuleLogic(f.RuleLogic) {
+		return fmt.Errorf("%w: rule_logic must be AND, OR or FIRST_MATCH", ErrInvalidFlagData)
+	}
+
+	if f.Lifecycle != "" && !ValidLifecycle(f.Lifecycle) {
+		return fmt.Errorf("%w: lifecycle must be draft, active, deprecated or archived", ErrInvalidFlagData)
+	}
+
+	if err := ValidateRules(f.Rules); err != nil {
+		return err
+	}
+	AssignRuleIDs(f.Rules)
+	return nil
+}
+
+// ValidateRules checks every rule's ID, attribute, operator, value type and rollout.
+// IDs are optional, since the server assigns them, but must be unique UUIDs when sent.
+// Returns a *RuleValidationError listing all problems, or nil if the rules are valid.
+func ValidateRules(rules []Rule) error {
+	var errs []RuleError
+	seen := make(map[string]struct{}, len(rules))
+	for i, rule := range rules {
+		add := func(field, message string) {
+			errs = append(errs, RuleError{Index: i, RuleID: rule.ID, Field: field, Message: message})
+		}
+
+		if rule.ID != "" {
+			if _, dup := seen[rule.ID]; dup {
+				add("id", "id is used by another rule of this flag")
+			} else if !validRuleID(rule.ID) {
+				add("id", "id must be a lowercase UUID; omit it on new rules to have one assigned")
+			}
+			seen[rule.ID] = struct{}{}
+		}
+
+		if rule.Operator == OperatorTimeWindow {
+			if rule.Attribute != "" {
+				add("attribute", "time_window rules match on the time of the evaluation, so attribute must be empty")
+			}
+			if rule.ContextKind != "" {
+				add("context_kind", "time_window rules match on the time of the evaluation, so context_kind must be empty")
+			}
+		} else if strings.TrimSpace(rule.Attribute) == "" {
+			add("attribute", "attribute is required")
+		}
+
+		if rule.ContextKind != "" && !slices.Contains(ContextKinds, rule.ContextKind) {
+			add("context_kind", fmt.Sprintf("context_kind must be one of %s", strings.Join(ContextKinds, ", ")))
+		}
+
+		switch rule.Operator {
+		case OperatorEquals, OperatorNotEquals:
+			if !isScalar(rule.Value) {
+				add("value", rule.Operator+" requires a string, number or boolean value")
+			}
+		case OperatorIn, OperatorNotIn:
+			values, ok := rule.Value.([]interface{})
+			if !ok || len(values) == 0 {
+				add("value", rule.Operator+" requires a non-empty array value")
+				break
+			}
+			for _, v := range values {
+				if !isScalar(v) {
+					add("value", rule.Operator+" values must be strings, numbers or booleans")
+					break
+				}
+			}
+		case OperatorGreaterThan, OperatorLessThan:
+			if _, isDate := ParseDate(rule.Value); !isNumber(rule.Value) && !isDate {
+				add("value", rule.Operator+" requires a number or date value")
+			}
+			if !rule.IsCaseSensitive() {
+				add("case_sensitive", rule.Operator+" compares numbers and dates, so case_sensitive doesn't apply")
+			}
+		case OperatorMatches:
+			pattern, ok := rule.Value.(string)
+			if !ok {
+				add("value", rule.Operator+" requires a string pattern")
+				break
+			}
+			if _, err := CompilePattern(pattern); err != nil {
+				add("value", err.Error())
+			}
+		case OperatorTimeWindow:
+			if _, _, err := ParseTimeWindow(rule.Value); err != nil {
+				add("value", err.Error())
+			}
+			if !rule.IsCaseSensitive() {
+				add("case_sensitive", rule.Operator+" compares times, so case_sensitive doesn't apply")
+			}
+		case "":
+			add("operator", "operator is required")
+		default:
+			add("operator", fmt.Sprintf("unknown operator %q", rule.Operator))
+		}
+
+		if rule.Rollout < 0 || rule.Rollout > 100 {
+			add("rollout", "rollout must be between 0 and 100")
+		}
+
+		if rule.Order < 0 {
+			add("order", "order must not be negative")
+		}
+	}
+
+	if len(errs) > 0 {
+		return &RuleValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// isScalar reports whether a rule value is a string, number or boolean
+func isScalar(v interface{}) bool {
+	if _, ok := v.(string); ok {
+		return true
+	}
+	if _, ok := v.(bool); ok {
+		return true
+	}
+	return isNumber(v)
+}
+
+// isNumber reports whether a rule value is numeric (JSON numbers decode as float64)
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case float64, float32, int, int32, int64:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateOwner checks that a flag's owner, if set, is a member of the tenant.
+// An empty owner ID clears the owner.
+func (s *service) validateOwner(ctx context.Context, f *Flag, tenantID string) error {
+	if f.OwnerUserID != nil && *f.OwnerUserID == "" {
+		f.OwnerUserID = nil
+	}
+	if f.OwnerUserID == nil {
+		return nil
+	}
+
+	if _, err := uuid.Parse(*f.OwnerUserID); err != nil {
+		return fmt.Errorf("%w: owner_user_id must be a valid user ID", ErrInvalidFlagData)
+	}
+
+	if err := s.validator.ValidateTenantMembership(ctx, *f.OwnerUserID, tenantID); err != nil {
+		if errors.Is(err, pkgErrors.ErrUserNotInTenant) {
+			s.logger.Warn("flag owner is not a tenant member",
+				slog.String("owner_user_id", *f.OwnerUserID),
+				slog.String("tenant_id", tenantID),
+			)
+			return fmt.Errorf("%w: owner must be a member of the tenant", ErrInvalidFlagData)
+		}
+		s.logger.Error("failed to validate flag owner",
+			slog.String("owner_user_id", *f.OwnerUserID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to validate flag owner: %w", err)
+	}
+
+	return nil
+}
+
+// sanitizeFlag cleans free-text fields in place so stored content is safe to render
+func (s *service) sanitizeFlag(f *Flag) error {
+	description, err := sanitize.Text(f.Description, sanitize.MaxDescriptionLength)
+	if err != nil {
+		return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidFlagData, sanitize.MaxDescriptionLength)
+	}
+	f.Description = description
+
+	return nil
+}
+
+type CreateRequest struct {
+	ProjectID   *string `json:"project_id,omitempty"`
+	OwnerUserID *string `json:"owner_user_id,omitempty"`
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
+	Rules       []Rule  `json:"rules"`
+	RuleLogic   string  `json:"rule_logic"`
+	// Lifecycle is draft or active (the default)
+	Lifecycle string `json:"lifecycle"`
+	// ExpiresAt, when set, automatically disables the flag at that time
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type CloneRequest struct {
+	ProjectID string `json:"project_id" binding:"required"`
+	Name      string `json:"name"`
+}
+
+type AddExclusionsRequest struct {
+	UserKeys []string `json:"user_keys" binding:"required"`
+	Reason   string   `json:"reason"`
+}
+
+type SetCustomFieldRequest struct {
+	Value string `json:"value" binding:"required"`
+}
+
+type SetEnvironmentConfigRequest struct {
+	Enabled   *bool  `json:"enabled" binding:"required"`
+	Rules     []Rule `json:"rules"`
+	RuleLogic string `json:"rule_logic"`
+}
+
+type PromoteRequest struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+type CreateOverrideRequest struct {
+	UserKey   string    `json:"user_key" binding:"required"`
+	Enabled   *bool     `json:"enabled" binding:"required"`
+	ExpiresAt time.Time `json:"expires_at" binding:"required"`
+	Reason    string    `json:"reason"`
+}
+
+type UpdateRequest struct {
+	ProjectID   *string    `json:"project_id,omitempty"`
+	OwnerUserID *string    `json:"owner_user_id,omitempty"` // empty string clears the owner
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	Enabled     *bool      `json:"enabled"`
+	Rules       []Rule     `json:"rules"`
+	RuleLogic   *string    `json:"rule_logic"`
+	Lifecycle   *string    `json:"lifecycle"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// ClearExpiresAt removes the flag's expiry; it takes precedence over ExpiresAt
+	ClearExpiresAt bool `json:"clear_expires_at,omitempty"`
+}
+
+// Apply copies the request's set fields onto a flag. Rules sent without IDs keep the IDs
+// of the flag's rules they replace; see KeepRuleIDs.
+func (r UpdateRequest) Apply(f *Flag) {
+	if r.ProjectID != nil {
+		f.ProjectID = r.ProjectID
+	}
+	if r.OwnerUserID != nil {
+		f.OwnerUserID = r.OwnerUserID
+	}
+	if r.Name != nil {
+		f.Name = *r.Name
+	}
+	if r.Description != nil {
+		f.Description = *r.Description
+	}
+	if r.Enabled != nil {
+		f.Enabled = *r.Enabled
+	}
+	if r.Rules != nil {
+		KeepRuleIDs(r.Rules, f.Rules)
+		f.Rules = r.Rules
+	}
+	if r.RuleLogic != nil {
+		f.RuleLogic = *r.RuleLogic
+	}
+	if r.Lifecycle != nil {
+		f.Lifecycle = *r.Lifecycle
+	}
+	if r.ExpiresAt != nil {
+		f.ExpiresAt = r.ExpiresAt
+	}
+	if r.ClearExpiresAt {
+		f.ExpiresAt = nil
+	}
+}
+
+// Fields a PatchRequest's update mask can name
+const (
+	PatchFieldName        = "name"
+	PatchFieldDescription = "description"
+	PatchFieldEnabled     = "enabled"
+	PatchFieldRules       = "rules"
+	PatchFieldRuleLogic   = "rule_logic"
+	PatchFieldProjectID   = "project_id"
+)
+
+// PatchFields lists the fields a PatchRequest can update
+var PatchFields = []string{PatchFieldName, PatchFieldDescription, PatchFieldEnabled, PatchFieldRules, PatchFieldRuleLogic, PatchFieldProjectID}
+
+// PatchRequest sets exactly the fields named in UpdateMask; the others are left as stored,
+// and a masked field left out of the body is set to its zero value
+type PatchRequest struct {
+	UpdateMask  []string `json:"update_mask" binding:"required"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Enabled     bool     `json:"enabled"`
+	Rules       []Rule   `json:"rules"`
+	RuleLogic   string   `json:"rule_logic"`
+	ProjectID   string   `json:"project_id"`
+}
+
+// Has reports whether the mask names field
+func (r PatchRequest) Has(field string) bool {
+	return slices.Contains(r.UpdateMask, field)
+}
+
+// Validate checks the mask and the masked fields
+func (r PatchRequest) Validate() error {
+	if len(r.UpdateMask) == 0 {
+		return fmt.Errorf("%w: update_mask must name at least one field", ErrInvalidFlagData)
+	}
+	for _, field := range r.UpdateMask {
+		if !slices.Contains(PatchFields, field) {
+			return fmt.Errorf("%w: update_mask field %q must be one of %s", ErrInvalidFlagData, field, strings.Join(PatchFields, ", "))
+		}
+	}
+	if r.Has(PatchFieldName) && r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidFlagData)
+	}
+	if r.Has(PatchFieldRuleLogic) && !ValidRuleLogic(r.RuleLogic) {
+		return fmt.Errorf("%w: rule_logic must be AND, OR or FIRST_MATCH", ErrInvalidFlagData)
+	}
+	if r.Has(PatchFieldProjectID) && r.ProjectID == "" {
+		return fmt.Errorf("%w: project_id cannot be empty", ErrInvalidFlagData)
+	}
+	if r.Has(PatchFieldRules) {
+		return ValidateRules(r.Rules)
+	}
+	return nil
+}
+
+// Flag returns the request's values as a flag for Repository.Patch. Its rules have only
+// the IDs they were sent with; Service.Patch fills in the rest.
+func (r PatchRequest) Flag() *Flag {
+	f := &Flag{Name: r.Name, Description: r.Description, Enabled: r.Enabled, Rules: r.Rules, RuleLogic: r.RuleLogic}
+	if f.Rules == nil {
+		f.Rules = []Rule{}
+	}
+	if r.ProjectID != "" {
+		f.ProjectID = &r.ProjectID
+	}
+	return f
+}
+
+// duplicateName returns a *DuplicateNameError if err is the project name unique violation, or nil
+func duplicateName(err error, f *Flag) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" || pqErr.Constraint != projectNameConstraint {
+		return nil
+	}
+
+	dupErr := &DuplicateNameError{Name: f.Name}
+	if f.ProjectID != nil {
+		dupErr.ProjectID = *f.ProjectID
+	}
+	return dupErr
+}
+
+// Validate checks the fields the request sets, before they are applied to a flag.
+// Moving a flag to another project is allowed; ownership is checked by Service.Update.
+func (r UpdateRequest) Validate() error {
+	if r.ProjectID != nil && *r.ProjectID == "" {
+		return fmt.Errorf("%w: project_id cannot be empty", ErrInvalidFlagData)
+	}
+	if r.RuleLogic != nil && !ValidRuleLogic(*r.RuleLogic) {
+		return fmt.Errorf("%w: rule_logic must be AND, OR or FIRST_MATCH", ErrInvalidFlagData)
+	}
+	if r.Lifecycle != nil && !ValidLifecycle(*r.Lifecycle) {
+		return fmt.Errorf("%w: lifecycle must be draft, active, deprecated or archived", ErrInvalidFlagData)
+	}
+	if r.Rules != nil {
+		return ValidateRules(r.Rules)
+	}
+	return nil
+}
+
+// IsEmpty reports whether the request changes nothing
+func (r UpdateRequest) IsEmpty() bool {
+	return r.ProjectID == nil && r.OwnerUserID == nil && r.Name == nil && r.Description == nil &&
+		r.Enabled == nil && r.Rules == nil && r.RuleLogic == nil && r.Lifecycle == nil && r.ExpiresAt == nil && !r.ClearExpiresAt
 }
@@ -4,15 +4,24 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/lib/pq"
 
 	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
 )
 
 type mockValidator struct {
 	validateProjectOwnershipFunc func(ctx context.Context, projectID, tenantID string) error
 	validateTenantExistsFunc     func(ctx context.Context, tenantID string) error
+	validateMembershipFunc       func(ctx context.Context, userID, tenantID string) error
 }
 
 func (m *mockValidator) ValidateProjectOwnership(ctx context.Context, projectID, tenantID string) error {
@@ -29,13 +38,45 @@ func (m *mockValidator) ValidateTenantExists(ctx context.Context, tenantID strin
 	return nil
 }
 
+func (m *mockValidator) ValidateTenantMembership(ctx context.Context, userID, tenantID string) error {
+	if m.validateMembershipFunc != nil {
+		return m.validateMembershipFunc(ctx, userID, tenantID)
+	}
+	return nil
+}
+
 type mockRepository struct {
-	createFunc      func(ctx context.Context, f *Flag) error
-	getByIDFunc     func(ctx context.Context, id string, tenantID string) (*Flag, error)
-	listFunc        func(ctx context.Context, tenantID string) ([]Flag, error)
-	listByProjectFn func(ctx context.Context, projectID string, tenantID string) ([]Flag, error)
-	updateFunc      func(ctx context.Context, f *Flag, tenantID string) error
-	deleteFunc      func(ctx context.Context, id string, tenantID string) error
+	createFunc           func(ctx context.Context, f *Flag) error
+	getByIDFunc          func(ctx context.Context, id string, tenantID string) (*Flag, error)
+	listFunc             func(ctx context.Context, tenantID string) ([]Flag, error)
+	listByProjectFn      func(ctx context.Context, projectID string, tenantID string) ([]Flag, error)
+	updateFunc           func(ctx context.Context, f *Flag, tenantID string) error
+	toggleFunc           func(ctx context.Context, id string, tenantID string) (*Flag, error)
+	deleteFunc           func(ctx context.Context, id string, tenantID string) error
+	listStaleFn          func(ctx context.Context, tenantID string, cutoff time.Time) ([]StaleFlag, error)
+	addExclusionsFn      func(ctx context.Context, flagID string, tenantID string, userKeys []string, reason string) error
+	removeExclFn         func(ctx context.Context, flagID string, tenantID string, userKey string) error
+	upsertOverrideFn     func(ctx context.Context, o *Override, tenantID string) error
+	deleteExpiredFn      func(ctx context.Context, now time.Time) (int64, error)
+	disableExpiredFn     func(ctx context.Context, now time.Time) (int64, error)
+	seenAttributes       map[string]time.Time
+	registeredAttributes []RegisteredAttribute
+
+	listCustomFieldsFn func(ctx context.Context, flagID string, tenantID string) ([]CustomField, error)
+	setCustomFieldFn   func(ctx context.Context, flagID string, tenantID string, name string, value string) (*CustomField, error)
+
+	environments   map[string]*EnvironmentConfig // environment key -> config; nil config means unconfigured
+	setEnvConfigFn func(ctx context.Context, c *EnvironmentConfig, tenantID string) error
+	history        []HistoryEntry
+	salts          map[string]string
+	patched        *Flag
+	patchMask      []string
+	resultCounts   map[string]ResultCount
+	resultsSince   time.Time
+	hourlyCounts   []HourlyResultCount
+	projectConfigs map[string][]EnvironmentConfig
+	// resetEnvironments lists the flags whose environment configs were reset
+	resetEnvironments []string
 }
 
 func (m *mockRepository) Create(ctx context.Context, f *Flag) error {
@@ -53,7 +94,7 @@ func (m *mockRepository) GetByID(ctx context.Context, id string, tenantID string
 	if m.getByIDFunc != nil {
 		return m.getByIDFunc(ctx, id, tenantID)
 	}
-	return nil, nil
+	return &Flag{ID: id, TenantID: tenantID, Lifecycle: LifecycleActive}, nil
 }
 
 func (m *mockRepository) List(ctx context.Context, tenantID string) ([]Flag, error) {
@@ -70,6 +111,10 @@ func (m *mockRepository) ListByProject(ctx context.Context, projectID string, te
 	return nil, nil
 }
 
+func (m *mockRepository) ListByOwner(ctx context.Context, tenantID string, ownerUserID string) ([]Flag, error) {
+	return nil, nil
+}
+
 func (m *mockRepository) Update(ctx context.Context, f *Flag, tenantID string) error {
 	if m.updateFunc != nil {
 		return m.updateFunc(ctx, f, tenantID)
@@ -77,6 +122,34 @@ func (m *mockRepository) Update(ctx context.Context, f *Flag, tenantID string) e
 	return nil
 }
 
+func (m *mockRepository) Toggle(ctx context.Context, id string, tenantID string) (*Flag, error) {
+	if m.toggleFunc != nil {
+		return m.toggleFunc(ctx, id, tenantID)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockRepository) Patch(ctx context.Context, id string, tenantID string, f *Flag, mask []string) (*Flag, error) {
+	if id == "missing" {
+		return nil, sql.ErrNoRows
+	}
+	m.patched, m.patchMask = f, mask
+	patched := *f
+	patched.ID = id
+	return &patched, nil
+}
+
+func (m *mockRepository) SetSalt(ctx context.Context, id string, tenantID string, salt string) error {
+	if id == "missing" {
+		return sql.ErrNoRows
+	}
+	if m.salts == nil {
+		m.salts = make(map[string]string)
+	}
+	m.salts[id] = salt
+	return nil
+}
+
 func (m *mockRepository) Delete(ctx context.Context, id string, tenantID string) error {
 	if m.deleteFunc != nil {
 		return m.deleteFunc(ctx, id, tenantID)
@@ -84,9 +157,214 @@ func (m *mockRepository) Delete(ctx context.Context, id string, tenantID string)
 	return nil
 }
 
+func (m *mockRepository) RecordEvaluations(ctx context.Context, flagIDs []string, at time.Time) error {
+	return nil
+}
+
+func (m *mockRepository) RecordResults(ctx context.Context, counts map[string]ResultCount, hour time.Time) error {
+	return nil
+}
+
+func (m *mockRepository) SumResults(ctx context.Context, tenantID string, since time.Time) (map[string]ResultCount, error) {
+	m.resultsSince = since
+	return m.resultCounts, nil
+}
+
+func (m *mockRepository) ListResultCounts(ctx context.Context, flagID string, tenantID string, since time.Time) ([]HourlyResultCount, error) {
+	m.resultsSince = since
+	return m.hourlyCounts, nil
+}
+
+func (m *mockRepository) DeleteResultCounts(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepository) ListStale(ctx context.Context, tenantID string, cutoff time.Time) ([]StaleFlag, error) {
+	if m.listStaleFn != nil {
+		return m.listStaleFn(ctx, tenantID, cutoff)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) RecordAttributes(ctx context.Context, projectID string, attributes []string, at time.Time) error {
+	return nil
+}
+
+func (m *mockRepository) ListSeenAttributes(ctx context.Context, projectID string, tenantID string) (map[string]time.Time, error) {
+	return m.seenAttributes, nil
+}
+
+func (m *mockRepository) ListRegisteredAttributes(ctx context.Context, projectID string, tenantID string) ([]RegisteredAttribute, error) {
+	return m.registeredAttributes, nil
+}
+
+func (m *mockRepository) UpsertRegisteredAttribute(ctx context.Context, a *RegisteredAttribute, tenantID string) error {
+	m.registeredAttributes = append(m.registeredAttributes, *a)
+	return nil
+}
+
+func (m *mockRepository) DeleteRegisteredAttribute(ctx context.Context, id string, projectID string, tenantID string) error {
+	return nil
+}
+
+func (m *mockRepository) DisableExpired(ctx context.Context, now time.Time) (int64, error) {
+	if m.disableExpiredFn != nil {
+		return m.disableExpiredFn(ctx, now)
+	}
+	return 0, nil
+}
+
+func (m *mockRepository) BackfillKeys(ctx context.Context, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepository) CountMissingKeys(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockRepository) ListHistory(ctx context.Context, flagID string, tenantID string) ([]HistoryEntry, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) ListCustomFields(ctx context.Context, flagID string, tenantID string) ([]CustomField, error) {
+	if m.listCustomFieldsFn != nil {
+		return m.listCustomFieldsFn(ctx, flagID, tenantID)
+	}
+	return []CustomField{}, nil
+}
+
+func (m *mockRepository) SetCustomField(ctx context.Context, flagID string, tenantID string, name string, value string) (*CustomField, error) {
+	if m.setCustomFieldFn != nil {
+		return m.setCustomFieldFn(ctx, flagID, tenantID, name, value)
+	}
+	return &CustomField{FlagID: flagID, Name: name, Value: value}, nil
+}
+
+func (m *mockRepository) DeleteCustomField(ctx context.Context, flagID string, tenantID string, name string) error {
+	return nil
+}
+
+func (m *mockRepository) ListEnvironmentConfigs(ctx context.Context, flagID string, tenantID string) ([]EnvironmentConfig, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) GetEnvironmentConfig(ctx context.Context, flagID string, environmentID string, tenantID string) (*EnvironmentConfig, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockRepository) SetEnvironmentConfig(ctx context.Context, c *EnvironmentConfig, tenantID string) error {
+	if m.setEnvConfigFn != nil {
+		return m.setEnvConfigFn(ctx, c, tenantID)
+	}
+	return nil
+}
+
+func (m *mockRepository) GetEnvironmentConfigByKey(ctx context.Context, flagID string, environmentKey string, tenantID string) (string, *EnvironmentConfig, error) {
+	config, ok := m.environments[environmentKey]
+	if !ok {
+		return "", nil, sql.ErrNoRows
+	}
+	return "env-" + environmentKey, config, nil
+}
+
+func (m *mockRepository) ResetEnvironmentConfigs(ctx context.Context, flagID string, tenantID string) error {
+	m.resetEnvironments = append(m.resetEnvironments, flagID)
+	return nil
+}
+
+func (m *mockRepository) RecordHistory(ctx context.Context, e *HistoryEntry, tenantID string) error {
+	m.history = append(m.history, *e)
+	return nil
+}
+
+func (m *mockRepository) ListEnvironmentConfigsByProject(ctx context.Context, projectID string, tenantID string) (map[string][]EnvironmentConfig, error) {
+	return m.projectConfigs, nil
+}
+
+func (m *mockRepository) ListExclusions(ctx context.Context, flagID string, tenantID string) ([]Exclusion, error) {
+	return []Exclusion{}, nil
+}
+
+func (m *mockRepository) AddExclusions(ctx context.Context, flagID string, tenantID string, userKeys []string, reason string) error {
+	if m.addExclusionsFn != nil {
+		return m.addExclusionsFn(ctx, flagID, tenantID, userKeys, reason)
+	}
+	return nil
+}
+
+func (m *mockRepository) RemoveExclusion(ctx context.Context, flagID string, tenantID string, userKey string) error {
+	if m.removeExclFn != nil {
+		return m.removeExclFn(ctx, flagID, tenantID, userKey)
+	}
+	return nil
+}
+
+func (m *mockRepository) ListExcludedFlagIDs(ctx context.Context, tenantID string, userKey string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) ListExclusionsByProject(ctx context.Context, projectID string, tenantID string) (map[string][]string, error) {
+	return map[string][]string{}, nil
+}
+
+func (m *mockRepository) UpsertOverride(ctx context.Context, o *Override, tenantID string) error {
+	if m.upsertOverrideFn != nil {
+		return m.upsertOverrideFn(ctx, o, tenantID)
+	}
+	return nil
+}
+
+func (m *mockRepository) ListOverrides(ctx context.Context, flagID string, tenantID string, now time.Time) ([]Override, error) {
+	return []Override{}, nil
+}
+
+func (m *mockRepository) DeleteOverride(ctx context.Context, flagID string, tenantID string, userKey string) error {
+	return nil
+}
+
+func (m *mockRepository) ListActiveOverrides(ctx context.Context, tenantID string, userKey string, now time.Time) (map[string]bool, error) {
+	return map[string]bool{}, nil
+}
+
+func (m *mockRepository) ListOverridesByProject(ctx context.Context, projectID string, tenantID string, now time.Time) (map[string][]Override, error) {
+	return map[string][]Override{}, nil
+}
+
+func (m *mockRepository) DeleteExpiredOverrides(ctx context.Context, now time.Time) (int64, error) {
+	if m.deleteExpiredFn != nil {
+		return m.deleteExpiredFn(ctx, now)
+	}
+	return 0, nil
+}
+
 // Note: We pass nil for validator in tests since validator logic is tested separately
 // In production, actual validator is injected via dependency injection
 
+func TestServiceCreate_DefaultsRuleLogicAndTenant(t *testing.T) {
+	var stored *Flag
+	mockRepo := &mockRepository{
+		createFunc: func(ctx context.Context, f *Flag) error {
+			stored = f
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	f := &Flag{Name: "test-flag", TenantID: "spoofed-tenant"}
+	if err := svc.Create(context.Background(), f, "tenant-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stored.TenantID != "tenant-1" {
+		t.Errorf("expected tenant from the caller, got %s", stored.TenantID)
+	}
+	if stored.RuleLogic != RuleLogicAnd {
+		t.Errorf("expected rule_logic to default to AND, got %q", stored.RuleLogic)
+	}
+	if stored.Rules == nil {
+		t.Error("expected rules to default to an empty list")
+	}
+}
+
 func TestServiceCreate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -411,117 +689,2186 @@ func TestServiceUpdate(t *testing.T) {
 	}
 }
 
-func TestServiceDelete(t *testing.T) {
+func TestServiceToggle(t *testing.T) {
 	tests := []struct {
 		name    string
 		id      string
-		mockFn  func(ctx context.Context, id string, tenantID string) error
+		mockFn  func(ctx context.Context, id string, tenantID string) (*Flag, error)
 		wantErr error
 	}{
 		{
-			name:    "successful delete",
-			id:      "test-id",
-			mockFn:  nil,
-			wantErr: nil,
+			name: "successful toggle",
+			id:   "test-id",
+			mockFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return &Flag{ID: id, Enabled: true}, nil
+			},
 		},
 		{
 			name:    "empty id",
 			id:      "",
-			mockFn:  nil,
 			wantErr: ErrInvalidFlagData,
 		},
 		{
 			name: "flag not found",
 			id:   "non-existent",
-			mockFn: func(ctx context.Context, id string, tenantID string) error {
-				return sql.ErrNoRows
+			mockFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return nil, sql.ErrNoRows
 			},
 			wantErr: pkgErrors.ErrNotFound,
 		},
 		{
 			name: "repository error",
 			id:   "test-id",
-			mockFn: func(ctx context.Context, id string, tenantID string) error {
-				return errors.New("database error")
+			mockFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return nil, errors.New("database error")
 			},
-			wantErr: errors.New("failed to delete flag: database error"),
+			wantErr: errors.New("failed to toggle flag: database error"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := &mockRepository{
-				deleteFunc: tt.mockFn,
+				toggleFunc: tt.mockFn,
 			}
-			mockVal := &mockValidator{}
-			svc := NewService(mockRepo, mockVal, slog.Default())
+			svc := NewService(mockRepo, &mockValidator{}, slog.Default())
 
-			err := svc.Delete(context.Background(), tt.id, "test-tenant-id")
+			flag, err := svc.Toggle(context.Background(), tt.id, "test-user-id", "test-tenant-id")
 
 			if tt.wantErr != nil {
 				if err == nil {
-					t.Errorf("expected error %v, got nil", tt.wantErr)
-					return
+					t.Fatalf("expected error %v, got nil", tt.wantErr)
 				}
 				if !errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error() {
 					t.Errorf("expected error %v, got %v", tt.wantErr, err)
 				}
-			} else {
-				if err != nil {
-					t.Errorf("expected no error, got %v", err)
-				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !flag.Enabled {
+				t.Error("expected the toggled flag to be returned")
 			}
 		})
 	}
 }
 
-func TestValidateFlag(t *testing.T) {
+func TestServiceToggle_RecordsActorInHistory(t *testing.T) {
+	mockRepo := &mockRepository{
+		toggleFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			return &Flag{ID: id, Enabled: false}, nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	if _, err := svc.Toggle(context.Background(), "test-id", "test-user-id", "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.Toggle(context.Background(), "test-id", "", "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(mockRepo.history) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(mockRepo.history))
+	}
+	entry := mockRepo.history[0]
+	if entry.Action != HistoryActionToggled || entry.FlagID != "test-id" {
+		t.Errorf("expected a toggled entry for test-id, got %+v", entry)
+	}
+	if entry.ActorID == nil || *entry.ActorID != "test-user-id" {
+		t.Errorf("expected the actor to be recorded, got %v", entry.ActorID)
+	}
+	if entry.Details["enabled"] != false {
+		t.Errorf("expected the new state to be recorded, got %v", entry.Details)
+	}
+	if mockRepo.history[1].ActorID != nil {
+		t.Errorf("expected a system toggle to have no actor, got %v", *mockRepo.history[1].ActorID)
+	}
+}
+
+func TestServicePatch(t *testing.T) {
 	tests := []struct {
 		name    string
-		flag    *Flag
+		id      string
+		req     PatchRequest
 		wantErr error
 	}{
-		{
-			name: "valid flag",
-			flag: &Flag{
-				Name:        "test-flag",
-				Description: "test description",
-			},
-			wantErr: nil,
-		},
-		{
-			name:    "nil flag",
-			flag:    nil,
-			wantErr: ErrInvalidFlagData,
-		},
-		{
-			name: "empty name",
-			flag: &Flag{
-				Name:        "",
-				Description: "test description",
-			},
-			wantErr: ErrInvalidFlagData,
-		},
+		{name: "disables without touching other fields", id: "test-id", req: PatchRequest{UpdateMask: []string{"enabled"}, Enabled: false}},
+		{name: "clears the description", id: "test-id", req: PatchRequest{UpdateMask: []string{"description", "name"}, Name: "renamed"}},
+		{name: "empty mask", id: "test-id", req: PatchRequest{}, wantErr: ErrInvalidFlagData},
+		{name: "unknown mask field", id: "test-id", req: PatchRequest{UpdateMask: []string{"salt"}}, wantErr: ErrInvalidFlagData},
+		{name: "masked name cannot be empty", id: "test-id", req: PatchRequest{UpdateMask: []string{"name"}}, wantErr: ErrInvalidFlagData},
+		{name: "masked rule logic must be valid", id: "test-id", req: PatchRequest{UpdateMask: []string{"rule_logic"}, RuleLogic: "XOR"}, wantErr: ErrInvalidFlagData},
+		{name: "masked rules are validated", id: "test-id", req: PatchRequest{UpdateMask: []string{"rules"}, Rules: []Rule{{Operator: OperatorEquals, Value: "AU"}}}, wantErr: ErrInvalidFlagData},
+		{name: "flag not found", id: "missing", req: PatchRequest{UpdateMask: []string{"enabled"}}, wantErr: pkgErrors.ErrNotFound},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			svc := &service{}
-			err := svc.validateFlag(tt.flag)
+			mockRepo := &mockRepository{}
+			svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+			_, err := svc.Patch(context.Background(), tt.id, tt.req, "test-tenant-id")
 
 			if tt.wantErr != nil {
-				if err == nil {
-					t.Errorf("expected error %v, got nil", tt.wantErr)
-					return
-				}
 				if !errors.Is(err, tt.wantErr) {
-					t.Errorf("expected error %v, got %v", tt.wantErr, err)
-				}
-			} else {
-				if err != nil {
-					t.Errorf("expected no error, got %v", err)
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
 				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !slices.Equal(mockRepo.patchMask, tt.req.UpdateMask) {
+				t.Errorf("expected mask %v to reach the repository, got %v", tt.req.UpdateMask, mockRepo.patchMask)
 			}
 		})
 	}
 }
+
+func TestServicePatch_ChecksProjectOwnership(t *testing.T) {
+	mockRepo := &mockRepository{}
+	validator := &mockValidator{
+		validateProjectOwnershipFunc: func(ctx context.Context, projectID, tenantID string) error {
+			return errors.New("project not in tenant")
+		},
+	}
+	svc := NewService(mockRepo, validator, slog.Default())
+
+	_, err := svc.Patch(context.Background(), "test-id", PatchRequest{UpdateMask: []string{"project_id"}, ProjectID: "other-project"}, "test-tenant-id")
+
+	if !errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+		t.Fatalf("expected ErrProjectNotInTenant, got %v", err)
+	}
+	if mockRepo.patched != nil {
+		t.Error("a flag must not be moved into another tenant's project")
+	}
+}
+
+func TestService_ProjectMoveResetsEnvironmentConfigs(t *testing.T) {
+	project1, project2 := "project-1", "project-2"
+	newRepo := func() *mockRepository {
+		return &mockRepository{
+			getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return &Flag{ID: id, ProjectID: &project1, Lifecycle: LifecycleActive}, nil
+			},
+		}
+	}
+
+	mockRepo := newRepo()
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+	if err := svc.Update(context.Background(), &Flag{ID: "flag-1", Name: "checkout", ProjectID: &project1}, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockRepo.resetEnvironments) != 0 {
+		t.Error("an update within the same project keeps the environment configs")
+	}
+	if err := svc.Update(context.Background(), &Flag{ID: "flag-1", Name: "checkout", ProjectID: &project2}, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.Equal(mockRepo.resetEnvironments, []string{"flag-1"}) {
+		t.Errorf("expected moving the flag to reset its environment configs, got %v", mockRepo.resetEnvironments)
+	}
+
+	mockRepo = newRepo()
+	svc = NewService(mockRepo, &mockValidator{}, slog.Default())
+	if _, err := svc.Patch(context.Background(), "flag-1", PatchRequest{UpdateMask: []string{"enabled"}}, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.Patch(context.Background(), "flag-1", PatchRequest{UpdateMask: []string{"project_id"}, ProjectID: project2}, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.Equal(mockRepo.resetEnvironments, []string{"flag-1"}) {
+		t.Errorf("expected only the project move to reset environment configs, got %v", mockRepo.resetEnvironments)
+	}
+}
+
+// stubApprovalGate requires approval for a project's flags when flags is set, and for their
+// configs in the listed environments
+type stubApprovalGate struct {
+	projectID    string
+	flags        bool
+	environments []string
+}
+
+func (g stubApprovalGate) RequiresApproval(ctx context.Context, projectID string, environmentID string, tenantID string) (bool, error) {
+	if projectID != g.projectID {
+		return false, nil
+	}
+	if environmentID == "" {
+		return g.flags, nil
+	}
+	return slices.Contains(g.environments, environmentID), nil
+}
+
+func TestService_ApprovalGate(t *testing.T) {
+	project1, project2 := "project-1", "project-2"
+	var writes []string
+	mockRepo := &mockRepository{
+		getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			projectID := project1
+			if id == "flag-2" {
+				projectID = project2
+			}
+			return &Flag{ID: id, Name: "checkout", ProjectID: &projectID, Lifecycle: LifecycleActive}, nil
+		},
+		updateFunc: func(ctx context.Context, f *Flag, tenantID string) error {
+			writes = append(writes, "update "+f.ID)
+			return nil
+		},
+		toggleFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			writes = append(writes, "toggle "+id)
+			return &Flag{ID: id}, nil
+		},
+		setEnvConfigFn: func(ctx context.Context, c *EnvironmentConfig, tenantID string) error {
+			writes = append(writes, "configure "+c.EnvironmentID)
+			return nil
+		},
+		environments: map[string]*EnvironmentConfig{"staging": {Enabled: true, RuleLogic: RuleLogicAnd}, "production": nil},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+	svc.SetApprovalGate(stubApprovalGate{projectID: project1, flags: true, environments: []string{"env-production"}})
+	ctx := context.Background()
+	enabled := true
+
+	_, patchErr := svc.Patch(ctx, "flag-1", PatchRequest{UpdateMask: []string{"enabled"}, Enabled: true}, "test-tenant-id")
+	_, moveErr := svc.Patch(ctx, "flag-2", PatchRequest{UpdateMask: []string{"project_id"}, ProjectID: project1}, "test-tenant-id")
+	_, toggleErr := svc.Toggle(ctx, "flag-1", "test-user-id", "test-tenant-id")
+	_, reshuffleErr := svc.Reshuffle(ctx, "flag-1", "test-user-id", "test-tenant-id")
+	_, configErr := svc.SetEnvironmentConfig(ctx, "flag-2", "env-production", SetEnvironmentConfigRequest{Enabled: &enabled}, "test-tenant-id")
+	_, productionErr := svc.SetEnvironmentConfig(ctx, "flag-1", "env-production", SetEnvironmentConfigRequest{Enabled: &enabled}, "test-tenant-id")
+	_, promoteErr := svc.Promote(ctx, "flag-1", PromoteRequest{From: "staging", To: "production"}, "test-user-id", "test-tenant-id")
+	refused := map[string]error{
+		"update":                svc.Update(ctx, &Flag{ID: "flag-1", Name: "checkout", ProjectID: &project1}, "test-tenant-id"),
+		"patch":                 patchErr,
+		"move into the project": moveErr,
+		"toggle":                toggleErr,
+		"reshuffle":             reshuffleErr,
+		"production config":     productionErr,
+		"promote to production": promoteErr,
+	}
+	for name, err := range refused {
+		if !errors.Is(err, ErrApprovalRequired) {
+			t.Errorf("%s: expected ErrApprovalRequired, got %v", name, err)
+		}
+	}
+	if configErr != nil {
+		t.Errorf("expected other projects' environments to be editable, got %v", configErr)
+	}
+	if !slices.Equal(writes, []string{"configure env-production"}) || mockRepo.patched != nil || len(mockRepo.salts) != 0 {
+		t.Fatalf("expected refused edits to write nothing, got %v", writes)
+	}
+
+	writes = nil
+	if err := svc.ApplyApprovedChange(ctx, &Flag{ID: "flag-1", Name: "checkout", ProjectID: &project1}, "test-tenant-id"); err != nil {
+		t.Fatalf("expected an approved change to be applied, got %v", err)
+	}
+	config, err := svc.ApplyApprovedEnvironmentChange(ctx, "flag-1", "production", UpdateRequest{Enabled: &enabled}, "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected an approved environment change to be applied, got %v", err)
+	}
+	if !config.Enabled || config.RuleLogic != RuleLogicAnd {
+		t.Errorf("expected the change to enable the unconfigured environment, got %+v", config)
+	}
+	if !slices.Equal(writes, []string{"update flag-1", "configure env-production"}) {
+		t.Errorf("expected approved changes to be written, got %v", writes)
+	}
+}
+
+func TestServiceReshuffle(t *testing.T) {
+	mockRepo := &mockRepository{}
+	cache := &recordingFlagCache{}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+	svc.SetFlagCache(cache)
+
+	if _, err := svc.Reshuffle(context.Background(), "missing", "test-user-id", "test-tenant-id"); !errors.Is(err, pkgErrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing flag, got %v", err)
+	}
+	if len(cache.invalidated) != 0 || len(mockRepo.history) != 0 {
+		t.Fatal("a failed reshuffle must not invalidate the cache or record history")
+	}
+
+	if _, err := svc.Reshuffle(context.Background(), "test-id", "test-user-id", "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	first := mockRepo.salts["test-id"]
+	if _, err := svc.Reshuffle(context.Background(), "test-id", "test-user-id", "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if first == "" || first == mockRepo.salts["test-id"] {
+		t.Errorf("expected each reshuffle to set a new salt, got %q then %q", first, mockRepo.salts["test-id"])
+	}
+	if len(mockRepo.history) != 2 || mockRepo.history[0].Action != HistoryActionReshuffled {
+		t.Fatalf("expected two reshuffled history entries, got %+v", mockRepo.history)
+	}
+	if actor := mockRepo.history[0].ActorID; actor == nil || *actor != "test-user-id" {
+		t.Errorf("expected the actor to be recorded, got %v", actor)
+	}
+	if len(cache.invalidated) != 2 {
+		t.Errorf("expected the cache to be invalidated on each reshuffle, got %v", cache.invalidated)
+	}
+}
+
+// recordingFlagCache records the tenants whose cached flags were invalidated
+type recordingFlagCache struct {
+	invalidated []string
+}
+
+func (c *recordingFlagCache) InvalidateTenant(tenantID string) {
+	c.invalidated = append(c.invalidated, tenantID)
+}
+
+func TestServiceToggle_InvalidatesFlagCache(t *testing.T) {
+	cache := &recordingFlagCache{}
+	mockRepo := &mockRepository{
+		toggleFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			if id == "missing" {
+				return nil, sql.ErrNoRows
+			}
+			return &Flag{ID: id, Enabled: true}, nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+	svc.SetFlagCache(cache)
+
+	if _, err := svc.Toggle(context.Background(), "missing", "test-user-id", "test-tenant-id"); err == nil {
+		t.Fatal("expected an error for a missing flag")
+	}
+	if len(cache.invalidated) != 0 {
+		t.Errorf("a failed toggle must not invalidate the cache, got %v", cache.invalidated)
+	}
+
+	if _, err := svc.Toggle(context.Background(), "test-id", "test-user-id", "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(cache.invalidated) != 1 || cache.invalidated[0] != "test-tenant-id" {
+		t.Errorf("expected the tenant's cached flags to be invalidated once, got %v", cache.invalidated)
+	}
+}
+
+func TestServiceDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		mockFn  func(ctx context.Context, id string, tenantID string) error
+		wantErr error
+	}{
+		{
+			name:    "successful delete",
+			id:      "test-id",
+			mockFn:  nil,
+			wantErr: nil,
+		},
+		{
+			name:    "empty id",
+			id:      "",
+			mockFn:  nil,
+			wantErr: ErrInvalidFlagData,
+		},
+		{
+			name: "flag not found",
+			id:   "non-existent",
+			mockFn: func(ctx context.Context, id string, tenantID string) error {
+				return sql.ErrNoRows
+			},
+			wantErr: pkgErrors.ErrNotFound,
+		},
+		{
+			name: "repository error",
+			id:   "test-id",
+			mockFn: func(ctx context.Context, id string, tenantID string) error {
+				return errors.New("database error")
+			},
+			wantErr: errors.New("failed to delete flag: database error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockRepository{
+				deleteFunc: tt.mockFn,
+			}
+			mockVal := &mockValidator{}
+			svc := NewService(mockRepo, mockVal, slog.Default())
+
+			err := svc.Delete(context.Background(), tt.id, "test-tenant-id")
+
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Errorf("expected error %v, got nil", tt.wantErr)
+					return
+				}
+				if !errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error() {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    *Flag
+		wantErr error
+	}{
+		{
+			name: "valid flag",
+			flag: &Flag{
+				Name:        "test-flag",
+				Description: "test description",
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "nil flag",
+			flag:    nil,
+			wantErr: ErrInvalidFlagData,
+		},
+		{
+			name: "empty name",
+			flag: &Flag{
+				Name:        "",
+				Description: "test description",
+			},
+			wantErr: ErrInvalidFlagData,
+		},
+		{
+			name:    "first match rule logic",
+			flag:    &Flag{Name: "test-flag", RuleLogic: RuleLogicFirstMatch},
+			wantErr: nil,
+		},
+		{
+			name:    "unknown rule logic",
+			flag:    &Flag{Name: "test-flag", RuleLogic: "XOR"},
+			wantErr: ErrInvalidFlagData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &service{}
+			err := svc.validateFlag(tt.flag)
+
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Errorf("expected error %v, got nil", tt.wantErr)
+					return
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceCreate_SanitizesDescription(t *testing.T) {
+	var stored *Flag
+	mockRepo := &mockRepository{
+		createFunc: func(ctx context.Context, f *Flag) error {
+			stored = f
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	f := &Flag{
+		Name:        "test-flag",
+		Description: "<script>alert('xss')</script>'; DELETE FROM projects; --",
+		Rules:       []Rule{},
+	}
+
+	if err := svc.Create(context.Background(), f, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if stored == nil {
+		t.Fatal("expected flag to reach the repository")
+	}
+	if stored.Description != "'; DELETE FROM projects; --" {
+		t.Errorf("expected script to be stripped, got %q", stored.Description)
+	}
+}
+
+func TestServiceUpdate_RejectsOversizedDescription(t *testing.T) {
+	mockRepo := &mockRepository{
+		updateFunc: func(ctx context.Context, f *Flag, tenantID string) error {
+			t.Error("repository should not be called for invalid description")
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	f := &Flag{
+		ID:          "test-id",
+		Name:        "test-flag",
+		Description: strings.Repeat("a", sanitize.MaxDescriptionLength+1),
+	}
+
+	err := svc.Update(context.Background(), f, "test-tenant-id")
+	if !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected ErrInvalidFlagData, got %v", err)
+	}
+}
+
+func TestServiceUpdate_RejectsInvalidLifecycleTransition(t *testing.T) {
+	mockRepo := &mockRepository{
+		getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			return &Flag{ID: id, TenantID: tenantID, Lifecycle: LifecycleArchived}, nil
+		},
+		updateFunc: func(ctx context.Context, f *Flag, tenantID string) error {
+			t.Error("repository should not be called for a disallowed transition")
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	f := &Flag{ID: "test-id", Name: "test-flag", Lifecycle: LifecycleActive, RuleLogic: "AND"}
+
+	err := svc.Update(context.Background(), f, "test-tenant-id")
+	if !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected ErrInvalidFlagData, got %v", err)
+	}
+}
+
+func TestServiceClone(t *testing.T) {
+	source := &Flag{
+		ID:          "source-id",
+		TenantID:    "test-tenant-id",
+		ProjectID:   stringPtr("source-project-id"),
+		Name:        "checkout-v2",
+		Description: "new checkout flow",
+		Enabled:     true,
+		Rules: []Rule{
+			{ID: "rule-1", Attribute: "country", Operator: "equals", Value: "AU", Rollout: 50},
+		},
+		RuleLogic: "OR",
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		projectID  string
+		cloneName  string
+		getFn      func(ctx context.Context, id string, tenantID string) (*Flag, error)
+		validateFn func(ctx context.Context, projectID, tenantID string) error
+		wantErr    error
+		wantName   string
+	}{
+		{
+			name:      "copies flag into target project",
+			id:        "source-id",
+			projectID: "target-project-id",
+			getFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return source, nil
+			},
+			wantName: "checkout-v2",
+		},
+		{
+			name:      "uses provided name",
+			id:        "source-id",
+			projectID: "target-project-id",
+			cloneName: "checkout-v2-copy",
+			getFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return source, nil
+			},
+			wantName: "checkout-v2-copy",
+		},
+		{
+			name:      "missing target project",
+			id:        "source-id",
+			projectID: "",
+			wantErr:   ErrInvalidFlagData,
+		},
+		{
+			name:      "source flag not found",
+			id:        "missing-id",
+			projectID: "target-project-id",
+			getFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return nil, sql.ErrNoRows
+			},
+			wantErr: pkgErrors.ErrNotFound,
+		},
+		{
+			name:      "target project belongs to another tenant",
+			id:        "source-id",
+			projectID: "foreign-project-id",
+			getFn: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return source, nil
+			},
+			validateFn: func(ctx context.Context, projectID, tenantID string) error {
+				return pkgErrors.ErrProjectNotInTenant
+			},
+			wantErr: pkgErrors.ErrProjectNotInTenant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *Flag
+			mockRepo := &mockRepository{
+				getByIDFunc: tt.getFn,
+				createFunc: func(ctx context.Context, f *Flag) error {
+					created = f
+					f.ID = "clone-id"
+					return nil
+				},
+			}
+			mockVal := &mockValidator{validateProjectOwnershipFunc: tt.validateFn}
+			svc := NewService(mockRepo, mockVal, slog.Default())
+
+			clone, err := svc.Clone(context.Background(), tt.id, tt.projectID, tt.cloneName, "test-tenant-id")
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				if created != nil {
+					t.Error("repository should not be called on error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if clone.ID != "clone-id" {
+				t.Errorf("expected clone ID 'clone-id', got '%s'", clone.ID)
+			}
+			if clone.ProjectID == nil || *clone.ProjectID != tt.projectID {
+				t.Errorf("expected project %s, got %v", tt.projectID, clone.ProjectID)
+			}
+			if clone.Name != tt.wantName {
+				t.Errorf("expected name '%s', got '%s'", tt.wantName, clone.Name)
+			}
+			if clone.Enabled {
+				t.Error("expected clone to start disabled")
+			}
+			if clone.Description != source.Description || clone.RuleLogic != source.RuleLogic {
+				t.Errorf("expected description and rule logic to be copied, got %q / %q", clone.Description, clone.RuleLogic)
+			}
+			if len(clone.Rules) != 1 || clone.Rules[0] != source.Rules[0] {
+				t.Errorf("expected rules to be copied, got %+v", clone.Rules)
+			}
+			if &clone.Rules[0] == &source.Rules[0] {
+				t.Error("expected clone rules to be a separate copy")
+			}
+		})
+	}
+}
+
+type mockTemplateSource struct {
+	applyFunc func(ctx context.Context, templateID string, tenantID string, f *Flag) error
+}
+
+func (m *mockTemplateSource) ApplyTemplate(ctx context.Context, templateID string, tenantID string, f *Flag) error {
+	return m.applyFunc(ctx, templateID, tenantID, f)
+}
+
+func TestServiceCreateFromTemplate(t *testing.T) {
+	applyDefaults := func(ctx context.Context, templateID string, tenantID string, f *Flag) error {
+		f.Rules = []Rule{{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 10}}
+		f.RuleLogic = "OR"
+		return nil
+	}
+
+	tests := []struct {
+		name      string
+		templates TemplateSource
+		wantErr   error
+		wantLogic string
+	}{
+		{
+			name:      "applies template before create",
+			templates: &mockTemplateSource{applyFunc: applyDefaults},
+			wantLogic: "OR",
+		},
+		{
+			name: "defaults when template leaves fields unset",
+			templates: &mockTemplateSource{applyFunc: func(ctx context.Context, templateID string, tenantID string, f *Flag) error {
+				return nil
+			}},
+			wantLogic: "AND",
+		},
+		{
+			name: "template not found",
+			templates: &mockTemplateSource{applyFunc: func(ctx context.Context, templateID string, tenantID string, f *Flag) error {
+				return pkgErrors.ErrNotFound
+			}},
+			wantErr: ErrTemplateNotFound,
+		},
+		{
+			name:      "no template source configured",
+			templates: nil,
+			wantErr:   ErrTemplateNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *Flag
+			mockRepo := &mockRepository{
+				createFunc: func(ctx context.Context, f *Flag) error {
+					created = f
+					return nil
+				},
+			}
+			svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+			if tt.templates != nil {
+				svc.SetTemplateSource(tt.templates)
+			}
+
+			err := svc.CreateFromTemplate(context.Background(), &Flag{Name: "from-template"}, "tpl-1", "test-tenant-id")
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				if created != nil {
+					t.Error("repository should not be called on error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if created == nil {
+				t.Fatal("expected flag to reach the repository")
+			}
+			if created.RuleLogic != tt.wantLogic {
+				t.Errorf("expected rule logic %s, got %s", tt.wantLogic, created.RuleLogic)
+			}
+			if created.Rules == nil {
+				t.Error("expected rules to be non-nil")
+			}
+		})
+	}
+}
+
+func TestServiceListStale(t *testing.T) {
+	t.Run("computes cutoff from days", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			listStaleFn: func(ctx context.Context, tenantID string, cutoff time.Time) ([]StaleFlag, error) {
+				expected := time.Now().AddDate(0, 0, -30)
+				if cutoff.Sub(expected).Abs() > time.Minute {
+					t.Errorf("expected cutoff near %v, got %v", expected, cutoff)
+				}
+				return nil, nil
+			},
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		flags, err := svc.ListStale(context.Background(), "test-tenant-id", 30)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if flags == nil {
+			t.Error("expected empty slice, got nil")
+		}
+	})
+
+	for _, days := range []int{0, -1, MaxStaleDays + 1} {
+		svc := NewService(&mockRepository{}, &mockValidator{}, slog.Default())
+
+		if _, err := svc.ListStale(context.Background(), "test-tenant-id", days); !errors.Is(err, ErrInvalidFlagData) {
+			t.Errorf("days=%d: expected ErrInvalidFlagData, got %v", days, err)
+		}
+	}
+}
+
+func TestServiceAttributeReport(t *testing.T) {
+	now := time.Now()
+	mockRepo := &mockRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+			return []Flag{
+				{ID: "flag-1", Rules: []Rule{{Attribute: "country"}, {Attribute: "plan"}, {Attribute: "plan"}}},
+				{ID: "flag-2", Rules: []Rule{{Attribute: "userTier"}}},
+			}, nil
+		},
+		seenAttributes: map[string]time.Time{
+			"country":   now.Add(-time.Hour),
+			"plan":      now.AddDate(0, 0, -30), // renamed client-side long ago
+			"user_tier": now.Add(-time.Hour),
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	report, err := svc.AttributeReport(context.Background(), "project-1", "test-tenant-id", 7)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	attributes := func(list []AttributeUsage) []string {
+		names := []string{}
+		for _, a := range list {
+			names = append(names, a.Attribute)
+		}
+		return names
+	}
+
+	if got := attributes(report.Unsent); !reflect.DeepEqual(got, []string{"plan", "userTier"}) {
+		t.Errorf("expected unsent [plan userTier], got %v", got)
+	}
+	if got := attributes(report.Unreferenced); !reflect.DeepEqual(got, []string{"user_tier"}) {
+		t.Errorf("expected unreferenced [user_tier], got %v", got)
+	}
+	if got := attributes(report.InUse); !reflect.DeepEqual(got, []string{"country"}) {
+		t.Errorf("expected in use [country], got %v", got)
+	}
+	if got := report.Unsent[0].FlagIDs; !reflect.DeepEqual(got, []string{"flag-1"}) {
+		t.Errorf("expected plan to be referenced once by flag-1, got %v", got)
+	}
+
+	t.Run("project in another tenant", func(t *testing.T) {
+		val := &mockValidator{
+			validateProjectOwnershipFunc: func(ctx context.Context, projectID, tenantID string) error {
+				return errors.New("not owned")
+			},
+		}
+		svc := NewService(&mockRepository{}, val, slog.Default())
+
+		if _, err := svc.AttributeReport(context.Background(), "project-1", "test-tenant-id", 7); !pkgErrors.IsNotFoundError(err) {
+			t.Errorf("expected not found, got %v", err)
+		}
+	})
+}
+
+func TestServiceAddExclusions(t *testing.T) {
+	t.Run("trims and de-duplicates user keys", func(t *testing.T) {
+		var stored []string
+		var storedReason string
+		mockRepo := &mockRepository{
+			getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+				return &Flag{ID: id, TenantID: tenantID}, nil
+			},
+			addExclusionsFn: func(ctx context.Context, flagID string, tenantID string, userKeys []string, reason string) error {
+				stored = userKeys
+				storedReason = reason
+				return nil
+			},
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		_, err := svc.AddExclusions(context.Background(), "flag-1", []string{" user-1 ", "user-2", "user-1"}, "<b>support ticket 42</b>", "tenant-1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if strings.Join(stored, ",") != "user-1,user-2" {
+			t.Errorf("expected [user-1 user-2], got %v", stored)
+		}
+		if storedReason != "support ticket 42" {
+			t.Errorf("expected sanitized reason, got %q", storedReason)
+		}
+	})
+
+	invalid := map[string][]string{
+		"no keys":       {},
+		"blank key":     {"  "},
+		"oversized key": {strings.Repeat("k", MaxUserKeyLength+1)},
+	}
+	for name, keys := range invalid {
+		t.Run(name, func(t *testing.T) {
+			svc := NewService(&mockRepository{}, &mockValidator{}, slog.Default())
+
+			if _, err := svc.AddExclusions(context.Background(), "flag-1", keys, "", "tenant-1"); !errors.Is(err, ErrInvalidFlagData) {
+				t.Errorf("expected ErrInvalidFlagData, got %v", err)
+			}
+		})
+	}
+
+	t.Run("flag in another tenant is not found", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			addExclusionsFn: func(ctx context.Context, flagID string, tenantID string, userKeys []string, reason string) error {
+				return sql.ErrNoRows
+			},
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		if _, err := svc.AddExclusions(context.Background(), "flag-1", []string{"user-1"}, "", "tenant-2"); !errors.Is(err, pkgErrors.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestServiceRemoveExclusion(t *testing.T) {
+	mockRepo := &mockRepository{
+		removeExclFn: func(ctx context.Context, flagID string, tenantID string, userKey string) error {
+			return sql.ErrNoRows
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	if err := svc.RemoveExclusion(context.Background(), "flag-1", "user-1", "tenant-1"); !errors.Is(err, pkgErrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestServiceCreate_ValidatesOwner(t *testing.T) {
+	memberID := "5b0f8f1e-3c1a-4c53-9a43-1f6d3c9b8e21"
+	outsiderID := "9d2a4c7e-8b1f-4e6a-a5d3-2c7b9e1f0a64"
+
+	tests := []struct {
+		name      string
+		owner     *string
+		wantErr   error
+		wantOwner *string
+	}{
+		{name: "no owner", owner: nil},
+		{name: "empty owner is cleared", owner: stringPtr(""), wantOwner: nil},
+		{name: "tenant member", owner: &memberID, wantOwner: &memberID},
+		{name: "not a tenant member", owner: &outsiderID, wantErr: ErrInvalidFlagData},
+		{name: "malformed owner ID", owner: stringPtr("not-a-uuid"), wantErr: ErrInvalidFlagData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val := &mockValidator{
+				validateMembershipFunc: func(ctx context.Context, userID, tenantID string) error {
+					if userID != memberID {
+						return pkgErrors.ErrUserNotInTenant
+					}
+					return nil
+				},
+			}
+			svc := NewService(&mockRepository{}, val, slog.Default())
+
+			f := &Flag{Name: "owned-flag", OwnerUserID: tt.owner, Rules: []Rule{}, RuleLogic: "AND"}
+			err := svc.Create(context.Background(), f, "test-tenant-id")
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if (f.OwnerUserID == nil) != (tt.wantOwner == nil) || (f.OwnerUserID != nil && *f.OwnerUserID != *tt.wantOwner) {
+				t.Errorf("expected owner %v, got %v", tt.wantOwner, f.OwnerUserID)
+			}
+		})
+	}
+}
+
+func TestServiceCreateOverride(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+		repoErr   error
+		wantErr   error
+	}{
+		{name: "valid override", expiresIn: time.Hour},
+		{name: "expiry in the past", expiresIn: -time.Minute, wantErr: ErrInvalidFlagData},
+		{name: "expiry too far out", expiresIn: MaxOverrideDuration + time.Hour, wantErr: ErrInvalidFlagData},
+		{name: "flag in another tenant", expiresIn: time.Hour, repoErr: sql.ErrNoRows, wantErr: pkgErrors.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockRepository{
+				upsertOverrideFn: func(ctx context.Context, o *Override, tenantID string) error {
+					if o.UserKey != "user-42" {
+						t.Errorf("expected trimmed user key, got %q", o.UserKey)
+					}
+					return tt.repoErr
+				},
+			}
+			svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+			o := &Override{FlagID: "flag-1", UserKey: " user-42 ", Enabled: true, ExpiresAt: time.Now().Add(tt.expiresIn)}
+			err := svc.CreateOverride(context.Background(), o, "test-tenant-id")
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceExpireOverrides(t *testing.T) {
+	var cutoff time.Time
+	mockRepo := &mockRepository{
+		deleteExpiredFn: func(ctx context.Context, now time.Time) (int64, error) {
+			cutoff = now
+			return 3, nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	if err := svc.ExpireOverrides(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if time.Since(cutoff) > time.Minute {
+		t.Errorf("expected cutoff near now, got %v", cutoff)
+	}
+}
+
+func TestServiceDisableExpired(t *testing.T) {
+	t.Run("passes current time to repository", func(t *testing.T) {
+		var cutoff time.Time
+		mockRepo := &mockRepository{
+			disableExpiredFn: func(ctx context.Context, now time.Time) (int64, error) {
+				cutoff = now
+				return 2, nil
+			},
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		if err := svc.DisableExpired(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if time.Since(cutoff) > time.Minute {
+			t.Errorf("expected cutoff near now, got %v", cutoff)
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		dbErr := errors.New("connection reset")
+		mockRepo := &mockRepository{
+			disableExpiredFn: func(ctx context.Context, now time.Time) (int64, error) {
+				return 0, dbErr
+			},
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		if err := svc.DisableExpired(context.Background()); !errors.Is(err, dbErr) {
+			t.Errorf("expected wrapped repository error, got %v", err)
+		}
+	})
+}
+
+func TestServiceCreateRejectsPastExpiry(t *testing.T) {
+	svc := NewService(&mockRepository{}, &mockValidator{}, slog.Default())
+
+	past := time.Now().Add(-time.Hour)
+	err := svc.Create(context.Background(), &Flag{Name: "expired", Rules: []Rule{}, RuleLogic: "AND", ExpiresAt: &past}, "test-tenant-id")
+	if !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected ErrInvalidFlagData, got %v", err)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       Rule
+		wantFields []string
+	}{
+		{name: "valid equals", rule: Rule{Attribute: "country", Operator: OperatorEquals, Value: "AU", Rollout: 50}},
+		{name: "valid in", rule: Rule{Attribute: "country", Operator: OperatorIn, Value: []interface{}{"AU", "NZ"}, Rollout: 100}},
+		{name: "valid greater_than", rule: Rule{Attribute: "age", Operator: OperatorGreaterThan, Value: float64(18)}},
+		{name: "missing attribute", rule: Rule{Operator: OperatorEquals, Value: "AU"}, wantFields: []string{"attribute"}},
+		{name: "unknown operator", rule: Rule{Attribute: "email", Operator: "contains", Value: "x"}, wantFields: []string{"operator"}},
+		{name: "missing operator", rule: Rule{Attribute: "email", Value: "x"}, wantFields: []string{"operator"}},
+		{name: "in requires array", rule: Rule{Attribute: "country", Operator: OperatorIn, Value: "AU"}, wantFields: []string{"value"}},
+		{name: "in requires non-empty array", rule: Rule{Attribute: "country", Operator: OperatorNotIn, Value: []interface{}{}}, wantFields: []string{"value"}},
+		{name: "in rejects nested values", rule: Rule{Attribute: "country", Operator: OperatorIn, Value: []interface{}{[]interface{}{"AU"}}}, wantFields: []string{"value"}},
+		{name: "greater_than requires number", rule: Rule{Attribute: "age", Operator: OperatorGreaterThan, Value: "18"}, wantFields: []string{"value"}},
+		{name: "valid matches", rule: Rule{Attribute: "email", Operator: OperatorMatches, Value: `@example\.com$`}},
+		{name: "matches requires string", rule: Rule{Attribute: "email", Operator: OperatorMatches, Value: float64(1)}, wantFields: []string{"value"}},
+		{name: "matches rejects invalid pattern", rule: Rule{Attribute: "email", Operator: OperatorMatches, Value: "(unclosed"}, wantFields: []string{"value"}},
+		{name: "matches rejects nested repetition", rule: Rule{Attribute: "email", Operator: OperatorMatches, Value: "^(a+)+$"}, wantFields: []string{"value"}},
+		{name: "matches rejects long pattern", rule: Rule{Attribute: "email", Operator: OperatorMatches, Value: strings.Repeat("a", MaxPatternLength+1)}, wantFields: []string{"value"}},
+		{name: "matches rejects complex pattern", rule: Rule{Attribute: "email", Operator: OperatorMatches, Value: "a{200}"}, wantFields: []string{"value"}},
+		{name: "case-insensitive equals", rule: Rule{Attribute: "country", Operator: OperatorEquals, Value: "au", CaseSensitive: boolPtr(false)}},
+		{name: "case_sensitive rejected on numbers", rule: Rule{Attribute: "age", Operator: OperatorGreaterThan, Value: float64(18), CaseSensitive: boolPtr(false)}, wantFields: []string{"case_sensitive"}},
+		{name: "equals rejects arrays", rule: Rule{Attribute: "country", Operator: OperatorEquals, Value: []interface{}{"AU"}}, wantFields: []string{"value"}},
+		{name: "equals rejects null", rule: Rule{Attribute: "country", Operator: OperatorEquals}, wantFields: []string{"value"}},
+		{name: "rollout above 100", rule: Rule{Attribute: "country", Operator: OperatorEquals, Value: "AU", Rollout: 101}, wantFields: []string{"rollout"}},
+		{name: "negative rollout", rule: Rule{Attribute: "country", Operator: OperatorEquals, Value: "AU", Rollout: -1}, wantFields: []string{"rollout"}},
+		{name: "negative order", rule: Rule{Attribute: "country", Operator: OperatorEquals, Value: "AU", Order: -1}, wantFields: []string{"order"}},
+		{name: "several problems", rule: Rule{Operator: OperatorLessThan, Value: true, Rollout: 200}, wantFields: []string{"attribute", "value", "rollout"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRules([]Rule{tt.rule})
+
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var rulesErr *RuleValidationError
+			if !errors.As(err, &rulesErr) {
+				t.Fatalf("expected RuleValidationError, got %v", err)
+			}
+			if !errors.Is(err, ErrInvalidFlagData) {
+				t.Error("expected error to wrap ErrInvalidFlagData")
+			}
+
+			var fields []string
+			for _, e := range rulesErr.Errors {
+				fields = append(fields, e.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("expected fields %v, got %v", tt.wantFields, fields)
+			}
+		})
+	}
+}
+
+func TestValidateRules_ReportsEachRule(t *testing.T) {
+	err := ValidateRules([]Rule{
+		{ID: "0b7f7f3e-5d0c-4a8e-9a55-3f1c2b6d8e01", Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+		{ID: "0b7f7f3e-5d0c-4a8e-9a55-3f1c2b6d8e02", Attribute: "country", Operator: "starts_with", Value: "AU"},
+		{ID: "0b7f7f3e-5d0c-4a8e-9a55-3f1c2b6d8e03", Attribute: "country", Operator: OperatorEquals, Value: "AU", Rollout: 150},
+	})
+
+	var rulesErr *RuleValidationError
+	if !errors.As(err, &rulesErr) {
+		t.Fatalf("expected RuleValidationError, got %v", err)
+	}
+	if len(rulesErr.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(rulesErr.Errors))
+	}
+	if rulesErr.Errors[0].Index != 1 || rulesErr.Errors[0].RuleID != "0b7f7f3e-5d0c-4a8e-9a55-3f1c2b6d8e02" {
+		t.Errorf("unexpected first error: %+v", rulesErr.Errors[0])
+	}
+	if rulesErr.Errors[1].Index != 2 || rulesErr.Errors[1].RuleID != "0b7f7f3e-5d0c-4a8e-9a55-3f1c2b6d8e03" {
+		t.Errorf("unexpected second error: %+v", rulesErr.Errors[1])
+	}
+}
+
+func TestValidateRules_RuleIDs(t *testing.T) {
+	const id = "0b7f7f3e-5d0c-4a8e-9a55-3f1c2b6d8e01"
+	rule := func(id string) Rule {
+		return Rule{ID: id, Attribute: "country", Operator: OperatorEquals, Value: "AU"}
+	}
+
+	tests := []struct {
+		name    string
+		rules   []Rule
+		wantIDs []int
+	}{
+		{name: "server-assigned and omitted", rules: []Rule{rule(id), rule("")}},
+		{name: "client-chosen name", rules: []Rule{rule("rule1")}, wantIDs: []int{0}},
+		{name: "uppercase uuid", rules: []Rule{rule(strings.ToUpper(id))}, wantIDs: []int{0}},
+		{name: "braced uuid", rules: []Rule{rule("{" + id + "}")}, wantIDs: []int{0}},
+		{name: "duplicate", rules: []Rule{rule(id), rule(""), rule(id)}, wantIDs: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRules(tt.rules)
+			if len(tt.wantIDs) == 0 {
+				if err != nil {
+					t.Fatalf("expected valid rules, got %v", err)
+				}
+				return
+			}
+
+			var rulesErr *RuleValidationError
+			if !errors.As(err, &rulesErr) {
+				t.Fatalf("expected RuleValidationError, got %v", err)
+			}
+			var indexes []int
+			for _, e := range rulesErr.Errors {
+				if e.Field != "id" {
+					t.Errorf("expected only id errors, got %+v", e)
+				}
+				indexes = append(indexes, e.Index)
+			}
+			if !reflect.DeepEqual(indexes, tt.wantIDs) {
+				t.Errorf("expected id errors on rules %v, got %v", tt.wantIDs, indexes)
+			}
+		})
+	}
+}
+
+func TestValidateRules_TimeWindow(t *testing.T) {
+	window := func(start, end, timezone string) map[string]interface{} {
+		return map[string]interface{}{"start_at": start, "end_at": end, "timezone": timezone}
+	}
+
+	tests := []struct {
+		name      string
+		rule      Rule
+		wantField string
+	}{
+		{name: "valid", rule: Rule{Operator: OperatorTimeWindow, Value: window("2026-12-20T00:00:00", "2026-12-27T00:00:00", "Australia/Sydney")}},
+		{name: "utc by default", rule: Rule{Operator: OperatorTimeWindow, Value: window("2026-12-20T00:00:00", "2026-12-27T00:00:00", "")}},
+		{name: "has an attribute", rule: Rule{Attribute: "country", Operator: OperatorTimeWindow, Value: window("2026-12-20T00:00:00", "2026-12-27T00:00:00", "")}, wantField: "attribute"},
+		{name: "ends before it starts", rule: Rule{Operator: OperatorTimeWindow, Value: window("2026-12-27T00:00:00", "2026-12-20T00:00:00", "")}, wantField: "value"},
+		{name: "date only", rule: Rule{Operator: OperatorTimeWindow, Value: window("2026-12-20", "2026-12-27", "")}, wantField: "value"},
+		{name: "unknown timezone", rule: Rule{Operator: OperatorTimeWindow, Value: window("2026-12-20T00:00:00", "2026-12-27T00:00:00", "Mars/Olympus")}, wantField: "value"},
+		{name: "not an object", rule: Rule{Operator: OperatorTimeWindow, Value: "2026-12-20"}, wantField: "value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRules([]Rule{tt.rule})
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("expected valid rule, got %v", err)
+				}
+				return
+			}
+
+			var rulesErr *RuleValidationError
+			if !errors.As(err, &rulesErr) || len(rulesErr.Errors) != 1 || rulesErr.Errors[0].Field != tt.wantField {
+				t.Errorf("expected one %s error, got %v", tt.wantField, err)
+			}
+		})
+	}
+}
+
+func TestValidateRules_ContextKind(t *testing.T) {
+	valid := []Rule{
+		{Attribute: "plan", Operator: OperatorEquals, Value: "enterprise", ContextKind: ContextKindOrganization},
+		{Attribute: "os", Operator: OperatorEquals, Value: "ios", ContextKind: ContextKindDevice},
+		{Attribute: "plan", Operator: OperatorEquals, Value: "free", ContextKind: ContextKindUser},
+	}
+	if err := ValidateRules(valid); err != nil {
+		t.Fatalf("expected valid rules, got %v", err)
+	}
+
+	invalid := []Rule{
+		{Attribute: "plan", Operator: OperatorEquals, Value: "enterprise", ContextKind: "team"},
+		{Operator: OperatorTimeWindow, Value: map[string]interface{}{"start_at": "2026-12-20T00:00:00", "end_at": "2026-12-27T00:00:00"}, ContextKind: ContextKindDevice},
+	}
+	var rulesErr *RuleValidationError
+	if err := ValidateRules(invalid); !errors.As(err, &rulesErr) || len(rulesErr.Errors) != 2 {
+		t.Fatalf("expected two errors, got %v", err)
+	}
+	for _, e := range rulesErr.Errors {
+		if e.Field != "context_kind" {
+			t.Errorf("expected a context_kind error, got %s: %s", e.Field, e.Message)
+		}
+	}
+}
+
+func TestCheckRegisteredTypes(t *testing.T) {
+	registry := []RegisteredAttribute{
+		{Name: "plan", ContextKind: ContextKindUser, Type: AttributeTypeString},
+		{Name: "seats", ContextKind: ContextKindOrganization, Type: AttributeTypeNumber},
+		{Name: "beta", ContextKind: ContextKindUser, Type: AttributeTypeBool},
+		{Name: "signed_up", ContextKind: ContextKindUser, Type: AttributeTypeDate},
+	}
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "string equals string", rule: Rule{Attribute: "plan", Operator: OperatorEquals, Value: "pro"}},
+		{name: "string matches", rule: Rule{Attribute: "plan", Operator: OperatorMatches, Value: "^ent"}},
+		{name: "string equals number", rule: Rule{Attribute: "plan", Operator: OperatorEquals, Value: float64(3)}, wantErr: true},
+		{name: "string greater than", rule: Rule{Attribute: "plan", Operator: OperatorGreaterThan, Value: float64(3)}, wantErr: true},
+		{name: "number on its kind", rule: Rule{Attribute: "seats", ContextKind: ContextKindOrganization, Operator: OperatorGreaterThan, Value: float64(50)}},
+		{name: "number in with a string", rule: Rule{Attribute: "seats", ContextKind: ContextKindOrganization, Operator: OperatorIn, Value: []interface{}{float64(5), "10"}}, wantErr: true},
+		{name: "same name on another kind is unregistered", rule: Rule{Attribute: "seats", Operator: OperatorEquals, Value: "many"}},
+		{name: "bool equals bool", rule: Rule{Attribute: "beta", Operator: OperatorEquals, Value: true}},
+		{name: "bool equals string", rule: Rule{Attribute: "beta", Operator: OperatorEquals, Value: "true"}, wantErr: true},
+		{name: "date after date", rule: Rule{Attribute: "signed_up", Operator: OperatorGreaterThan, Value: "2026-01-01"}},
+		{name: "date after non-date", rule: Rule{Attribute: "signed_up", Operator: OperatorGreaterThan, Value: "last year"}, wantErr: true},
+		{name: "date matches", rule: Rule{Attribute: "signed_up", Operator: OperatorMatches, Value: "^2026"}, wantErr: true},
+		{name: "unregistered attribute", rule: Rule{Attribute: "country", Operator: OperatorEquals, Value: float64(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRegisteredTypes([]Rule{tt.rule}, registry)
+			if tt.wantErr {
+				var rulesErr *RuleValidationError
+				if !errors.As(err, &rulesErr) || rulesErr.Errors[0].Field != "value" {
+					t.Errorf("expected a value error, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServiceCreate_ChecksAttributeRegistry(t *testing.T) {
+	projectID := "project-1"
+	repo := &mockRepository{registeredAttributes: []RegisteredAttribute{
+		{Name: "seats", ContextKind: ContextKindUser, Type: AttributeTypeNumber},
+	}}
+	svc := NewService(repo, &mockValidator{}, slog.Default())
+
+	f := &Flag{Name: "big-teams", ProjectID: &projectID, Rules: []Rule{
+		{Attribute: "seats", Operator: OperatorGreaterThan, Value: "50"},
+	}}
+	if err := svc.Create(context.Background(), f, "test-tenant-id"); !errors.Is(err, ErrInvalidFlagData) {
+		t.Fatalf("expected invalid flag data, got %v", err)
+	}
+
+	f.Rules[0].Value = float64(50)
+	if err := svc.Create(context.Background(), f, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestServicePatch_MoveChecksAttributeRegistry(t *testing.T) {
+	source, destination := "project-1", "project-2"
+	repo := &mockRepository{
+		registeredAttributes: []RegisteredAttribute{
+			{Name: "seats", ContextKind: ContextKindUser, Type: AttributeTypeNumber},
+		},
+		getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			return &Flag{ID: id, TenantID: tenantID, ProjectID: &source, Lifecycle: LifecycleActive, Rules: []Rule{
+				{Attribute: "seats", Operator: OperatorGreaterThan, Value: "50"},
+			}}, nil
+		},
+	}
+	svc := NewService(repo, &mockValidator{}, slog.Default())
+
+	req := PatchRequest{UpdateMask: []string{PatchFieldProjectID}, ProjectID: destination}
+	if _, err := svc.Patch(context.Background(), "flag-1", req, "test-tenant-id"); !errors.Is(err, ErrInvalidFlagData) {
+		t.Fatalf("expected invalid flag data, got %v", err)
+	}
+	if repo.patched != nil {
+		t.Error("expected the move not to be written")
+	}
+
+	req = PatchRequest{
+		UpdateMask: []string{PatchFieldProjectID, PatchFieldRules},
+		ProjectID:  destination,
+		Rules:      []Rule{{Attribute: "seats", Operator: OperatorGreaterThan, Value: float64(50)}},
+	}
+	if _, err := svc.Patch(context.Background(), "flag-1", req, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestServiceCreate_AssignsRuleIDs(t *testing.T) {
+	const kept = "0b7f7f3e-5d0c-4a8e-9a55-3f1c2b6d8e01"
+	svc := NewService(&mockRepository{}, &mockValidator{}, slog.Default())
+
+	f := &Flag{Name: "with-rules", RuleLogic: RuleLogicOr, Rules: []Rule{
+		{ID: kept, Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+		{Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+		{Attribute: "plan", Operator: OperatorEquals, Value: "team"},
+	}}
+	if err := svc.Create(context.Background(), f, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if f.Rules[0].ID != kept {
+		t.Errorf("expected the sent rule ID to be kept, got %q", f.Rules[0].ID)
+	}
+	if !validRuleID(f.Rules[1].ID) || !validRuleID(f.Rules[2].ID) || f.Rules[1].ID == f.Rules[2].ID {
+		t.Errorf("expected distinct UUIDs for new rules, got %q and %q", f.Rules[1].ID, f.Rules[2].ID)
+	}
+}
+
+func TestKeepRuleIDs(t *testing.T) {
+	current := []Rule{
+		{ID: "rule-country", Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+		{ID: "rule-plan", Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+		{ID: "rule-beta", Attribute: "beta", Operator: OperatorEquals, Value: "yes"},
+	}
+
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  []string
+	}{
+		{
+			name: "unchanged rules keep their IDs",
+			rules: []Rule{
+				{Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+				{Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+				{Attribute: "beta", Operator: OperatorEquals, Value: "yes"},
+			},
+			want: []string{"rule-country", "rule-plan", "rule-beta"},
+		},
+		{
+			name: "reordered rules are matched by content",
+			rules: []Rule{
+				{Attribute: "beta", Operator: OperatorEquals, Value: "yes"},
+				{Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+			},
+			want: []string{"rule-beta", "rule-country"},
+		},
+		{
+			name: "an edited rule keeps the ID at its position",
+			rules: []Rule{
+				{Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+				{Attribute: "plan", Operator: OperatorEquals, Value: "team"},
+			},
+			want: []string{"rule-country", "rule-plan"},
+		},
+		{
+			name: "sent IDs are not given to other rules",
+			rules: []Rule{
+				{Attribute: "country", Operator: OperatorEquals, Value: "NZ"},
+				{ID: "rule-country", Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+			},
+			want: []string{"", "rule-country"},
+		},
+		{
+			name: "rules past the current ones stay new",
+			rules: []Rule{
+				{Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+				{Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+				{Attribute: "beta", Operator: OperatorEquals, Value: "yes"},
+				{Attribute: "email", Operator: OperatorEquals, Value: "a@example.com"},
+			},
+			want: []string{"rule-country", "rule-plan", "rule-beta", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			KeepRuleIDs(tt.rules, current)
+
+			var ids []string
+			for _, r := range tt.rules {
+				ids = append(ids, r.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("expected IDs %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestServicePatch_KeepsRuleIDs(t *testing.T) {
+	repo := &mockRepository{
+		getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			return &Flag{ID: id, TenantID: tenantID, Lifecycle: LifecycleActive, Rules: []Rule{
+				{ID: "rule-country", Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+				{ID: "rule-plan", Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+			}}, nil
+		},
+	}
+	svc := NewService(repo, &mockValidator{}, slog.Default())
+
+	req := PatchRequest{UpdateMask: []string{PatchFieldRules}, Rules: []Rule{
+		{Attribute: "country", Operator: OperatorEquals, Value: "NZ"},
+		{Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+		{Attribute: "beta", Operator: OperatorEquals, Value: "yes"},
+	}}
+	if _, err := svc.Patch(context.Background(), "flag-1", req, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	rules := repo.patched.Rules
+	if rules[0].ID != "rule-country" {
+		t.Errorf("expected the edited rule to keep the ID at its position, got %q", rules[0].ID)
+	}
+	if rules[1].ID != "rule-plan" {
+		t.Errorf("expected the unchanged rule to keep its ID, got %q", rules[1].ID)
+	}
+	if !validRuleID(rules[2].ID) {
+		t.Errorf("expected a UUID for the added rule, got %q", rules[2].ID)
+	}
+}
+
+func TestUpdateRequestApply_KeepsRuleIDs(t *testing.T) {
+	f := &Flag{ID: "flag-1", Rules: []Rule{
+		{ID: "rule-country", Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+	}}
+
+	UpdateRequest{Rules: []Rule{
+		{Attribute: "country", Operator: OperatorEquals, Value: "AU"},
+		{Attribute: "plan", Operator: OperatorEquals, Value: "pro"},
+	}}.Apply(f)
+
+	if f.Rules[0].ID != "rule-country" {
+		t.Errorf("expected the unchanged rule to keep its ID, got %q", f.Rules[0].ID)
+	}
+	if f.Rules[1].ID != "" {
+		t.Errorf("expected the added rule to be left for AssignRuleIDs, got %q", f.Rules[1].ID)
+	}
+}
+
+func TestOrderedRules(t *testing.T) {
+	rules := []Rule{
+		{ID: "c", Order: 2},
+		{ID: "a", Order: 0},
+		{ID: "b1", Order: 1},
+		{ID: "b2", Order: 1},
+	}
+
+	ordered := OrderedRules(rules)
+
+	var ids []string
+	for _, r := range ordered {
+		ids = append(ids, r.ID)
+	}
+	if want := []string{"a", "b1", "b2", "c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected %v, got %v", want, ids)
+	}
+	if rules[0].ID != "c" {
+		t.Error("OrderedRules must not reorder the input")
+	}
+}
+
+func TestServiceExport(t *testing.T) {
+	mockRepo := &mockRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+			return []Flag{
+				{ID: "f2", Key: "new-checkout", Name: "New Checkout", Enabled: true, RuleLogic: "AND"},
+				{ID: "f1", Key: "beta-banner", Name: "Beta Banner", RuleLogic: "OR", Rules: []Rule{{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 50}}},
+			}, nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	doc, err := svc.Export(context.Background(), "project-1", "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if doc.Version != ExportVersion || doc.ProjectID != "project-1" {
+		t.Errorf("unexpected document header: %+v", doc)
+	}
+	if len(doc.Flags) != 2 || doc.Flags[0].Key != "beta-banner" || doc.Flags[1].Key != "new-checkout" {
+		t.Fatalf("expected flags ordered by key, got %+v", doc.Flags)
+	}
+	if doc.Flags[1].Rules == nil {
+		t.Error("expected empty rules to export as an empty list")
+	}
+}
+
+func TestServiceExportProjectNotInTenant(t *testing.T) {
+	mockVal := &mockValidator{
+		validateProjectOwnershipFunc: func(ctx context.Context, projectID, tenantID string) error {
+			return pkgErrors.ErrNotFound
+		},
+	}
+	svc := NewService(&mockRepository{}, mockVal, slog.Default())
+
+	if _, err := svc.Export(context.Background(), "project-1", "test-tenant-id"); !errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+		t.Errorf("expected ErrProjectNotInTenant, got %v", err)
+	}
+}
+
+// mockSealer "seals" by prefixing the tenant ID, so only the same tenant can open
+type mockSealer struct{}
+
+func (mockSealer) Seal(ctx context.Context, tenantID string, plaintext []byte) (string, error) {
+	return tenantID + ":" + string(plaintext), nil
+}
+
+func (mockSealer) Open(ctx context.Context, tenantID string, value string) ([]byte, error) {
+	plaintext, ok := strings.CutPrefix(value, tenantID+":")
+	if !ok {
+		return nil, errors.New("sealed for another tenant")
+	}
+	return []byte(plaintext), nil
+}
+
+func TestServiceExportSealed(t *testing.T) {
+	mockRepo := &mockRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+			return []Flag{{ID: "f1", Key: "beta-banner", Name: "Beta Banner", RuleLogic: "AND"}}, nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+	ctx := context.Background()
+
+	if _, err := svc.ExportSealed(ctx, "project-1", "test-tenant-id"); !errors.Is(err, ErrInvalidFlagData) {
+		t.Fatalf("expected ErrInvalidFlagData without a sealer, got %v", err)
+	}
+
+	svc.SetSealer(mockSealer{})
+	doc, err := svc.ExportSealed(ctx, "project-1", "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if doc.Flags != nil || doc.Sealed == "" {
+		t.Fatalf("expected only sealed contents, got %+v", doc)
+	}
+
+	// The same tenant can import it; the export is opened before the usual checks
+	result, err := svc.Import(ctx, "project-1", "test-tenant-id", doc, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "beta-banner" {
+		t.Errorf("expected beta-banner skipped as existing, got %+v", result)
+	}
+
+	// Another tenant can't
+	if _, err := svc.Import(ctx, "project-2", "other-tenant-id", doc, ImportOptions{DryRun: true}); !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected ErrInvalidFlagData for another tenant, got %v", err)
+	}
+}
+
+func TestServiceImport(t *testing.T) {
+	owner := "owner-1"
+	existing := []Flag{{ID: "existing-id", Key: "beta-banner", Name: "Beta Banner", OwnerUserID: &owner, RuleLogic: "AND"}}
+	doc := &ExportDocument{
+		Version: ExportVersion,
+		Flags: []ExportedFlag{
+			{Key: "beta-banner", Name: "Beta Banner", Enabled: true},
+			{Name: "New Checkout", Rules: []Rule{{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100}}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		opts        ImportOptions
+		wantCreated []string
+		wantUpdated []string
+		wantSkipped []string
+		wantWrites  int
+	}{
+		{name: "skip existing by default", opts: ImportOptions{}, wantCreated: []string{"new-checkout"}, wantUpdated: []string{}, wantSkipped: []string{"beta-banner"}, wantWrites: 1},
+		{name: "overwrite existing", opts: ImportOptions{OnConflict: ImportConflictOverwrite}, wantCreated: []string{"new-checkout"}, wantUpdated: []string{"beta-banner"}, wantSkipped: []string{}, wantWrites: 2},
+		{name: "dry run writes nothing", opts: ImportOptions{OnConflict: ImportConflictOverwrite, DryRun: true}, wantCreated: []string{"new-checkout"}, wantUpdated: []string{"beta-banner"}, wantSkipped: []string{}, wantWrites: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writes := 0
+			mockRepo := &mockRepository{
+				listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+					return existing, nil
+				},
+				createFunc: func(ctx context.Context, f *Flag) error {
+					writes++
+					if f.Key != "new-checkout" || f.ProjectID == nil || *f.ProjectID != "project-1" {
+						t.Errorf("unexpected created flag: %+v", f)
+					}
+					return nil
+				},
+				updateFunc: func(ctx context.Context, f *Flag, tenantID string) error {
+					writes++
+					if f.ID != "existing-id" || f.OwnerUserID == nil || *f.OwnerUserID != owner || !f.Enabled {
+						t.Errorf("expected overwrite to keep identity and apply changes, got %+v", f)
+					}
+					return nil
+				},
+			}
+			svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+			result, err := svc.Import(context.Background(), "project-1", "test-tenant-id", doc, tt.opts)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(result.Created, tt.wantCreated) || !reflect.DeepEqual(result.Updated, tt.wantUpdated) || !reflect.DeepEqual(result.Skipped, tt.wantSkipped) {
+				t.Errorf("unexpected result: %+v", result)
+			}
+			if writes != tt.wantWrites {
+				t.Errorf("expected %d writes, got %d", tt.wantWrites, writes)
+			}
+		})
+	}
+}
+
+func TestServiceImportRejectsInvalidFlags(t *testing.T) {
+	writes := 0
+	mockRepo := &mockRepository{
+		createFunc: func(ctx context.Context, f *Flag) error {
+			writes++
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	doc := &ExportDocument{Flags: []ExportedFlag{
+		{Key: "valid", Name: "Valid"},
+		{Key: "bad-rule", Name: "Bad Rule", Rules: []Rule{{Attribute: "plan", Operator: "starts_with", Value: "pro", Rollout: 100}}},
+		{Key: "valid", Name: "Duplicate"},
+	}}
+
+	result, err := svc.Import(context.Background(), "project-1", "test-tenant-id", doc, ImportOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if writes != 0 {
+		t.Errorf("expected nothing written when a flag is invalid, got %d writes", writes)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Index != 1 || result.Errors[1].Index != 2 {
+		t.Fatalf("expected errors for flags 1 and 2, got %+v", result.Errors)
+	}
+	if len(result.Errors[0].RuleErrors) == 0 {
+		t.Error("expected per-rule errors for the invalid rule")
+	}
+}
+
+func TestServiceImportRejectsUnknownConflictMode(t *testing.T) {
+	svc := NewService(&mockRepository{}, &mockValidator{}, slog.Default())
+
+	_, err := svc.Import(context.Background(), "project-1", "test-tenant-id", &ExportDocument{}, ImportOptions{OnConflict: "merge"})
+	if !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected ErrInvalidFlagData, got %v", err)
+	}
+}
+
+func TestServiceApply(t *testing.T) {
+	stored := []Flag{}
+	writes := map[string]int{}
+	mockRepo := &mockRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+			return stored, nil
+		},
+		createFunc: func(ctx context.Context, f *Flag) error {
+			writes["create"]++
+			f.ID = "flag-1"
+			stored = append(stored, *f)
+			return nil
+		},
+		updateFunc: func(ctx context.Context, f *Flag, tenantID string) error {
+			writes["update"]++
+			stored[0] = *f
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+	ctx := context.Background()
+
+	def := ExportedFlag{Name: "New Checkout", Enabled: true, Rules: []Rule{{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 50}}}
+
+	result, err := svc.Apply(ctx, "project-1", "new-checkout", def, "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Result != ApplyCreated || result.Flag.Key != "new-checkout" || writes["create"] != 1 {
+		t.Fatalf("expected the flag created under its key, got %+v", result)
+	}
+	ruleID := stored[0].Rules[0].ID
+
+	// The definition never names rule IDs, yet reapplying it matches the stored flag
+	def.Rules = []Rule{{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 50}}
+	result, err = svc.Apply(ctx, "project-1", "new-checkout", def, "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Result != ApplyUnchanged || writes["update"] != 0 {
+		t.Fatalf("expected reapplying to write nothing, got %+v after %d updates", result, writes["update"])
+	}
+
+	def.Enabled = false
+	def.Rules = []Rule{{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100}}
+	result, err = svc.Apply(ctx, "project-1", "new-checkout", def, "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Result != ApplyUpdated || writes["update"] != 1 || result.Flag.Enabled {
+		t.Fatalf("expected the flag updated to the definition, got %+v", result)
+	}
+	if stored[0].Rules[0].ID != ruleID {
+		t.Errorf("expected the rule to keep its ID, got %s want %s", stored[0].Rules[0].ID, ruleID)
+	}
+
+	if _, err := svc.Apply(ctx, "project-1", "New Checkout", def, "test-tenant-id"); !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected a key that isn't a slug to be rejected, got %v", err)
+	}
+	def.Key = "other-key"
+	if _, err := svc.Apply(ctx, "project-1", "new-checkout", def, "test-tenant-id"); !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected a mismatched body key to be rejected, got %v", err)
+	}
+}
+
+func TestServiceListByProject(t *testing.T) {
+	t.Run("project in tenant", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+				return nil, nil
+			},
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		flags, err := svc.ListByProject(context.Background(), "project-1", "test-tenant-id")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if flags == nil {
+			t.Error("expected an empty list, got nil")
+		}
+	})
+
+	t.Run("project in another tenant", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+				t.Error("expected repository not to be called")
+				return nil, nil
+			},
+		}
+		mockVal := &mockValidator{
+			validateProjectOwnershipFunc: func(ctx context.Context, projectID, tenantID string) error {
+				return pkgErrors.ErrNotFound
+			},
+		}
+		svc := NewService(mockRepo, mockVal, slog.Default())
+
+		if _, err := svc.ListByProject(context.Background(), "project-1", "test-tenant-id"); !errors.Is(err, pkgErrors.ErrProjectNotInTenant) {
+			t.Errorf("expected ErrProjectNotInTenant, got %v", err)
+		}
+	})
+}
+
+func TestUpdateRequestValidate(t *testing.T) {
+	empty := ""
+	project := "project-2"
+	firstMatch := RuleLogicFirstMatch
+	unknown := "XOR"
+
+	tests := []struct {
+		name    string
+		req     UpdateRequest
+		wantErr bool
+	}{
+		{name: "move project", req: UpdateRequest{ProjectID: &project}},
+		{name: "change rule logic", req: UpdateRequest{RuleLogic: &firstMatch}},
+		{name: "empty project", req: UpdateRequest{ProjectID: &empty}, wantErr: true},
+		{name: "empty rule logic", req: UpdateRequest{RuleLogic: &empty}, wantErr: true},
+		{name: "unknown rule logic", req: UpdateRequest{RuleLogic: &unknown}, wantErr: true},
+		{name: "invalid rules", req: UpdateRequest{Rules: []Rule{{Attribute: "plan", Operator: "starts_with", Value: "pro"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidFlagData) {
+				t.Errorf("expected ErrInvalidFlagData, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServiceDuplicateNames(t *testing.T) {
+	projectID := "project-1"
+	nameTaken := &pq.Error{Code: "23505", Constraint: projectNameConstraint}
+
+	t.Run("create", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			createFunc: func(ctx context.Context, f *Flag) error { return nameTaken },
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		err := svc.Create(context.Background(), &Flag{ProjectID: &projectID, Name: "checkout", Rules: []Rule{}, RuleLogic: "AND"}, "test-tenant-id")
+		var dupErr *DuplicateNameError
+		if !errors.As(err, &dupErr) || !errors.Is(err, ErrDuplicateName) {
+			t.Fatalf("expected DuplicateNameError, got %v", err)
+		}
+		if dupErr.ProjectID != projectID || dupErr.Name != "checkout" {
+			t.Errorf("unexpected conflict details: %+v", dupErr)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			updateFunc: func(ctx context.Context, f *Flag, tenantID string) error { return nameTaken },
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		err := svc.Update(context.Background(), &Flag{ID: "flag-1", ProjectID: &projectID, Name: "checkout", Rules: []Rule{}, RuleLogic: "AND"}, "test-tenant-id")
+		if !errors.Is(err, ErrDuplicateName) {
+			t.Errorf("expected ErrDuplicateName, got %v", err)
+		}
+	})
+
+	t.Run("other unique violations are not name conflicts", func(t *testing.T) {
+		mockRepo := &mockRepository{
+			createFunc: func(ctx context.Context, f *Flag) error {
+				return &pq.Error{Code: "23505", Constraint: "flags_pkey"}
+			},
+		}
+		svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+		err := svc.Create(context.Background(), &Flag{ProjectID: &projectID, Name: "checkout", Rules: []Rule{}, RuleLogic: "AND"}, "test-tenant-id")
+		if err == nil || errors.Is(err, ErrDuplicateName) {
+			t.Errorf("expected a generic error, got %v", err)
+		}
+	})
+}
+
+func TestServiceSetCustomField(t *testing.T) {
+	full := make([]CustomField, MaxCustomFieldsPerFlag)
+	for i := range full {
+		full[i] = CustomField{Name: fmt.Sprintf("field-%d", i)}
+	}
+	full[0].Name = CustomFieldService
+
+	tests := []struct {
+		name      string
+		field     string
+		value     string
+		existing  []CustomField
+		repoErr   error
+		wantErr   error
+		wantValue string
+	}{
+		{name: "valid field", field: CustomFieldService, value: " <b>checkout-api</b> ", wantValue: "checkout-api"},
+		{name: "uppercase name", field: "Service", value: "checkout-api", wantErr: ErrInvalidFlagData},
+		{name: "blank value", field: CustomFieldService, value: "   ", wantErr: ErrInvalidFlagData},
+		{name: "oversized value", field: CustomFieldService, value: strings.Repeat("v", MaxCustomFieldValueLength+1), wantErr: ErrInvalidFlagData},
+		{name: "too many fields", field: "team", value: "payments", existing: full, wantErr: ErrInvalidFlagData},
+		{name: "replacing at the limit", field: CustomFieldService, value: "checkout-api", existing: full, wantValue: "checkout-api"},
+		{name: "flag in another tenant", field: CustomFieldService, value: "checkout-api", repoErr: sql.ErrNoRows, wantErr: pkgErrors.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockRepository{
+				getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+					return &Flag{ID: id, TenantID: tenantID}, nil
+				},
+				listCustomFieldsFn: func(ctx context.Context, flagID string, tenantID string) ([]CustomField, error) {
+					return tt.existing, nil
+				},
+				setCustomFieldFn: func(ctx context.Context, flagID string, tenantID string, name string, value string) (*CustomField, error) {
+					if tt.repoErr != nil {
+						return nil, tt.repoErr
+					}
+					return &CustomField{FlagID: flagID, Name: name, Value: value}, nil
+				},
+			}
+			svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+			field, err := svc.SetCustomField(context.Background(), "flag-1", tt.field, tt.value, "tenant-1")
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if field.Value != tt.wantValue {
+				t.Errorf("expected value %q, got %q", tt.wantValue, field.Value)
+			}
+		})
+	}
+}
+
+func TestServiceDiffEnvironments(t *testing.T) {
+	rules := []Rule{{ID: "r1", Attribute: "country", Operator: OperatorEquals, Value: "AU", Rollout: 50}}
+	mockRepo := &mockRepository{
+		environments: map[string]*EnvironmentConfig{
+			"staging":    {Enabled: true, Rules: rules, RuleLogic: RuleLogicAnd},
+			"production": {Enabled: false, Rules: rules, RuleLogic: RuleLogicAnd},
+			"qa":         nil,
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	diff, err := svc.DiffEnvironments(context.Background(), "flag-1", "staging", "production", "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(diff.Differences) != 1 || diff.Differences[0] != DiffFieldEnabled {
+		t.Errorf("expected only enabled to differ, got %v", diff.Differences)
+	}
+
+	diff, err = svc.DiffEnvironments(context.Background(), "flag-1", "staging", "qa", "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if diff.Target != nil || len(diff.Differences) != 2 {
+		t.Errorf("expected an unconfigured target differing in enabled and rules, got %+v", diff)
+	}
+
+	if _, err := svc.DiffEnvironments(context.Background(), "flag-1", "staging", "missing", "test-tenant-id"); !errors.Is(err, pkgErrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown environment, got %v", err)
+	}
+	if _, err := svc.DiffEnvironments(context.Background(), "flag-1", "staging", "staging", "test-tenant-id"); !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected ErrInvalidFlagData comparing an environment with itself, got %v", err)
+	}
+}
+
+func TestServicePromote_CopiesConfigAndRecordsHistory(t *testing.T) {
+	rules := []Rule{{ID: "r1", Attribute: "plan", Operator: OperatorEquals, Value: "pro", Rollout: 100}}
+	var written *EnvironmentConfig
+	mockRepo := &mockRepository{
+		environments: map[string]*EnvironmentConfig{
+			"staging":    {Enabled: true, Rules: rules, RuleLogic: RuleLogicOr},
+			"production": nil,
+		},
+		setEnvConfigFn: func(ctx context.Context, c *EnvironmentConfig, tenantID string) error {
+			written = c
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	diff, err := svc.Promote(context.Background(), "flag-1", PromoteRequest{From: "staging", To: "production"}, "user-1", "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if written == nil || written.EnvironmentID != "env-production" || !written.Enabled || written.RuleLogic != RuleLogicOr || len(written.Rules) != 1 {
+		t.Errorf("expected staging's config written to production, got %+v", written)
+	}
+	if len(diff.Differences) != 3 {
+		t.Errorf("expected the promotion to change every field, got %v", diff.Differences)
+	}
+	if len(mockRepo.history) != 1 || mockRepo.history[0].Action != HistoryActionPromoted || *mockRepo.history[0].ActorID != "user-1" {
+		t.Errorf("expected one promoted history entry, got %+v", mockRepo.history)
+	}
+}
+
+func TestServicePromote_UnknownEnvironmentWritesNothing(t *testing.T) {
+	mockRepo := &mockRepository{
+		environments: map[string]*EnvironmentConfig{"staging": {Enabled: true, Rules: []Rule{}, RuleLogic: RuleLogicAnd}},
+		setEnvConfigFn: func(ctx context.Context, c *EnvironmentConfig, tenantID string) error {
+			t.Error("no config should be written when the target environment doesn't exist")
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	_, err := svc.Promote(context.Background(), "flag-1", PromoteRequest{From: "staging", To: "prod"}, "user-1", "test-tenant-id")
+	if !errors.Is(err, pkgErrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if len(mockRepo.history) != 0 {
+		t.Error("no history should be recorded for a failed promotion")
+	}
+}
+
+func TestServiceAttachStats(t *testing.T) {
+	mockRepo := &mockRepository{
+		resultCounts: map[string]ResultCount{"flag-1": {Evaluations: 3, True: 1, Reasons: map[string]int64{"rules_passed": 1, "rules_failed": 2}}},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+	flags := []Flag{{ID: "flag-1"}, {ID: "flag-2"}}
+
+	if err := svc.AttachStats(context.Background(), flags, "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if flags[0].Stats == nil || flags[0].Stats.Evaluations != 3 || flags[0].Stats.TruePercent != 33.33 {
+		t.Errorf("expected 3 evaluations at 33.33%% true, got %+v", flags[0].Stats)
+	}
+	if flags[0].Stats.Reasons["rules_passed"] != 1 || flags[0].Stats.Reasons["rules_failed"] != 2 {
+		t.Errorf("expected reason counts to be carried into the stats, got %+v", flags[0].Stats.Reasons)
+	}
+	if flags[1].Stats == nil || flags[1].Stats.Evaluations != 0 || flags[1].Stats.TruePercent != 0 {
+		t.Errorf("expected zero stats for an unevaluated flag, got %+v", flags[1].Stats)
+	}
+	if flags[1].Stats.Reasons == nil {
+		t.Error("expected an empty reasons map for an unevaluated flag, got nil")
+	}
+	if window := time.Since(mockRepo.resultsSince); window > StatsWindow {
+		t.Errorf("expected stats to cover at most %v, covered %v", StatsWindow, window)
+	}
+}
+
+func TestNewFlagMetrics(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	hour := func(ago int) time.Time { return now.Truncate(time.Hour).Add(-time.Duration(ago) * time.Hour) }
+
+	m := NewFlagMetrics("flag-1", []HourlyResultCount{
+		{Hour: hour(100), ResultCount: ResultCount{Evaluations: 10, True: 10}},
+		{Hour: hour(5), ResultCount: ResultCount{Evaluations: 4, True: 1}},
+		{Hour: hour(0), ResultCount: ResultCount{Evaluations: 2, True: 1}},
+	}, now)
+
+	want := map[string]WindowMetrics{
+		"1h":  {Evaluations: 2, True: 1, False: 1, TruePercent: 50},
+		"24h": {Evaluations: 6, True: 2, False: 4, TruePercent: 33.33},
+		"7d":  {Evaluations: 16, True: 12, False: 4, TruePercent: 75},
+	}
+	if !reflect.DeepEqual(m.Windows, want) {
+		t.Errorf("expected windows %+v, got %+v", want, m.Windows)
+	}
+	if len(m.Hourly) != 3 {
+		t.Errorf("expected the hourly counts to be returned, got %d", len(m.Hourly))
+	}
+
+	empty := NewFlagMetrics("flag-2", nil, now)
+	if empty.Hourly == nil || empty.Windows["24h"] != (WindowMetrics{}) {
+		t.Errorf("expected zero metrics for an unevaluated flag, got %+v", empty)
+	}
+}
+
+func TestServiceMetrics(t *testing.T) {
+	mockRepo := &mockRepository{
+		getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Flag, error) {
+			if id == "missing" {
+				return nil, sql.ErrNoRows
+			}
+			return &Flag{ID: id}, nil
+		},
+		hourlyCounts: []HourlyResultCount{{Hour: time.Now().Truncate(time.Hour), ResultCount: ResultCount{Evaluations: 5, True: 4}}},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	m, err := svc.Metrics(context.Background(), "flag-1", "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := m.Windows["24h"]; got.Evaluations != 5 || got.True != 4 || got.False != 1 {
+		t.Errorf("expected 5 evaluations, 4 true, got %+v", got)
+	}
+	if window := time.Since(mockRepo.resultsSince); window > ResultCountRetention {
+		t.Errorf("expected metrics to cover at most %v, covered %v", ResultCountRetention, window)
+	}
+
+	if _, err := svc.Metrics(context.Background(), "missing", "test-tenant-id"); !errors.Is(err, pkgErrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestServiceRenameAttribute(t *testing.T) {
+	var saved []EnvironmentConfig
+	mockRepo := &mockRepository{
+		listByProjectFn: func(ctx context.Context, projectID string, tenantID string) ([]Flag, error) {
+			return []Flag{
+				{ID: "flag-1", Name: "checkout", Rules: []Rule{{ID: "rule-1", Attribute: "country"}, {ID: "rule-2", Attribute: "plan"}}},
+				{ID: "flag-2", Name: "search", Rules: []Rule{{ID: "rule-3", Attribute: "plan"}}},
+			}, nil
+		},
+		projectConfigs: map[string][]EnvironmentConfig{
+			"flag-2": {{FlagID: "flag-2", EnvironmentID: "env-1", Rules: []Rule{{ID: "rule-4", Attribute: "country"}}}},
+		},
+		setEnvConfigFn: func(ctx context.Context, c *EnvironmentConfig, tenantID string) error {
+			saved = append(saved, *c)
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+	req := RenameAttributeRequest{From: "country", To: "geo_country", DryRun: true}
+
+	result, err := svc.RenameAttribute(context.Background(), "project-1", req, "user-1", "test-tenant-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.RulesChanged != 2 || len(result.Changes) != 2 {
+		t.Fatalf("expected 2 rules in 2 rule sets, got %+v", result)
+	}
+	if c := result.Changes[1]; c.FlagID != "flag-2" || c.EnvironmentID != "env-1" || len(c.RuleIDs) != 1 || c.RuleIDs[0] != "rule-4" {
+		t.Errorf("expected rule-4 in flag-2's env-1 rules, got %+v", c)
+	}
+	if mockRepo.patched != nil || len(saved) != 0 || len(mockRepo.history) != 0 {
+		t.Error("expected a dry run to change nothing")
+	}
+
+	req.DryRun = false
+	if _, err := svc.RenameAttribute(context.Background(), "project-1", req, "user-1", "test-tenant-id"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rules := mockRepo.patched.Rules; rules[0].Attribute != "geo_country" || rules[1].Attribute != "plan" {
+		t.Errorf("expected only the country rule renamed, got %+v", rules)
+	}
+	if len(saved) != 1 || saved[0].Rules[0].Attribute != "geo_country" {
+		t.Errorf("expected env-1's rules renamed, got %+v", saved)
+	}
+	if len(mockRepo.history) != 2 || mockRepo.history[0].Action != HistoryActionAttributeRenamed {
+		t.Errorf("expected a rename in each changed flag's history, got %+v", mockRepo.history)
+	}
+
+	if _, err := svc.RenameAttribute(context.Background(), "project-1", RenameAttributeRequest{From: "plan", To: " plan "}, "user-1", "test-tenant-id"); !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected ErrInvalidFlagData renaming an attribute to itself, got %v", err)
+	}
+}
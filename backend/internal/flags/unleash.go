@@ -0,0 +1,620 @@
+package flag
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unleash's JSON state export lets teams move flags between a self-hosted Unleash and Toggle.
+// Unleash turns a feature on for a user when any of its strategies matches; a strategy
+// matches when all of its constraints do and the user falls within its rollout. Toggle
+// flags are boolean, so variants aren't carried over. Unleash buckets users by murmur3 of the
+// strategy's groupId and user; projects using murmur3 bucketing admit the same users to a
+// partial rollout when the groupId is the flag key, as it is by default and in exports, while
+// sha256 projects admit different ones. Context fields map to attributes
+// of the same name; userWithId strategies match the userId attribute, which SDKs must send.
+
+// UnleashExportVersion is the Unleash state format version written to exports. Unleash 4 and
+// later import it into their default environment.
+const UnleashExportVersion = 1
+
+// Unleash strategies with a Toggle equivalent
+const (
+	UnleashStrategyDefault          = "default"
+	UnleashStrategyFlexibleRollout  = "flexibleRollout"
+	UnleashStrategyUserWithID       = "userWithId"
+	UnleashStrategyGradualUserID    = "gradualRolloutUserId"
+	UnleashStrategyGradualSessionID = "gradualRolloutSessionId"
+	UnleashStrategyGradualRandom    = "gradualRolloutRandom"
+)
+
+// Unleash constraint operators with a Toggle equivalent
+const (
+	UnleashOperatorIn            = "IN"
+	UnleashOperatorNotIn         = "NOT_IN"
+	UnleashOperatorStrContains   = "STR_CONTAINS"
+	UnleashOperatorStrStartsWith = "STR_STARTS_WITH"
+	UnleashOperatorStrEndsWith   = "STR_ENDS_WITH"
+	UnleashOperatorNumEq         = "NUM_EQ"
+	UnleashOperatorNumGT         = "NUM_GT"
+	UnleashOperatorNumLT         = "NUM_LT"
+	UnleashOperatorDateAfter     = "DATE_AFTER"
+	UnleashOperatorDateBefore    = "DATE_BEFORE"
+)
+
+// Unleash context fields with a meaning of their own
+const (
+	unleashCurrentTime = "currentTime" // the time of the evaluation, matched by time_window rules
+	unleashUserID      = "userId"      // matched by the userWithId strategy
+)
+
+// UnleashExport is an Unleash state export. Features carry their strategies in format
+// version 1; Unleash 4 and later list strategies and enabled states per environment instead.
+type UnleashExport struct {
+	Version             int                         `json:"version"`
+	Features            []UnleashFeature            `json:"features"`
+	FeatureStrategies   []UnleashFeatureStrategy    `json:"featureStrategies,omitempty"`
+	FeatureEnvironments []UnleashFeatureEnvironment `json:"featureEnvironments,omitempty"`
+}
+
+// UnleashFeature is an Unleash feature toggle
+type UnleashFeature struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Type        string            `json:"type,omitempty"`
+	Enabled     bool              `json:"enabled"`
+	Strategies  []UnleashStrategy `json:"strategies,omitempty"`
+}
+
+// UnleashStrategy is one way an Unleash feature can turn on
+type UnleashStrategy struct {
+	Name        string                 `json:"name"`
+	Parameters  map[string]interface{} `json:"parameters"`
+	Constraints []UnleashConstraint    `json:"constraints"`
+	SortOrder   int                    `json:"sortOrder,omitempty"`
+}
+
+// UnleashFeatureStrategy is a strategy of a feature in one environment
+type UnleashFeatureStrategy struct {
+	FeatureName string `json:"featureName"`
+	Environment string `json:"environment"`
+	UnleashStrategy
+}
+
+// UnleashFeatureEnvironment is whether a feature is on in one environment
+type UnleashFeatureEnvironment struct {
+	FeatureName string `json:"featureName"`
+	Environment string `json:"environment"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// UnleashConstraint is one condition of an Unleash strategy
+type UnleashConstraint struct {
+	ContextName     string   `json:"contextName"`
+	Operator        string   `json:"operator"`
+	Values          []string `json:"values,omitempty"`
+	Value           string   `json:"value,omitempty"`
+	Inverted        bool     `json:"inverted,omitempty"`
+	CaseInsensitive bool     `json:"caseInsensitive,omitempty"`
+}
+
+// errUnleashUnsupported marks Unleash features and Toggle flags the other side can't express
+var errUnleashUnsupported = errors.New("no equivalent")
+
+// Bounds of the time window that stands for "always". Toggle rules always test a condition,
+// so a strategy that rolls out to every user becomes a time_window rule spanning all time.
+const (
+	alwaysStartAt = "0001-01-01T00:00:00"
+	alwaysEndAt   = "9999-12-31T23:59:59"
+)
+
+// alwaysRule returns a rule every evaluation matches, with the given rollout
+func alwaysRule(rollout int) Rule {
+	return Rule{
+		Operator: OperatorTimeWindow,
+		Value:    map[string]interface{}{"start_at": alwaysStartAt, "end_at": alwaysEndAt},
+		Rollout:  rollout,
+	}
+}
+
+// isAlwaysRule reports whether every evaluation matches the rule's condition
+func isAlwaysRule(rule Rule) bool {
+	if rule.Operator != OperatorTimeWindow {
+		return false
+	}
+	start, end, err := ParseTimeWindow(rule.Value)
+	return err == nil && start.Year() == 1 && end.Year() == 9999
+}
+
+// Environments lists the environments an Unleash 4 export has strategies or states for, sorted
+func (u *UnleashExport) Environments() []string {
+	var envs []string
+	for _, s := range u.FeatureStrategies {
+		envs = append(envs, s.Environment)
+	}
+	for _, e := range u.FeatureEnvironments {
+		envs = append(envs, e.Environment)
+	}
+	slices.Sort(envs)
+	return slices.Compact(envs)
+}
+
+// unleashConversion is an Unleash export converted to an export document. Features that
+// can't be expressed as Toggle rules are left out of the document and reported in Errors;
+// Indexes maps each document flag back to its feature.
+type unleashConversion struct {
+	Document *ExportDocument
+	Indexes  []int
+	Errors   []ImportError
+}
+
+// convertUnleash converts an Unleash export, reading strategies and enabled states from
+// environment when the export keeps them per environment. The environment may be omitted
+// when the export has only one.
+func convertUnleash(u *UnleashExport, environment string) (*unleashConversion, error) {
+	if u.Features == nil {
+		return nil, fmt.Errorf("%w: features is required", ErrInvalidFlagData)
+	}
+
+	perEnvironment := len(u.FeatureStrategies) > 0 || len(u.FeatureEnvironments) > 0
+	if perEnvironment {
+		envs := u.Environments()
+		if environment == "" {
+			if len(envs) != 1 {
+				return nil, fmt.Errorf("%w: environment is required; the export has %s", ErrInvalidFlagData, strings.Join(envs, ", "))
+			}
+			environment = envs[0]
+		} else if !slices.Contains(envs, environment) {
+			return nil, fmt.Errorf("%w: the export has no environment %q", ErrInvalidFlagData, environment)
+		}
+	}
+
+	strategies := make(map[string][]UnleashStrategy)
+	enabled := make(map[string]bool)
+	for _, s := range u.FeatureStrategies {
+		if s.Environment == environment {
+			strategies[s.FeatureName] = append(strategies[s.FeatureName], s.UnleashStrategy)
+		}
+	}
+	for _, e := range u.FeatureEnvironments {
+		if e.Environment == environment {
+			enabled[e.FeatureName] = e.Enabled
+		}
+	}
+
+	conv := &unleashConversion{
+		Document: &ExportDocument{Version: ExportVersion, Flags: make([]ExportedFlag, 0, len(u.Features))},
+		Indexes:  []int{},
+		Errors:   []ImportError{},
+	}
+	for i, feature := range u.Features {
+		f := ExportedFlag{Name: feature.Name, Description: feature.Description, Enabled: feature.Enabled}
+		featureStrategies := feature.Strategies
+		if perEnvironment {
+			f.Enabled = enabled[feature.Name]
+			featureStrategies = strategies[feature.Name]
+		}
+
+		rules, logic, err := unleashRules(featureStrategies)
+		if err != nil {
+			conv.Errors = append(conv.Errors, ImportError{Index: i, Key: KeyFromName(feature.Name), Error: err.Error()})
+			continue
+		}
+		f.Rules, f.RuleLogic = rules, logic
+		conv.Document.Flags = append(conv.Document.Flags, f)
+		conv.Indexes = append(conv.Indexes, i)
+	}
+	return conv, nil
+}
+
+// unleashRules converts a feature's strategies into rules. A strategy that admits every
+// user leaves the flag without rules. One strategy becomes its constraints under AND, each
+// with the strategy's rollout; several become one rule each under OR, so each may have at
+// most one condition.
+func unleashRules(strategies []UnleashStrategy) ([]Rule, string, error) {
+	type converted struct {
+		conditions []Rule
+		rollout    int
+	}
+
+	ordered := slices.Clone(strategies)
+	slices.SortStableFunc(ordered, func(a, b UnleashStrategy) int { return a.SortOrder - b.SortOrder })
+
+	all := make([]converted, 0, len(ordered))
+	for i, s := range ordered {
+		conditions, rollout, err := unleashStrategy(s)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: strategies[%d]: %w", ErrInvalidFlagData, i, err)
+		}
+		if len(conditions) == 0 && rollout == 100 {
+			return []Rule{}, RuleLogicAnd, nil
+		}
+		all = append(all, converted{conditions, rollout})
+	}
+
+	if len(all) == 0 {
+		return []Rule{}, RuleLogicAnd, nil
+	}
+	if len(all) == 1 {
+		if len(all[0].conditions) == 0 {
+			return []Rule{alwaysRule(all[0].rollout)}, RuleLogicAnd, nil
+		}
+		rules := all[0].conditions
+		for i := range rules {
+			rules[i].Rollout = all[0].rollout
+		}
+		return rules, RuleLogicAnd, nil
+	}
+
+	rules := make([]Rule, 0, len(all))
+	for i, s := range all {
+		switch len(s.conditions) {
+		case 0:
+			rules = append(rules, alwaysRule(s.rollout))
+		case 1:
+			rule := s.conditions[0]
+			rule.Rollout = s.rollout
+			rules = append(rules, rule)
+		default:
+			return nil, "", fmt.Errorf("%w: strategies[%d] has %d conditions; with several strategies each may have only one",
+				ErrInvalidFlagData, i, len(s.conditions))
+		}
+	}
+	return rules, RuleLogicOr, nil
+}
+
+// unleashStrategy converts one strategy into the conditions it requires and its rollout
+func unleashStrategy(s UnleashStrategy) ([]Rule, int, error) {
+	var conditions []Rule
+	rollout := 100
+
+	switch s.Name {
+	case UnleashStrategyDefault:
+	case UnleashStrategyFlexibleRollout:
+		r, err := unleashPercentage(s.Parameters, "rollout")
+		if err != nil {
+			return nil, 0, err
+		}
+		rollout = r
+	case UnleashStrategyGradualUserID, UnleashStrategyGradualSessionID, UnleashStrategyGradualRandom:
+		r, err := unleashPercentage(s.Parameters, "percentage")
+		if err != nil {
+			return nil, 0, err
+		}
+		rollout = r
+	case UnleashStrategyUserWithID:
+		ids, _ := s.Parameters["userIds"].(string)
+		var values []interface{}
+		for _, id := range strings.Split(ids, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				values = append(values, id)
+			}
+		}
+		if len(values) == 0 {
+			return nil, 0, errors.New("userWithId requires userIds")
+		}
+		conditions = append(conditions, Rule{Attribute: unleashUserID, Operator: OperatorIn, Value: values})
+	default:
+		return nil, 0, fmt.Errorf("strategy %q has %w in Toggle", s.Name, errUnleashUnsupported)
+	}
+
+	var window map[string]interface{}
+	for i, c := range s.Constraints {
+		if c.ContextName == unleashCurrentTime {
+			if window == nil {
+				window = map[string]interface{}{"start_at": alwaysStartAt, "end_at": alwaysEndAt}
+			}
+			if err := unleashTimeBound(window, c); err != nil {
+				return nil, 0, fmt.Errorf("constraints[%d]: %w", i, err)
+			}
+			continue
+		}
+		rule, err := unleashConstraint(c)
+		if err != nil {
+			return nil, 0, fmt.Errorf("constraints[%d]: %w", i, err)
+		}
+		conditions = append(conditions, rule)
+	}
+	if window != nil {
+		conditions = append(conditions, Rule{Operator: OperatorTimeWindow, Value: window})
+	}
+	return conditions, rollout, nil
+}
+
+// unleashPercentage reads a 0-100 percentage parameter, which Unleash stores as a string
+func unleashPercentage(params map[string]interface{}, name string) (int, error) {
+	var value float64
+	switch v := params[name].(type) {
+	case float64:
+		value = v
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s must be a number", name)
+		}
+		value = parsed
+	default:
+		return 0, fmt.Errorf("%s is required", name)
+	}
+	if value < 0 || value > 100 {
+		return 0, fmt.Errorf("%s must be between 0 and 100", name)
+	}
+	return int(math.Round(value)), nil
+}
+
+// unleashTimeBound narrows a time window by a currentTime constraint
+func unleashTimeBound(window map[string]interface{}, c UnleashConstraint) error {
+	if c.Inverted {
+		return fmt.Errorf("inverted %s has %w in Toggle", c.Operator, errUnleashUnsupported)
+	}
+	at, err := time.Parse(time.RFC3339, c.Value)
+	if err != nil {
+		return fmt.Errorf("%s value must be an RFC 3339 time", c.Operator)
+	}
+	switch c.Operator {
+	case UnleashOperatorDateAfter:
+		window["start_at"] = at.UTC().Format(TimeWindowLayout)
+	case UnleashOperatorDateBefore:
+		window["end_at"] = at.UTC().Format(TimeWindowLayout)
+	default:
+		return fmt.Errorf("%s on %s has %w in Toggle", c.Operator, unleashCurrentTime, errUnleashUnsupported)
+	}
+	return nil
+}
+
+// unleashConstraint converts a constraint into a rule condition. Context fields named
+// "organization.*" or "device.*" target that context kind.
+func unleashConstraint(c UnleashConstraint) (Rule, error) {
+	rule := Rule{Attribute: c.ContextName}
+	if kind, name, ok := strings.Cut(c.ContextName, "."); ok && kind != ContextKindUser && slices.Contains(ContextKinds, kind) {
+		rule.ContextKind, rule.Attribute = kind, name
+	}
+	if c.CaseInsensitive {
+		caseSensitive := false
+		rule.CaseSensitive = &caseSensitive
+	}
+	unsupported := func() (Rule, error) {
+		if c.Inverted {
+			return Rule{}, fmt.Errorf("inverted %s has %w in Toggle", c.Operator, errUnleashUnsupported)
+		}
+		return Rule{}, fmt.Errorf("operator %s has %w in Toggle", c.Operator, errUnleashUnsupported)
+	}
+
+	switch c.Operator {
+	case UnleashOperatorIn, UnleashOperatorNotIn:
+		if len(c.Values) == 0 {
+			return Rule{}, fmt.Errorf("%s requires values", c.Operator)
+		}
+		negate := (c.Operator == UnleashOperatorNotIn) != c.Inverted
+		if len(c.Values) == 1 {
+			rule.Operator, rule.Value = OperatorEquals, c.Values[0]
+			if negate {
+				rule.Operator = OperatorNotEquals
+			}
+			return rule, nil
+		}
+		values := make([]interface{}, len(c.Values))
+		for i, v := range c.Values {
+			values[i] = v
+		}
+		rule.Operator, rule.Value = OperatorIn, values
+		if negate {
+			rule.Operator = OperatorNotIn
+		}
+		return rule, nil
+
+	case UnleashOperatorNumEq, UnleashOperatorNumGT, UnleashOperatorNumLT:
+		n, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return Rule{}, fmt.Errorf("%s value must be a number", c.Operator)
+		}
+		rule.Value = n
+		rule.CaseSensitive = nil // numbers have no case
+		switch {
+		case c.Operator == UnleashOperatorNumEq && c.Inverted:
+			rule.Operator = OperatorNotEquals
+		case c.Operator == UnleashOperatorNumEq:
+			rule.Operator = OperatorEquals
+		case c.Inverted:
+			return unsupported()
+		case c.Operator == UnleashOperatorNumGT:
+			rule.Operator = OperatorGreaterThan
+		default:
+			rule.Operator = OperatorLessThan
+		}
+		return rule, nil
+
+	case UnleashOperatorDateAfter, UnleashOperatorDateBefore:
+		if c.Inverted {
+			return unsupported()
+		}
+		rule.CaseSensitive = nil // nor do dates
+		if _, ok := ParseDate(c.Value); !ok {
+			return Rule{}, fmt.Errorf("%s value must be a date", c.Operator)
+		}
+		rule.Operator, rule.Value = OperatorGreaterThan, c.Value
+		if c.Operator == UnleashOperatorDateBefore {
+			rule.Operator = OperatorLessThan
+		}
+		return rule, nil
+
+	case UnleashOperatorStrContains, UnleashOperatorStrStartsWith, UnleashOperatorStrEndsWith:
+		if c.Inverted {
+			return unsupported()
+		}
+		if len(c.Values) == 0 {
+			return Rule{}, fmt.Errorf("%s requires values", c.Operator)
+		}
+		quoted := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			quoted[i] = regexp.QuoteMeta(v)
+		}
+		pattern := "(" + strings.Join(quoted, "|") + ")"
+		switch c.Operator {
+		case UnleashOperatorStrStartsWith:
+			pattern = "^" + pattern
+		case UnleashOperatorStrEndsWith:
+			pattern += "$"
+		}
+		rule.Operator, rule.Value = OperatorMatches, pattern
+		return rule, nil
+	}
+	return unsupported()
+}
+
+// UnleashFromExport converts an export document into an Unleash state export. A flag's rules
+// become one strategy with every condition under AND, or one strategy per rule under OR;
+// FIRST_MATCH flags export like OR when all their rules share a rollout. Flags Unleash can't
+// express, such as those with matches rules, are left out and their keys returned.
+func UnleashFromExport(doc *ExportDocument) (*UnleashExport, []string) {
+	u := &UnleashExport{Version: UnleashExportVersion, Features: make([]UnleashFeature, 0, len(doc.Flags))}
+	skipped := []string{}
+	for _, f := range doc.Flags {
+		strategies, err := unleashStrategies(f)
+		if err != nil {
+			skipped = append(skipped, f.Key)
+			continue
+		}
+		u.Features = append(u.Features, UnleashFeature{
+			Name:        f.Key,
+			Description: f.Description,
+			Type:        "release",
+			Enabled:     f.Enabled,
+			Strategies:  strategies,
+		})
+	}
+	return u, skipped
+}
+
+// unleashStrategies converts a flag's rules into Unleash strategies
+func unleashStrategies(f ExportedFlag) ([]UnleashStrategy, error) {
+	if len(f.Rules) == 0 {
+		return []UnleashStrategy{{Name: UnleashStrategyDefault, Parameters: map[string]interface{}{}, Constraints: []UnleashConstraint{}}}, nil
+	}
+	rollout := func(r int) UnleashStrategy {
+		return UnleashStrategy{
+			Name:        UnleashStrategyFlexibleRollout,
+			Parameters:  map[string]interface{}{"rollout": strconv.Itoa(r), "stickiness": "default", "groupId": f.Key},
+			Constraints: []UnleashConstraint{},
+		}
+	}
+
+	if f.RuleLogic == RuleLogicAnd || f.RuleLogic == "" {
+		// Every rule shares the user's bucket, so the smallest rollout decides
+		s := rollout(100)
+		smallest := 100
+		for _, rule := range f.Rules {
+			constraints, err := unleashConstraints(rule)
+			if err != nil {
+				return nil, err
+			}
+			s.Constraints = append(s.Constraints, constraints...)
+			smallest = min(smallest, rule.Rollout)
+		}
+		s.Parameters["rollout"] = strconv.Itoa(smallest)
+		return []UnleashStrategy{s}, nil
+	}
+
+	rules := f.Rules
+	if f.RuleLogic == RuleLogicFirstMatch {
+		rules = OrderedRules(f.Rules)
+		for _, rule := range rules {
+			if rule.Rollout != rules[0].Rollout {
+				return nil, fmt.Errorf("FIRST_MATCH rules with different rollouts have %w in Unleash", errUnleashUnsupported)
+			}
+		}
+	}
+	strategies := make([]UnleashStrategy, 0, len(rules))
+	for i, rule := range rules {
+		constraints, err := unleashConstraints(rule)
+		if err != nil {
+			return nil, err
+		}
+		s := rollout(rule.Rollout)
+		s.Constraints = constraints
+		s.SortOrder = i
+		strategies = append(strategies, s)
+	}
+	return strategies, nil
+}
+
+// unleashConstraints converts a rule's condition into Unleash constraints; a condition every
+// evaluation matches needs none
+func unleashConstraints(rule Rule) ([]UnleashConstraint, error) {
+	if isAlwaysRule(rule) {
+		return nil, nil
+	}
+	if rule.Operator == OperatorTimeWindow {
+		start, end, err := ParseTimeWindow(rule.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []UnleashConstraint{
+			{ContextName: unleashCurrentTime, Operator: UnleashOperatorDateAfter, Value: start.UTC().Format(time.RFC3339)},
+			{ContextName: unleashCurrentTime, Operator: UnleashOperatorDateBefore, Value: end.UTC().Format(time.RFC3339)},
+		}, nil
+	}
+
+	c := UnleashConstraint{ContextName: rule.QualifiedAttribute(), CaseInsensitive: !rule.IsCaseSensitive()}
+	unsupported := fmt.Errorf("%s rules on %T values have %w in Unleash", rule.Operator, rule.Value, errUnleashUnsupported)
+
+	switch rule.Operator {
+	case OperatorEquals, OperatorNotEquals:
+		switch v := rule.Value.(type) {
+		case string:
+			c.Operator, c.Values = UnleashOperatorIn, []string{v}
+			if rule.Operator == OperatorNotEquals {
+				c.Operator = UnleashOperatorNotIn
+			}
+		case float64:
+			c.Operator, c.Value = UnleashOperatorNumEq, strconv.FormatFloat(v, 'f', -1, 64)
+			c.Inverted = rule.Operator == OperatorNotEquals
+		default:
+			return nil, unsupported
+		}
+	case OperatorIn, OperatorNotIn:
+		values, ok := rule.Value.([]interface{})
+		if !ok {
+			return nil, unsupported
+		}
+		c.Operator = UnleashOperatorIn
+		if rule.Operator == OperatorNotIn {
+			c.Operator = UnleashOperatorNotIn
+		}
+		for _, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				return nil, unsupported
+			}
+			c.Values = append(c.Values, s)
+		}
+	case OperatorGreaterThan, OperatorLessThan:
+		switch v := rule.Value.(type) {
+		case float64:
+			c.Operator, c.Value = UnleashOperatorNumGT, strconv.FormatFloat(v, 'f', -1, 64)
+			if rule.Operator == OperatorLessThan {
+				c.Operator = UnleashOperatorNumLT
+			}
+		case string:
+			at, ok := ParseDate(v)
+			if !ok {
+				return nil, unsupported
+			}
+			c.Operator, c.Value = UnleashOperatorDateAfter, at.UTC().Format(time.RFC3339)
+			if rule.Operator == OperatorLessThan {
+				c.Operator = UnleashOperatorDateBefore
+			}
+		default:
+			return nil, unsupported
+		}
+	default:
+		return nil, unsupported
+	}
+	return []UnleashConstraint{c}, nil
+}
@@ -0,0 +1,192 @@
+package flag
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestConvertUnleash(t *testing.T) {
+	doc := &UnleashExport{Version: 1, Features: []UnleashFeature{
+		{Name: "everyone", Enabled: true, Strategies: []UnleashStrategy{
+			{Name: UnleashStrategyFlexibleRollout, Parameters: map[string]interface{}{"rollout": "10"}},
+			{Name: UnleashStrategyDefault},
+		}},
+		{Name: "pro-users", Enabled: true, Strategies: []UnleashStrategy{
+			{Name: UnleashStrategyFlexibleRollout, Parameters: map[string]interface{}{"rollout": "25"}, Constraints: []UnleashConstraint{
+				{ContextName: "plan", Operator: UnleashOperatorIn, Values: []string{"pro", "team"}, CaseInsensitive: true},
+				{ContextName: "organization.country", Operator: UnleashOperatorIn, Values: []string{"AU"}, Inverted: true},
+			}},
+		}},
+		{Name: "beta", Strategies: []UnleashStrategy{
+			{Name: UnleashStrategyGradualRandom, Parameters: map[string]interface{}{"percentage": float64(10)}, SortOrder: 2},
+			{Name: UnleashStrategyUserWithID, Parameters: map[string]interface{}{"userIds": "user-1, user-2"}, SortOrder: 1},
+		}},
+		{Name: "semver", Strategies: []UnleashStrategy{
+			{Name: UnleashStrategyDefault, Constraints: []UnleashConstraint{{ContextName: "version", Operator: "SEMVER_GT", Value: "1.2.0"}}},
+		}},
+		{Name: "campaign", Enabled: true, Strategies: []UnleashStrategy{
+			{Name: UnleashStrategyDefault, Constraints: []UnleashConstraint{
+				{ContextName: "currentTime", Operator: UnleashOperatorDateAfter, Value: "2026-11-01T00:00:00+11:00"},
+				{ContextName: "email", Operator: UnleashOperatorStrEndsWith, Values: []string{"@example.com"}},
+			}},
+		}},
+	}}
+
+	conv, err := convertUnleash(doc, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(conv.Indexes, []int{0, 1, 2, 4}) {
+		t.Fatalf("expected features 0, 1, 2 and 4 converted, got %v", conv.Indexes)
+	}
+	if len(conv.Errors) != 1 || conv.Errors[0].Index != 3 || conv.Errors[0].Key != "semver" {
+		t.Fatalf("expected an error for the semver feature, got %+v", conv.Errors)
+	}
+
+	caseInsensitive := false
+	want := []ExportedFlag{
+		{Name: "everyone", Enabled: true, Rules: []Rule{}, RuleLogic: RuleLogicAnd},
+		{Name: "pro-users", Enabled: true, RuleLogic: RuleLogicAnd, Rules: []Rule{
+			{Attribute: "plan", Operator: OperatorIn, Value: []interface{}{"pro", "team"}, Rollout: 25, CaseSensitive: &caseInsensitive},
+			{Attribute: "country", ContextKind: ContextKindOrganization, Operator: OperatorNotEquals, Value: "AU", Rollout: 25},
+		}},
+		{Name: "beta", RuleLogic: RuleLogicOr, Rules: []Rule{
+			{Attribute: "userId", Operator: OperatorIn, Value: []interface{}{"user-1", "user-2"}, Rollout: 100},
+			alwaysRule(10),
+		}},
+		{Name: "campaign", Enabled: true, RuleLogic: RuleLogicAnd, Rules: []Rule{
+			{Attribute: "email", Operator: OperatorMatches, Value: `(@example\.com)$`, Rollout: 100},
+			{Operator: OperatorTimeWindow, Value: map[string]interface{}{"start_at": "2026-10-31T13:00:00", "end_at": alwaysEndAt}, Rollout: 100},
+		}},
+	}
+	if !reflect.DeepEqual(conv.Document.Flags, want) {
+		t.Errorf("unexpected flags:\n got %+v\nwant %+v", conv.Document.Flags, want)
+	}
+	for _, f := range conv.Document.Flags {
+		if err := ValidateRules(f.Rules); err != nil {
+			t.Errorf("expected valid rules for %s, got %v", f.Name, err)
+		}
+	}
+}
+
+func TestConvertUnleash_PerEnvironment(t *testing.T) {
+	doc := &UnleashExport{
+		Version:  2,
+		Features: []UnleashFeature{{Name: "checkout"}},
+		FeatureStrategies: []UnleashFeatureStrategy{
+			{FeatureName: "checkout", Environment: "development", UnleashStrategy: UnleashStrategy{Name: UnleashStrategyDefault}},
+			{FeatureName: "checkout", Environment: "production", UnleashStrategy: UnleashStrategy{
+				Name: UnleashStrategyFlexibleRollout, Parameters: map[string]interface{}{"rollout": "5"},
+			}},
+		},
+		FeatureEnvironments: []UnleashFeatureEnvironment{
+			{FeatureName: "checkout", Environment: "development", Enabled: true},
+			{FeatureName: "checkout", Environment: "production", Enabled: true},
+		},
+	}
+
+	if _, err := convertUnleash(doc, ""); !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected the environment to be required with two environments, got %v", err)
+	}
+	if _, err := convertUnleash(doc, "staging"); !errors.Is(err, ErrInvalidFlagData) {
+		t.Errorf("expected an unknown environment to be rejected, got %v", err)
+	}
+
+	conv, err := convertUnleash(doc, "production")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	f := conv.Document.Flags[0]
+	if !f.Enabled || !reflect.DeepEqual(f.Rules, []Rule{alwaysRule(5)}) {
+		t.Errorf("expected production's 5%% rollout, got %+v", f)
+	}
+}
+
+func TestUnleashFromExport_RoundTrips(t *testing.T) {
+	doc := &ExportDocument{Flags: []ExportedFlag{
+		{Key: "on-for-all", Name: "On For All", Enabled: true, Rules: []Rule{}, RuleLogic: RuleLogicAnd},
+		{Key: "adults", Name: "Adults", Enabled: true, RuleLogic: RuleLogicAnd, Rules: []Rule{
+			{Attribute: "plan", Operator: OperatorEquals, Value: "pro", Rollout: 50},
+			{Attribute: "age", Operator: OperatorGreaterThan, Value: float64(17), Rollout: 30},
+		}},
+		{Key: "either", Name: "Either", RuleLogic: RuleLogicOr, Rules: []Rule{
+			{Attribute: "plan", ContextKind: ContextKindOrganization, Operator: OperatorNotIn, Value: []interface{}{"free", "trial"}, Rollout: 100},
+			alwaysRule(20),
+		}},
+		{Key: "pattern", Name: "Pattern", RuleLogic: RuleLogicAnd, Rules: []Rule{
+			{Attribute: "email", Operator: OperatorMatches, Value: "^admin@", Rollout: 100},
+		}},
+		{Key: "first", Name: "First", RuleLogic: RuleLogicFirstMatch, Rules: []Rule{
+			{Attribute: "plan", Operator: OperatorEquals, Value: "pro", Rollout: 10},
+			{Attribute: "plan", Operator: OperatorEquals, Value: "team", Rollout: 100},
+		}},
+	}}
+
+	u, skipped := UnleashFromExport(doc)
+	if !reflect.DeepEqual(skipped, []string{"pattern", "first"}) {
+		t.Errorf("expected matches and mixed-rollout FIRST_MATCH flags skipped, got %v", skipped)
+	}
+	if len(u.Features) != 3 || u.Version != UnleashExportVersion {
+		t.Fatalf("expected 3 features, got %+v", u)
+	}
+	if rollout := u.Features[1].Strategies[0].Parameters["rollout"]; rollout != "30" {
+		t.Errorf("expected AND rules to export with their smallest rollout, got %v", rollout)
+	}
+
+	conv, err := convertUnleash(u, "")
+	if err != nil || len(conv.Errors) != 0 {
+		t.Fatalf("expected the export to import cleanly, got %v %+v", err, conv)
+	}
+	want := []ExportedFlag{
+		{Name: "on-for-all", Enabled: true, Rules: []Rule{}, RuleLogic: RuleLogicAnd},
+		{Name: "adults", Enabled: true, RuleLogic: RuleLogicAnd, Rules: []Rule{
+			{Attribute: "plan", Operator: OperatorEquals, Value: "pro", Rollout: 30},
+			{Attribute: "age", Operator: OperatorGreaterThan, Value: float64(17), Rollout: 30},
+		}},
+		{Name: "either", RuleLogic: RuleLogicOr, Rules: []Rule{
+			{Attribute: "plan", ContextKind: ContextKindOrganization, Operator: OperatorNotIn, Value: []interface{}{"free", "trial"}, Rollout: 100},
+			alwaysRule(20),
+		}},
+	}
+	if !reflect.DeepEqual(conv.Document.Flags, want) {
+		t.Errorf("unexpected round trip:\n got %+v\nwant %+v", conv.Document.Flags, want)
+	}
+}
+
+func TestServiceImportUnleash_UnconvertibleFeatureStopsImport(t *testing.T) {
+	writes := 0
+	mockRepo := &mockRepository{
+		createFunc: func(ctx context.Context, f *Flag) error {
+			writes++
+			return nil
+		},
+	}
+	svc := NewService(mockRepo, &mockValidator{}, slog.Default())
+
+	doc := &UnleashExport{Version: 1, Features: []UnleashFeature{
+		{Name: "remote", Strategies: []UnleashStrategy{{Name: "remoteAddress", Parameters: map[string]interface{}{"IPs": "10.0.0.1"}}}},
+		{Name: "fine", Enabled: true},
+	}}
+
+	result, err := svc.ImportUnleash(context.Background(), "project-1", "test-tenant-id", doc, ImportOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if writes != 0 || result.DryRun {
+		t.Errorf("expected nothing written while a feature can't be converted, got %d writes", writes)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Index != 0 || result.Errors[0].Key != "remote" {
+		t.Fatalf("expected an error for feature 0, got %+v", result.Errors)
+	}
+
+	doc.Features = doc.Features[1:]
+	if _, err := svc.ImportUnleash(context.Background(), "project-1", "test-tenant-id", doc, ImportOptions{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if writes != 1 {
+		t.Errorf("expected the convertible feature created, got %d writes", writes)
+	}
+}
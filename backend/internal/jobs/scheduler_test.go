@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestScheduler_RunsJobsUntilCancelled(t *testing.T) {
+	var runs atomic.Int32
+	s := NewScheduler(testLogger())
+	s.Register(Job{
+		Name:     "counter",
+		Interval: 5 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+
+	assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+
+	cancel()
+	s.Wait()
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load(), "job should not run after cancellation")
+}
+
+func TestScheduler_KeepsRunningAfterFailuresAndPanics(t *testing.T) {
+	var runs atomic.Int32
+	s := NewScheduler(testLogger())
+	s.Register(Job{
+		Name:     "flaky",
+		Interval: 5 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			if runs.Add(1)%2 == 0 {
+				panic("boom")
+			}
+			return errors.New("temporary failure")
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	assert.Eventually(t, func() bool { return runs.Load() >= 4 }, time.Second, time.Millisecond)
+}
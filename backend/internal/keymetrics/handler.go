@@ -0,0 +1,44 @@
+package keymetrics
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/projects/:id/keys/:keyID/metrics", h.Metrics)
+}
+
+// Metrics returns an SDK key's request counts and error rates, for capacity planning and
+// spotting misbehaving integrations. keyID is as listed by GET /projects/:id/api-keys.
+func (h *handler) Metrics(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	metrics, err := h.service.Metrics(c.Request.Context(), c.Param("id"), c.Param("keyID"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load api key metrics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
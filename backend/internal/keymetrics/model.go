@@ -0,0 +1,77 @@
+package keymetrics
+
+import (
+	"math"
+	"time"
+)
+
+// Retention is how long hourly request counts are kept
+const Retention = 7 * 24 * time.Hour
+
+// Windows are the rolling periods a key's requests are totalled over. Counts are kept by
+// the hour for Retention, so the longest is that long.
+var Windows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", Retention},
+}
+
+// Counts is how many SDK requests a key made, and how many of them failed
+type Counts struct {
+	Requests     int64 `json:"requests" db:"request_count"`
+	ClientErrors int64 `json:"client_errors" db:"client_error_count"` // 4xx responses, including rate limiting
+	ServerErrors int64 `json:"server_errors" db:"server_error_count"` // 5xx responses
+}
+
+// HourlyCounts is a key's request counts for the hour starting at Hour
+type HourlyCounts struct {
+	Hour time.Time `json:"hour" db:"hour"`
+	Counts
+}
+
+// WindowMetrics totals a key's requests over one rolling window
+type WindowMetrics struct {
+	Counts
+	ErrorPercent float64 `json:"error_percent"` // client and server errors; 0 when there were no requests
+}
+
+// KeyMetrics are an SDK key's request counters. Requests are counted in memory and written
+// periodically, so the latest minute may be missing.
+type KeyMetrics struct {
+	ProjectID string `json:"project_id"`
+	// KeyID is the project ID for the project's own key, or the environment ID of an environment key
+	KeyID string `json:"key_id"`
+	// Windows totals the requests over each of Windows, by name
+	Windows map[string]WindowMetrics `json:"windows"`
+	// Hourly lists the hours with requests, oldest first
+	Hourly []HourlyCounts `json:"hourly"`
+}
+
+// NewKeyMetrics totals hourly counts into each of Windows, ending at now's hour
+func NewKeyMetrics(projectID string, keyID string, hourly []HourlyCounts, now time.Time) *KeyMetrics {
+	m := &KeyMetrics{ProjectID: projectID, KeyID: keyID, Windows: make(map[string]WindowMetrics, len(Windows)), Hourly: hourly}
+	if m.Hourly == nil {
+		m.Hourly = []HourlyCounts{}
+	}
+
+	for _, w := range Windows {
+		since := now.Add(-w.Duration).Truncate(time.Hour).Add(time.Hour)
+		var total WindowMetrics
+		for _, h := range hourly {
+			if h.Hour.Before(since) {
+				continue
+			}
+			total.Requests += h.Requests
+			total.ClientErrors += h.ClientErrors
+			total.ServerErrors += h.ServerErrors
+		}
+		if total.Requests > 0 {
+			total.ErrorPercent = math.Round(float64(total.ClientErrors+total.ServerErrors)/float64(total.Requests)*10000) / 100
+		}
+		m.Windows[w.Name] = total
+	}
+	return m
+}
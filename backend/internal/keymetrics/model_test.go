@@ -0,0 +1,31 @@
+package keymetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKeyMetrics_TotalsEachWindow(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	hourly := []HourlyCounts{
+		{Hour: now.Add(-72 * time.Hour).Truncate(time.Hour), Counts: Counts{Requests: 100, ServerErrors: 10}},
+		{Hour: now.Add(-3 * time.Hour).Truncate(time.Hour), Counts: Counts{Requests: 50, ClientErrors: 5}},
+		{Hour: now.Truncate(time.Hour), Counts: Counts{Requests: 50}},
+	}
+
+	m := NewKeyMetrics("project-1", "env-1", hourly, now)
+
+	assert.Equal(t, WindowMetrics{Counts: Counts{Requests: 50}}, m.Windows["1h"])
+	assert.Equal(t, WindowMetrics{Counts: Counts{Requests: 100, ClientErrors: 5}, ErrorPercent: 5}, m.Windows["24h"])
+	assert.Equal(t, WindowMetrics{Counts: Counts{Requests: 200, ClientErrors: 5, ServerErrors: 10}, ErrorPercent: 7.5}, m.Windows["7d"])
+	assert.Len(t, m.Hourly, 3)
+}
+
+func TestNewKeyMetrics_NoRequests(t *testing.T) {
+	m := NewKeyMetrics("project-1", "project-1", nil, time.Now())
+
+	assert.Equal(t, []HourlyCounts{}, m.Hourly)
+	assert.Equal(t, WindowMetrics{}, m.Windows["7d"], "no division by zero")
+}
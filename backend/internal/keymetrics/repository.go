@@ -0,0 +1,90 @@
+package keymetrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	CountStore
+	// KeyExists reports whether keyID is the project's own key (the project ID) or one of its environments
+	KeyExists(ctx context.Context, projectID string, keyID string, tenantID string) (bool, error)
+	ListCounts(ctx context.Context, projectID string, keyID string, tenantID string, since time.Time) ([]HourlyCounts, error)
+	DeleteCounts(ctx context.Context, before time.Time) (int64, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// RecordCounts adds request counts to each key's bucket for the given hour
+func (r *postgresRepository) RecordCounts(ctx context.Context, counts map[Key]Counts, hour time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for key, c := range counts {
+		// The tenant is repeated in the WHERE clause so a key can't write to another tenant's project
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO api_key_request_counts (project_id, tenant_id, key_id, hour, request_count, client_error_count, server_error_count)
+			SELECT p.id, p.tenant_id, $3, $4, $5, $6, $7
+			FROM projects p
+			WHERE p.id = $1 AND p.tenant_id = $2
+			ON CONFLICT (project_id, key_id, hour) DO UPDATE
+			SET request_count = api_key_request_counts.request_count + EXCLUDED.request_count,
+			    client_error_count = api_key_request_counts.client_error_count + EXCLUDED.client_error_count,
+			    server_error_count = api_key_request_counts.server_error_count + EXCLUDED.server_error_count
+		`, key.ProjectID, key.TenantID, key.KeyID, hour, c.Requests, c.ClientErrors, c.ServerErrors)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *postgresRepository) KeyExists(ctx context.Context, projectID string, keyID string, tenantID string) (bool, error) {
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM projects WHERE id = $1 AND tenant_id = $3 AND id::text = $2
+		) OR EXISTS (
+			SELECT 1 FROM environments WHERE project_id = $1 AND tenant_id = $3 AND id::text = $2
+		)
+	`, projectID, keyID, tenantID)
+	return exists, err
+}
+
+// ListCounts returns a key's hourly request counts since the given hour, oldest first
+func (r *postgresRepository) ListCounts(ctx context.Context, projectID string, keyID string, tenantID string, since time.Time) ([]HourlyCounts, error) {
+	counts := []HourlyCounts{}
+	err := sqlx.SelectContext(ctx, r.db, &counts, `
+		SELECT hour, request_count, client_error_count, server_error_count
+		FROM api_key_request_counts
+		WHERE project_id = $1 AND key_id = $2 AND tenant_id = $3 AND hour >= $4
+		ORDER BY hour ASC
+	`, projectID, keyID, tenantID, since)
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// DeleteCounts removes hourly counts older than before
+func (r *postgresRepository) DeleteCounts(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM api_key_request_counts WHERE hour < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,79 @@
+package keymetrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/validator"
+)
+
+type Service interface {
+	// Metrics returns an SDK key's request and error counts over each of Windows
+	Metrics(ctx context.Context, projectID string, keyID string, tenantID string) (*KeyMetrics, error)
+	// Prune deletes request counts older than Retention (run by the jobs scheduler)
+	Prune(ctx context.Context) error
+}
+
+type service struct {
+	repo      Repository
+	validator validator.Validator
+	logger    *slog.Logger
+}
+
+func NewService(repo Repository, val validator.Validator, logger *slog.Logger) Service {
+	return &service{
+		repo:      repo,
+		validator: val,
+		logger:    logger,
+	}
+}
+
+func (s *service) Metrics(ctx context.Context, projectID string, keyID string, tenantID string) (*KeyMetrics, error) {
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	exists, err := s.repo.KeyExists(ctx, projectID, keyID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to look up api key",
+			slog.String("project_id", projectID),
+			slog.String("key_id", keyID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to load api key metrics: %w", err)
+	}
+	if !exists {
+		return nil, pkgErrors.ErrNotFound
+	}
+
+	now := time.Now()
+	since := now.Add(-Retention).Truncate(time.Hour).Add(time.Hour)
+	hourly, err := s.repo.ListCounts(ctx, projectID, keyID, tenantID, since)
+	if err != nil {
+		s.logger.Error("failed to load api key metrics",
+			slog.String("project_id", projectID),
+			slog.String("key_id", keyID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to load api key metrics: %w", err)
+	}
+
+	return NewKeyMetrics(projectID, keyID, hourly, now), nil
+}
+
+func (s *service) Prune(ctx context.Context) error {
+	deleted, err := s.repo.DeleteCounts(ctx, time.Now().Add(-Retention))
+	if err != nil {
+		return fmt.Errorf("failed to prune api key request counts: %w", err)
+	}
+
+	if deleted > 0 {
+		s.logger.Debug("api key request counts pruned", slog.Int64("count", deleted))
+	}
+
+	return nil
+}
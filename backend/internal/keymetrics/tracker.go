@@ -0,0 +1,124 @@
+package keymetrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/jalil32/toggle/internal/pkg/batch"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	"github.com/jalil32/toggle/internal/pkg/degrade"
+)
+
+// CountStore persists request counts per key
+type CountStore interface {
+	RecordCounts(ctx context.Context, counts map[Key]Counts, hour time.Time) error
+}
+
+// Key identifies one SDK key of one project
+type Key struct {
+	ProjectID string
+	TenantID  string
+	// KeyID is the project ID for the project's own key, or the environment ID of an environment key
+	KeyID string
+}
+
+// Tracker counts SDK requests and errors per key in memory and writes the counts to the
+// store periodically, keeping database writes off the evaluation path
+type Tracker struct {
+	store    CountStore
+	interval time.Duration
+	gate     degrade.Gate
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending map[Key]Counts
+}
+
+func NewTracker(store CountStore, interval time.Duration, logger *slog.Logger) *Tracker {
+	return &Tracker{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		pending:  make(map[Key]Counts),
+	}
+}
+
+// SetGate stops counting while analytics writes are shed by an overloaded instance
+func (t *Tracker) SetGate(gate degrade.Gate) {
+	t.gate = gate
+}
+
+// Middleware counts every request by the key that authenticated it, and its status once
+// handled; it must run after API key authentication. Requests with invalid keys aren't
+// attributed to any key.
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if t.gate != nil && !t.gate.Allow(degrade.FeatureAnalytics) {
+			return
+		}
+		ctx := c.Request.Context()
+		projectID, _ := appContext.ProjectID(ctx)
+		tenantID, _ := appContext.TenantID(ctx)
+		keyID := appContext.EnvironmentID(ctx)
+		if keyID == "" {
+			keyID = projectID
+		}
+		t.Record(Key{ProjectID: projectID, TenantID: tenantID, KeyID: keyID}, c.Writer.Status())
+	}
+}
+
+// Record counts one request by a key with its response status; it never blocks on the database
+func (t *Tracker) Record(key Key, status int) {
+	if key.ProjectID == "" || key.TenantID == "" || key.KeyID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counts := t.pending[key]
+	counts.Requests++
+	switch {
+	case status >= http.StatusInternalServerError:
+		counts.ServerErrors++
+	case status >= http.StatusBadRequest:
+		counts.ClientErrors++
+	}
+	t.pending[key] = counts
+}
+
+// Flush writes all pending counts to the store, in the current hour
+func (t *Tracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	counts := t.pending
+	t.pending = make(map[Key]Counts)
+	t.mu.Unlock()
+
+	if err := t.store.RecordCounts(ctx, counts, time.Now().Truncate(time.Hour)); err != nil {
+		// Telemetry is best-effort: dropped counts are recovered by the next requests
+		t.logger.Warn("failed to record api key requests",
+			slog.Int("keys", len(counts)),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	t.logger.Debug("api key requests recorded", slog.Int("keys", len(counts)))
+	return nil
+}
+
+// Run flushes pending counts every interval until ctx is cancelled, then flushes once more
+func (t *Tracker) Run(ctx context.Context) {
+	batch.Run(ctx, t.interval, t.Flush)
+}
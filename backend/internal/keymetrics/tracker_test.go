@@ -0,0 +1,104 @@
+package keymetrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+type mockStore struct {
+	recorded []map[Key]Counts
+	hours    []time.Time
+	err      error
+}
+
+func (m *mockStore) RecordCounts(ctx context.Context, counts map[Key]Counts, hour time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.recorded = append(m.recorded, counts)
+	m.hours = append(m.hours, hour)
+	return nil
+}
+
+func TestTracker_MiddlewareCountsPerKeyAndStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &mockStore{}
+	tracker := NewTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := appContext.WithSDKAuth(c.Request.Context(), "project-1", "tenant-1")
+		if env := c.GetHeader("X-Environment-ID"); env != "" {
+			ctx = appContext.WithEnvironment(ctx, env)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	router.Use(tracker.Middleware())
+	router.GET("/sdk/evaluate", func(c *gin.Context) {
+		status, _ := strconv.Atoi(c.Query("status"))
+		c.Status(status)
+	})
+
+	requests := []struct {
+		environmentID string
+		status        int
+	}{
+		{"", http.StatusOK},
+		{"", http.StatusTooManyRequests},
+		{"env-1", http.StatusOK},
+		{"env-1", http.StatusServiceUnavailable},
+		{"env-1", http.StatusNotModified},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(http.MethodGet, "/sdk/evaluate?status="+strconv.Itoa(r.status), nil)
+		req.Header.Set("X-Environment-ID", r.environmentID)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	require.Len(t, store.recorded, 1)
+	assert.Equal(t, map[Key]Counts{
+		{ProjectID: "project-1", TenantID: "tenant-1", KeyID: "project-1"}: {Requests: 2, ClientErrors: 1},
+		{ProjectID: "project-1", TenantID: "tenant-1", KeyID: "env-1"}:     {Requests: 3, ServerErrors: 1},
+	}, store.recorded[0])
+	assert.Equal(t, store.hours[0], store.hours[0].Truncate(time.Hour), "counts are written to an hour bucket")
+}
+
+func TestTracker_IgnoresUnauthenticatedRequests(t *testing.T) {
+	store := &mockStore{}
+	tracker := NewTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tracker.Record(Key{TenantID: "tenant-1", KeyID: "project-1"}, http.StatusOK)
+	tracker.Record(Key{ProjectID: "project-1", KeyID: "project-1"}, http.StatusOK)
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Empty(t, store.recorded, "nothing to flush")
+}
+
+func TestTracker_FlushFailureDropsCounts(t *testing.T) {
+	store := &mockStore{err: errors.New("database down")}
+	tracker := NewTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	key := Key{ProjectID: "project-1", TenantID: "tenant-1", KeyID: "project-1"}
+
+	tracker.Record(key, http.StatusOK)
+	assert.Error(t, tracker.Flush(context.Background()))
+
+	store.err = nil
+	tracker.Record(key, http.StatusOK)
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Equal(t, Counts{Requests: 1}, store.recorded[0][key], "counts from the failed flush aren't retried")
+}
@@ -0,0 +1,87 @@
+package maintenance
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler struct {
+	service Service
+	token   string
+}
+
+// NewHandler serves the maintenance admin API to callers presenting token as a bearer token.
+// Operators don't belong to a tenant, so the token stands in for an instance administrator.
+func NewHandler(service Service, token string) *Handler {
+	return &Handler{service: service, token: token}
+}
+
+// RegisterRoutes registers the maintenance routes; they authenticate their own callers and
+// must not be behind Auth
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup, middleware ...gin.HandlerFunc) {
+	admin := r.Group("/admin/maintenance", append(middleware, h.authorize)...)
+	admin.GET("", h.List)
+	admin.POST("", h.Announce)
+	admin.DELETE("/:id", h.Cancel)
+}
+
+func (h *Handler) authorize(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid maintenance token"})
+		return
+	}
+	c.Next()
+}
+
+// List returns the announced windows that haven't ended
+func (h *Handler) List(c *gin.Context) {
+	windows, err := h.service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list maintenance windows"})
+		return
+	}
+
+	c.JSON(http.StatusOK, windows)
+}
+
+// Announce schedules a maintenance window
+func (h *Handler) Announce(c *gin.Context) {
+	var req AnnounceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	window, err := h.service.Announce(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidWindow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to announce maintenance window"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, window)
+}
+
+// Cancel withdraws a maintenance window
+func (h *Handler) Cancel(c *gin.Context) {
+	if err := h.service.Cancel(c.Request.Context(), c.Param("id")); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "maintenance window not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel maintenance window"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package maintenance
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+)
+
+// Expected impact of a maintenance window on SDKs, from least to most disruptive
+const (
+	DegradationDelayedUpdates = "delayed_updates" // SDKs are served, but flag changes may arrive late
+	DegradationReadOnly       = "read_only"       // the management API rejects changes; SDKs are served
+	DegradationUnavailable    = "unavailable"     // SDK requests may fail; SDKs should serve cached flags
+)
+
+// Degradations lists the valid degradation levels
+var Degradations = []string{DegradationDelayedUpdates, DegradationReadOnly, DegradationUnavailable}
+
+// AdvisoryHeader is set on SDK responses while a maintenance window is under way
+const AdvisoryHeader = "X-Maintenance"
+
+// HeadsUpLead is how long before a window starts streaming SDKs are told about it
+const HeadsUpLead = time.Hour
+
+// MaxWindowLength is the longest maintenance window that can be announced
+const MaxWindowLength = 24 * time.Hour
+
+// maxMessageLength caps the message shown to SDKs
+const maxMessageLength = 500
+
+// Window is planned maintenance announced by the instance's operators
+type Window struct {
+	ID          string    `json:"id" db:"id"`
+	StartsAt    time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt      time.Time `json:"ends_at" db:"ends_at"`
+	Degradation string    `json:"degradation" db:"degradation"`
+	Message     string    `json:"message" db:"message"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Active reports whether the window is under way at now
+func (w Window) Active(now time.Time) bool {
+	return !now.Before(w.StartsAt) && now.Before(w.EndsAt)
+}
+
+// Advisory is the AdvisoryHeader value, e.g. "degradation=read_only; ends=2026-10-20T03:00:00Z"
+func (w Window) Advisory() string {
+	return fmt.Sprintf("degradation=%s; ends=%s", w.Degradation, w.EndsAt.UTC().Format(time.RFC3339))
+}
+
+// Notice is the window as announced to streaming SDKs
+func (w Window) Notice() *evaluation.MaintenanceNotice {
+	return &evaluation.MaintenanceNotice{
+		ID:          w.ID,
+		StartsAt:    w.StartsAt.UTC(),
+		EndsAt:      w.EndsAt.UTC(),
+		Degradation: w.Degradation,
+		Message:     w.Message,
+	}
+}
+
+// AnnounceRequest announces a maintenance window
+type AnnounceRequest struct {
+	StartsAt    time.Time `json:"starts_at" binding:"required"`
+	EndsAt      time.Time `json:"ends_at" binding:"required"`
+	Degradation string    `json:"degradation" binding:"required"`
+	Message     string    `json:"message"`
+}
+
+// Validate checks the window ends after it starts, hasn't already ended, and is within
+// MaxWindowLength
+func (r AnnounceRequest) Validate(now time.Time) error {
+	switch {
+	case !r.EndsAt.After(r.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidWindow)
+	case !r.EndsAt.After(now):
+		return fmt.Errorf("%w: the window has already ended", ErrInvalidWindow)
+	case r.EndsAt.Sub(r.StartsAt) > MaxWindowLength:
+		return fmt.Errorf("%w: windows can be at most %s long", ErrInvalidWindow, MaxWindowLength)
+	case !slices.Contains(Degradations, r.Degradation):
+		return fmt.Errorf("%w: degradation must be one of %s", ErrInvalidWindow, strings.Join(Degradations, ", "))
+	case len(r.Message) > maxMessageLength:
+		return fmt.Errorf("%w: message can be at most %d characters", ErrInvalidWindow, maxMessageLength)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Reader lists the windows a Schedule serves
+type Reader interface {
+	// ListCurrent returns the windows that haven't ended by now, earliest first
+	ListCurrent(ctx context.Context, now time.Time) ([]Window, error)
+}
+
+type Repository interface {
+	Reader
+	// Create stores a window, setting its ID and creation time
+	Create(ctx context.Context, w *Window) error
+	// Delete cancels a window; sql.ErrNoRows means there was none
+	Delete(ctx context.Context, id string) error
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) Create(ctx context.Context, w *Window) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO maintenance_windows (starts_at, ends_at, degradation, message)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, w.StartsAt, w.EndsAt, w.Degradation, w.Message).Scan(&w.ID, &w.CreatedAt)
+}
+
+func (r *postgresRepository) ListCurrent(ctx context.Context, now time.Time) ([]Window, error) {
+	windows := []Window{}
+	err := sqlx.SelectContext(ctx, r.db, &windows, `
+		SELECT id, starts_at, ends_at, degradation, message, created_at
+		FROM maintenance_windows
+		WHERE ends_at > $1
+		ORDER BY starts_at
+	`, now)
+	return windows, err
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
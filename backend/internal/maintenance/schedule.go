@@ -0,0 +1,103 @@
+package maintenance
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+)
+
+// PollInterval is how often each instance reloads the announced windows, so windows
+// announced through another instance reach its SDKs within this long
+const PollInterval = 30 * time.Second
+
+// Schedule holds the maintenance windows that haven't ended, reloaded from the repository
+// in the background so SDK requests never wait on it
+type Schedule struct {
+	repo   Reader
+	logger *slog.Logger
+
+	mu      sync.RWMutex
+	windows []Window
+}
+
+func NewSchedule(repo Reader, logger *slog.Logger) *Schedule {
+	return &Schedule{repo: repo, logger: logger}
+}
+
+// Refresh reloads the windows that haven't ended
+func (s *Schedule) Refresh(ctx context.Context) error {
+	windows, err := s.repo.ListCurrent(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.windows = windows
+	s.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the schedule every interval until ctx is cancelled. A failed refresh keeps
+// the windows already loaded.
+func (s *Schedule) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			s.logger.Warn("failed to refresh maintenance windows", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Active returns the window under way at now, or nil. Overlapping windows report the one
+// that started first.
+func (s *Schedule) Active(now time.Time) *Window {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, w := range s.windows {
+		if w.Active(now) {
+			return &w
+		}
+	}
+	return nil
+}
+
+// Notice returns the window under way at now, or else the next one starting within
+// HeadsUpLead, as announced to streaming SDKs
+func (s *Schedule) Notice(now time.Time) *evaluation.MaintenanceNotice {
+	if w := s.Active(now); w != nil {
+		return w.Notice()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, w := range s.windows {
+		if w.StartsAt.After(now) && w.StartsAt.Sub(now) <= HeadsUpLead {
+			return w.Notice()
+		}
+	}
+	return nil
+}
+
+// Middleware sets AdvisoryHeader on responses while a window is under way
+func (s *Schedule) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if w := s.Active(time.Now()); w != nil {
+			c.Header(AdvisoryHeader, w.Advisory())
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,96 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+// ErrInvalidWindow indicates a window that has ended, is too long, or has an unknown degradation
+var ErrInvalidWindow = errors.New("invalid maintenance window")
+
+type Service interface {
+	// Announce schedules a maintenance window
+	Announce(ctx context.Context, req AnnounceRequest) (*Window, error)
+	// List returns the windows that haven't ended, earliest first
+	List(ctx context.Context) ([]Window, error)
+	// Cancel withdraws a window; not found if there is none
+	Cancel(ctx context.Context, id string) error
+}
+
+type service struct {
+	repo     Repository
+	schedule *Schedule
+	logger   *slog.Logger
+}
+
+// NewService announces windows through repo. Changes reach schedule at once; the schedules
+// of other instances pick them up within PollInterval.
+func NewService(repo Repository, schedule *Schedule, logger *slog.Logger) Service {
+	return &service{
+		repo:     repo,
+		schedule: schedule,
+		logger:   logger,
+	}
+}
+
+func (s *service) Announce(ctx context.Context, req AnnounceRequest) (*Window, error) {
+	if err := req.Validate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	w := &Window{StartsAt: req.StartsAt, EndsAt: req.EndsAt, Degradation: req.Degradation, Message: req.Message}
+	if err := s.repo.Create(ctx, w); err != nil {
+		s.logger.Error("failed to announce maintenance window", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("failed to announce maintenance window: %w", err)
+	}
+	s.refresh(ctx)
+
+	s.logger.Info("maintenance window announced",
+		slog.String("id", w.ID),
+		slog.Time("starts_at", w.StartsAt),
+		slog.Time("ends_at", w.EndsAt),
+		slog.String("degradation", w.Degradation),
+	)
+	return w, nil
+}
+
+func (s *service) List(ctx context.Context) ([]Window, error) {
+	windows, err := s.repo.ListCurrent(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance windows: %w", err)
+	}
+	return windows, nil
+}
+
+func (s *service) Cancel(ctx context.Context, id string) error {
+	// Malformed IDs can't match, and would fail as UUIDs in the query
+	if _, err := uuid.Parse(id); err != nil {
+		return pkgErrors.ErrNotFound
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		return fmt.Errorf("failed to cancel maintenance window: %w", err)
+	}
+	s.refresh(ctx)
+
+	s.logger.Info("maintenance window cancelled", slog.String("id", id))
+	return nil
+}
+
+// refresh reloads this instance's schedule after a change; on failure the next poll catches up
+func (s *service) refresh(ctx context.Context) {
+	if err := s.schedule.Refresh(ctx); err != nil {
+		s.logger.Warn("failed to refresh maintenance windows", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,149 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type mockRepository struct {
+	windows []Window
+}
+
+func (m *mockRepository) Create(ctx context.Context, w *Window) error {
+	w.ID = "window-1"
+	m.windows = append(m.windows, *w)
+	return nil
+}
+
+func (m *mockRepository) ListCurrent(ctx context.Context, now time.Time) ([]Window, error) {
+	current := []Window{}
+	for _, w := range m.windows {
+		if w.EndsAt.After(now) {
+			current = append(current, w)
+		}
+	}
+	return current, nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string) error {
+	return sql.ErrNoRows
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestAnnounceRequestValidate(t *testing.T) {
+	now := time.Now()
+	valid := AnnounceRequest{StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour), Degradation: DegradationReadOnly}
+
+	tests := []struct {
+		name   string
+		modify func(r *AnnounceRequest)
+		valid  bool
+	}{
+		{name: "valid", modify: func(r *AnnounceRequest) {}, valid: true},
+		{name: "under way", modify: func(r *AnnounceRequest) { r.StartsAt = now.Add(-time.Hour) }, valid: true},
+		{name: "ends before it starts", modify: func(r *AnnounceRequest) { r.EndsAt = r.StartsAt }},
+		{name: "already ended", modify: func(r *AnnounceRequest) {
+			r.StartsAt, r.EndsAt = now.Add(-2*time.Hour), now.Add(-time.Hour)
+		}},
+		{name: "too long", modify: func(r *AnnounceRequest) { r.EndsAt = r.StartsAt.Add(MaxWindowLength + time.Minute) }},
+		{name: "unknown degradation", modify: func(r *AnnounceRequest) { r.Degradation = "slow" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			err := req.Validate(now)
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidWindow) {
+				t.Errorf("expected ErrInvalidWindow, got %v", err)
+			}
+		})
+	}
+}
+
+func TestScheduleNotice(t *testing.T) {
+	now := time.Now()
+	repo := &mockRepository{}
+	schedule := NewSchedule(repo, discardLogger())
+	svc := NewService(repo, schedule, discardLogger())
+
+	window, err := svc.Announce(context.Background(), AnnounceRequest{
+		StartsAt:    now.Add(90 * time.Minute),
+		EndsAt:      now.Add(2 * time.Hour),
+		Degradation: DegradationDelayedUpdates,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Announcing refreshes this instance's schedule without waiting for a poll
+	if n := schedule.Notice(now); n != nil {
+		t.Errorf("expected no notice more than %v ahead, got %+v", HeadsUpLead, n)
+	}
+	if n := schedule.Notice(now.Add(45 * time.Minute)); n == nil || n.ID != window.ID {
+		t.Errorf("expected a heads-up within %v of the window, got %+v", HeadsUpLead, n)
+	}
+	if schedule.Active(now.Add(45*time.Minute)) != nil {
+		t.Error("expected the window not to be active before it starts")
+	}
+	if w := schedule.Active(now.Add(100 * time.Minute)); w == nil || w.ID != window.ID {
+		t.Errorf("expected the window to be active, got %+v", w)
+	}
+	if n := schedule.Notice(now.Add(3 * time.Hour)); n != nil {
+		t.Errorf("expected no notice after the window, got %+v", n)
+	}
+}
+
+func TestScheduleMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	repo := &mockRepository{windows: []Window{{
+		ID:          "window-1",
+		StartsAt:    now.Add(-time.Minute),
+		EndsAt:      time.Date(2099, 1, 1, 3, 0, 0, 0, time.UTC),
+		Degradation: DegradationReadOnly,
+	}}}
+	schedule := NewSchedule(repo, discardLogger())
+	if err := schedule.Refresh(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	router := gin.New()
+	router.Use(schedule.Middleware())
+	router.GET("/sdk/evaluate", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sdk/evaluate", nil))
+
+	if got, want := w.Header().Get(AdvisoryHeader), "degradation=read_only; ends=2099-01-01T03:00:00Z"; got != want {
+		t.Errorf("expected %s %q, got %q", AdvisoryHeader, want, got)
+	}
+}
+
+func TestServiceCancel_NotFound(t *testing.T) {
+	repo := &mockRepository{}
+	svc := NewService(repo, NewSchedule(repo, discardLogger()), discardLogger())
+
+	for _, id := range []string{"not-a-uuid", "5f0c4a36-0b4e-4f0e-9d0a-6c0b6a1e2f01"} {
+		if err := svc.Cancel(context.Background(), id); !errors.Is(err, pkgErrors.ErrNotFound) {
+			t.Errorf("expected ErrNotFound for %q, got %v", id, err)
+		}
+	}
+}
@@ -13,8 +13,9 @@ import (
 	"github.com/jalil32/toggle/internal/projects"
 )
 
-// APIKey middleware authenticates SDK requests using client_api_key
-// and injects project_id and tenant_id into context
+// APIKey middleware authenticates SDK requests using a project or environment client_api_key,
+// or a project's client_side_api_key, and injects project_id and tenant_id into context, plus
+// environment_id for environment keys and a client-side marker for client-side keys
 func APIKey(projectRepo projects.Repository, logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -38,8 +39,17 @@ func APIKey(projectRepo projects.Repository, logger *slog.Logger) gin.HandlerFun
 			return
 		}
 
-		// Lookup project by API key
+		// Lookup project by API key, then by environment key, then by client-side key
+		var environment *projects.Environment
+		clientSide := false
 		project, err := projectRepo.GetByAPIKey(c.Request.Context(), apiKey)
+		if errors.Is(err, sql.ErrNoRows) {
+			project, environment, err = projectRepo.GetByEnvironmentAPIKey(c.Request.Context(), apiKey)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			project, err = projectRepo.GetByClientSideAPIKey(c.Request.Context(), apiKey)
+			clientSide = err == nil
+		}
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				logger.Warn("invalid API key",
@@ -59,11 +69,21 @@ func APIKey(projectRepo projects.Repository, logger *slog.Logger) gin.HandlerFun
 
 		// Inject project and tenant context (similar to tenant middleware)
 		ctx := appContext.WithSDKAuth(c.Request.Context(), project.ID, project.TenantID)
+		environmentID := ""
+		if environment != nil {
+			environmentID = environment.ID
+			ctx = appContext.WithEnvironment(ctx, environmentID)
+		}
+		if clientSide {
+			ctx = appContext.WithClientSideKey(ctx)
+		}
 		c.Request = c.Request.WithContext(ctx)
 
 		logger.Debug("SDK request authenticated",
 			slog.String("project_id", project.ID),
 			slog.String("tenant_id", project.TenantID),
+			slog.String("environment_id", environmentID),
+			slog.Bool("client_side", clientSide),
 		)
 
 		c.Next()
@@ -1,4 +1,7 @@
-package middleware
+// Package management authenticates management API requests: user JWTs, tenant membership
+// and quotas, over REST and gRPC. SDK middleware stays in internal/middleware, so the
+// standalone evaluator does not link the user and tenant packages.
+package management
 
 import (
 	"log/slog"
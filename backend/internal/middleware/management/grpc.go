@@ -0,0 +1,113 @@
+package management
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jalil32/toggle/config"
+	"github.com/jalil32/toggle/internal/auth"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	"github.com/jalil32/toggle/internal/quotas"
+	"github.com/jalil32/toggle/internal/tenants"
+)
+
+// gRPC metadata keys, the counterparts of the Authorization and X-Tenant-ID headers
+const (
+	GRPCAuthorizationKey = "authorization"
+	GRPCTenantKey        = "x-tenant-id"
+)
+
+// TokenVerifier verifies a user's access token
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (*auth.BetterAuthClaims, error)
+}
+
+// devTokenVerifier accepts any token as the dev user, like devModeMiddleware
+type devTokenVerifier struct{}
+
+func (devTokenVerifier) VerifyToken(ctx context.Context, token string) (*auth.BetterAuthClaims, error) {
+	return &auth.BetterAuthClaims{UserID: "00000000-0000-0000-0000-000000000001"}, nil
+}
+
+// GRPCVerifier returns the verifier of gRPC access tokens: the JWT verifier, or with
+// SKIP_AUTH one that takes every request as the dev user
+func GRPCVerifier(cfg *config.Config, logger *slog.Logger) TokenVerifier {
+	if cfg.JWT.SkipAuth {
+		logger.Warn("grpc auth disabled - SKIP_AUTH is true")
+		return devTokenVerifier{}
+	}
+	if cfg.JWT.JWKSURL == "" || cfg.JWT.Issuer == "" || cfg.JWT.Audience == "" {
+		panic("JWT_JWKS_URL, JWT_ISSUER, and JWT_AUDIENCE must be set when SKIP_AUTH is false")
+	}
+	return auth.NewJWTVerifier(cfg.JWT.JWKSURL, cfg.JWT.Issuer, cfg.JWT.Audience)
+}
+
+// GRPCAuth is Auth, Tenant and Quota for the gRPC management API. Every call needs the
+// user's token in the authorization metadata and the tenant it acts on in x-tenant-id;
+// the user's role in that tenant is put in the call's context, and the call counts
+// against the tenant's management API quota.
+func GRPCAuth(verifier TokenVerifier, tenantRepo tenants.Repository, quotaService quotas.Service, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		token, err := auth.ExtractTokenFromHeader(firstValue(md, GRPCAuthorizationKey))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+		}
+		claims, err := verifier.VerifyToken(ctx, token)
+		if err != nil {
+			logger.Warn("grpc token validation failed",
+				slog.String("error", err.Error()),
+				slog.String("method", info.FullMethod),
+			)
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		if claims.UserID == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing user identifier in token")
+		}
+
+		tenantID := firstValue(md, GRPCTenantKey)
+		if tenantID == "" {
+			return nil, status.Error(codes.InvalidArgument, "x-tenant-id metadata required")
+		}
+		role, err := tenantRepo.GetMembership(ctx, claims.UserID, tenantID)
+		if err != nil {
+			logger.Error("grpc auth: failed to verify tenant access",
+				slog.String("user_id", claims.UserID),
+				slog.String("tenant_id", tenantID),
+				slog.String("error", err.Error()),
+			)
+			return nil, status.Error(codes.Internal, "failed to verify tenant access")
+		}
+		if role == "" {
+			return nil, status.Error(codes.PermissionDenied, "access denied to this tenant")
+		}
+
+		// As with Quota, an unavailable counter lets the call through
+		quota, err := quotaService.Consume(ctx, tenantID)
+		if err != nil {
+			logger.Error("grpc auth: failed to record request",
+				slog.String("tenant_id", tenantID),
+				slog.String("error", err.Error()),
+			)
+		} else if quota.Exceeded() {
+			return nil, status.Error(codes.ResourceExhausted, "tenant API quota exceeded")
+		}
+
+		return handler(appContext.WithAuth(ctx, claims.UserID, tenantID, role), req)
+	}
+}
+
+// firstValue returns the first value of a metadata key, or "" when it's missing
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
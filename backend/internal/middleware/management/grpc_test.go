@@ -0,0 +1,102 @@
+package management
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/jalil32/toggle/internal/auth"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	"github.com/jalil32/toggle/internal/quotas"
+	"github.com/jalil32/toggle/internal/tenants"
+)
+
+type stubVerifier struct{}
+
+func (stubVerifier) VerifyToken(ctx context.Context, token string) (*auth.BetterAuthClaims, error) {
+	if token != "valid" {
+		return nil, errors.New("bad signature")
+	}
+	return &auth.BetterAuthClaims{UserID: "user-1"}, nil
+}
+
+// stubMembers makes user-1 an admin of tenant-1 and of no other tenant
+type stubMembers struct {
+	tenants.Repository
+}
+
+func (stubMembers) GetMembership(ctx context.Context, userID, tenantID string) (string, error) {
+	if userID == "user-1" && tenantID == "tenant-1" {
+		return "admin", nil
+	}
+	return "", nil
+}
+
+func grpcAuthInterceptor(limit int) grpc.UnaryServerInterceptor {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := quotas.NewService(stubPlans{}, quotas.NewMemoryCounter(), quotas.Limits{quotas.PlanFree: limit}, logger)
+	return GRPCAuth(stubVerifier{}, stubMembers{}, svc, logger)
+}
+
+// grpcAuthCall runs a call through interceptor, returning the handler's context if it ran
+func grpcAuthCall(interceptor grpc.UnaryServerInterceptor, md metadata.MD) (context.Context, error) {
+	var got context.Context
+	_, err := interceptor(metadata.NewIncomingContext(context.Background(), md), nil,
+		&grpc.UnaryServerInfo{FullMethod: "/toggle.management.v1.FlagService/ListFlags"},
+		func(ctx context.Context, req any) (any, error) {
+			got = ctx
+			return nil, nil
+		})
+	return got, err
+}
+
+func TestGRPCAuth_SetsUserTenantAndRole(t *testing.T) {
+	ctx, err := grpcAuthCall(grpcAuthInterceptor(10), metadata.Pairs(GRPCAuthorizationKey, "Bearer valid", GRPCTenantKey, "tenant-1"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "user-1", appContext.MustUserID(ctx))
+	assert.Equal(t, "tenant-1", appContext.MustTenantID(ctx))
+	assert.Equal(t, "admin", appContext.UserRole(ctx))
+}
+
+func TestGRPCAuth_Rejects(t *testing.T) {
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want codes.Code
+	}{
+		{name: "missing token", md: metadata.Pairs(GRPCTenantKey, "tenant-1"), want: codes.Unauthenticated},
+		{name: "invalid token", md: metadata.Pairs(GRPCAuthorizationKey, "Bearer forged", GRPCTenantKey, "tenant-1"), want: codes.Unauthenticated},
+		{name: "missing tenant", md: metadata.Pairs(GRPCAuthorizationKey, "Bearer valid"), want: codes.InvalidArgument},
+		{name: "not a member", md: metadata.Pairs(GRPCAuthorizationKey, "Bearer valid", GRPCTenantKey, "tenant-2"), want: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := grpcAuthCall(grpcAuthInterceptor(10), tt.md)
+			assert.Equal(t, tt.want, status.Code(err))
+			assert.Nil(t, ctx, "handler should not run")
+		})
+	}
+}
+
+func TestGRPCAuth_BlocksOverQuota(t *testing.T) {
+	interceptor := grpcAuthInterceptor(1)
+	md := metadata.Pairs(GRPCAuthorizationKey, "Bearer valid", GRPCTenantKey, "tenant-1")
+
+	_, err := grpcAuthCall(interceptor, md)
+	require.NoError(t, err)
+
+	ctx, err := grpcAuthCall(interceptor, md)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Nil(t, ctx)
+}
@@ -0,0 +1,57 @@
+package management
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	"github.com/jalil32/toggle/internal/quotas"
+)
+
+// Quota headers sent on every tenant-scoped response
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset" // unix seconds when the window resets
+)
+
+// Quota enforces the tenant's management API quota and reports usage in X-RateLimit-* headers.
+// This middleware must run AFTER the Tenant middleware. If counters are unavailable the
+// request is let through, so a quota store outage doesn't take down the API.
+func Quota(service quotas.Service, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := appContext.MustTenantID(c.Request.Context())
+
+		status, err := service.Consume(c.Request.Context(), tenantID)
+		if err != nil {
+			logger.Error("quota middleware: failed to record request",
+				slog.String("tenant_id", tenantID),
+				slog.String("error", err.Error()),
+			)
+			c.Next()
+			return
+		}
+
+		c.Header(RateLimitLimitHeader, strconv.Itoa(status.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(status.Remaining))
+		c.Header(RateLimitResetHeader, strconv.FormatInt(status.ResetAt.Unix(), 10))
+
+		if status.Exceeded() {
+			logger.Warn("tenant quota exceeded",
+				slog.String("tenant_id", tenantID),
+				slog.String("plan", status.Plan),
+				slog.Int("limit", status.Limit),
+			)
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(status.ResetAt).Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "tenant API quota exceeded", "quota": status})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
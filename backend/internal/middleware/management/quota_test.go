@@ -0,0 +1,83 @@
+package management
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	"github.com/jalil32/toggle/internal/quotas"
+)
+
+type stubPlans struct{}
+
+func (stubPlans) GetPlan(ctx context.Context, tenantID string) (string, error) {
+	if tenantID == "missing" {
+		return "", sql.ErrNoRows
+	}
+	return quotas.PlanFree, nil
+}
+
+func quotaRouter(limit int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := quotas.NewService(stubPlans{}, quotas.NewMemoryCounter(), quotas.Limits{quotas.PlanFree: limit}, logger)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := appContext.WithTenant(c.Request.Context(), c.GetHeader("X-Tenant-ID"), "member")
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	router.Use(Quota(svc, logger))
+	router.GET("/flags", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func quotaRequest(router *gin.Engine, tenantID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/flags", nil)
+	req.Header.Set("X-Tenant-ID", tenantID)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestQuota_SetsHeadersAndBlocksOverLimit(t *testing.T) {
+	router := quotaRouter(2)
+
+	w := quotaRequest(router, "tenant-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "1", w.Header().Get(RateLimitRemainingHeader))
+	assert.NotEmpty(t, w.Header().Get(RateLimitResetHeader))
+
+	w = quotaRequest(router, "tenant-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+
+	w = quotaRequest(router, "tenant-1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Another tenant has its own quota
+	w = quotaRequest(router, "tenant-2")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestQuota_FailsOpenWhenStatusUnavailable(t *testing.T) {
+	router := quotaRouter(1)
+
+	w := quotaRequest(router, "missing")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(RateLimitLimitHeader))
+}
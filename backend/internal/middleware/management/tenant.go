@@ -1,4 +1,4 @@
-package middleware
+package management
 
 import (
 	"log/slog"
@@ -1,4 +1,4 @@
-package middleware_test
+package management_test
 
 import (
 	"context"
@@ -10,7 +10,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jalil32/toggle/internal/middleware"
+	"github.com/jalil32/toggle/internal/middleware/management"
 	pkgcontext "github.com/jalil32/toggle/internal/pkg/context"
 	"github.com/jalil32/toggle/internal/tenants"
 	"github.com/jalil32/toggle/internal/testutil"
@@ -22,7 +22,7 @@ import (
 func TestMain(m *testing.M) {
 	ctx := context.Background()
 
-	_, err := testutil.SetupTestDatabase(ctx, "../../migrations")
+	_, err := testutil.SetupTestDatabase(ctx, "../../../migrations")
 	if err != nil {
 		panic(err)
 	}
@@ -44,7 +44,7 @@ func setupTestRouter(tenantRepo tenants.Repository) *gin.Engine {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 
 	// Apply tenant middleware
-	router.Use(middleware.Tenant(tenantRepo, logger))
+	router.Use(management.Tenant(tenantRepo, logger))
 
 	// Test endpoint that returns tenant info from context
 	router.GET("/test", func(c *gin.Context) {
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+// RateLimit middleware limits each caller to limit requests per window.
+// Callers are identified by user ID when authenticated, otherwise by client IP.
+// Counters are kept in memory, so limits apply per server instance.
+func RateLimit(limit int, window time.Duration, logger *slog.Logger) gin.HandlerFunc {
+	limiter := newWindowLimiter(limit, window, time.Now)
+
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, err := appContext.UserID(c.Request.Context()); err == nil {
+			key = "user:" + userID
+		}
+
+		allowed, retryAfter := limiter.allow(key)
+		if !allowed {
+			logger.Warn("rate limit exceeded",
+				slog.String("key", key),
+				slog.String("path", c.Request.URL.Path),
+			)
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// windowLimiter is a fixed-window request counter keyed by caller
+type windowLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	now     func() time.Time
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newWindowLimiter(limit int, window time.Duration, now func() time.Time) *windowLimiter {
+	return &windowLimiter{
+		limit:   limit,
+		window:  window,
+		now:     now,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// allow records a request for key and reports whether it is within the limit,
+// and if not, how long until the current window resets
+func (l *windowLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		// Drop expired windows so the map does not grow without bound
+		if len(l.windows) > 10000 {
+			for k, old := range l.windows {
+				if now.Sub(old.start) >= l.window {
+					delete(l.windows, k)
+				}
+			}
+		}
+		l.windows[key] = &rateWindow{start: now, count: 1}
+		return true, 0
+	}
+
+	if w.count >= l.limit {
+		return false, l.window - now.Sub(w.start)
+	}
+
+	w.count++
+	return true, 0
+}
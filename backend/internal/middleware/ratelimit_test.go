@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+func TestWindowLimiter_ResetsAfterWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newWindowLimiter(2, time.Minute, func() time.Time { return now })
+
+	allowed, _ := limiter.allow("user:a")
+	assert.True(t, allowed)
+	allowed, _ = limiter.allow("user:a")
+	assert.True(t, allowed)
+
+	allowed, retryAfter := limiter.allow("user:a")
+	assert.False(t, allowed, "third request in the window should be rejected")
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// Other callers have their own budget
+	allowed, _ = limiter.allow("user:b")
+	assert.True(t, allowed)
+
+	now = now.Add(time.Minute)
+	allowed, _ = limiter.allow("user:a")
+	assert.True(t, allowed, "budget should reset in the next window")
+}
+
+func TestRateLimit_KeysByUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	router := gin.New()
+	router.Use(RateLimit(1, time.Minute, logger))
+	router.GET("/lookup", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/lookup", nil)
+		req = req.WithContext(appContext.WithUserOnly(req.Context(), userID))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send("user-1").Code)
+
+	w := send("user-1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, send("user-2").Code)
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ready middleware rejects requests with 503 and Retry-After until ready reports true,
+// so load balancers and SDKs back off while a new instance is still warming up
+func Ready(ready func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !ready() {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is starting"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady_RejectsUntilReady(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ready := false
+
+	router := gin.New()
+	router.Use(Ready(func() bool { return ready }))
+	router.GET("/sdk/evaluate", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sdk/evaluate", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	ready = true
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sdk/evaluate", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
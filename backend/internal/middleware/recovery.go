@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+// ErrorEvent describes an unexpected failure, tagged with the request it happened in
+type ErrorEvent struct {
+	Err       error
+	Stack     []byte
+	RequestID string
+	TenantID  string
+	UserID    string
+	Method    string
+	Path      string
+}
+
+// ErrorReporter sends unexpected failures to an error tracking service (e.g. Sentry).
+// Implementations must not block the request for long.
+type ErrorReporter interface {
+	Report(ctx context.Context, event ErrorEvent)
+}
+
+// LogReporter reports errors to the logger; it is the default when no tracker is configured
+type LogReporter struct {
+	Logger *slog.Logger
+}
+
+func (r LogReporter) Report(ctx context.Context, event ErrorEvent) {
+	r.Logger.Error("unexpected error",
+		slog.String("error", event.Err.Error()),
+		slog.String("request_id", event.RequestID),
+		slog.String("tenant_id", event.TenantID),
+		slog.String("user_id", event.UserID),
+		slog.String("method", event.Method),
+		slog.String("path", event.Path),
+		slog.String("stack", string(event.Stack)),
+	)
+}
+
+// Recovery middleware turns panics in later handlers into a structured 500 response
+// and reports them, with their stack trace and request/tenant tags, to reporter
+func Recovery(reporter ErrorReporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// The client went away; net/http expects this panic to propagate
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+			err = fmt.Errorf("panic: %w", err)
+
+			ctx := c.Request.Context()
+			event := ErrorEvent{
+				Err:       err,
+				Stack:     debug.Stack(),
+				RequestID: appContext.RequestID(ctx),
+				Method:    c.Request.Method,
+				Path:      c.FullPath(),
+			}
+			if tenantID, err := appContext.TenantID(ctx); err == nil {
+				event.TenantID = tenantID
+			}
+			if userID, err := appContext.UserID(ctx); err == nil {
+				event.UserID = userID
+			}
+			if event.Path == "" {
+				event.Path = c.Request.URL.Path
+			}
+
+			// Report with a context that outlives a cancelled or timed-out request
+			reporter.Report(context.WithoutCancel(ctx), event)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":      "internal server error",
+				"request_id": event.RequestID,
+			})
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+type captureReporter struct {
+	events []ErrorEvent
+}
+
+func (r *captureReporter) Report(ctx context.Context, event ErrorEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestRecovery_ReportsPanicWithTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reporter := &captureReporter{}
+
+	router := gin.New()
+	router.Use(RequestID(), Recovery(reporter))
+	router.GET("/flags/:id", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(appContext.WithAuth(c.Request.Context(), "user-1", "tenant-1", "admin"))
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/flags/123", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "internal server error", body["error"])
+	assert.Equal(t, "req-42", body["request_id"])
+
+	require.Len(t, reporter.events, 1)
+	event := reporter.events[0]
+	assert.EqualError(t, event.Err, "panic: boom")
+	assert.Equal(t, "req-42", event.RequestID)
+	assert.Equal(t, "tenant-1", event.TenantID)
+	assert.Equal(t, "user-1", event.UserID)
+	assert.Equal(t, "/flags/:id", event.Path)
+	assert.NotEmpty(t, event.Stack)
+}
+
+func TestRecovery_MissingTenantContextPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reporter := &captureReporter{}
+
+	router := gin.New()
+	router.Use(RequestID(), Recovery(reporter))
+	router.GET("/flags", func(c *gin.Context) {
+		appContext.MustTenantID(c.Request.Context())
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flags", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotEmpty(t, w.Header().Get(RequestIDHeader), "request ID should be generated")
+	require.Len(t, reporter.events, 1)
+	assert.Empty(t, reporter.events[0].TenantID)
+}
+
+func TestTimeout_Returns504WhenDeadlinePasses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Timeout(10 * time.Millisecond))
+	router.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	router.GET("/fast", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
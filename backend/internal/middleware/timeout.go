@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request time budgets applied in routes
+const (
+	DefaultRequestTimeout = 30 * time.Second
+	SDKRequestTimeout     = 5 * time.Second
+)
+
+// Timeout middleware bounds how long later handlers may work on a request. The request
+// context gets a deadline, so database calls made with it are cancelled when it passes.
+// If the deadline passed and nothing was written yet, the client receives a 504.
+// Nested timeouts can only shorten the budget, so routes can tighten a group's default.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "request timed out"})
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package batch runs the flush loops of the in-memory trackers that keep database writes
+// off the request path, and lets the server wait for their final flushes on shutdown.
+package batch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FinalFlushTimeout bounds the flush a loop runs after its context is cancelled
+const FinalFlushTimeout = 5 * time.Second
+
+// Run calls flush every interval until ctx is cancelled, then flushes once more. Flush
+// errors are left to flush to log: tracked writes are best-effort.
+func Run(ctx context.Context, interval time.Duration, flush func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = flush(ctx)
+		case <-ctx.Done():
+			// Use a fresh context so the final flush isn't cancelled with the loop
+			flushCtx, cancel := context.WithTimeout(context.Background(), FinalFlushTimeout)
+			_ = flush(flushCtx)
+			cancel()
+			return
+		}
+	}
+}
+
+// Group runs flush loops in the background and waits for them to drain
+type Group struct {
+	wg sync.WaitGroup
+}
+
+// Go runs a loop, such as a tracker's Run, in its own goroutine until ctx is cancelled
+func (g *Group) Go(ctx context.Context, run func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		run(ctx)
+	}()
+}
+
+// Wait blocks until every loop has stopped after ctx cancellation and run its final flush
+func (g *Group) Wait() {
+	g.wg.Wait()
+}
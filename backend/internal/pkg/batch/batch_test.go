@@ -0,0 +1,55 @@
+package batch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingFlush counts flushes and the context errors they saw
+type recordingFlush struct {
+	mu      sync.Mutex
+	flushes int
+	ctxErrs []error
+}
+
+func (r *recordingFlush) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushes++
+	r.ctxErrs = append(r.ctxErrs, ctx.Err())
+	return nil
+}
+
+func (r *recordingFlush) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.flushes
+}
+
+func TestRun_FlushesEveryInterval(t *testing.T) {
+	flush := &recordingFlush{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go Run(ctx, time.Millisecond, flush.Flush)
+
+	assert.Eventually(t, func() bool { return flush.count() >= 2 }, time.Second, time.Millisecond)
+}
+
+func TestGroup_WaitRunsFinalFlushWithLiveContext(t *testing.T) {
+	flush := &recordingFlush{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var group Group
+	group.Go(ctx, func(ctx context.Context) { Run(ctx, time.Hour, flush.Flush) })
+	cancel()
+	group.Wait()
+
+	require.Equal(t, 1, flush.count())
+	assert.NoError(t, flush.ctxErrs[0], "final flush should not see the cancelled loop context")
+}
@@ -8,10 +8,13 @@ import (
 type contextKey string
 
 const (
-	tenantIDKey  contextKey = "tenant_id"
-	userRoleKey  contextKey = "user_role"
-	userIDKey    contextKey = "user_id"
-	projectIDKey contextKey = "project_id"
+	tenantIDKey      contextKey = "tenant_id"
+	userRoleKey      contextKey = "user_role"
+	userIDKey        contextKey = "user_id"
+	projectIDKey     contextKey = "project_id"
+	environmentIDKey contextKey = "environment_id"
+	clientSideKey    contextKey = "client_side"
+	requestIDKey     contextKey = "request_id"
 )
 
 var (
@@ -126,3 +129,37 @@ func MustProjectID(ctx context.Context) string {
 	}
 	return projectID
 }
+
+// WithEnvironment adds the environment of an SDK request authenticated with an environment key
+func WithEnvironment(ctx context.Context, environmentID string) context.Context {
+	return context.WithValue(ctx, environmentIDKey, environmentID)
+}
+
+// EnvironmentID extracts the SDK request's environment, or "" if it used the project key
+func EnvironmentID(ctx context.Context) string {
+	environmentID, _ := ctx.Value(environmentIDKey).(string)
+	return environmentID
+}
+
+// WithRequestID adds the request's correlation ID to the context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID extracts the request's correlation ID, or "" if none was assigned
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithClientSideKey marks an SDK request as authenticated with a project's public
+// client-side key, which may evaluate flags but not read their rules
+func WithClientSideKey(ctx context.Context) context.Context {
+	return context.WithValue(ctx, clientSideKey, true)
+}
+
+// IsClientSideKey reports whether the SDK request used a client-side key
+func IsClientSideKey(ctx context.Context) bool {
+	clientSide, _ := ctx.Value(clientSideKey).(bool)
+	return clientSide
+}
@@ -0,0 +1,244 @@
+// Package degrade sheds non-critical work when an instance is overloaded, so that
+// flag evaluation and management CRUD keep their latency under pressure.
+//
+// A Controller watches two signals: the latency of requests served by the instance and
+// the latency of a database ping. When either stays above its threshold for a sustained
+// number of checks, the controller raises its level by one, shedding the next feature in
+// ShedOrder. It steps back down one level at a time after a longer healthy stretch.
+// Only features listed here are ever shed; critical paths never consult the controller.
+package degrade
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Feature is a non-critical piece of work that can be shed under pressure
+type Feature string
+
+const (
+	FeatureDebugLogging Feature = "debug_logging"    // per-flag evaluation debug logs
+	FeatureAnalytics    Feature = "analytics_writes" // flag usage, result, attribute and SDK version tracking
+	FeatureCatalogPush  Feature = "catalog_push"     // outbound flag catalog webhooks
+)
+
+// ShedOrder lists features in the order they are shed; level n sheds the first n
+var ShedOrder = []Feature{FeatureDebugLogging, FeatureAnalytics, FeatureCatalogPush}
+
+// Gate reports whether non-critical work may run (implemented by Controller)
+type Gate interface {
+	Allow(f Feature) bool
+}
+
+// Pinger checks database connectivity (implemented by *sqlx.DB)
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Thresholds configure when the controller degrades and recovers
+type Thresholds struct {
+	RequestLatency time.Duration // smoothed request latency considered overloaded
+	DBLatency      time.Duration // database ping latency considered overloaded
+	Sustain        int           // consecutive overloaded checks before shedding another feature
+	Recover        int           // consecutive healthy checks before restoring one
+}
+
+// DefaultThresholds shed work after 30s of pressure and restore it after a minute of health
+var DefaultThresholds = Thresholds{
+	RequestLatency: 2 * time.Second,
+	DBLatency:      250 * time.Millisecond,
+	Sustain:        3,
+	Recover:        6,
+}
+
+// CheckInterval is how often the controller samples the database and re-evaluates its level
+const CheckInterval = 10 * time.Second
+
+// requestLatencyWeight is the weight of each new request in the smoothed latency
+const requestLatencyWeight = 0.05
+
+// State is the controller's current level and the signals behind it
+type State struct {
+	Level            int               `json:"level"`
+	Degraded         bool              `json:"degraded"`
+	Shed             []Feature         `json:"shed"`
+	Since            time.Time         `json:"since"` // when the current level was entered
+	RequestLatencyMS float64           `json:"request_latency_ms"`
+	DBLatencyMS      float64           `json:"db_latency_ms"`
+	ShedCounts       map[Feature]int64 `json:"shed_counts"` // work skipped per feature since startup
+}
+
+// Controller tracks load signals and decides which non-critical features may run
+type Controller struct {
+	db         Pinger
+	thresholds Thresholds
+	logger     *slog.Logger
+
+	level atomic.Int32
+
+	mu             sync.Mutex
+	requestLatency float64 // smoothed, in nanoseconds
+	dbLatency      time.Duration
+	overloaded     int // consecutive overloaded checks
+	healthy        int // consecutive healthy checks
+	since          time.Time
+	shedCounts     map[Feature]*atomic.Int64
+}
+
+func NewController(db Pinger, thresholds Thresholds, logger *slog.Logger) *Controller {
+	shedCounts := make(map[Feature]*atomic.Int64, len(ShedOrder))
+	for _, f := range ShedOrder {
+		shedCounts[f] = &atomic.Int64{}
+	}
+	return &Controller{
+		db:         db,
+		thresholds: thresholds,
+		logger:     logger,
+		since:      time.Now(),
+		shedCounts: shedCounts,
+	}
+}
+
+// Allow reports whether f may run at the current level; skipped work is counted
+func (c *Controller) Allow(f Feature) bool {
+	level := int(c.level.Load())
+	for i := 0; i < level && i < len(ShedOrder); i++ {
+		if ShedOrder[i] == f {
+			if count, ok := c.shedCounts[f]; ok {
+				count.Add(1)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// Guard wraps background work so it is skipped while f is shed
+func (c *Controller) Guard(f Feature, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if !c.Allow(f) {
+			return nil
+		}
+		return run(ctx)
+	}
+}
+
+// Middleware feeds request latency into the controller
+func (c *Controller) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		c.ObserveRequest(time.Since(start))
+	}
+}
+
+// ObserveRequest adds one request's latency to the smoothed request latency
+func (c *Controller) ObserveRequest(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.requestLatency == 0 {
+		c.requestLatency = float64(d)
+		return
+	}
+	c.requestLatency += requestLatencyWeight * (float64(d) - c.requestLatency)
+}
+
+// Check pings the database and moves the level up or down one step when pressure
+// has been sustained (or absent) for long enough
+func (c *Controller) Check(ctx context.Context) {
+	start := time.Now()
+	err := c.db.PingContext(ctx)
+	dbLatency := time.Since(start)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dbLatency = dbLatency
+	overloaded := err != nil ||
+		dbLatency > c.thresholds.DBLatency ||
+		time.Duration(c.requestLatency) > c.thresholds.RequestLatency
+
+	level := int(c.level.Load())
+	if overloaded {
+		c.overloaded++
+		c.healthy = 0
+		if c.overloaded >= c.thresholds.Sustain && level < len(ShedOrder) {
+			c.setLevel(level+1, dbLatency, err)
+		}
+		return
+	}
+
+	c.healthy++
+	c.overloaded = 0
+	if c.healthy >= c.thresholds.Recover && level > 0 {
+		c.setLevel(level-1, dbLatency, nil)
+	}
+}
+
+// setLevel changes the level and restarts the sustain/recover counts; c.mu must be held
+func (c *Controller) setLevel(level int, dbLatency time.Duration, err error) {
+	previous := int(c.level.Swap(int32(level)))
+	c.overloaded = 0
+	c.healthy = 0
+	c.since = time.Now()
+
+	attrs := []any{
+		slog.Int("level", level),
+		slog.Int("previous_level", previous),
+		slog.Any("shed", ShedOrder[:level]),
+		slog.Duration("request_latency", time.Duration(c.requestLatency)),
+		slog.Duration("db_latency", dbLatency),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("db_error", err.Error()))
+	}
+	if level > previous {
+		c.logger.Warn("degrading: shedding non-critical work", attrs...)
+	} else {
+		c.logger.Info("recovering: restoring non-critical work", attrs...)
+	}
+}
+
+// State returns the current level, signals and shed counts
+func (c *Controller) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	level := int(c.level.Load())
+	shedCounts := make(map[Feature]int64, len(c.shedCounts))
+	for f, count := range c.shedCounts {
+		shedCounts[f] = count.Load()
+	}
+	return State{
+		Level:            level,
+		Degraded:         level > 0,
+		Shed:             append([]Feature{}, ShedOrder[:level]...),
+		Since:            c.since,
+		RequestLatencyMS: c.requestLatency / float64(time.Millisecond),
+		DBLatencyMS:      float64(c.dbLatency) / float64(time.Millisecond),
+		ShedCounts:       shedCounts,
+	}
+}
+
+// Run checks every interval until ctx is cancelled
+func (c *Controller) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			c.Check(checkCtx)
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package degrade
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type mockPinger struct {
+	err   error
+	delay time.Duration
+}
+
+func (m *mockPinger) PingContext(ctx context.Context) error {
+	time.Sleep(m.delay)
+	return m.err
+}
+
+func newTestController(db *mockPinger) *Controller {
+	return NewController(db, Thresholds{
+		RequestLatency: 100 * time.Millisecond,
+		DBLatency:      50 * time.Millisecond,
+		Sustain:        2,
+		Recover:        3,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestController_ShedsInOrderUnderSustainedPressure(t *testing.T) {
+	db := &mockPinger{err: errors.New("connection refused")}
+	c := newTestController(db)
+	ctx := context.Background()
+
+	c.Check(ctx)
+	if c.State().Level != 0 {
+		t.Fatal("expected a single overloaded check not to degrade")
+	}
+
+	c.Check(ctx)
+	if !c.State().Degraded || c.Allow(FeatureDebugLogging) || !c.Allow(FeatureAnalytics) {
+		t.Fatalf("expected only debug logging shed at level 1, got %+v", c.State())
+	}
+
+	for i := 0; i < 10; i++ {
+		c.Check(ctx)
+	}
+	state := c.State()
+	if state.Level != len(ShedOrder) {
+		t.Fatalf("expected the level to stop at %d, got %d", len(ShedOrder), state.Level)
+	}
+	for _, f := range ShedOrder {
+		if c.Allow(f) {
+			t.Errorf("expected %s to be shed", f)
+		}
+	}
+	if counts := c.State().ShedCounts; counts[FeatureDebugLogging] != 2 {
+		t.Errorf("expected skipped debug logging to be counted, got %v", counts)
+	}
+}
+
+func TestController_RecoversOneLevelAtATime(t *testing.T) {
+	db := &mockPinger{err: errors.New("connection refused")}
+	c := newTestController(db)
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		c.Check(ctx)
+	}
+	if c.State().Level != 2 {
+		t.Fatalf("expected level 2, got %d", c.State().Level)
+	}
+
+	db.err = nil
+	for i := 0; i < 2; i++ {
+		c.Check(ctx)
+	}
+	if c.State().Level != 2 {
+		t.Fatal("expected the level to hold until recovery is sustained")
+	}
+	c.Check(ctx)
+	if c.State().Level != 1 {
+		t.Fatalf("expected level 1 after recovering, got %d", c.State().Level)
+	}
+	for i := 0; i < 3; i++ {
+		c.Check(ctx)
+	}
+	if c.State().Degraded {
+		t.Errorf("expected full recovery, got %+v", c.State())
+	}
+}
+
+func TestController_RequestLatencyCountsAsPressure(t *testing.T) {
+	c := newTestController(&mockPinger{})
+
+	for i := 0; i < 100; i++ {
+		c.ObserveRequest(time.Second)
+	}
+	c.Check(context.Background())
+	c.Check(context.Background())
+
+	if !c.State().Degraded {
+		t.Errorf("expected slow requests to degrade, got %+v", c.State())
+	}
+}
+
+func TestController_GuardSkipsShedWork(t *testing.T) {
+	c := newTestController(&mockPinger{err: errors.New("down")})
+	for i := 0; i < 6; i++ {
+		c.Check(context.Background())
+	}
+
+	ran := false
+	run := c.Guard(FeatureCatalogPush, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	if err := run(context.Background()); err != nil || ran {
+		t.Errorf("expected shed work to be skipped, ran=%t err=%v", ran, err)
+	}
+}
@@ -0,0 +1,155 @@
+// Package dualwrite coordinates online column migrations across multiple running replicas.
+//
+// A migration moves data from a legacy source (an old column, or a value derived at read
+// time) to a new column in four phases, configured per replica:
+//
+//	off        -> read and write the legacy source only
+//	dual_write -> write both, read legacy
+//	dual_read  -> write both, read new and fall back to legacy when the new value is missing
+//	cutover    -> read and write the new column only
+//
+// Adjacent phases are compatible, so replicas can be rolled forward one phase at a time.
+// While writes go to the new column, a verifier job backfills rows written before the
+// rollout and counts the rows still missing. Cutover is gated: a replica configured for
+// cutover keeps dual-read behaviour until its verifier has seen zero pending rows.
+package dualwrite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Phase is a step in an online column migration
+type Phase string
+
+const (
+	PhaseOff       Phase = "off"
+	PhaseDualWrite Phase = "dual_write"
+	PhaseDualRead  Phase = "dual_read"
+	PhaseCutover   Phase = "cutover"
+)
+
+// DefaultBackfillBatch is how many rows the verifier backfills per run
+const DefaultBackfillBatch = 500
+
+// ParsePhase parses a configured phase; empty means PhaseOff
+func ParsePhase(s string) (Phase, error) {
+	switch Phase(s) {
+	case "":
+		return PhaseOff, nil
+	case PhaseOff, PhaseDualWrite, PhaseDualRead, PhaseCutover:
+		return Phase(s), nil
+	default:
+		return "", fmt.Errorf("unknown migration phase %q", s)
+	}
+}
+
+// WritesNew reports whether writes must populate the new column
+func (p Phase) WritesNew() bool {
+	return p != PhaseOff
+}
+
+// WritesLegacy reports whether writes must keep the legacy source up to date
+func (p Phase) WritesLegacy() bool {
+	return p != PhaseCutover
+}
+
+// ReadsNew reports whether reads prefer the new column
+func (p Phase) ReadsNew() bool {
+	return p == PhaseDualRead || p == PhaseCutover
+}
+
+// FallsBack reports whether reads fall back to the legacy source when the new value is missing
+func (p Phase) FallsBack() bool {
+	return p != PhaseCutover
+}
+
+// Store backfills and counts rows for one migration
+type Store interface {
+	// Backfill populates the new column for up to limit rows still missing it
+	Backfill(ctx context.Context, limit int) (int64, error)
+	// Pending counts rows whose new column is still missing
+	Pending(ctx context.Context) (int64, error)
+}
+
+// Migration tracks the configured and effective phase of one column migration
+type Migration struct {
+	name       string
+	configured Phase
+	verified   atomic.Bool
+	logger     *slog.Logger
+}
+
+func NewMigration(name string, phase Phase, logger *slog.Logger) *Migration {
+	return &Migration{
+		name:       name,
+		configured: phase,
+		logger:     logger,
+	}
+}
+
+// Name returns the migration's name
+func (m *Migration) Name() string {
+	return m.name
+}
+
+// Phase returns the phase repositories should follow. A configured cutover only takes
+// effect once verification has passed; until then the migration stays in dual-read.
+func (m *Migration) Phase() Phase {
+	if m == nil {
+		return PhaseOff
+	}
+	if m.configured == PhaseCutover && !m.verified.Load() {
+		return PhaseDualRead
+	}
+	return m.configured
+}
+
+// Verify runs one backfill batch and checks whether any rows are still pending.
+// It does nothing while the migration is off, since nothing writes the new column yet.
+func (m *Migration) Verify(ctx context.Context, store Store) error {
+	if !m.configured.WritesNew() {
+		return nil
+	}
+
+	backfilled, err := store.Backfill(ctx, DefaultBackfillBatch)
+	if err != nil {
+		return fmt.Errorf("failed to backfill %s: %w", m.name, err)
+	}
+
+	pending, err := store.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count pending rows for %s: %w", m.name, err)
+	}
+
+	if backfilled > 0 || pending > 0 {
+		m.logger.Info("migration backfill progress",
+			slog.String("migration", m.name),
+			slog.Int64("backfilled", backfilled),
+			slog.Int64("pending", pending),
+		)
+	}
+
+	if pending > 0 {
+		return nil
+	}
+
+	if !m.verified.Swap(true) {
+		m.logger.Info("migration verified",
+			slog.String("migration", m.name),
+			slog.String("phase", string(m.configured)),
+			slog.Bool("cutover", m.configured == PhaseCutover),
+		)
+	}
+
+	return nil
+}
+
+// Verifier returns a scheduler job function that verifies the migration against store
+func (m *Migration) Verifier(store Store) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return m.Verify(ctx, store)
+	}
+}
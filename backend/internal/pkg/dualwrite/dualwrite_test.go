@@ -0,0 +1,104 @@
+package dualwrite
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+type mockStore struct {
+	pending int64
+}
+
+func (m *mockStore) Backfill(ctx context.Context, limit int) (int64, error) {
+	n := m.pending
+	if n > int64(limit) {
+		n = int64(limit)
+	}
+	m.pending -= n
+	return n, nil
+}
+
+func (m *mockStore) Pending(ctx context.Context) (int64, error) {
+	return m.pending, nil
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestParsePhase(t *testing.T) {
+	tests := map[string]Phase{
+		"":           PhaseOff,
+		"off":        PhaseOff,
+		"dual_write": PhaseDualWrite,
+		"dual_read":  PhaseDualRead,
+		"cutover":    PhaseCutover,
+	}
+	for input, want := range tests {
+		got, err := ParsePhase(input)
+		if err != nil {
+			t.Errorf("ParsePhase(%q): unexpected error %v", input, err)
+		}
+		if got != want {
+			t.Errorf("ParsePhase(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	if _, err := ParsePhase("dual-write"); err == nil {
+		t.Error("expected error for unknown phase")
+	}
+}
+
+func TestPhase_AdjacentPhasesAreCompatible(t *testing.T) {
+	phases := []Phase{PhaseOff, PhaseDualWrite, PhaseDualRead, PhaseCutover}
+	for i := 0; i < len(phases)-1; i++ {
+		older, newer := phases[i], phases[i+1]
+
+		// Whatever the newer phase reads must still be written by the older one
+		if newer.ReadsNew() && !newer.FallsBack() && !older.WritesNew() {
+			t.Errorf("%s reads only the new column but %s does not write it", newer, older)
+		}
+		// Replicas still on the older phase must be able to read what the newer one writes
+		if !older.ReadsNew() && !newer.WritesLegacy() {
+			t.Errorf("%s reads only legacy but %s does not write it", older, newer)
+		}
+	}
+}
+
+func TestMigration_CutoverWaitsForVerification(t *testing.T) {
+	m := NewMigration("flag-key", PhaseCutover, discardLogger())
+	store := &mockStore{pending: DefaultBackfillBatch + 10}
+
+	if got := m.Phase(); got != PhaseDualRead {
+		t.Fatalf("expected dual_read before verification, got %s", got)
+	}
+
+	// First run backfills a batch but rows remain
+	if err := m.Verify(context.Background(), store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := m.Phase(); got != PhaseDualRead {
+		t.Errorf("expected dual_read while rows are pending, got %s", got)
+	}
+
+	if err := m.Verify(context.Background(), store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := m.Phase(); got != PhaseCutover {
+		t.Errorf("expected cutover after verification, got %s", got)
+	}
+}
+
+func TestMigration_OffDoesNotBackfill(t *testing.T) {
+	m := NewMigration("flag-key", PhaseOff, discardLogger())
+	store := &mockStore{pending: 3}
+
+	if err := m.Verify(context.Background(), store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.pending != 3 {
+		t.Errorf("expected no backfill while off, %d rows pending", store.pending)
+	}
+}
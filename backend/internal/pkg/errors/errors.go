@@ -21,6 +21,9 @@ var (
 	// ErrProjectNotInTenant indicates a project does not belong to the specified tenant
 	// This is an internal error that should be mapped to ErrNotFound in handlers
 	ErrProjectNotInTenant = errors.New("project does not belong to tenant")
+
+	// ErrUserNotInTenant indicates a referenced user is not a member of the specified tenant
+	ErrUserNotInTenant = errors.New("user is not a member of tenant")
 )
 
 // IsNotFoundError checks if an error should be returned as a 404 Not Found response
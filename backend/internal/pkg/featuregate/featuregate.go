@@ -0,0 +1,58 @@
+// Package featuregate declares the previews of Toggle's own subsystems that tenants can
+// enroll in, so risky features can be rolled out gradually before they are on for everyone.
+//
+// Previews are declared here in code; tenant enrollments are stored by the previews
+// package, which implements Checker. Code behind a preview asks a Checker before running.
+package featuregate
+
+import "context"
+
+// Preview stages, from least to most mature
+const (
+	StageAlpha = "alpha" // may change or be withdrawn without notice
+	StageBeta  = "beta"  // feature complete, still being hardened
+)
+
+// Preview keys
+const (
+	// PayloadTemplates lets catalog push targets render their payload with a custom template
+	PayloadTemplates = "payload-templates"
+)
+
+// Preview is a feature of Toggle itself that tenants opt in to
+type Preview struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Stage       string `json:"stage"`
+}
+
+// previews are every preview currently offered, in the order they are listed
+var previews = []Preview{
+	{
+		Key:         PayloadTemplates,
+		Name:        "Catalog push payload templates",
+		Description: "Render the catalog push payload with your own template instead of the Backstage feed.",
+		Stage:       StageBeta,
+	},
+}
+
+// Previews returns every preview currently offered
+func Previews() []Preview {
+	return append([]Preview(nil), previews...)
+}
+
+// Lookup returns the preview with the given key
+func Lookup(key string) (Preview, bool) {
+	for _, p := range previews {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return Preview{}, false
+}
+
+// Checker reports whether a tenant is enrolled in a preview
+type Checker interface {
+	Enabled(ctx context.Context, tenantID string, key string) bool
+}
@@ -0,0 +1,77 @@
+// Package grpcjson serves and calls gRPC methods whose messages are Go structs encoded as
+// JSON, so gRPC services can share the request and response types of the REST API
+// instead of generating protobuf messages.
+//
+// Messages use the "json" content subtype (application/grpc+json). Clients made with
+// Invoke request it on every call; other gRPC clients must set the content subtype
+// themselves.
+package grpcjson
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+// Name is the content subtype of JSON-encoded messages
+const Name = "json"
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+// Codec encodes gRPC messages as JSON
+type Codec struct{}
+
+func (Codec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (Codec) Name() string {
+	return Name
+}
+
+// Method describes the unary method name of service, served by call on the server
+// registered for the service. Requests are checked against their binding tags like
+// REST request bodies, and answer InvalidArgument when they don't pass.
+func Method[S, Req, Resp any](service, name string, call func(S, context.Context, *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+			}
+			if err := binding.Validator.ValidateStruct(req); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+
+			handler := func(ctx context.Context, req any) (any, error) {
+				return call(srv.(S), ctx, req.(*Req))
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + service + "/" + name}, handler)
+		},
+	}
+}
+
+// Invoke calls the unary method name of service with JSON-encoded messages
+func Invoke[Req, Resp any](ctx context.Context, cc grpc.ClientConnInterface, service, name string, req *Req, opts ...grpc.CallOption) (*Resp, error) {
+	resp := new(Resp)
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(Name)}, opts...)
+	if err := cc.Invoke(ctx, "/"+service+"/"+name, req, resp, opts...); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
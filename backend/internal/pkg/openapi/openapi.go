@@ -0,0 +1,376 @@
+// Package openapi builds the API's OpenAPI 3 document from operations declared in Go.
+//
+// Each handler package declares its operations next to its routes, naming the structs it
+// binds and returns; request and response schemas are reflected from those structs' json
+// tags. The document is generated from the same types the handlers use, so it can't drift
+// from them, and a routes test checks every route is declared.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.0.3"
+
+// Operation is one route as a handler package declares it
+type Operation struct {
+	Method string
+	// Path is the gin path relative to the group the handler registers on, e.g. /flags/:id
+	Path    string
+	Summary string
+	Query   []Param
+	// Request is a value of the JSON body's type; nil when the operation takes no body
+	Request any
+	// Response is a value of the success body's type; nil when the success has no body
+	Response any
+	// Status is the success status; zero means 200
+	Status int
+}
+
+// Param is a query parameter; parameters are strings as far as the document is concerned
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Security is what a group's operations authenticate with
+type Security int
+
+const (
+	// SecurityUser is a user's bearer token
+	SecurityUser Security = iota
+	// SecurityTenant is a user's bearer token and the X-Tenant-ID header
+	SecurityTenant
+	// SecuritySDK is a project or environment API key
+	SecuritySDK
+)
+
+// Security scheme names in generated documents
+const (
+	schemeUser   = "user"
+	schemeTenant = "tenant"
+	schemeAPIKey = "apiKey"
+)
+
+// Group is a set of operations served under one path prefix
+type Group struct {
+	Prefix     string
+	Tag        string
+	Security   Security
+	Operations []Operation
+}
+
+// Document is an OpenAPI 3 document, reduced to the parts generated documents use
+type Document struct {
+	OpenAPI    string                        `json:"openapi"`
+	Info       Info                          `json:"info"`
+	Paths      map[string]map[string]*PathOp `json:"paths"`
+	Components Components                    `json:"components"`
+	Tags       []Tag                         `json:"tags,omitempty"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathOp is an operation of a path item in a Document
+type PathOp struct {
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema as OpenAPI 3.0 extends it. The zero Schema allows any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	Responses       map[string]Response       `json:"responses"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// errorResponse is the component every operation's default response refers to
+const errorResponse = "Error"
+
+// New builds the document of the groups' operations
+func New(title, version string, groups ...Group) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]map[string]*PathOp{},
+		Components: Components{
+			Schemas: map[string]*Schema{
+				errorResponse: {Type: "object", Properties: map[string]*Schema{"error": {Type: "string"}}, Required: []string{"error"}},
+			},
+			Responses: map[string]Response{
+				errorResponse: {Description: "Error", Content: jsonContent(&Schema{Ref: schemaRef(errorResponse)})},
+			},
+			SecuritySchemes: map[string]SecurityScheme{
+				schemeUser:   {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "A user's access token"},
+				schemeTenant: {Type: "apiKey", In: "header", Name: "X-Tenant-ID", Description: "The organization the request acts on"},
+				schemeAPIKey: {Type: "http", Scheme: "bearer", Description: "A project or environment API key"},
+			},
+		},
+	}
+
+	for _, g := range groups {
+		if g.Tag != "" && !slices.Contains(doc.Tags, Tag{Name: g.Tag}) {
+			doc.Tags = append(doc.Tags, Tag{Name: g.Tag})
+		}
+		for _, op := range g.Operations {
+			doc.add(g, op)
+		}
+	}
+	return doc
+}
+
+// Has reports whether the document declares the operation, given a gin method and full path
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[Path(path)][strings.ToLower(method)]
+	return ok
+}
+
+// Handler serves the document as JSON
+func (d *Document) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, d)
+	}
+}
+
+func (d *Document) add(g Group, op Operation) {
+	path := Path(g.Prefix + op.Path)
+	out := &PathOp{Summary: op.Summary, Responses: map[string]Response{}}
+	if g.Tag != "" {
+		out.Tags = []string{g.Tag}
+	}
+
+	for _, name := range pathParams(op.Path) {
+		out.Parameters = append(out.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, p := range op.Query {
+		out.Parameters = append(out.Parameters, Parameter{Name: p.Name, In: "query", Description: p.Description, Required: p.Required, Schema: &Schema{Type: "string"}})
+	}
+
+	if op.Request != nil {
+		out.RequestBody = &RequestBody{Required: true, Content: jsonContent(d.schema(reflect.TypeOf(op.Request)))}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	if op.Response != nil {
+		success.Content = jsonContent(d.schema(reflect.TypeOf(op.Response)))
+	}
+	out.Responses[strconv.Itoa(status)] = success
+	out.Responses["default"] = Response{Ref: "#/components/responses/" + errorResponse}
+
+	switch g.Security {
+	case SecurityUser:
+		out.Security = []map[string][]string{{schemeUser: {}}}
+	case SecurityTenant:
+		out.Security = []map[string][]string{{schemeUser: {}, schemeTenant: {}}}
+	case SecuritySDK:
+		out.Security = []map[string][]string{{schemeAPIKey: {}}}
+	}
+
+	if d.Paths[path] == nil {
+		d.Paths[path] = map[string]*PathOp{}
+	}
+	d.Paths[path][strings.ToLower(op.Method)] = out
+}
+
+// Path converts a gin path to an OpenAPI path, e.g. /flags/:id to /flags/{id}
+func Path(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// pathParams returns the names of a gin path's parameters, in order
+func pathParams(ginPath string) []string {
+	var names []string
+	for _, s := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			names = append(names, s[1:])
+		}
+	}
+	return names
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+func schemaRef(name string) string {
+	return "#/components/schemas/" + name
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+	marshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schema returns the schema of values of t as encoding/json writes them. Named structs
+// become components, referenced by their qualified Go name such as flag.Flag.
+func (d *Document) schema(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	case t == rawMessageType, t.Implements(marshalerType), reflect.PointerTo(t).Implements(marshalerType):
+		// Custom JSON encodings could be anything
+		return &Schema{}
+	case t.Implements(textMarshalType), reflect.PointerTo(t).Implements(textMarshalType):
+		return &Schema{Type: "string", Nullable: nullable}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Nullable: nullable}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Nullable: nullable}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Nullable: nullable}
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: nullable}
+		}
+		// encoding/json writes nil slices as null
+		return &Schema{Type: "array", Items: d.schema(t.Elem()), Nullable: t.Kind() == reflect.Slice || nullable}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.object(t)
+		}
+		name := t.String()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Registered before its fields so recursive types refer to themselves
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.object(t)
+		}
+		return &Schema{Ref: schemaRef(name)}
+	default:
+		return &Schema{}
+	}
+}
+
+// object returns the schema of a struct's JSON fields, with embedded structs' fields inlined
+func (d *Document) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded := d.object(ft)
+			for n, p := range embedded.Properties {
+				s.Properties[n] = p
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = d.schema(field.Type)
+		if required(field) {
+			s.Required = append(s.Required, name)
+		}
+	}
+	slices.Sort(s.Required)
+	return s
+}
+
+// required reports whether gin binding requires the field
+func required(field reflect.StructField) bool {
+	return slices.Contains(strings.Split(field.Tag.Get("binding"), ","), "required")
+}
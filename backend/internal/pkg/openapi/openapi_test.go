@@ -0,0 +1,87 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type base struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type node struct {
+	base
+	Name     string                 `json:"name" binding:"required,max=255"`
+	Parent   *node                  `json:"parent,omitempty"`
+	Tags     []string               `json:"tags"`
+	Counts   map[string]int64       `json:"counts"`
+	Value    interface{}            `json:"value"`
+	Raw      json.RawMessage        `json:"raw"`
+	Enabled  *bool                  `json:"enabled" binding:"required"`
+	Duration time.Duration          `json:"-"`
+	Extra    map[string]interface{} `json:"extra,omitempty"`
+}
+
+func TestNew_ReflectsSchemasFromJSONTags(t *testing.T) {
+	doc := New("Test", "1", Group{Prefix: "/api", Tag: "nodes", Operations: []Operation{
+		{Method: http.MethodPost, Path: "/nodes", Request: node{}, Response: &node{}, Status: http.StatusCreated},
+	}})
+
+	s := doc.Components.Schemas["openapi.node"]
+	require.NotNil(t, s)
+	assert.Equal(t, []string{"enabled", "name"}, s.Required)
+	assert.ElementsMatch(t, []string{"id", "created_at", "name", "parent", "tags", "counts", "value", "raw", "enabled", "extra"}, keys(s.Properties))
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, s.Properties["created_at"])
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/openapi.node"}, s.Properties["parent"], "recursive types refer to their component")
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}, Nullable: true}, s.Properties["tags"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int64"}, Nullable: true}, s.Properties["counts"])
+	assert.Equal(t, &Schema{}, s.Properties["value"])
+	assert.Equal(t, &Schema{}, s.Properties["raw"])
+	assert.Equal(t, &Schema{Type: "boolean", Nullable: true}, s.Properties["enabled"])
+
+	op := doc.Paths["/api/nodes"]["post"]
+	require.NotNil(t, op)
+	assert.Equal(t, []string{"nodes"}, op.Tags)
+	assert.Contains(t, op.Responses, "201")
+	assert.Contains(t, op.Responses, "default")
+	assert.Equal(t, "#/components/schemas/openapi.node", op.RequestBody.Content["application/json"].Schema.Ref)
+}
+
+func TestNew_PathParametersAndSecurity(t *testing.T) {
+	doc := New("Test", "1",
+		Group{Prefix: "/api/sdk", Security: SecuritySDK, Operations: []Operation{
+			{Method: http.MethodGet, Path: "/flags/:id/evaluate", Query: []Param{{Name: "context", Required: true}}},
+		}},
+		Group{Prefix: "/api", Security: SecurityTenant, Operations: []Operation{
+			{Method: http.MethodDelete, Path: "/flags/:id/overrides/:user_key", Status: http.StatusNoContent},
+		}},
+	)
+
+	assert.True(t, doc.Has(http.MethodGet, "/api/sdk/flags/:id/evaluate"))
+	assert.False(t, doc.Has(http.MethodPost, "/api/sdk/flags/:id/evaluate"))
+
+	get := doc.Paths["/api/sdk/flags/{id}/evaluate"]["get"]
+	require.Len(t, get.Parameters, 2)
+	assert.Equal(t, Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}, get.Parameters[0])
+	assert.Equal(t, "query", get.Parameters[1].In)
+	assert.Equal(t, []map[string][]string{{"apiKey": {}}}, get.Security)
+
+	del := doc.Paths["/api/flags/{id}/overrides/{user_key}"]["delete"]
+	assert.Equal(t, []string{"id", "user_key"}, []string{del.Parameters[0].Name, del.Parameters[1].Name})
+	assert.Nil(t, del.Responses["204"].Content)
+	assert.Equal(t, []map[string][]string{{"user": {}, "tenant": {}}}, del.Security)
+}
+
+func keys(m map[string]*Schema) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,162 @@
+// Package payloadtmpl renders tenant-supplied Go text templates into outbound webhook payloads.
+//
+// Templates are sandboxed: they only see the data they are rendered with and a small set
+// of pure functions, can't define or include other templates, can only range over data
+// (never over numbers they compute), and their output is capped at MaxOutputBytes. Together
+// these keep a hostile template from reading anything else or running unbounded.
+package payloadtmpl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// MaxTemplateLength is the longest template source accepted, in bytes
+const MaxTemplateLength = 16 * 1024
+
+// MaxOutputBytes caps a rendered payload
+const MaxOutputBytes = 1 << 20
+
+var (
+	ErrInvalidTemplate = errors.New("invalid template")
+	ErrOutputTooLarge  = errors.New("rendered payload too large")
+	// ErrRenderFailed indicates the template failed on the data, e.g. referencing a missing field
+	ErrRenderFailed = errors.New("failed to render template")
+)
+
+// IsTemplateError reports whether err is the template's fault rather than the caller's:
+// invalid source, a failed render or oversized output
+func IsTemplateError(err error) bool {
+	return errors.Is(err, ErrInvalidTemplate) || errors.Is(err, ErrRenderFailed) || errors.Is(err, ErrOutputTooLarge)
+}
+
+// funcs are the only functions templates may call besides text/template's builtins
+var funcs = template.FuncMap{
+	"json":  toJSON,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"join":  strings.Join,
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	"rfc3339": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+}
+
+// toJSON encodes a value for embedding in a JSON payload, e.g. {"name": {{json .Name}}}
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// Template is a parsed and checked payload template
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse checks a template's source against the sandbox rules.
+// Errors wrap ErrInvalidTemplate and describe the problem.
+func Parse(source string) (*Template, error) {
+	if len(source) > MaxTemplateLength {
+		return nil, fmt.Errorf("%w: must be at most %d bytes", ErrInvalidTemplate, MaxTemplateLength)
+	}
+
+	tmpl, err := template.New("payload").Funcs(funcs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err.Error())
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("%w: define and block are not allowed", ErrInvalidTemplate)
+	}
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return nil, fmt.Errorf("%w: template is empty", ErrInvalidTemplate)
+	}
+	if err := checkNode(tmpl.Tree.Root); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err.Error())
+	}
+
+	return &Template{tmpl: tmpl}, nil
+}
+
+// checkNode rejects template inclusion and ranges over anything but data
+func checkNode(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkNode(child); err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		return fmt.Errorf("including templates is not allowed")
+	case *parse.RangeNode:
+		if !rangesOverData(n.Pipe) {
+			return fmt.Errorf("range must be over a field or variable")
+		}
+		return checkBranch(&n.BranchNode)
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode)
+	}
+	return nil
+}
+
+func checkBranch(b *parse.BranchNode) error {
+	if err := checkNode(b.List); err != nil {
+		return err
+	}
+	return checkNode(b.ElseList)
+}
+
+// rangesOverData reports whether a range pipeline is a plain field, variable or dot,
+// which can only iterate over the (finite) data the template was given
+func rangesOverData(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode, *parse.VariableNode, *parse.ChainNode, *parse.DotNode:
+		return true
+	}
+	return false
+}
+
+// Render executes the template with data, failing with ErrOutputTooLarge past MaxOutputBytes
+func (t *Template) Render(data any) ([]byte, error) {
+	out := &limitedBuffer{limit: MaxOutputBytes}
+	if err := t.tmpl.Execute(out, data); err != nil {
+		if errors.Is(err, ErrOutputTooLarge) {
+			return nil, ErrOutputTooLarge
+		}
+		return nil, fmt.Errorf("%w: %s", ErrRenderFailed, err.Error())
+	}
+	return out.buf.Bytes(), nil
+}
+
+// limitedBuffer is a writer that refuses to grow past limit
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.limit {
+		return 0, ErrOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
@@ -0,0 +1,73 @@
+package payloadtmpl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flag struct {
+	Name    string
+	Enabled bool
+	Owner   string
+}
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse(`{"flags": [{{range $i, $f := .Flags}}{{if $i}}, {{end}}{"name": {{json $f.Name}}, "owner": {{json (default "unknown" $f.Owner)}}}{{end}}], "at": "{{rfc3339 .At}}"}`)
+	require.NoError(t, err)
+
+	out, err := tmpl.Render(map[string]any{
+		"Flags": []flag{{Name: `Say "hi"`, Owner: "payments"}, {Name: "Dark Mode"}},
+		"At":    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"flags": [{"name": "Say \"hi\"", "owner": "payments"}, {"name": "Dark Mode", "owner": "unknown"}], "at": "2026-01-02T03:04:05Z"}`, string(out))
+}
+
+func TestParse_RejectsUnsafeTemplates(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "syntax error", source: "{{.Name"},
+		{name: "unknown function", source: `{{exec "ls"}}`},
+		{name: "define", source: `{{define "x"}}{{template "x"}}{{end}}{{template "x"}}`},
+		{name: "block", source: `{{block "x" .}}hi{{end}}`},
+		{name: "range over a number", source: `{{range 1000000000}}{{end}}`},
+		{name: "range over a computed number", source: `{{range $f := .Flags}}{{range (len $.Flags)}}{{end}}{{end}}`},
+		{name: "too long", source: strings.Repeat("a", MaxTemplateLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			assert.ErrorIs(t, err, ErrInvalidTemplate)
+		})
+	}
+}
+
+func TestRender_CapsOutput(t *testing.T) {
+	tmpl, err := Parse(`{{range .}}{{.}}{{end}}`)
+	require.NoError(t, err)
+
+	chunk := strings.Repeat("x", 1024)
+	data := make([]string, MaxOutputBytes/len(chunk)+1)
+	for i := range data {
+		data[i] = chunk
+	}
+
+	_, err = tmpl.Render(data)
+	assert.ErrorIs(t, err, ErrOutputTooLarge)
+}
+
+func TestRender_MissingFieldFails(t *testing.T) {
+	tmpl, err := Parse(`{{.Missing}}`)
+	require.NoError(t, err)
+
+	_, err = tmpl.Render(map[string]any{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package sanitize
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Maximum stored lengths (in characters) for free-text fields
+const (
+	MaxDescriptionLength = 2000
+	MaxCommentLength     = 5000
+)
+
+// ErrTooLong indicates the sanitized text exceeds the allowed length
+var ErrTooLong = errors.New("text exceeds maximum length")
+
+var (
+	// Script and style blocks are removed together with their content.
+	// An unterminated block is removed up to the end of the input.
+	blockPattern = regexp.MustCompile(`(?is)<(script|style)\b[^>]*>.*?(</(script|style)\s*>|$)`)
+
+	// Any remaining tag (including comments and closing tags) is stripped, keeping the inner text
+	tagPattern = regexp.MustCompile(`(?s)<!--.*?-->|</?[a-zA-Z][^>]*>`)
+
+	// A comment that is never closed, or a tag that is never closed once it has an attribute
+	// value ("<img src=x onerror=..."), is removed up to the end of the input, like an
+	// unterminated script block. Any other "<" is ordinary text ("5 < 10", "score<threshold").
+	danglingPattern = regexp.MustCompile(`(?s)<!--.*$|</?[a-zA-Z][^\s/<>]*[\s/][^<>]*=[^<>]*$`)
+)
+
+// Text cleans user-supplied free text (descriptions, comments) so it is safe to store
+// and render in the web UI. It normalizes unicode to NFC, removes script/style blocks
+// and HTML tags, drops control and bidi-override characters, and trims whitespace.
+// Returns ErrTooLong if the cleaned text is longer than maxLen characters.
+func Text(input string, maxLen int) (string, error) {
+	out := norm.NFC.String(input)
+	// Stripping can join the pieces of a nested tag ("<<img>img onerror=...>") into a new
+	// one, so strip until nothing changes
+	for {
+		stripped := blockPattern.ReplaceAllString(out, "")
+		stripped = tagPattern.ReplaceAllString(stripped, "")
+		if stripped == out {
+			break
+		}
+		out = stripped
+	}
+	out = danglingPattern.ReplaceAllString(out, "")
+	out = strings.Map(dropUnsafeRune, out)
+	out = strings.TrimSpace(out)
+
+	if maxLen > 0 && utf8.RuneCountInString(out) > maxLen {
+		return "", ErrTooLong
+	}
+
+	return out, nil
+}
+
+// dropUnsafeRune removes control characters (except common whitespace) and
+// bidirectional override characters that can be used to spoof rendered text
+func dropUnsafeRune(r rune) rune {
+	switch r {
+	case '\n', '\r', '\t':
+		return r
+	}
+	if unicode.IsControl(r) {
+		return -1
+	}
+	if (r >= '\u202A' && r <= '\u202E') || (r >= '\u2066' && r <= '\u2069') {
+		return -1
+	}
+	return r
+}
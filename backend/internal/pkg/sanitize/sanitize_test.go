@@ -0,0 +1,83 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestText_StripsXSSPayloads(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		// Payloads used by the security test-suite
+		{"script with sql injection", "<script>alert('xss')</script>'; DELETE FROM projects; --", "'; DELETE FROM projects; --"},
+		{"bare script", "<script>alert('xss')</script>", ""},
+		{"uppercase script", "<SCRIPT src=//evil.com/x.js></SCRIPT>hello", "hello"},
+		{"unterminated script", "safe <script>alert(1)", "safe"},
+		{"event handler attribute", `<img src=x onerror="alert(1)">caption`, "caption"},
+		{"style block", "<style>body{display:none}</style>visible", "visible"},
+		{"html comment", "before<!-- <script>alert(1)</script> -->after", "beforeafter"},
+		{"nested tag", "<<img>img src=x onerror=alert(1)>caption", "caption"},
+		{"nested script", "<scr<script></script>ipt>alert(1)</script>", "alert(1)"},
+		{"unterminated tag", "caption <img src=x onerror=alert(1)//", "caption"},
+		{"unterminated slash-separated tag", "caption <svg/onload=alert(1)", "caption"},
+		{"unterminated comment", "caption <!-- <img src=x onerror=alert(1)>", "caption"},
+		{"inline markup keeps text", "<b>bold</b> and <a href=\"javascript:alert(1)\">link</a>", "bold and link"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Text(tt.input, MaxDescriptionLength)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestText_PreservesPlainText(t *testing.T) {
+	inputs := []string{
+		"Roll out to 5 < 10 users & AU customers.\nSee runbook.",
+		"Enable when score<threshold for beta",
+		"use a<b comparisons",
+		"x <y",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			got, err := Text(input, MaxDescriptionLength)
+
+			require.NoError(t, err)
+			assert.Equal(t, input, got)
+		})
+	}
+}
+
+func TestText_NormalizesUnicode(t *testing.T) {
+	// "e" followed by a combining acute accent normalizes to the precomposed form
+	got, err := Text("cafe\u0301", MaxDescriptionLength)
+
+	require.NoError(t, err)
+	assert.Equal(t, "caf\u00e9", got)
+}
+
+func TestText_RemovesControlAndBidiCharacters(t *testing.T) {
+	got, err := Text("admin\u202Egnp.exe\x00\x07", MaxDescriptionLength)
+
+	require.NoError(t, err)
+	assert.Equal(t, "admingnp.exe", got)
+}
+
+func TestText_EnforcesMaxLength(t *testing.T) {
+	_, err := Text(strings.Repeat("a", 11), 10)
+	assert.ErrorIs(t, err, ErrTooLong)
+
+	// Length is measured after sanitization, in characters rather than bytes
+	got, err := Text("<b>"+strings.Repeat("é", 10)+"</b>", 10)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("é", 10), got)
+}
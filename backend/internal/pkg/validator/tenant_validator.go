@@ -12,6 +12,7 @@ import (
 type Validator interface {
 	ValidateProjectOwnership(ctx context.Context, projectID, tenantID string) error
 	ValidateTenantExists(ctx context.Context, tenantID string) error
+	ValidateTenantMembership(ctx context.Context, userID, tenantID string) error
 }
 
 // TenantValidator provides reusable tenant ownership validation
@@ -60,3 +61,21 @@ func (v *TenantValidator) ValidateTenantExists(ctx context.Context, tenantID str
 
 	return nil
 }
+
+// ValidateTenantMembership verifies that a user is a member of a tenant
+// Returns ErrUserNotInTenant if the user doesn't exist OR isn't a member
+func (v *TenantValidator) ValidateTenantMembership(ctx context.Context, userID, tenantID string) error {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM tenant_members WHERE user_id = $1 AND tenant_id = $2)`
+
+	err := v.db.GetContext(ctx, &exists, query, userID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return pkgErrors.ErrUserNotInTenant
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"flag":"checkout","enabled":false}`)
+	signature := Sign("s3cret", 1_700_000_000, "nonce-1", body)
+
+	if signature != Sign("s3cret", 1_700_000_000, "nonce-1", body) {
+		t.Error("expected signing to be deterministic")
+	}
+	if signature == Sign("other", 1_700_000_000, "nonce-1", body) {
+		t.Error("expected the secret to change the signature")
+	}
+	if signature == Sign("s3cret", 1_700_000_000, "nonce-2", body) {
+		t.Error("expected the nonce to change the signature")
+	}
+	if signature == Sign("s3cret", 1_700_000_001, "nonce-1", body) {
+		t.Error("expected the timestamp to change the signature")
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	body := []byte("payload")
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := SignRequest(req, "s3cret", body); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	timestamp, err := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("expected a unix timestamp, got %q", req.Header.Get(TimestampHeader))
+	}
+	if skew := time.Since(time.Unix(timestamp, 0)); skew > time.Minute || skew < -time.Minute {
+		t.Errorf("expected the current time, got %v", time.Unix(timestamp, 0))
+	}
+	if got, want := req.Header.Get(SignatureHeader), Sign("s3cret", timestamp, req.Header.Get(NonceHeader), body); got != want {
+		t.Errorf("expected signature %s, got %s", want, got)
+	}
+
+	other, _ := http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	if err := SignRequest(other, "s3cret", body); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if other.Header.Get(NonceHeader) == req.Header.Get(NonceHeader) {
+		t.Error("expected each request to get a fresh nonce")
+	}
+}
@@ -0,0 +1,39 @@
+package presets
+
+import "time"
+
+// PresetResponse is a preset as the API returns it, with its context decrypted.
+// The sealed column never leaves the service.
+type PresetResponse struct {
+	ID          string                 `json:"id"`
+	TenantID    string                 `json:"tenant_id"`
+	ProjectID   string                 `json:"project_id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	UserID      string                 `json:"user_id"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+func newPresetResponse(p *Preset) PresetResponse {
+	return PresetResponse{
+		ID:          p.ID,
+		TenantID:    p.TenantID,
+		ProjectID:   p.ProjectID,
+		Name:        p.Name,
+		Description: p.Description,
+		UserID:      p.UserID,
+		Attributes:  p.Attributes,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+func newPresetResponses(presets []Preset) []PresetResponse {
+	out := make([]PresetResponse, len(presets))
+	for i := range presets {
+		out[i] = newPresetResponse(&presets[i])
+	}
+	return out
+}
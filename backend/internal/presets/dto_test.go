@@ -0,0 +1,23 @@
+package presets
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPresetResponse_OmitsSealedContext(t *testing.T) {
+	sealed := "v1:ciphertext-4d2f"
+	preset := &Preset{ID: "preset-1", Name: "QA iPhone", UserID: "qa-user", Sealed: &sealed}
+
+	b, err := json.Marshal(newPresetResponses([]Preset{*preset}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(string(b), sealed) || strings.Contains(string(b), `"sealed"`) {
+		t.Errorf("expected the sealed context to be left out, got %s", b)
+	}
+	if !strings.Contains(string(b), `"user_id":"qa-user"`) {
+		t.Errorf("expected the decrypted context, got %s", b)
+	}
+}
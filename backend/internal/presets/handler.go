@@ -0,0 +1,142 @@
+package presets
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/projects/:id/presets", h.Create)
+	r.GET("/projects/:id/presets", h.List)
+	r.GET("/projects/:id/presets/:presetID", h.Get)
+	r.PUT("/projects/:id/presets/:presetID", h.Update)
+	r.DELETE("/projects/:id/presets/:presetID", h.Delete)
+}
+
+func (h *handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	preset := &Preset{
+		ProjectID:   c.Param("id"),
+		Name:        req.Name,
+		Description: req.Description,
+		UserID:      req.UserID,
+		Attributes:  req.Attributes,
+	}
+
+	if err := h.service.Create(c.Request.Context(), preset, tenantID); err != nil {
+		h.writeError(c, err, "failed to create preset")
+		return
+	}
+
+	c.JSON(http.StatusCreated, newPresetResponse(preset))
+}
+
+func (h *handler) List(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	presets, err := h.service.List(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to list presets")
+		return
+	}
+
+	c.JSON(http.StatusOK, newPresetResponses(presets))
+}
+
+func (h *handler) Get(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	preset, err := h.service.GetByID(c.Request.Context(), c.Param("presetID"), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get preset")
+		return
+	}
+
+	c.JSON(http.StatusOK, newPresetResponse(preset))
+}
+
+func (h *handler) Update(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preset, err := h.service.GetByID(c.Request.Context(), c.Param("presetID"), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get preset")
+		return
+	}
+
+	if req.Name != nil {
+		preset.Name = *req.Name
+	}
+	if req.Description != nil {
+		preset.Description = *req.Description
+	}
+	if req.UserID != nil {
+		preset.UserID = *req.UserID
+	}
+	if req.Attributes != nil {
+		preset.Attributes = req.Attributes
+	}
+
+	if err := h.service.Update(c.Request.Context(), preset, tenantID); err != nil {
+		h.writeError(c, err, "failed to update preset")
+		return
+	}
+
+	c.JSON(http.StatusOK, newPresetResponse(preset))
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	if err := h.service.Delete(c.Request.Context(), c.Param("presetID"), c.Param("id"), tenantID); err != nil {
+		h.writeError(c, err, "failed to delete preset")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *handler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidPresetData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDuplicateName):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, pkgErrors.ErrProjectNotInTenant):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+	case pkgErrors.IsNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "preset not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,56 @@
+package presets
+
+import (
+	"time"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+)
+
+// Preset is a named evaluation context saved for a project, e.g. "QA iPhone US premium user"
+type Preset struct {
+	ID          string                 `json:"id" db:"id"`
+	TenantID    string                 `json:"tenant_id" db:"tenant_id"`
+	ProjectID   string                 `json:"project_id" db:"project_id"`
+	Name        string                 `json:"name" db:"name"`
+	Description string                 `json:"description" db:"description"`
+	UserID      string                 `json:"user_id" db:"user_id"`
+	Attributes  map[string]interface{} `json:"attributes" db:"attributes"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
+	// Sealed holds UserID and Attributes encrypted with the tenant data key, when the
+	// tenant has one; the plain columns are then stored empty
+	Sealed *string `json:"-" db:"sealed"`
+}
+
+// sealedContext is what a preset's Sealed value decrypts to
+type sealedContext struct {
+	UserID     string                 `json:"user_id"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// Context returns the preset as an evaluation context
+func (p *Preset) Context() evaluation.EvaluationContext {
+	attributes := make(map[string]interface{}, len(p.Attributes))
+	for k, v := range p.Attributes {
+		attributes[k] = v
+	}
+
+	return evaluation.EvaluationContext{
+		UserID:     p.UserID,
+		Attributes: attributes,
+	}
+}
+
+type CreateRequest struct {
+	Name        string                 `json:"name" binding:"required,max=255"`
+	Description string                 `json:"description"`
+	UserID      string                 `json:"user_id" binding:"required,max=255"`
+	Attributes  map[string]interface{} `json:"attributes"`
+}
+
+type UpdateRequest struct {
+	Name        *string                `json:"name" binding:"omitempty,max=255"`
+	Description *string                `json:"description"`
+	UserID      *string                `json:"user_id" binding:"omitempty,max=255"`
+	Attributes  map[string]interface{} `json:"attributes"`
+}
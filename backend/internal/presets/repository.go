@@ -0,0 +1,217 @@
+package presets
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	Create(ctx context.Context, p *Preset) error
+	GetByID(ctx context.Context, id string, projectID string, tenantID string) (*Preset, error)
+	ListByProject(ctx context.Context, projectID string, tenantID string) ([]Preset, error)
+	Update(ctx context.Context, p *Preset) error
+	Delete(ctx context.Context, id string, projectID string, tenantID string) error
+	// ListPendingSeal returns up to limit presets not sealed with the given key version
+	ListPendingSeal(ctx context.Context, tenantID string, version int, limit int) ([]Preset, error)
+	CountPendingSeal(ctx context.Context, tenantID string, version int) (int, error)
+	// UpdateSealed stores a preset's re-sealed context without touching its other fields
+	UpdateSealed(ctx context.Context, p *Preset) error
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *postgresRepository) Create(ctx context.Context, p *Preset) error {
+	userID, attributesJSON, err := plainColumns(p)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO context_presets (tenant_id, project_id, name, description, user_id, attributes, sealed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, p.TenantID, p.ProjectID, p.Name, p.Description, userID, attributesJSON, p.Sealed).
+		Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// plainColumns returns the user_id and attributes column values; both are empty when the preset is sealed
+func plainColumns(p *Preset) (string, []byte, error) {
+	if p.Sealed != nil {
+		return "", []byte("{}"), nil
+	}
+
+	attributesJSON, err := json.Marshal(p.Attributes)
+	if err != nil {
+		return "", nil, err
+	}
+	return p.UserID, attributesJSON, nil
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id string, projectID string, tenantID string) (*Preset, error) {
+	query := `
+		SELECT id, tenant_id, project_id, name, description, user_id, attributes, sealed, created_at, updated_at
+		FROM context_presets
+		WHERE id = $1 AND project_id = $2 AND tenant_id = $3
+	`
+	return scanPreset(r.getDB(ctx).QueryRowxContext(ctx, query, id, projectID, tenantID))
+}
+
+func (r *postgresRepository) ListByProject(ctx context.Context, projectID string, tenantID string) ([]Preset, error) {
+	query := `
+		SELECT id, tenant_id, project_id, name, description, user_id, attributes, sealed, created_at, updated_at
+		FROM context_presets
+		WHERE project_id = $1 AND tenant_id = $2
+		ORDER BY name ASC
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []Preset
+	for rows.Next() {
+		p, err := scanPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, *p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return presets, nil
+}
+
+func (r *postgresRepository) Update(ctx context.Context, p *Preset) error {
+	userID, attributesJSON, err := plainColumns(p)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE context_presets
+		SET name = $4, description = $5, user_id = $6, attributes = $7, sealed = $8
+		WHERE id = $1 AND project_id = $2 AND tenant_id = $3
+		RETURNING updated_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, p.ID, p.ProjectID, p.TenantID, p.Name, p.Description, userID, attributesJSON, p.Sealed).
+		Scan(&p.UpdatedAt)
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, id string, projectID string, tenantID string) error {
+	result, err := r.getDB(ctx).ExecContext(ctx,
+		`DELETE FROM context_presets WHERE id = $1 AND project_id = $2 AND tenant_id = $3`, id, projectID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// pendingSealCondition matches presets not sealed with key version $2
+const pendingSealCondition = `(sealed IS NULL OR sealed NOT LIKE 'tk1:' || $2::text || ':%')`
+
+func (r *postgresRepository) ListPendingSeal(ctx context.Context, tenantID string, version int, limit int) ([]Preset, error) {
+	query := `
+		SELECT id, tenant_id, project_id, name, description, user_id, attributes, sealed, created_at, updated_at
+		FROM context_presets
+		WHERE tenant_id = $1 AND ` + pendingSealCondition + `
+		ORDER BY id
+		LIMIT $3
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, tenantID, version, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []Preset
+	for rows.Next() {
+		p, err := scanPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, *p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return presets, nil
+}
+
+func (r *postgresRepository) CountPendingSeal(ctx context.Context, tenantID string, version int) (int, error) {
+	query := `SELECT COUNT(*) FROM context_presets WHERE tenant_id = $1 AND ` + pendingSealCondition
+	var count int
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, tenantID, version).Scan(&count)
+	return count, err
+}
+
+func (r *postgresRepository) UpdateSealed(ctx context.Context, p *Preset) error {
+	userID, attributesJSON, err := plainColumns(p)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE context_presets
+		SET user_id = $3, attributes = $4, sealed = $5
+		WHERE id = $1 AND tenant_id = $2
+	`
+	_, err = r.getDB(ctx).ExecContext(ctx, query, p.ID, p.TenantID, userID, attributesJSON, p.Sealed)
+	return err
+}
+
+// rowScanner is implemented by both *sqlx.Row and *sqlx.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPreset(row rowScanner) (*Preset, error) {
+	var p Preset
+	var attributesJSON []byte
+
+	err := row.Scan(&p.ID, &p.TenantID, &p.ProjectID, &p.Name, &p.Description, &p.UserID, &attributesJSON, &p.Sealed, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(attributesJSON, &p.Attributes); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,369 @@
+package presets
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+
+	"github.com/jalil32/toggle/internal/encryption"
+	"github.com/jalil32/toggle/internal/evaluation"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
+	"github.com/jalil32/toggle/internal/pkg/validator"
+)
+
+// MaxAttributes bounds the number of attributes a preset may hold
+const MaxAttributes = 100
+
+var (
+	ErrInvalidPresetData = errors.New("invalid preset data")
+	ErrDuplicateName     = errors.New("a preset with this name already exists in the project")
+)
+
+type Service interface {
+	Create(ctx context.Context, p *Preset, tenantID string) error
+	GetByID(ctx context.Context, id string, projectID string, tenantID string) (*Preset, error)
+	List(ctx context.Context, projectID string, tenantID string) ([]Preset, error)
+	Update(ctx context.Context, p *Preset, tenantID string) error
+	Delete(ctx context.Context, id string, projectID string, tenantID string) error
+
+	// ResolveContext returns a preset's evaluation context, for QA tooling that accepts a preset instead of raw JSON
+	ResolveContext(ctx context.Context, id string, projectID string, tenantID string) (evaluation.EvaluationContext, error)
+
+	// Preset contexts can identify real users, so they are sealed with the tenant data key.
+	// CountPending and Reencrypt make the service an encryption.Reencrypter.
+	SetSealer(sealer Sealer)
+	CountPending(ctx context.Context, tenantID string, version int) (int, error)
+	Reencrypt(ctx context.Context, tenantID string, version int, limit int) (int, error)
+}
+
+// Sealer encrypts values with a tenant's data key (implemented by encryption.Keyring)
+type Sealer interface {
+	Seal(ctx context.Context, tenantID string, plaintext []byte) (string, error)
+	Open(ctx context.Context, tenantID string, value string) ([]byte, error)
+}
+
+type service struct {
+	repo      Repository
+	validator validator.Validator
+	sealer    Sealer
+	logger    *slog.Logger
+}
+
+func NewService(repo Repository, val validator.Validator, logger *slog.Logger) Service {
+	return &service{
+		repo:      repo,
+		validator: val,
+		logger:    logger,
+	}
+}
+
+func (s *service) Create(ctx context.Context, p *Preset, tenantID string) error {
+	if err := s.validatePreset(p); err != nil {
+		s.logger.Warn("preset validation failed",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	if err := s.validator.ValidateProjectOwnership(ctx, p.ProjectID, tenantID); err != nil {
+		s.logger.Warn("project ownership validation failed",
+			slog.String("project_id", p.ProjectID),
+			slog.String("tenant_id", tenantID),
+		)
+		return pkgErrors.ErrProjectNotInTenant
+	}
+
+	p.TenantID = tenantID
+
+	if err := s.seal(ctx, p); err != nil {
+		return err
+	}
+
+	if err := s.repo.Create(ctx, p); err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateName
+		}
+		s.logger.Error("failed to create preset",
+			slog.String("name", p.Name),
+			slog.String("project_id", p.ProjectID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to create preset: %w", err)
+	}
+
+	s.logger.Info("preset created",
+		slog.String("id", p.ID),
+		slog.String("name", p.Name),
+		slog.String("project_id", p.ProjectID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+func (s *service) GetByID(ctx context.Context, id string, projectID string, tenantID string) (*Preset, error) {
+	if id == "" {
+		return nil, ErrInvalidPresetData
+	}
+
+	p, err := s.repo.GetByID(ctx, id, projectID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("preset not found or forbidden",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get preset",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to get preset: %w", err)
+	}
+
+	if err := s.open(ctx, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *service) List(ctx context.Context, projectID string, tenantID string) ([]Preset, error) {
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	presets, err := s.repo.ListByProject(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list presets",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list presets: %w", err)
+	}
+
+	if presets == nil {
+		return []Preset{}, nil
+	}
+
+	for i := range presets {
+		if err := s.open(ctx, &presets[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return presets, nil
+}
+
+func (s *service) Update(ctx context.Context, p *Preset, tenantID string) error {
+	if err := s.validatePreset(p); err != nil {
+		s.logger.Warn("preset validation failed on update",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	p.TenantID = tenantID
+
+	if err := s.seal(ctx, p); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(ctx, p); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return ErrDuplicateName
+		}
+		s.logger.Error("failed to update preset",
+			slog.String("id", p.ID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to update preset: %w", err)
+	}
+
+	s.logger.Info("preset updated",
+		slog.String("id", p.ID),
+		slog.String("name", p.Name),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+func (s *service) Delete(ctx context.Context, id string, projectID string, tenantID string) error {
+	if id == "" {
+		return ErrInvalidPresetData
+	}
+
+	if err := s.repo.Delete(ctx, id, projectID, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to delete preset",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to delete preset: %w", err)
+	}
+
+	s.logger.Info("preset deleted",
+		slog.String("id", id),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+func (s *service) ResolveContext(ctx context.Context, id string, projectID string, tenantID string) (evaluation.EvaluationContext, error) {
+	p, err := s.GetByID(ctx, id, projectID, tenantID)
+	if err != nil {
+		return evaluation.EvaluationContext{}, err
+	}
+
+	return p.Context(), nil
+}
+
+// SetSealer sets the sealer used for preset contexts (called after initialization)
+func (s *service) SetSealer(sealer Sealer) {
+	s.sealer = sealer
+}
+
+// seal encrypts the preset's user ID and attributes when the tenant has a data key
+func (s *service) seal(ctx context.Context, p *Preset) error {
+	p.Sealed = nil
+	if s.sealer == nil {
+		return nil
+	}
+
+	plaintext, err := json.Marshal(sealedContext{UserID: p.UserID, Attributes: p.Attributes})
+	if err != nil {
+		return err
+	}
+
+	sealed, err := s.sealer.Seal(ctx, p.TenantID, plaintext)
+	if err != nil {
+		if errors.Is(err, encryption.ErrNoKey) {
+			return nil
+		}
+		s.logger.Error("failed to seal preset",
+			slog.String("id", p.ID),
+			slog.String("tenant_id", p.TenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to seal preset: %w", err)
+	}
+
+	p.Sealed = &sealed
+	return nil
+}
+
+// open decrypts a sealed preset's user ID and attributes in place
+func (s *service) open(ctx context.Context, p *Preset) error {
+	if p.Sealed == nil {
+		return nil
+	}
+	if s.sealer == nil {
+		return fmt.Errorf("failed to open preset %s: %w", p.ID, encryption.ErrNotConfigured)
+	}
+
+	plaintext, err := s.sealer.Open(ctx, p.TenantID, *p.Sealed)
+	if err != nil {
+		s.logger.Error("failed to open preset",
+			slog.String("id", p.ID),
+			slog.String("tenant_id", p.TenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to open preset: %w", err)
+	}
+
+	var sc sealedContext
+	if err := json.Unmarshal(plaintext, &sc); err != nil {
+		return fmt.Errorf("failed to open preset: %w", err)
+	}
+	p.UserID = sc.UserID
+	p.Attributes = sc.Attributes
+	if p.Attributes == nil {
+		p.Attributes = map[string]interface{}{}
+	}
+
+	return nil
+}
+
+// CountPending counts the tenant's presets not sealed with the given key version
+func (s *service) CountPending(ctx context.Context, tenantID string, version int) (int, error) {
+	return s.repo.CountPendingSeal(ctx, tenantID, version)
+}
+
+// Reencrypt re-seals up to limit presets with the tenant's active key
+func (s *service) Reencrypt(ctx context.Context, tenantID string, version int, limit int) (int, error) {
+	presets, err := s.repo.ListPendingSeal(ctx, tenantID, version, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list presets to re-encrypt: %w", err)
+	}
+
+	for i := range presets {
+		p := &presets[i]
+		if err := s.open(ctx, p); err != nil {
+			return i, err
+		}
+		if err := s.seal(ctx, p); err != nil {
+			return i, err
+		}
+		if err := s.repo.UpdateSealed(ctx, p); err != nil {
+			return i, fmt.Errorf("failed to store re-encrypted preset: %w", err)
+		}
+	}
+
+	return len(presets), nil
+}
+
+func (s *service) validatePreset(p *Preset) error {
+	if p == nil {
+		return ErrInvalidPresetData
+	}
+
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPresetData)
+	}
+
+	if p.UserID == "" {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidPresetData)
+	}
+
+	if len(p.Attributes) > MaxAttributes {
+		return fmt.Errorf("%w: at most %d attributes are allowed", ErrInvalidPresetData, MaxAttributes)
+	}
+
+	if p.Attributes == nil {
+		p.Attributes = map[string]interface{}{}
+	}
+
+	description, err := sanitize.Text(p.Description, sanitize.MaxDescriptionLength)
+	if err != nil {
+		return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidPresetData, sanitize.MaxDescriptionLength)
+	}
+	p.Description = description
+
+	return nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
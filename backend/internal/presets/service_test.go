@@ -0,0 +1,261 @@
+package presets
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"github.com/jalil32/toggle/internal/encryption"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type mockRepository struct {
+	createFunc  func(ctx context.Context, p *Preset) error
+	getByIDFunc func(ctx context.Context, id string, projectID string, tenantID string) (*Preset, error)
+}
+
+func (m *mockRepository) Create(ctx context.Context, p *Preset) error {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, p)
+	}
+	p.ID = "test-generated-id"
+	return nil
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string, projectID string, tenantID string) (*Preset, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(ctx, id, projectID, tenantID)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockRepository) ListByProject(ctx context.Context, projectID string, tenantID string) ([]Preset, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) Update(ctx context.Context, p *Preset) error {
+	return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string, projectID string, tenantID string) error {
+	return nil
+}
+
+func (m *mockRepository) ListPendingSeal(ctx context.Context, tenantID string, version int, limit int) ([]Preset, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) CountPendingSeal(ctx context.Context, tenantID string, version int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockRepository) UpdateSealed(ctx context.Context, p *Preset) error {
+	return nil
+}
+
+type mockValidator struct {
+	ownedProjects map[string]string // project ID -> tenant ID
+}
+
+func (m *mockValidator) ValidateProjectOwnership(ctx context.Context, projectID, tenantID string) error {
+	if m.ownedProjects[projectID] != tenantID {
+		return pkgErrors.ErrProjectNotInTenant
+	}
+	return nil
+}
+
+func (m *mockValidator) ValidateTenantExists(ctx context.Context, tenantID string) error {
+	return nil
+}
+
+func (m *mockValidator) ValidateTenantMembership(ctx context.Context, userID, tenantID string) error {
+	return nil
+}
+
+func newTestService(repo *mockRepository) Service {
+	val := &mockValidator{ownedProjects: map[string]string{"project-1": "tenant-1"}}
+	return NewService(repo, val, slog.Default())
+}
+
+func TestServiceCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		preset  *Preset
+		tenant  string
+		mockFn  func(ctx context.Context, p *Preset) error
+		wantErr error
+	}{
+		{
+			name:   "valid preset",
+			preset: &Preset{ProjectID: "project-1", Name: "QA iPhone US premium user", UserID: "qa-1", Attributes: map[string]interface{}{"country": "US"}},
+			tenant: "tenant-1",
+		},
+		{
+			name:    "missing name",
+			preset:  &Preset{ProjectID: "project-1", UserID: "qa-1"},
+			tenant:  "tenant-1",
+			wantErr: ErrInvalidPresetData,
+		},
+		{
+			name:    "missing user ID",
+			preset:  &Preset{ProjectID: "project-1", Name: "qa"},
+			tenant:  "tenant-1",
+			wantErr: ErrInvalidPresetData,
+		},
+		{
+			name:    "project in another tenant",
+			preset:  &Preset{ProjectID: "project-1", Name: "qa", UserID: "qa-1"},
+			tenant:  "tenant-2",
+			wantErr: pkgErrors.ErrProjectNotInTenant,
+		},
+		{
+			name:   "duplicate name",
+			preset: &Preset{ProjectID: "project-1", Name: "qa", UserID: "qa-1"},
+			tenant: "tenant-1",
+			mockFn: func(ctx context.Context, p *Preset) error {
+				return &pq.Error{Code: "23505"}
+			},
+			wantErr: ErrDuplicateName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(&mockRepository{createFunc: tt.mockFn})
+
+			err := svc.Create(context.Background(), tt.preset, tt.tenant)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.preset.TenantID != tt.tenant {
+				t.Errorf("expected tenant %s, got %s", tt.tenant, tt.preset.TenantID)
+			}
+		})
+	}
+}
+
+func TestServiceCreate_LimitsAttributes(t *testing.T) {
+	attributes := make(map[string]interface{}, MaxAttributes+1)
+	for i := 0; i <= MaxAttributes; i++ {
+		attributes[fmt.Sprintf("attr-%d", i)] = i
+	}
+	svc := newTestService(&mockRepository{})
+
+	err := svc.Create(context.Background(), &Preset{ProjectID: "project-1", Name: "big", UserID: "qa-1", Attributes: attributes}, "tenant-1")
+
+	if !errors.Is(err, ErrInvalidPresetData) {
+		t.Errorf("expected ErrInvalidPresetData, got %v", err)
+	}
+}
+
+func TestServiceResolveContext(t *testing.T) {
+	stored := &Preset{ID: "preset-1", ProjectID: "project-1", TenantID: "tenant-1", UserID: "qa-1", Attributes: map[string]interface{}{"plan": "premium"}}
+	svc := newTestService(&mockRepository{
+		getByIDFunc: func(ctx context.Context, id string, projectID string, tenantID string) (*Preset, error) {
+			if id != stored.ID || projectID != stored.ProjectID || tenantID != stored.TenantID {
+				return nil, sql.ErrNoRows
+			}
+			return stored, nil
+		},
+	})
+
+	evalCtx, err := svc.ResolveContext(context.Background(), "preset-1", "project-1", "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if evalCtx.UserID != "qa-1" || evalCtx.Attributes["plan"] != "premium" {
+		t.Errorf("unexpected context %+v", evalCtx)
+	}
+
+	// Callers may adjust the resolved context without changing the preset
+	evalCtx.Attributes["plan"] = "free"
+	if stored.Attributes["plan"] != "premium" {
+		t.Error("expected resolved context to be a copy")
+	}
+
+	if _, err := svc.ResolveContext(context.Background(), "preset-1", "project-1", "tenant-2"); !errors.Is(err, pkgErrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another tenant, got %v", err)
+	}
+}
+
+// mockSealer "seals" by prefixing the tenant ID, so only the same tenant can open
+type mockSealer struct {
+	noKey bool
+}
+
+func (m mockSealer) Seal(ctx context.Context, tenantID string, plaintext []byte) (string, error) {
+	if m.noKey {
+		return "", encryption.ErrNoKey
+	}
+	return tenantID + ":" + string(plaintext), nil
+}
+
+func (m mockSealer) Open(ctx context.Context, tenantID string, value string) ([]byte, error) {
+	plaintext, ok := strings.CutPrefix(value, tenantID+":")
+	if !ok {
+		return nil, errors.New("sealed for another tenant")
+	}
+	return []byte(plaintext), nil
+}
+
+func TestServiceCreate_SealsContext(t *testing.T) {
+	var stored *Preset
+	repo := &mockRepository{
+		createFunc: func(ctx context.Context, p *Preset) error {
+			copied := *p
+			stored = &copied
+			return nil
+		},
+		getByIDFunc: func(ctx context.Context, id string, projectID string, tenantID string) (*Preset, error) {
+			copied := *stored
+			return &copied, nil
+		},
+	}
+
+	t.Run("tenant without a key stores plain text", func(t *testing.T) {
+		svc := newTestService(repo)
+		svc.SetSealer(mockSealer{noKey: true})
+
+		p := &Preset{ProjectID: "project-1", Name: "qa", UserID: "qa-1"}
+		if err := svc.Create(context.Background(), p, "tenant-1"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if stored.Sealed != nil {
+			t.Errorf("expected no sealed value, got %q", *stored.Sealed)
+		}
+	})
+
+	t.Run("tenant with a key seals and opens", func(t *testing.T) {
+		svc := newTestService(repo)
+		svc.SetSealer(mockSealer{})
+
+		p := &Preset{ProjectID: "project-1", Name: "qa", UserID: "qa-1", Attributes: map[string]interface{}{"country": "US"}}
+		if err := svc.Create(context.Background(), p, "tenant-1"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if stored.Sealed == nil {
+			t.Fatal("expected the context to be sealed")
+		}
+
+		got, err := svc.GetByID(context.Background(), "preset-1", "project-1", "tenant-1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.UserID != "qa-1" || got.Attributes["country"] != "US" {
+			t.Errorf("expected opened context, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package previews
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/previews", h.List)
+	r.PUT("/previews/:key", h.Set)
+}
+
+// List returns the previews of Toggle features the tenant can enroll in
+func (h *handler) List(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	previews, err := h.service.List(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list previews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, previews)
+}
+
+// Set opts the tenant in to or out of a preview
+func (h *handler) Set(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+
+	var req SetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := h.service.Set(ctx, tenantID, c.Param("key"), appContext.MustUserID(ctx), appContext.UserRole(ctx), *req.Enabled)
+	if err != nil {
+		switch {
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "preview not found"})
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set preview"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
@@ -0,0 +1,28 @@
+package previews
+
+import (
+	"time"
+
+	"github.com/jalil32/toggle/internal/pkg/featuregate"
+)
+
+// Enrollment is a tenant's latest opt-in or opt-out of a preview
+type Enrollment struct {
+	TenantID   string    `json:"-" db:"tenant_id"`
+	PreviewKey string    `json:"-" db:"preview_key"`
+	Enabled    bool      `json:"enabled" db:"enabled"`
+	ChangedBy  *string   `json:"changed_by" db:"changed_by"` // nil once the user is deleted
+	ChangedAt  time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// TenantPreview is an available preview with the tenant's enrollment, if it ever changed it
+type TenantPreview struct {
+	featuregate.Preview
+	Enabled bool `json:"enabled"`
+	// Enrollment records who last opted the tenant in or out, and when
+	Enrollment *Enrollment `json:"enrollment,omitempty"`
+}
+
+type SetRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
@@ -0,0 +1,73 @@
+package previews
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	// List returns the tenant's enrollments, by preview key
+	List(ctx context.Context, tenantID string) ([]Enrollment, error)
+	Get(ctx context.Context, tenantID string, key string) (*Enrollment, error)
+	// Set records an opt-in or opt-out, replacing the tenant's previous one for the preview
+	Set(ctx context.Context, e *Enrollment) error
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *postgresRepository) List(ctx context.Context, tenantID string) ([]Enrollment, error) {
+	query := `
+		SELECT tenant_id, preview_key, enabled, changed_by, changed_at
+		FROM tenant_previews
+		WHERE tenant_id = $1
+		ORDER BY preview_key ASC
+	`
+	enrollments := []Enrollment{}
+	if err := sqlx.SelectContext(ctx, r.getDB(ctx), &enrollments, query, tenantID); err != nil {
+		return nil, err
+	}
+	return enrollments, nil
+}
+
+func (r *postgresRepository) Get(ctx context.Context, tenantID string, key string) (*Enrollment, error) {
+	query := `
+		SELECT tenant_id, preview_key, enabled, changed_by, changed_at
+		FROM tenant_previews
+		WHERE tenant_id = $1 AND preview_key = $2
+	`
+	var e Enrollment
+	if err := sqlx.GetContext(ctx, r.getDB(ctx), &e, query, tenantID, key); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *postgresRepository) Set(ctx context.Context, e *Enrollment) error {
+	query := `
+		INSERT INTO tenant_previews (tenant_id, preview_key, enabled, changed_by, changed_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (tenant_id, preview_key) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+		    changed_by = EXCLUDED.changed_by,
+		    changed_at = EXCLUDED.changed_at
+		RETURNING changed_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, e.TenantID, e.PreviewKey, e.Enabled, e.ChangedBy).Scan(&e.ChangedAt)
+}
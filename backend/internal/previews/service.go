@@ -0,0 +1,105 @@
+package previews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/featuregate"
+)
+
+var ErrInsufficientPermissions = errors.New("insufficient permissions")
+
+type Service interface {
+	// List returns every available preview with the tenant's enrollment
+	List(ctx context.Context, tenantID string) ([]TenantPreview, error)
+	// Set opts the tenant in or out of a preview on behalf of userID; owners and admins only
+	Set(ctx context.Context, tenantID string, key string, userID string, role string, enabled bool) (*TenantPreview, error)
+	featuregate.Checker
+}
+
+type service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) Service {
+	return &service{repo: repo, logger: logger}
+}
+
+func (s *service) List(ctx context.Context, tenantID string) ([]TenantPreview, error) {
+	enrollments, err := s.repo.List(ctx, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list preview enrollments",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list preview enrollments: %w", err)
+	}
+
+	byKey := make(map[string]*Enrollment, len(enrollments))
+	for i := range enrollments {
+		byKey[enrollments[i].PreviewKey] = &enrollments[i]
+	}
+
+	available := featuregate.Previews()
+	previews := make([]TenantPreview, len(available))
+	for i, p := range available {
+		previews[i] = newTenantPreview(p, byKey[p.Key])
+	}
+	return previews, nil
+}
+
+func newTenantPreview(p featuregate.Preview, e *Enrollment) TenantPreview {
+	return TenantPreview{Preview: p, Enabled: e != nil && e.Enabled, Enrollment: e}
+}
+
+func (s *service) Set(ctx context.Context, tenantID string, key string, userID string, role string, enabled bool) (*TenantPreview, error) {
+	preview, ok := featuregate.Lookup(key)
+	if !ok {
+		return nil, pkgErrors.ErrNotFound
+	}
+	if role != "owner" && role != "admin" {
+		return nil, ErrInsufficientPermissions
+	}
+
+	e := &Enrollment{TenantID: tenantID, PreviewKey: key, Enabled: enabled, ChangedBy: &userID}
+	if err := s.repo.Set(ctx, e); err != nil {
+		s.logger.Error("failed to set preview enrollment",
+			slog.String("tenant_id", tenantID),
+			slog.String("preview", key),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to set preview enrollment: %w", err)
+	}
+
+	s.logger.Info("preview enrollment changed",
+		slog.String("tenant_id", tenantID),
+		slog.String("preview", key),
+		slog.Bool("enabled", enabled),
+		slog.String("user_id", userID),
+	)
+
+	tp := newTenantPreview(preview, e)
+	return &tp, nil
+}
+
+// Enabled reports whether the tenant opted in to a preview. Lookup failures keep the
+// preview off, since previews guard features that are not ready for everyone.
+func (s *service) Enabled(ctx context.Context, tenantID string, key string) bool {
+	e, err := s.repo.Get(ctx, tenantID, key)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Warn("failed to check preview enrollment",
+				slog.String("tenant_id", tenantID),
+				slog.String("preview", key),
+				slog.String("error", err.Error()),
+			)
+		}
+		return false
+	}
+	return e.Enabled
+}
@@ -0,0 +1,130 @@
+package previews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/featuregate"
+)
+
+type mockRepository struct {
+	enrollments map[string]Enrollment // by tenant ID + "/" + preview key
+	err         error
+}
+
+func (m *mockRepository) List(ctx context.Context, tenantID string) ([]Enrollment, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var out []Enrollment
+	for _, e := range m.enrollments {
+		if e.TenantID == tenantID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRepository) Get(ctx context.Context, tenantID string, key string) (*Enrollment, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	e, ok := m.enrollments[tenantID+"/"+key]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &e, nil
+}
+
+func (m *mockRepository) Set(ctx context.Context, e *Enrollment) error {
+	if m.err != nil {
+		return m.err
+	}
+	e.ChangedAt = time.Now()
+	m.enrollments[e.TenantID+"/"+e.PreviewKey] = *e
+	return nil
+}
+
+func TestServiceList_MergesEnrollments(t *testing.T) {
+	repo := &mockRepository{enrollments: map[string]Enrollment{}}
+	svc := NewService(repo, slog.Default())
+	if _, err := svc.Set(context.Background(), "tenant-1", featuregate.PayloadTemplates, "user-1", "admin", true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	previews, err := svc.List(context.Background(), "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(previews) != len(featuregate.Previews()) {
+		t.Fatalf("expected every available preview, got %d", len(previews))
+	}
+	for _, p := range previews {
+		if p.Key != featuregate.PayloadTemplates {
+			continue
+		}
+		if !p.Enabled || p.Enrollment == nil || *p.Enrollment.ChangedBy != "user-1" {
+			t.Errorf("expected the enrollment by user-1, got %+v", p)
+		}
+	}
+
+	other, err := svc.List(context.Background(), "tenant-2")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, p := range other {
+		if p.Enabled || p.Enrollment != nil {
+			t.Errorf("expected tenant-2 not to be enrolled in %s", p.Key)
+		}
+	}
+}
+
+func TestServiceSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		role    string
+		wantErr error
+	}{
+		{name: "owner", key: featuregate.PayloadTemplates, role: "owner"},
+		{name: "admin", key: featuregate.PayloadTemplates, role: "admin"},
+		{name: "member", key: featuregate.PayloadTemplates, role: "member", wantErr: ErrInsufficientPermissions},
+		{name: "unknown preview", key: "time-travel", role: "owner", wantErr: pkgErrors.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{enrollments: map[string]Enrollment{}}
+			svc := NewService(repo, slog.Default())
+
+			_, err := svc.Set(context.Background(), "tenant-1", tt.key, "user-1", tt.role, true)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if got := svc.Enabled(context.Background(), "tenant-1", tt.key); got != (tt.wantErr == nil) {
+				t.Errorf("expected enabled %v, got %v", tt.wantErr == nil, got)
+			}
+		})
+	}
+}
+
+func TestServiceEnabled_FailsClosed(t *testing.T) {
+	repo := &mockRepository{enrollments: map[string]Enrollment{
+		"tenant-1/" + featuregate.PayloadTemplates: {TenantID: "tenant-1", PreviewKey: featuregate.PayloadTemplates, Enabled: true},
+	}}
+	svc := NewService(repo, slog.Default())
+
+	if !svc.Enabled(context.Background(), "tenant-1", featuregate.PayloadTemplates) {
+		t.Fatal("expected the enrolled tenant to have the preview")
+	}
+
+	repo.err = errors.New("connection refused")
+	if svc.Enabled(context.Background(), "tenant-1", featuregate.PayloadTemplates) {
+		t.Error("expected lookup failures to keep the preview off")
+	}
+}
@@ -0,0 +1,138 @@
+package projects
+
+import "time"
+
+// Response DTOs decouple the API from the storage structs. Client API keys authenticate
+// SDKs, so they are only returned in full by the requests that create them and by an
+// audited reveal; everywhere else they are masked to a preview.
+
+type ProjectResponse struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name"`
+	// ClientAPIKeyPreview identifies the key without revealing it
+	ClientAPIKeyPreview string `json:"client_api_key_preview"`
+	// ClientSideAPIKey is shipped to browsers and apps, so it is always shown in full
+	ClientSideAPIKey string    `json:"client_side_api_key"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// CreatedProjectResponse is returned once, when the project and its key are created
+type CreatedProjectResponse struct {
+	ProjectResponse
+	ClientAPIKey string `json:"client_api_key"`
+}
+
+type EnvironmentResponse struct {
+	ID        string `json:"id"`
+	TenantID  string `json:"tenant_id"`
+	ProjectID string `json:"project_id"`
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	// ClientAPIKeyPreview identifies the key without revealing it
+	ClientAPIKeyPreview string    `json:"client_api_key_preview"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// CreatedEnvironmentResponse is returned once, when the environment and its key are created
+type CreatedEnvironmentResponse struct {
+	EnvironmentResponse
+	ClientAPIKey string `json:"client_api_key"`
+}
+
+type APIKeyResponse struct {
+	ID             string `json:"id"`
+	Kind           string `json:"kind"`
+	EnvironmentKey string `json:"environment_key,omitempty"`
+	Preview        string `json:"preview"`
+}
+
+// RevealedAPIKeyResponse is returned by an audited reveal
+type RevealedAPIKeyResponse struct {
+	APIKeyResponse
+	ClientAPIKey string `json:"client_api_key"`
+}
+
+// apiKeyPreviewChars is how many characters of a key its preview shows at each end
+const apiKeyPreviewChars = 4
+
+// maskAPIKey returns a key's prefix and last four characters, such as "a3f1…1e2f".
+// Keys too short to mask safely are hidden entirely.
+func maskAPIKey(key string) string {
+	if len(key) < 4*apiKeyPreviewChars {
+		return "…"
+	}
+	return key[:apiKeyPreviewChars] + "…" + key[len(key)-apiKeyPreviewChars:]
+}
+
+func newProjectResponse(p *Project) ProjectResponse {
+	return ProjectResponse{
+		ID:                  p.ID,
+		TenantID:            p.TenantID,
+		Name:                p.Name,
+		ClientAPIKeyPreview: maskAPIKey(p.ClientAPIKey),
+		ClientSideAPIKey:    p.ClientSideAPIKey,
+		CreatedAt:           p.CreatedAt,
+		UpdatedAt:           p.UpdatedAt,
+	}
+}
+
+func newProjectResponses(projects []Project) []ProjectResponse {
+	out := make([]ProjectResponse, len(projects))
+	for i := range projects {
+		out[i] = newProjectResponse(&projects[i])
+	}
+	return out
+}
+
+func newCreatedProjectResponse(p *Project) CreatedProjectResponse {
+	return CreatedProjectResponse{ProjectResponse: newProjectResponse(p), ClientAPIKey: p.ClientAPIKey}
+}
+
+func newEnvironmentResponse(e *Environment) EnvironmentResponse {
+	return EnvironmentResponse{
+		ID:                  e.ID,
+		TenantID:            e.TenantID,
+		ProjectID:           e.ProjectID,
+		Key:                 e.Key,
+		Name:                e.Name,
+		ClientAPIKeyPreview: maskAPIKey(e.ClientAPIKey),
+		CreatedAt:           e.CreatedAt,
+		UpdatedAt:           e.UpdatedAt,
+	}
+}
+
+func newEnvironmentResponses(environments []Environment) []EnvironmentResponse {
+	out := make([]EnvironmentResponse, len(environments))
+	for i := range environments {
+		out[i] = newEnvironmentResponse(&environments[i])
+	}
+	return out
+}
+
+func newCreatedEnvironmentResponse(e *Environment) CreatedEnvironmentResponse {
+	return CreatedEnvironmentResponse{EnvironmentResponse: newEnvironmentResponse(e), ClientAPIKey: e.ClientAPIKey}
+}
+
+func newAPIKeyResponse(k *APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:             k.ID,
+		Kind:           k.Kind,
+		EnvironmentKey: k.EnvironmentKey,
+		Preview:        maskAPIKey(k.Key),
+	}
+}
+
+func newAPIKeyResponses(keys []APIKey) []APIKeyResponse {
+	out := make([]APIKeyResponse, len(keys))
+	for i := range keys {
+		out[i] = newAPIKeyResponse(&keys[i])
+	}
+	return out
+}
+
+func newRevealedAPIKeyResponse(k *APIKey) RevealedAPIKeyResponse {
+	return RevealedAPIKeyResponse{APIKeyResponse: newAPIKeyResponse(k), ClientAPIKey: k.Key}
+}
@@ -0,0 +1,60 @@
+package projects
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAPIKey = "a3f1c9e27b5d4e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f"
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestProjectResponses_OnlyCreationRevealsAPIKey(t *testing.T) {
+	project := &Project{ID: "project-1", TenantID: "tenant-1", Name: "Web", ClientAPIKey: testAPIKey, CreatedAt: time.Now()}
+
+	assert.NotContains(t, marshal(t, newProjectResponse(project)), testAPIKey)
+	assert.NotContains(t, marshal(t, newProjectResponses([]Project{*project})), testAPIKey)
+	assert.NotContains(t, marshal(t, newProjectResponse(project)), `"client_api_key":`)
+
+	created := marshal(t, newCreatedProjectResponse(project))
+	assert.Contains(t, created, `"client_api_key":"`+testAPIKey+`"`)
+	assert.Contains(t, created, `"id":"project-1"`)
+}
+
+func TestEnvironmentResponses_OnlyCreationRevealsAPIKey(t *testing.T) {
+	env := &Environment{ID: "env-1", TenantID: "tenant-1", ProjectID: "project-1", Key: "production", Name: "Production", ClientAPIKey: testAPIKey}
+
+	assert.NotContains(t, marshal(t, newEnvironmentResponse(env)), testAPIKey)
+	assert.NotContains(t, marshal(t, newEnvironmentResponses([]Environment{*env})), testAPIKey)
+
+	created := marshal(t, newCreatedEnvironmentResponse(env))
+	assert.Contains(t, created, `"client_api_key":"`+testAPIKey+`"`)
+	assert.Contains(t, created, `"key":"production"`)
+}
+
+func TestAPIKeyResponses_ShowPreviewUntilRevealed(t *testing.T) {
+	key := &APIKey{ID: "env-1", Kind: APIKeyKindEnvironment, EnvironmentKey: "production", Key: testAPIKey}
+
+	listed := marshal(t, newAPIKeyResponses([]APIKey{*key}))
+	assert.NotContains(t, listed, testAPIKey)
+	assert.Contains(t, listed, `"preview":"a3f1…1e2f"`)
+
+	revealed := marshal(t, newRevealedAPIKeyResponse(key))
+	assert.Contains(t, revealed, `"client_api_key":"`+testAPIKey+`"`)
+	assert.Contains(t, revealed, `"environment_key":"production"`)
+}
+
+func TestMaskAPIKey(t *testing.T) {
+	assert.Equal(t, "a3f1…1e2f", maskAPIKey(testAPIKey))
+	assert.Equal(t, "…", maskAPIKey("short"))
+	assert.Equal(t, "…", maskAPIKey(""))
+}
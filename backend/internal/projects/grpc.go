@@ -0,0 +1,126 @@
+package projects
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/grpcjson"
+)
+
+// GRPCServiceName is the project service's name on the gRPC management API
+const GRPCServiceName = "toggle.management.v1.ProjectService"
+
+type ListProjectsRequest struct{}
+
+type ListProjectsResponse struct {
+	Projects []ProjectResponse `json:"projects"`
+}
+
+type GetProjectRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+type DeleteProjectRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+type DeleteProjectResponse struct{}
+
+// GRPCServer serves project CRUD on the gRPC management API. Calls must have passed
+// management.GRPCAuth.
+type GRPCServer struct {
+	service *Service
+}
+
+func NewGRPCServer(service *Service) *GRPCServer {
+	return &GRPCServer{service: service}
+}
+
+var projectServiceDesc = grpc.ServiceDesc{
+	ServiceName: GRPCServiceName,
+	HandlerType: (*any)(nil), // grpcjson.Method checks the server's type
+	Methods: []grpc.MethodDesc{
+		grpcjson.Method(GRPCServiceName, "ListProjects", (*GRPCServer).ListProjects),
+		grpcjson.Method(GRPCServiceName, "GetProject", (*GRPCServer).GetProject),
+		grpcjson.Method(GRPCServiceName, "CreateProject", (*GRPCServer).CreateProject),
+		grpcjson.Method(GRPCServiceName, "DeleteProject", (*GRPCServer).DeleteProject),
+	},
+}
+
+func (s *GRPCServer) Register(r grpc.ServiceRegistrar) {
+	r.RegisterService(&projectServiceDesc, s)
+}
+
+func (s *GRPCServer) ListProjects(ctx context.Context, _ *ListProjectsRequest) (*ListProjectsResponse, error) {
+	projects, err := s.service.ListByTenantID(ctx, appContext.MustTenantID(ctx))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list projects")
+	}
+	return &ListProjectsResponse{Projects: newProjectResponses(projects)}, nil
+}
+
+func (s *GRPCServer) GetProject(ctx context.Context, req *GetProjectRequest) (*ProjectResponse, error) {
+	project, err := s.service.GetByID(ctx, req.ID, appContext.MustTenantID(ctx))
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	resp := newProjectResponse(project)
+	return &resp, nil
+}
+
+// CreateProject returns the project's client API key, which is only shown once
+func (s *GRPCServer) CreateProject(ctx context.Context, req *CreateRequest) (*CreatedProjectResponse, error) {
+	project, err := s.service.Create(ctx, appContext.MustTenantID(ctx), appContext.UserRole(ctx), req.Name)
+	if err != nil {
+		if errors.Is(err, ErrInsufficientPermissions) {
+			return nil, status.Error(codes.PermissionDenied, "insufficient permissions")
+		}
+		return nil, status.Error(codes.Internal, "failed to create project")
+	}
+	resp := newCreatedProjectResponse(project)
+	return &resp, nil
+}
+
+func (s *GRPCServer) DeleteProject(ctx context.Context, req *DeleteProjectRequest) (*DeleteProjectResponse, error) {
+	if err := s.service.Delete(ctx, req.ID, appContext.MustTenantID(ctx)); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Error(codes.Internal, "internal server error")
+	}
+	return &DeleteProjectResponse{}, nil
+}
+
+// GRPCClient is a typed client of the project service
+type GRPCClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGRPCClient(cc grpc.ClientConnInterface) *GRPCClient {
+	return &GRPCClient{cc: cc}
+}
+
+func (c *GRPCClient) ListProjects(ctx context.Context, req *ListProjectsRequest, opts ...grpc.CallOption) (*ListProjectsResponse, error) {
+	return grpcjson.Invoke[ListProjectsRequest, ListProjectsResponse](ctx, c.cc, GRPCServiceName, "ListProjects", req, opts...)
+}
+
+func (c *GRPCClient) GetProject(ctx context.Context, req *GetProjectRequest, opts ...grpc.CallOption) (*ProjectResponse, error) {
+	return grpcjson.Invoke[GetProjectRequest, ProjectResponse](ctx, c.cc, GRPCServiceName, "GetProject", req, opts...)
+}
+
+func (c *GRPCClient) CreateProject(ctx context.Context, req *CreateRequest, opts ...grpc.CallOption) (*CreatedProjectResponse, error) {
+	return grpcjson.Invoke[CreateRequest, CreatedProjectResponse](ctx, c.cc, GRPCServiceName, "CreateProject", req, opts...)
+}
+
+func (c *GRPCClient) DeleteProject(ctx context.Context, req *DeleteProjectRequest, opts ...grpc.CallOption) (*DeleteProjectResponse, error) {
+	return grpcjson.Invoke[DeleteProjectRequest, DeleteProjectResponse](ctx, c.cc, GRPCServiceName, "DeleteProject", req, opts...)
+}
@@ -1,6 +1,7 @@
 package projects
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -22,6 +23,19 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/projects", h.List)
 	r.GET("/projects/:id", h.GetByID)
 	r.DELETE("/projects/:id", h.Delete)
+	r.GET("/projects/:id/cache-settings", h.GetCacheSettings)
+	r.PUT("/projects/:id/cache-settings", h.UpdateCacheSettings)
+	r.GET("/projects/:id/bucketing", h.GetBucketing)
+	r.PUT("/projects/:id/bucketing", h.UpdateBucketing)
+	r.GET("/projects/:id/geo-targeting", h.GetGeoTargeting)
+	r.PUT("/projects/:id/geo-targeting", h.UpdateGeoTargeting)
+	r.GET("/projects/:id/default-attributes", h.GetDefaultAttributes)
+	r.PUT("/projects/:id/default-attributes", h.UpdateDefaultAttributes)
+	r.GET("/projects/:id/environments", h.ListEnvironments)
+	r.POST("/projects/:id/environments", h.CreateEnvironment)
+	r.DELETE("/projects/:id/environments/:envID", h.DeleteEnvironment)
+	r.GET("/projects/:id/api-keys", h.ListAPIKeys)
+	r.POST("/projects/:id/api-keys/:keyID/reveal", h.RevealAPIKey)
 }
 
 func (h *Handler) Create(c *gin.Context) {
@@ -32,14 +46,19 @@ func (h *Handler) Create(c *gin.Context) {
 	}
 
 	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
 
-	project, err := h.service.Create(c.Request.Context(), tenantID, req.Name)
+	project, err := h.service.Create(c.Request.Context(), tenantID, role, req.Name)
 	if err != nil {
+		if errors.Is(err, ErrInsufficientPermissions) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, project)
+	c.JSON(http.StatusCreated, newCreatedProjectResponse(project))
 }
 
 func (h *Handler) List(c *gin.Context) {
@@ -51,7 +70,7 @@ func (h *Handler) List(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, projects)
+	c.JSON(http.StatusOK, newProjectResponses(projects))
 }
 
 func (h *Handler) GetByID(c *gin.Context) {
@@ -68,7 +87,7 @@ func (h *Handler) GetByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, project)
+	c.JSON(http.StatusOK, newProjectResponse(project))
 }
 
 func (h *Handler) Delete(c *gin.Context) {
@@ -86,3 +105,272 @@ func (h *Handler) Delete(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+func (h *Handler) GetCacheSettings(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	settings, err := h.service.GetCacheSettings(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) UpdateCacheSettings(c *gin.Context) {
+	var req UpdateCacheSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+
+	settings, err := h.service.UpdateCacheSettings(ctx, c.Param("id"), tenantID, appContext.UserRole(ctx), req)
+	if err != nil {
+		switch {
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		case errors.Is(err, ErrInvalidCacheSettings):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) GetBucketing(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	settings, err := h.service.GetBucketing(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) UpdateBucketing(c *gin.Context) {
+	var req UpdateBucketingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+
+	settings, err := h.service.UpdateBucketing(ctx, c.Param("id"), tenantID, appContext.UserRole(ctx), req)
+	if err != nil {
+		switch {
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		case errors.Is(err, ErrInvalidBucketing):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) GetGeoTargeting(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	settings, err := h.service.GetGeoTargeting(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) UpdateGeoTargeting(c *gin.Context) {
+	var req UpdateGeoTargetingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+
+	settings, err := h.service.UpdateGeoTargeting(ctx, c.Param("id"), tenantID, appContext.UserRole(ctx), req)
+	if err != nil {
+		switch {
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) GetDefaultAttributes(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	defaults, err := h.service.GetDefaultAttributes(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, defaults)
+}
+
+func (h *Handler) UpdateDefaultAttributes(c *gin.Context) {
+	var req UpdateDefaultAttributesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+
+	defaults, err := h.service.UpdateDefaultAttributes(ctx, c.Param("id"), tenantID, appContext.UserRole(ctx), req)
+	if err != nil {
+		switch {
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		case errors.Is(err, ErrInvalidDefaults):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, defaults)
+}
+
+func (h *Handler) ListEnvironments(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	environments, err := h.service.ListEnvironments(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newEnvironmentResponses(environments))
+}
+
+func (h *Handler) CreateEnvironment(c *gin.Context) {
+	var req CreateEnvironmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	env, err := h.service.CreateEnvironment(c.Request.Context(), c.Param("id"), tenantID, req)
+	if err != nil {
+		switch {
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		case errors.Is(err, ErrInvalidEnvironment):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrDuplicateEnvironment):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCreatedEnvironmentResponse(env))
+}
+
+func (h *Handler) DeleteEnvironment(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	if err := h.service.DeleteEnvironment(c.Request.Context(), c.Param("envID"), c.Param("id"), tenantID); err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	keys, err := h.service.ListAPIKeys(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newAPIKeyResponses(keys))
+}
+
+func (h *Handler) RevealAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+	userID := appContext.MustUserID(ctx)
+
+	key, err := h.service.RevealAPIKey(ctx, c.Param("id"), c.Param("keyID"), tenantID, userID, appContext.UserRole(ctx))
+	if err != nil {
+		switch {
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newRevealedAPIKeyResponse(key))
+}
@@ -3,14 +3,123 @@ package projects
 import "time"
 
 type Project struct {
+	ID           string `json:"id" db:"id"`
+	TenantID     string `json:"tenant_id" db:"tenant_id"`
+	Name         string `json:"name" db:"name"`
+	ClientAPIKey string `json:"client_api_key" db:"client_api_key"`
+	// ClientSideAPIKey is the public key browser and mobile SDKs evaluate flags with; it
+	// can't read the project's rules
+	ClientSideAPIKey string    `json:"client_side_api_key" db:"client_side_api_key"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MaxCacheStalenessSeconds is the longest a project may let bulk evaluation serve cached
+// flags without checking they are current
+const MaxCacheStalenessSeconds = 300
+
+// CacheSettings trade flag freshness on /sdk/evaluate for fewer Postgres reads
+type CacheSettings struct {
+	ProjectID string `json:"project_id" db:"id"`
+	// MaxStalenessSeconds is how long cached flags are served without revalidation; 0 always revalidates
+	MaxStalenessSeconds int `json:"max_staleness_seconds" db:"cache_max_staleness_seconds"`
+}
+
+type UpdateCacheSettingsRequest struct {
+	MaxStalenessSeconds *int `json:"max_staleness_seconds" binding:"required"`
+}
+
+// BucketingSettings choose how a project's users are assigned rollout buckets
+type BucketingSettings struct {
+	ProjectID string `json:"project_id" db:"id"`
+	// Algorithm is one of evaluation.BucketingAlgorithms
+	Algorithm string `json:"algorithm" db:"bucketing"`
+}
+
+type UpdateBucketingRequest struct {
+	Algorithm string `json:"algorithm" binding:"required"`
+}
+
+// GeoTargetingSettings choose whether SDK evaluations fill in missing country and region
+// attributes from the caller's IP. They need a GeoIP database (GEOIP_DATABASE) to have effect.
+type GeoTargetingSettings struct {
+	ProjectID string `json:"project_id" db:"id"`
+	Enabled   bool   `json:"enabled" db:"geo_targeting"`
+}
+
+type UpdateGeoTargetingRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// Limits on a project's default attributes
+const (
+	MaxDefaultAttributes          = 50
+	MaxDefaultAttributeNameLength = 128
+)
+
+// DefaultAttributes are merged into the user attributes of every SDK evaluation context in
+// the project, such as environment: production. Attributes the SDK sends take precedence.
+type DefaultAttributes struct {
+	ProjectID  string                 `json:"project_id"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+type UpdateDefaultAttributesRequest struct {
+	Attributes map[string]interface{} `json:"attributes" binding:"required"`
+}
+
+// Environment is a deployment stage of a project. SDKs authenticated with its key
+// evaluate flags with the environment's config instead of the flag's own.
+type Environment struct {
 	ID           string    `json:"id" db:"id"`
 	TenantID     string    `json:"tenant_id" db:"tenant_id"`
+	ProjectID    string    `json:"project_id" db:"project_id"`
+	Key          string    `json:"key" db:"key"`
 	Name         string    `json:"name" db:"name"`
 	ClientAPIKey string    `json:"client_api_key" db:"client_api_key"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
-type CreateRequest struct {
+// DefaultEnvironments are created with every project, as key -> name
+var DefaultEnvironments = []struct{ Key, Name string }{
+	{"development", "Development"},
+	{"staging", "Staging"},
+	{"production", "Production"},
+}
+
+// MaxEnvironmentsPerProject bounds how many environments a project can have
+const MaxEnvironmentsPerProject = 20
+
+type CreateEnvironmentRequest struct {
+	Key  string `json:"key" binding:"required"`
 	Name string `json:"name" binding:"required"`
 }
+
+// Kinds of SDK API key
+const (
+	APIKeyKindProject     = "project"
+	APIKeyKindEnvironment = "environment"
+)
+
+// APIKey is one of a project's SDK keys
+type APIKey struct {
+	// ID is the project ID for the project's own key, or the environment ID
+	ID             string
+	Kind           string
+	EnvironmentKey string
+	Key            string
+}
+
+// APIKeyReveal records a user being shown a full SDK key after its creation
+type APIKeyReveal struct {
+	TenantID   string    `db:"tenant_id"`
+	ProjectID  string    `db:"project_id"`
+	KeyID      string    `db:"key_id"`
+	RevealedBy string    `db:"revealed_by"`
+	RevealedAt time.Time `db:"revealed_at"`
+}
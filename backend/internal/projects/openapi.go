@@ -0,0 +1,30 @@
+package projects
+
+import (
+	"net/http"
+
+	"github.com/jalil32/toggle/internal/pkg/openapi"
+)
+
+// Operations documents the routes RegisterRoutes serves, for the OpenAPI document
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: http.MethodPost, Path: "/projects", Summary: "Create a project and its API key", Request: CreateRequest{}, Response: CreatedProjectResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/projects", Summary: "List the tenant's projects", Response: []ProjectResponse{}},
+		{Method: http.MethodGet, Path: "/projects/:id", Summary: "Get a project", Response: ProjectResponse{}},
+		{Method: http.MethodDelete, Path: "/projects/:id", Summary: "Delete a project", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/projects/:id/cache-settings", Summary: "Get a project's evaluation cache settings", Response: CacheSettings{}},
+		{Method: http.MethodPut, Path: "/projects/:id/cache-settings", Summary: "Update a project's evaluation cache settings", Request: UpdateCacheSettingsRequest{}, Response: CacheSettings{}},
+		{Method: http.MethodGet, Path: "/projects/:id/bucketing", Summary: "Get a project's bucketing algorithm", Response: BucketingSettings{}},
+		{Method: http.MethodPut, Path: "/projects/:id/bucketing", Summary: "Change a project's bucketing algorithm", Request: UpdateBucketingRequest{}, Response: BucketingSettings{}},
+		{Method: http.MethodGet, Path: "/projects/:id/geo-targeting", Summary: "Get whether a project fills in location attributes", Response: GeoTargetingSettings{}},
+		{Method: http.MethodPut, Path: "/projects/:id/geo-targeting", Summary: "Turn a project's geo targeting on or off", Request: UpdateGeoTargetingRequest{}, Response: GeoTargetingSettings{}},
+		{Method: http.MethodGet, Path: "/projects/:id/default-attributes", Summary: "Get the attributes merged into every context", Response: DefaultAttributes{}},
+		{Method: http.MethodPut, Path: "/projects/:id/default-attributes", Summary: "Replace the attributes merged into every context", Request: UpdateDefaultAttributesRequest{}, Response: DefaultAttributes{}},
+		{Method: http.MethodGet, Path: "/projects/:id/environments", Summary: "List a project's environments", Response: []EnvironmentResponse{}},
+		{Method: http.MethodPost, Path: "/projects/:id/environments", Summary: "Create an environment and its API key", Request: CreateEnvironmentRequest{}, Response: CreatedEnvironmentResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/projects/:id/environments/:envID", Summary: "Delete an environment", Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/projects/:id/api-keys", Summary: "List a project's API keys, masked", Response: []APIKeyResponse{}},
+		{Method: http.MethodPost, Path: "/projects/:id/api-keys/:keyID/reveal", Summary: "Reveal an API key in full; the reveal is audited", Response: RevealedAPIKeyResponse{}},
+	}
+}
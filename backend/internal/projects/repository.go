@@ -5,7 +5,10 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
+	"time"
 
+	"github.com/jalil32/toggle/internal/evaluation"
 	"github.com/jalil32/toggle/internal/pkg/transaction"
 	"github.com/jmoiron/sqlx"
 )
@@ -14,8 +17,29 @@ type Repository interface {
 	Create(ctx context.Context, tenantID, name string) (*Project, error)
 	GetByID(ctx context.Context, id string, tenantID string) (*Project, error)
 	GetByAPIKey(ctx context.Context, apiKey string) (*Project, error)
+	// GetByClientSideAPIKey returns the project a client-side SDK key belongs to
+	GetByClientSideAPIKey(ctx context.Context, apiKey string) (*Project, error)
 	ListByTenantID(ctx context.Context, tenantID string) ([]Project, error)
+	GetGeneration(ctx context.Context, id string, tenantID string) (int64, error)
+	GetCacheState(ctx context.Context, id string, tenantID string) (int64, time.Duration, error)
+	GetCacheSettings(ctx context.Context, id string, tenantID string) (*CacheSettings, error)
+	UpdateCacheSettings(ctx context.Context, settings *CacheSettings, tenantID string) error
+	GetBucketing(ctx context.Context, id string, tenantID string) (string, error)
+	GetEvaluationSettings(ctx context.Context, id string, tenantID string) (*evaluation.ProjectSettings, error)
+	GetSDKConfig(ctx context.Context, tenantID string) (*evaluation.SDKConfig, error)
+	UpdateBucketing(ctx context.Context, settings *BucketingSettings, tenantID string) error
+	GetGeoTargeting(ctx context.Context, id string, tenantID string) (bool, error)
+	UpdateGeoTargeting(ctx context.Context, settings *GeoTargetingSettings, tenantID string) error
+	GetDefaultAttributes(ctx context.Context, id string, tenantID string) (map[string]interface{}, error)
+	UpdateDefaultAttributes(ctx context.Context, defaults *DefaultAttributes, tenantID string) error
 	Delete(ctx context.Context, id string, tenantID string) error
+	CreateEnvironment(ctx context.Context, tenantID, projectID, key, name string) (*Environment, error)
+	ListEnvironments(ctx context.Context, projectID string, tenantID string) ([]Environment, error)
+	DeleteEnvironment(ctx context.Context, id string, projectID string, tenantID string) error
+	GetByEnvironmentAPIKey(ctx context.Context, apiKey string) (*Project, *Environment, error)
+	GetEnvironment(ctx context.Context, id string, projectID string, tenantID string) (*Environment, error)
+	// RecordAPIKeyReveal adds a reveal to the audit log, setting its time
+	RecordAPIKeyReveal(ctx context.Context, reveal *APIKeyReveal) error
 }
 
 type postgresRepo struct {
@@ -44,7 +68,7 @@ func (r *postgresRepo) Create(ctx context.Context, tenantID, name string) (*Proj
 	err = r.getDB(ctx).QueryRowxContext(ctx, `
 		INSERT INTO projects (tenant_id, name, client_api_key)
 		VALUES ($1, $2, $3)
-		RETURNING id, tenant_id, name, client_api_key, created_at, updated_at
+		RETURNING id, tenant_id, name, client_api_key, client_side_api_key, created_at, updated_at
 	`, tenantID, name, apiKey).StructScan(&project)
 	if err != nil {
 		return nil, err
@@ -57,7 +81,7 @@ func (r *postgresRepo) GetByID(ctx context.Context, id string, tenantID string)
 	executor := r.getDB(ctx)
 
 	err := sqlx.GetContext(ctx, executor, &project, `
-		SELECT id, tenant_id, name, client_api_key, created_at, updated_at
+		SELECT id, tenant_id, name, client_api_key, client_side_api_key, created_at, updated_at
 		FROM projects WHERE id = $1 AND tenant_id = $2
 	`, id, tenantID)
 	if err != nil {
@@ -71,7 +95,7 @@ func (r *postgresRepo) GetByAPIKey(ctx context.Context, apiKey string) (*Project
 	executor := r.getDB(ctx)
 
 	err := sqlx.GetContext(ctx, executor, &project, `
-		SELECT id, tenant_id, name, client_api_key, created_at, updated_at
+		SELECT id, tenant_id, name, client_api_key, client_side_api_key, created_at, updated_at
 		FROM projects WHERE client_api_key = $1
 	`, apiKey)
 	if err != nil {
@@ -80,12 +104,26 @@ func (r *postgresRepo) GetByAPIKey(ctx context.Context, apiKey string) (*Project
 	return &project, nil
 }
 
+func (r *postgresRepo) GetByClientSideAPIKey(ctx context.Context, apiKey string) (*Project, error) {
+	var project Project
+	executor := r.getDB(ctx)
+
+	err := sqlx.GetContext(ctx, executor, &project, `
+		SELECT id, tenant_id, name, client_api_key, client_side_api_key, created_at, updated_at
+		FROM projects WHERE client_side_api_key = $1
+	`, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
 func (r *postgresRepo) ListByTenantID(ctx context.Context, tenantID string) ([]Project, error) {
 	projects := []Project{} // Initialize as empty slice instead of nil
 	executor := r.getDB(ctx)
 
 	err := sqlx.SelectContext(ctx, executor, &projects, `
-		SELECT id, tenant_id, name, client_api_key, created_at, updated_at
+		SELECT id, tenant_id, name, client_api_key, client_side_api_key, created_at, updated_at
 		FROM projects WHERE tenant_id = $1
 		ORDER BY created_at DESC
 	`, tenantID)
@@ -95,6 +133,216 @@ func (r *postgresRepo) ListByTenantID(ctx context.Context, tenantID string) ([]P
 	return projects, nil
 }
 
+// GetGeneration returns the project's flag generation counter (bumped by trigger on every flag change)
+func (r *postgresRepo) GetGeneration(ctx context.Context, id string, tenantID string) (int64, error) {
+	var generation int64
+	executor := r.getDB(ctx)
+
+	err := sqlx.GetContext(ctx, executor, &generation, `
+		SELECT generation FROM projects WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return generation, nil
+}
+
+// GetCacheState returns the project's generation with how long bulk evaluation may serve
+// cached flags without reading the generation again
+func (r *postgresRepo) GetCacheState(ctx context.Context, id string, tenantID string) (int64, time.Duration, error) {
+	var state struct {
+		Generation   int64 `db:"generation"`
+		MaxStaleness int   `db:"cache_max_staleness_seconds"`
+	}
+
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &state, `
+		SELECT generation, cache_max_staleness_seconds FROM projects WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return state.Generation, time.Duration(state.MaxStaleness) * time.Second, nil
+}
+
+func (r *postgresRepo) GetCacheSettings(ctx context.Context, id string, tenantID string) (*CacheSettings, error) {
+	var settings CacheSettings
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &settings, `
+		SELECT id, cache_max_staleness_seconds FROM projects WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateCacheSettings stores a project's cache settings; sql.ErrNoRows if the project isn't in the tenant
+func (r *postgresRepo) UpdateCacheSettings(ctx context.Context, settings *CacheSettings, tenantID string) error {
+	result, err := r.getDB(ctx).ExecContext(ctx, `
+		UPDATE projects SET cache_max_staleness_seconds = $3, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`, settings.ProjectID, tenantID, settings.MaxStalenessSeconds)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetBucketing returns the project's bucketing algorithm
+func (r *postgresRepo) GetBucketing(ctx context.Context, id string, tenantID string) (string, error) {
+	var bucketing string
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &bucketing, `
+		SELECT bucketing FROM projects WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		return "", err
+	}
+	return bucketing, nil
+}
+
+// GetEvaluationSettings returns the settings evaluation applies to the project. Its bucketing
+// is the project's algorithm, in its strict variant when the tenant has opted into strict rollouts.
+func (r *postgresRepo) GetEvaluationSettings(ctx context.Context, id string, tenantID string) (*evaluation.ProjectSettings, error) {
+	var row struct {
+		Bucketing         string `db:"bucketing"`
+		StrictRollouts    bool   `db:"strict_rollouts"`
+		GeoTargeting      bool   `db:"geo_targeting"`
+		DefaultAttributes []byte `db:"default_attributes"`
+	}
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &row, `
+		SELECT p.bucketing, t.strict_rollouts, p.geo_targeting, p.default_attributes
+		FROM projects p
+		JOIN tenants t ON t.id = p.tenant_id
+		WHERE p.id = $1 AND p.tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	settings := &evaluation.ProjectSettings{Bucketing: row.Bucketing, GeoTargeting: row.GeoTargeting}
+	if err := json.Unmarshal(row.DefaultAttributes, &settings.DefaultAttributes); err != nil {
+		return nil, err
+	}
+	if row.StrictRollouts {
+		settings.Bucketing = evaluation.StrictBucketing(row.Bucketing)
+	}
+	return settings, nil
+}
+
+// GetSDKConfig returns what the tenant's SDKs are told by GET /sdk/config. The tenant's
+// settings are managed by the tenants package.
+func (r *postgresRepo) GetSDKConfig(ctx context.Context, tenantID string) (*evaluation.SDKConfig, error) {
+	cfg := evaluation.SDKConfig{PayloadVersion: evaluation.PayloadVersion}
+	err := r.getDB(ctx).QueryRowxContext(ctx, `
+		SELECT sdk_polling_interval_seconds, sdk_streaming_enabled FROM tenants WHERE id = $1
+	`, tenantID).Scan(&cfg.PollingIntervalSeconds, &cfg.StreamingAvailable)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// UpdateBucketing stores a project's bucketing algorithm and bumps its generation, since
+// cached snapshots carry the algorithm; sql.ErrNoRows if the project isn't in the tenant
+func (r *postgresRepo) UpdateBucketing(ctx context.Context, settings *BucketingSettings, tenantID string) error {
+	result, err := r.getDB(ctx).ExecContext(ctx, `
+		UPDATE projects SET bucketing = $3, generation = generation + 1, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`, settings.ProjectID, tenantID, settings.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetGeoTargeting reports whether the project fills in country and region from caller IPs
+func (r *postgresRepo) GetGeoTargeting(ctx context.Context, id string, tenantID string) (bool, error) {
+	var enabled bool
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &enabled, `
+		SELECT geo_targeting FROM projects WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
+
+// UpdateGeoTargeting switches a project's geo targeting and bumps its generation, since
+// cached snapshots carry the setting; sql.ErrNoRows if the project isn't in the tenant
+func (r *postgresRepo) UpdateGeoTargeting(ctx context.Context, settings *GeoTargetingSettings, tenantID string) error {
+	result, err := r.getDB(ctx).ExecContext(ctx, `
+		UPDATE projects SET geo_targeting = $3, generation = generation + 1, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`, settings.ProjectID, tenantID, settings.Enabled)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDefaultAttributes returns the attributes merged into the project's evaluation contexts
+func (r *postgresRepo) GetDefaultAttributes(ctx context.Context, id string, tenantID string) (map[string]interface{}, error) {
+	var raw []byte
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &raw, `
+		SELECT default_attributes FROM projects WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &attributes); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// UpdateDefaultAttributes replaces a project's default attributes and bumps its generation,
+// since cached snapshots carry them; sql.ErrNoRows if the project isn't in the tenant
+func (r *postgresRepo) UpdateDefaultAttributes(ctx context.Context, defaults *DefaultAttributes, tenantID string) error {
+	raw, err := json.Marshal(defaults.Attributes)
+	if err != nil {
+		return err
+	}
+	result, err := r.getDB(ctx).ExecContext(ctx, `
+		UPDATE projects SET default_attributes = $3, generation = generation + 1, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+	`, defaults.ProjectID, tenantID, raw)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (r *postgresRepo) Delete(ctx context.Context, id string, tenantID string) error {
 	result, err := r.getDB(ctx).ExecContext(ctx, `
 		DELETE FROM projects WHERE id = $1 AND tenant_id = $2
@@ -115,6 +363,107 @@ func (r *postgresRepo) Delete(ctx context.Context, id string, tenantID string) e
 	return nil
 }
 
+// CreateEnvironment adds an environment to a project with a fresh SDK key.
+// Returns sql.ErrNoRows if the project does not exist in the tenant.
+func (r *postgresRepo) CreateEnvironment(ctx context.Context, tenantID, projectID, key, name string) (*Environment, error) {
+	apiKey, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var env Environment
+	err = sqlx.GetContext(ctx, r.getDB(ctx), &env, `
+		INSERT INTO environments (tenant_id, project_id, key, name, client_api_key)
+		SELECT p.tenant_id, p.id, $3, $4, $5
+		FROM projects p
+		WHERE p.id = $2 AND p.tenant_id = $1
+		RETURNING id, tenant_id, project_id, key, name, client_api_key, created_at, updated_at
+	`, tenantID, projectID, key, name, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// ListEnvironments returns a project's environments, oldest first
+func (r *postgresRepo) ListEnvironments(ctx context.Context, projectID string, tenantID string) ([]Environment, error) {
+	environments := []Environment{}
+
+	err := sqlx.SelectContext(ctx, r.getDB(ctx), &environments, `
+		SELECT id, tenant_id, project_id, key, name, client_api_key, created_at, updated_at
+		FROM environments WHERE project_id = $1 AND tenant_id = $2
+		ORDER BY created_at ASC, key ASC
+	`, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return environments, nil
+}
+
+// DeleteEnvironment removes an environment and its flag configs
+// Returns sql.ErrNoRows if the project has no such environment
+func (r *postgresRepo) DeleteEnvironment(ctx context.Context, id string, projectID string, tenantID string) error {
+	result, err := r.getDB(ctx).ExecContext(ctx, `
+		DELETE FROM environments WHERE id = $1 AND project_id = $2 AND tenant_id = $3
+	`, id, projectID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetByEnvironmentAPIKey returns the environment an SDK key belongs to and its project
+func (r *postgresRepo) GetByEnvironmentAPIKey(ctx context.Context, apiKey string) (*Project, *Environment, error) {
+	var env Environment
+	executor := r.getDB(ctx)
+
+	err := sqlx.GetContext(ctx, executor, &env, `
+		SELECT id, tenant_id, project_id, key, name, client_api_key, created_at, updated_at
+		FROM environments WHERE client_api_key = $1
+	`, apiKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	project, err := r.GetByID(ctx, env.ProjectID, env.TenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return project, &env, nil
+}
+
+// GetEnvironment returns one of a project's environments
+// Returns sql.ErrNoRows if the project has no such environment
+func (r *postgresRepo) GetEnvironment(ctx context.Context, id string, projectID string, tenantID string) (*Environment, error) {
+	var env Environment
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &env, `
+		SELECT id, tenant_id, project_id, key, name, client_api_key, created_at, updated_at
+		FROM environments WHERE id = $1 AND project_id = $2 AND tenant_id = $3
+	`, id, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+func (r *postgresRepo) RecordAPIKeyReveal(ctx context.Context, reveal *APIKeyReveal) error {
+	return r.getDB(ctx).QueryRowxContext(ctx, `
+		INSERT INTO api_key_reveals (tenant_id, project_id, key_id, revealed_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING revealed_at
+	`, reveal.TenantID, reveal.ProjectID, reveal.KeyID, reveal.RevealedBy).Scan(&reveal.RevealedAt)
+}
+
 func generateAPIKey() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
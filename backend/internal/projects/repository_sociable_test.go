@@ -3,9 +3,12 @@ package projects_test
 import (
 	"context"
 	"database/sql"
+	"io"
+	"log/slog"
 	"os"
 	"testing"
 
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
 	"github.com/jalil32/toggle/internal/pkg/transaction"
 	"github.com/jalil32/toggle/internal/projects"
 	"github.com/jalil32/toggle/internal/testutil"
@@ -227,3 +230,175 @@ func TestRepository_ListByTenantID_EmptyTenant(t *testing.T) {
 		assert.Len(t, projects, 0, "Should have zero projects")
 	})
 }
+
+// TestRepository_GetGeneration_BumpsOnFlagChanges tests that the generation
+// counter advances on every flag write and is scoped to the tenant
+func TestRepository_GetGeneration_BumpsOnFlagChanges(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		otherTenant := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+		project := testutil.CreateProject(t, tx, tenant.ID, "Project 1", "api-key-1")
+		otherProject := testutil.CreateProject(t, tx, tenant.ID, "Project 2", "api-key-2")
+
+		repo := projects.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		start, err := repo.GetGeneration(ctx, project.ID, tenant.ID)
+		require.NoError(t, err)
+
+		// Insert bumps the owning project
+		flag := testutil.CreateFlag(t, tx, tenant.ID, &project.ID, "flag-1", "", false)
+		afterInsert, err := repo.GetGeneration(ctx, project.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Greater(t, afterInsert, start)
+
+		// Update bumps the owning project
+		_, err = tx.ExecContext(ctx, `UPDATE flags SET enabled = true WHERE id = $1`, flag.ID)
+		require.NoError(t, err)
+		afterUpdate, err := repo.GetGeneration(ctx, project.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Greater(t, afterUpdate, afterInsert)
+
+		// Moving the flag bumps both projects
+		otherStart, err := repo.GetGeneration(ctx, otherProject.ID, tenant.ID)
+		require.NoError(t, err)
+		_, err = tx.ExecContext(ctx, `UPDATE flags SET project_id = $1 WHERE id = $2`, otherProject.ID, flag.ID)
+		require.NoError(t, err)
+		afterMove, err := repo.GetGeneration(ctx, project.ID, tenant.ID)
+		require.NoError(t, err)
+		otherAfterMove, err := repo.GetGeneration(ctx, otherProject.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Greater(t, afterMove, afterUpdate)
+		assert.Greater(t, otherAfterMove, otherStart)
+
+		// Delete bumps the project the flag was in
+		_, err = tx.ExecContext(ctx, `DELETE FROM flags WHERE id = $1`, flag.ID)
+		require.NoError(t, err)
+		otherAfterDelete, err := repo.GetGeneration(ctx, otherProject.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Greater(t, otherAfterDelete, otherAfterMove)
+
+		// Another tenant cannot read the generation
+		_, err = repo.GetGeneration(ctx, project.ID, otherTenant.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+// TestRepository_Environments_KeysResolveToTheirProject tests that environment SDK keys
+// authenticate as their project and that environments are tenant scoped
+func TestRepository_Environments_KeysResolveToTheirProject(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+		project := testutil.CreateProject(t, tx, tenant1.ID, "Project 1", "api-key-1")
+
+		repo := projects.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		env, err := repo.CreateEnvironment(ctx, tenant1.ID, project.ID, "staging", "Staging")
+		require.NoError(t, err)
+		assert.Len(t, env.ClientAPIKey, 64)
+		assert.NotEqual(t, project.ClientAPIKey, env.ClientAPIKey)
+
+		// Another tenant can't add environments to the project
+		_, err = repo.CreateEnvironment(ctx, tenant2.ID, project.ID, "qa", "QA")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		resolved, resolvedEnv, err := repo.GetByEnvironmentAPIKey(ctx, env.ClientAPIKey)
+		require.NoError(t, err)
+		assert.Equal(t, project.ID, resolved.ID)
+		assert.Equal(t, env.ID, resolvedEnv.ID)
+
+		_, _, err = repo.GetByEnvironmentAPIKey(ctx, project.ClientAPIKey)
+		assert.ErrorIs(t, err, sql.ErrNoRows, "project keys aren't environment keys")
+
+		environments, err := repo.ListEnvironments(ctx, project.ID, tenant2.ID)
+		require.NoError(t, err)
+		assert.Empty(t, environments)
+
+		assert.ErrorIs(t, repo.DeleteEnvironment(ctx, env.ID, project.ID, tenant2.ID), sql.ErrNoRows)
+		require.NoError(t, repo.DeleteEnvironment(ctx, env.ID, project.ID, tenant1.ID))
+
+		_, _, err = repo.GetByEnvironmentAPIKey(ctx, env.ClientAPIKey)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestService_RevealAPIKey_RequiresAdminAndIsAudited(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant1 := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		tenant2 := testutil.CreateTenant(t, tx, "Tenant 2", "tenant-2")
+		user := testutil.CreateUser(t, tx, "Admin", "admin@example.com")
+		project := testutil.CreateProject(t, tx, tenant1.ID, "Project 1", "api-key-1")
+
+		repo := projects.NewRepository(testutil.GetTestDB())
+		service := projects.NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		ctx = transaction.InjectTx(ctx, tx)
+
+		env, err := repo.CreateEnvironment(ctx, tenant1.ID, project.ID, "staging", "Staging")
+		require.NoError(t, err)
+
+		keys, err := service.ListAPIKeys(ctx, project.ID, tenant1.ID)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, projects.APIKeyKindProject, keys[0].Kind)
+		assert.Equal(t, env.ID, keys[1].ID)
+
+		_, err = service.RevealAPIKey(ctx, project.ID, env.ID, tenant1.ID, user.ID, "member")
+		assert.ErrorIs(t, err, projects.ErrInsufficientPermissions)
+
+		_, err = service.RevealAPIKey(ctx, project.ID, env.ID, tenant2.ID, user.ID, "owner")
+		assert.True(t, pkgErrors.IsNotFoundError(err), "other tenants can't reveal the key")
+
+		key, err := service.RevealAPIKey(ctx, project.ID, env.ID, tenant1.ID, user.ID, "admin")
+		require.NoError(t, err)
+		assert.Equal(t, env.ClientAPIKey, key.Key)
+
+		key, err = service.RevealAPIKey(ctx, project.ID, project.ID, tenant1.ID, user.ID, "owner")
+		require.NoError(t, err)
+		assert.Equal(t, "api-key-1", key.Key)
+
+		var reveals int
+		require.NoError(t, tx.GetContext(ctx, &reveals,
+			`SELECT COUNT(*) FROM api_key_reveals WHERE project_id = $1 AND revealed_by = $2`, project.ID, user.ID))
+		assert.Equal(t, 2, reveals)
+	})
+}
+
+func TestService_DefaultAttributes_ReachEvaluationSettings(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant := testutil.CreateTenant(t, tx, "Tenant 1", "tenant-1")
+		project := testutil.CreateProject(t, tx, tenant.ID, "Project 1", "api-key-1")
+
+		repo := projects.NewRepository(testutil.GetTestDB())
+		service := projects.NewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		ctx = transaction.InjectTx(ctx, tx)
+
+		defaults, err := service.GetDefaultAttributes(ctx, project.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Empty(t, defaults.Attributes)
+
+		before, err := repo.GetGeneration(ctx, project.ID, tenant.ID)
+		require.NoError(t, err)
+
+		req := projects.UpdateDefaultAttributesRequest{Attributes: map[string]interface{}{"environment": "production", "tier": float64(2)}}
+		_, err = service.UpdateDefaultAttributes(ctx, project.ID, tenant.ID, "member", req)
+		assert.ErrorIs(t, err, projects.ErrInsufficientPermissions)
+
+		_, err = service.UpdateDefaultAttributes(ctx, project.ID, tenant.ID, "admin", projects.UpdateDefaultAttributesRequest{
+			Attributes: map[string]interface{}{"regions": []interface{}{"eu"}},
+		})
+		assert.ErrorIs(t, err, projects.ErrInvalidDefaults)
+
+		_, err = service.UpdateDefaultAttributes(ctx, project.ID, tenant.ID, "admin", req)
+		require.NoError(t, err)
+
+		settings, err := repo.GetEvaluationSettings(ctx, project.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, req.Attributes, settings.DefaultAttributes)
+
+		after, err := repo.GetGeneration(ctx, project.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Greater(t, after, before, "cached snapshots carry the defaults")
+	})
+}
@@ -4,14 +4,42 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
+	"regexp"
+	"strings"
 
+	"github.com/lib/pq"
+
+	"github.com/jalil32/toggle/internal/evaluation"
 	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+var (
+	// ErrInsufficientPermissions indicates the tenant's policies don't let the member's role do this
+	ErrInsufficientPermissions = errors.New("insufficient permissions")
+	ErrInvalidEnvironment      = errors.New("invalid environment")
+	ErrDuplicateEnvironment    = errors.New("environment key already exists in project")
+	ErrInvalidCacheSettings    = errors.New("invalid cache settings")
+	ErrInvalidBucketing        = errors.New("invalid bucketing algorithm")
+	ErrInvalidDefaults         = errors.New("invalid default attributes")
 )
 
+// environmentKeyPattern matches environment keys such as "production" or "qa-eu"
+var environmentKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// PolicyChecker defines the minimal interface needed from the tenants package
+// This avoids circular dependency with tenants package
+type PolicyChecker interface {
+	CanCreateProject(ctx context.Context, tenantID, role string) (bool, error)
+}
+
 type Service struct {
-	repo   Repository
-	logger *slog.Logger
+	repo     Repository
+	policies PolicyChecker
+	uow      transaction.UnitOfWork
+	logger   *slog.Logger
 }
 
 func NewService(repo Repository, logger *slog.Logger) *Service {
@@ -21,8 +49,53 @@ func NewService(repo Repository, logger *slog.Logger) *Service {
 	}
 }
 
-func (s *Service) Create(ctx context.Context, tenantID, name string) (*Project, error) {
-	project, err := s.repo.Create(ctx, tenantID, name)
+// SetPolicies sets the tenant policy checker (called after service initialization to avoid circular dependency)
+func (s *Service) SetPolicies(policies PolicyChecker) {
+	s.policies = policies
+}
+
+// SetUnitOfWork sets the unit of work that creates a project together with its environments
+func (s *Service) SetUnitOfWork(uow transaction.UnitOfWork) {
+	s.uow = uow
+}
+
+// Create creates a project and its default environments if the tenant's policies allow the member's role to
+func (s *Service) Create(ctx context.Context, tenantID, role, name string) (*Project, error) {
+	if s.policies != nil {
+		allowed, err := s.policies.CanCreateProject(ctx, tenantID, role)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			s.logger.Warn("project creation denied by tenant policy",
+				slog.String("tenant_id", tenantID),
+				slog.String("role", role),
+			)
+			return nil, ErrInsufficientPermissions
+		}
+	}
+
+	var project *Project
+	write := func(ctx context.Context) error {
+		var err error
+		project, err = s.repo.Create(ctx, tenantID, name)
+		if err != nil {
+			return err
+		}
+		for _, env := range DefaultEnvironments {
+			if _, err := s.repo.CreateEnvironment(ctx, tenantID, project.ID, env.Key, env.Name); err != nil {
+				return fmt.Errorf("create %s environment: %w", env.Key, err)
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if s.uow != nil {
+		err = s.uow.RunInTransaction(ctx, write)
+	} else {
+		err = write(ctx)
+	}
 	if err != nil {
 		s.logger.Error("failed to create project",
 			slog.String("tenant_id", tenantID),
@@ -98,3 +171,371 @@ func (s *Service) Delete(ctx context.Context, id string, tenantID string) error
 
 	return nil
 }
+
+// GetCacheSettings returns how stale a project's cached flags may be on /sdk/evaluate
+func (s *Service) GetCacheSettings(ctx context.Context, id string, tenantID string) (*CacheSettings, error) {
+	settings, err := s.repo.GetCacheSettings(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get project cache settings",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpdateCacheSettings changes how stale a project's cached flags may be; owners and admins only.
+// Instances pick up the new tolerance the next time they revalidate the project.
+func (s *Service) UpdateCacheSettings(ctx context.Context, id string, tenantID string, role string, req UpdateCacheSettingsRequest) (*CacheSettings, error) {
+	if role != "owner" && role != "admin" {
+		return nil, ErrInsufficientPermissions
+	}
+	if req.MaxStalenessSeconds == nil || *req.MaxStalenessSeconds < 0 || *req.MaxStalenessSeconds > MaxCacheStalenessSeconds {
+		return nil, fmt.Errorf("%w: max_staleness_seconds must be between 0 and %d", ErrInvalidCacheSettings, MaxCacheStalenessSeconds)
+	}
+
+	settings := &CacheSettings{ProjectID: id, MaxStalenessSeconds: *req.MaxStalenessSeconds}
+	if err := s.repo.UpdateCacheSettings(ctx, settings, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to update project cache settings",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("project cache settings updated",
+		slog.String("id", id),
+		slog.Int("max_staleness_seconds", settings.MaxStalenessSeconds),
+		slog.String("tenant_id", tenantID),
+	)
+	return settings, nil
+}
+
+// GetBucketing returns how a project's users are assigned rollout buckets
+func (s *Service) GetBucketing(ctx context.Context, id string, tenantID string) (*BucketingSettings, error) {
+	algorithm, err := s.repo.GetBucketing(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get project bucketing",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return &BucketingSettings{ProjectID: id, Algorithm: algorithm}, nil
+}
+
+// UpdateBucketing switches a project's bucketing algorithm; owners and admins only.
+// Switching reassigns users to new buckets, so partial rollouts reach a different set of users.
+func (s *Service) UpdateBucketing(ctx context.Context, id string, tenantID string, role string, req UpdateBucketingRequest) (*BucketingSettings, error) {
+	if role != "owner" && role != "admin" {
+		return nil, ErrInsufficientPermissions
+	}
+	if !evaluation.IsBucketingAlgorithm(req.Algorithm) {
+		return nil, fmt.Errorf("%w: algorithm must be one of %s", ErrInvalidBucketing, strings.Join(evaluation.BucketingAlgorithms, ", "))
+	}
+
+	settings := &BucketingSettings{ProjectID: id, Algorithm: req.Algorithm}
+	if err := s.repo.UpdateBucketing(ctx, settings, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to update project bucketing",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("project bucketing updated",
+		slog.String("id", id),
+		slog.String("algorithm", settings.Algorithm),
+		slog.String("tenant_id", tenantID),
+	)
+	return settings, nil
+}
+
+// GetGeoTargeting returns whether a project's SDK evaluations are geo targeted
+func (s *Service) GetGeoTargeting(ctx context.Context, id string, tenantID string) (*GeoTargetingSettings, error) {
+	enabled, err := s.repo.GetGeoTargeting(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get project geo targeting",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return &GeoTargetingSettings{ProjectID: id, Enabled: enabled}, nil
+}
+
+// UpdateGeoTargeting switches a project's geo targeting; owners and admins only.
+// Country and region attributes SDKs send themselves always take precedence.
+func (s *Service) UpdateGeoTargeting(ctx context.Context, id string, tenantID string, role string, req UpdateGeoTargetingRequest) (*GeoTargetingSettings, error) {
+	if role != "owner" && role != "admin" {
+		return nil, ErrInsufficientPermissions
+	}
+
+	settings := &GeoTargetingSettings{ProjectID: id, Enabled: *req.Enabled}
+	if err := s.repo.UpdateGeoTargeting(ctx, settings, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to update project geo targeting",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("project geo targeting updated",
+		slog.String("id", id),
+		slog.Bool("enabled", settings.Enabled),
+		slog.String("tenant_id", tenantID),
+	)
+	return settings, nil
+}
+
+// GetDefaultAttributes returns the attributes merged into a project's evaluation contexts
+func (s *Service) GetDefaultAttributes(ctx context.Context, id string, tenantID string) (*DefaultAttributes, error) {
+	attributes, err := s.repo.GetDefaultAttributes(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get project default attributes",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return &DefaultAttributes{ProjectID: id, Attributes: attributes}, nil
+}
+
+// UpdateDefaultAttributes replaces a project's default attributes; owners and admins only.
+// Values must be strings, numbers or booleans, like the values rules compare against.
+func (s *Service) UpdateDefaultAttributes(ctx context.Context, id string, tenantID string, role string, req UpdateDefaultAttributesRequest) (*DefaultAttributes, error) {
+	if role != "owner" && role != "admin" {
+		return nil, ErrInsufficientPermissions
+	}
+	if err := validateDefaultAttributes(req.Attributes); err != nil {
+		return nil, err
+	}
+
+	defaults := &DefaultAttributes{ProjectID: id, Attributes: req.Attributes}
+	if err := s.repo.UpdateDefaultAttributes(ctx, defaults, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to update project default attributes",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("project default attributes updated",
+		slog.String("id", id),
+		slog.Int("attributes", len(defaults.Attributes)),
+		slog.String("tenant_id", tenantID),
+	)
+	return defaults, nil
+}
+
+// validateDefaultAttributes checks default attribute names and values
+func validateDefaultAttributes(attributes map[string]interface{}) error {
+	if len(attributes) > MaxDefaultAttributes {
+		return fmt.Errorf("%w: at most %d attributes", ErrInvalidDefaults, MaxDefaultAttributes)
+	}
+	for name, value := range attributes {
+		if strings.TrimSpace(name) == "" || len(name) > MaxDefaultAttributeNameLength {
+			return fmt.Errorf("%w: attribute names must be 1-%d characters", ErrInvalidDefaults, MaxDefaultAttributeNameLength)
+		}
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return fmt.Errorf("%w: %s must be a string, number or boolean", ErrInvalidDefaults, name)
+		}
+	}
+	return nil
+}
+
+// ListEnvironments returns a project's environments
+func (s *Service) ListEnvironments(ctx context.Context, projectID string, tenantID string) ([]Environment, error) {
+	if _, err := s.GetByID(ctx, projectID, tenantID); err != nil {
+		return nil, err
+	}
+
+	environments, err := s.repo.ListEnvironments(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list environments",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return environments, nil
+}
+
+// CreateEnvironment adds an environment, with its own SDK key, to a project
+func (s *Service) CreateEnvironment(ctx context.Context, projectID string, tenantID string, req CreateEnvironmentRequest) (*Environment, error) {
+	if !environmentKeyPattern.MatchString(req.Key) {
+		return nil, fmt.Errorf("%w: keys must be lowercase letters, digits, _ or - (at most 32)", ErrInvalidEnvironment)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 255 {
+		return nil, fmt.Errorf("%w: name is required and must be at most 255 characters", ErrInvalidEnvironment)
+	}
+
+	existing, err := s.ListEnvironments(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MaxEnvironmentsPerProject {
+		return nil, fmt.Errorf("%w: a project can have at most %d environments", ErrInvalidEnvironment, MaxEnvironmentsPerProject)
+	}
+
+	env, err := s.repo.CreateEnvironment(ctx, tenantID, projectID, req.Key, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrDuplicateEnvironment
+		}
+		s.logger.Error("failed to create environment",
+			slog.String("project_id", projectID),
+			slog.String("key", req.Key),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("environment created",
+		slog.String("id", env.ID),
+		slog.String("project_id", projectID),
+		slog.String("key", env.Key),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return env, nil
+}
+
+// DeleteEnvironment removes an environment; its SDK key stops working immediately
+func (s *Service) DeleteEnvironment(ctx context.Context, id string, projectID string, tenantID string) error {
+	if err := s.repo.DeleteEnvironment(ctx, id, projectID, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to delete environment",
+			slog.String("id", id),
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.logger.Info("environment deleted",
+		slog.String("id", id),
+		slog.String("project_id", projectID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// ListAPIKeys returns a project's SDK keys: its own key followed by one per environment
+func (s *Service) ListAPIKeys(ctx context.Context, projectID string, tenantID string) ([]APIKey, error) {
+	project, err := s.GetByID(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	environments, err := s.ListEnvironments(ctx, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]APIKey, 0, len(environments)+1)
+	keys = append(keys, APIKey{ID: project.ID, Kind: APIKeyKindProject, Key: project.ClientAPIKey})
+	for _, env := range environments {
+		keys = append(keys, APIKey{ID: env.ID, Kind: APIKeyKindEnvironment, EnvironmentKey: env.Key, Key: env.ClientAPIKey})
+	}
+	return keys, nil
+}
+
+// RevealAPIKey returns one of a project's SDK keys in full; owners and admins only.
+// keyID is the project ID for the project's own key or an environment ID. Every reveal
+// is recorded in the audit log.
+func (s *Service) RevealAPIKey(ctx context.Context, projectID string, keyID string, tenantID string, userID string, role string) (*APIKey, error) {
+	if role != "owner" && role != "admin" {
+		return nil, ErrInsufficientPermissions
+	}
+
+	var key *APIKey
+	if keyID == projectID {
+		project, err := s.GetByID(ctx, projectID, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		key = &APIKey{ID: project.ID, Kind: APIKeyKindProject, Key: project.ClientAPIKey}
+	} else {
+		env, err := s.repo.GetEnvironment(ctx, keyID, projectID, tenantID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, pkgErrors.ErrNotFound
+			}
+			s.logger.Error("failed to get environment",
+				slog.String("id", keyID),
+				slog.String("project_id", projectID),
+				slog.String("tenant_id", tenantID),
+				slog.String("error", err.Error()),
+			)
+			return nil, err
+		}
+		key = &APIKey{ID: env.ID, Kind: APIKeyKindEnvironment, EnvironmentKey: env.Key, Key: env.ClientAPIKey}
+	}
+
+	// The key is only returned once the reveal is on record
+	reveal := &APIKeyReveal{TenantID: tenantID, ProjectID: projectID, KeyID: key.ID, RevealedBy: userID}
+	if err := s.repo.RecordAPIKeyReveal(ctx, reveal); err != nil {
+		s.logger.Error("failed to record API key reveal",
+			slog.String("key_id", key.ID),
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("API key revealed",
+		slog.String("key_id", key.ID),
+		slog.String("kind", key.Kind),
+		slog.String("project_id", projectID),
+		slog.String("user_id", userID),
+		slog.String("tenant_id", tenantID),
+	)
+	return key, nil
+}
@@ -0,0 +1,114 @@
+package publicstatus
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+	// RegisterPublicRoutes registers the token-less status route; it must not be behind Auth
+	RegisterPublicRoutes(r *gin.RouterGroup, middleware ...gin.HandlerFunc)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/projects/:id/public-status", h.Get)
+	r.PUT("/projects/:id/public-status", h.Update)
+	r.DELETE("/projects/:id/public-status", h.Disable)
+}
+
+func (h *handler) RegisterPublicRoutes(r *gin.RouterGroup, middleware ...gin.HandlerFunc) {
+	r.GET("/public/projects/:id/flag-status", append(middleware, h.Public)...)
+}
+
+// Get returns whether the project serves a public status and which flags it lists
+func (h *handler) Get(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	page, err := h.service.Get(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get public status")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// Update enables the project's public status with exactly the flags in the request
+func (h *handler) Update(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+
+	page, err := h.service.Update(ctx, c.Param("id"), tenantID, appContext.UserRole(ctx), req)
+	if err != nil {
+		h.writeError(c, err, "failed to update public status")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// Disable stops serving the project's public status
+func (h *handler) Disable(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenantID := appContext.MustTenantID(ctx)
+
+	if err := h.service.Disable(ctx, c.Param("id"), tenantID, appContext.UserRole(ctx)); err != nil {
+		h.writeError(c, err, "failed to disable public status")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Public serves the listed flags' names, descriptions and status without credentials.
+// Any origin may read it, so docs portals can fetch it from the browser.
+func (h *handler) Public(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+
+	status, err := h.service.Public(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "public status not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get public status"})
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(CacheMaxAge.Seconds())))
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *handler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidFlags):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInsufficientPermissions):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case pkgErrors.IsNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,39 @@
+package publicstatus
+
+import "time"
+
+// MaxFlags bounds how many flags a project's public status can list
+const MaxFlags = 200
+
+// CacheMaxAge is how long browsers and proxies may cache a public status response
+const CacheMaxAge = time.Minute
+
+// Page is a project's public status configuration
+type Page struct {
+	ProjectID string    `json:"project_id" db:"project_id"`
+	Enabled   bool      `json:"enabled" db:"-"`
+	FlagIDs   []string  `json:"flag_ids" db:"-"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UpdateRequest enables a project's public status with the flags it lists
+type UpdateRequest struct {
+	FlagIDs []string `json:"flag_ids" binding:"required"`
+}
+
+// FlagStatus is what the public status shows of a flag: no rules, overrides or owners
+type FlagStatus struct {
+	ID          string    `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Enabled     bool      `json:"enabled" db:"enabled"`
+	Lifecycle   string    `json:"lifecycle" db:"lifecycle"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Status is the public view of a project's selected flags
+type Status struct {
+	ProjectID   string       `json:"project_id"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Flags       []FlagStatus `json:"flags"`
+}
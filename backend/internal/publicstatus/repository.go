@@ -0,0 +1,143 @@
+package publicstatus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	// Get returns a project's public status page; sql.ErrNoRows means it hasn't opted in
+	Get(ctx context.Context, projectID string, tenantID string) (*Page, error)
+	// Replace enables a project's public status listing exactly the given flags
+	Replace(ctx context.Context, projectID string, tenantID string, flagIDs []string) (*Page, error)
+	// Delete disables a project's public status; sql.ErrNoRows means it wasn't enabled
+	Delete(ctx context.Context, projectID string, tenantID string) error
+	// CountProjectFlags counts how many of the flag IDs belong to the project
+	CountProjectFlags(ctx context.Context, projectID string, tenantID string, flagIDs []string) (int, error)
+	// ListPublic returns the listed flags of a project that opted in, by name;
+	// sql.ErrNoRows means it hasn't. Not tenant scoped: the opt-in is the authorization.
+	ListPublic(ctx context.Context, projectID string) ([]FlagStatus, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *postgresRepository) Get(ctx context.Context, projectID string, tenantID string) (*Page, error) {
+	var page Page
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &page, `
+		SELECT project_id, updated_at FROM public_status_pages
+		WHERE project_id = $1 AND tenant_id = $2
+	`, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Enabled = true
+	page.FlagIDs = []string{}
+	err = sqlx.SelectContext(ctx, r.getDB(ctx), &page.FlagIDs, `
+		SELECT flag_id FROM public_status_flags WHERE project_id = $1 AND tenant_id = $2 ORDER BY flag_id
+	`, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (r *postgresRepository) Replace(ctx context.Context, projectID string, tenantID string, flagIDs []string) (*Page, error) {
+	db := r.getDB(ctx)
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO public_status_pages (project_id, tenant_id)
+		VALUES ($1, $2)
+		ON CONFLICT (project_id) DO UPDATE SET updated_at = NOW()
+	`, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM public_status_flags WHERE project_id = $1 AND tenant_id = $2`, projectID, tenantID); err != nil {
+		return nil, err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO public_status_flags (project_id, flag_id, tenant_id)
+		SELECT $1, id, $2 FROM flags
+		WHERE id = ANY($3) AND project_id = $1 AND tenant_id = $2
+	`, projectID, tenantID, pq.Array(flagIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, projectID, tenantID)
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, projectID string, tenantID string) error {
+	result, err := r.getDB(ctx).ExecContext(ctx, `
+		DELETE FROM public_status_pages WHERE project_id = $1 AND tenant_id = $2
+	`, projectID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *postgresRepository) CountProjectFlags(ctx context.Context, projectID string, tenantID string, flagIDs []string) (int, error) {
+	var count int
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &count, `
+		SELECT COUNT(*) FROM flags WHERE id = ANY($1) AND project_id = $2 AND tenant_id = $3
+	`, pq.Array(flagIDs), projectID, tenantID)
+	return count, err
+}
+
+func (r *postgresRepository) ListPublic(ctx context.Context, projectID string) ([]FlagStatus, error) {
+	var enabled bool
+	err := sqlx.GetContext(ctx, r.getDB(ctx), &enabled, `
+		SELECT EXISTS (SELECT 1 FROM public_status_pages WHERE project_id = $1)
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, sql.ErrNoRows
+	}
+
+	statuses := []FlagStatus{}
+	err = sqlx.SelectContext(ctx, r.getDB(ctx), &statuses, `
+		SELECT f.id, f.name, f.description, f.enabled, f.lifecycle, f.updated_at
+		FROM public_status_flags p
+		INNER JOIN flags f ON f.id = p.flag_id AND f.project_id = p.project_id
+		WHERE p.project_id = $1
+		ORDER BY f.name
+	`, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return statuses, nil
+	}
+	return statuses, err
+}
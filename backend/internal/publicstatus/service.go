@@ -0,0 +1,160 @@
+package publicstatus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/validator"
+)
+
+// ErrInvalidFlags indicates flag IDs that are malformed, repeated or not in the project
+var ErrInvalidFlags = errors.New("invalid flags")
+
+// ErrInsufficientPermissions indicates the member's role can't change the public status
+var ErrInsufficientPermissions = errors.New("insufficient permissions")
+
+type Service interface {
+	// Get returns a project's public status configuration; disabled when it hasn't opted in
+	Get(ctx context.Context, projectID string, tenantID string) (*Page, error)
+	// Update enables a project's public status listing the given flags; owners and admins only
+	Update(ctx context.Context, projectID string, tenantID string, role string, req UpdateRequest) (*Page, error)
+	// Disable stops serving a project's public status; owners and admins only
+	Disable(ctx context.Context, projectID string, tenantID string, role string) error
+	// Public returns the listed flags of a project that opted in; not found otherwise
+	Public(ctx context.Context, projectID string) (*Status, error)
+}
+
+type service struct {
+	repo      Repository
+	validator validator.Validator
+	logger    *slog.Logger
+}
+
+func NewService(repo Repository, val validator.Validator, logger *slog.Logger) Service {
+	return &service{
+		repo:      repo,
+		validator: val,
+		logger:    logger,
+	}
+}
+
+func (s *service) Get(ctx context.Context, projectID string, tenantID string) (*Page, error) {
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	page, err := s.repo.Get(ctx, projectID, tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Page{ProjectID: projectID, FlagIDs: []string{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public status: %w", err)
+	}
+	return page, nil
+}
+
+func (s *service) Update(ctx context.Context, projectID string, tenantID string, role string, req UpdateRequest) (*Page, error) {
+	if role != "owner" && role != "admin" {
+		return nil, ErrInsufficientPermissions
+	}
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+	if err := validateFlagIDs(req.FlagIDs); err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountProjectFlags(ctx, projectID, tenantID, req.FlagIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check flags: %w", err)
+	}
+	if count != len(req.FlagIDs) {
+		return nil, fmt.Errorf("%w: every flag must belong to the project", ErrInvalidFlags)
+	}
+
+	page, err := s.repo.Replace(ctx, projectID, tenantID, req.FlagIDs)
+	if err != nil {
+		s.logger.Error("failed to update public status",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to update public status: %w", err)
+	}
+
+	s.logger.Info("public status updated",
+		slog.String("project_id", projectID),
+		slog.Int("flags", len(page.FlagIDs)),
+		slog.String("tenant_id", tenantID),
+	)
+	return page, nil
+}
+
+func (s *service) Disable(ctx context.Context, projectID string, tenantID string, role string) error {
+	if role != "owner" && role != "admin" {
+		return ErrInsufficientPermissions
+	}
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		return pkgErrors.ErrProjectNotInTenant
+	}
+
+	if err := s.repo.Delete(ctx, projectID, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to disable public status: %w", err)
+	}
+
+	s.logger.Info("public status disabled",
+		slog.String("project_id", projectID),
+		slog.String("tenant_id", tenantID),
+	)
+	return nil
+}
+
+func (s *service) Public(ctx context.Context, projectID string) (*Status, error) {
+	// Malformed IDs can't have opted in, and would fail as UUIDs in the query
+	if _, err := uuid.Parse(projectID); err != nil {
+		return nil, pkgErrors.ErrNotFound
+	}
+
+	flags, err := s.repo.ListPublic(ctx, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, pkgErrors.ErrNotFound
+	}
+	if err != nil {
+		s.logger.Error("failed to list public flag status",
+			slog.String("project_id", projectID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list public flag status: %w", err)
+	}
+
+	return &Status{ProjectID: projectID, GeneratedAt: time.Now().UTC(), Flags: flags}, nil
+}
+
+// validateFlagIDs checks the selection is within MaxFlags and has no malformed or repeated IDs
+func validateFlagIDs(flagIDs []string) error {
+	if len(flagIDs) > MaxFlags {
+		return fmt.Errorf("%w: at most %d flags can be public", ErrInvalidFlags, MaxFlags)
+	}
+
+	seen := make(map[string]struct{}, len(flagIDs))
+	for _, id := range flagIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("%w: %q is not a flag ID", ErrInvalidFlags, id)
+		}
+		if _, ok := seen[id]; ok {
+			return fmt.Errorf("%w: flag %s is listed twice", ErrInvalidFlags, id)
+		}
+		seen[id] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package publicstatus
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+const (
+	flagA = "5f0c4a36-0b4e-4f0e-9d0a-6c0b6a1e2f01"
+	flagB = "5f0c4a36-0b4e-4f0e-9d0a-6c0b6a1e2f02"
+)
+
+type mockRepository struct {
+	projectFlags map[string]bool // flag IDs in project-1
+	replaced     []string
+	public       map[string][]FlagStatus
+}
+
+func (m *mockRepository) Get(ctx context.Context, projectID string, tenantID string) (*Page, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockRepository) Replace(ctx context.Context, projectID string, tenantID string, flagIDs []string) (*Page, error) {
+	m.replaced = flagIDs
+	return &Page{ProjectID: projectID, Enabled: true, FlagIDs: flagIDs}, nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, projectID string, tenantID string) error {
+	return sql.ErrNoRows
+}
+
+func (m *mockRepository) CountProjectFlags(ctx context.Context, projectID string, tenantID string, flagIDs []string) (int, error) {
+	count := 0
+	for _, id := range flagIDs {
+		if m.projectFlags[id] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockRepository) ListPublic(ctx context.Context, projectID string) ([]FlagStatus, error) {
+	flags, ok := m.public[projectID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return flags, nil
+}
+
+type mockValidator struct{}
+
+func (m *mockValidator) ValidateProjectOwnership(ctx context.Context, projectID, tenantID string) error {
+	if projectID != "project-1" || tenantID != "tenant-1" {
+		return pkgErrors.ErrProjectNotInTenant
+	}
+	return nil
+}
+
+func (m *mockValidator) ValidateTenantExists(ctx context.Context, tenantID string) error {
+	return nil
+}
+
+func (m *mockValidator) ValidateTenantMembership(ctx context.Context, userID, tenantID string) error {
+	return nil
+}
+
+func newTestService(repo *mockRepository) Service {
+	return NewService(repo, &mockValidator{}, slog.Default())
+}
+
+func TestServiceUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		project string
+		flagIDs []string
+		wantErr error
+	}{
+		{name: "admin selects project flags", role: "admin", project: "project-1", flagIDs: []string{flagA}},
+		{name: "empty selection", role: "owner", project: "project-1", flagIDs: []string{}},
+		{name: "members can't change it", role: "member", project: "project-1", flagIDs: []string{flagA}, wantErr: ErrInsufficientPermissions},
+		{name: "other tenant's project", role: "owner", project: "project-2", flagIDs: []string{flagA}, wantErr: pkgErrors.ErrProjectNotInTenant},
+		{name: "flag outside the project", role: "owner", project: "project-1", flagIDs: []string{flagA, flagB}, wantErr: ErrInvalidFlags},
+		{name: "malformed flag ID", role: "owner", project: "project-1", flagIDs: []string{"dark-mode"}, wantErr: ErrInvalidFlags},
+		{name: "repeated flag", role: "owner", project: "project-1", flagIDs: []string{flagA, flagA}, wantErr: ErrInvalidFlags},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{projectFlags: map[string]bool{flagA: true}}
+			svc := newTestService(repo)
+
+			page, err := svc.Update(context.Background(), tt.project, "tenant-1", tt.role, UpdateRequest{FlagIDs: tt.flagIDs})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				if repo.replaced != nil {
+					t.Error("expected nothing stored on error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !page.Enabled || len(page.FlagIDs) != len(tt.flagIDs) {
+				t.Errorf("unexpected page %+v", page)
+			}
+		})
+	}
+}
+
+func TestServiceUpdate_LimitsFlags(t *testing.T) {
+	flagIDs := make([]string, MaxFlags+1)
+	for i := range flagIDs {
+		flagIDs[i] = flagA
+	}
+
+	_, err := newTestService(&mockRepository{}).Update(context.Background(), "project-1", "tenant-1", "owner", UpdateRequest{FlagIDs: flagIDs})
+	if !errors.Is(err, ErrInvalidFlags) || !strings.Contains(err.Error(), "at most") {
+		t.Errorf("expected flag limit error, got %v", err)
+	}
+}
+
+func TestServiceGet_DisabledWithoutPage(t *testing.T) {
+	page, err := newTestService(&mockRepository{}).Get(context.Background(), "project-1", "tenant-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Enabled || page.FlagIDs == nil {
+		t.Errorf("expected a disabled page with no flags, got %+v", page)
+	}
+}
+
+func TestServicePublic(t *testing.T) {
+	const optedIn = "7a1d2c3b-4e5f-4a6b-8c7d-9e0f1a2b3c4d"
+	repo := &mockRepository{public: map[string][]FlagStatus{
+		optedIn: {{ID: flagA, Name: "dark-mode", Enabled: true, Lifecycle: "active"}},
+	}}
+	svc := newTestService(repo)
+
+	status, err := svc.Public(context.Background(), optedIn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(status.Flags) != 1 || status.ProjectID != optedIn {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	for _, projectID := range []string{"8b2e3d4c-5f60-4b7c-9d8e-0f1a2b3c4d5e", "not-a-uuid"} {
+		if _, err := svc.Public(context.Background(), projectID); !errors.Is(err, pkgErrors.ErrNotFound) {
+			t.Errorf("expected not found for %q, got %v", projectID, err)
+		}
+	}
+}
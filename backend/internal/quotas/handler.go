@@ -0,0 +1,61 @@
+package quotas
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/tenant/quota", h.GetStatus)
+	r.GET("/tenant/limits", h.GetLimits)
+}
+
+// GetStatus returns the tenant's plan, limit and usage in the current quota window
+func (h *handler) GetStatus(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	status, err := h.service.Status(c.Request.Context(), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get quota status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetLimits returns every limit that applies to the tenant and how much of each is used
+func (h *handler) GetLimits(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	report, err := h.service.Limits(c.Request.Context(), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get limits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
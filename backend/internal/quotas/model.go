@@ -0,0 +1,104 @@
+package quotas
+
+import "time"
+
+// Tenant plans
+const (
+	PlanFree       = "free"
+	PlanTeam       = "team"
+	PlanEnterprise = "enterprise"
+)
+
+// Window is the length of a quota period; counters reset at the start of each window
+const Window = time.Hour
+
+// Limits maps a plan to the management API requests allowed per Window
+type Limits map[string]int
+
+// DefaultLimits apply to plans without a configured limit
+var DefaultLimits = Limits{
+	PlanFree:       1000,
+	PlanTeam:       10000,
+	PlanEnterprise: 100000,
+}
+
+// Status is a tenant's quota usage in the current window
+type Status struct {
+	Plan          string    `json:"plan"`
+	Limit         int       `json:"limit"`
+	Used          int       `json:"used"`
+	Remaining     int       `json:"remaining"`
+	ResetAt       time.Time `json:"reset_at"`
+	WindowSeconds int       `json:"window_seconds"`
+}
+
+// Exceeded reports whether the tenant has used more than its limit
+func (s Status) Exceeded() bool {
+	return s.Used > s.Limit
+}
+
+// Limit names reported by the limits endpoint
+const (
+	LimitAPIRequests            = "api_requests"
+	LimitMembers                = "members"
+	LimitFlagsPerProject        = "flags_per_project"
+	LimitRulesPerFlag           = "rules_per_flag"
+	LimitEnvironmentsPerProject = "environments_per_project"
+	LimitCustomFieldsPerFlag    = "custom_fields_per_flag"
+)
+
+// Scopes a limit applies within
+const (
+	ScopeTenant  = "tenant"
+	ScopeProject = "project"
+	ScopeFlag    = "flag"
+)
+
+// Limit is one limit that applies to a tenant and how much of it is used
+type Limit struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	// Max is nil when the tenant's plan doesn't cap it
+	Max *int `json:"max"`
+	// Used is the current consumption. For project and flag limits it's the highest of any
+	// project or flag, the one closest to the limit, and UsedBy is its ID.
+	Used   int    `json:"used"`
+	UsedBy string `json:"used_by,omitempty"`
+	// Remaining is nil when Max is
+	Remaining *int `json:"remaining"`
+	// WindowSeconds and ResetAt are set for rate limits, which reset every window
+	WindowSeconds int        `json:"window_seconds,omitempty"`
+	ResetAt       *time.Time `json:"reset_at,omitempty"`
+}
+
+// newLimit returns a limit of max, nil for uncapped, with used consumed by usedBy
+func newLimit(name, scope string, limit *int, used int, usedBy string) Limit {
+	l := Limit{Name: name, Scope: scope, Max: limit, Used: used, UsedBy: usedBy}
+	if limit != nil {
+		remaining := max(*limit-used, 0)
+		l.Remaining = &remaining
+	}
+	return l
+}
+
+// LimitsReport lists every limit that applies to a tenant, so clients can check an action
+// fits before attempting it
+type LimitsReport struct {
+	Plan   string  `json:"plan"`
+	Limits []Limit `json:"limits"`
+}
+
+// Usage is a tenant's consumption of its resource limits
+type Usage struct {
+	Members                int
+	FlagsPerProject        Peak
+	RulesPerFlag           Peak
+	EnvironmentsPerProject Peak
+	CustomFieldsPerFlag    Peak
+}
+
+// Peak is the highest count of something within any one project or flag, and which it is
+type Peak struct {
+	ID    string `db:"id"`
+	Count int    `db:"count"`
+}
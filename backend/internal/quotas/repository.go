@@ -0,0 +1,161 @@
+package quotas
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PlanReader looks up a tenant's plan
+type PlanReader interface {
+	GetPlan(ctx context.Context, tenantID string) (string, error)
+}
+
+// Counter counts requests per tenant per window
+type Counter interface {
+	// Increment records one request and returns the window's count including it
+	Increment(ctx context.Context, tenantID string, windowStart time.Time) (int, error)
+	Get(ctx context.Context, tenantID string, windowStart time.Time) (int, error)
+	// Prune removes windows that started before the given time
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// UsageReader counts the tenant resources that limits apply to
+type UsageReader interface {
+	GetUsage(ctx context.Context, tenantID string) (*Usage, error)
+}
+
+// Repository reads plans and keeps request counters in Postgres,
+// so quotas are shared by every server instance
+type Repository interface {
+	PlanReader
+	Counter
+	UsageReader
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) GetPlan(ctx context.Context, tenantID string) (string, error) {
+	var plan string
+	err := r.db.QueryRowxContext(ctx, `SELECT plan FROM tenants WHERE id = $1`, tenantID).Scan(&plan)
+	return plan, err
+}
+
+func (r *postgresRepository) Increment(ctx context.Context, tenantID string, windowStart time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowxContext(ctx, `
+		INSERT INTO tenant_request_counts (tenant_id, window_start, count)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (tenant_id, window_start) DO UPDATE SET count = tenant_request_counts.count + 1
+		RETURNING count
+	`, tenantID, windowStart).Scan(&count)
+	return count, err
+}
+
+func (r *postgresRepository) Get(ctx context.Context, tenantID string, windowStart time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowxContext(ctx, `
+		SELECT COALESCE(MAX(count), 0) FROM tenant_request_counts
+		WHERE tenant_id = $1 AND window_start = $2
+	`, tenantID, windowStart).Scan(&count)
+	return count, err
+}
+
+func (r *postgresRepository) Prune(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM tenant_request_counts WHERE window_start < $1`, before)
+	return err
+}
+
+func (r *postgresRepository) GetUsage(ctx context.Context, tenantID string) (*Usage, error) {
+	var usage Usage
+	if err := r.db.QueryRowxContext(ctx,
+		`SELECT COUNT(*) FROM tenant_members WHERE tenant_id = $1`, tenantID,
+	).Scan(&usage.Members); err != nil {
+		return nil, fmt.Errorf("count members: %w", err)
+	}
+
+	peaks := []struct {
+		peak  *Peak
+		query string
+	}{
+		{&usage.FlagsPerProject, `
+			SELECT project_id AS id, COUNT(*) AS count FROM flags
+			WHERE tenant_id = $1 AND project_id IS NOT NULL
+			GROUP BY project_id ORDER BY count DESC, id LIMIT 1`},
+		{&usage.RulesPerFlag, `
+			SELECT id, jsonb_array_length(rules) AS count FROM flags
+			WHERE tenant_id = $1
+			ORDER BY count DESC, id LIMIT 1`},
+		{&usage.EnvironmentsPerProject, `
+			SELECT project_id AS id, COUNT(*) AS count FROM environments
+			WHERE tenant_id = $1
+			GROUP BY project_id ORDER BY count DESC, id LIMIT 1`},
+		{&usage.CustomFieldsPerFlag, `
+			SELECT flag_id AS id, COUNT(*) AS count FROM flag_custom_fields
+			WHERE tenant_id = $1
+			GROUP BY flag_id ORDER BY count DESC, id LIMIT 1`},
+	}
+	for _, p := range peaks {
+		// No rows leaves the peak at zero
+		if err := r.db.GetContext(ctx, p.peak, p.query, tenantID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("count usage: %w", err)
+		}
+	}
+
+	return &usage, nil
+}
+
+// memoryCounter keeps counters in process memory; quotas then apply per server instance
+type memoryCounter struct {
+	mu     sync.Mutex
+	counts map[memoryKey]int
+}
+
+type memoryKey struct {
+	tenantID    string
+	windowStart time.Time
+}
+
+// NewMemoryCounter returns a Counter for single-instance deployments and tests
+func NewMemoryCounter() Counter {
+	return &memoryCounter{counts: make(map[memoryKey]int)}
+}
+
+func (m *memoryCounter) Increment(ctx context.Context, tenantID string, windowStart time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memoryKey{tenantID: tenantID, windowStart: windowStart}
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+func (m *memoryCounter) Get(ctx context.Context, tenantID string, windowStart time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.counts[memoryKey{tenantID: tenantID, windowStart: windowStart}], nil
+}
+
+func (m *memoryCounter) Prune(ctx context.Context, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.counts {
+		if key.windowStart.Before(before) {
+			delete(m.counts, key)
+		}
+	}
+	return nil
+}
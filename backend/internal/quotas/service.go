@@ -0,0 +1,145 @@
+package quotas
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/projects"
+)
+
+type Service interface {
+	// Consume records one management API request and returns the tenant's updated status
+	Consume(ctx context.Context, tenantID string) (Status, error)
+	Status(ctx context.Context, tenantID string) (Status, error)
+	// PruneWindows drops counters for windows that have ended
+	PruneWindows(ctx context.Context) error
+	// Limits reports every limit that applies to the tenant with its current consumption
+	Limits(ctx context.Context, tenantID string) (*LimitsReport, error)
+	SetUsageReader(usage UsageReader)
+}
+
+type service struct {
+	plans   PlanReader
+	counter Counter
+	usage   UsageReader
+	limits  Limits
+	now     func() time.Time
+	logger  *slog.Logger
+}
+
+// NewService creates a quota service; plans missing from limits use DefaultLimits
+func NewService(plans PlanReader, counter Counter, limits Limits, logger *slog.Logger) Service {
+	merged := make(Limits, len(DefaultLimits))
+	for plan, limit := range DefaultLimits {
+		merged[plan] = limit
+	}
+	for plan, limit := range limits {
+		if limit > 0 {
+			merged[plan] = limit
+		}
+	}
+
+	return &service{
+		plans:   plans,
+		counter: counter,
+		limits:  merged,
+		now:     time.Now,
+		logger:  logger,
+	}
+}
+
+func (s *service) Consume(ctx context.Context, tenantID string) (Status, error) {
+	return s.status(ctx, tenantID, s.counter.Increment)
+}
+
+func (s *service) Status(ctx context.Context, tenantID string) (Status, error) {
+	return s.status(ctx, tenantID, s.counter.Get)
+}
+
+func (s *service) status(ctx context.Context, tenantID string, count func(context.Context, string, time.Time) (int, error)) (Status, error) {
+	plan, err := s.plans.GetPlan(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Status{}, pkgErrors.ErrNotFound
+		}
+		return Status{}, fmt.Errorf("failed to get tenant plan: %w", err)
+	}
+
+	limit, ok := s.limits[plan]
+	if !ok {
+		s.logger.Warn("unknown tenant plan, applying free limit",
+			slog.String("tenant_id", tenantID),
+			slog.String("plan", plan),
+		)
+		limit = s.limits[PlanFree]
+	}
+
+	windowStart := s.now().UTC().Truncate(Window)
+	used, err := count(ctx, tenantID, windowStart)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to count requests: %w", err)
+	}
+
+	return Status{
+		Plan:          plan,
+		Limit:         limit,
+		Used:          used,
+		Remaining:     max(limit-used, 0),
+		ResetAt:       windowStart.Add(Window),
+		WindowSeconds: int(Window.Seconds()),
+	}, nil
+}
+
+func (s *service) PruneWindows(ctx context.Context) error {
+	return s.counter.Prune(ctx, s.now().UTC().Truncate(Window))
+}
+
+func (s *service) SetUsageReader(usage UsageReader) {
+	s.usage = usage
+}
+
+func (s *service) Limits(ctx context.Context, tenantID string) (*LimitsReport, error) {
+	status, err := s.Status(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{}
+	if s.usage != nil {
+		usage, err = s.usage.GetUsage(ctx, tenantID)
+		if err != nil {
+			s.logger.Error("failed to get tenant usage",
+				slog.String("tenant_id", tenantID),
+				slog.String("error", err.Error()),
+			)
+			return nil, fmt.Errorf("failed to get tenant usage: %w", err)
+		}
+	}
+
+	requests := newLimit(LimitAPIRequests, ScopeTenant, &status.Limit, status.Used, "")
+	requests.WindowSeconds = status.WindowSeconds
+	requests.ResetAt = &status.ResetAt
+
+	environments := projects.MaxEnvironmentsPerProject
+	customFields := flag.MaxCustomFieldsPerFlag
+
+	return &LimitsReport{
+		Plan: status.Plan,
+		Limits: []Limit{
+			requests,
+			// Members, flags and rules aren't capped on any plan, but their counts are still
+			// reported so clients can show them alongside the limits
+			newLimit(LimitMembers, ScopeTenant, nil, usage.Members, ""),
+			newLimit(LimitFlagsPerProject, ScopeProject, nil, usage.FlagsPerProject.Count, usage.FlagsPerProject.ID),
+			newLimit(LimitRulesPerFlag, ScopeFlag, nil, usage.RulesPerFlag.Count, usage.RulesPerFlag.ID),
+			newLimit(LimitEnvironmentsPerProject, ScopeProject, &environments, usage.EnvironmentsPerProject.Count, usage.EnvironmentsPerProject.ID),
+			newLimit(LimitCustomFieldsPerFlag, ScopeFlag, &customFields, usage.CustomFieldsPerFlag.Count, usage.CustomFieldsPerFlag.ID),
+		},
+	}, nil
+}
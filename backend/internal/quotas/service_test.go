@@ -0,0 +1,161 @@
+package quotas
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"testing"
+	"time"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type mockPlans map[string]string
+
+func (m mockPlans) GetPlan(ctx context.Context, tenantID string) (string, error) {
+	plan, ok := m[tenantID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return plan, nil
+}
+
+func newTestService(plans mockPlans, limits Limits, now time.Time) *service {
+	svc := NewService(plans, NewMemoryCounter(), limits, slog.Default()).(*service)
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+func TestServiceConsume(t *testing.T) {
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	svc := newTestService(mockPlans{"tenant-1": PlanFree, "tenant-2": PlanTeam}, Limits{PlanFree: 2}, now)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		status, err := svc.Consume(ctx, "tenant-1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if status.Used != i {
+			t.Errorf("request %d: expected used %d, got %d", i, i, status.Used)
+		}
+		if wantExceeded := i > 2; status.Exceeded() != wantExceeded {
+			t.Errorf("request %d: expected exceeded=%t", i, wantExceeded)
+		}
+	}
+
+	status, err := svc.Status(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.Remaining != 0 || status.Limit != 2 {
+		t.Errorf("expected limit 2 with none remaining, got %+v", status)
+	}
+	if want := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC); !status.ResetAt.Equal(want) {
+		t.Errorf("expected reset at %v, got %v", want, status.ResetAt)
+	}
+
+	// Tenants are counted separately, and unconfigured plans keep their default
+	status, err = svc.Consume(ctx, "tenant-2")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.Used != 1 || status.Limit != DefaultLimits[PlanTeam] {
+		t.Errorf("expected first team request against default limit, got %+v", status)
+	}
+}
+
+func TestServiceConsume_NewWindowResets(t *testing.T) {
+	now := time.Date(2026, 10, 15, 9, 59, 0, 0, time.UTC)
+	svc := newTestService(mockPlans{"tenant-1": PlanFree}, Limits{PlanFree: 1}, now)
+	ctx := context.Background()
+
+	if _, err := svc.Consume(ctx, "tenant-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	svc.now = func() time.Time { return now.Add(2 * time.Minute) }
+	status, err := svc.Consume(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.Used != 1 || status.Exceeded() {
+		t.Errorf("expected a fresh window, got %+v", status)
+	}
+}
+
+func TestServiceConsume_UnknownTenant(t *testing.T) {
+	svc := newTestService(mockPlans{}, nil, time.Now())
+
+	if _, err := svc.Consume(context.Background(), "missing"); !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestServiceConsume_UnknownPlanUsesFreeLimit(t *testing.T) {
+	svc := newTestService(mockPlans{"tenant-1": "legacy"}, Limits{PlanFree: 5}, time.Now())
+
+	status, err := svc.Consume(context.Background(), "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.Limit != 5 {
+		t.Errorf("expected free limit 5, got %d", status.Limit)
+	}
+}
+
+type mockUsage Usage
+
+func (m mockUsage) GetUsage(ctx context.Context, tenantID string) (*Usage, error) {
+	usage := Usage(m)
+	return &usage, nil
+}
+
+func TestServiceLimits(t *testing.T) {
+	svc := newTestService(mockPlans{"tenant-1": PlanFree}, Limits{PlanFree: 10}, time.Now())
+	svc.SetUsageReader(mockUsage{
+		Members:                4,
+		FlagsPerProject:        Peak{ID: "project-1", Count: 12},
+		EnvironmentsPerProject: Peak{ID: "project-2", Count: 25},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Consume(ctx, "tenant-1"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	report, err := svc.Limits(ctx, "tenant-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.Plan != PlanFree {
+		t.Errorf("expected plan %q, got %q", PlanFree, report.Plan)
+	}
+
+	limits := make(map[string]Limit, len(report.Limits))
+	for _, l := range report.Limits {
+		limits[l.Name] = l
+	}
+
+	requests := limits[LimitAPIRequests]
+	if requests.Max == nil || *requests.Max != 10 || requests.Used != 3 || *requests.Remaining != 7 {
+		t.Errorf("expected 3 of 10 requests used, got %+v", requests)
+	}
+	if requests.WindowSeconds != int(Window.Seconds()) || requests.ResetAt == nil {
+		t.Errorf("expected the request limit to carry its window, got %+v", requests)
+	}
+
+	if flags := limits[LimitFlagsPerProject]; flags.Max != nil || flags.Remaining != nil || flags.Used != 12 || flags.UsedBy != "project-1" {
+		t.Errorf("expected uncapped flags per project used 12 by project-1, got %+v", flags)
+	}
+	if members := limits[LimitMembers]; members.Used != 4 || members.Scope != ScopeTenant {
+		t.Errorf("expected 4 members, got %+v", members)
+	}
+
+	// Projects already over a limit report none remaining rather than a negative count
+	if envs := limits[LimitEnvironmentsPerProject]; envs.Max == nil || *envs.Remaining != 0 {
+		t.Errorf("expected no environments remaining, got %+v", envs)
+	}
+}
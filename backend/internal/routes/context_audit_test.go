@@ -0,0 +1,938 @@
+package routes
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The context audit statically walks routes.go, the SDK routes it shares with the
+// evaluator (sdk/routes.go) and every handler they register, and checks that each
+// appContext.Must* call reachable from a route is backed by a middleware on that route's
+// group. Calls are followed within the handler's own package
+// (handler -> service -> helpers) by the receiver's type; calls into other packages are
+// not followed. Route groups handed to code the audit cannot follow fail the audit.
+
+const (
+	modulePath     = "github.com/jalil32/toggle"
+	contextPkgPath = modulePath + "/internal/pkg/context"
+)
+
+// contextValue is a value stored in the request context by a middleware
+type contextValue string
+
+const (
+	ctxUser    contextValue = "user"
+	ctxTenant  contextValue = "tenant"
+	ctxProject contextValue = "project"
+)
+
+// mustAccessors maps appContext accessors that panic when the value is missing
+var mustAccessors = map[string]contextValue{
+	"MustUserID":    ctxUser,
+	"MustTenantID":  ctxTenant,
+	"MustProjectID": ctxProject,
+}
+
+// middlewareProvides lists the context values each middleware guarantees.
+// Auth only guarantees a user: new users without a tenant get WithUserOnly.
+var middlewareProvides = map[string][]contextValue{
+	"Auth":   {ctxUser},
+	"Tenant": {ctxTenant},
+	"APIKey": {ctxProject, ctxTenant},
+}
+
+// middlewareRequires lists the context values a middleware itself needs from earlier middleware
+var middlewareRequires = map[string][]contextValue{
+	"Tenant": {ctxUser},
+}
+
+// routeGroup is a gin router group declared in a route file
+type routeGroup struct {
+	name       string
+	parent     string
+	middleware []string
+}
+
+// registration is a handler.RegisterX(group) call in a route file
+type registration struct {
+	pkgDir      string
+	pkgName     string
+	constructor string
+	function    string
+	group       string
+}
+
+// contextViolation describes a Must* call that is not backed by middleware
+type contextViolation struct {
+	route    string
+	accessor string
+	missing  contextValue
+}
+
+func (v contextViolation) String() string {
+	return fmt.Sprintf("%s calls %s but its route group does not provide %q context", v.route, v.accessor, v.missing)
+}
+
+func TestContextAudit_HandlersOnlyUseProvidedContext(t *testing.T) {
+	routesFile, err := filepath.Abs("routes.go")
+	require.NoError(t, err)
+	moduleRoot := filepath.Join(filepath.Dir(routesFile), "..", "..")
+
+	// The SDK routes are shared with the standalone evaluator
+	violations, registrations, err := auditContext(moduleRoot, routesFile, filepath.Join(filepath.Dir(routesFile), "sdk", "routes.go"))
+	require.NoError(t, err)
+
+	// Guard against the audit silently passing because the route files could not be understood
+	require.NotEmpty(t, registrations, "no handler registrations found in the route files")
+
+	for _, v := range violations {
+		t.Error(v.String())
+	}
+}
+
+func TestContextAudit_DetectsMissingMiddleware(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "internal", "widgets", "handler.go"), `package widgets
+
+import (
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+type Handler struct{ service *Service }
+
+func NewHandler(s *Service) *Handler { return &Handler{service: s} }
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/widgets", h.List)
+}
+
+func (h *Handler) RegisterSDKRoutes(r *gin.RouterGroup) {
+	r.GET("/widgets", h.List)
+}
+
+func (h *Handler) List(c *gin.Context) {
+	h.service.List(c.Request.Context())
+}
+
+type Service struct{}
+
+func (s *Service) List(ctx context.Context) {
+	_ = appContext.MustProjectID(ctx)
+}
+
+type Report interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type report struct{ catalog Catalog }
+
+func NewReportHandler() Report { return &report{catalog: catalog{}} }
+
+func (h *report) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/widgets/report", h.List)
+}
+
+func (h *report) List(c *gin.Context) {
+	h.catalog.List(c.Request.Context(), 10)
+}
+
+type Catalog interface {
+	List(ctx context.Context, limit int)
+}
+
+type catalog struct{}
+
+func (catalog) List(ctx context.Context, limit int) {}
+`)
+
+	routesFile := filepath.Join(root, "internal", "routes", "routes.go")
+	writeFile(t, routesFile, `package routes
+
+import (
+	"github.com/jalil32/toggle/internal/middleware"
+	"github.com/jalil32/toggle/internal/widgets"
+)
+
+func Routes(router *gin.Engine) {
+	widgetHandler := widgets.NewHandler(nil)
+
+	api := router.Group("/api/v1")
+
+	sdk := api.Group("/sdk")
+	sdk.Use(middleware.APIKey(nil, nil))
+	widgetHandler.RegisterSDKRoutes(sdk)
+
+	protected := api.Group("")
+	protected.Use(middleware.Auth(nil, nil, nil, nil))
+	widgetHandler.RegisterRoutes(protected)
+	widgets.NewReportHandler().RegisterRoutes(protected)
+}
+`)
+
+	violations, registrations, err := auditContext(root, routesFile)
+	require.NoError(t, err)
+
+	// report.List only reaches Catalog implementations, not the other Lists in the package
+	assert.Len(t, registrations, 3)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "widgets.RegisterRoutes -> Handler.List", violations[0].route)
+	assert.Equal(t, "MustProjectID", violations[0].accessor)
+	assert.Equal(t, ctxProject, violations[0].missing)
+}
+
+func TestContextAudit_FailsOnUnresolvedRegistrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		register string
+		wantErr  string
+	}{
+		{name: "unknown handler", register: "mounter.RegisterRoutes(protected)", wantErr: "cannot resolve the handler behind mounter.RegisterRoutes"},
+		{name: "group passed elsewhere", register: "widgets.Mount(protected)", wantErr: "route group protected is passed to widgets.Mount"},
+		{name: "no route group", register: "widgets.NewHandler(nil).RegisterRoutes(api.Group(\"/widgets\"))", wantErr: "registers no route group"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			routesFile := filepath.Join(root, "internal", "routes", "routes.go")
+			writeFile(t, routesFile, `package routes
+
+import (
+	"github.com/jalil32/toggle/internal/middleware"
+	"github.com/jalil32/toggle/internal/widgets"
+)
+
+func Routes(router *gin.Engine, mounter Mounter) {
+	api := router.Group("/api/v1")
+
+	protected := api.Group("")
+	protected.Use(middleware.Auth(nil, nil, nil, nil))
+	`+tt.register+`
+}
+`)
+
+			_, _, err := auditContext(root, routesFile)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+// auditContext parses the route files and returns every Must* call that the route's middleware does not back
+func auditContext(moduleRoot string, routeFiles ...string) ([]contextViolation, []registration, error) {
+	groups, registrations, err := parseRoutes(moduleRoot, routeFiles...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var violations []contextViolation
+
+	// Middleware must run after the middleware it depends on
+	for _, g := range groups {
+		provided := map[contextValue]bool{}
+		for _, chain := range groupChain(groups, g.name) {
+			for _, mw := range chain.middleware {
+				for _, need := range middlewareRequires[mw] {
+					if !provided[need] {
+						violations = append(violations, contextViolation{
+							route:    fmt.Sprintf("middleware.%s on group %s", mw, chain.name),
+							accessor: "its own context lookup",
+							missing:  need,
+						})
+					}
+				}
+				for _, v := range middlewareProvides[mw] {
+					provided[v] = true
+				}
+			}
+		}
+	}
+
+	pkgs := map[string]*handlerPackage{}
+	for _, reg := range registrations {
+		pkg, ok := pkgs[reg.pkgDir]
+		if !ok {
+			pkg, err = loadHandlerPackage(reg.pkgDir)
+			if err != nil {
+				return nil, nil, err
+			}
+			pkgs[reg.pkgDir] = pkg
+		}
+
+		provided := map[contextValue]bool{}
+		for _, chain := range groupChain(groups, reg.group) {
+			for _, mw := range chain.middleware {
+				for _, v := range middlewareProvides[mw] {
+					provided[v] = true
+				}
+			}
+		}
+
+		handlerType, err := pkg.constructorType(reg.constructor)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", reg.pkgName, err)
+		}
+		routes, err := pkg.routeHandlers(handlerType, reg.function)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", reg.pkgName, err)
+		}
+
+		for _, route := range routes {
+			for _, accessor := range pkg.reachableAccessors(route) {
+				if need := mustAccessors[accessor]; !provided[need] {
+					violations = append(violations, contextViolation{
+						route:    fmt.Sprintf("%s.%s -> %s", reg.pkgName, reg.function, route),
+						accessor: accessor,
+						missing:  need,
+					})
+				}
+			}
+		}
+	}
+
+	return violations, registrations, nil
+}
+
+// groupChain returns the group and its ancestors, outermost first
+func groupChain(groups map[string]*routeGroup, name string) []*routeGroup {
+	var chain []*routeGroup
+	for g, ok := groups[name]; ok; g, ok = groups[g.parent] {
+		chain = append([]*routeGroup{g}, chain...)
+	}
+	return chain
+}
+
+// parseRoutes extracts router groups, their middleware and handler registrations from the
+// route files. Groups are matched by variable name across the files, and functions of the
+// route files may be handed groups; anything else the audit cannot follow is an error
+// rather than a gap.
+func parseRoutes(moduleRoot string, paths ...string) (map[string]*routeGroup, []registration, error) {
+	fset := token.NewFileSet()
+	files := map[string]*ast.File{}
+	filePkgs := map[string]string{}
+	routeFuncs := map[string]bool{} // import path.function
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		rel, err := filepath.Rel(moduleRoot, filepath.Dir(path))
+		if err != nil {
+			return nil, nil, err
+		}
+		files[path] = file
+		filePkgs[path] = modulePath + "/" + filepath.ToSlash(rel)
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				routeFuncs[filePkgs[path]+"."+fn.Name.Name] = true
+			}
+		}
+	}
+
+	groups := map[string]*routeGroup{}
+	var registrations []registration
+	for _, path := range paths {
+		regs, err := parseRouteFile(fset, files[path], filePkgs[path], moduleRoot, groups, routeFuncs)
+		if err != nil {
+			return nil, nil, err
+		}
+		registrations = append(registrations, regs...)
+	}
+	return groups, registrations, nil
+}
+
+// parseRouteFile adds the groups declared in one route file to groups and returns its registrations
+func parseRouteFile(fset *token.FileSet, file *ast.File, pkgPath, moduleRoot string, groups map[string]*routeGroup, routeFuncs map[string]bool) ([]registration, error) {
+	imports := importAliases(file)
+	handlerVars := map[string]handlerRef{}
+
+	// handlerOf resolves a handler variable or an inline pkg.NewXHandler(...) call
+	handlerOf := func(expr ast.Expr) (handlerRef, bool) {
+		switch x := expr.(type) {
+		case *ast.Ident:
+			ref, ok := handlerVars[x.Name]
+			return ref, ok
+		case *ast.CallExpr:
+			return handlerConstructor(x, imports)
+		}
+		return handlerRef{}, false
+	}
+
+	var registrations []registration
+	var parseErr error
+	fail := func(node ast.Node, format string, args ...any) {
+		if parseErr == nil {
+			parseErr = fmt.Errorf("%s: %s", fset.Position(node.Pos()), fmt.Sprintf(format, args...))
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if parseErr != nil {
+			return false
+		}
+
+		switch stmt := n.(type) {
+		case *ast.AssignStmt:
+			if len(stmt.Lhs) != 1 || len(stmt.Rhs) != 1 {
+				return true
+			}
+			lhs, ok := stmt.Lhs[0].(*ast.Ident)
+			if !ok {
+				return true
+			}
+			call, ok := stmt.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if ref, ok := handlerConstructor(call, imports); ok {
+				handlerVars[lhs.Name] = ref
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Group" {
+				return true
+			}
+			if recv, ok := sel.X.(*ast.Ident); ok {
+				groups[lhs.Name] = &routeGroup{name: lhs.Name, parent: recv.Name}
+			}
+
+		case *ast.CallExpr:
+			var groupArgs []string
+			for _, arg := range stmt.Args {
+				if ident, ok := arg.(*ast.Ident); ok && groups[ident.Name] != nil {
+					groupArgs = append(groupArgs, ident.Name)
+				}
+			}
+
+			switch fun := stmt.Fun.(type) {
+			case *ast.SelectorExpr:
+				// Functions of the other route files are walked along with them
+				if pkg, ok := fun.X.(*ast.Ident); ok && routeFuncs[imports[pkg.Name]+"."+fun.Sel.Name] {
+					return true
+				}
+
+				if fun.Sel.Name == "Use" {
+					if recv, ok := fun.X.(*ast.Ident); ok && groups[recv.Name] != nil {
+						for _, arg := range stmt.Args {
+							if name := middlewareName(arg, imports); name != "" {
+								groups[recv.Name].middleware = append(groups[recv.Name].middleware, name)
+							}
+						}
+					}
+					return true
+				}
+
+				if strings.HasPrefix(fun.Sel.Name, "Register") {
+					ref, ok := handlerOf(fun.X)
+					switch {
+					case ok && len(groupArgs) == 0:
+						fail(stmt, "%s registers no route group declared in the route files", types.ExprString(stmt.Fun))
+					case !ok && len(groupArgs) > 0:
+						fail(stmt, "cannot resolve the handler behind %s", types.ExprString(stmt.Fun))
+					}
+					for _, group := range groupArgs {
+						registrations = append(registrations, registration{
+							pkgDir:      filepath.Join(moduleRoot, strings.TrimPrefix(ref.importPath, modulePath+"/")),
+							pkgName:     filepath.Base(ref.importPath),
+							constructor: ref.constructor,
+							function:    fun.Sel.Name,
+							group:       group,
+						})
+					}
+					return true
+				}
+			case *ast.Ident:
+				// Helpers in the same file are walked along with the rest of it
+				if routeFuncs[pkgPath+"."+fun.Name] {
+					return true
+				}
+			}
+
+			if len(groupArgs) > 0 {
+				fail(stmt, "route group %s is passed to %s, which the audit cannot follow", groupArgs[0], types.ExprString(stmt.Fun))
+			}
+		}
+		return true
+	})
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return registrations, nil
+}
+
+// handlerRef is the constructor of a handler registered in a route file
+type handlerRef struct {
+	importPath  string
+	constructor string
+}
+
+// handlerConstructor matches a pkg.NewHandler(...) or pkg.NewXHandler(...) call
+func handlerConstructor(call *ast.CallExpr, imports map[string]string) (handlerRef, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !strings.HasPrefix(sel.Sel.Name, "New") || !strings.Contains(sel.Sel.Name, "Handler") {
+		return handlerRef{}, false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return handlerRef{}, false
+	}
+	importPath, ok := imports[pkg.Name]
+	if !ok || !strings.HasPrefix(importPath, modulePath+"/") {
+		return handlerRef{}, false
+	}
+	return handlerRef{importPath: importPath, constructor: sel.Sel.Name}, true
+}
+
+// middlewarePkgs are the packages whose middleware appear in middlewareProvides
+var middlewarePkgs = map[string]bool{
+	modulePath + "/internal/middleware":            true,
+	modulePath + "/internal/middleware/management": true,
+}
+
+// middlewareName returns X for a middleware.X(...) argument
+func middlewareName(expr ast.Expr, imports map[string]string) string {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || !middlewarePkgs[imports[pkg.Name]] {
+		return ""
+	}
+	return sel.Sel.Name
+}
+
+// importAliases maps the local name of each import to its path
+func importAliases(file *ast.File) map[string]string {
+	aliases := map[string]string{}
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := filepath.Base(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		aliases[name] = path
+	}
+	return aliases
+}
+
+// externalType is the type of values declared in another package, whose methods are not followed
+const externalType = "-"
+
+// handlerPackage holds the non-test declarations of a handler package. Methods are keyed
+// Type.Method and functions by name, so calls resolve by their receiver's type.
+type handlerPackage struct {
+	funcs map[string]*ast.FuncDecl
+	// imports are the import aliases of the file declaring each function
+	imports map[*ast.FuncDecl]map[string]string
+	types   map[string]ast.Expr
+	// methods lists the types declaring each method name
+	methods map[string][]string
+}
+
+func loadHandlerPackage(dir string) (*handlerPackage, error) {
+	fset := token.NewFileSet()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &handlerPackage{
+		funcs:   map[string]*ast.FuncDecl{},
+		imports: map[*ast.FuncDecl]map[string]string{},
+		types:   map[string]ast.Expr{},
+		methods: map[string][]string{},
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		imports := importAliases(file)
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						pkg.types[ts.Name.Name] = ts.Type
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Body == nil {
+					continue
+				}
+				key := decl.Name.Name
+				if decl.Recv != nil && len(decl.Recv.List) > 0 {
+					recv := typeName(decl.Recv.List[0].Type)
+					key = recv + "." + key
+					pkg.methods[decl.Name.Name] = append(pkg.methods[decl.Name.Name], recv)
+				}
+				pkg.funcs[key] = decl
+				pkg.imports[decl] = imports
+			}
+		}
+	}
+	return pkg, nil
+}
+
+// typeName strips pointers, parentheses and type arguments from a type expression
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return typeName(t.X)
+	case *ast.ParenExpr:
+		return typeName(t.X)
+	case *ast.IndexExpr:
+		return typeName(t.X)
+	case *ast.IndexListExpr:
+		return typeName(t.X)
+	}
+	return externalType
+}
+
+// localType returns the package's type named by expr, or externalType
+func (p *handlerPackage) localType(expr ast.Expr) string {
+	if name := typeName(expr); p.types[name] != nil {
+		return name
+	}
+	return externalType
+}
+
+func (p *handlerPackage) isInterface(name string) bool {
+	_, ok := p.types[name].(*ast.InterfaceType)
+	return ok
+}
+
+// fieldType returns the type of a field of a local struct, or "" when it has no such field
+func (p *handlerPackage) fieldType(structName, field string) string {
+	st, ok := p.types[structName].(*ast.StructType)
+	if !ok {
+		return ""
+	}
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 && typeName(f.Type) == field {
+			return p.localType(f.Type)
+		}
+		for _, name := range f.Names {
+			if name.Name == field {
+				return p.localType(f.Type)
+			}
+		}
+	}
+	return ""
+}
+
+// implements reports whether typ declares every method of the interface iface with the same
+// signature. Interfaces embedding others are treated as implemented by anything.
+func (p *handlerPackage) implements(typ, iface string) bool {
+	for _, m := range p.types[iface].(*ast.InterfaceType).Methods.List {
+		if len(m.Names) == 0 {
+			return true
+		}
+		for _, name := range m.Names {
+			fn := p.funcs[typ+"."+name.Name]
+			if fn == nil || signature(fn.Type) != signature(m.Type.(*ast.FuncType)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// signature renders a function type's parameter and result types, without their names
+func signature(ft *ast.FuncType) string {
+	fieldTypes := func(fields *ast.FieldList) []string {
+		var out []string
+		if fields == nil {
+			return out
+		}
+		for _, f := range fields.List {
+			for range max(len(f.Names), 1) {
+				out = append(out, types.ExprString(f.Type))
+			}
+		}
+		return out
+	}
+	return strings.Join(fieldTypes(ft.Params), ",") + " -> " + strings.Join(fieldTypes(ft.Results), ",")
+}
+
+// methodTargets returns the Type.Method keys a call of method on a value of typ may run.
+// Values of unknown type, and promoted methods, conservatively match every method of that name.
+func (p *handlerPackage) methodTargets(typ, method string) []string {
+	var targets []string
+	switch {
+	case typ == externalType:
+	case typ != "" && p.isInterface(typ):
+		for _, impl := range p.methods[method] {
+			if p.implements(impl, typ) {
+				targets = append(targets, impl+"."+method)
+			}
+		}
+	case typ != "" && p.funcs[typ+"."+method] != nil:
+		targets = append(targets, typ+"."+method)
+	default:
+		for _, impl := range p.methods[method] {
+			targets = append(targets, impl+"."+method)
+		}
+	}
+	return targets
+}
+
+// scope maps the variables of a function to their types, ignoring shadowing
+type scope struct {
+	vars    map[string]string
+	imports map[string]string
+}
+
+func (s scope) isPackage(ident *ast.Ident) bool {
+	_, isVar := s.vars[ident.Name]
+	_, isImport := s.imports[ident.Name]
+	return isImport && !isVar
+}
+
+func (p *handlerPackage) scopeOf(fn *ast.FuncDecl) scope {
+	s := scope{vars: map[string]string{}, imports: p.imports[fn]}
+	fields := fn.Type.Params.List
+	if fn.Recv != nil {
+		fields = append(append([]*ast.Field{}, fn.Recv.List...), fields...)
+	}
+	for _, f := range fields {
+		for _, name := range f.Names {
+			s.vars[name.Name] = p.localType(f.Type)
+		}
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch stmt := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range stmt.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok || ident.Name == "_" {
+					continue
+				}
+				switch {
+				case len(stmt.Rhs) == len(stmt.Lhs):
+					s.vars[ident.Name] = p.exprType(s, stmt.Rhs[i])
+				case i == 0 && len(stmt.Rhs) == 1:
+					s.vars[ident.Name] = p.exprType(s, stmt.Rhs[0])
+				default:
+					s.vars[ident.Name] = ""
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range stmt.Names {
+				switch {
+				case stmt.Type != nil:
+					s.vars[name.Name] = p.localType(stmt.Type)
+				case i < len(stmt.Values):
+					s.vars[name.Name] = p.exprType(s, stmt.Values[i])
+				}
+			}
+		}
+		return true
+	})
+	return s
+}
+
+// exprType returns the local type of expr, externalType, or "" when it cannot tell
+func (p *handlerPackage) exprType(s scope, expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return p.exprType(s, e.X)
+	case *ast.StarExpr:
+		return p.exprType(s, e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return p.exprType(s, e.X)
+		}
+	case *ast.CompositeLit:
+		if e.Type != nil {
+			return p.localType(e.Type)
+		}
+	case *ast.BasicLit:
+		return externalType
+	case *ast.Ident:
+		return s.vars[e.Name]
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok && s.isPackage(x) {
+			return externalType
+		}
+		switch typ := p.exprType(s, e.X); {
+		case typ == externalType:
+			return externalType
+		case typ != "":
+			return p.fieldType(typ, e.Sel.Name)
+		}
+	case *ast.CallExpr:
+		switch fun := e.Fun.(type) {
+		case *ast.Ident:
+			if p.types[fun.Name] != nil {
+				return fun.Name // conversion
+			}
+			if fn := p.funcs[fun.Name]; fn != nil {
+				return p.resultType(fn)
+			}
+		case *ast.SelectorExpr:
+			if x, ok := fun.X.(*ast.Ident); ok && s.isPackage(x) {
+				return externalType
+			}
+			if targets := p.methodTargets(p.exprType(s, fun.X), fun.Sel.Name); len(targets) == 1 {
+				return p.resultType(p.funcs[targets[0]])
+			}
+		}
+	}
+	return ""
+}
+
+func (p *handlerPackage) resultType(fn *ast.FuncDecl) string {
+	if fn.Type.Results == nil || len(fn.Type.Results.List) == 0 {
+		return ""
+	}
+	return p.localType(fn.Type.Results.List[0].Type)
+}
+
+// constructorType returns the concrete type a handler constructor returns, looking past
+// constructors declared to return an interface
+func (p *handlerPackage) constructorType(constructor string) (string, error) {
+	fn := p.funcs[constructor]
+	if fn == nil {
+		return "", fmt.Errorf("%s is not declared", constructor)
+	}
+	s := p.scopeOf(fn)
+
+	var concrete string
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		ret, ok := n.(*ast.ReturnStmt)
+		if ok && len(ret.Results) > 0 && concrete == "" {
+			if typ := p.exprType(s, ret.Results[0]); typ != "" && typ != externalType && !p.isInterface(typ) {
+				concrete = typ
+			}
+		}
+		return true
+	})
+	if concrete == "" {
+		return "", fmt.Errorf("cannot tell which type %s returns", constructor)
+	}
+	return concrete, nil
+}
+
+// routeHandlers returns the methods of typ passed to gin from its named Register method
+func (p *handlerPackage) routeHandlers(typ, register string) ([]string, error) {
+	fn := p.funcs[typ+"."+register]
+	if fn == nil {
+		return nil, fmt.Errorf("%s has no method %s", typ, register)
+	}
+	s := p.scopeOf(fn)
+
+	seen := map[string]bool{}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		for _, arg := range call.Args {
+			sel, ok := arg.(*ast.SelectorExpr)
+			if !ok {
+				continue
+			}
+			if key := p.exprType(s, sel.X) + "." + sel.Sel.Name; p.funcs[key] != nil {
+				seen[key] = true
+			}
+		}
+		return true
+	})
+
+	handlers := make([]string, 0, len(seen))
+	for key := range seen {
+		handlers = append(handlers, key)
+	}
+	sort.Strings(handlers)
+	return handlers, nil
+}
+
+// reachableAccessors returns the Must* accessors called from start or anything it calls in the package
+func (p *handlerPackage) reachableAccessors(start string) []string {
+	visited := map[string]bool{}
+	found := map[string]bool{}
+	queue := []string{start}
+
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		fn := p.funcs[key]
+		if visited[key] || fn == nil {
+			continue
+		}
+		visited[key] = true
+
+		s := p.scopeOf(fn)
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.CallExpr:
+				switch fun := node.Fun.(type) {
+				case *ast.SelectorExpr:
+					if x, ok := fun.X.(*ast.Ident); ok && s.isPackage(x) {
+						if _, ok := mustAccessors[fun.Sel.Name]; ok && s.imports[x.Name] == contextPkgPath {
+							found[fun.Sel.Name] = true
+						}
+						return true
+					}
+					queue = append(queue, p.methodTargets(p.exprType(s, fun.X), fun.Sel.Name)...)
+				case *ast.Ident:
+					if _, isVar := s.vars[fun.Name]; !isVar {
+						queue = append(queue, fun.Name)
+					}
+				}
+			case *ast.SelectorExpr:
+				// Method values, e.g. callbacks, run with the same context
+				if typ := p.exprType(s, node.X); typ != "" && typ != externalType {
+					queue = append(queue, typ+"."+node.Sel.Name)
+				}
+			}
+			return true
+		})
+	}
+
+	accessors := make([]string, 0, len(found))
+	for name := range found {
+		accessors = append(accessors, name)
+	}
+	sort.Strings(accessors)
+	return accessors
+}
@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jalil32/toggle/config"
+	"github.com/jalil32/toggle/internal/pkg/batch"
+	"github.com/jalil32/toggle/internal/pkg/openapi"
+)
+
+// documentedPackages are the handler packages whose routes the OpenAPI document must cover
+var documentedPackages = []string{"flags", "projects", "tenants", "evaluation"}
+
+func TestOpenAPIDocument_MatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Nothing connects during registration; background jobs fail and are ignored
+	db, err := sql.Open("postgres", "postgres://toggle@127.0.0.1:1/toggle?sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	cfg := &config.Config{}
+	cfg.JWT.SkipAuth = true
+	router := gin.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	var flushers batch.Group
+	defer func() {
+		cancel()
+		flushers.Wait()
+	}()
+	require.NoError(t, Routes(ctx, &flushers, router, slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, sqlx.NewDb(db, "postgres")))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+
+	served := map[string]bool{}
+	for _, route := range router.Routes() {
+		if !documented(route.Handler) {
+			continue
+		}
+		served[route.Method+" "+openapi.Path(route.Path)] = true
+		assert.True(t, doc.Has(route.Method, route.Path), "%s %s is served but missing from the OpenAPI document", route.Method, route.Path)
+	}
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			assert.True(t, served[strings.ToUpper(method)+" "+path], "%s %s is documented but not served", strings.ToUpper(method), path)
+		}
+	}
+}
+
+// documented reports whether a route's handler belongs to one of documentedPackages
+func documented(handler string) bool {
+	for _, pkg := range documentedPackages {
+		if strings.HasPrefix(handler, modulePath+"/internal/"+pkg+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,34 +1,92 @@
 package routes
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"net"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc"
 
 	"github.com/jalil32/toggle/config"
+	"github.com/jalil32/toggle/internal/activity"
+	"github.com/jalil32/toggle/internal/catalog"
+	"github.com/jalil32/toggle/internal/changes"
+	"github.com/jalil32/toggle/internal/changesets"
+	"github.com/jalil32/toggle/internal/comments"
+	"github.com/jalil32/toggle/internal/deprecations"
+	"github.com/jalil32/toggle/internal/encryption"
 	"github.com/jalil32/toggle/internal/evaluation"
+	"github.com/jalil32/toggle/internal/events"
 	flags "github.com/jalil32/toggle/internal/flags"
+	"github.com/jalil32/toggle/internal/jobs"
+	"github.com/jalil32/toggle/internal/keymetrics"
+	"github.com/jalil32/toggle/internal/maintenance"
 	"github.com/jalil32/toggle/internal/middleware"
+	"github.com/jalil32/toggle/internal/middleware/management"
+	"github.com/jalil32/toggle/internal/pkg/batch"
+	"github.com/jalil32/toggle/internal/pkg/degrade"
+	"github.com/jalil32/toggle/internal/pkg/openapi"
 	"github.com/jalil32/toggle/internal/pkg/transaction"
 	"github.com/jalil32/toggle/internal/pkg/validator"
+	"github.com/jalil32/toggle/internal/presets"
+	"github.com/jalil32/toggle/internal/previews"
 	"github.com/jalil32/toggle/internal/projects"
+	"github.com/jalil32/toggle/internal/publicstatus"
+	"github.com/jalil32/toggle/internal/quotas"
+	"github.com/jalil32/toggle/internal/routes/sdk"
+	"github.com/jalil32/toggle/internal/scenarios"
+	"github.com/jalil32/toggle/internal/sdkversions"
+	"github.com/jalil32/toggle/internal/smoke"
+	"github.com/jalil32/toggle/internal/templates"
 	"github.com/jalil32/toggle/internal/tenants"
 	"github.com/jalil32/toggle/internal/users"
 )
 
-func Routes(router *gin.Engine, logger *slog.Logger, cfg *config.Config, db *sqlx.DB) error {
+// Routes wires the full API onto router. Background work runs until ctx is cancelled, and
+// the trackers' final flushes can be awaited with flushers.
+func Routes(ctx context.Context, flushers *batch.Group, router *gin.Engine, logger *slog.Logger, cfg *config.Config, db *sqlx.DB) error {
 	// Unit of Work
 	uow := transaction.NewUnitOfWork(db)
 
 	// Validators
 	tenantValidator := validator.NewTenantValidator(db)
 
+	// Online column migrations
+	flagKeyMigration, err := sdk.NewFlagKeyMigration(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Tenant data keys are wrapped by the master key; without one nothing is encrypted
+	masterKey, err := newMasterKey(cfg)
+	if err != nil {
+		return err
+	}
+
 	// Repositories
 	tenantRepo := tenants.NewRepository(db)
 	userRepo := users.NewRepository(db)
 	projectRepo := projects.NewRepository(db)
-	flagRepo := flags.NewRepository(db)
+	flagRepo := flags.NewRepository(db, flags.WithKeyMigration(flagKeyMigration))
+	templateRepo := templates.NewRepository(db)
+	changeRepo := changes.NewRepository(db)
+	changeSetRepo := changesets.NewRepository(db)
+	presetRepo := presets.NewRepository(db)
+	commentRepo := comments.NewRepository(db)
+	quotaRepo := quotas.NewRepository(db)
+	deprecationRepo := deprecations.NewRepository(db)
+	scenarioRepo := scenarios.NewRepository(db)
+	encryptionRepo := encryption.NewRepository(db)
+	catalogRepo := catalog.NewRepository(db)
+	activityRepo := activity.NewRepository(db)
+	sdkVersionRepo := sdkversions.NewRepository(db)
+	keyMetricRepo := keymetrics.NewRepository(db)
+	previewRepo := previews.NewRepository(db)
+	eventRepo := events.NewRepository(db)
 
 	// Services
 	tenantService := tenants.NewService(tenantRepo, uow, logger)
@@ -39,52 +97,240 @@ func Routes(router *gin.Engine, logger *slog.Logger, cfg *config.Config, db *sql
 
 	projectService := projects.NewService(projectRepo, logger)
 	flagService := flags.NewService(flagRepo, tenantValidator, logger)
-	evaluationService := evaluation.NewService(flagRepo, logger)
+	templateService := templates.NewService(templateRepo, logger)
+	changeService := changes.NewService(changeRepo, flagService, uow, logger)
+	changeSetService := changesets.NewService(changeSetRepo, flagService, uow, logger)
+	presetService := presets.NewService(presetRepo, tenantValidator, logger)
+	commentService := comments.NewService(commentRepo, flagService, logger)
+	scenarioService := scenarios.NewService(scenarioRepo, flagService, logger)
+	catalogService := catalog.NewService(catalogRepo, logger)
+	activityService := activity.NewService(activityRepo, logger)
+	eventService := events.NewService(eventRepo, logger)
+	sdkVersionService := sdkversions.NewService(sdkVersionRepo, tenantValidator, logger)
+	keyMetricService := keymetrics.NewService(keyMetricRepo, tenantValidator, logger)
+	publicStatusService := publicstatus.NewService(publicstatus.NewRepository(db), tenantValidator, logger)
+	previewService := previews.NewService(previewRepo, logger)
+
+	// Payload templates are in preview; tenants opt in before configuring one
+	catalogService.SetPreviews(previewService)
+
+	// Sensitive preset fields and encrypted exports are sealed with tenant data keys
+	keyring := encryption.NewKeyring(encryptionRepo, masterKey)
+	encryptionService := encryption.NewService(encryptionRepo, keyring, uow, logger)
+	presetService.SetSealer(keyring)
+	flagService.SetSealer(keyring)
+	encryptionService.AddReencrypter(presetService)
+
+	// Management API quotas count in Postgres unless configured per instance
+	var quotaCounter quotas.Counter = quotaRepo
+	if cfg.Quotas.Store == "memory" {
+		quotaCounter = quotas.NewMemoryCounter()
+	}
+	quotaService := quotas.NewService(quotaRepo, quotaCounter, quotas.Limits{
+		quotas.PlanFree:       cfg.Quotas.FreeLimit,
+		quotas.PlanTeam:       cfg.Quotas.TeamLimit,
+		quotas.PlanEnterprise: cfg.Quotas.EnterpriseLimit,
+	}, logger)
+	quotaService.SetUsageReader(quotaRepo)
+
+	// Evaluation runs the same way here as in the standalone evaluator
+	sdkStack, err := sdk.NewStack(ctx, flushers, cfg, db, flagRepo, projectRepo, logger)
+	if err != nil {
+		return err
+	}
+	// Flag changes made here reach projects that tolerate cache staleness immediately
+	flagService.SetFlagCache(sdkStack.Cache)
+
+	// Deprecated routes and fields are counted per tenant the same way
+	deprecationTracker := deprecations.NewTracker(deprecationRepo, time.Minute, logger)
+	deprecationService := deprecations.NewService(deprecationRepo, deprecationTracker, logger)
+	flushers.Go(ctx, deprecationTracker.Run)
+
+	// Background jobs
+	scheduler := jobs.NewScheduler(logger)
+	scheduler.Register(jobs.Job{Name: "expire-flag-overrides", Interval: time.Minute, Run: flagService.ExpireOverrides})
+	scheduler.Register(jobs.Job{Name: "disable-expired-flags", Interval: time.Minute, Run: flagService.DisableExpired})
+	scheduler.Register(jobs.Job{Name: "prune-flag-evaluation-counts", Interval: time.Hour, Run: flagService.PruneResultCounts})
+	scheduler.Register(jobs.Job{Name: "prune-evaluation-events", Interval: time.Hour, Run: eventService.Prune})
+	scheduler.Register(jobs.Job{Name: "prune-api-key-request-counts", Interval: time.Hour, Run: keyMetricService.Prune})
+	scheduler.Register(jobs.Job{Name: "verify-flag-key-migration", Interval: time.Minute, Run: flagKeyMigration.Verifier(flags.KeyStore{Repo: flagRepo})})
+	scheduler.Register(jobs.Job{Name: "prune-quota-windows", Interval: quotas.Window, Run: quotaService.PruneWindows})
+	scheduler.Register(jobs.Job{Name: "reencrypt-tenant-data", Interval: time.Minute, Run: encryptionService.ReencryptPending})
+	scheduler.Register(jobs.Job{Name: "push-flag-catalog", Interval: catalog.PushInterval, Run: sdkStack.Degradation.Guard(degrade.FeatureCatalogPush, catalogService.PushAll)})
+	scheduler.Start(ctx)
+
+	// Tenant policies decide who may invite members and create projects
+	userService.SetInvitePolicy(tenantService)
+	projectService.SetPolicies(tenantService)
+	projectService.SetUnitOfWork(uow)
+
+	// Inject templates into flag service (templates imports flags, so flags can't import templates)
+	flagService.SetTemplateSource(templateService)
+	flagService.SetUnitOfWork(uow)
+	// Direct edits follow the projects' approval policies; approved change requests bypass them
+	flagService.SetApprovalGate(changeService)
 
 	// Handlers
 	userHandler := users.NewHandler(userService, tenantService)
 	tenantHandler := tenants.NewHandler(tenantService)
 	projectHandler := projects.NewHandler(projectService)
 	flagHandler := flags.NewHandler(flagService)
-	evaluationHandler := evaluation.NewHandler(evaluationService)
+	templateHandler := templates.NewHandler(templateService)
+	changeHandler := changes.NewHandler(changeService)
+	changeSetHandler := changesets.NewHandler(changeSetService)
+	presetHandler := presets.NewHandler(presetService)
+	commentHandler := comments.NewHandler(commentService)
+	scenarioHandler := scenarios.NewHandler(scenarioService)
+	quotaHandler := quotas.NewHandler(quotaService)
+	deprecationHandler := deprecations.NewHandler(deprecationService)
+	encryptionHandler := encryption.NewHandler(encryptionService)
+	catalogHandler := catalog.NewHandler(catalogService)
+	activityHandler := activity.NewHandler(activityService)
+	sdkVersionHandler := sdkversions.NewHandler(sdkVersionService)
+	keyMetricHandler := keymetrics.NewHandler(keyMetricService)
+	publicStatusHandler := publicstatus.NewHandler(publicStatusService)
+	previewHandler := previews.NewHandler(previewService)
+	explainHandler := evaluation.NewExplainHandler(sdkStack.Service)
 
 	// Routes
 	api := router.Group("/api/v1")
+	api.Use(middleware.Timeout(middleware.DefaultRequestTimeout))
+	api.Use(sdkStack.Degradation.Middleware())
 
-	// Health check (public)
-	api.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Health checks and SDK routes (public / API key authentication, no Auth0)
+	sdk.RegisterRoutes(router, api, sdkStack, projectRepo, logger)
 
-	// SDK routes (API key authentication, no Auth0)
-	sdk := api.Group("/sdk")
-	sdk.Use(middleware.APIKey(projectRepo, logger))
-	{
-		evaluationHandler.RegisterRoutes(sdk)
+	// OpenAPI document of the flag, project, organization and SDK APIs (public)
+	api.GET("/openapi.json", apiDocument().Handler())
+
+	// Public flag status for docs portals (no credentials; projects opt in)
+	publicStatusHandler.RegisterPublicRoutes(api, middleware.RateLimit(120, time.Minute, logger))
+
+	// Deployment smoke test (smoke test token, no Auth0); only served when a token is configured
+	if cfg.SmokeTest.Token != "" {
+		smokeRunner := smoke.NewRunner(tenantService, projectService, flagService, sdkStack.Changes, router, logger)
+		smoke.NewHandler(smokeRunner, cfg.SmokeTest.Token).RegisterRoutes(api, middleware.RateLimit(10, time.Minute, logger))
+	}
+
+	// Evaluation benchmark (benchmark token, no Auth0); only served when a token is configured
+	if cfg.Benchmark.Token != "" {
+		evaluation.NewBenchmarkHandler(evaluation.NewBenchmarker(), cfg.Benchmark.Token).RegisterRoutes(api, middleware.RateLimit(10, time.Minute, logger))
+	}
+
+	// Maintenance announcements (maintenance token, no Auth0); only served when a token is configured
+	if cfg.Maintenance.Token != "" {
+		maintenanceService := maintenance.NewService(maintenance.NewRepository(db), sdkStack.Maintenance, logger)
+		maintenance.NewHandler(maintenanceService, cfg.Maintenance.Token).RegisterRoutes(api, middleware.RateLimit(30, time.Minute, logger))
 	}
 
 	// Protected routes (auth required)
 	protected := api.Group("")
-	protected.Use(middleware.Auth(cfg, logger, userService, tenantService))
+	protected.Use(management.Auth(cfg, logger, userService, tenantService))
 
 	// User-level routes (auth only, no tenant context required)
 	userRoutes := protected.Group("/me")
 	{
 		userHandler.RegisterRoutes(userRoutes)
-		tenantHandler.RegisterUserRoutes(userRoutes)
+		tenantHandler.RegisterUserRoutes(userRoutes, deprecationTracker)
+		tenantHandler.RegisterOrganizationRoutes(userRoutes)
+		tenantHandler.RegisterJoinRoutes(userRoutes, middleware.RateLimit(20, time.Minute, logger))
 	}
 
 	// Tenant-scoped routes (auth + X-Tenant-ID header required)
 	tenantScoped := protected.Group("")
-	tenantScoped.Use(middleware.Tenant(tenantRepo, logger))
+	tenantScoped.Use(management.Tenant(tenantRepo, logger))
+	tenantScoped.Use(management.Quota(quotaService, logger))
 	{
 		// Tenant operations
-		tenantHandler.RegisterRoutes(tenantScoped)
+		tenantHandler.RegisterRoutes(tenantScoped, deprecationTracker)
+
+		// User lookup for invitations (rate limited against email scraping)
+		userHandler.RegisterTenantRoutes(tenantScoped, middleware.RateLimit(30, time.Minute, logger))
 
 		// Projects and flags are tenant-scoped
 		projectHandler.RegisterRoutes(tenantScoped)
 		flagHandler.RegisterRoutes(tenantScoped)
+		templateHandler.RegisterRoutes(tenantScoped)
+		changeHandler.RegisterRoutes(tenantScoped)
+		changeSetHandler.RegisterRoutes(tenantScoped)
+		presetHandler.RegisterRoutes(tenantScoped)
+		commentHandler.RegisterRoutes(tenantScoped)
+		scenarioHandler.RegisterRoutes(tenantScoped)
+		quotaHandler.RegisterRoutes(tenantScoped)
+		deprecationHandler.RegisterRoutes(tenantScoped)
+		encryptionHandler.RegisterRoutes(tenantScoped)
+		catalogHandler.RegisterRoutes(tenantScoped)
+		activityHandler.RegisterRoutes(tenantScoped)
+		sdkVersionHandler.RegisterRoutes(tenantScoped)
+		keyMetricHandler.RegisterRoutes(tenantScoped)
+		publicStatusHandler.RegisterRoutes(tenantScoped)
+		explainHandler.RegisterRoutes(tenantScoped)
+		previewHandler.RegisterRoutes(tenantScoped)
+	}
+
+	// Live tails stream for minutes, so like SDK streams they skip the request timeout and
+	// the latency the degradation controller watches
+	tails := router.Group("/api/v1")
+	tails.Use(management.Auth(cfg, logger, userService, tenantService))
+	tails.Use(management.Tenant(tenantRepo, logger))
+	tails.Use(management.Quota(quotaService, logger))
+	{
+		events.NewHandler(sdkStack.Tail, flagService, logger).RegisterRoutes(tails, middleware.RateLimit(5, time.Minute, logger))
+	}
+
+	if cfg.Backend.GRPCPort != "" {
+		server := grpc.NewServer(grpc.UnaryInterceptor(management.GRPCAuth(management.GRPCVerifier(cfg, logger), tenantRepo, quotaService, logger)))
+		flags.NewGRPCServer(flagService).Register(server)
+		projects.NewGRPCServer(projectService).Register(server)
+		if err := serveGRPC(ctx, server, cfg.Backend.GRPCPort, logger); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// serveGRPC serves the gRPC management API on port alongside the REST API, until ctx is cancelled
+func serveGRPC(ctx context.Context, server *grpc.Server, port string, logger *slog.Logger) error {
+	lis, err := net.Listen("tcp", "0.0.0.0:"+port)
+	if err != nil {
+		return fmt.Errorf("failed to listen on GRPC_PORT: %w", err)
+	}
+
+	logger.Info("Starting gRPC server", "port", port)
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			logger.Error("gRPC server stopped", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	return nil
+}
+
+// apiDocument is the OpenAPI document of the routes whose packages declare their operations.
+// Each group's prefix and security must match the router group its routes are registered on.
+func apiDocument() *openapi.Document {
+	return openapi.New("Toggle API", "v1",
+		openapi.Group{Prefix: "/api/v1", Tag: "flags", Security: openapi.SecurityTenant, Operations: flags.Operations()},
+		openapi.Group{Prefix: "/api/v1", Tag: "projects", Security: openapi.SecurityTenant, Operations: projects.Operations()},
+		openapi.Group{Prefix: "/api/v1", Tag: "organizations", Security: openapi.SecurityTenant, Operations: tenants.Operations()},
+		openapi.Group{Prefix: "/api/v1/me", Tag: "organizations", Security: openapi.SecurityUser, Operations: tenants.UserOperations()},
+		openapi.Group{Prefix: "/api/v1", Tag: "evaluation", Security: openapi.SecurityTenant, Operations: evaluation.Operations()},
+		openapi.Group{Prefix: "/api/v1/sdk", Tag: "sdk", Security: openapi.SecuritySDK, Operations: evaluation.SDKOperations()},
+	)
+}
+
+// newMasterKey parses ENCRYPTION_MASTER_KEY; nil when unset
+func newMasterKey(cfg *config.Config) (*encryption.MasterKey, error) {
+	if cfg.Encryption.MasterKey == "" {
+		return nil, nil
+	}
+	masterKey, err := encryption.ParseMasterKey(cfg.Encryption.MasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ENCRYPTION_MASTER_KEY: %w", err)
+	}
+	return masterKey, nil
+}
@@ -0,0 +1,46 @@
+package sdk
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jalil32/toggle/config"
+	"github.com/jalil32/toggle/internal/pkg/batch"
+)
+
+func TestEvaluatorRoutes_RegistersOnlySDKRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Nothing connects during registration; cache priming fails in the background and is ignored
+	db, err := sql.Open("postgres", "postgres://evaluator@127.0.0.1:1/toggle?sslmode=disable")
+	require.NoError(t, err)
+	defer db.Close()
+
+	router := gin.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	var flushers batch.Group
+	defer func() {
+		cancel()
+		flushers.Wait()
+	}()
+	require.NoError(t, EvaluatorRoutes(ctx, &flushers, router, logger, &config.Config{}, sqlx.NewDb(db, "postgres")))
+
+	var paths []string
+	for _, route := range router.Routes() {
+		paths = append(paths, route.Path)
+		public := route.Path == "/api/v1/health" || route.Path == "/api/v1/ready"
+		assert.True(t, public || strings.HasPrefix(route.Path, "/api/v1/sdk/"), "unexpected route %s %s", route.Method, route.Path)
+	}
+	assert.Contains(t, paths, "/api/v1/sdk/evaluate")
+}
@@ -0,0 +1,238 @@
+// Package sdk wires the evaluation service and the API key authenticated SDK routes. It is
+// shared by the full API (internal/routes) and the standalone evaluator
+// (cmd/toggle-evaluator), and must not import the management packages, so the evaluator
+// links no user, tenant or JWT code.
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jalil32/toggle/config"
+	"github.com/jalil32/toggle/internal/evaluation"
+	"github.com/jalil32/toggle/internal/events"
+	flags "github.com/jalil32/toggle/internal/flags"
+	"github.com/jalil32/toggle/internal/keymetrics"
+	"github.com/jalil32/toggle/internal/maintenance"
+	"github.com/jalil32/toggle/internal/middleware"
+	"github.com/jalil32/toggle/internal/pkg/batch"
+	"github.com/jalil32/toggle/internal/pkg/degrade"
+	"github.com/jalil32/toggle/internal/pkg/dualwrite"
+	"github.com/jalil32/toggle/internal/projects"
+	"github.com/jalil32/toggle/internal/sdkversions"
+)
+
+// EvaluatorRoutes registers only the health checks and SDK routes, for the standalone
+// evaluation tier (cmd/toggle-evaluator). It needs no JWT settings and runs no
+// management jobs; those stay with the control plane.
+func EvaluatorRoutes(ctx context.Context, flushers *batch.Group, router *gin.Engine, logger *slog.Logger, cfg *config.Config, db *sqlx.DB) error {
+	flagKeyMigration, err := NewFlagKeyMigration(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	projectRepo := projects.NewRepository(db)
+	flagRepo := flags.NewRepository(db, flags.WithKeyMigration(flagKeyMigration))
+
+	stack, err := NewStack(ctx, flushers, cfg, db, flagRepo, projectRepo, logger)
+	if err != nil {
+		return err
+	}
+
+	api := router.Group("/api/v1")
+	api.Use(middleware.Timeout(middleware.DefaultRequestTimeout))
+	api.Use(stack.Degradation.Middleware())
+
+	RegisterRoutes(router, api, stack, projectRepo, logger)
+
+	return nil
+}
+
+// NewFlagKeyMigration returns the flag key column migration at its configured phase
+func NewFlagKeyMigration(cfg *config.Config, logger *slog.Logger) (*dualwrite.Migration, error) {
+	flagKeyPhase, err := dualwrite.ParsePhase(cfg.Migrations.FlagKeyPhase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FLAG_KEY_MIGRATION_PHASE: %w", err)
+	}
+	return dualwrite.NewMigration("flag-key", flagKeyPhase, logger), nil
+}
+
+// archiveGracePeriod parses ARCHIVED_FLAG_GRACE_PERIOD (checked by config validation);
+// empty keeps the default
+func archiveGracePeriod(cfg *config.Config) time.Duration {
+	grace, err := time.ParseDuration(cfg.Evaluation.ArchiveGracePeriod)
+	if err != nil {
+		return evaluation.DefaultArchiveGracePeriod
+	}
+	return grace
+}
+
+// evaluationBudget parses EVALUATION_BUDGET (checked by config validation);
+// empty keeps the default
+func evaluationBudget(cfg *config.Config) time.Duration {
+	budget, err := time.ParseDuration(cfg.Evaluation.Budget)
+	if err != nil {
+		return evaluation.DefaultEvaluationBudget
+	}
+	return budget
+}
+
+// newGeoLocator loads the GEOIP_DATABASE file; nil when unset
+func newGeoLocator(cfg *config.Config, logger *slog.Logger) (evaluation.GeoLocator, error) {
+	if cfg.Evaluation.GeoIPDatabase == "" {
+		return nil, nil
+	}
+	database, err := evaluation.LoadGeoIPDatabase(cfg.Evaluation.GeoIPDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GEOIP_DATABASE: %w", err)
+	}
+	logger.Info("geoip database loaded",
+		slog.String("path", cfg.Evaluation.GeoIPDatabase),
+		slog.Int("ranges", database.Len()),
+	)
+	return database, nil
+}
+
+// Stack is the evaluation service, snapshot cache, live tail, SDK version and API key trackers and maintenance schedule
+// behind the SDK routes, with the degradation controller that sheds their non-critical work under load
+type Stack struct {
+	Service     evaluation.Service
+	Cache       *evaluation.SnapshotCache
+	Changes     *evaluation.ChangeWatcher
+	Tail        *events.Tail
+	SDKs        *sdkversions.Tracker
+	Keys        *keymetrics.Tracker
+	Degradation *degrade.Controller
+	Maintenance *maintenance.Schedule
+}
+
+// NewStack builds the evaluation service and starts its background work until ctx is
+// cancelled. Its trackers flush what they hold on the way out; flushers waits for them.
+func NewStack(ctx context.Context, flushers *batch.Group, cfg *config.Config, db *sqlx.DB, flagRepo flags.Repository, projectRepo projects.Repository, logger *slog.Logger) (*Stack, error) {
+	// Projects with geo targeting locate SDK callers in the GeoIP database, if one is configured
+	geoLocator, err := newGeoLocator(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluationService := evaluation.NewService(flagRepo, projectRepo, logger)
+	evaluationService.SetArchiveGracePeriod(archiveGracePeriod(cfg))
+	evaluationService.SetEvaluationBudget(evaluationBudget(cfg))
+	evaluationService.SetGeoLocator(geoLocator)
+
+	// Under sustained overload or database latency, non-critical work is shed before
+	// evaluation or management requests are affected
+	degradation := degrade.NewController(db, degrade.DefaultThresholds, logger)
+	evaluationService.SetDegradation(degradation)
+	go degradation.Run(ctx, degrade.CheckInterval)
+	snapshotRepo := evaluation.NewSnapshotRepository(db)
+
+	// Bulk evaluations are served from cached project snapshots. The cache is primed from
+	// persisted snapshots in the background; SDK routes answer 503 until that finishes.
+	snapshotCache := evaluation.NewSnapshotCache(logger)
+	evaluationService.SetSnapshotCache(snapshotCache, snapshotRepo)
+	go func() {
+		primeCtx, cancel := context.WithTimeout(ctx, evaluation.PrimeTimeout)
+		defer cancel()
+		snapshotCache.Prime(primeCtx, snapshotRepo)
+	}()
+
+	// Flag usage is recorded in the background for stale flag detection
+	usageTracker := evaluation.NewUsageTracker(flagRepo, time.Minute, logger)
+	evaluationService.SetUsageRecorder(usageTracker)
+	flushers.Go(ctx, usageTracker.Run)
+
+	// Evaluation results are counted the same way for the flag list stats
+	resultTracker := evaluation.NewResultTracker(flagRepo, time.Minute, logger)
+	evaluationService.SetResultRecorder(resultTracker)
+	flushers.Go(ctx, resultTracker.Run)
+
+	// Context attribute names are recorded the same way for the attribute mismatch report
+	attributeTracker := evaluation.NewAttributeTracker(flagRepo, time.Minute, logger)
+	evaluationService.SetAttributeRecorder(attributeTracker)
+	flushers.Go(ctx, attributeTracker.Run)
+
+	// Every evaluation is buffered as an event and written in batches for analytics, and
+	// passed to any live tails of its flag
+	eventBuffer := events.NewBuffer(events.NewRepository(db), events.FlushInterval, logger)
+	eventTail := events.NewTail()
+	evaluationService.SetEventRecorder(events.Tee{eventBuffer, eventTail})
+	flushers.Go(ctx, eventBuffer.Run)
+
+	// SDK names and versions from request headers are counted per project the same way
+	sdkTracker := sdkversions.NewTracker(sdkversions.NewRepository(db), time.Minute, logger)
+	sdkTracker.SetGate(degradation)
+	flushers.Go(ctx, sdkTracker.Run)
+
+	// Requests and errors are counted per API key the same way
+	keyTracker := keymetrics.NewTracker(keymetrics.NewRepository(db), time.Minute, logger)
+	keyTracker.SetGate(degradation)
+	flushers.Go(ctx, keyTracker.Run)
+
+	// Streaming SDKs are told about flag changes by polling only the projects they watch
+	changeWatcher := evaluation.NewChangeWatcher(projectRepo, logger)
+	go changeWatcher.Run(ctx, evaluation.ChangePollInterval)
+
+	// Announced maintenance is read the same way, so SDK requests never wait on it
+	maintenanceSchedule := maintenance.NewSchedule(maintenance.NewRepository(db), logger)
+	go maintenanceSchedule.Run(ctx, maintenance.PollInterval)
+
+	return &Stack{Service: evaluationService, Cache: snapshotCache, Changes: changeWatcher, Tail: eventTail, SDKs: sdkTracker, Keys: keyTracker, Degradation: degradation, Maintenance: maintenanceSchedule}, nil
+}
+
+// RegisterRoutes registers the public health checks and the API key authenticated SDK routes
+func RegisterRoutes(router *gin.Engine, api *gin.RouterGroup, stack *Stack, projectRepo projects.Repository, logger *slog.Logger) {
+	evaluationHandler := evaluation.NewHandler(stack.Service)
+
+	// Health check (public): reports "degraded" while non-critical work is shed
+	api.GET("/health", func(c *gin.Context) {
+		state := stack.Degradation.State()
+		status := "ok"
+		if state.Degraded {
+			status = "degraded"
+		}
+		c.JSON(200, gin.H{"status": status, "degradation": state})
+	})
+
+	// Readiness check (public): 503 until the evaluation cache is primed
+	api.GET("/ready", func(c *gin.Context) {
+		if !stack.Cache.Ready() {
+			c.JSON(503, gin.H{"status": "starting"})
+			return
+		}
+		c.JSON(200, gin.H{"status": "ready", "cached_projects": stack.Cache.Len()})
+	})
+
+	// SDK routes (API key authentication, no Auth0)
+	sdk := api.Group("/sdk")
+	sdk.Use(middleware.Timeout(middleware.SDKRequestTimeout))
+	sdk.Use(middleware.Ready(stack.Cache.Ready))
+	sdk.Use(middleware.APIKey(projectRepo, logger))
+	sdk.Use(stack.Keys.Middleware())
+	sdk.Use(stack.SDKs.Middleware())
+	sdk.Use(stack.Maintenance.Middleware())
+	{
+		evaluationHandler.RegisterRoutes(sdk)
+		evaluation.NewOFREPHandler(stack.Service).RegisterRoutes(sdk)
+		evaluation.NewSDKConfigHandler(projectRepo).RegisterRoutes(sdk)
+	}
+
+	// SDK streams stay open indefinitely, so they skip the request timeouts and are kept
+	// out of the request latency the degradation controller watches. Each connection is
+	// counted against its key when it closes.
+	stream := router.Group("/api/v1/sdk")
+	stream.Use(middleware.Ready(stack.Cache.Ready))
+	stream.Use(middleware.APIKey(projectRepo, logger))
+	stream.Use(stack.Keys.Middleware())
+	stream.Use(stack.SDKs.Middleware())
+	{
+		streamHandler := evaluation.NewStreamHandler(stack.Changes)
+		streamHandler.SetMaintenance(stack.Maintenance)
+		streamHandler.RegisterRoutes(stream)
+	}
+}
@@ -0,0 +1,111 @@
+package scenarios
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/scenarios", h.Create)
+	r.GET("/scenarios/:id", h.Get)
+	r.POST("/scenarios/:id/replay", h.Replay)
+	r.POST("/flags/:id/preview", h.Preview)
+}
+
+// Create saves a shareable evaluation scenario for a flag and context
+func (h *handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+
+	sc := &Scenario{
+		FlagID:    req.FlagID,
+		Context:   req.Context,
+		Note:      req.Note,
+		CreatedBy: &userID,
+	}
+
+	if err := h.service.Create(c.Request.Context(), sc, tenantID); err != nil {
+		h.writeError(c, err, "failed to create scenario")
+		return
+	}
+
+	c.JSON(http.StatusCreated, sc)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	sc, err := h.service.GetByID(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get scenario")
+		return
+	}
+
+	c.JSON(http.StatusOK, sc)
+}
+
+// Replay evaluates a saved scenario again and compares it with the flag as it is now
+func (h *handler) Replay(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	result, err := h.service.Replay(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to replay scenario")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Preview dry-runs a flag against a context and reports which rules matched
+func (h *handler) Preview(c *gin.Context) {
+	var req PreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	result, err := h.service.Preview(c.Request.Context(), c.Param("id"), tenantID, req.Context)
+	if err != nil {
+		h.writeError(c, err, "failed to preview flag")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *handler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidScenarioData):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case pkgErrors.IsNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
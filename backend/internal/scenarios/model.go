@@ -0,0 +1,44 @@
+package scenarios
+
+import (
+	"time"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// Scenario is a saved evaluation: a snapshot of a flag as it was, the context it was
+// evaluated with and the result. Its short ID makes it easy to paste into tickets.
+type Scenario struct {
+	ID        string                       `json:"id" db:"id"`
+	TenantID  string                       `json:"tenant_id" db:"tenant_id"`
+	FlagID    string                       `json:"flag_id" db:"flag_id"`
+	Flag      flag.Flag                    `json:"flag" db:"flag_snapshot"`
+	Context   evaluation.EvaluationContext `json:"context" db:"context"`
+	Enabled   bool                         `json:"enabled" db:"enabled"`
+	Note      string                       `json:"note" db:"note"`
+	CreatedBy *string                      `json:"created_by" db:"created_by"`
+	CreatedAt time.Time                    `json:"created_at" db:"created_at"`
+}
+
+// ReplayResult is a scenario evaluated again, alongside the flag as it is now
+type ReplayResult struct {
+	ScenarioID string `json:"scenario_id"`
+	// Enabled is the snapshot evaluated with the saved context; it never changes
+	Enabled bool `json:"enabled"`
+	// CurrentEnabled is the current flag evaluated with the saved context, nil if the flag was deleted
+	CurrentEnabled *bool `json:"current_enabled"`
+	// Changed reports whether the flag's current definition gives a different result
+	Changed bool `json:"changed"`
+}
+
+type CreateRequest struct {
+	FlagID  string                       `json:"flag_id" binding:"required"`
+	Context evaluation.EvaluationContext `json:"context" binding:"required"`
+	Note    string                       `json:"note"`
+}
+
+// PreviewRequest is a dry-run evaluation of one flag from the dashboard
+type PreviewRequest struct {
+	Context evaluation.EvaluationContext `json:"context" binding:"required"`
+}
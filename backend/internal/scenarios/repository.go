@@ -0,0 +1,76 @@
+package scenarios
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	Create(ctx context.Context, s *Scenario) error
+	GetByID(ctx context.Context, id string, tenantID string) (*Scenario, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+// Create stores a scenario under its pre-generated short ID
+func (r *postgresRepository) Create(ctx context.Context, s *Scenario) error {
+	flagJSON, err := json.Marshal(s.Flag)
+	if err != nil {
+		return err
+	}
+	contextJSON, err := json.Marshal(s.Context)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO evaluation_scenarios (id, tenant_id, flag_id, flag_snapshot, context, enabled, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, s.ID, s.TenantID, s.FlagID, flagJSON, contextJSON, s.Enabled, s.Note, s.CreatedBy).
+		Scan(&s.CreatedAt)
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id string, tenantID string) (*Scenario, error) {
+	query := `
+		SELECT id, tenant_id, flag_id, flag_snapshot, context, enabled, note, created_by, created_at
+		FROM evaluation_scenarios
+		WHERE id = $1 AND tenant_id = $2
+	`
+
+	var s Scenario
+	var flagJSON, contextJSON []byte
+	err := r.getDB(ctx).QueryRowxContext(ctx, query, id, tenantID).
+		Scan(&s.ID, &s.TenantID, &s.FlagID, &flagJSON, &contextJSON, &s.Enabled, &s.Note, &s.CreatedBy, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(flagJSON, &s.Flag); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contextJSON, &s.Context); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,166 @@
+package scenarios
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
+)
+
+var ErrInvalidScenarioData = errors.New("invalid scenario")
+
+// idAlphabet leaves out look-alike characters so IDs survive being retyped from a ticket
+const idAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// IDLength is the length of a scenario's short ID
+const IDLength = 10
+
+type Service interface {
+	Create(ctx context.Context, s *Scenario, tenantID string) error
+	GetByID(ctx context.Context, id string, tenantID string) (*Scenario, error)
+	Replay(ctx context.Context, id string, tenantID string) (*ReplayResult, error)
+	Preview(ctx context.Context, flagID string, tenantID string, evalCtx evaluation.EvaluationContext) (*evaluation.Explanation, error)
+}
+
+type service struct {
+	repo      Repository
+	flags     flag.Service
+	evaluator *evaluation.Evaluator
+	logger    *slog.Logger
+}
+
+func NewService(repo Repository, flags flag.Service, logger *slog.Logger) Service {
+	return &service{
+		repo:      repo,
+		flags:     flags,
+		evaluator: evaluation.NewEvaluator(),
+		logger:    logger,
+	}
+}
+
+// Create snapshots a flag in the tenant, evaluates it with the given context and saves both.
+// Only the flag definition is captured; per-user overrides and exclusions are not replayed.
+func (s *service) Create(ctx context.Context, sc *Scenario, tenantID string) error {
+	if sc == nil || sc.FlagID == "" {
+		return fmt.Errorf("%w: flag_id is required", ErrInvalidScenarioData)
+	}
+	if err := sc.Context.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidScenarioData, err)
+	}
+
+	note, err := sanitize.Text(sc.Note, sanitize.MaxDescriptionLength)
+	if err != nil {
+		return fmt.Errorf("%w: note must be at most %d characters", ErrInvalidScenarioData, sanitize.MaxDescriptionLength)
+	}
+	sc.Note = note
+
+	// GetByID maps flags outside the tenant to ErrNotFound
+	f, err := s.flags.GetByID(ctx, sc.FlagID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return fmt.Errorf("failed to generate scenario id: %w", err)
+	}
+
+	sc.ID = id
+	sc.TenantID = tenantID
+	sc.Flag = *f
+	sc.Enabled = s.evaluator.Evaluate(f, sc.Context)
+
+	if err := s.repo.Create(ctx, sc); err != nil {
+		s.logger.Error("failed to create scenario",
+			slog.String("flag_id", sc.FlagID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to create scenario: %w", err)
+	}
+
+	s.logger.Info("scenario created",
+		slog.String("id", sc.ID),
+		slog.String("flag_id", sc.FlagID),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+func (s *service) GetByID(ctx context.Context, id string, tenantID string) (*Scenario, error) {
+	sc, err := s.repo.GetByID(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get scenario",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to get scenario: %w", err)
+	}
+
+	return sc, nil
+}
+
+// Replay evaluates a saved scenario again, and the flag's current definition with the same context
+func (s *service) Replay(ctx context.Context, id string, tenantID string) (*ReplayResult, error) {
+	sc, err := s.GetByID(ctx, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReplayResult{
+		ScenarioID: sc.ID,
+		Enabled:    s.evaluator.Evaluate(&sc.Flag, sc.Context),
+	}
+
+	current, err := s.flags.GetByID(ctx, sc.FlagID, tenantID)
+	if err != nil && !pkgErrors.IsNotFoundError(err) {
+		return nil, err
+	}
+	if current != nil {
+		enabled := s.evaluator.Evaluate(current, sc.Context)
+		result.CurrentEnabled = &enabled
+		result.Changed = enabled != result.Enabled
+	}
+
+	return result, nil
+}
+
+// Preview evaluates a flag's current definition with the given context without saving anything.
+// Like scenarios it ignores per-user overrides and exclusions, and it works on flags in any
+// lifecycle state so targeting can be checked before a flag is switched on.
+func (s *service) Preview(ctx context.Context, flagID string, tenantID string, evalCtx evaluation.EvaluationContext) (*evaluation.Explanation, error) {
+	if err := evalCtx.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidScenarioData, err)
+	}
+
+	f, err := s.flags.GetByID(ctx, flagID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.evaluator.Explain(f, evalCtx), nil
+}
+
+// newID returns a random short ID drawn from idAlphabet
+func newID() (string, error) {
+	b := make([]byte, IDLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = idAlphabet[int(b[i])%len(idAlphabet)]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,202 @@
+package scenarios
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type mockRepository struct {
+	created map[string]*Scenario
+}
+
+func (m *mockRepository) Create(ctx context.Context, s *Scenario) error {
+	if m.created == nil {
+		m.created = make(map[string]*Scenario)
+	}
+	copied := *s
+	m.created[s.ID] = &copied
+	return nil
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string, tenantID string) (*Scenario, error) {
+	s, ok := m.created[id]
+	if !ok || s.TenantID != tenantID {
+		return nil, sql.ErrNoRows
+	}
+	copied := *s
+	return &copied, nil
+}
+
+// mockFlagService implements flag.Service; only GetByID is used by scenarios
+type mockFlagService struct {
+	flag.Service
+	flag *flag.Flag
+}
+
+func (m *mockFlagService) GetByID(ctx context.Context, id string, tenantID string) (*flag.Flag, error) {
+	if m.flag == nil || m.flag.ID != id || m.flag.TenantID != tenantID {
+		return nil, pkgErrors.ErrNotFound
+	}
+	copied := *m.flag
+	return &copied, nil
+}
+
+func proPlanFlag() *flag.Flag {
+	return &flag.Flag{
+		ID:        "flag-1",
+		TenantID:  "tenant-1",
+		Enabled:   true,
+		RuleLogic: flag.RuleLogicAnd,
+		Rules:     []flag.Rule{{Attribute: "plan", Operator: "equals", Value: "pro", Rollout: 100}},
+	}
+}
+
+func proContext() evaluation.EvaluationContext {
+	return evaluation.EvaluationContext{UserID: "user-1", Attributes: map[string]interface{}{"plan": "pro"}}
+}
+
+func TestCreate_SnapshotsFlagAndResult(t *testing.T) {
+	repo := &mockRepository{}
+	svc := NewService(repo, &mockFlagService{flag: proPlanFlag()}, slog.Default())
+
+	sc := &Scenario{FlagID: "flag-1", Context: proContext(), Note: "  pro users not seeing checkout  "}
+	if err := svc.Create(context.Background(), sc, "tenant-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sc.ID) != IDLength || strings.Trim(sc.ID, idAlphabet) != "" {
+		t.Errorf("expected a %d character short ID, got %q", IDLength, sc.ID)
+	}
+	if !sc.Enabled {
+		t.Error("expected the saved result to be enabled")
+	}
+	if sc.Flag.ID != "flag-1" || len(sc.Flag.Rules) != 1 {
+		t.Errorf("expected the flag to be snapshotted, got %+v", sc.Flag)
+	}
+	if sc.Note != "pro users not seeing checkout" {
+		t.Errorf("expected trimmed note, got %q", sc.Note)
+	}
+	if _, ok := repo.created[sc.ID]; !ok {
+		t.Error("expected scenario to be stored")
+	}
+}
+
+func TestCreate_FlagInOtherTenant(t *testing.T) {
+	repo := &mockRepository{}
+	svc := NewService(repo, &mockFlagService{flag: proPlanFlag()}, slog.Default())
+
+	err := svc.Create(context.Background(), &Scenario{FlagID: "flag-1", Context: proContext()}, "tenant-2")
+	if !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if len(repo.created) != 0 {
+		t.Error("expected nothing to be stored")
+	}
+}
+
+func TestCreate_RequiresUserID(t *testing.T) {
+	svc := NewService(&mockRepository{}, &mockFlagService{flag: proPlanFlag()}, slog.Default())
+
+	err := svc.Create(context.Background(), &Scenario{FlagID: "flag-1"}, "tenant-1")
+	if !errors.Is(err, ErrInvalidScenarioData) {
+		t.Errorf("expected ErrInvalidScenarioData, got %v", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     func(f *flag.Flag) *flag.Flag
+		wantCurrent *bool
+		wantChanged bool
+	}{
+		{name: "flag unchanged", current: func(f *flag.Flag) *flag.Flag { return f }, wantCurrent: boolPtr(true)},
+		{name: "flag disabled since", current: func(f *flag.Flag) *flag.Flag {
+			f.Enabled = false
+			return f
+		}, wantCurrent: boolPtr(false), wantChanged: true},
+		{name: "flag deleted since", current: func(f *flag.Flag) *flag.Flag { return nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{}
+			flags := &mockFlagService{flag: proPlanFlag()}
+			svc := NewService(repo, flags, slog.Default())
+
+			sc := &Scenario{FlagID: "flag-1", Context: proContext()}
+			if err := svc.Create(context.Background(), sc, "tenant-1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			flags.flag = tt.current(proPlanFlag())
+
+			result, err := svc.Replay(context.Background(), sc.ID, "tenant-1")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.Enabled {
+				t.Error("expected the snapshot to replay as enabled")
+			}
+			if (result.CurrentEnabled == nil) != (tt.wantCurrent == nil) ||
+				result.CurrentEnabled != nil && *result.CurrentEnabled != *tt.wantCurrent {
+				t.Errorf("expected current %v, got %v", tt.wantCurrent, result.CurrentEnabled)
+			}
+			if result.Changed != tt.wantChanged {
+				t.Errorf("expected changed %v, got %v", tt.wantChanged, result.Changed)
+			}
+		})
+	}
+}
+
+func TestReplay_ScenarioInOtherTenant(t *testing.T) {
+	repo := &mockRepository{}
+	svc := NewService(repo, &mockFlagService{flag: proPlanFlag()}, slog.Default())
+
+	sc := &Scenario{FlagID: "flag-1", Context: proContext()}
+	if err := svc.Create(context.Background(), sc, "tenant-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := svc.Replay(context.Background(), sc.ID, "tenant-2"); !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestPreview(t *testing.T) {
+	f := proPlanFlag()
+	f.Enabled = false
+	f.Rules = append(f.Rules, flag.Rule{ID: "rule-2", Attribute: "country", Operator: "equals", Value: "AU", Rollout: 100})
+	svc := NewService(&mockRepository{}, &mockFlagService{flag: f}, slog.Default())
+
+	result, err := svc.Preview(context.Background(), "flag-1", "tenant-1", proContext())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Enabled || result.Reason != evaluation.ReasonDisabled {
+		t.Errorf("expected a switched off flag to preview as disabled, got %+v", result)
+	}
+	if result.Targeted {
+		t.Error("expected the AND rules not to target a user without a country")
+	}
+	if len(result.Rules) != 2 || !result.Rules[0].Matched || result.Rules[1].Matched {
+		t.Errorf("expected only the plan rule to match, got %+v", result.Rules)
+	}
+}
+
+func TestPreview_FlagInOtherTenant(t *testing.T) {
+	svc := NewService(&mockRepository{}, &mockFlagService{flag: proPlanFlag()}, slog.Default())
+
+	if _, err := svc.Preview(context.Background(), "flag-1", "tenant-2", proContext()); !pkgErrors.IsNotFoundError(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
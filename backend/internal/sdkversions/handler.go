@@ -0,0 +1,43 @@
+package sdkversions
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/projects/:id/sdk-versions", h.List)
+}
+
+// List returns the SDK names and versions calling the project, so outdated SDKs can be upgraded
+func (h *handler) List(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	usage, err := h.service.List(c.Request.Context(), c.Param("id"), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sdk versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,104 @@
+package sdkversions
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SDKHeader identifies the calling SDK as "name/version", e.g. "toggle-go/1.4.0".
+// Without it, a User-Agent whose first product is a toggle SDK is used instead.
+const SDKHeader = "X-Toggle-SDK"
+
+// userAgentPrefix marks User-Agent products sent by our SDKs; other agents
+// (browsers, HTTP client defaults) aren't SDKs and aren't tracked
+const userAgentPrefix = "toggle-"
+
+// MaxFieldLength bounds SDK names and versions taken from request headers
+const MaxFieldLength = 64
+
+// SDK is the name and version of a client SDK
+type SDK struct {
+	Name    string `json:"sdk_name" db:"sdk_name"`
+	Version string `json:"sdk_version" db:"sdk_version"`
+}
+
+// Usage is how often one SDK version called a project's SDK routes
+type Usage struct {
+	SDK
+	RequestCount int64     `json:"request_count" db:"request_count"`
+	FirstSeenAt  time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt   time.Time `json:"last_seen_at" db:"last_seen_at"`
+	// Outdated is set when the project has seen a newer version of the same SDK
+	Outdated bool `json:"outdated" db:"-"`
+}
+
+// ParseSDK identifies the SDK from the X-Toggle-SDK header, falling back to the User-Agent
+func ParseSDK(sdkHeader string, userAgent string) (SDK, bool) {
+	if sdk, ok := parseProduct(sdkHeader); ok {
+		return sdk, true
+	}
+
+	fields := strings.Fields(userAgent)
+	if len(fields) == 0 || !strings.HasPrefix(strings.ToLower(fields[0]), userAgentPrefix) {
+		return SDK{}, false
+	}
+	return parseProduct(fields[0])
+}
+
+// parseProduct parses a "name/version" product token
+func parseProduct(s string) (SDK, bool) {
+	name, version, ok := strings.Cut(strings.TrimSpace(s), "/")
+	name, version = strings.TrimSpace(name), strings.TrimSpace(version)
+	if !ok || name == "" || version == "" || len(name) > MaxFieldLength || len(version) > MaxFieldLength {
+		return SDK{}, false
+	}
+	return SDK{Name: strings.ToLower(name), Version: version}, true
+}
+
+// CompareVersions orders dotted versions numerically ("1.10.0" > "1.9.2"), ignoring a
+// leading "v"; a pre-release ("2.0.0-beta") sorts before its release. Returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		if c := compareParts(part(aParts, i), part(bParts, i)); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+func part(parts []string, i int) string {
+	if i < len(parts) {
+		return parts[i]
+	}
+	return "0"
+}
+
+// compareParts compares numerically when both parts are numbers, lexically otherwise
+func compareParts(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case an < bn:
+		return -1
+	case an > bn:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package sdkversions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSDK(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		userAgent string
+		want      SDK
+		ok        bool
+	}{
+		{name: "sdk header", header: "toggle-go/1.4.0", want: SDK{Name: "toggle-go", Version: "1.4.0"}, ok: true},
+		{name: "header wins over user agent", header: "Toggle-JS/2.0.0", userAgent: "toggle-go/1.0.0", want: SDK{Name: "toggle-js", Version: "2.0.0"}, ok: true},
+		{name: "sdk user agent", userAgent: "toggle-python/0.9.1 (CPython 3.12)", want: SDK{Name: "toggle-python", Version: "0.9.1"}, ok: true},
+		{name: "other user agent", userAgent: "Go-http-client/1.1"},
+		{name: "browser user agent", userAgent: "Mozilla/5.0 (X11; Linux x86_64)"},
+		{name: "missing version", header: "toggle-go"},
+		{name: "no headers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseSDK(tt.header, tt.userAgent)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.2", 1},
+		{"v1.2.0", "1.2", 0},
+		{"2.0.0-beta", "2.0.0", -1},
+		{"2.0.0-beta.2", "2.0.0-beta.1", 1},
+		{"0.9", "1.0", -1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CompareVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+	}
+}
+
+func TestMarkOutdated(t *testing.T) {
+	usage := []Usage{
+		{SDK: SDK{Name: "toggle-js", Version: "1.9.0"}},
+		{SDK: SDK{Name: "toggle-go", Version: "1.4.0"}},
+		{SDK: SDK{Name: "toggle-js", Version: "1.10.0"}},
+	}
+
+	markOutdated(usage)
+
+	assert.Equal(t, []Usage{
+		{SDK: SDK{Name: "toggle-go", Version: "1.4.0"}},
+		{SDK: SDK{Name: "toggle-js", Version: "1.10.0"}},
+		{SDK: SDK{Name: "toggle-js", Version: "1.9.0"}, Outdated: true},
+	}, usage)
+}
@@ -0,0 +1,65 @@
+package sdkversions
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository interface {
+	UsageStore
+	ListByProject(ctx context.Context, projectID string, tenantID string) ([]Usage, error)
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// RecordUsage adds request counts per project and SDK version
+func (r *postgresRepository) RecordUsage(ctx context.Context, counts map[UsageKey]int64, at time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for key, count := range counts {
+		// The tenant is repeated in the WHERE clause so a key can't write to another tenant's project
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO sdk_usage (project_id, tenant_id, sdk_name, sdk_version, request_count, first_seen_at, last_seen_at)
+			SELECT p.id, p.tenant_id, $3, $4, $5, $6, $6
+			FROM projects p
+			WHERE p.id = $1 AND p.tenant_id = $2
+			ON CONFLICT (project_id, sdk_name, sdk_version) DO UPDATE
+			SET request_count = sdk_usage.request_count + EXCLUDED.request_count,
+			    last_seen_at = GREATEST(sdk_usage.last_seen_at, EXCLUDED.last_seen_at)
+		`, key.ProjectID, key.TenantID, key.SDK.Name, key.SDK.Version, count, at)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListByProject returns a project's SDK versions, ordered by SDK name
+func (r *postgresRepository) ListByProject(ctx context.Context, projectID string, tenantID string) ([]Usage, error) {
+	usage := []Usage{}
+	err := sqlx.SelectContext(ctx, r.db, &usage, `
+		SELECT sdk_name, sdk_version, request_count, first_seen_at, last_seen_at
+		FROM sdk_usage
+		WHERE project_id = $1 AND tenant_id = $2
+		ORDER BY sdk_name, last_seen_at DESC
+	`, projectID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
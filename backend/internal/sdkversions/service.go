@@ -0,0 +1,70 @@
+package sdkversions
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/validator"
+)
+
+type Service interface {
+	// List returns the SDK versions calling a project, newest version of each SDK first,
+	// with older versions marked outdated
+	List(ctx context.Context, projectID string, tenantID string) ([]Usage, error)
+}
+
+type service struct {
+	repo      Repository
+	validator validator.Validator
+	logger    *slog.Logger
+}
+
+func NewService(repo Repository, val validator.Validator, logger *slog.Logger) Service {
+	return &service{
+		repo:      repo,
+		validator: val,
+		logger:    logger,
+	}
+}
+
+func (s *service) List(ctx context.Context, projectID string, tenantID string) ([]Usage, error) {
+	if err := s.validator.ValidateProjectOwnership(ctx, projectID, tenantID); err != nil {
+		return nil, pkgErrors.ErrProjectNotInTenant
+	}
+
+	usage, err := s.repo.ListByProject(ctx, projectID, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list sdk versions",
+			slog.String("project_id", projectID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list sdk versions: %w", err)
+	}
+
+	markOutdated(usage)
+	return usage, nil
+}
+
+// markOutdated sorts usage by SDK name and newest version, flagging every version
+// older than the newest one seen for its SDK
+func markOutdated(usage []Usage) {
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].Name != usage[j].Name {
+			return usage[i].Name < usage[j].Name
+		}
+		return CompareVersions(usage[i].Version, usage[j].Version) > 0
+	})
+
+	var newest string
+	for i := range usage {
+		if i == 0 || usage[i].Name != usage[i-1].Name {
+			newest = usage[i].Version
+			continue
+		}
+		usage[i].Outdated = CompareVersions(usage[i].Version, newest) < 0
+	}
+}
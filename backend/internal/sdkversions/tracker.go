@@ -0,0 +1,126 @@
+package sdkversions
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/jalil32/toggle/internal/pkg/batch"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	"github.com/jalil32/toggle/internal/pkg/degrade"
+)
+
+// MaxTrackedVersionsPerProject bounds distinct SDK versions counted per project between
+// flushes, so clients sending arbitrary headers can't grow memory or the table without limit
+const MaxTrackedVersionsPerProject = 100
+
+// UsageStore persists SDK request counts
+type UsageStore interface {
+	RecordUsage(ctx context.Context, counts map[UsageKey]int64, at time.Time) error
+}
+
+// UsageKey identifies one SDK version calling one project
+type UsageKey struct {
+	ProjectID string
+	TenantID  string
+	SDK       SDK
+}
+
+// Tracker counts SDK versions per project in memory and writes the counts to the store
+// periodically, keeping database writes off the evaluation path
+type Tracker struct {
+	store    UsageStore
+	interval time.Duration
+	gate     degrade.Gate
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	pending  map[UsageKey]int64
+	versions map[string]int // project ID -> distinct versions pending
+}
+
+func NewTracker(store UsageStore, interval time.Duration, logger *slog.Logger) *Tracker {
+	return &Tracker{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+		pending:  make(map[UsageKey]int64),
+		versions: make(map[string]int),
+	}
+}
+
+// SetGate stops counting while analytics writes are shed by an overloaded instance
+func (t *Tracker) SetGate(gate degrade.Gate) {
+	t.gate = gate
+}
+
+// Middleware records the calling SDK of every request; it must run after API key
+// authentication so the project is known. Requests without an SDK header are ignored.
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if t.gate != nil && !t.gate.Allow(degrade.FeatureAnalytics) {
+			c.Next()
+			return
+		}
+		if sdk, ok := ParseSDK(c.GetHeader(SDKHeader), c.Request.UserAgent()); ok {
+			ctx := c.Request.Context()
+			projectID, _ := appContext.ProjectID(ctx)
+			tenantID, _ := appContext.TenantID(ctx)
+			t.Record(projectID, tenantID, sdk)
+		}
+		c.Next()
+	}
+}
+
+// Record counts one request from an SDK version; it never blocks on the database
+func (t *Tracker) Record(projectID string, tenantID string, sdk SDK) {
+	if projectID == "" || tenantID == "" {
+		return
+	}
+
+	key := UsageKey{ProjectID: projectID, TenantID: tenantID, SDK: sdk}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.pending[key]; !ok {
+		if t.versions[projectID] >= MaxTrackedVersionsPerProject {
+			return
+		}
+		t.versions[projectID]++
+	}
+	t.pending[key]++
+}
+
+// Flush writes all pending counts to the store
+func (t *Tracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	counts := t.pending
+	t.pending = make(map[UsageKey]int64)
+	t.versions = make(map[string]int)
+	t.mu.Unlock()
+
+	if err := t.store.RecordUsage(ctx, counts, time.Now()); err != nil {
+		// Telemetry is best-effort: dropped counts are recovered by the next requests
+		t.logger.Warn("failed to record sdk usage",
+			slog.Int("keys", len(counts)),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	t.logger.Debug("sdk usage recorded", slog.Int("keys", len(counts)))
+	return nil
+}
+
+// Run flushes pending counts every interval until ctx is cancelled, then flushes once more
+func (t *Tracker) Run(ctx context.Context) {
+	batch.Run(ctx, t.interval, t.Flush)
+}
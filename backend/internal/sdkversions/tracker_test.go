@@ -0,0 +1,99 @@
+package sdkversions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+)
+
+type mockStore struct {
+	recorded []map[UsageKey]int64
+	err      error
+}
+
+func (m *mockStore) RecordUsage(ctx context.Context, counts map[UsageKey]int64, at time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.recorded = append(m.recorded, counts)
+	return nil
+}
+
+func TestTracker_MiddlewareCountsPerProjectAndVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &mockStore{}
+	tracker := NewTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(appContext.WithSDKAuth(c.Request.Context(), c.GetHeader("X-Project-ID"), "tenant-1"))
+		c.Next()
+	})
+	router.Use(tracker.Middleware())
+	router.GET("/sdk/evaluate", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	requests := []struct{ projectID, sdk, userAgent string }{
+		{"project-1", "toggle-go/1.4.0", ""},
+		{"project-1", "toggle-go/1.4.0", ""},
+		{"project-1", "", "toggle-js/2.0.0"},
+		{"project-2", "toggle-go/1.3.0", ""},
+		{"project-2", "", "curl/8.0"},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(http.MethodGet, "/sdk/evaluate", nil)
+		req.Header.Set("X-Project-ID", r.projectID)
+		req.Header.Set(SDKHeader, r.sdk)
+		req.Header.Set("User-Agent", r.userAgent)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	require.Len(t, store.recorded, 1)
+	assert.Equal(t, map[UsageKey]int64{
+		{ProjectID: "project-1", TenantID: "tenant-1", SDK: SDK{Name: "toggle-go", Version: "1.4.0"}}: 2,
+		{ProjectID: "project-1", TenantID: "tenant-1", SDK: SDK{Name: "toggle-js", Version: "2.0.0"}}: 1,
+		{ProjectID: "project-2", TenantID: "tenant-1", SDK: SDK{Name: "toggle-go", Version: "1.3.0"}}: 1,
+	}, store.recorded[0])
+
+	// Nothing pending: no write
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Len(t, store.recorded, 1)
+}
+
+func TestTracker_BoundsVersionsPerProject(t *testing.T) {
+	store := &mockStore{}
+	tracker := NewTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i := 0; i < MaxTrackedVersionsPerProject+10; i++ {
+		tracker.Record("project-1", "tenant-1", SDK{Name: "toggle-go", Version: fmt.Sprintf("1.0.%d", i)})
+	}
+	tracker.Record("project-2", "tenant-1", SDK{Name: "toggle-go", Version: "1.0.0"})
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Len(t, store.recorded[0], MaxTrackedVersionsPerProject+1)
+}
+
+func TestTracker_FlushReportsStoreErrors(t *testing.T) {
+	store := &mockStore{err: errors.New("database error")}
+	tracker := NewTracker(store, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tracker.Record("project-1", "tenant-1", SDK{Name: "toggle-go", Version: "1.0.0"})
+
+	assert.Error(t, tracker.Flush(context.Background()))
+}
@@ -36,7 +36,7 @@ func TestAPIKey_InvalidKey_Returns401(t *testing.T) {
 	projectRepo := projects.NewRepository(db)
 	flagRepo := flagspkg.NewRepository(db)
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	evalService := evaluation.NewService(flagRepo, logger)
+	evalService := evaluation.NewService(flagRepo, projectRepo, logger)
 	evalHandler := evaluation.NewHandler(evalService)
 
 	// Setup Gin router with SDK routes
@@ -103,7 +103,7 @@ func TestAPIKey_SQLInjection_Safe(t *testing.T) {
 	projectRepo := projects.NewRepository(db)
 	flagRepo := flagspkg.NewRepository(db)
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	evalService := evaluation.NewService(flagRepo, logger)
+	evalService := evaluation.NewService(flagRepo, projectRepo, logger)
 	evalHandler := evaluation.NewHandler(evalService)
 
 	// Setup Gin router with SDK routes
@@ -159,7 +159,7 @@ func TestAPIKey_TenantIsolation_StrictSeparation(t *testing.T) {
 	projectRepo := projects.NewRepository(db)
 	flagRepo := flagspkg.NewRepository(db)
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	evalService := evaluation.NewService(flagRepo, logger)
+	evalService := evaluation.NewService(flagRepo, projectRepo, logger)
 	evalHandler := evaluation.NewHandler(evalService)
 
 	// Setup Gin router with SDK routes
@@ -298,3 +298,49 @@ func TestAPIKey_DirectRepositoryAccess_ParameterizedQueries(t *testing.T) {
 		}
 	})
 }
+
+// TestAPIKey_ClientSideKey_CannotReadRules tests that a project's public client-side key
+// evaluates flags but is refused the ruleset and snapshot, which carry every rule
+func TestAPIKey_ClientSideKey_CannotReadRules(t *testing.T) {
+	db := testutil.GetTestDB()
+	projectRepo := projects.NewRepository(db)
+	flagRepo := flagspkg.NewRepository(db)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	evalHandler := evaluation.NewHandler(evaluation.NewService(flagRepo, projectRepo, logger))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	sdk := router.Group("/sdk")
+	sdk.Use(middleware.APIKey(projectRepo, logger))
+	evalHandler.RegisterRoutes(sdk)
+
+	tx, err := db.Beginx()
+	require.NoError(t, err)
+	defer func() {
+		_, _ = db.Exec("DELETE FROM flags WHERE project_id IN (SELECT id FROM projects WHERE name = 'Client Side Project')")
+		_, _ = db.Exec("DELETE FROM projects WHERE name = 'Client Side Project'")
+		_, _ = db.Exec("DELETE FROM tenants WHERE slug = 'client-side-test'")
+	}()
+	tenant := testutil.CreateTenant(t, tx, "Client Side", "client-side-test")
+	project := testutil.CreateProject(t, tx, tenant.ID, "Client Side Project", generateAPIKey())
+	testutil.CreateFlag(t, tx, tenant.ID, &project.ID, "client-side-flag", "Client Side Flag", true)
+	require.NoError(t, tx.Commit())
+
+	stored, err := projectRepo.GetByID(context.Background(), project.ID, tenant.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, stored.ClientSideAPIKey)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+stored.ClientSideAPIKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/sdk/ruleset", "").Code, "client-side key must not read the ruleset")
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/sdk/snapshot", "").Code, "client-side key must not read the snapshot")
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/sdk/bootstrap", `{"context":{"user_id":"test-user"}}`).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/sdk/evaluate", `{"context":{"user_id":"test-user"}}`).Code)
+}
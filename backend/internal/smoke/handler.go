@@ -0,0 +1,53 @@
+package smoke
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	runner *Runner
+	token  string
+}
+
+// NewHandler serves the runner to callers presenting token as a bearer token.
+// Pipelines don't sign in, so the token stands in for an instance administrator.
+func NewHandler(runner *Runner, token string) *Handler {
+	return &Handler{runner: runner, token: token}
+}
+
+// RegisterRoutes registers the smoke test route; it authenticates its own callers and must
+// not be behind Auth
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup, middleware ...gin.HandlerFunc) {
+	r.POST("/admin/smoke-test", append(middleware, h.Run)...)
+}
+
+// Run executes the smoke test. It responds 200 when every step passed and 503 when one
+// failed, with the step results either way, so pipelines can gate on the status alone.
+func (h *Handler) Run(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid smoke test token"})
+		return
+	}
+
+	result, err := h.runner.Run(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, ErrRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "smoke test failed to run"})
+		return
+	}
+
+	status := http.StatusOK
+	if !result.Passed {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, result)
+}
@@ -0,0 +1,299 @@
+// Package smoke runs an end-to-end smoke test against the running instance, so
+// deployment pipelines can check a new release before shifting traffic to it.
+package smoke
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+	flag "github.com/jalil32/toggle/internal/flags"
+	"github.com/jalil32/toggle/internal/projects"
+	"github.com/jalil32/toggle/internal/tenants"
+)
+
+// MaxDuration bounds a run, well within the management request timeout.
+// Cleanup still runs after it.
+const MaxDuration = 20 * time.Second
+
+// streamTimeout is how long the stream step waits for the flag change event, several
+// times the change poll interval
+const streamTimeout = 5 * time.Second
+
+// Step statuses
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // an earlier step failed
+)
+
+// Step names, in the order they run
+const (
+	StepCreateTenant  = "create_tenant"
+	StepCreateProject = "create_project"
+	StepCreateFlag    = "create_flag"
+	StepEvaluate      = "evaluate"
+	StepStream        = "stream"
+	StepCleanup       = "cleanup"
+)
+
+// evaluatePath is the SDK bulk evaluation route the evaluate step calls
+const evaluatePath = "/api/v1/sdk/evaluate"
+
+var ErrRunning = errors.New("a smoke test is already running on this instance")
+
+// StepResult is the outcome of one step
+type StepResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Result is the machine-readable outcome of a run. Passed is false if any step failed,
+// including cleanup, since a failed cleanup leaves a tenant behind.
+type Result struct {
+	Passed     bool         `json:"passed"`
+	StartedAt  time.Time    `json:"started_at"`
+	DurationMS float64      `json:"duration_ms"`
+	Steps      []StepResult `json:"steps"`
+}
+
+// TenantStore creates and removes the temporary tenant; *tenants.Service satisfies it
+type TenantStore interface {
+	Create(ctx context.Context, name string) (*tenants.Tenant, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// ProjectCreator creates the temporary project; *projects.Service satisfies it
+type ProjectCreator interface {
+	Create(ctx context.Context, tenantID, role, name string) (*projects.Project, error)
+}
+
+// FlagWriter creates the temporary flag and changes it; flags.Service satisfies it
+type FlagWriter interface {
+	Create(ctx context.Context, f *flag.Flag, tenantID string) error
+	Toggle(ctx context.Context, id string, actorID string, tenantID string) (*flag.Flag, error)
+}
+
+// ChangeSubscriber delivers the flag change events SDK streams send;
+// *evaluation.ChangeWatcher satisfies it
+type ChangeSubscriber interface {
+	Subscribe(ctx context.Context, projectID, tenantID string) (<-chan evaluation.ChangeEvent, int64, func(), error)
+}
+
+// Runner runs smoke tests one at a time. Management writes go through the services, and
+// the evaluation goes through the instance's own HTTP handler, so it passes the same
+// readiness, API key and evaluation middleware an SDK request does.
+type Runner struct {
+	tenants  TenantStore
+	projects ProjectCreator
+	flags    FlagWriter
+	changes  ChangeSubscriber
+	handler  http.Handler
+	logger   *slog.Logger
+	running  sync.Mutex
+}
+
+func NewRunner(tenants TenantStore, projects ProjectCreator, flags FlagWriter, changes ChangeSubscriber, handler http.Handler, logger *slog.Logger) *Runner {
+	return &Runner{tenants: tenants, projects: projects, flags: flags, changes: changes, handler: handler, logger: logger}
+}
+
+// run holds the state passed between the steps of one run
+type run struct {
+	tenant  *tenants.Tenant
+	project *projects.Project
+	flag    *flag.Flag
+}
+
+// Run executes the suite: create tenant, project and flag, evaluate the flag with the
+// project's API key, toggle it and wait for the change event SDK streams receive, then
+// delete the tenant. Each step only runs if the previous ones
+// passed, but cleanup always runs. Returns ErrRunning if a run is in progress.
+func (r *Runner) Run(ctx context.Context) (*Result, error) {
+	if !r.running.TryLock() {
+		return nil, ErrRunning
+	}
+	defer r.running.Unlock()
+
+	result := &Result{StartedAt: time.Now().UTC(), Steps: []StepResult{}}
+	runCtx, cancel := context.WithTimeout(ctx, MaxDuration)
+	defer cancel()
+
+	state := &run{}
+	steps := []struct {
+		name string
+		fn   func(context.Context, *run) error
+	}{
+		{StepCreateTenant, r.createTenant},
+		{StepCreateProject, r.createProject},
+		{StepCreateFlag, r.createFlag},
+		{StepEvaluate, r.evaluate},
+		{StepStream, r.stream},
+	}
+
+	failed := false
+	for _, step := range steps {
+		if failed {
+			result.Steps = append(result.Steps, StepResult{Name: step.name, Status: StatusSkipped})
+			continue
+		}
+		sr := timeStep(step.name, func() error { return step.fn(runCtx, state) })
+		failed = sr.Status == StatusFailed
+		result.Steps = append(result.Steps, sr)
+	}
+
+	// Clean up even if the run timed out or the caller went away
+	if state.tenant != nil {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), MaxDuration)
+		defer cancel()
+		result.Steps = append(result.Steps, timeStep(StepCleanup, func() error {
+			return r.tenants.Delete(cleanupCtx, state.tenant.ID)
+		}))
+	} else {
+		result.Steps = append(result.Steps, StepResult{Name: StepCleanup, Status: StatusSkipped})
+	}
+
+	result.Passed = true
+	for _, sr := range result.Steps {
+		if sr.Status == StatusFailed {
+			result.Passed = false
+		}
+	}
+	result.DurationMS = msSince(result.StartedAt)
+
+	level := slog.LevelInfo
+	if !result.Passed {
+		level = slog.LevelError
+	}
+	r.logger.Log(ctx, level, "smoke test finished",
+		slog.Bool("passed", result.Passed),
+		slog.Float64("duration_ms", result.DurationMS),
+	)
+
+	return result, nil
+}
+
+func timeStep(name string, fn func() error) StepResult {
+	start := time.Now()
+	err := fn()
+	sr := StepResult{Name: name, Status: StatusPassed, DurationMS: msSince(start)}
+	if err != nil {
+		sr.Status = StatusFailed
+		sr.Error = err.Error()
+	}
+	return sr
+}
+
+func msSince(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
+
+func (r *Runner) createTenant(ctx context.Context, state *run) error {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix) // crypto/rand.Read on most systems cannot fail
+
+	tenant, err := r.tenants.Create(ctx, "smoke-test-"+hex.EncodeToString(suffix))
+	if err != nil {
+		return err
+	}
+	state.tenant = tenant
+	return nil
+}
+
+func (r *Runner) createProject(ctx context.Context, state *run) error {
+	project, err := r.projects.Create(ctx, state.tenant.ID, "owner", "Smoke Test")
+	if err != nil {
+		return err
+	}
+	if project.ClientAPIKey == "" {
+		return errors.New("project was created without an API key")
+	}
+	state.project = project
+	return nil
+}
+
+func (r *Runner) createFlag(ctx context.Context, state *run) error {
+	f := &flag.Flag{
+		ProjectID: &state.project.ID,
+		Name:      "smoke-test",
+		Enabled:   true,
+	}
+	if err := r.flags.Create(ctx, f, state.tenant.ID); err != nil {
+		return err
+	}
+	state.flag = f
+	return nil
+}
+
+func (r *Runner) evaluate(ctx context.Context, state *run) error {
+	body, _ := json.Marshal(map[string]any{"context": map[string]any{"user_id": "smoke-test"}})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, evaluatePath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+state.project.ClientAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	r.handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", evaluatePath, rec.Code, bytes.TrimSpace(rec.Body.Bytes()))
+	}
+
+	var resp struct {
+		Flags   map[string]bool `json:"flags"`
+		Partial bool            `json:"partial"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		return fmt.Errorf("decode evaluation response: %w", err)
+	}
+	enabled, ok := resp.Flags[state.flag.ID]
+	if !ok {
+		if resp.Partial {
+			return errors.New("evaluation ran out of budget before reaching the flag")
+		}
+		return errors.New("evaluation response is missing the flag")
+	}
+	if !enabled {
+		return errors.New("enabled flag evaluated to false")
+	}
+	return nil
+}
+
+func (r *Runner) stream(ctx context.Context, state *run) error {
+	events, _, unsubscribe, err := r.changes.Subscribe(ctx, state.project.ID, state.tenant.ID)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	if _, err := r.flags.Toggle(ctx, state.flag.ID, "", state.tenant.ID); err != nil {
+		return fmt.Errorf("toggle flag: %w", err)
+	}
+
+	timeout := time.NewTimer(streamTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case event := <-events:
+			if event.Type == evaluation.EventFlagsChanged {
+				return nil
+			}
+		case <-timeout.C:
+			return fmt.Errorf("no flag change event within %s", streamTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
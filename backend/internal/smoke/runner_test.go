@@ -0,0 +1,168 @@
+package smoke
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jalil32/toggle/internal/evaluation"
+	flag "github.com/jalil32/toggle/internal/flags"
+	"github.com/jalil32/toggle/internal/projects"
+	"github.com/jalil32/toggle/internal/tenants"
+)
+
+type fakeTenants struct {
+	deleted []string
+}
+
+func (f *fakeTenants) Create(ctx context.Context, name string) (*tenants.Tenant, error) {
+	return &tenants.Tenant{ID: "tenant-1", Name: name}, nil
+}
+
+func (f *fakeTenants) Delete(ctx context.Context, id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeProjects struct{}
+
+func (fakeProjects) Create(ctx context.Context, tenantID, role, name string) (*projects.Project, error) {
+	return &projects.Project{ID: "project-1", TenantID: tenantID, ClientAPIKey: "key-1"}, nil
+}
+
+type fakeFlags struct {
+	err error
+}
+
+func (f fakeFlags) Create(ctx context.Context, fl *flag.Flag, tenantID string) error {
+	fl.ID = "flag-1"
+	return f.err
+}
+
+func (f fakeFlags) Toggle(ctx context.Context, id string, actorID string, tenantID string) (*flag.Flag, error) {
+	return &flag.Flag{ID: id}, nil
+}
+
+// fakeChanges reports a flag change as soon as a stream subscribes
+type fakeChanges struct{}
+
+func (fakeChanges) Subscribe(ctx context.Context, projectID, tenantID string) (<-chan evaluation.ChangeEvent, int64, func(), error) {
+	events := make(chan evaluation.ChangeEvent, 1)
+	events <- evaluation.ChangeEvent{Type: evaluation.EventFlagsChanged, ProjectID: projectID, Version: 2}
+	return events, 1, func() {}, nil
+}
+
+// sdkHandler serves /sdk/evaluate, answering key-1 with the given flags
+func sdkHandler(flags map[string]bool) http.Handler {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST(evaluatePath, func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer key-1" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"flags": flags})
+	})
+	return router
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func statuses(result *Result) map[string]string {
+	out := make(map[string]string, len(result.Steps))
+	for _, step := range result.Steps {
+		out[step.Name] = step.Status
+	}
+	return out
+}
+
+func TestRunner_Run_Passes(t *testing.T) {
+	ts := &fakeTenants{}
+	runner := NewRunner(ts, fakeProjects{}, fakeFlags{}, fakeChanges{}, sdkHandler(map[string]bool{"flag-1": true}), discardLogger())
+
+	result, err := runner.Run(context.Background())
+
+	require.NoError(t, err)
+	assert.True(t, result.Passed)
+	assert.Equal(t, map[string]string{
+		StepCreateTenant:  StatusPassed,
+		StepCreateProject: StatusPassed,
+		StepCreateFlag:    StatusPassed,
+		StepEvaluate:      StatusPassed,
+		StepStream:        StatusPassed,
+		StepCleanup:       StatusPassed,
+	}, statuses(result))
+	assert.Equal(t, []string{"tenant-1"}, ts.deleted)
+}
+
+func TestRunner_Run_FailedStepSkipsTheRestButCleansUp(t *testing.T) {
+	ts := &fakeTenants{}
+	runner := NewRunner(ts, fakeProjects{}, fakeFlags{err: errors.New("invalid flag data")}, fakeChanges{}, sdkHandler(nil), discardLogger())
+
+	result, err := runner.Run(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, result.Passed)
+	got := statuses(result)
+	assert.Equal(t, StatusFailed, got[StepCreateFlag])
+	assert.Equal(t, StatusSkipped, got[StepEvaluate])
+	assert.Equal(t, StatusSkipped, got[StepStream])
+	assert.Equal(t, StatusPassed, got[StepCleanup])
+	assert.Equal(t, []string{"tenant-1"}, ts.deleted)
+}
+
+func TestRunner_Run_FailsWhenFlagEvaluatesWrong(t *testing.T) {
+	runner := NewRunner(&fakeTenants{}, fakeProjects{}, fakeFlags{}, fakeChanges{}, sdkHandler(map[string]bool{"flag-1": false}), discardLogger())
+
+	result, err := runner.Run(context.Background())
+
+	require.NoError(t, err)
+	assert.False(t, result.Passed)
+	assert.Equal(t, StatusFailed, statuses(result)[StepEvaluate])
+}
+
+func TestHandler_Run(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	runner := NewRunner(&fakeTenants{}, fakeProjects{}, fakeFlags{}, fakeChanges{}, sdkHandler(map[string]bool{"flag-1": true}), discardLogger())
+	router := gin.New()
+	NewHandler(runner, "9f2c4e6a8b0d1f3e5a7c9b2d4f6e8a0c").RegisterRoutes(router.Group(""))
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "missing token", want: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer 00000000000000000000000000000000", want: http.StatusUnauthorized},
+		{name: "valid token", header: "Bearer 9f2c4e6a8b0d1f3e5a7c9b2d4f6e8a0c", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/smoke-test", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusOK {
+				var result Result
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+				assert.True(t, result.Passed)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package templates
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type Handler interface {
+	RegisterRoutes(r *gin.RouterGroup)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+func (h *handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/templates", h.Create)
+	r.GET("/templates", h.List)
+	r.GET("/templates/:id", h.Get)
+	r.PUT("/templates/:id", h.Update)
+	r.DELETE("/templates/:id", h.Delete)
+}
+
+func (h *handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	template := &Template{
+		Name:        req.Name,
+		Description: req.Description,
+		Rules:       req.Rules,
+		RuleLogic:   req.RuleLogic,
+	}
+
+	if err := h.service.Create(c.Request.Context(), template, tenantID); err != nil {
+		h.writeError(c, err, "failed to create template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *handler) List(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	templates, err := h.service.List(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	template, err := h.service.GetByID(c.Request.Context(), id, tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *handler) Update(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.service.GetByID(c.Request.Context(), id, tenantID)
+	if err != nil {
+		h.writeError(c, err, "failed to get template")
+		return
+	}
+
+	if req.Name != nil {
+		template.Name = *req.Name
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+	if req.Rules != nil {
+		template.Rules = req.Rules
+	}
+	if req.RuleLogic != nil {
+		template.RuleLogic = *req.RuleLogic
+	}
+
+	if err := h.service.Update(c.Request.Context(), template, tenantID); err != nil {
+		h.writeError(c, err, "failed to update template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	if err := h.service.Delete(c.Request.Context(), id, tenantID); err != nil {
+		h.writeError(c, err, "failed to delete template")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *handler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidTemplateData):
+		c.JSON(http.StatusBadRequest, flag.InvalidDataResponse(err))
+	case errors.Is(err, ErrDuplicateName):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case pkgErrors.IsNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,33 @@
+package templates
+
+import (
+	"time"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+)
+
+// Template holds default configuration that new flags can be created from
+type Template struct {
+	ID          string      `json:"id" db:"id"`
+	TenantID    string      `json:"tenant_id" db:"tenant_id"`
+	Name        string      `json:"name" db:"name"`
+	Description string      `json:"description" db:"description"`
+	Rules       []flag.Rule `json:"rules" db:"rules"`
+	RuleLogic   string      `json:"rule_logic" db:"rule_logic"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+type CreateRequest struct {
+	Name        string      `json:"name" binding:"required,max=255"`
+	Description string      `json:"description"`
+	Rules       []flag.Rule `json:"rules"`
+	RuleLogic   string      `json:"rule_logic"`
+}
+
+type UpdateRequest struct {
+	Name        *string     `json:"name" binding:"omitempty,max=255"`
+	Description *string     `json:"description"`
+	Rules       []flag.Rule `json:"rules"`
+	RuleLogic   *string     `json:"rule_logic"`
+}
@@ -0,0 +1,143 @@
+package templates
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/jalil32/toggle/internal/pkg/transaction"
+)
+
+type Repository interface {
+	Create(ctx context.Context, t *Template) error
+	GetByID(ctx context.Context, id string, tenantID string) (*Template, error)
+	List(ctx context.Context, tenantID string) ([]Template, error)
+	Update(ctx context.Context, t *Template) error
+	Delete(ctx context.Context, id string, tenantID string) error
+}
+
+type postgresRepository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(db *sqlx.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+// getDB returns the transaction from context if present, otherwise returns the DB
+func (r *postgresRepository) getDB(ctx context.Context) sqlx.ExtContext {
+	if tx, ok := transaction.GetTx(ctx); ok {
+		return tx
+	}
+	return r.db
+}
+
+func (r *postgresRepository) Create(ctx context.Context, t *Template) error {
+	rulesJSON, err := json.Marshal(t.Rules)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO flag_templates (tenant_id, name, description, rules, rule_logic)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, t.TenantID, t.Name, t.Description, rulesJSON, t.RuleLogic).
+		Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id string, tenantID string) (*Template, error) {
+	query := `
+		SELECT id, tenant_id, name, description, rules, rule_logic, created_at, updated_at
+		FROM flag_templates
+		WHERE id = $1 AND tenant_id = $2
+	`
+	return scanTemplate(r.getDB(ctx).QueryRowxContext(ctx, query, id, tenantID))
+}
+
+func (r *postgresRepository) List(ctx context.Context, tenantID string) ([]Template, error) {
+	query := `
+		SELECT id, tenant_id, name, description, rules, rule_logic, created_at, updated_at
+		FROM flag_templates
+		WHERE tenant_id = $1
+		ORDER BY name ASC
+	`
+	rows, err := r.getDB(ctx).QueryxContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (r *postgresRepository) Update(ctx context.Context, t *Template) error {
+	rulesJSON, err := json.Marshal(t.Rules)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE flag_templates
+		SET name = $3, description = $4, rules = $5, rule_logic = $6
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING updated_at
+	`
+	return r.getDB(ctx).QueryRowxContext(ctx, query, t.ID, t.TenantID, t.Name, t.Description, rulesJSON, t.RuleLogic).
+		Scan(&t.UpdatedAt)
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, id string, tenantID string) error {
+	result, err := r.getDB(ctx).ExecContext(ctx, `DELETE FROM flag_templates WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// rowScanner is implemented by both *sqlx.Row and *sqlx.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTemplate(row rowScanner) (*Template, error) {
+	var t Template
+	var rulesJSON []byte
+
+	err := row.Scan(&t.ID, &t.TenantID, &t.Name, &t.Description, &rulesJSON, &t.RuleLogic, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(rulesJSON, &t.Rules); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
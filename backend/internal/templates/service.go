@@ -0,0 +1,244 @@
+package templates
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+	"github.com/jalil32/toggle/internal/pkg/sanitize"
+)
+
+var (
+	ErrInvalidTemplateData = errors.New("invalid template data")
+	ErrDuplicateName       = errors.New("a template with this name already exists")
+)
+
+type Service interface {
+	Create(ctx context.Context, t *Template, tenantID string) error
+	GetByID(ctx context.Context, id string, tenantID string) (*Template, error)
+	List(ctx context.Context, tenantID string) ([]Template, error)
+	Update(ctx context.Context, t *Template, tenantID string) error
+	Delete(ctx context.Context, id string, tenantID string) error
+
+	// ApplyTemplate fills the flag's unset fields from a template (implements flag.TemplateSource)
+	ApplyTemplate(ctx context.Context, templateID string, tenantID string, f *flag.Flag) error
+}
+
+type service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *service) Create(ctx context.Context, t *Template, tenantID string) error {
+	if err := s.validateTemplate(t); err != nil {
+		s.logger.Warn("template validation failed",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	t.TenantID = tenantID
+
+	if err := s.repo.Create(ctx, t); err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateName
+		}
+		s.logger.Error("failed to create template",
+			slog.String("name", t.Name),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+
+	s.logger.Info("template created",
+		slog.String("id", t.ID),
+		slog.String("name", t.Name),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+func (s *service) GetByID(ctx context.Context, id string, tenantID string) (*Template, error) {
+	if id == "" {
+		return nil, ErrInvalidTemplateData
+	}
+
+	t, err := s.repo.GetByID(ctx, id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logger.Debug("template not found or forbidden",
+				slog.String("id", id),
+				slog.String("tenant_id", tenantID),
+			)
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get template",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	return t, nil
+}
+
+func (s *service) List(ctx context.Context, tenantID string) ([]Template, error) {
+	templates, err := s.repo.List(ctx, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list templates",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	if templates == nil {
+		return []Template{}, nil
+	}
+
+	return templates, nil
+}
+
+func (s *service) Update(ctx context.Context, t *Template, tenantID string) error {
+	if err := s.validateTemplate(t); err != nil {
+		s.logger.Warn("template validation failed on update",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	t.TenantID = tenantID
+
+	if err := s.repo.Update(ctx, t); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return ErrDuplicateName
+		}
+		s.logger.Error("failed to update template",
+			slog.String("id", t.ID),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+
+	s.logger.Info("template updated",
+		slog.String("id", t.ID),
+		slog.String("name", t.Name),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+func (s *service) Delete(ctx context.Context, id string, tenantID string) error {
+	if id == "" {
+		return ErrInvalidTemplateData
+	}
+
+	if err := s.repo.Delete(ctx, id, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to delete template",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+
+	s.logger.Info("template deleted",
+		slog.String("id", id),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+// ApplyTemplate copies the template's defaults into fields the caller left unset.
+// Explicit values on the flag always win over the template.
+func (s *service) ApplyTemplate(ctx context.Context, templateID string, tenantID string, f *flag.Flag) error {
+	t, err := s.GetByID(ctx, templateID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	if f.Description == "" {
+		f.Description = t.Description
+	}
+	if f.Rules == nil {
+		f.Rules = make([]flag.Rule, len(t.Rules))
+		copy(f.Rules, t.Rules)
+	}
+	if f.RuleLogic == "" {
+		f.RuleLogic = t.RuleLogic
+	}
+
+	s.logger.Debug("template applied to flag",
+		slog.String("template_id", templateID),
+		slog.String("flag_name", f.Name),
+		slog.String("tenant_id", tenantID),
+	)
+
+	return nil
+}
+
+func (s *service) validateTemplate(t *Template) error {
+	if t == nil {
+		return ErrInvalidTemplateData
+	}
+
+	if t.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTemplateData)
+	}
+
+	if t.RuleLogic == "" {
+		t.RuleLogic = "AND"
+	}
+	if !flag.ValidRuleLogic(t.RuleLogic) {
+		return fmt.Errorf("%w: rule_logic must be AND, OR or FIRST_MATCH", ErrInvalidTemplateData)
+	}
+
+	if t.Rules == nil {
+		t.Rules = []flag.Rule{}
+	}
+	if err := flag.ValidateRules(t.Rules); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTemplateData, err)
+	}
+	flag.AssignRuleIDs(t.Rules)
+
+	description, err := sanitize.Text(t.Description, sanitize.MaxDescriptionLength)
+	if err != nil {
+		return fmt.Errorf("%w: description must be at most %d characters", ErrInvalidTemplateData, sanitize.MaxDescriptionLength)
+	}
+	t.Description = description
+
+	return nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
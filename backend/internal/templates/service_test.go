@@ -0,0 +1,197 @@
+package templates
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/lib/pq"
+
+	flag "github.com/jalil32/toggle/internal/flags"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
+)
+
+type mockRepository struct {
+	createFunc  func(ctx context.Context, t *Template) error
+	getByIDFunc func(ctx context.Context, id string, tenantID string) (*Template, error)
+	listFunc    func(ctx context.Context, tenantID string) ([]Template, error)
+	updateFunc  func(ctx context.Context, t *Template) error
+	deleteFunc  func(ctx context.Context, id string, tenantID string) error
+}
+
+func (m *mockRepository) Create(ctx context.Context, t *Template) error {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, t)
+	}
+	t.ID = "test-generated-id"
+	return nil
+}
+
+func (m *mockRepository) GetByID(ctx context.Context, id string, tenantID string) (*Template, error) {
+	if m.getByIDFunc != nil {
+		return m.getByIDFunc(ctx, id, tenantID)
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockRepository) List(ctx context.Context, tenantID string) ([]Template, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, tenantID)
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) Update(ctx context.Context, t *Template) error {
+	if m.updateFunc != nil {
+		return m.updateFunc(ctx, t)
+	}
+	return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string, tenantID string) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, id, tenantID)
+	}
+	return nil
+}
+
+func TestServiceCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  *Template
+		mockFn    func(ctx context.Context, t *Template) error
+		wantErr   error
+		wantLogic string
+	}{
+		{
+			name:      "defaults rule logic and rules",
+			template:  &Template{Name: "gradual-rollout"},
+			wantLogic: "AND",
+		},
+		{
+			name:      "keeps explicit rule logic",
+			template:  &Template{Name: "any-match", RuleLogic: "OR"},
+			wantLogic: "OR",
+		},
+		{
+			name:     "missing name",
+			template: &Template{},
+			wantErr:  ErrInvalidTemplateData,
+		},
+		{
+			name:     "invalid rule logic",
+			template: &Template{Name: "bad-logic", RuleLogic: "XOR"},
+			wantErr:  ErrInvalidTemplateData,
+		},
+		{
+			name:     "duplicate name",
+			template: &Template{Name: "gradual-rollout"},
+			mockFn: func(ctx context.Context, t *Template) error {
+				return &pq.Error{Code: "23505"}
+			},
+			wantErr: ErrDuplicateName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(&mockRepository{createFunc: tt.mockFn}, slog.Default())
+
+			err := svc.Create(context.Background(), tt.template, "test-tenant-id")
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.template.TenantID != "test-tenant-id" {
+				t.Errorf("expected tenant to be set, got '%s'", tt.template.TenantID)
+			}
+			if tt.template.RuleLogic != tt.wantLogic {
+				t.Errorf("expected rule logic %s, got %s", tt.wantLogic, tt.template.RuleLogic)
+			}
+			if tt.template.Rules == nil {
+				t.Error("expected rules to be non-nil")
+			}
+		})
+	}
+}
+
+func TestServiceGetByID_NotFound(t *testing.T) {
+	svc := NewService(&mockRepository{}, slog.Default())
+
+	_, err := svc.GetByID(context.Background(), "missing-id", "test-tenant-id")
+
+	if !errors.Is(err, pkgErrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestServiceApplyTemplate(t *testing.T) {
+	template := &Template{
+		ID:          "tpl-1",
+		TenantID:    "test-tenant-id",
+		Name:        "beta-cohort",
+		Description: "Beta users only",
+		Rules: []flag.Rule{
+			{ID: "rule-1", Attribute: "beta", Operator: "equals", Value: true, Rollout: 25},
+		},
+		RuleLogic: "OR",
+	}
+	mockRepo := &mockRepository{
+		getByIDFunc: func(ctx context.Context, id string, tenantID string) (*Template, error) {
+			if id != template.ID || tenantID != template.TenantID {
+				return nil, sql.ErrNoRows
+			}
+			return template, nil
+		},
+	}
+	svc := NewService(mockRepo, slog.Default())
+
+	t.Run("fills unset fields", func(t *testing.T) {
+		f := &flag.Flag{Name: "new-flag"}
+
+		if err := svc.ApplyTemplate(context.Background(), "tpl-1", "test-tenant-id", f); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if f.Description != "Beta users only" || f.RuleLogic != "OR" {
+			t.Errorf("expected template defaults, got %q / %q", f.Description, f.RuleLogic)
+		}
+		if len(f.Rules) != 1 || f.Rules[0] != template.Rules[0] {
+			t.Errorf("expected template rules, got %+v", f.Rules)
+		}
+		if &f.Rules[0] == &template.Rules[0] {
+			t.Error("expected rules to be copied")
+		}
+	})
+
+	t.Run("explicit values win", func(t *testing.T) {
+		f := &flag.Flag{Name: "new-flag", Description: "Custom", Rules: []flag.Rule{}, RuleLogic: "AND"}
+
+		if err := svc.ApplyTemplate(context.Background(), "tpl-1", "test-tenant-id", f); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if f.Description != "Custom" || f.RuleLogic != "AND" || len(f.Rules) != 0 {
+			t.Errorf("expected explicit values to be kept, got %+v", f)
+		}
+	})
+
+	t.Run("template from another tenant", func(t *testing.T) {
+		f := &flag.Flag{Name: "new-flag"}
+
+		err := svc.ApplyTemplate(context.Background(), "tpl-1", "other-tenant-id", f)
+
+		if !errors.Is(err, pkgErrors.ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
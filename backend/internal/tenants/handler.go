@@ -2,11 +2,15 @@
 package tenants
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/jalil32/toggle/internal/deprecations"
 	appContext "github.com/jalil32/toggle/internal/pkg/context"
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
 )
 
 type Handler struct {
@@ -17,15 +21,61 @@ func NewHandler(service *Service) *Handler {
 	return &Handler{service: service}
 }
 
-func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+// Routes kept for backward compatibility, superseded by /me/organizations
+var (
+	TenantRouteDeprecation = deprecations.Notice{
+		Key:         "route:/tenant",
+		Description: "GET and PUT /tenant act on the X-Tenant-ID tenant",
+		Replacement: "GET and PUT /me/organizations/:id",
+		Since:       time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		Sunset:      timePtr(time.Date(2027, 4, 16, 0, 0, 0, 0, time.UTC)),
+	}
+	CreateTenantRouteDeprecation = deprecations.Notice{
+		Key:         "route:POST /me/tenants",
+		Description: "POST /me/tenants creates an organization",
+		Replacement: "POST /me/organizations",
+		Since:       time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		Sunset:      timePtr(time.Date(2027, 4, 16, 0, 0, 0, 0, time.UTC)),
+	}
+)
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup, deprecated *deprecations.Tracker) {
 	// Keep backward compatible route names for now
-	r.GET("/tenant", h.GetTenant)
-	r.PUT("/tenant", h.UpdateTenant)
+	r.GET("/tenant", deprecated.Mark(TenantRouteDeprecation), h.GetTenant)
+	r.PUT("/tenant", deprecated.Mark(TenantRouteDeprecation), h.UpdateTenant)
+
+	r.GET("/tenant/policies", h.GetPolicies)
+	r.PUT("/tenant/policies", h.UpdatePolicies)
+	r.GET("/tenant/bucketing", h.GetBucketing)
+	r.PUT("/tenant/bucketing", h.UpdateBucketing)
+	r.GET("/tenant/sdk-config", h.GetSDKConfig)
+	r.PUT("/tenant/sdk-config", h.UpdateSDKConfig)
+
+	r.GET("/tenant/invite-links", h.ListInviteLinks)
+	r.POST("/tenant/invite-links", h.CreateInviteLink)
+	r.DELETE("/tenant/invite-links/:id", h.RevokeInviteLink)
 }
 
-func (h *Handler) RegisterUserRoutes(r *gin.RouterGroup) {
+func (h *Handler) RegisterUserRoutes(r *gin.RouterGroup, deprecated *deprecations.Tracker) {
 	// User-level routes (no tenant context required)
-	r.POST("/tenants", h.CreateTenant)
+	r.POST("/tenants", deprecated.Mark(CreateTenantRouteDeprecation), h.CreateTenant)
+}
+
+// RegisterJoinRoutes registers invite link redemption; limiter guards against guessing codes
+func (h *Handler) RegisterJoinRoutes(r *gin.RouterGroup, limiter gin.HandlerFunc) {
+	r.POST("/join/:code", limiter, h.JoinWithInviteLink)
+}
+
+func (h *Handler) RegisterOrganizationRoutes(r *gin.RouterGroup) {
+	// User-level routes: access is checked against the user's membership, not X-Tenant-ID
+	r.GET("/organizations", h.ListOrganizations)
+	r.POST("/organizations", h.CreateTenant)
+	r.GET("/organizations/:id", h.GetOrganization)
+	r.PUT("/organizations/:id", h.UpdateOrganization)
+	r.DELETE("/organizations/:id", h.DeleteOrganization)
+	r.POST("/organizations/:id/merge", h.MergeOrganization)
 }
 
 func (h *Handler) GetTenant(c *gin.Context) {
@@ -69,6 +119,214 @@ func (h *Handler) UpdateTenant(c *gin.Context) {
 	c.JSON(http.StatusOK, tenant)
 }
 
+// GetPolicies returns the tenant's membership policies; any member can read them
+func (h *Handler) GetPolicies(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	policies, err := h.service.GetPolicies(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// UpdatePolicies changes the tenant's membership policies; only owners may change them
+func (h *Handler) UpdatePolicies(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	var req UpdatePoliciesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policies, err := h.service.UpdatePolicies(c.Request.Context(), tenantID, role, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		case errors.Is(err, ErrInvalidPolicy):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update policies"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// GetBucketing returns whether the tenant's rollouts use strict bucketing; any member can read it
+func (h *Handler) GetBucketing(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	settings, err := h.service.GetBucketing(c.Request.Context(), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get bucketing"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateBucketing switches the tenant's rollouts to or from strict bucketing; owners and admins only
+func (h *Handler) UpdateBucketing(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	var req UpdateBucketingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.service.UpdateBucketing(c.Request.Context(), tenantID, role, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update bucketing"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetSDKConfig returns what the tenant's SDKs are told to tune themselves with; any member can read it
+func (h *Handler) GetSDKConfig(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+
+	cfg, err := h.service.GetSDKConfig(c.Request.Context(), tenantID)
+	if err != nil {
+		if pkgErrors.IsNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get sdk config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// UpdateSDKConfig changes the polling interval or streaming recommended to the tenant's SDKs;
+// owners and admins only
+func (h *Handler) UpdateSDKConfig(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	var req UpdateSDKConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cfg, err := h.service.UpdateSDKConfig(c.Request.Context(), tenantID, role, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInsufficientPermissions):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		case errors.Is(err, ErrInvalidSDKConfig):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case pkgErrors.IsNotFoundError(err):
+			c.JSON(http.StatusNotFound, gin.H{"error": "tenant not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update sdk config"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, cfg)
+}
+
+// ListInviteLinks returns the tenant's invite links; only owners and admins may see them
+func (h *Handler) ListInviteLinks(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	links, err := h.service.ListInviteLinks(c.Request.Context(), tenantID, role)
+	if err != nil {
+		h.inviteLinkError(c, err, "failed to list invite links")
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// CreateInviteLink creates a shareable invite link bound to a role, with limited uses and an expiry
+func (h *Handler) CreateInviteLink(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	userID := appContext.MustUserID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	var req CreateInviteLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := h.service.CreateInviteLink(c.Request.Context(), tenantID, userID, role, req)
+	if err != nil {
+		h.inviteLinkError(c, err, "failed to create invite link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// RevokeInviteLink revokes one of the tenant's invite links
+func (h *Handler) RevokeInviteLink(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	if err := h.service.RevokeInviteLink(c.Request.Context(), c.Param("id"), tenantID, role); err != nil {
+		h.inviteLinkError(c, err, "failed to revoke invite link")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// JoinWithInviteLink adds the authenticated user to the tenant of the invite link in the path
+func (h *Handler) JoinWithInviteLink(c *gin.Context) {
+	userID := appContext.MustUserID(c.Request.Context())
+
+	result, err := h.service.RedeemInviteLink(c.Request.Context(), c.Param("code"), userID)
+	if err != nil {
+		h.inviteLinkError(c, err, "failed to join organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// inviteLinkError maps invite link service errors to responses
+func (h *Handler) inviteLinkError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInsufficientPermissions):
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+	case errors.Is(err, ErrInvalidInviteLink):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInviteLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "invite link not found"})
+	case errors.Is(err, ErrInviteLinkUnavailable):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
 type CreateRequest struct {
 	Name string `json:"name" binding:"required,max=255"`
 }
@@ -97,3 +355,97 @@ func (h *Handler) CreateTenant(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, tenant)
 }
+
+func (h *Handler) ListOrganizations(c *gin.Context) {
+	userID := appContext.MustUserID(c.Request.Context())
+
+	organizations, err := h.service.ListOrganizations(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list organizations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, organizations)
+}
+
+func (h *Handler) GetOrganization(c *gin.Context) {
+	id := c.Param("id")
+	userID := appContext.MustUserID(c.Request.Context())
+
+	organization, err := h.service.GetOrganization(c.Request.Context(), id, userID)
+	if err != nil {
+		h.organizationError(c, err, "failed to get organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+func (h *Handler) UpdateOrganization(c *gin.Context) {
+	id := c.Param("id")
+	userID := appContext.MustUserID(c.Request.Context())
+
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	organization, err := h.service.UpdateOrganization(c.Request.Context(), id, userID, req.Name)
+	if err != nil {
+		h.organizationError(c, err, "failed to update organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+func (h *Handler) DeleteOrganization(c *gin.Context) {
+	id := c.Param("id")
+	userID := appContext.MustUserID(c.Request.Context())
+
+	if err := h.service.DeleteOrganization(c.Request.Context(), id, userID); err != nil {
+		h.organizationError(c, err, "failed to delete organization")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MergeOrganization merges the organization in the body into the one in the path.
+// Returns the merge report; a blocked merge is a 409 with the report listing its blockers.
+func (h *Handler) MergeOrganization(c *gin.Context) {
+	id := c.Param("id")
+	userID := appContext.MustUserID(c.Request.Context())
+
+	var req MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.MergeOrganization(c.Request.Context(), id, userID, req)
+	switch {
+	case errors.Is(err, ErrMergeBlocked):
+		c.JSON(http.StatusConflict, report)
+	case errors.Is(err, ErrInvalidMerge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		h.organizationError(c, err, "failed to merge organization")
+	default:
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// organizationError maps organization service errors to responses
+// Non-members get 404 so organization IDs cannot be enumerated
+func (h *Handler) organizationError(c *gin.Context, err error, fallback string) {
+	switch {
+	case pkgErrors.IsNotFoundError(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+	case errors.Is(err, ErrInsufficientPermissions):
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
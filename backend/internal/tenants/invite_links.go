@@ -0,0 +1,66 @@
+package tenants
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// MaxInviteLinkUses is the most members one invite link may admit
+const MaxInviteLinkUses = 500
+
+// ErrInviteLinkNotFound indicates an unknown invite link code or ID
+var ErrInviteLinkNotFound = errors.New("invite link not found")
+
+// ErrInviteLinkUnavailable indicates an invite link that has expired, been revoked or used up
+var ErrInviteLinkUnavailable = errors.New("invite link is no longer valid")
+
+// ErrInvalidInviteLink indicates an invite link request with a bad role or use count
+var ErrInvalidInviteLink = errors.New("invalid invite link")
+
+// InviteLink lets anyone with its code join the tenant with Role, until it has been used
+// MaxUses times, expires or is revoked
+type InviteLink struct {
+	ID        string     `json:"id" db:"id"`
+	TenantID  string     `json:"tenant_id" db:"tenant_id"`
+	Code      string     `json:"code" db:"code"`
+	Role      string     `json:"role" db:"role"`
+	MaxUses   int        `json:"max_uses" db:"max_uses"`
+	Uses      int        `json:"uses" db:"uses"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedBy *string    `json:"created_by" db:"created_by"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Redeemable reports whether the link can still admit a new member at now
+func (l *InviteLink) Redeemable(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt) && l.Uses < l.MaxUses
+}
+
+// CreateInviteLinkRequest creates an invite link. ExpiresInDays is capped by the tenant's
+// max_invite_days policy; zero uses the maximum.
+type CreateInviteLinkRequest struct {
+	Role          string `json:"role" binding:"required"`
+	MaxUses       int    `json:"max_uses" binding:"required"`
+	ExpiresInDays int    `json:"expires_in_days"`
+}
+
+// JoinResult is the tenant a user joined, or already belonged to, through an invite link
+type JoinResult struct {
+	TenantID string `json:"tenant_id"`
+	Role     string `json:"role"`
+	// AlreadyMember is set when the user was a member before; their role is unchanged and
+	// the link's use isn't counted
+	AlreadyMember bool `json:"already_member"`
+}
+
+// newInviteLinkCode returns a random, unguessable invite link code
+func newInviteLinkCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,118 @@
+package tenants
+
+import "errors"
+
+// ErrInvalidMerge indicates a merge of a tenant into itself
+var ErrInvalidMerge = errors.New("invalid merge")
+
+// ErrMergeBlocked indicates the merge report lists blockers, so nothing was merged
+var ErrMergeBlocked = errors.New("merge blocked")
+
+// MergeRequest merges a source tenant into the tenant it is sent to
+type MergeRequest struct {
+	SourceID string `json:"source_id" binding:"required"`
+	// DryRun reports what the merge would do without changing anything
+	DryRun bool `json:"dry_run"`
+}
+
+// MergeReport is what a merge moved, or would move on a dry run, and every conflict it resolved
+type MergeReport struct {
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+	DryRun   bool   `json:"dry_run"`
+	Merged   bool   `json:"merged"`
+	// Moved counts the source rows moved into the target, by table
+	Moved map[string]int `json:"moved"`
+	// Members are the source members and the role each has in the target after the merge
+	Members []MemberMerge `json:"members"`
+	// RenamedTemplates are source templates renamed because the target has one of the same name
+	RenamedTemplates []TemplateRename `json:"renamed_templates"`
+	// DroppedInvitations are source invitations for emails the target already invited
+	DroppedInvitations int `json:"dropped_invitations"`
+	// Discarded lists source tenant settings that are dropped; the target's are kept
+	Discarded []string `json:"discarded"`
+	// Blockers prevent the merge; a dry run lists them so they can be resolved first
+	Blockers []string `json:"blockers"`
+
+	// sealedPresets counts source presets sealed with the source tenant's data key
+	sealedPresets int
+}
+
+// MemberMerge is one source member's role before and after the merge
+type MemberMerge struct {
+	UserID     string `json:"user_id" db:"user_id"`
+	Email      string `json:"email" db:"email"`
+	SourceRole string `json:"source_role" db:"source_role"`
+	// TargetRole is the member's existing role in the target, empty if they aren't a member
+	TargetRole string `json:"target_role" db:"target_role"`
+	Role       string `json:"role" db:"-"`
+	// Conflict is set when the resulting role differs from the member's source role
+	Conflict bool `json:"conflict" db:"-"`
+}
+
+// TemplateRename is a source template whose name is taken in the target
+type TemplateRename struct {
+	ID      string `json:"id" db:"id"`
+	Name    string `json:"name" db:"name"`
+	NewName string `json:"new_name" db:"-"`
+}
+
+// mergedTables hold tenant data that moves to the target with the source's projects and flags.
+// Tables not listed, such as policies, quotas and encryption keys, describe the source
+// tenant itself and are removed with it.
+var mergedTables = []string{
+	"projects",
+	"environments",
+	"flags",
+	"flag_environments",
+	"flag_exclusions",
+	"flag_overrides",
+	"flag_custom_fields",
+	"flag_history",
+	"flag_comments",
+	"flag_change_requests",
+	"flag_change_approvals",
+	"project_approval_policies",
+	"change_sets",
+	"context_presets",
+	"evaluation_scenarios",
+	"project_snapshots",
+	"project_attributes",
+	"sdk_usage",
+	"flag_evaluation_counts",
+	"flag_evaluation_reasons",
+	"evaluation_events",
+	"public_status_pages",
+	"public_status_flags",
+	"flag_templates",
+	"api_key_reveals",
+	"api_key_request_counts",
+}
+
+// discardedSettings are per-tenant settings the merge drops from the source, by table
+var discardedSettings = map[string]string{
+	"tenant_policies":      "membership policies",
+	"catalog_push_targets": "catalog push target",
+	"tenant_previews":      "feature preview enrollments",
+	"tenant_invite_links":  "invite links",
+}
+
+// ResolveMemberRole is the role a source member gets in the target: the higher of their
+// existing target role and their source role. Source owners join as admins, since owning
+// the target is for its existing owners to grant.
+func ResolveMemberRole(sourceRole, targetRole string) string {
+	role := sourceRole
+	if role == RoleOwner {
+		role = RoleAdmin
+	}
+	if targetRole != "" && RoleAtLeast(targetRole, role) {
+		return targetRole
+	}
+	return role
+}
+
+// resolve sets the member's resulting role and whether it differs from their source role
+func (m *MemberMerge) resolve() {
+	m.Role = ResolveMemberRole(m.SourceRole, m.TargetRole)
+	m.Conflict = m.Role != m.SourceRole
+}
@@ -0,0 +1,54 @@
+package tenants
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveMemberRole(t *testing.T) {
+	tests := []struct {
+		source, target, want string
+	}{
+		{source: RoleMember, target: "", want: RoleMember},
+		{source: RoleAdmin, target: "", want: RoleAdmin},
+		{source: RoleOwner, target: "", want: RoleAdmin},
+		{source: RoleMember, target: RoleAdmin, want: RoleAdmin},
+		{source: RoleAdmin, target: RoleMember, want: RoleAdmin},
+		{source: RoleOwner, target: RoleOwner, want: RoleOwner},
+		{source: RoleOwner, target: RoleMember, want: RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		if got := ResolveMemberRole(tt.source, tt.target); got != tt.want {
+			t.Errorf("ResolveMemberRole(%q, %q) = %q, want %q", tt.source, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMerge(t *testing.T) {
+	report := &MergeReport{
+		Members: []MemberMerge{
+			{UserID: "same", SourceRole: RoleMember, TargetRole: RoleMember},
+			{UserID: "raised", SourceRole: RoleMember, TargetRole: RoleAdmin},
+			{UserID: "owner", SourceRole: RoleOwner},
+		},
+		RenamedTemplates: []TemplateRename{{ID: "tpl-1", Name: "Kill switch"}},
+		sealedPresets:    2,
+	}
+
+	resolveMerge(report, &Tenant{Slug: "acquired-co"})
+
+	conflicts := map[string]bool{}
+	for _, m := range report.Members {
+		conflicts[m.UserID] = m.Conflict
+	}
+	if conflicts["same"] || !conflicts["raised"] || !conflicts["owner"] {
+		t.Errorf("unexpected conflicts: %v", conflicts)
+	}
+	if got := report.RenamedTemplates[0].NewName; got != "Kill switch (acquired-co)" {
+		t.Errorf("expected template renamed after the source slug, got %q", got)
+	}
+	if len(report.Blockers) != 1 || !strings.Contains(report.Blockers[0], "2 context presets") {
+		t.Errorf("expected sealed presets to block the merge, got %v", report.Blockers)
+	}
+}
@@ -27,3 +27,113 @@ type TenantMembership struct {
 	TenantName string `db:"tenant_name" json:"tenant_name"`
 	TenantSlug string `db:"tenant_slug" json:"tenant_slug"`
 }
+
+// Organization is a tenant as seen by one of its members, including the member's role
+type Organization struct {
+	Tenant
+	Role string `db:"role" json:"role"`
+}
+
+// Member roles, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// roleRank orders roles by privilege; unknown roles rank below member
+var roleRank = map[string]int{RoleOwner: 3, RoleAdmin: 2, RoleMember: 1}
+
+// RoleAtLeast reports whether role is at least as privileged as min
+func RoleAtLeast(role, min string) bool {
+	return roleRank[role] > 0 && roleRank[role] >= roleRank[min]
+}
+
+// MaxInviteDaysLimit is the longest invitation lifetime a tenant may allow
+const MaxInviteDaysLimit = 90
+
+// Policies are a tenant's membership settings
+type Policies struct {
+	TenantID string `json:"tenant_id" db:"tenant_id"`
+	// InviteRole is the lowest role that may invite members
+	InviteRole string `json:"invite_role" db:"invite_role"`
+	// AutoJoinRole is the role given to users joining through a verified email domain
+	AutoJoinRole             string    `json:"auto_join_role" db:"auto_join_role"`
+	MembersCanCreateProjects bool      `json:"members_can_create_projects" db:"members_can_create_projects"`
+	MaxInviteDays            int       `json:"max_invite_days" db:"max_invite_days"`
+	UpdatedAt                time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultPolicies are the policies of a tenant that hasn't changed any
+func DefaultPolicies(tenantID string) *Policies {
+	return &Policies{
+		TenantID:                 tenantID,
+		InviteRole:               RoleAdmin,
+		AutoJoinRole:             RoleMember,
+		MembersCanCreateProjects: true,
+		MaxInviteDays:            7,
+	}
+}
+
+// CanInvite reports whether a member with the given role may invite others
+func (p *Policies) CanInvite(role string) bool {
+	return RoleAtLeast(role, p.InviteRole)
+}
+
+// CanCreateProject reports whether a member with the given role may create projects
+func (p *Policies) CanCreateProject(role string) bool {
+	if p.MembersCanCreateProjects {
+		return RoleAtLeast(role, RoleMember)
+	}
+	return RoleAtLeast(role, RoleAdmin)
+}
+
+// InviteExpiry caps a requested invitation lifetime at the tenant's maximum; zero requests the maximum
+func (p *Policies) InviteExpiry(requested time.Duration) time.Duration {
+	limit := time.Duration(p.MaxInviteDays) * 24 * time.Hour
+	if requested <= 0 || requested > limit {
+		return limit
+	}
+	return requested
+}
+
+// BucketingSettings is how a tenant's rollouts admit users
+type BucketingSettings struct {
+	TenantID string `json:"tenant_id"`
+	// StrictRollouts buckets sha256 projects so a rollout of N% admits exactly N% of users
+	// and 0% admits nobody. Turning it on moves about 1% of users in or out of each rollout.
+	StrictRollouts bool `json:"strict_rollouts"`
+}
+
+// UpdateBucketingRequest switches a tenant's rollouts to or from strict bucketing
+type UpdateBucketingRequest struct {
+	StrictRollouts *bool `json:"strict_rollouts" binding:"required"`
+}
+
+// Bounds of the polling interval a tenant may recommend to its SDKs
+const (
+	MinPollingIntervalSeconds = 5
+	MaxPollingIntervalSeconds = 3600
+)
+
+// SDKConfig is what a tenant's SDKs are told by GET /sdk/config to tune themselves
+type SDKConfig struct {
+	TenantID string `json:"tenant_id"`
+	// PollingIntervalSeconds is how often SDKs that aren't streaming should re-evaluate
+	PollingIntervalSeconds int  `json:"polling_interval_seconds" db:"sdk_polling_interval_seconds"`
+	StreamingEnabled       bool `json:"streaming_enabled" db:"sdk_streaming_enabled"`
+}
+
+// UpdateSDKConfigRequest changes some of a tenant's SDK config; omitted fields keep their value
+type UpdateSDKConfigRequest struct {
+	PollingIntervalSeconds *int  `json:"polling_interval_seconds"`
+	StreamingEnabled       *bool `json:"streaming_enabled"`
+}
+
+// UpdatePoliciesRequest changes some of a tenant's policies; omitted fields keep their value
+type UpdatePoliciesRequest struct {
+	InviteRole               *string `json:"invite_role"`
+	AutoJoinRole             *string `json:"auto_join_role"`
+	MembersCanCreateProjects *bool   `json:"members_can_create_projects"`
+	MaxInviteDays            *int    `json:"max_invite_days"`
+}
@@ -0,0 +1,73 @@
+package tenants
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicies(t *testing.T) {
+	p := DefaultPolicies("tenant-1")
+
+	if !p.CanInvite(RoleAdmin) || p.CanInvite(RoleMember) {
+		t.Error("expected only owners and admins to invite by default")
+	}
+	if !p.CanCreateProject(RoleMember) {
+		t.Error("expected members to create projects by default")
+	}
+
+	p.InviteRole = RoleMember
+	p.MembersCanCreateProjects = false
+	if !p.CanInvite(RoleMember) {
+		t.Error("expected members to invite once allowed")
+	}
+	if p.CanCreateProject(RoleMember) || !p.CanCreateProject(RoleAdmin) {
+		t.Error("expected only admins and owners to create projects once members can't")
+	}
+	if p.CanInvite("") || p.CanCreateProject("viewer") {
+		t.Error("expected unknown roles to be denied")
+	}
+}
+
+func TestPoliciesInviteExpiry(t *testing.T) {
+	p := DefaultPolicies("tenant-1")
+	week := 7 * 24 * time.Hour
+
+	tests := []struct {
+		requested time.Duration
+		want      time.Duration
+	}{
+		{requested: 0, want: week},
+		{requested: 24 * time.Hour, want: 24 * time.Hour},
+		{requested: 30 * 24 * time.Hour, want: week},
+	}
+	for _, tt := range tests {
+		if got := p.InviteExpiry(tt.requested); got != tt.want {
+			t.Errorf("InviteExpiry(%v) = %v, want %v", tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestInviteLinkRedeemable(t *testing.T) {
+	now := time.Now()
+	revoked := now.Add(-time.Minute)
+
+	tests := []struct {
+		name string
+		link InviteLink
+		want bool
+	}{
+		{name: "unused", link: InviteLink{MaxUses: 5, ExpiresAt: now.Add(time.Hour)}, want: true},
+		{name: "one use left", link: InviteLink{MaxUses: 5, Uses: 4, ExpiresAt: now.Add(time.Hour)}, want: true},
+		{name: "used up", link: InviteLink{MaxUses: 5, Uses: 5, ExpiresAt: now.Add(time.Hour)}, want: false},
+		{name: "expired", link: InviteLink{MaxUses: 5, ExpiresAt: now}, want: false},
+		{name: "revoked", link: InviteLink{MaxUses: 5, ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.link.Redeemable(now); got != tt.want {
+				t.Errorf("Redeemable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package tenants
+
+import (
+	"net/http"
+
+	"github.com/jalil32/toggle/internal/pkg/openapi"
+)
+
+// Operations documents the routes RegisterRoutes serves, for the OpenAPI document
+func Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: http.MethodGet, Path: "/tenant", Summary: "Get the X-Tenant-ID organization (deprecated)", Response: Tenant{}},
+		{Method: http.MethodPut, Path: "/tenant", Summary: "Rename the X-Tenant-ID organization (deprecated)", Request: UpdateRequest{}, Response: Tenant{}},
+		{Method: http.MethodGet, Path: "/tenant/policies", Summary: "Get the organization's membership policies", Response: Policies{}},
+		{Method: http.MethodPut, Path: "/tenant/policies", Summary: "Update the organization's membership policies", Request: UpdatePoliciesRequest{}, Response: Policies{}},
+		{Method: http.MethodGet, Path: "/tenant/bucketing", Summary: "Get how the organization's rollouts admit users", Response: BucketingSettings{}},
+		{Method: http.MethodPut, Path: "/tenant/bucketing", Summary: "Switch the organization's rollouts to or from strict bucketing", Request: UpdateBucketingRequest{}, Response: BucketingSettings{}},
+		{Method: http.MethodGet, Path: "/tenant/sdk-config", Summary: "Get the polling and streaming settings sent to SDKs", Response: SDKConfig{}},
+		{Method: http.MethodPut, Path: "/tenant/sdk-config", Summary: "Update the polling and streaming settings sent to SDKs", Request: UpdateSDKConfigRequest{}, Response: SDKConfig{}},
+		{Method: http.MethodGet, Path: "/tenant/invite-links", Summary: "List the organization's invite links", Response: []InviteLink{}},
+		{Method: http.MethodPost, Path: "/tenant/invite-links", Summary: "Create an invite link", Request: CreateInviteLinkRequest{}, Response: InviteLink{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/tenant/invite-links/:id", Summary: "Revoke an invite link", Status: http.StatusNoContent},
+	}
+}
+
+// UserOperations documents the routes RegisterUserRoutes, RegisterJoinRoutes and
+// RegisterOrganizationRoutes serve, for the OpenAPI document
+func UserOperations() []openapi.Operation {
+	return []openapi.Operation{
+		{Method: http.MethodPost, Path: "/tenants", Summary: "Create an organization (deprecated)", Request: CreateRequest{}, Response: Tenant{}, Status: http.StatusCreated},
+		{Method: http.MethodPost, Path: "/join/:code", Summary: "Join an organization with an invite link", Response: JoinResult{}},
+		{Method: http.MethodGet, Path: "/organizations", Summary: "List the user's organizations", Response: []Organization{}},
+		{Method: http.MethodPost, Path: "/organizations", Summary: "Create an organization owned by the user", Request: CreateRequest{}, Response: Tenant{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/organizations/:id", Summary: "Get one of the user's organizations", Response: Organization{}},
+		{Method: http.MethodPut, Path: "/organizations/:id", Summary: "Rename an organization", Request: CreateRequest{}, Response: Organization{}},
+		{Method: http.MethodDelete, Path: "/organizations/:id", Summary: "Delete an organization", Status: http.StatusNoContent},
+		{Method: http.MethodPost, Path: "/organizations/:id/merge", Summary: "Merge another organization into this one", Request: MergeRequest{}, Response: MergeReport{}},
+	}
+}
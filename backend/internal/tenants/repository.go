@@ -2,6 +2,11 @@ package tenants
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 
@@ -15,12 +20,38 @@ type Repository interface {
 	GetBySlug(ctx context.Context, slug string) (*Tenant, error)
 	SlugExists(ctx context.Context, slug string) (bool, error)
 	Update(ctx context.Context, id, name string) (*Tenant, error)
+	Delete(ctx context.Context, id string) error
 
 	// Membership operations
 	GetMembership(ctx context.Context, userID, tenantID string) (string, error)
 	HasMemberships(ctx context.Context, userID string) (bool, error)
 	CreateMembership(ctx context.Context, userID, tenantID, role string) error
 	ListUserTenants(ctx context.Context, userID string) ([]*TenantMembership, error)
+	ListOrganizations(ctx context.Context, userID string) ([]*Organization, error)
+
+	// Policy operations
+	GetPolicies(ctx context.Context, tenantID string) (*Policies, error)
+	UpsertPolicies(ctx context.Context, p *Policies) error
+
+	// Bucketing operations
+	GetStrictRollouts(ctx context.Context, tenantID string) (bool, error)
+	SetStrictRollouts(ctx context.Context, tenantID string, strict bool) error
+
+	// SDK config operations
+	GetSDKConfig(ctx context.Context, tenantID string) (*SDKConfig, error)
+	UpdateSDKConfig(ctx context.Context, cfg *SDKConfig) error
+
+	// Invite link operations
+	CreateInviteLink(ctx context.Context, link *InviteLink) error
+	ListInviteLinks(ctx context.Context, tenantID string) ([]*InviteLink, error)
+	RevokeInviteLink(ctx context.Context, id, tenantID string) error
+	GetInviteLinkForUpdate(ctx context.Context, code string) (*InviteLink, error)
+	RecordInviteLinkUse(ctx context.Context, link *InviteLink, userID string) error
+
+	// Merge operations
+	LockForMerge(ctx context.Context, sourceID, targetID string) error
+	PlanMerge(ctx context.Context, sourceID, targetID string) (*MergeReport, error)
+	ApplyMerge(ctx context.Context, report *MergeReport) error
 }
 
 type postgresRepo struct {
@@ -112,6 +143,28 @@ func (r *postgresRepo) Update(ctx context.Context, id, name string) (*Tenant, er
 	return &tenant, nil
 }
 
+// Delete removes a tenant; members, projects and flags are removed by cascade
+// Returns sql.ErrNoRows if the tenant does not exist
+func (r *postgresRepo) Delete(ctx context.Context, id string) error {
+	executor := r.getExecutor(ctx)
+
+	result, err := executor.ExecContext(ctx, `DELETE FROM tenants WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
 // Membership repository methods
 
 // GetMembership returns the role of a user in a tenant
@@ -184,3 +237,378 @@ func (r *postgresRepo) ListUserTenants(ctx context.Context, userID string) ([]*T
 
 	return memberships, nil
 }
+
+// ListOrganizations returns the full tenant records a user is a member of, with the user's role
+func (r *postgresRepo) ListOrganizations(ctx context.Context, userID string) ([]*Organization, error) {
+	executor := r.getExecutor(ctx)
+
+	query := `
+		SELECT t.id, t.name, t.slug, t.created_at, t.updated_at, tm.role
+		FROM tenant_members tm
+		INNER JOIN tenants t ON tm.tenant_id = t.id
+		WHERE tm.user_id = $1
+		ORDER BY tm.created_at ASC
+	`
+
+	var organizations []*Organization
+	err := sqlx.SelectContext(ctx, executor, &organizations, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+// Policy repository methods
+
+// GetPolicies returns a tenant's stored policies; sql.ErrNoRows means the defaults apply
+func (r *postgresRepo) GetPolicies(ctx context.Context, tenantID string) (*Policies, error) {
+	var p Policies
+	executor := r.getExecutor(ctx)
+
+	query := `
+		SELECT tenant_id, invite_role, auto_join_role, members_can_create_projects, max_invite_days, updated_at
+		FROM tenant_policies
+		WHERE tenant_id = $1
+	`
+
+	if err := sqlx.GetContext(ctx, executor, &p, query, tenantID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpsertPolicies stores a tenant's policies, replacing any stored before
+func (r *postgresRepo) UpsertPolicies(ctx context.Context, p *Policies) error {
+	executor := r.getExecutor(ctx)
+
+	query := `
+		INSERT INTO tenant_policies (tenant_id, invite_role, auto_join_role, members_can_create_projects, max_invite_days)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			invite_role = EXCLUDED.invite_role,
+			auto_join_role = EXCLUDED.auto_join_role,
+			members_can_create_projects = EXCLUDED.members_can_create_projects,
+			max_invite_days = EXCLUDED.max_invite_days,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	return sqlx.GetContext(ctx, executor, &p.UpdatedAt, query,
+		p.TenantID, p.InviteRole, p.AutoJoinRole, p.MembersCanCreateProjects, p.MaxInviteDays)
+}
+
+// Bucketing repository methods
+
+// GetStrictRollouts reports whether a tenant has opted into strict rollout bucketing
+func (r *postgresRepo) GetStrictRollouts(ctx context.Context, tenantID string) (bool, error) {
+	var strict bool
+	executor := r.getExecutor(ctx)
+
+	query := `SELECT strict_rollouts FROM tenants WHERE id = $1`
+
+	if err := sqlx.GetContext(ctx, executor, &strict, query, tenantID); err != nil {
+		return false, err
+	}
+	return strict, nil
+}
+
+// SetStrictRollouts switches a tenant's rollout bucketing and bumps the generation of each of
+// its projects, since cached snapshots carry the bucketing algorithm
+func (r *postgresRepo) SetStrictRollouts(ctx context.Context, tenantID string, strict bool) error {
+	executor := r.getExecutor(ctx)
+
+	result, err := executor.ExecContext(ctx, `
+		UPDATE tenants SET strict_rollouts = $2, updated_at = NOW() WHERE id = $1
+	`, tenantID, strict)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = executor.ExecContext(ctx, `
+		UPDATE projects SET generation = generation + 1, updated_at = NOW() WHERE tenant_id = $1
+	`, tenantID)
+	return err
+}
+
+// SDK config repository methods
+
+// GetSDKConfig returns what a tenant's SDKs are told to tune themselves with
+func (r *postgresRepo) GetSDKConfig(ctx context.Context, tenantID string) (*SDKConfig, error) {
+	cfg := SDKConfig{TenantID: tenantID}
+	executor := r.getExecutor(ctx)
+
+	query := `SELECT sdk_polling_interval_seconds, sdk_streaming_enabled FROM tenants WHERE id = $1`
+
+	if err := sqlx.GetContext(ctx, executor, &cfg, query, tenantID); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// UpdateSDKConfig stores a tenant's SDK config; sql.ErrNoRows if the tenant doesn't exist
+func (r *postgresRepo) UpdateSDKConfig(ctx context.Context, cfg *SDKConfig) error {
+	executor := r.getExecutor(ctx)
+
+	result, err := executor.ExecContext(ctx, `
+		UPDATE tenants
+		SET sdk_polling_interval_seconds = $2, sdk_streaming_enabled = $3, updated_at = NOW()
+		WHERE id = $1
+	`, cfg.TenantID, cfg.PollingIntervalSeconds, cfg.StreamingEnabled)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Invite link repository methods
+
+const inviteLinkColumns = `id, tenant_id, code, role, max_uses, uses, expires_at, created_by, revoked_at, created_at`
+
+// CreateInviteLink stores a new invite link, filling in its ID and creation time
+func (r *postgresRepo) CreateInviteLink(ctx context.Context, link *InviteLink) error {
+	executor := r.getExecutor(ctx)
+
+	query := `
+		INSERT INTO tenant_invite_links (tenant_id, code, role, max_uses, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	return executor.QueryRowxContext(ctx, query,
+		link.TenantID, link.Code, link.Role, link.MaxUses, link.ExpiresAt, link.CreatedBy,
+	).Scan(&link.ID, &link.CreatedAt)
+}
+
+// ListInviteLinks returns a tenant's invite links, newest first, including spent ones
+func (r *postgresRepo) ListInviteLinks(ctx context.Context, tenantID string) ([]*InviteLink, error) {
+	executor := r.getExecutor(ctx)
+
+	query := `SELECT ` + inviteLinkColumns + `
+		FROM tenant_invite_links
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+	`
+
+	var links []*InviteLink
+	if err := sqlx.SelectContext(ctx, executor, &links, query, tenantID); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// RevokeInviteLink revokes a tenant's invite link
+// Returns sql.ErrNoRows if the tenant has no such link or it was already revoked
+func (r *postgresRepo) RevokeInviteLink(ctx context.Context, id, tenantID string) error {
+	executor := r.getExecutor(ctx)
+
+	query := `
+		UPDATE tenant_invite_links
+		SET revoked_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := executor.ExecContext(ctx, query, id, tenantID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetInviteLinkForUpdate returns the invite link with the given code, locked for the rest
+// of the transaction so concurrent redemptions can't exceed its uses
+func (r *postgresRepo) GetInviteLinkForUpdate(ctx context.Context, code string) (*InviteLink, error) {
+	var link InviteLink
+	executor := r.getExecutor(ctx)
+
+	query := `SELECT ` + inviteLinkColumns + `
+		FROM tenant_invite_links
+		WHERE code = $1
+		FOR UPDATE
+	`
+
+	if err := sqlx.GetContext(ctx, executor, &link, query, code); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// RecordInviteLinkUse counts a use of the link and records the user who joined through it
+func (r *postgresRepo) RecordInviteLinkUse(ctx context.Context, link *InviteLink, userID string) error {
+	executor := r.getExecutor(ctx)
+
+	if err := sqlx.GetContext(ctx, executor, &link.Uses,
+		`UPDATE tenant_invite_links SET uses = uses + 1 WHERE id = $1 RETURNING uses`, link.ID,
+	); err != nil {
+		return fmt.Errorf("count invite link use: %w", err)
+	}
+
+	if _, err := executor.ExecContext(ctx, `
+		INSERT INTO tenant_invite_link_redemptions (link_id, tenant_id, user_id)
+		VALUES ($1, $2, $3)
+	`, link.ID, link.TenantID, userID); err != nil {
+		return fmt.Errorf("record invite link redemption: %w", err)
+	}
+	return nil
+}
+
+// Merge repository methods
+
+// LockForMerge locks both tenants for the rest of the transaction, so concurrent merges
+// or deletes of either tenant wait until the merge commits
+func (r *postgresRepo) LockForMerge(ctx context.Context, sourceID, targetID string) error {
+	executor := r.getExecutor(ctx)
+
+	var locked []string
+	query := `SELECT id FROM tenants WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`
+	if err := sqlx.SelectContext(ctx, executor, &locked, query, sourceID, targetID); err != nil {
+		return err
+	}
+	if len(locked) != 2 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// droppedInvitationCondition matches source invitations the target already covers,
+// either by inviting the same email or by having that user as a member
+const droppedInvitationCondition = `
+	tenant_id = $1 AND (
+		email IN (SELECT email FROM tenant_invitations WHERE tenant_id = $2)
+		OR email IN (
+			SELECT u.email FROM tenant_members tm
+			INNER JOIN users u ON u.id = tm.user_id
+			WHERE tm.tenant_id = $2
+		)
+	)`
+
+// PlanMerge reports what merging the source into the target would move and which
+// members and templates conflict. Roles and template names are left for the caller to resolve.
+func (r *postgresRepo) PlanMerge(ctx context.Context, sourceID, targetID string) (*MergeReport, error) {
+	executor := r.getExecutor(ctx)
+	report := &MergeReport{SourceID: sourceID, TargetID: targetID, Moved: make(map[string]int)}
+
+	counts := make([]string, 0, len(mergedTables)+1)
+	for _, table := range append(slices.Clone(mergedTables), "tenant_invitations") {
+		counts = append(counts, fmt.Sprintf(`SELECT '%s' AS name, COUNT(*) AS count FROM %s WHERE tenant_id = $1`, table, table))
+	}
+	var moved []struct {
+		Name  string `db:"name"`
+		Count int    `db:"count"`
+	}
+	if err := sqlx.SelectContext(ctx, executor, &moved, strings.Join(counts, " UNION ALL "), sourceID); err != nil {
+		return nil, fmt.Errorf("count tenant data: %w", err)
+	}
+	for _, m := range moved {
+		report.Moved[m.Name] = m.Count
+	}
+
+	members := `
+		SELECT s.user_id, u.email, s.role AS source_role, COALESCE(t.role, '') AS target_role
+		FROM tenant_members s
+		INNER JOIN users u ON u.id = s.user_id
+		LEFT JOIN tenant_members t ON t.user_id = s.user_id AND t.tenant_id = $2
+		WHERE s.tenant_id = $1
+		ORDER BY u.email
+	`
+	if err := sqlx.SelectContext(ctx, executor, &report.Members, members, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+
+	templates := `
+		SELECT s.id, s.name
+		FROM flag_templates s
+		INNER JOIN flag_templates t ON t.name = s.name AND t.tenant_id = $2
+		WHERE s.tenant_id = $1
+		ORDER BY s.name
+	`
+	if err := sqlx.SelectContext(ctx, executor, &report.RenamedTemplates, templates, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("list template conflicts: %w", err)
+	}
+
+	dropped := `SELECT COUNT(*) FROM tenant_invitations WHERE ` + droppedInvitationCondition
+	if err := sqlx.GetContext(ctx, executor, &report.DroppedInvitations, dropped, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("count invitation conflicts: %w", err)
+	}
+	report.Moved["tenant_invitations"] -= report.DroppedInvitations
+
+	for _, table := range slices.Sorted(maps.Keys(discardedSettings)) {
+		var exists bool
+		query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE tenant_id = $1)`, table)
+		if err := sqlx.GetContext(ctx, executor, &exists, query, sourceID); err != nil {
+			return nil, fmt.Errorf("check %s: %w", table, err)
+		}
+		if exists {
+			report.Discarded = append(report.Discarded, discardedSettings[table])
+		}
+	}
+
+	// Presets sealed with the source's data key authenticate the source tenant ID,
+	// so they can't be opened once they belong to the target
+	sealed := `SELECT COUNT(*) FROM context_presets WHERE tenant_id = $1 AND sealed IS NOT NULL`
+	if err := sqlx.GetContext(ctx, executor, &report.sealedPresets, sealed, sourceID); err != nil {
+		return nil, fmt.Errorf("count sealed presets: %w", err)
+	}
+
+	return report, nil
+}
+
+// ApplyMerge moves the source tenant's data and members into the target as planned.
+// The source tenant itself is left for the caller to delete in the same transaction.
+func (r *postgresRepo) ApplyMerge(ctx context.Context, report *MergeReport) error {
+	executor := r.getExecutor(ctx)
+	sourceID, targetID := report.SourceID, report.TargetID
+
+	for _, t := range report.RenamedTemplates {
+		if _, err := executor.ExecContext(ctx, `UPDATE flag_templates SET name = $2 WHERE id = $1`, t.ID, t.NewName); err != nil {
+			return fmt.Errorf("rename template %s: %w", t.ID, err)
+		}
+	}
+
+	for _, table := range mergedTables {
+		query := fmt.Sprintf(`UPDATE %s SET tenant_id = $2 WHERE tenant_id = $1`, table)
+		if _, err := executor.ExecContext(ctx, query, sourceID, targetID); err != nil {
+			return fmt.Errorf("move %s: %w", table, err)
+		}
+	}
+
+	for _, m := range report.Members {
+		if err := r.CreateMembership(ctx, m.UserID, targetID, m.Role); err != nil {
+			return fmt.Errorf("merge member %s: %w", m.UserID, err)
+		}
+	}
+
+	if _, err := executor.ExecContext(ctx, `DELETE FROM tenant_invitations WHERE `+droppedInvitationCondition, sourceID, targetID); err != nil {
+		return fmt.Errorf("drop invitations: %w", err)
+	}
+	if _, err := executor.ExecContext(ctx, `UPDATE tenant_invitations SET tenant_id = $2 WHERE tenant_id = $1`, sourceID, targetID); err != nil {
+		return fmt.Errorf("move invitations: %w", err)
+	}
+
+	if _, err := executor.ExecContext(ctx, `UPDATE users SET last_active_tenant_id = $2 WHERE last_active_tenant_id = $1`, sourceID, targetID); err != nil {
+		return fmt.Errorf("move active tenant: %w", err)
+	}
+
+	return nil
+}
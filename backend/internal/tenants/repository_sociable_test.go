@@ -2,8 +2,10 @@ package tenants_test
 
 import (
 	"context"
+	"database/sql"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/jalil32/toggle/internal/pkg/transaction"
 	"github.com/jalil32/toggle/internal/tenants"
@@ -159,3 +161,283 @@ func TestRepository_CaseSensitiveSlug(t *testing.T) {
 		assert.True(t, exists2)
 	})
 }
+
+// TestRepository_ListOrganizations_OnlyReturnsMemberships tests that a user
+// only sees the organizations they belong to, with their role in each
+func TestRepository_ListOrganizations_OnlyReturnsMemberships(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		repo := tenants.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		user := testutil.CreateUser(t, tx, "Alice", "alice@example.com")
+		owned := testutil.CreateTenant(t, tx, "Alice Co", "alice-co")
+		joined := testutil.CreateTenant(t, tx, "Partner Co", "partner-co")
+		other := testutil.CreateTenant(t, tx, "Other Co", "other-co")
+
+		testutil.CreateTenantMember(t, tx, user.ID, owned.ID, "owner")
+		testutil.CreateTenantMember(t, tx, user.ID, joined.ID, "member")
+
+		organizations, err := repo.ListOrganizations(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, organizations, 2)
+
+		roles := map[string]string{}
+		for _, org := range organizations {
+			roles[org.ID] = org.Role
+			assert.NotEmpty(t, org.Name)
+			assert.False(t, org.CreatedAt.IsZero())
+		}
+		assert.Equal(t, "owner", roles[owned.ID])
+		assert.Equal(t, "member", roles[joined.ID])
+		assert.NotContains(t, roles, other.ID)
+	})
+}
+
+// TestRepository_Delete_CascadesToTenantData tests that deleting a tenant
+// removes its memberships and projects within the same transaction
+func TestRepository_Delete_CascadesToTenantData(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		repo := tenants.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		user := testutil.CreateUser(t, tx, "Bob", "bob@example.com")
+		tenant := testutil.CreateTenant(t, tx, "Doomed Co", "doomed-co")
+		testutil.CreateTenantMember(t, tx, user.ID, tenant.ID, "owner")
+		testutil.CreateProject(t, tx, tenant.ID, "Web", "doomed-api-key")
+
+		err := repo.Delete(ctx, tenant.ID)
+		require.NoError(t, err)
+
+		_, err = repo.GetByID(ctx, tenant.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		role, err := repo.GetMembership(ctx, user.ID, tenant.ID)
+		require.NoError(t, err)
+		assert.Empty(t, role)
+
+		var projectCount int
+		err = tx.GetContext(ctx, &projectCount, `SELECT COUNT(*) FROM projects WHERE tenant_id = $1`, tenant.ID)
+		require.NoError(t, err)
+		assert.Zero(t, projectCount)
+
+		// Deleting again reports no rows
+		err = repo.Delete(ctx, tenant.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+// TestRepository_Policies_UpsertAndGet tests that tenants start without stored
+// policies and that upserting replaces earlier values
+func TestRepository_Policies_UpsertAndGet(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		repo := tenants.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		tenant, err := repo.Create(ctx, "Policy Corp", "policy-corp")
+		require.NoError(t, err)
+
+		_, err = repo.GetPolicies(ctx, tenant.ID)
+		assert.ErrorIs(t, err, sql.ErrNoRows, "tenants without stored policies use the defaults")
+
+		policies := tenants.DefaultPolicies(tenant.ID)
+		policies.MembersCanCreateProjects = false
+		require.NoError(t, repo.UpsertPolicies(ctx, policies))
+
+		policies.InviteRole = tenants.RoleMember
+		policies.MaxInviteDays = 30
+		require.NoError(t, repo.UpsertPolicies(ctx, policies))
+
+		stored, err := repo.GetPolicies(ctx, tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, tenants.RoleMember, stored.InviteRole)
+		assert.Equal(t, tenants.RoleMember, stored.AutoJoinRole)
+		assert.False(t, stored.MembersCanCreateProjects)
+		assert.Equal(t, 30, stored.MaxInviteDays)
+
+		// The database rejects owners as the auto-join role
+		policies.AutoJoinRole = tenants.RoleOwner
+		assert.Error(t, repo.UpsertPolicies(ctx, policies))
+	})
+}
+
+func TestRepository_StrictRollouts_BumpsProjectGenerations(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		repo := tenants.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		tenant := testutil.CreateTenant(t, tx, "Rollout Corp", "rollout-corp")
+		project := testutil.CreateProject(t, tx, tenant.ID, "Web", "rollout-corp-key")
+
+		strict, err := repo.GetStrictRollouts(ctx, tenant.ID)
+		require.NoError(t, err)
+		assert.False(t, strict, "tenants keep sha256 bucketing until they opt in")
+
+		var before, after int64
+		require.NoError(t, tx.Get(&before, `SELECT generation FROM projects WHERE id = $1`, project.ID))
+		require.NoError(t, repo.SetStrictRollouts(ctx, tenant.ID, true))
+		require.NoError(t, tx.Get(&after, `SELECT generation FROM projects WHERE id = $1`, project.ID))
+		assert.Greater(t, after, before, "cached snapshots carry the bucketing algorithm")
+
+		strict, err = repo.GetStrictRollouts(ctx, tenant.ID)
+		require.NoError(t, err)
+		assert.True(t, strict)
+
+		assert.ErrorIs(t, repo.SetStrictRollouts(ctx, "00000000-0000-0000-0000-000000000000", true), sql.ErrNoRows)
+	})
+}
+
+func TestRepository_SDKConfig_DefaultsAndUpdates(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		repo := tenants.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		tenant := testutil.CreateTenant(t, tx, "Polling Corp", "polling-corp")
+
+		cfg, err := repo.GetSDKConfig(ctx, tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, &tenants.SDKConfig{TenantID: tenant.ID, PollingIntervalSeconds: 30, StreamingEnabled: true}, cfg)
+
+		cfg.PollingIntervalSeconds = 120
+		cfg.StreamingEnabled = false
+		require.NoError(t, repo.UpdateSDKConfig(ctx, cfg))
+
+		stored, err := repo.GetSDKConfig(ctx, tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, cfg, stored)
+
+		missing := &tenants.SDKConfig{TenantID: "00000000-0000-0000-0000-000000000000", PollingIntervalSeconds: 30}
+		assert.ErrorIs(t, repo.UpdateSDKConfig(ctx, missing), sql.ErrNoRows)
+	})
+}
+
+// TestRepository_InviteLinks_TracksUsesAndRevocation tests that invite link uses are counted
+// and recorded, can't exceed max_uses, and that revoking is scoped to the link's tenant
+func TestRepository_InviteLinks_TracksUsesAndRevocation(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		repo := tenants.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		tenant := testutil.CreateTenant(t, tx, "Link Corp", "link-corp")
+		other := testutil.CreateTenant(t, tx, "Other Corp", "other-corp")
+		admin := testutil.CreateUser(t, tx, "Admin", "admin@link.example")
+		alice := testutil.CreateUser(t, tx, "Alice", "alice@link.example")
+		bob := testutil.CreateUser(t, tx, "Bob", "bob@link.example")
+
+		link := &tenants.InviteLink{
+			TenantID:  tenant.ID,
+			Code:      "team-onboarding-code",
+			Role:      tenants.RoleMember,
+			MaxUses:   1,
+			ExpiresAt: time.Now().Add(24 * time.Hour),
+			CreatedBy: &admin.ID,
+		}
+		require.NoError(t, repo.CreateInviteLink(ctx, link))
+		require.NotEmpty(t, link.ID)
+
+		locked, err := repo.GetInviteLinkForUpdate(ctx, link.Code)
+		require.NoError(t, err)
+		assert.True(t, locked.Redeemable(time.Now()))
+
+		require.NoError(t, repo.RecordInviteLinkUse(ctx, locked, alice.ID))
+		assert.Equal(t, 1, locked.Uses)
+
+		// The database refuses uses beyond max_uses
+		_, err = tx.ExecContext(ctx, "SAVEPOINT over_use")
+		require.NoError(t, err)
+		assert.Error(t, repo.RecordInviteLinkUse(ctx, locked, bob.ID))
+		_, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT over_use")
+		require.NoError(t, err)
+
+		var redeemed []string
+		require.NoError(t, tx.SelectContext(ctx, &redeemed,
+			`SELECT user_id FROM tenant_invite_link_redemptions WHERE link_id = $1`, link.ID))
+		assert.Equal(t, []string{alice.ID}, redeemed)
+
+		assert.ErrorIs(t, repo.RevokeInviteLink(ctx, link.ID, other.ID), sql.ErrNoRows,
+			"links can only be revoked by their own tenant")
+		require.NoError(t, repo.RevokeInviteLink(ctx, link.ID, tenant.ID))
+		assert.ErrorIs(t, repo.RevokeInviteLink(ctx, link.ID, tenant.ID), sql.ErrNoRows)
+
+		links, err := repo.ListInviteLinks(ctx, tenant.ID)
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		assert.NotNil(t, links[0].RevokedAt)
+		assert.False(t, links[0].Redeemable(time.Now()))
+
+		_, err = repo.GetInviteLinkForUpdate(ctx, "no-such-code")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+// TestRepository_Merge_MovesDataAndMembers tests that a planned merge moves the source's
+// projects and flags, resolves member roles and template names, and leaves the source empty
+func TestRepository_Merge_MovesDataAndMembers(t *testing.T) {
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		repo := tenants.NewRepository(testutil.GetTestDB())
+		ctx = transaction.InjectTx(ctx, tx)
+
+		owner := testutil.CreateUser(t, tx, "Olive", "olive@example.com")
+		member := testutil.CreateUser(t, tx, "Max", "max@example.com")
+		target := testutil.CreateTenant(t, tx, "Acquirer", "acquirer")
+		source := testutil.CreateTenant(t, tx, "Acquired", "acquired")
+		testutil.CreateTenantMember(t, tx, owner.ID, target.ID, "owner")
+		testutil.CreateTenantMember(t, tx, owner.ID, source.ID, "owner")
+		testutil.CreateTenantMember(t, tx, member.ID, source.ID, "member")
+		project := testutil.CreateProject(t, tx, source.ID, "Mobile", "acquired-api-key")
+		testutil.CreateFlag(t, tx, source.ID, &project.ID, "dark-mode", "", true)
+		_, err := tx.ExecContext(ctx, `INSERT INTO project_attributes (tenant_id, project_id, name, type) VALUES ($1, $2, 'seats', 'number')`, source.ID, project.ID)
+		require.NoError(t, err)
+		_, err = tx.ExecContext(ctx, `INSERT INTO api_key_request_counts (project_id, tenant_id, key_id, hour, request_count) VALUES ($1, $2, $1, date_trunc('hour', NOW()), 12)`, project.ID, source.ID)
+		require.NoError(t, err)
+
+		for _, tenantID := range []string{target.ID, source.ID} {
+			_, err := tx.ExecContext(ctx, `INSERT INTO flag_templates (tenant_id, name) VALUES ($1, 'Kill switch')`, tenantID)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, repo.LockForMerge(ctx, source.ID, target.ID))
+
+		report, err := repo.PlanMerge(ctx, source.ID, target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Moved["projects"])
+		assert.Equal(t, 1, report.Moved["flags"])
+		require.Len(t, report.Members, 2)
+		require.Len(t, report.RenamedTemplates, 1)
+
+		for i := range report.Members {
+			report.Members[i].Role = tenants.ResolveMemberRole(report.Members[i].SourceRole, report.Members[i].TargetRole)
+		}
+		report.RenamedTemplates[0].NewName = "Kill switch (acquired)"
+
+		require.NoError(t, repo.ApplyMerge(ctx, report))
+		require.NoError(t, repo.Delete(ctx, source.ID))
+
+		var flagTenant string
+		err = tx.GetContext(ctx, &flagTenant, `SELECT tenant_id FROM flags WHERE project_id = $1`, project.ID)
+		require.NoError(t, err)
+		assert.Equal(t, target.ID, flagTenant)
+
+		var attributeTenants []string
+		err = tx.SelectContext(ctx, &attributeTenants, `SELECT tenant_id FROM project_attributes WHERE project_id = $1`, project.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{target.ID}, attributeTenants, "the attribute registry moves with its project")
+
+		var keyRequests int
+		err = tx.GetContext(ctx, &keyRequests, `SELECT COALESCE(SUM(request_count), 0) FROM api_key_request_counts WHERE project_id = $1 AND tenant_id = $2`, project.ID, target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 12, keyRequests, "API key metrics move with their project")
+
+		role, err := repo.GetMembership(ctx, owner.ID, target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "owner", role, "existing target owners keep their role")
+
+		role, err = repo.GetMembership(ctx, member.ID, target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "member", role)
+
+		var templates []string
+		err = tx.SelectContext(ctx, &templates, `SELECT name FROM flag_templates WHERE tenant_id = $1 ORDER BY name`, target.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Kill switch", "Kill switch (acquired)"}, templates)
+	})
+}
@@ -2,13 +2,26 @@ package tenants
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
+	pkgErrors "github.com/jalil32/toggle/internal/pkg/errors"
 	"github.com/jalil32/toggle/internal/pkg/slugs"
 	"github.com/jalil32/toggle/internal/pkg/transaction"
 )
 
+// ErrInsufficientPermissions indicates the member's role does not allow the operation
+var ErrInsufficientPermissions = errors.New("insufficient permissions")
+
+// ErrInvalidPolicy indicates a policy update with an unknown role or out-of-range value
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// ErrInvalidSDKConfig indicates an SDK config update with an out-of-range value
+var ErrInvalidSDKConfig = errors.New("invalid sdk config")
+
 // UserRepository defines the minimal interface needed from users package
 // This avoids circular dependency with users package
 type UserRepository interface {
@@ -148,6 +161,24 @@ func (s *Service) GetByID(ctx context.Context, id string) (*Tenant, error) {
 	return tenant, nil
 }
 
+// Delete removes a tenant and all of its data without a membership check, for tenants the
+// system created itself; user-initiated deletes go through DeleteOrganization
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to delete tenant",
+			slog.String("id", id),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.logger.Info("tenant deleted", slog.String("id", id))
+	return nil
+}
+
 func (s *Service) GetBySlug(ctx context.Context, slug string) (*Tenant, error) {
 	tenant, err := s.repo.GetBySlug(ctx, slug)
 	if err != nil {
@@ -190,3 +221,628 @@ func (s *Service) GetMembership(ctx context.Context, userID, tenantID string) (s
 func (s *Service) ListUserTenants(ctx context.Context, userID string) ([]*TenantMembership, error) {
 	return s.repo.ListUserTenants(ctx, userID)
 }
+
+// Organization methods (user-scoped access to the tenants a user belongs to)
+
+// ListOrganizations returns all organizations the user is a member of
+func (s *Service) ListOrganizations(ctx context.Context, userID string) ([]*Organization, error) {
+	organizations, err := s.repo.ListOrganizations(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to list organizations",
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	if organizations == nil {
+		return []*Organization{}, nil
+	}
+
+	return organizations, nil
+}
+
+// GetOrganization returns an organization the user is a member of
+// Returns ErrNotFound if the organization does not exist or the user is not a member
+func (s *Service) GetOrganization(ctx context.Context, id, userID string) (*Organization, error) {
+	role, err := s.repo.GetMembership(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		s.logger.Debug("organization not found or forbidden",
+			slog.String("id", id),
+			slog.String("user_id", userID),
+		)
+		return nil, pkgErrors.ErrNotFound
+	}
+
+	tenant, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get organization",
+			slog.String("id", id),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	return &Organization{Tenant: *tenant, Role: role}, nil
+}
+
+// UpdateOrganization renames an organization; only owners and admins may update
+func (s *Service) UpdateOrganization(ctx context.Context, id, userID, name string) (*Organization, error) {
+	var organization *Organization
+
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		role, err := s.repo.GetMembership(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			return pkgErrors.ErrNotFound
+		}
+		if role != "owner" && role != "admin" {
+			return ErrInsufficientPermissions
+		}
+
+		tenant, err := s.repo.Update(txCtx, id, name)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return pkgErrors.ErrNotFound
+			}
+			return fmt.Errorf("update organization: %w", err)
+		}
+
+		organization = &Organization{Tenant: *tenant, Role: role}
+		return nil
+	})
+
+	if err != nil {
+		s.logger.Warn("failed to update organization",
+			slog.String("id", id),
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("organization updated",
+		slog.String("id", id),
+		slog.String("name", name),
+		slog.String("user_id", userID),
+	)
+
+	return organization, nil
+}
+
+// DeleteOrganization deletes an organization and all of its data; only owners may delete
+func (s *Service) DeleteOrganization(ctx context.Context, id, userID string) error {
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		role, err := s.repo.GetMembership(txCtx, userID, id)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			return pkgErrors.ErrNotFound
+		}
+		if role != "owner" {
+			return ErrInsufficientPermissions
+		}
+
+		if err := s.repo.Delete(txCtx, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return pkgErrors.ErrNotFound
+			}
+			return fmt.Errorf("delete organization: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		s.logger.Warn("failed to delete organization",
+			slog.String("id", id),
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.logger.Info("organization deleted",
+		slog.String("id", id),
+		slog.String("user_id", userID),
+	)
+
+	return nil
+}
+
+// MergeOrganization merges the source organization into the target: projects, flags,
+// their history and the source's members move to the target, and the source is deleted.
+// The user must own both. A dry run, or a merge with blockers, changes nothing; a
+// blocked merge returns its report with ErrMergeBlocked.
+func (s *Service) MergeOrganization(ctx context.Context, targetID, userID string, req MergeRequest) (*MergeReport, error) {
+	if req.SourceID == targetID {
+		return nil, fmt.Errorf("%w: an organization can't be merged into itself", ErrInvalidMerge)
+	}
+
+	var report *MergeReport
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		for _, id := range []string{targetID, req.SourceID} {
+			role, err := s.repo.GetMembership(txCtx, userID, id)
+			if err != nil {
+				return err
+			}
+			if role == "" {
+				return pkgErrors.ErrNotFound
+			}
+			if role != RoleOwner {
+				return ErrInsufficientPermissions
+			}
+		}
+
+		if err := s.repo.LockForMerge(txCtx, req.SourceID, targetID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return pkgErrors.ErrNotFound
+			}
+			return fmt.Errorf("lock organizations: %w", err)
+		}
+
+		source, err := s.repo.GetByID(txCtx, req.SourceID)
+		if err != nil {
+			return fmt.Errorf("get source organization: %w", err)
+		}
+
+		report, err = s.repo.PlanMerge(txCtx, req.SourceID, targetID)
+		if err != nil {
+			return fmt.Errorf("plan merge: %w", err)
+		}
+		resolveMerge(report, source)
+		report.DryRun = req.DryRun
+
+		if req.DryRun {
+			return nil
+		}
+		if len(report.Blockers) > 0 {
+			return ErrMergeBlocked
+		}
+
+		if err := s.repo.ApplyMerge(txCtx, report); err != nil {
+			return fmt.Errorf("apply merge: %w", err)
+		}
+		if err := s.repo.Delete(txCtx, req.SourceID); err != nil {
+			return fmt.Errorf("delete source organization: %w", err)
+		}
+		report.Merged = true
+		return nil
+	})
+
+	if errors.Is(err, ErrMergeBlocked) {
+		s.logger.Warn("organization merge blocked",
+			slog.String("source_id", req.SourceID),
+			slog.String("target_id", targetID),
+			slog.Any("blockers", report.Blockers),
+		)
+		return report, err
+	}
+	if err != nil {
+		s.logger.Warn("failed to merge organization",
+			slog.String("source_id", req.SourceID),
+			slog.String("target_id", targetID),
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	if report.Merged {
+		s.logger.Info("organization merged",
+			slog.String("source_id", req.SourceID),
+			slog.String("target_id", targetID),
+			slog.String("user_id", userID),
+			slog.Any("moved", report.Moved),
+		)
+	}
+
+	return report, nil
+}
+
+// resolveMerge settles a planned merge's conflicts: member roles, template names taken in
+// the target, and blockers
+func resolveMerge(report *MergeReport, source *Tenant) {
+	for i := range report.Members {
+		report.Members[i].resolve()
+	}
+	for i := range report.RenamedTemplates {
+		t := &report.RenamedTemplates[i]
+		t.NewName = fmt.Sprintf("%s (%s)", t.Name, source.Slug)
+	}
+	if report.sealedPresets > 0 {
+		report.Blockers = append(report.Blockers, fmt.Sprintf(
+			"%d context presets are sealed with the source organization's encryption key and can't be opened by the target; delete them before merging",
+			report.sealedPresets,
+		))
+	}
+}
+
+// Policy methods
+
+// GetPolicies returns a tenant's policies, or the defaults if it hasn't changed any
+func (s *Service) GetPolicies(ctx context.Context, tenantID string) (*Policies, error) {
+	p, err := s.repo.GetPolicies(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultPolicies(tenantID), nil
+		}
+		s.logger.Error("failed to get tenant policies",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("get tenant policies: %w", err)
+	}
+	return p, nil
+}
+
+// UpdatePolicies applies a partial policy update; only owners may change policies
+func (s *Service) UpdatePolicies(ctx context.Context, tenantID, role string, req UpdatePoliciesRequest) (*Policies, error) {
+	if role != RoleOwner {
+		return nil, ErrInsufficientPermissions
+	}
+
+	var policies *Policies
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		p, err := s.GetPolicies(txCtx, tenantID)
+		if err != nil {
+			return err
+		}
+
+		if req.InviteRole != nil {
+			if _, ok := roleRank[*req.InviteRole]; !ok {
+				return fmt.Errorf("%w: invite_role must be owner, admin or member", ErrInvalidPolicy)
+			}
+			p.InviteRole = *req.InviteRole
+		}
+		if req.AutoJoinRole != nil {
+			// Auto-joined users never become owners
+			if *req.AutoJoinRole != RoleAdmin && *req.AutoJoinRole != RoleMember {
+				return fmt.Errorf("%w: auto_join_role must be admin or member", ErrInvalidPolicy)
+			}
+			p.AutoJoinRole = *req.AutoJoinRole
+		}
+		if req.MembersCanCreateProjects != nil {
+			p.MembersCanCreateProjects = *req.MembersCanCreateProjects
+		}
+		if req.MaxInviteDays != nil {
+			if *req.MaxInviteDays < 1 || *req.MaxInviteDays > MaxInviteDaysLimit {
+				return fmt.Errorf("%w: max_invite_days must be between 1 and %d", ErrInvalidPolicy, MaxInviteDaysLimit)
+			}
+			p.MaxInviteDays = *req.MaxInviteDays
+		}
+
+		if err := s.repo.UpsertPolicies(txCtx, p); err != nil {
+			return fmt.Errorf("update tenant policies: %w", err)
+		}
+		policies = p
+		return nil
+	})
+
+	if err != nil {
+		s.logger.Warn("failed to update tenant policies",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	s.logger.Info("tenant policies updated",
+		slog.String("tenant_id", tenantID),
+		slog.String("invite_role", policies.InviteRole),
+		slog.Bool("members_can_create_projects", policies.MembersCanCreateProjects),
+	)
+
+	return policies, nil
+}
+
+// Bucketing methods
+
+// GetBucketing returns how a tenant's rollouts admit users
+func (s *Service) GetBucketing(ctx context.Context, tenantID string) (*BucketingSettings, error) {
+	strict, err := s.repo.GetStrictRollouts(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get tenant bucketing",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("get tenant bucketing: %w", err)
+	}
+	return &BucketingSettings{TenantID: tenantID, StrictRollouts: strict}, nil
+}
+
+// UpdateBucketing switches a tenant's rollouts to or from strict bucketing; owners and admins only.
+// Switching either way moves the users in sha256's bucket 0, about 1% of each rollout.
+func (s *Service) UpdateBucketing(ctx context.Context, tenantID, role string, req UpdateBucketingRequest) (*BucketingSettings, error) {
+	if !RoleAtLeast(role, RoleAdmin) {
+		return nil, ErrInsufficientPermissions
+	}
+
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		return s.repo.SetStrictRollouts(txCtx, tenantID, *req.StrictRollouts)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to update tenant bucketing",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("update tenant bucketing: %w", err)
+	}
+
+	s.logger.Info("tenant bucketing updated",
+		slog.String("tenant_id", tenantID),
+		slog.Bool("strict_rollouts", *req.StrictRollouts),
+	)
+	return &BucketingSettings{TenantID: tenantID, StrictRollouts: *req.StrictRollouts}, nil
+}
+
+// SDK config methods
+
+// GetSDKConfig returns what the tenant's SDKs are told to tune themselves with
+func (s *Service) GetSDKConfig(ctx context.Context, tenantID string) (*SDKConfig, error) {
+	cfg, err := s.repo.GetSDKConfig(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to get tenant sdk config",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("get tenant sdk config: %w", err)
+	}
+	return cfg, nil
+}
+
+// UpdateSDKConfig applies a partial SDK config update; owners and admins only. SDKs pick
+// the change up the next time they read GET /sdk/config.
+func (s *Service) UpdateSDKConfig(ctx context.Context, tenantID, role string, req UpdateSDKConfigRequest) (*SDKConfig, error) {
+	if !RoleAtLeast(role, RoleAdmin) {
+		return nil, ErrInsufficientPermissions
+	}
+	if req.PollingIntervalSeconds != nil &&
+		(*req.PollingIntervalSeconds < MinPollingIntervalSeconds || *req.PollingIntervalSeconds > MaxPollingIntervalSeconds) {
+		return nil, fmt.Errorf("%w: polling_interval_seconds must be between %d and %d",
+			ErrInvalidSDKConfig, MinPollingIntervalSeconds, MaxPollingIntervalSeconds)
+	}
+
+	var cfg *SDKConfig
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		current, err := s.repo.GetSDKConfig(txCtx, tenantID)
+		if err != nil {
+			return err
+		}
+		if req.PollingIntervalSeconds != nil {
+			current.PollingIntervalSeconds = *req.PollingIntervalSeconds
+		}
+		if req.StreamingEnabled != nil {
+			current.StreamingEnabled = *req.StreamingEnabled
+		}
+		if err := s.repo.UpdateSDKConfig(txCtx, current); err != nil {
+			return err
+		}
+		cfg = current
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, pkgErrors.ErrNotFound
+		}
+		s.logger.Error("failed to update tenant sdk config",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("update tenant sdk config: %w", err)
+	}
+
+	s.logger.Info("tenant sdk config updated",
+		slog.String("tenant_id", tenantID),
+		slog.Int("polling_interval_seconds", cfg.PollingIntervalSeconds),
+		slog.Bool("streaming_enabled", cfg.StreamingEnabled),
+	)
+	return cfg, nil
+}
+
+// CanInvite reports whether a member with the given role may invite others to the tenant
+func (s *Service) CanInvite(ctx context.Context, tenantID, role string) (bool, error) {
+	p, err := s.GetPolicies(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return p.CanInvite(role), nil
+}
+
+// CanCreateProject reports whether a member with the given role may create projects in the tenant
+func (s *Service) CanCreateProject(ctx context.Context, tenantID, role string) (bool, error) {
+	p, err := s.GetPolicies(ctx, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return p.CanCreateProject(role), nil
+}
+
+// Invite link methods
+
+// CreateInviteLink creates a shareable invite link; the creator must be allowed to invite,
+// at least an admin, and can't hand out a role above their own. Links never grant ownership.
+func (s *Service) CreateInviteLink(ctx context.Context, tenantID, userID, role string, req CreateInviteLinkRequest) (*InviteLink, error) {
+	if !RoleAtLeast(role, RoleAdmin) {
+		return nil, ErrInsufficientPermissions
+	}
+	if req.Role != RoleAdmin && req.Role != RoleMember {
+		return nil, fmt.Errorf("%w: role must be admin or member", ErrInvalidInviteLink)
+	}
+	if !RoleAtLeast(role, req.Role) {
+		return nil, ErrInsufficientPermissions
+	}
+	if req.MaxUses < 1 || req.MaxUses > MaxInviteLinkUses {
+		return nil, fmt.Errorf("%w: max_uses must be between 1 and %d", ErrInvalidInviteLink, MaxInviteLinkUses)
+	}
+	if req.ExpiresInDays < 0 {
+		return nil, fmt.Errorf("%w: expires_in_days can't be negative", ErrInvalidInviteLink)
+	}
+
+	policies, err := s.GetPolicies(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !policies.CanInvite(role) {
+		return nil, ErrInsufficientPermissions
+	}
+
+	code, err := newInviteLinkCode()
+	if err != nil {
+		return nil, fmt.Errorf("generate invite link code: %w", err)
+	}
+
+	link := &InviteLink{
+		TenantID:  tenantID,
+		Code:      code,
+		Role:      req.Role,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: time.Now().Add(policies.InviteExpiry(time.Duration(req.ExpiresInDays) * 24 * time.Hour)),
+		CreatedBy: &userID,
+	}
+	if err := s.repo.CreateInviteLink(ctx, link); err != nil {
+		s.logger.Error("failed to create invite link",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("create invite link: %w", err)
+	}
+
+	s.logger.Info("invite link created",
+		slog.String("id", link.ID),
+		slog.String("tenant_id", tenantID),
+		slog.String("role", link.Role),
+		slog.Int("max_uses", link.MaxUses),
+		slog.String("user_id", userID),
+	)
+
+	return link, nil
+}
+
+// ListInviteLinks returns the tenant's invite links with their usage; only admins and owners
+// may see them, since a link's code is enough to join
+func (s *Service) ListInviteLinks(ctx context.Context, tenantID, role string) ([]*InviteLink, error) {
+	if !RoleAtLeast(role, RoleAdmin) {
+		return nil, ErrInsufficientPermissions
+	}
+
+	links, err := s.repo.ListInviteLinks(ctx, tenantID)
+	if err != nil {
+		s.logger.Error("failed to list invite links",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	if links == nil {
+		return []*InviteLink{}, nil
+	}
+	return links, nil
+}
+
+// RevokeInviteLink stops an invite link from admitting anyone else; members who already
+// joined through it stay
+func (s *Service) RevokeInviteLink(ctx context.Context, id, tenantID, role string) error {
+	if !RoleAtLeast(role, RoleAdmin) {
+		return ErrInsufficientPermissions
+	}
+
+	if err := s.repo.RevokeInviteLink(ctx, id, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInviteLinkNotFound
+		}
+		s.logger.Error("failed to revoke invite link",
+			slog.String("id", id),
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.logger.Info("invite link revoked",
+		slog.String("id", id),
+		slog.String("tenant_id", tenantID),
+	)
+	return nil
+}
+
+// RedeemInviteLink adds the user to the link's tenant with the link's role and makes it their
+// active tenant. Existing members keep their role and don't use up the link.
+func (s *Service) RedeemInviteLink(ctx context.Context, code, userID string) (*JoinResult, error) {
+	var result *JoinResult
+
+	err := s.uow.RunInTransaction(ctx, func(txCtx context.Context) error {
+		link, err := s.repo.GetInviteLinkForUpdate(txCtx, code)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInviteLinkNotFound
+			}
+			return fmt.Errorf("get invite link: %w", err)
+		}
+
+		role, err := s.repo.GetMembership(txCtx, userID, link.TenantID)
+		if err != nil {
+			return err
+		}
+		if role != "" {
+			result = &JoinResult{TenantID: link.TenantID, Role: role, AlreadyMember: true}
+			return nil
+		}
+
+		if !link.Redeemable(time.Now()) {
+			return ErrInviteLinkUnavailable
+		}
+
+		if err := s.repo.CreateMembership(txCtx, userID, link.TenantID, link.Role); err != nil {
+			return fmt.Errorf("create tenant membership: %w", err)
+		}
+		if err := s.repo.RecordInviteLinkUse(txCtx, link, userID); err != nil {
+			return err
+		}
+
+		if s.usersRepo != nil {
+			if err := s.usersRepo.UpdateLastActiveTenant(txCtx, userID, link.TenantID); err != nil {
+				return fmt.Errorf("update last active tenant: %w", err)
+			}
+		}
+
+		result = &JoinResult{TenantID: link.TenantID, Role: link.Role}
+		return nil
+	})
+
+	if err != nil {
+		s.logger.Warn("failed to redeem invite link",
+			slog.String("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	if !result.AlreadyMember {
+		s.logger.Info("user joined tenant through invite link",
+			slog.String("tenant_id", result.TenantID),
+			slog.String("user_id", userID),
+			slog.String("role", result.Role),
+		)
+	}
+
+	return result, nil
+}
@@ -1,6 +1,7 @@
 package users
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -26,6 +27,11 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.PUT("/active-tenant", h.SetActiveTenant)
 }
 
+// RegisterTenantRoutes registers tenant-scoped user routes; limiter guards lookups against scraping
+func (h *Handler) RegisterTenantRoutes(r *gin.RouterGroup, limiter gin.HandlerFunc) {
+	r.GET("/users/lookup", limiter, h.LookupUser)
+}
+
 // TenantResponse represents a tenant in API responses
 type TenantResponse struct {
 	ID        string `json:"id"`
@@ -98,3 +104,37 @@ func (h *Handler) SetActiveTenant(c *gin.Context) {
 		"tenant_id": req.TenantID,
 	})
 }
+
+type LookupRequest struct {
+	Email string `form:"email" binding:"required,email,max=255"`
+}
+
+// LookupUser tells the invite UI whether an email belongs to a tenant member,
+// has a pending invitation, or neither. Only roles the tenant's invite policy allows can invite.
+func (h *Handler) LookupUser(c *gin.Context) {
+	tenantID := appContext.MustTenantID(c.Request.Context())
+	role := appContext.UserRole(c.Request.Context())
+
+	if err := h.service.AuthorizeInvite(c.Request.Context(), tenantID, role); err != nil {
+		if errors.Is(err, ErrInsufficientPermissions) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to look up user"})
+		return
+	}
+
+	var req LookupRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a valid email is required"})
+		return
+	}
+
+	result, err := h.service.LookupByEmail(c.Request.Context(), req.Email, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to look up user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
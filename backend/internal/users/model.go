@@ -12,3 +12,17 @@ type User struct {
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
 }
+
+// Lookup statuses returned when searching for a user by email
+const (
+	LookupStatusMember  = "member"  // already a member of the tenant
+	LookupStatusInvited = "invited" // has a pending invitation to the tenant
+	LookupStatusNone    = "none"    // neither; an invitation email should be sent
+)
+
+// LookupResult is the privacy-preserving result of an email lookup.
+// It never reveals whether an account exists outside the caller's tenant.
+type LookupResult struct {
+	Status string `json:"status"`
+	UserID string `json:"user_id,omitempty"`
+}
@@ -12,6 +12,8 @@ import (
 type Repository interface {
 	GetByID(ctx context.Context, id string) (*User, error)
 	UpdateLastActiveTenant(ctx context.Context, userID, tenantID string) error
+	FindMemberByEmail(ctx context.Context, email, tenantID string) (string, error)
+	HasPendingInvitation(ctx context.Context, email, tenantID string) (bool, error)
 }
 
 type postgresRepo struct {
@@ -57,4 +59,41 @@ func (r *postgresRepo) UpdateLastActiveTenant(ctx context.Context, userID, tenan
 	return err
 }
 
+// FindMemberByEmail returns the ID of the tenant member with the given email (case-insensitive)
+// Returns sql.ErrNoRows if no member of the tenant has that email
+func (r *postgresRepo) FindMemberByEmail(ctx context.Context, email, tenantID string) (string, error) {
+	var userID string
+	executor := r.getExecutor(ctx)
+
+	query := `
+		SELECT u.id
+		FROM users u
+		INNER JOIN tenant_members tm ON tm.user_id = u.id
+		WHERE LOWER(u.email) = LOWER($1) AND tm.tenant_id = $2
+	`
+
+	err := sqlx.GetContext(ctx, executor, &userID, query, email, tenantID)
+	if err != nil {
+		return "", err
+	}
+	return userID, nil
+}
+
+// HasPendingInvitation checks if an unaccepted, unexpired invitation exists for the email in the tenant
+func (r *postgresRepo) HasPendingInvitation(ctx context.Context, email, tenantID string) (bool, error) {
+	var exists bool
+	executor := r.getExecutor(ctx)
+
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM tenant_invitations
+			WHERE LOWER(email) = LOWER($1) AND tenant_id = $2
+			  AND accepted_at IS NULL AND expires_at > NOW()
+		)
+	`
+
+	err := sqlx.GetContext(ctx, executor, &exists, query, email, tenantID)
+	return exists, err
+}
+
 var ErrNotFound = sql.ErrNoRows
@@ -2,13 +2,24 @@ package users
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 )
 
+// ErrInsufficientPermissions indicates the tenant's policies don't let the member's role invite
+var ErrInsufficientPermissions = errors.New("insufficient permissions")
+
+// InvitePolicy decides which roles may invite members; implemented by tenants.Service
+type InvitePolicy interface {
+	CanInvite(ctx context.Context, tenantID, role string) (bool, error)
+}
+
 type Service struct {
-	repo   Repository
-	logger *slog.Logger
+	repo         Repository
+	invitePolicy InvitePolicy
+	logger       *slog.Logger
 }
 
 func NewService(repo Repository, logger *slog.Logger) *Service {
@@ -18,6 +29,28 @@ func NewService(repo Repository, logger *slog.Logger) *Service {
 	}
 }
 
+// SetInvitePolicy sets the tenant invite policy (called after service initialization to avoid circular dependency)
+func (s *Service) SetInvitePolicy(policy InvitePolicy) {
+	s.invitePolicy = policy
+}
+
+// AuthorizeInvite checks that a member with the given role may invite others to the tenant.
+// Without a policy only owners and admins may invite.
+func (s *Service) AuthorizeInvite(ctx context.Context, tenantID, role string) error {
+	allowed := role == "owner" || role == "admin"
+	if s.invitePolicy != nil {
+		var err error
+		allowed, err = s.invitePolicy.CanInvite(ctx, tenantID, role)
+		if err != nil {
+			return fmt.Errorf("check invite policy: %w", err)
+		}
+	}
+	if !allowed {
+		return ErrInsufficientPermissions
+	}
+	return nil
+}
+
 func (s *Service) GetUser(ctx context.Context, userID string) (*User, error) {
 	user, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
@@ -48,3 +81,33 @@ func (s *Service) UpdateLastActiveTenant(ctx context.Context, userID, tenantID s
 
 	return nil
 }
+
+// LookupByEmail reports whether an email belongs to a member of the tenant or has a pending invitation
+func (s *Service) LookupByEmail(ctx context.Context, email, tenantID string) (*LookupResult, error) {
+	userID, err := s.repo.FindMemberByEmail(ctx, email, tenantID)
+	if err == nil {
+		return &LookupResult{Status: LookupStatusMember, UserID: userID}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		s.logger.Error("failed to look up member by email",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("lookup member: %w", err)
+	}
+
+	invited, err := s.repo.HasPendingInvitation(ctx, email, tenantID)
+	if err != nil {
+		s.logger.Error("failed to check pending invitation",
+			slog.String("tenant_id", tenantID),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("check invitation: %w", err)
+	}
+
+	if invited {
+		return &LookupResult{Status: LookupStatusInvited}, nil
+	}
+
+	return &LookupResult{Status: LookupStatusNone}, nil
+}
@@ -106,3 +106,53 @@ func TestUpdateLastActiveTenant(t *testing.T) {
 		assert.Equal(t, tenant.ID, *updated.LastActiveTenantID)
 	})
 }
+
+// TestLookupByEmail_OnlyRevealsTenantMembersAndInvites tests that lookups
+// distinguish members and pending invites without leaking other tenants' users
+func TestLookupByEmail_OnlyRevealsTenantMembersAndInvites(t *testing.T) {
+	db := testutil.GetTestDB()
+	userRepo := users.NewRepository(db)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	userService := users.NewService(userRepo, logger)
+
+	testutil.WithTestDB(t, func(ctx context.Context, tx *sqlx.Tx) {
+		tenant := testutil.CreateTenant(t, tx, "Acme", "acme")
+		otherTenant := testutil.CreateTenant(t, tx, "Other", "other")
+
+		admin := testutil.CreateUser(t, tx, "Admin", "admin@acme.com")
+		member := testutil.CreateUser(t, tx, "Member", "member@acme.com")
+		outsider := testutil.CreateUser(t, tx, "Outsider", "outsider@other.com")
+		testutil.CreateTenantMember(t, tx, admin.ID, tenant.ID, "owner")
+		testutil.CreateTenantMember(t, tx, member.ID, tenant.ID, "member")
+		testutil.CreateTenantMember(t, tx, outsider.ID, otherTenant.ID, "owner")
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO tenant_invitations (tenant_id, email, invited_by, token, expires_at)
+			VALUES ($1, 'invitee@example.com', $2, 'invite-token', NOW() + INTERVAL '7 days'),
+			       ($1, 'expired@example.com', $2, 'expired-token', NOW() - INTERVAL '1 day')
+		`, tenant.ID, admin.ID)
+		require.NoError(t, err)
+
+		ctx = transaction.InjectTx(ctx, tx)
+
+		result, err := userService.LookupByEmail(ctx, "MEMBER@acme.com", tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, users.LookupStatusMember, result.Status)
+		assert.Equal(t, member.ID, result.UserID)
+
+		result, err = userService.LookupByEmail(ctx, "invitee@example.com", tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, users.LookupStatusInvited, result.Status)
+		assert.Empty(t, result.UserID)
+
+		result, err = userService.LookupByEmail(ctx, "expired@example.com", tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, users.LookupStatusNone, result.Status)
+
+		// Existing account in another tenant looks the same as an unknown email
+		result, err = userService.LookupByEmail(ctx, "outsider@other.com", tenant.ID)
+		require.NoError(t, err)
+		assert.Equal(t, users.LookupStatusNone, result.Status)
+		assert.Empty(t, result.UserID)
+	})
+}
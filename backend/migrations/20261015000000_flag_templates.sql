@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag templates - Reusable default configuration for new flags (scoped to tenant)
+CREATE TABLE flag_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    rules JSONB NOT NULL DEFAULT '[]',
+    rule_logic VARCHAR(10) NOT NULL DEFAULT 'AND',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(tenant_id, name),
+    CONSTRAINT flag_templates_rule_logic_check CHECK (rule_logic IN ('AND', 'OR'))
+);
+
+CREATE INDEX idx_flag_templates_tenant ON flag_templates(tenant_id);
+
+CREATE TRIGGER update_flag_templates_updated_at BEFORE UPDATE ON flag_templates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE flag_templates IS 'Reusable default rules and rule logic for new flags';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS update_flag_templates_updated_at ON flag_templates;
+DROP TABLE IF EXISTS flag_templates CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Generation counter - Incremented whenever any flag in the project changes.
+-- SDK relays use it to detect whether their cached snapshot is current.
+ALTER TABLE projects ADD COLUMN generation BIGINT NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION bump_project_generation()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.project_id IS NOT NULL THEN
+        UPDATE projects SET generation = generation + 1 WHERE id = OLD.project_id;
+    END IF;
+
+    -- A flag moved between projects changes both snapshots
+    IF TG_OP = 'INSERT' AND NEW.project_id IS NOT NULL
+       OR TG_OP = 'UPDATE' AND NEW.project_id IS NOT NULL AND NEW.project_id IS DISTINCT FROM OLD.project_id THEN
+        UPDATE projects SET generation = generation + 1 WHERE id = NEW.project_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER bump_project_generation_on_flags AFTER INSERT OR UPDATE OR DELETE ON flags
+    FOR EACH ROW EXECUTE FUNCTION bump_project_generation();
+
+COMMENT ON COLUMN projects.generation IS 'Monotonic counter bumped on every flag change in the project';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS bump_project_generation_on_flags ON flags;
+DROP FUNCTION IF EXISTS bump_project_generation();
+ALTER TABLE projects DROP COLUMN IF EXISTS generation;
+
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag usage - When each flag was last evaluated by an SDK.
+-- Kept out of the flags table so frequent usage writes don't touch updated_at
+-- or bump the project generation.
+CREATE TABLE flag_usage (
+    flag_id UUID PRIMARY KEY REFERENCES flags(id) ON DELETE CASCADE,
+    last_evaluated_at TIMESTAMPTZ NOT NULL
+);
+
+COMMENT ON TABLE flag_usage IS 'Last SDK evaluation time per flag, used for stale flag detection';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS flag_usage CASCADE;
+
+-- +goose StatementEnd
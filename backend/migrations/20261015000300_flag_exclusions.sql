@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag exclusions - User keys that must never see a flag, even when bucketed in.
+-- Applied after rollout; kept apart from targeting rules so support can edit them
+-- one key at a time without touching the flag definition.
+CREATE TABLE flag_exclusions (
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_key VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flag_id, user_key)
+);
+
+-- Evaluation looks up all exclusions for one user within a tenant
+CREATE INDEX idx_flag_exclusions_tenant_user ON flag_exclusions(tenant_id, user_key);
+
+-- Exclusions change evaluation results, so they invalidate the project snapshot
+CREATE OR REPLACE FUNCTION bump_project_generation_for_exclusion()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE projects SET generation = generation + 1
+    WHERE id = (SELECT project_id FROM flags WHERE id = COALESCE(NEW.flag_id, OLD.flag_id));
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER bump_project_generation_on_exclusions AFTER INSERT OR UPDATE OR DELETE ON flag_exclusions
+    FOR EACH ROW EXECUTE FUNCTION bump_project_generation_for_exclusion();
+
+COMMENT ON TABLE flag_exclusions IS 'Per-flag opt-out user keys, applied after rollout bucketing';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS bump_project_generation_on_exclusions ON flag_exclusions;
+DROP FUNCTION IF EXISTS bump_project_generation_for_exclusion();
+DROP TABLE IF EXISTS flag_exclusions CASCADE;
+
+-- +goose StatementEnd
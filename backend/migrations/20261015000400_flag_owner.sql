@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag owner - The tenant member responsible for a flag
+ALTER TABLE flags ADD COLUMN owner_user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_flags_tenant_owner ON flags(tenant_id, owner_user_id);
+
+COMMENT ON COLUMN flags.owner_user_id IS 'Tenant member responsible for the flag (nullable)';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_flags_tenant_owner;
+ALTER TABLE flags DROP COLUMN IF EXISTS owner_user_id;
+
+-- +goose StatementEnd
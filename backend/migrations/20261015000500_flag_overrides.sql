@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag overrides - Temporarily force a flag on or off for a single user key.
+-- Consulted before rules; expired rows are ignored and removed by a background job.
+CREATE TABLE flag_overrides (
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_key VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flag_id, user_key)
+);
+
+-- Evaluation looks up all overrides for one user within a tenant
+CREATE INDEX idx_flag_overrides_tenant_user ON flag_overrides(tenant_id, user_key);
+
+-- Expiry job scans by expiration time
+CREATE INDEX idx_flag_overrides_expires_at ON flag_overrides(expires_at);
+
+-- Overrides change evaluation results, so they invalidate the project snapshot
+CREATE OR REPLACE FUNCTION bump_project_generation_for_override()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE projects SET generation = generation + 1
+    WHERE id = (SELECT project_id FROM flags WHERE id = COALESCE(NEW.flag_id, OLD.flag_id));
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER bump_project_generation_on_overrides AFTER INSERT OR UPDATE OR DELETE ON flag_overrides
+    FOR EACH ROW EXECUTE FUNCTION bump_project_generation_for_override();
+
+COMMENT ON TABLE flag_overrides IS 'Time-boxed per-user flag overrides, consulted before rules';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS bump_project_generation_on_overrides ON flag_overrides;
+DROP FUNCTION IF EXISTS bump_project_generation_for_override();
+DROP TABLE IF EXISTS flag_overrides CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag change requests - Proposed flag edits that an owner or admin must approve
+-- before they are applied
+CREATE TABLE flag_change_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    proposed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT flag_change_requests_status_check CHECK (status IN ('pending', 'approved', 'rejected'))
+);
+
+CREATE INDEX idx_flag_change_requests_flag ON flag_change_requests(flag_id, created_at DESC);
+CREATE INDEX idx_flag_change_requests_tenant_status ON flag_change_requests(tenant_id, status);
+
+CREATE TRIGGER update_flag_change_requests_updated_at BEFORE UPDATE ON flag_change_requests
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE flag_change_requests IS 'Proposed flag changes awaiting owner/admin approval';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS update_flag_change_requests_updated_at ON flag_change_requests;
+DROP TABLE IF EXISTS flag_change_requests CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Context presets - Saved evaluation contexts for QA, scoped to a project
+CREATE TABLE context_presets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    user_id VARCHAR(255) NOT NULL,
+    attributes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(project_id, name)
+);
+
+CREATE INDEX idx_context_presets_tenant_project ON context_presets(tenant_id, project_id);
+
+CREATE TRIGGER update_context_presets_updated_at BEFORE UPDATE ON context_presets
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE context_presets IS 'Named evaluation contexts reused by QA tooling';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS update_context_presets_updated_at ON context_presets;
+DROP TABLE IF EXISTS context_presets CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag comments - Rollout decisions and incident notes attached to a flag
+CREATE TABLE flag_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_flag_comments_flag ON flag_comments(flag_id, created_at);
+
+COMMENT ON TABLE flag_comments IS 'Discussion and notes attached to flags';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS flag_comments CASCADE;
+
+-- +goose StatementEnd
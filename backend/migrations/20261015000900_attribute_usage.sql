@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Attribute usage - When each context attribute was last sent to a project by an SDK.
+-- Compared against rule attributes to find rules targeting attributes clients never send.
+CREATE TABLE attribute_usage (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    attribute TEXT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (project_id, attribute)
+);
+
+COMMENT ON TABLE attribute_usage IS 'Last time each evaluation context attribute was seen per project';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS attribute_usage CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag expiration - Flags are disabled automatically once expires_at passes
+ALTER TABLE flags ADD COLUMN expires_at TIMESTAMPTZ;
+
+CREATE INDEX idx_flags_expires_at ON flags(expires_at) WHERE expires_at IS NOT NULL;
+
+COMMENT ON COLUMN flags.expires_at IS 'When the flag is automatically disabled (nullable)';
+
+-- Flag history - Audit trail of actions taken on flags
+CREATE TABLE flag_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    details JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_flag_history_flag ON flag_history(flag_id, created_at DESC);
+
+COMMENT ON TABLE flag_history IS 'Audit trail of flag actions; actor_id is NULL for system actions';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS flag_history CASCADE;
+DROP INDEX IF EXISTS idx_flags_expires_at;
+ALTER TABLE flags DROP COLUMN IF EXISTS expires_at;
+
+-- +goose StatementEnd
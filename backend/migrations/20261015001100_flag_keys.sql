@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag keys - Stable identifiers derived from the flag name at creation.
+-- Rolled out with the dual-write pattern (see internal/pkg/dualwrite): the column starts
+-- nullable, writers populate it once FLAG_KEY_MIGRATION_PHASE is dual_write or later,
+-- and a verifier job backfills existing rows before cutover.
+ALTER TABLE flags ADD COLUMN key TEXT;
+
+CREATE INDEX idx_flags_missing_key ON flags(created_at) WHERE key IS NULL;
+
+COMMENT ON COLUMN flags.key IS 'Stable URL-safe identifier; NULL until written or backfilled';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_flags_missing_key;
+ALTER TABLE flags DROP COLUMN IF EXISTS key;
+
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Per-rule rollout - The evaluator used to apply only the first rule's rollout to the whole flag;
+-- it now honours each rule's own rollout. Copy the first rule's rollout onto every rule so
+-- existing flags and templates keep evaluating exactly as before. Owners can then set
+-- different rollouts per rule deliberately.
+UPDATE flags
+SET rules = (
+        SELECT jsonb_agg(rule || jsonb_build_object('rollout', rules->0->'rollout') ORDER BY ord)
+        FROM jsonb_array_elements(rules) WITH ORDINALITY AS r(rule, ord)
+    )
+WHERE jsonb_array_length(rules) > 1
+  AND EXISTS (
+        SELECT 1 FROM jsonb_array_elements(rules) AS r(rule)
+        WHERE rule->'rollout' IS DISTINCT FROM rules->0->'rollout'
+    );
+
+UPDATE flag_templates
+SET rules = (
+        SELECT jsonb_agg(rule || jsonb_build_object('rollout', rules->0->'rollout') ORDER BY ord)
+        FROM jsonb_array_elements(rules) WITH ORDINALITY AS r(rule, ord)
+    )
+WHERE jsonb_array_length(rules) > 1
+  AND EXISTS (
+        SELECT 1 FROM jsonb_array_elements(rules) AS r(rule)
+        WHERE rule->'rollout' IS DISTINCT FROM rules->0->'rollout'
+    );
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Rollouts were normalised in place; the previous per-rule values are not recoverable
+SELECT 1;
+
+-- +goose StatementEnd
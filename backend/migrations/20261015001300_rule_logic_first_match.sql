@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- FIRST_MATCH rule logic - Rules carry an "order" and the first matching rule decides.
+-- FIRST_MATCH doesn't fit VARCHAR(10), so widen the column alongside the checks.
+ALTER TABLE flags ALTER COLUMN rule_logic TYPE VARCHAR(20);
+ALTER TABLE flags DROP CONSTRAINT rule_logic_check;
+ALTER TABLE flags ADD CONSTRAINT rule_logic_check CHECK (rule_logic IN ('AND', 'OR', 'FIRST_MATCH'));
+
+ALTER TABLE flag_templates ALTER COLUMN rule_logic TYPE VARCHAR(20);
+ALTER TABLE flag_templates DROP CONSTRAINT flag_templates_rule_logic_check;
+ALTER TABLE flag_templates ADD CONSTRAINT flag_templates_rule_logic_check CHECK (rule_logic IN ('AND', 'OR', 'FIRST_MATCH'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- FIRST_MATCH flags fall back to OR, the closest remaining semantics
+UPDATE flags SET rule_logic = 'OR' WHERE rule_logic = 'FIRST_MATCH';
+UPDATE flag_templates SET rule_logic = 'OR' WHERE rule_logic = 'FIRST_MATCH';
+
+ALTER TABLE flags DROP CONSTRAINT rule_logic_check;
+ALTER TABLE flags ADD CONSTRAINT rule_logic_check CHECK (rule_logic IN ('AND', 'OR'));
+ALTER TABLE flags ALTER COLUMN rule_logic TYPE VARCHAR(10);
+
+ALTER TABLE flag_templates DROP CONSTRAINT flag_templates_rule_logic_check;
+ALTER TABLE flag_templates ADD CONSTRAINT flag_templates_rule_logic_check CHECK (rule_logic IN ('AND', 'OR'));
+ALTER TABLE flag_templates ALTER COLUMN rule_logic TYPE VARCHAR(10);
+
+-- +goose StatementEnd
@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Change sets - Groups of flag edits in one project applied in a single transaction.
+-- Each row is the audit entry for all of its edits.
+CREATE TABLE change_sets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    description TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    changes JSONB NOT NULL,
+    generation BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_change_sets_project ON change_sets(project_id, created_at DESC);
+
+COMMENT ON TABLE change_sets IS 'Atomic multi-flag edits; one audit entry per set';
+COMMENT ON COLUMN change_sets.generation IS 'Project generation after the change set was applied';
+
+-- Change sets bump the project generation once after all of their flag updates.
+-- They set toggle.defer_generation_bump for their transaction to skip the per-row bump.
+CREATE OR REPLACE FUNCTION bump_project_generation()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('toggle.defer_generation_bump', true) = 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.project_id IS NOT NULL THEN
+        UPDATE projects SET generation = generation + 1 WHERE id = OLD.project_id;
+    END IF;
+
+    -- A flag moved between projects changes both snapshots
+    IF TG_OP = 'INSERT' AND NEW.project_id IS NOT NULL
+       OR TG_OP = 'UPDATE' AND NEW.project_id IS NOT NULL AND NEW.project_id IS DISTINCT FROM OLD.project_id THEN
+        UPDATE projects SET generation = generation + 1 WHERE id = NEW.project_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+CREATE OR REPLACE FUNCTION bump_project_generation()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.project_id IS NOT NULL THEN
+        UPDATE projects SET generation = generation + 1 WHERE id = OLD.project_id;
+    END IF;
+
+    -- A flag moved between projects changes both snapshots
+    IF TG_OP = 'INSERT' AND NEW.project_id IS NOT NULL
+       OR TG_OP = 'UPDATE' AND NEW.project_id IS NOT NULL AND NEW.project_id IS DISTINCT FROM OLD.project_id THEN
+        UPDATE projects SET generation = generation + 1 WHERE id = NEW.project_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+DROP TABLE IF EXISTS change_sets;
+
+-- +goose StatementEnd
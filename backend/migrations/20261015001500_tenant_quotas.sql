@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tenant plans - Select the management API quota applied to each tenant.
+-- Plans are managed outside the API (billing); new tenants start on the free plan.
+ALTER TABLE tenants ADD COLUMN plan VARCHAR(20) NOT NULL DEFAULT 'free';
+ALTER TABLE tenants ADD CONSTRAINT tenants_plan_check CHECK (plan IN ('free', 'team', 'enterprise'));
+
+-- Request counters - Management API requests per tenant per quota window.
+-- Ended windows are pruned by a background job.
+CREATE TABLE tenant_request_counts (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    window_start TIMESTAMPTZ NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, window_start)
+);
+
+CREATE INDEX idx_tenant_request_counts_window ON tenant_request_counts(window_start);
+
+COMMENT ON COLUMN tenants.plan IS 'Billing plan: free, team or enterprise';
+COMMENT ON TABLE tenant_request_counts IS 'Management API request counts per tenant per quota window';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tenant_request_counts;
+ALTER TABLE tenants DROP CONSTRAINT IF EXISTS tenants_plan_check;
+ALTER TABLE tenants DROP COLUMN IF EXISTS plan;
+
+-- +goose StatementEnd
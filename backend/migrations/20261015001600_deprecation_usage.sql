@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Deprecation usage - How often each tenant uses deprecated endpoints and fields,
+-- so we know who to contact before removing them. Requests without a tenant are
+-- counted under a NULL tenant_id.
+CREATE TABLE deprecation_usage (
+    key TEXT NOT NULL,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    count BIGINT NOT NULL DEFAULT 0,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_deprecation_usage_key_tenant
+    ON deprecation_usage(key, COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid));
+CREATE INDEX idx_deprecation_usage_tenant ON deprecation_usage(tenant_id);
+
+COMMENT ON TABLE deprecation_usage IS 'Per-tenant usage counts of deprecated endpoints and fields';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS deprecation_usage;
+
+-- +goose StatementEnd
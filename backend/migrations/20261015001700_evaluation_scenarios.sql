@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Evaluation scenarios - A flag snapshot and evaluation context saved under a short ID,
+-- so a targeting problem can be shared in a ticket and replayed by anyone in the tenant.
+-- The snapshot outlives the flag so old scenarios still replay.
+CREATE TABLE evaluation_scenarios (
+    id TEXT PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    flag_id UUID NOT NULL,
+    flag_snapshot JSONB NOT NULL,
+    context JSONB NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_evaluation_scenarios_tenant_flag ON evaluation_scenarios(tenant_id, flag_id);
+
+COMMENT ON TABLE evaluation_scenarios IS 'Shareable, replayable flag evaluations';
+COMMENT ON COLUMN evaluation_scenarios.enabled IS 'Result when the scenario was saved';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS evaluation_scenarios;
+
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Project snapshots - The latest evaluable snapshot of each project, written by the
+-- evaluation tier. New instances prime their in-process cache from this table at boot
+-- instead of rebuilding every project from flags after a deploy.
+CREATE TABLE project_snapshots (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    generation BIGINT NOT NULL,
+    snapshot JSONB NOT NULL,
+    generated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_project_snapshots_generated_at ON project_snapshots(generated_at);
+
+COMMENT ON TABLE project_snapshots IS 'Latest evaluation snapshot per project, used to prime caches at boot';
+COMMENT ON COLUMN project_snapshots.generation IS 'Project generation the snapshot was built at';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS project_snapshots;
+
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag names are unique within a project. Existing duplicates are renamed first:
+-- the oldest flag keeps its name, later ones get a short ID suffix.
+UPDATE flags f
+SET name = LEFT(f.name, 240) || ' (' || LEFT(f.id::text, 8) || ')'
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY project_id, name ORDER BY created_at, id) AS position
+    FROM flags
+    WHERE project_id IS NOT NULL
+) duplicates
+WHERE f.id = duplicates.id AND duplicates.position > 1;
+
+CREATE UNIQUE INDEX idx_flags_project_name ON flags(project_id, name);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_flags_project_name;
+
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tenant data keys - Per-tenant data encryption keys, wrapped by the server's master key.
+-- The highest version is active; older versions stay to open data sealed before a rotation.
+CREATE TABLE tenant_data_keys (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL CHECK (version > 0),
+    wrapped_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, version)
+);
+
+-- Key rotations - One row per tenant tracking re-encryption to the active key version
+CREATE TABLE tenant_key_rotations (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    key_version INTEGER NOT NULL,
+    state VARCHAR(20) NOT NULL CHECK (state IN ('rotating', 'complete')),
+    reencrypted INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_tenant_key_rotations_rotating ON tenant_key_rotations(state) WHERE state = 'rotating';
+
+-- Sealed preset contexts - When the tenant has a data key, user_id and attributes are
+-- stored here encrypted and the plain columns are left empty
+ALTER TABLE context_presets ADD COLUMN sealed TEXT;
+
+COMMENT ON TABLE tenant_data_keys IS 'Per-tenant data encryption keys wrapped by the master key';
+COMMENT ON TABLE tenant_key_rotations IS 'Per-tenant key rotation and background re-encryption status';
+COMMENT ON COLUMN context_presets.sealed IS 'user_id and attributes sealed with the tenant data key, when it has one';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE context_presets DROP COLUMN IF EXISTS sealed;
+DROP TABLE IF EXISTS tenant_key_rotations;
+DROP TABLE IF EXISTS tenant_data_keys;
+
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag custom fields - Free-form metadata on a flag, e.g. the service it gates.
+-- Custom fields don't affect evaluation.
+CREATE TABLE flag_custom_fields (
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flag_id, name)
+);
+
+CREATE INDEX idx_flag_custom_fields_tenant ON flag_custom_fields(tenant_id, name);
+
+-- Catalog push targets - Where a tenant's Backstage catalog feed is pushed
+CREATE TABLE catalog_push_targets (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    last_pushed_at TIMESTAMPTZ,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE flag_custom_fields IS 'Free-form flag metadata; the service field maps flags into service catalogs';
+COMMENT ON TABLE catalog_push_targets IS 'Webhook receiving the tenant flag catalog in Backstage format';
+COMMENT ON COLUMN catalog_push_targets.secret IS 'HMAC-SHA256 key for the X-Toggle-Signature header; empty means unsigned';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS catalog_push_targets;
+DROP TABLE IF EXISTS flag_custom_fields;
+
+-- +goose StatementEnd
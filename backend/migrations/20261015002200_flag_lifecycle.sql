@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag lifecycle - draft -> active -> deprecated -> archived.
+-- Only active flags are served to SDKs; existing flags are active.
+ALTER TABLE flags ADD COLUMN lifecycle VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE flags ADD CONSTRAINT flags_lifecycle_check CHECK (lifecycle IN ('draft', 'active', 'deprecated', 'archived'));
+
+CREATE INDEX idx_flags_tenant_lifecycle ON flags(tenant_id, lifecycle);
+
+COMMENT ON COLUMN flags.lifecycle IS 'Lifecycle state: draft, active, deprecated or archived; only active flags are evaluated';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_flags_tenant_lifecycle;
+ALTER TABLE flags DROP CONSTRAINT IF EXISTS flags_lifecycle_check;
+ALTER TABLE flags DROP COLUMN IF EXISTS lifecycle;
+
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tenant policies - Membership settings chosen by each tenant's owners.
+-- Tenants without a row use the defaults in internal/tenants (DefaultPolicies).
+CREATE TABLE tenant_policies (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    invite_role VARCHAR(20) NOT NULL DEFAULT 'admin',
+    auto_join_role VARCHAR(20) NOT NULL DEFAULT 'member',
+    members_can_create_projects BOOLEAN NOT NULL DEFAULT TRUE,
+    max_invite_days INTEGER NOT NULL DEFAULT 7,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT tenant_policies_invite_role_check CHECK (invite_role IN ('owner', 'admin', 'member')),
+    CONSTRAINT tenant_policies_auto_join_role_check CHECK (auto_join_role IN ('admin', 'member')),
+    CONSTRAINT tenant_policies_max_invite_days_check CHECK (max_invite_days BETWEEN 1 AND 90)
+);
+
+COMMENT ON TABLE tenant_policies IS 'Per-tenant membership policies: who may invite, auto-join role, project creation, invite lifetime';
+COMMENT ON COLUMN tenant_policies.invite_role IS 'Lowest role allowed to invite members';
+COMMENT ON COLUMN tenant_policies.auto_join_role IS 'Role given to users joining through a verified email domain';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tenant_policies;
+
+-- +goose StatementEnd
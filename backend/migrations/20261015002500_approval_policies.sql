@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Project approval policies - How change requests to a project's flags are approved.
+-- Projects without a row require one approval from an owner or admin other than the author.
+CREATE TABLE project_approval_policies (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    require_approval BOOLEAN NOT NULL DEFAULT TRUE,
+    required_approvals INTEGER NOT NULL DEFAULT 1,
+    allow_self_approval BOOLEAN NOT NULL DEFAULT FALSE,
+    reviewer_roles TEXT[] NOT NULL DEFAULT '{owner,admin}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT project_approval_policies_required_check CHECK (required_approvals BETWEEN 1 AND 10),
+    CONSTRAINT project_approval_policies_roles_check CHECK (
+        cardinality(reviewer_roles) > 0 AND reviewer_roles <@ ARRAY['owner', 'admin', 'member']::TEXT[]
+    )
+);
+
+CREATE INDEX idx_project_approval_policies_tenant ON project_approval_policies(tenant_id);
+
+-- Change approvals - One row per reviewer approving a change request.
+-- The change is applied once the project's required number of approvals is reached.
+CREATE TABLE flag_change_approvals (
+    change_request_id UUID NOT NULL REFERENCES flag_change_requests(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (change_request_id, user_id)
+);
+
+COMMENT ON TABLE project_approval_policies IS 'Per-project change request approval settings';
+COMMENT ON TABLE flag_change_approvals IS 'Reviewer approvals of pending flag change requests';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS flag_change_approvals;
+DROP TABLE IF EXISTS project_approval_policies;
+
+-- +goose StatementEnd
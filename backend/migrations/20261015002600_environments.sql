@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Environments - Deployment stages within a project (development, staging, production).
+-- Each environment has its own SDK key, so an SDK only ever sees one environment's config.
+CREATE TABLE environments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key VARCHAR(32) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    client_api_key VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX idx_environments_tenant_project ON environments(tenant_id, project_id);
+
+CREATE TRIGGER update_environments_updated_at BEFORE UPDATE ON environments
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Flag environments - A flag's enabled state and rules in one environment.
+-- A flag with no row for an environment is disabled there.
+CREATE TABLE flag_environments (
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    rules JSONB NOT NULL DEFAULT '[]',
+    rule_logic VARCHAR(16) NOT NULL DEFAULT 'AND',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (flag_id, environment_id)
+);
+
+CREATE INDEX idx_flag_environments_environment ON flag_environments(environment_id);
+
+CREATE TRIGGER update_flag_environments_updated_at BEFORE UPDATE ON flag_environments
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Environment configs change evaluation results, so they invalidate the project snapshot
+CREATE OR REPLACE FUNCTION bump_project_generation_for_flag_environment()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE projects SET generation = generation + 1
+    WHERE id = (SELECT project_id FROM flags WHERE id = COALESCE(NEW.flag_id, OLD.flag_id));
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER bump_project_generation_on_flag_environments AFTER INSERT OR UPDATE OR DELETE ON flag_environments
+    FOR EACH ROW EXECUTE FUNCTION bump_project_generation_for_flag_environment();
+
+-- Existing projects get the default environments, each starting from the flags' current config
+INSERT INTO environments (tenant_id, project_id, key, name, client_api_key)
+SELECT p.tenant_id, p.id, d.key, d.name,
+       replace(gen_random_uuid()::text, '-', '') || replace(gen_random_uuid()::text, '-', '')
+FROM projects p
+CROSS JOIN (VALUES ('development', 'Development'), ('staging', 'Staging'), ('production', 'Production')) AS d(key, name);
+
+INSERT INTO flag_environments (flag_id, environment_id, tenant_id, enabled, rules, rule_logic)
+SELECT f.id, e.id, f.tenant_id, f.enabled, f.rules, f.rule_logic
+FROM flags f
+INNER JOIN environments e ON e.project_id = f.project_id AND e.tenant_id = f.tenant_id;
+
+COMMENT ON TABLE environments IS 'Deployment stages of a project, each with its own SDK key';
+COMMENT ON TABLE flag_environments IS 'Per-environment enabled state and rules of a flag; missing rows mean disabled';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS bump_project_generation_on_flag_environments ON flag_environments;
+DROP FUNCTION IF EXISTS bump_project_generation_for_flag_environment();
+DROP TABLE IF EXISTS flag_environments CASCADE;
+DROP TABLE IF EXISTS environments CASCADE;
+
+-- +goose StatementEnd
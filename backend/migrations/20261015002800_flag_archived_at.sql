@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Archive time - When a flag was archived. Archived flags keep being served to SDKs
+-- for a grace period after this, so laggard deployments don't break instantly.
+ALTER TABLE flags ADD COLUMN archived_at TIMESTAMPTZ;
+
+-- Flags archived before this migration count from their last change
+UPDATE flags SET archived_at = updated_at WHERE lifecycle = 'archived';
+
+CREATE OR REPLACE FUNCTION set_flag_archived_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.lifecycle = 'archived' AND (TG_OP = 'INSERT' OR OLD.lifecycle <> 'archived') THEN
+        NEW.archived_at = NOW();
+    ELSIF NEW.lifecycle <> 'archived' THEN
+        NEW.archived_at = NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER set_flags_archived_at BEFORE INSERT OR UPDATE OF lifecycle ON flags
+    FOR EACH ROW EXECUTE FUNCTION set_flag_archived_at();
+
+COMMENT ON COLUMN flags.archived_at IS 'When the flag was archived (nullable); set by trigger';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS set_flags_archived_at ON flags;
+DROP FUNCTION IF EXISTS set_flag_archived_at();
+ALTER TABLE flags DROP COLUMN IF EXISTS archived_at;
+
+-- +goose StatementEnd
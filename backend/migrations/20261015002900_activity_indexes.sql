@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The tenant activity feed reads the newest flag history, change requests and flag/project
+-- changes across a tenant, so each source is indexed by tenant and time
+CREATE INDEX idx_flag_history_tenant_created ON flag_history(tenant_id, created_at DESC);
+CREATE INDEX idx_flag_change_requests_tenant_created ON flag_change_requests(tenant_id, created_at DESC);
+CREATE INDEX idx_flags_tenant_updated ON flags(tenant_id, updated_at DESC);
+CREATE INDEX idx_projects_tenant_updated ON projects(tenant_id, updated_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_projects_tenant_updated;
+DROP INDEX IF EXISTS idx_flags_tenant_updated;
+DROP INDEX IF EXISTS idx_flag_change_requests_tenant_created;
+DROP INDEX IF EXISTS idx_flag_history_tenant_created;
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- SDK usage - Which SDK names and versions call each project's SDK routes, from the
+-- X-Toggle-SDK or User-Agent header, so outdated SDKs can be found before breaking changes
+CREATE TABLE sdk_usage (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    sdk_name VARCHAR(64) NOT NULL,
+    sdk_version VARCHAR(64) NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (project_id, sdk_name, sdk_version)
+);
+
+CREATE INDEX idx_sdk_usage_tenant ON sdk_usage(tenant_id);
+
+COMMENT ON TABLE sdk_usage IS 'Request counts per SDK name and version per project';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS sdk_usage CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag evaluation counts - SDK evaluations and true results per flag per hour, for the
+-- stats in the flag list. Like flag_usage, kept out of the flags table so frequent
+-- writes don't touch updated_at or bump the project generation.
+CREATE TABLE flag_evaluation_counts (
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    hour TIMESTAMPTZ NOT NULL,
+    evaluations BIGINT NOT NULL DEFAULT 0,
+    true_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (flag_id, hour)
+);
+
+CREATE INDEX idx_flag_evaluation_counts_tenant_hour ON flag_evaluation_counts(tenant_id, hour);
+
+COMMENT ON TABLE flag_evaluation_counts IS 'Hourly SDK evaluation and true result counts per flag';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS flag_evaluation_counts CASCADE;
+
+-- +goose StatementEnd
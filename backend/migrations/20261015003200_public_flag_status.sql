@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Public flag status - Projects that opted in to serving selected flags' names, descriptions
+-- and status without credentials, for embedding in internal docs portals
+CREATE TABLE public_status_pages (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_public_status_pages_tenant ON public_status_pages(tenant_id);
+
+-- Public flags - The flags a project's public status lists; never their rules
+CREATE TABLE public_status_flags (
+    project_id UUID NOT NULL REFERENCES public_status_pages(project_id) ON DELETE CASCADE,
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    PRIMARY KEY (project_id, flag_id)
+);
+
+CREATE INDEX idx_public_status_flags_tenant ON public_status_flags(tenant_id);
+
+COMMENT ON TABLE public_status_pages IS 'Projects serving a token-less read-only flag status list';
+COMMENT ON TABLE public_status_flags IS 'Flags listed on a project public status';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS public_status_flags CASCADE;
+DROP TABLE IF EXISTS public_status_pages CASCADE;
+
+-- +goose StatementEnd
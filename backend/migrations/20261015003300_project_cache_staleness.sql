@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Cache staleness tolerance - How long /sdk/evaluate may serve a project's cached flags
+-- without checking its generation in Postgres. Zero checks on every request.
+ALTER TABLE projects ADD COLUMN cache_max_staleness_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD CONSTRAINT projects_cache_max_staleness_check
+    CHECK (cache_max_staleness_seconds BETWEEN 0 AND 300);
+
+COMMENT ON COLUMN projects.cache_max_staleness_seconds IS 'Seconds bulk evaluation may serve cached flags without revalidating; 0 always revalidates';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_cache_max_staleness_check;
+ALTER TABLE projects DROP COLUMN IF EXISTS cache_max_staleness_seconds;
+
+-- +goose StatementEnd
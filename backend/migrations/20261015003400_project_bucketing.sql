@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Bucketing algorithm - How the project's users are assigned rollout buckets. murmur3
+-- buckets users like Unleash SDKs, so rollouts keep the same users when migrating flags in.
+ALTER TABLE projects ADD COLUMN bucketing TEXT NOT NULL DEFAULT 'sha256';
+ALTER TABLE projects ADD CONSTRAINT projects_bucketing_check
+    CHECK (bucketing IN ('sha256', 'murmur3'));
+
+COMMENT ON COLUMN projects.bucketing IS 'Rollout bucketing algorithm: sha256 (default) or murmur3';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE projects DROP CONSTRAINT IF EXISTS projects_bucketing_check;
+ALTER TABLE projects DROP COLUMN IF EXISTS bucketing;
+
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Push target templates - A tenant-supplied Go text template rendering the pushed payload
+-- in place of the Backstage feed, and the content type it is sent with
+ALTER TABLE catalog_push_targets ADD COLUMN template TEXT NOT NULL DEFAULT '';
+ALTER TABLE catalog_push_targets ADD COLUMN content_type TEXT NOT NULL DEFAULT 'application/yaml';
+
+COMMENT ON COLUMN catalog_push_targets.template IS 'Sandboxed payload template (see pkg/payloadtmpl); empty pushes the Backstage feed';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE catalog_push_targets DROP COLUMN IF EXISTS content_type;
+ALTER TABLE catalog_push_targets DROP COLUMN IF EXISTS template;
+
+-- +goose StatementEnd
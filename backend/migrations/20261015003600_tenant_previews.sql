@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tenant previews - Each tenant's latest opt-in or opt-out of a preview of a Toggle
+-- feature (declared in pkg/featuregate), with who changed it and when
+CREATE TABLE tenant_previews (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    preview_key VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, preview_key)
+);
+
+COMMENT ON TABLE tenant_previews IS 'Tenant enrollment in previews of Toggle features';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tenant_previews;
+
+-- +goose StatementEnd
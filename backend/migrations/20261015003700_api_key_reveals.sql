@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- API key reveals - Audit log of full SDK keys shown after creation. key_id is the
+-- project ID for the project's own key, or the environment ID for an environment key.
+CREATE TABLE api_key_reveals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key_id UUID NOT NULL,
+    revealed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    revealed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_key_reveals_tenant ON api_key_reveals(tenant_id, revealed_at DESC);
+
+COMMENT ON TABLE api_key_reveals IS 'Audit log of SDK API keys revealed after creation';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS api_key_reveals;
+
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag evaluation reasons - SDK evaluations per flag per hour by the reason for their
+-- result, so flag stats show whether targeting rules match anyone. Pruned with
+-- flag_evaluation_counts.
+CREATE TABLE flag_evaluation_reasons (
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    hour TIMESTAMPTZ NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    evaluations BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (flag_id, hour, reason)
+);
+
+CREATE INDEX idx_flag_evaluation_reasons_tenant_hour ON flag_evaluation_reasons(tenant_id, hour);
+
+COMMENT ON TABLE flag_evaluation_reasons IS 'Hourly SDK evaluation counts per flag by evaluation reason';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS flag_evaluation_reasons CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Evaluation events - One row per SDK flag evaluation (flag, user, result, time), written
+-- in batches from an in-memory buffer for analytics. Kept for a week.
+CREATE TABLE evaluation_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    flag_id UUID NOT NULL REFERENCES flags(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    evaluated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_evaluation_events_flag ON evaluation_events(flag_id, evaluated_at DESC);
+CREATE INDEX idx_evaluation_events_evaluated_at ON evaluation_events(evaluated_at);
+
+COMMENT ON TABLE evaluation_events IS 'SDK flag evaluations, for analytics';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS evaluation_events CASCADE;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Flag salt - mixed into rollout bucketing so a flag's rollout population can be
+-- re-randomized without changing user IDs. Existing flags keep their buckets.
+ALTER TABLE flags ADD COLUMN salt VARCHAR(64) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN flags.salt IS 'Rollout bucketing salt; empty until the flag is first reshuffled';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE flags DROP COLUMN IF EXISTS salt;
+
+-- +goose StatementEnd
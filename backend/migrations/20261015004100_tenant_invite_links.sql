@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tenant invite links - shareable codes that let anyone signed in join a tenant with a
+-- fixed role, up to max_uses times until they expire or are revoked
+CREATE TABLE tenant_invite_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    code VARCHAR(64) UNIQUE NOT NULL,
+    role VARCHAR(50) NOT NULL,
+    max_uses INTEGER NOT NULL,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT tenant_invite_links_role_check CHECK (role IN ('admin', 'member')),
+    CONSTRAINT tenant_invite_links_uses_check CHECK (uses >= 0 AND uses <= max_uses)
+);
+
+CREATE INDEX idx_tenant_invite_links_tenant ON tenant_invite_links(tenant_id, created_at DESC);
+
+-- Who joined through each link
+CREATE TABLE tenant_invite_link_redemptions (
+    link_id UUID NOT NULL REFERENCES tenant_invite_links(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redeemed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (link_id, user_id)
+);
+
+COMMENT ON TABLE tenant_invite_links IS 'Shareable, role-bound tenant invite links with limited uses and an expiry';
+COMMENT ON TABLE tenant_invite_link_redemptions IS 'Users who joined a tenant through an invite link';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS tenant_invite_link_redemptions CASCADE;
+DROP TABLE IF EXISTS tenant_invite_links CASCADE;
+
+-- +goose StatementEnd
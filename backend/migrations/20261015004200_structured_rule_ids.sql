@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Structured rule IDs - Rule IDs used to be chosen by clients ("rule1") and weren't checked.
+-- The server now assigns UUIDs and rejects anything else, so give every stored rule without
+-- a lowercase UUID, and every repeat of an ID within one rule list, a new one. Rules that
+-- already have a unique UUID keep it.
+CREATE FUNCTION pg_temp.assign_rule_ids(rules JSONB) RETURNS JSONB AS $$
+    SELECT COALESCE(jsonb_agg(
+               CASE WHEN valid THEN rule
+                    ELSE rule || jsonb_build_object('id', gen_random_uuid()::text)
+               END ORDER BY ord), '[]'::jsonb)
+    FROM (
+        SELECT rule, ord,
+               COALESCE(rule->>'id', '') ~ '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+               AND ROW_NUMBER() OVER (PARTITION BY rule->>'id' ORDER BY ord) = 1 AS valid
+        FROM jsonb_array_elements(rules) WITH ORDINALITY AS r(rule, ord)
+    ) numbered
+$$ LANGUAGE SQL;
+
+UPDATE flags SET rules = pg_temp.assign_rule_ids(rules) WHERE jsonb_array_length(rules) > 0;
+UPDATE flag_environments SET rules = pg_temp.assign_rule_ids(rules) WHERE jsonb_array_length(rules) > 0;
+UPDATE flag_templates SET rules = pg_temp.assign_rule_ids(rules) WHERE jsonb_array_length(rules) > 0;
+
+-- Pending change requests are applied through the same validation, so fix their rules too
+UPDATE flag_change_requests
+SET changes = jsonb_set(changes, '{rules}', pg_temp.assign_rule_ids(changes->'rules'))
+WHERE status = 'pending' AND jsonb_typeof(changes->'rules') = 'array';
+
+DROP FUNCTION pg_temp.assign_rule_ids(JSONB);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Rule IDs were replaced in place; the previous client-chosen IDs are not recoverable
+SELECT 1;
+
+-- +goose StatementEnd
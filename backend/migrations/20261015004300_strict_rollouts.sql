@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Strict rollouts - sha256 bucketing maps users to 0-100 and admits bucket 0 to every rollout,
+-- even 0%. Tenants that opt in bucket with sha256_strict instead (internal/evaluation), which
+-- moves only the users in bucket 0. Existing tenants stay on sha256 until they switch.
+ALTER TABLE tenants ADD COLUMN strict_rollouts BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN tenants.strict_rollouts IS 'Bucket sha256 projects with sha256_strict, so 0% rollouts admit nobody';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS strict_rollouts;
+
+-- +goose StatementEnd
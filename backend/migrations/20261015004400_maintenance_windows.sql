@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Maintenance windows - Planned maintenance announced by the instance's operators.
+-- SDK responses carry an advisory header during a window, and streaming SDKs are told
+-- ahead of time so they can extend their local cache TTLs (internal/maintenance).
+CREATE TABLE maintenance_windows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    degradation VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT maintenance_windows_period_check CHECK (ends_at > starts_at),
+    CONSTRAINT maintenance_windows_degradation_check CHECK (degradation IN ('delayed_updates', 'read_only', 'unavailable'))
+);
+
+CREATE INDEX idx_maintenance_windows_ends_at ON maintenance_windows(ends_at);
+
+COMMENT ON TABLE maintenance_windows IS 'Planned maintenance announced to SDKs through response headers and stream events';
+COMMENT ON COLUMN maintenance_windows.degradation IS 'Expected impact: delayed_updates, read_only or unavailable';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS maintenance_windows;
+
+-- +goose StatementEnd
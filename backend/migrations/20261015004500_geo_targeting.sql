@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Geo targeting - SDK evaluations of opted-in projects get country and region attributes
+-- located from the caller's IP when the context doesn't supply them (internal/evaluation).
+ALTER TABLE projects ADD COLUMN geo_targeting BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN projects.geo_targeting IS 'Fill in missing country and region evaluation attributes from the SDK caller''s IP';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE projects DROP COLUMN IF EXISTS geo_targeting;
+
+-- +goose StatementEnd
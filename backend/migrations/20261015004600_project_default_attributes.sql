@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Default attributes - merged into the user attributes of every SDK evaluation context in the
+-- project, so integrations needn't send values like environment: production themselves.
+-- Attributes the SDK sends take precedence (internal/evaluation).
+ALTER TABLE projects ADD COLUMN default_attributes JSONB NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN projects.default_attributes IS 'Attributes merged into every evaluation context that does not already have them';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE projects DROP COLUMN IF EXISTS default_attributes;
+
+-- +goose StatementEnd
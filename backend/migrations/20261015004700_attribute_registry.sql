@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Project attributes - The attributes a project's SDKs send, with their types.
+-- Rules on a registered attribute are type checked when a flag is saved; rule editors
+-- autocomplete from this list.
+CREATE TABLE project_attributes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(128) NOT NULL,
+    context_kind VARCHAR(32) NOT NULL DEFAULT 'user',
+    type VARCHAR(16) NOT NULL CHECK (type IN ('string', 'number', 'bool', 'date')),
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (project_id, context_kind, name)
+);
+
+CREATE INDEX idx_project_attributes_tenant ON project_attributes(tenant_id, project_id);
+
+COMMENT ON TABLE project_attributes IS 'Per-project registry of context attributes and their types';
+COMMENT ON COLUMN project_attributes.type IS 'string, number, bool or date (an RFC 3339 timestamp or YYYY-MM-DD string)';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS project_attributes;
+
+-- +goose StatementEnd
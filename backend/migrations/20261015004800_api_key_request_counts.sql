@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- API key request counts - SDK requests and errors per key per hour, for capacity planning
+-- and abuse detection per integration. key_id is the project ID for the project's own key
+-- or the environment ID of an environment key.
+CREATE TABLE api_key_request_counts (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    key_id UUID NOT NULL,
+    hour TIMESTAMPTZ NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    client_error_count BIGINT NOT NULL DEFAULT 0,
+    server_error_count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (project_id, key_id, hour)
+);
+
+CREATE INDEX idx_api_key_request_counts_hour ON api_key_request_counts(hour);
+
+COMMENT ON TABLE api_key_request_counts IS 'Hourly SDK request and error counts per API key; kept for 7 days';
+COMMENT ON COLUMN api_key_request_counts.client_error_count IS '4xx responses, including rate limiting';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS api_key_request_counts;
+
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- SDK config - What GET /sdk/config recommends to a tenant's SDKs: how often to poll when
+-- they aren't streaming, and whether to stream at all. Tenants tune these for their traffic.
+ALTER TABLE tenants ADD COLUMN sdk_polling_interval_seconds INTEGER NOT NULL DEFAULT 30
+    CHECK (sdk_polling_interval_seconds BETWEEN 5 AND 3600);
+ALTER TABLE tenants ADD COLUMN sdk_streaming_enabled BOOLEAN NOT NULL DEFAULT TRUE;
+
+COMMENT ON COLUMN tenants.sdk_polling_interval_seconds IS 'Polling interval recommended to SDKs that are not streaming';
+COMMENT ON COLUMN tenants.sdk_streaming_enabled IS 'Whether SDKs are told to stream flag changes instead of polling';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS sdk_streaming_enabled;
+ALTER TABLE tenants DROP COLUMN IF EXISTS sdk_polling_interval_seconds;
+
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Approval policies now govern direct flag edits as well as change requests, so approval is
+-- opt-in: projects without a stored policy allow direct edits and apply proposals at once.
+ALTER TABLE project_approval_policies ALTER COLUMN require_approval SET DEFAULT FALSE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE project_approval_policies ALTER COLUMN require_approval SET DEFAULT TRUE;
+
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Environments whose flag configs may only change through approved change requests, by key.
+-- They are independent of require_approval, which covers the flags' own config.
+ALTER TABLE project_approval_policies ADD COLUMN environments TEXT[] NOT NULL DEFAULT '{}';
+
+-- The environment a change request edits the flag's config in; NULL edits the flag itself
+ALTER TABLE flag_change_requests ADD COLUMN environment VARCHAR(32);
+
+COMMENT ON COLUMN project_approval_policies.environments IS 'Keys of the environments whose flag configs require approved change requests';
+COMMENT ON COLUMN flag_change_requests.environment IS 'Key of the environment whose config the change edits, or NULL for the flag itself';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE flag_change_requests DROP COLUMN IF EXISTS environment;
+ALTER TABLE project_approval_policies DROP COLUMN IF EXISTS environments;
+
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Client-side SDK keys - Browser and mobile SDKs ship their key to every user, so they get a
+-- key of their own that can only evaluate flags. Rulesets and snapshots, which carry every
+-- rule and targeted user key, stay behind the server-side client_api_key.
+ALTER TABLE projects ADD COLUMN client_side_api_key VARCHAR(64) UNIQUE NOT NULL
+    DEFAULT replace(gen_random_uuid()::text, '-', '') || replace(gen_random_uuid()::text, '-', '');
+
+COMMENT ON COLUMN projects.client_side_api_key IS 'Public SDK key for browsers and mobile apps; evaluates flags but cannot read rules';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE projects DROP COLUMN IF EXISTS client_side_api_key;
+
+-- +goose StatementEnd